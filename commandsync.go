@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/commands"
+)
+
+// syncModeFlag is the value of --sync-commands.
+// It can be given without a value like the former boolean flag, so a mode must be given as --sync-commands=diff.
+type syncModeFlag struct {
+	mode commands.SyncMode
+}
+
+func (f *syncModeFlag) String() string {
+	if f.mode == "" {
+		return string(commands.SyncModeOff)
+	}
+	return string(f.mode)
+}
+
+func (f *syncModeFlag) Set(value string) error {
+	mode, err := commands.ParseSyncMode(value)
+	if err != nil {
+		return err
+	}
+	f.mode = mode
+	return nil
+}
+
+func (f *syncModeFlag) IsBoolFlag() bool {
+	return true
+}
+
+// syncCommands syncs the commands with discord as selected by the mode.
+// A global sync in SyncModeGlobal is confirmed on stdin, unless assumeYes is set.
+func syncCommands(client bot.Client, syncer *commands.CommandSyncer, mode commands.SyncMode, devGuilds []snowflake.ID, assumeYes bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	switch mode {
+	case commands.SyncModeOff, "":
+		return nil
+	case commands.SyncModeAlways, commands.SyncModeDiff:
+		targets := devGuilds
+		if len(targets) == 0 {
+			targets = []snowflake.ID{0}
+		}
+		for _, guildID := range targets {
+			if err := syncTarget(ctx, client, syncer, guildID, mode == commands.SyncModeAlways); err != nil {
+				return err
+			}
+		}
+		return nil
+	case commands.SyncModeGuild:
+		if len(devGuilds) == 0 {
+			return errors.New("guild sync requires bot.dev_guilds to be set")
+		}
+		for _, guildID := range devGuilds {
+			if err := syncTarget(ctx, client, syncer, guildID, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	case commands.SyncModeGlobal:
+		diff, err := syncer.Diff(ctx, client, 0)
+		if err != nil {
+			return err
+		}
+		if diff.IsEmpty() {
+			slog.Info("Global commands are up to date")
+			return nil
+		}
+		if !assumeYes && !confirm(os.Stdin, os.Stdout, "Sync the global commands ("+diff.String()+")?") {
+			slog.Warn("Global command sync cancelled")
+			return nil
+		}
+		return syncTarget(ctx, client, syncer, 0, false)
+	}
+	return fmt.Errorf("unknown sync mode %q", mode)
+}
+
+func syncTarget(ctx context.Context, client bot.Client, syncer *commands.CommandSyncer, guildID snowflake.ID, force bool) error {
+	target := "global"
+	if guildID != 0 {
+		target = guildID.String()
+	}
+	diff, err := syncer.Sync(ctx, client, guildID, force)
+	if err != nil {
+		return fmt.Errorf("failed to sync %s commands: %w", target, err)
+	}
+	slog.Info("Synced commands", slog.String("target", target), slog.String("diff", diff.String()))
+	return nil
+}
+
+// confirm asks the question and reports whether it was answered with "yes".
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s Type \"yes\" to continue: ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false
+	}
+	return strings.TrimSpace(answer) == "yes"
+}
@@ -2,17 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"path"
 	"strconv"
 	"syscall"
 	"time"
+	// embed the time zone database, the runtime image does not ship tzdata.
+	_ "time/tzdata"
 
 	texttospeech "cloud.google.com/go/texttospeech/apiv1"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/polly"
 	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
 	"github.com/disgoorg/disgo/handler"
 	"github.com/disgoorg/snowflake/v2"
@@ -24,11 +33,18 @@ import (
 	_ "modernc.org/sqlite" // sqlite driver
 
 	"github.com/makeitchaccha/text-to-speech/ttsbot"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/alert"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/commands"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/entitlement"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/logging"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/retention"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/session"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/webhook"
 
 	_ "github.com/go-sql-driver/mysql" // mysql driver
 )
@@ -40,6 +56,13 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "selftest" {
+		os.Exit(runSelftest(os.Args[2:]))
+	}
+
 	trs, err := i18n.LoadTextResources("./locales/text/", "en-US")
 	if err != nil {
 		slog.Error("Failed to load text resources", slog.Any("err", err))
@@ -50,9 +73,16 @@ func main() {
 		slog.Error("Failed to load voice resources", slog.Any("err", err))
 		os.Exit(-1)
 	}
+	if err := vrs.LoadPacks("./locales/packs/"); err != nil {
+		slog.Error("Failed to load voice packs", slog.Any("err", err))
+		os.Exit(-1)
+	}
 
-	shouldSyncCommands := flag.Bool("sync-commands", false, "Whether to sync commands to discord")
+	var syncMode syncModeFlag
+	flag.Var(&syncMode, "sync-commands", "How to sync commands to discord: off, always (same as true), diff, guild or global")
+	assumeYes := flag.Bool("yes", false, "Sync global commands without asking for confirmation")
 	path := flag.String("config", "config.toml", "path to config")
+	shouldMigrate := flag.Bool("migrate", false, "Whether to apply pending migrations when the database schema is behind the expected version")
 	flag.Parse()
 
 	cfg, err := ttsbot.LoadConfig(*path)
@@ -61,15 +91,26 @@ func main() {
 		os.Exit(-1)
 	}
 
-	setupLogger(cfg.Log)
+	logFile := setupLogger(cfg.Log)
+	if logFile != nil {
+		defer logFile.Close()
+	}
 	slog.Info("Starting ttsbot...", slog.String("version", Version), slog.String("commit", Commit))
 	slog.Info("Connecting to Google Cloud TTS")
 
-	slog.Info("Syncing commands", slog.Bool("sync", *shouldSyncCommands))
+	slog.Info("Syncing commands", slog.String("mode", syncMode.String()))
 
 	b := ttsbot.New(*cfg, Version, Commit)
 
-	opts := make([]engineOpt, 0)
+	notifier, channelNotifier := buildNotifier(cfg.Operator)
+
+	latencyRecorder := tts.NewLatencyRecorder()
+	// latency is measured closest to the engine, so that cache hits do not hide slow engine responses.
+	// the circuit breaker sits inside the cache, so that cached speech is still played while an engine is down.
+	opts := []engineOpt{
+		withLatencyRecorder(latencyRecorder, cfg.Bot.SlowSynthesisThreshold),
+		withCircuitBreaker(notifier),
+	}
 	var redisClient *redis.Client
 	if cfg.Redis.Enabled {
 		slog.Info("Connecting to Redis", slog.String("url", cfg.Redis.Url))
@@ -86,6 +127,9 @@ func main() {
 		}
 
 		slog.Info("Connected to Redis", slog.String("url", cfg.Redis.Url))
+		alert.StartCheckLoop(notifier, alert.KindRedisUnavailable, "Redis is unreachable; sessions are not persisted and speech is not cached.", time.Minute, func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		})
 
 		opts = append(opts, withCache(cache.New(&cache.Options{
 			Redis:      redisClient,
@@ -94,56 +138,213 @@ func main() {
 	}
 
 	sessionManager := session.NewSessionManager()
+	voiceDiagnostics := session.NewVoiceDiagnostics(3)
+	rejoinGuard := session.NewRejoinGuard(30*time.Second, 30*time.Minute, 3)
+	sessionManager.AddObserver(rejoinGuard)
+	// members are refreshed in the background after 5 minutes, and fetched again after 30 minutes without messages.
+	memberResolver := session.NewMemberResolver(30*time.Minute, 5*time.Minute)
+	decoder, err := buildDecoder(cfg.Audio)
+	if err != nil {
+		slog.Error("Failed to set up the audio decoder", slog.Any("err", err))
+		os.Exit(-1)
+	}
+	var frameCache *session.OpusFrameCache
+	if cfg.Audio.AnnouncementCacheSize > 0 {
+		frameCache = session.NewOpusFrameCache(cfg.Audio.AnnouncementCacheSize)
+	}
+	maxQueuedAudio := int64(cfg.Audio.MaxQueuedAudioMB) << 20
 
 	engineRegistry := tts.NewEngineRegistry()
-	registerDefaultEngines(engineRegistry, opts...)
+	if err := registerDefaultEngines(engineRegistry, cfg.Engines, opts...); err != nil {
+		slog.Error("Failed to register engines", slog.Any("err", err))
+		os.Exit(-1)
+	}
+
+	// voices rarely change, so they are listed once an hour at most.
+	voiceCatalog := tts.NewVoiceCatalog(engineRegistry, time.Hour)
 
 	presetRegistry := preset.NewPresetRegistry()
 	for identifier, presetConfig := range cfg.Presets {
-		if err := registerPreset(engineRegistry, presetRegistry, identifier, presetConfig); err != nil {
+		if err := registerPreset(engineRegistry, voiceCatalog, presetRegistry, identifier, presetConfig); err != nil {
 			slog.Error("Failed to register preset", slog.String("identifier", identifier), slog.Any("err", err))
 			os.Exit(-1)
 		}
 	}
-
-	db, err := sqlx.Connect(cfg.Database.Driver, cfg.Database.Dsn)
-	if err != nil {
-		slog.Error("Failed to connect to database", slog.Any("err", err))
+	switch cfg.Bot.VoiceValidation {
+	case "", "off":
+	case "warn", "fail":
+		if err := validatePresetVoices(voiceCatalog, presetRegistry.List()); err != nil {
+			if cfg.Bot.VoiceValidation == "fail" {
+				slog.Error("Presets use voices unknown to their engines", slog.Any("err", err))
+				os.Exit(-1)
+			}
+			slog.Warn("Presets use voices unknown to their engines", slog.Any("err", err))
+		}
+	default:
+		slog.Error("Unknown voice validation mode", slog.String("mode", cfg.Bot.VoiceValidation))
 		os.Exit(-1)
 	}
-	defer db.Close()
 
-	if err := validateDBVersion(db, cfg.Database.Driver); err != nil {
-		slog.Error("Failed to validate database version", slog.Any("err", err))
-		os.Exit(-1)
+	var (
+		presetIDRepository  preset.PresetIDRepository
+		settingsRepository  settings.GuildSettingsRepository
+		departureRepository retention.DepartureRepository
+		usageRepository     tts.UsageRepository
+	)
+	if cfg.Database.Driver == "none" {
+		slog.Warn("Running without a database, presets and settings are kept in memory and lost on restart")
+		presetIDRepository = preset.NewMemoryPresetIDRepository()
+		settingsRepository = settings.NewMemoryGuildSettingsRepository()
+		departureRepository = retention.NewMemoryDepartureRepository()
+		usageRepository = tts.NewMemoryUsageRepository()
+	} else {
+		db, err := database.Open(cfg.Database.Driver, cfg.Database.Dsn, databaseOptions(cfg.Database))
+		if err != nil {
+			slog.Error("Failed to connect to database", slog.Any("err", err))
+			os.Exit(-1)
+		}
+		defer db.Close()
+
+		if err := validateDBVersion(db, cfg.Database.Driver, *shouldMigrate); err != nil {
+			slog.Error("Failed to validate database version", slog.Any("err", err))
+			os.Exit(-1)
+		}
+		if ExpectedMigrationVersion != "" {
+			alert.StartCheckLoop(notifier, alert.KindMigrationMismatch, "The database schema changed while the bot is running; deploy the matching version of the bot.", 5*time.Minute, func(ctx context.Context) error {
+				return checkDBVersion(ctx, db)
+			})
+		}
+
+		var keyRing *database.KeyRing
+		if cfg.Database.EncryptionKeyFile != "" {
+			keyRing, err = database.LoadKeyRingFile(cfg.Database.EncryptionKeyFile)
+			if err != nil {
+				slog.Error("Failed to load encryption keys", slog.Any("err", err))
+				os.Exit(-1)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			reencrypted, err := settings.ReencryptGuildSettings(ctx, db, keyRing)
+			cancel()
+			if err != nil {
+				slog.Error("Failed to re-encrypt guild settings", slog.Any("err", err))
+				os.Exit(-1)
+			}
+			if reencrypted > 0 {
+				slog.Info("Re-encrypted guild settings with the primary key", slog.Int("guilds", reencrypted))
+			}
+		}
+
+		presetIDRepository = preset.NewPresetIDRepository(db)
+		settingsRepository = settings.NewEncryptedGuildSettingsRepository(db, keyRing)
+		departureRepository = retention.NewDepartureRepository(db)
+		usageRepository = tts.NewUsageRepository(db)
 	}
 
-	presetResolver, err := preset.NewPresetResolver(presetRegistry, preset.NewPresetIDRepository(db), preset.PresetID(cfg.Bot.FallbackPresetID))
+	settingsRepository = settings.NewPolicyRepository(settingsRepository, buildSettingsPolicy(cfg.Guilds))
+
+	entitlementChecker, discordEntitlements := buildEntitlementChecker(cfg.Entitlements)
+
+	usageTracker := tts.NewUsageTracker(usageRepository, func(guildID snowflake.ID) int64 {
+		if entitlementChecker.Premium(guildID) {
+			return cfg.Guilds.Limits.PremiumMonthlyCharacters
+		}
+		return cfg.Guilds.Limits.MonthlyCharacters
+	})
+	usageTracker.StartFlushLoop(time.Minute)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := usageTracker.Flush(ctx); err != nil {
+			slog.Error("Failed to flush usage", slog.Any("err", err))
+		}
+	}()
+
+	webhookDispatcher := webhook.NewDispatcher(settingsRepository, webhook.NewClient(15*time.Second), 100)
+	webhookDispatcher.Start(context.Background())
+	sessionManager.AddObserver(webhook.NewSessionObserver(webhookDispatcher))
+
+	restrictions, err := buildRestrictions(cfg.Restrictions, entitlementChecker)
 	if err != nil {
-		slog.Error("Failed to create preset resolver", slog.Any("err", err))
+		slog.Error("Failed to build preset restrictions", slog.Any("err", err))
 		os.Exit(-1)
 	}
 
-	h := handler.New()
-	h.Command("/join", commands.JoinHandler(engineRegistry, presetResolver, sessionManager, trs, vrs))
+	presetResolver, err := preset.NewPresetResolver(presetRegistry, presetIDRepository, restrictions, preset.PresetID(cfg.Bot.FallbackPresetID))
 	if err != nil {
-		slog.Error("Failed to create join autocomplete handler", slog.Any("err", err))
+		slog.Error("Failed to create preset resolver", slog.Any("err", err))
 		os.Exit(-1)
 	}
-	h.Command("/leave", commands.LeaveHandler(sessionManager, trs))
-	h.Command("/preset", commands.PresetHandler(presetRegistry, presetResolver, preset.NewPresetIDRepository(db), trs))
+
+	commandTimings := commands.NewCommandTimings()
+	commandSyncer := commands.NewCommandSyncer(commands.Commands(trs))
+	owners := commands.NewOwners()
+
+	h := handler.New()
+	// timings wrap the recovery, so that handlers that panicked are counted as failed,
+	// and the recovery is localized, so that the invoker is told in their language.
+	h.Use(commands.Correlate(), commands.Timing(commandTimings, 2500*time.Millisecond), commands.Localize(trs), commands.Recover())
+	h.Command("/leave", commands.LeaveHandler(sessionManager))
+	h.Command("/preset", commands.PresetHandler(presetRegistry, presetResolver, presetIDRepository, restrictions, settingsRepository))
+	h.Command("/mydata", commands.MydataHandler(presetIDRepository))
 	h.Command("/version", commands.VersionHandler(b))
+	h.Command("/voices", commands.VoicesHandler(engineRegistry, voiceCatalog))
+	h.Component("/voices/{engine}/{language}/{page}", commands.VoicesPageHandler(engineRegistry, voiceCatalog))
+	h.Group(func(r handler.Router) {
+		r.Use(commands.GuildOnly())
+		r.Command("/join", commands.JoinHandler(engineRegistry, presetResolver, sessionManager, settingsRepository, voiceDiagnostics, memberResolver, decoder, frameCache, maxQueuedAudio, usageTracker, vrs))
+		r.Component("/join/takeover/{userID}/{voiceChannelID}", commands.JoinTakeoverHandler(engineRegistry, presetResolver, sessionManager, settingsRepository, voiceDiagnostics, memberResolver, decoder, frameCache, maxQueuedAudio, usageTracker, vrs))
+		r.Component("/join/cancel/{userID}", commands.JoinCancelHandler())
+		r.Command("/settings", commands.SettingsHandler(settingsRepository, vrs))
+		r.Command("/setup", commands.SetupHandler(presetRegistry, presetIDRepository, restrictions, settingsRepository))
+		r.Group(func(r handler.Router) {
+			r.Use(commands.RequirePermission(discord.PermissionManageGuild, func(tr i18n.TextResource) string {
+				return tr.Commands.Setup.ErrorPermission
+			}))
+			r.Component("/setup/{step}", commands.SetupComponentHandler(presetRegistry, presetIDRepository, restrictions, settingsRepository))
+		})
+		r.Command("/usage", commands.UsageHandler(usageTracker))
+		r.Command("/preview", commands.PreviewHandler(presetResolver, settingsRepository, memberResolver, vrs))
+		r.Command("/debug", commands.DebugHandler(sessionManager, voiceDiagnostics, latencyRecorder, commandTimings))
+		r.Command("/admin", commands.AdminHandler(owners, commandSyncer, usageTracker, enginePricing(cfg.Engines)))
+		r.Component("/admin/sync/global/{userID}", commands.AdminSyncGlobalHandler(owners, commandSyncer))
+	})
+
+	sessionManager.HandleTextCommand("skip", commands.SkipTextCommandHandler())
+	sessionManager.HandleTextCommand("leave", commands.LeaveTextCommandHandler(sessionManager))
+	sessionManager.HandleTextCommand("preset", commands.PresetTextCommandHandler(presetRegistry, presetIDRepository, restrictions))
 
 	listeners := []bot.EventListener{
 		h,
 		bot.NewListenerFunc(b.OnReady),
 		sessionManager.CreateMessageHandler(),
 		sessionManager.CreateVoiceStateHandler(),
+		voiceDiagnostics.CreateVoiceServerUpdateHandler(),
+		commands.SetupGuildJoinListener(presetRegistry, presetIDRepository, restrictions, settingsRepository, trs),
+		createGuildLeaveListener(sessionManager),
+	}
+	if discordEntitlements != nil {
+		listeners = append(listeners, entitlementChecker.CreateEntitlementHandler())
+	}
+
+	if cfg.Retention.DepartedGuilds > 0 {
+		cleaner := retention.NewCleaner(departureRepository, cfg.Retention.DepartedGuilds,
+			settingsRepository.Delete,
+			usageTracker.Delete,
+			func(ctx context.Context, guildID snowflake.ID) error {
+				return presetIDRepository.Delete(ctx, preset.ScopeGuild, guildID)
+			},
+		)
+		listeners = append(listeners, cleaner.CreateGuildHandler())
+		purgeInterval := cfg.Retention.PurgeInterval
+		if purgeInterval <= 0 {
+			purgeInterval = time.Hour
+		}
+		cleaner.StartPurgeLoop(purgeInterval)
 	}
 
 	// FIXME: make this optional via config and write this in safety way.
 	if cfg.Redis.Enabled {
-		sessionRestorationListener := createSessionRestorationListener(redisClient, engineRegistry, presetResolver, sessionManager, trs, vrs)
+		sessionRestorationListener := createSessionRestorationListener(redisClient, engineRegistry, presetResolver, sessionManager, settingsRepository, voiceDiagnostics, rejoinGuard, memberResolver, decoder, frameCache, maxQueuedAudio, usageTracker, notifier, trs, vrs)
 		listeners = append(listeners, sessionRestorationListener)
 	}
 
@@ -151,6 +352,12 @@ func main() {
 		slog.Error("Failed to setup bot", slog.Any("err", err))
 		os.Exit(-1)
 	}
+	if channelNotifier != nil {
+		channelNotifier.Start(context.Background(), b.Client.Rest())
+	}
+	if discordEntitlements != nil {
+		discordEntitlements.SetClient(b.Client.ApplicationID(), b.Client.Rest())
+	}
 
 	defer func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -158,9 +365,9 @@ func main() {
 		b.Client.Close(ctx)
 	}()
 
-	if *shouldSyncCommands {
-		slog.Info("Syncing commands", slog.Any("guild_ids", cfg.Bot.DevGuilds))
-		if err = handler.SyncCommands(b.Client, commands.Commands(trs), cfg.Bot.DevGuilds); err != nil {
+	if syncMode.mode != commands.SyncModeOff {
+		slog.Info("Syncing commands", slog.String("mode", syncMode.String()), slog.Any("guild_ids", cfg.Bot.DevGuilds))
+		if err = syncCommands(b.Client, commandSyncer, syncMode.mode, cfg.Bot.DevGuilds, *assumeYes); err != nil {
 			slog.Error("Failed to sync commands", slog.Any("err", err))
 		}
 	}
@@ -179,7 +386,7 @@ func main() {
 	slog.Info("Shutting down bot...")
 }
 
-func validateDBVersion(db *sqlx.DB, driverName string) error {
+func validateDBVersion(db *sqlx.DB, driverName string, shouldMigrate bool) error {
 	if ExpectedMigrationVersion == "" {
 		slog.Warn("Expected migration version not set, skipping database schema validation. (This is normal in local development)")
 		return nil
@@ -187,7 +394,7 @@ func validateDBVersion(db *sqlx.DB, driverName string) error {
 
 	slog.Info("Validating database schema version", "expected", ExpectedMigrationVersion)
 
-	if err := goose.SetDialect(driverName); err != nil {
+	if err := setupGoose(driverName); err != nil {
 		return fmt.Errorf("failed to set goose dialect: %w", err)
 	}
 
@@ -201,31 +408,120 @@ func validateDBVersion(db *sqlx.DB, driverName string) error {
 		return fmt.Errorf("failed to parse expected migration version: %w", err)
 	}
 
+	if currentVersion < expectedVersion && shouldMigrate {
+		slog.Info("Applying pending migrations", "current", currentVersion, "expected", expectedVersion)
+		if err := goose.UpTo(db.DB, migrationsDir, expectedVersion); err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+		currentVersion = expectedVersion
+	}
+
 	if currentVersion != expectedVersion {
-		return fmt.Errorf("database schema version mismatch. expected: %d, but got: %d. please run migration", expectedVersion, currentVersion)
+		return fmt.Errorf("database schema version mismatch. expected: %d, but got: %d. please run `ttsbot migrate up` or start with -migrate", expectedVersion, currentVersion)
 	}
 
 	slog.Info("Database schema version validated successfully", "version", currentVersion)
 	return nil
 }
 
-func setupLogger(cfg ttsbot.LogConfig) {
+// checkDBVersion reports an error if the schema version of the database is not the expected one.
+func checkDBVersion(ctx context.Context, db *sqlx.DB) error {
+	currentVersion, err := goose.GetDBVersionContext(ctx, db.DB)
+	if err != nil {
+		return fmt.Errorf("failed to get current db version: %w", err)
+	}
+	expectedVersion, err := strconv.ParseInt(ExpectedMigrationVersion, 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse expected migration version: %w", err)
+	}
+	if currentVersion != expectedVersion {
+		return fmt.Errorf("database schema version mismatch. expected: %d, but got: %d", expectedVersion, currentVersion)
+	}
+	return nil
+}
+
+// buildNotifier returns the notifier of operator alerts. Without an operator channel, alerts are only logged,
+// and the returned channel notifier is nil.
+func buildNotifier(operatorConfig ttsbot.OperatorConfig) (alert.Notifier, *alert.ChannelNotifier) {
+	if operatorConfig.ChannelID == 0 {
+		return alert.LogNotifier{}, nil
+	}
+	interval := operatorConfig.AlertInterval
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	channelNotifier := alert.NewChannelNotifier(operatorConfig.ChannelID, interval)
+	return channelNotifier, channelNotifier
+}
+
+// databaseOptions converts the database config into connection options.
+func databaseOptions(cfg ttsbot.DatabaseConfig) database.Options {
+	return database.Options{
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		SQLite: database.SQLiteOptions{
+			JournalMode: cfg.SQLite.JournalMode,
+			BusyTimeout: cfg.SQLite.BusyTimeout,
+		},
+	}
+}
+
+// setupLogger sets the default logger, and returns the log file it writes to, if any, to be closed on shutdown.
+func setupLogger(cfg ttsbot.LogConfig) *logging.RotatingFile {
 	opts := &slog.HandlerOptions{
 		AddSource: cfg.AddSource,
 		Level:     cfg.Level,
 	}
 
+	var (
+		output io.Writer
+		file   *logging.RotatingFile
+	)
+	switch cfg.Output {
+	case "", "stdout":
+		output = os.Stdout
+	case "file", "both":
+		var err error
+		file, err = logging.OpenRotatingFile(cfg.File.Path, logging.RotatingFileOptions{
+			MaxSize:    int64(cfg.File.MaxSize) * 1024 * 1024,
+			MaxAge:     cfg.File.MaxAge,
+			MaxBackups: cfg.File.MaxBackups,
+		})
+		if err != nil {
+			slog.Error("Failed to open log file", slog.String("path", cfg.File.Path), slog.Any("err", err))
+			os.Exit(-1)
+		}
+		output = file
+		if cfg.Output == "both" {
+			output = io.MultiWriter(os.Stdout, file)
+		}
+	default:
+		slog.Error("Unknown log output", slog.String("output", cfg.Output))
+		os.Exit(-1)
+	}
+
 	var sHandler slog.Handler
 	switch cfg.Format {
 	case "json":
-		sHandler = slog.NewJSONHandler(os.Stdout, opts)
+		sHandler = slog.NewJSONHandler(output, opts)
 	case "text":
-		sHandler = slog.NewTextHandler(os.Stdout, opts)
+		sHandler = slog.NewTextHandler(output, opts)
 	default:
 		slog.Error("Unknown log format", slog.String("format", cfg.Format))
 		os.Exit(-1)
 	}
-	slog.SetDefault(slog.New(sHandler))
+
+	if len(cfg.Sampling) > 0 {
+		rules := make(map[string]logging.SamplingRule, len(cfg.Sampling))
+		for component, sampling := range cfg.Sampling {
+			rules[component] = logging.SamplingRule{Level: sampling.Level, Every: sampling.Every}
+		}
+		sHandler = logging.NewSamplingHandler(sHandler, rules)
+	}
+	slog.SetDefault(slog.New(logging.NewCorrelationHandler(sHandler)))
+	return file
 }
 
 type engineOpt func(tts.Engine) tts.Engine
@@ -236,6 +532,23 @@ func withCache(redisCache *cache.Cache, ttl time.Duration) engineOpt {
 	}
 }
 
+func withLatencyRecorder(recorder *tts.LatencyRecorder, slowThreshold time.Duration) engineOpt {
+	return func(e tts.Engine) tts.Engine {
+		return tts.NewMeasuredEngine(e, recorder, slowThreshold)
+	}
+}
+
+// withCircuitBreaker stops calling an engine for 30 seconds after 5 consecutive failures, and alerts the operators.
+func withCircuitBreaker(notifier alert.Notifier) engineOpt {
+	return func(e tts.Engine) tts.Engine {
+		return tts.NewCircuitBreakerEngine(e, 5, 30*time.Second, func(engine string, open bool, err error) {
+			if open {
+				notifier.Notify(alert.Alert{Kind: alert.KindEngineUnavailable, Message: fmt.Sprintf("Requests to %s are paused after repeated failures.", engine), Err: err})
+			}
+		})
+	}
+}
+
 func applyEngineOpts(engine tts.Engine, opts ...engineOpt) tts.Engine {
 	for _, opt := range opts {
 		engine = opt(engine)
@@ -243,19 +556,129 @@ func applyEngineOpts(engine tts.Engine, opts ...engineOpt) tts.Engine {
 	return engine
 }
 
-func registerDefaultEngines(registry *tts.EngineRegistry, opts ...engineOpt) error {
-	googleEngine, err := prepareGoogleTTSEngine()
-	if err != nil {
-		slog.Error("Failed to prepare Google TTS engine", slog.Any("err", err))
-		return err
+func registerDefaultEngines(registry *tts.EngineRegistry, enginesConfig map[string]ttsbot.EngineConfig, opts ...engineOpt) error {
+	if !enginesConfig["google"].Disabled {
+		googleOutput, err := outputFormat(enginesConfig["google"], tts.DefaultOutputFormat)
+		if err != nil {
+			return fmt.Errorf("invalid config of engine google: %w", err)
+		}
+		googleEngine, err := prepareGoogleTTSEngine(googleOutput)
+		if err != nil {
+			slog.Error("Failed to prepare Google TTS engine", slog.Any("err", err))
+			return err
+		}
+		registry.Register("google", applyEngineOpts(rateLimited(googleEngine, enginesConfig["google"]), opts...))
+	}
+
+	if !enginesConfig["polly"].Disabled {
+		pollyEngine, err := preparePollyEngine(enginesConfig["polly"])
+		switch {
+		case errors.Is(err, errAWSNotConfigured):
+			slog.Info("AWS is not configured, skipping the Polly engine")
+		case err != nil:
+			slog.Error("Failed to prepare Polly engine", slog.Any("err", err))
+			return err
+		default:
+			registry.Register("polly", applyEngineOpts(rateLimited(pollyEngine, enginesConfig["polly"]), opts...))
+		}
 	}
 
-	registry.Register("google", applyEngineOpts(googleEngine, opts...))
-	slog.Info("Default TTS engines registered")
+	if voicevoxConfig := enginesConfig["voicevox"]; voicevoxConfig.URL != "" && !voicevoxConfig.Disabled {
+		voicevoxEngine, err := prepareVoicevoxEngine(voicevoxConfig)
+		if err != nil {
+			slog.Error("Failed to prepare VOICEVOX engine", slog.Any("err", err))
+			return err
+		}
+		registry.Register("voicevox", applyEngineOpts(rateLimited(voicevoxEngine, enginesConfig["voicevox"]), opts...))
+	}
+
+	if openAIConfig := enginesConfig["openai"]; openAIConfig.APIKey != "" && !openAIConfig.Disabled {
+		openAIEngine, err := prepareOpenAIEngine(openAIConfig)
+		if err != nil {
+			slog.Error("Failed to prepare OpenAI engine", slog.Any("err", err))
+			return err
+		}
+		registry.Register("openai", applyEngineOpts(rateLimited(openAIEngine, enginesConfig["openai"]), opts...))
+	}
+
+	if elevenLabsConfig := enginesConfig["elevenlabs"]; elevenLabsConfig.APIKey != "" && !elevenLabsConfig.Disabled {
+		elevenLabsEngine, err := prepareElevenLabsEngine(elevenLabsConfig)
+		if err != nil {
+			slog.Error("Failed to prepare ElevenLabs engine", slog.Any("err", err))
+			return err
+		}
+		registry.Register("elevenlabs", applyEngineOpts(rateLimited(elevenLabsEngine, enginesConfig["elevenlabs"]), opts...))
+	}
+
+	slog.Info("Default TTS engines registered", slog.Any("engines", registry.Identifiers()))
 	return nil
 }
 
-func prepareGoogleTTSEngine() (tts.Engine, error) {
+// rateLimited wraps the engine to keep its requests under the rate limit of its config, if it has one.
+// It wraps the engine before the other options, so that cached speech does not count against the limit.
+func rateLimited(engine tts.Engine, engineConfig ttsbot.EngineConfig) tts.Engine {
+	if engineConfig.RequestsPerMinute <= 0 && engineConfig.CharactersPerMinute <= 0 {
+		return engine
+	}
+	return tts.NewRateLimitedEngine(engine, tts.RateLimit{
+		RequestsPerMinute:   engineConfig.RequestsPerMinute,
+		CharactersPerMinute: engineConfig.CharactersPerMinute,
+	})
+}
+
+// enginePricing returns the prices of the engines set in the config.
+func enginePricing(enginesConfig map[string]ttsbot.EngineConfig) tts.Pricing {
+	pricing := make(tts.Pricing)
+	for name, engineConfig := range enginesConfig {
+		if engineConfig.CostPerMillionCharacters > 0 {
+			pricing[name] = engineConfig.CostPerMillionCharacters
+		}
+	}
+	return pricing
+}
+
+// buildDecoder returns the decoder of synthesized speech selected in the config.
+func buildDecoder(audioConfig ttsbot.AudioConfig) (session.Decoder, error) {
+	var decoder session.Decoder
+	switch audioConfig.Decoder {
+	case "", "native":
+		decoder = session.NativeDecoder{}
+	case "ffmpeg":
+		ffmpegDecoder, err := session.NewFFmpegDecoder(audioConfig.FFmpegPath)
+		if err != nil {
+			return nil, err
+		}
+		decoder = ffmpegDecoder
+	default:
+		return nil, fmt.Errorf("unknown audio decoder: %q", audioConfig.Decoder)
+	}
+
+	if audioConfig.PrebufferFrames > 0 {
+		decoder = session.NewPrebufferingDecoder(decoder, audioConfig.PrebufferFrames)
+	}
+	return decoder, nil
+}
+
+// outputFormat returns the output format configured for an engine, filling in the defaults of the engine.
+func outputFormat(engineConfig ttsbot.EngineConfig, defaults tts.OutputFormat) (tts.OutputFormat, error) {
+	output := defaults
+	if engineConfig.AudioEncoding != "" {
+		format, err := tts.ParseAudioFormat(engineConfig.AudioEncoding)
+		if err != nil {
+			return tts.OutputFormat{}, err
+		}
+		output.Format = format
+	}
+	if engineConfig.SampleRate < 0 {
+		return tts.OutputFormat{}, fmt.Errorf("invalid sample rate: %d", engineConfig.SampleRate)
+	}
+	if engineConfig.SampleRate != 0 {
+		output.SampleRate = engineConfig.SampleRate
+	}
+	return output, nil
+}
+
+func prepareGoogleTTSEngine(output tts.OutputFormat) (tts.Engine, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	ttsClient, err := texttospeech.NewClient(ctx)
@@ -263,34 +686,376 @@ func prepareGoogleTTSEngine() (tts.Engine, error) {
 		return nil, err
 	}
 
-	return tts.NewGoogleTTSEngine(ttsClient), nil
+	slog.Info("Google TTS engine output", slog.String("format", output.Format.String()), slog.Int("sampleRate", output.SampleRate))
+	return tts.NewGoogleTTSEngine(ttsClient, output), nil
+}
+
+// errAWSNotConfigured is returned by preparePollyEngine when neither the config nor the AWS environment sets a region,
+// or no credentials can be retrieved, so that the bot starts without the optional engine.
+var errAWSNotConfigured = errors.New("AWS is not configured")
+
+// preparePollyEngine creates the Polly engine with the credentials of the AWS environment or profile.
+func preparePollyEngine(engineConfig ttsbot.EngineConfig) (tts.Engine, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	awsConfig, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(engineConfig.Region))
+	if err != nil {
+		return nil, err
+	}
+	if awsConfig.Region == "" {
+		return nil, errAWSNotConfigured
+	}
+	if _, err := awsConfig.Credentials.Retrieve(ctx); err != nil {
+		slog.Warn("Failed to retrieve AWS credentials, skipping the Polly engine", slog.String("region", awsConfig.Region), slog.Any("err", err))
+		return nil, fmt.Errorf("%w: failed to retrieve AWS credentials: %w", errAWSNotConfigured, err)
+	}
+
+	output, err := outputFormat(engineConfig, tts.PollyDefaultOutputFormat)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of engine polly: %w", err)
+	}
+	engine, err := tts.NewPollyEngine(polly.NewFromConfig(awsConfig), engineConfig.VoiceEngine, engineConfig.Stream, output)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of engine polly: %w", err)
+	}
+
+	slog.Info("Polly engine output", slog.String("region", awsConfig.Region), slog.Bool("stream", engineConfig.Stream), slog.String("format", output.Format.String()), slog.Int("sampleRate", output.SampleRate))
+	return engine, nil
+}
+
+// prepareVoicevoxEngine creates the engine calling the VOICEVOX server at the configured URL.
+// VOICEVOX synthesizes WAV only, so the output defaults to LINEAR16.
+func prepareVoicevoxEngine(engineConfig ttsbot.EngineConfig) (tts.Engine, error) {
+	output, err := outputFormat(engineConfig, tts.OutputFormat{Format: tts.AudioFormatLinear16, SampleRate: tts.DefaultOutputFormat.SampleRate})
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of engine voicevox: %w", err)
+	}
+	engine, err := tts.NewVoicevoxEngine(http.DefaultClient, engineConfig.URL, output)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of engine voicevox: %w", err)
+	}
+
+	slog.Info("VOICEVOX engine output", slog.String("url", engineConfig.URL), slog.Int("sampleRate", output.SampleRate))
+	return engine, nil
+}
+
+// prepareOpenAIEngine creates the engine calling the audio/speech API of OpenAI with the configured API key.
+// OpenAI synthesizes speech at 24000 hertz only, so the output defaults to MP3 at that rate.
+func prepareOpenAIEngine(engineConfig ttsbot.EngineConfig) (tts.Engine, error) {
+	output, err := outputFormat(engineConfig, tts.OpenAIDefaultOutputFormat)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of engine openai: %w", err)
+	}
+	engine, err := tts.NewOpenAIEngine(http.DefaultClient, engineConfig.URL, engineConfig.APIKey, engineConfig.Model, engineConfig.Stream, output)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of engine openai: %w", err)
+	}
+
+	slog.Info("OpenAI engine output", slog.String("model", engineConfig.Model), slog.Bool("stream", engineConfig.Stream), slog.String("format", output.Format.String()))
+	return engine, nil
+}
+
+// prepareElevenLabsEngine creates the engine calling the ElevenLabs API with the configured API key.
+func prepareElevenLabsEngine(engineConfig ttsbot.EngineConfig) (tts.Engine, error) {
+	output, err := outputFormat(engineConfig, tts.ElevenLabsDefaultOutputFormat)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of engine elevenlabs: %w", err)
+	}
+	engine, err := tts.NewElevenLabsEngine(http.DefaultClient, engineConfig.URL, engineConfig.APIKey, engineConfig.Model, engineConfig.Stream, output)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of engine elevenlabs: %w", err)
+	}
+
+	slog.Info("ElevenLabs engine output", slog.Bool("stream", engineConfig.Stream), slog.String("format", output.Format.String()), slog.Int("sampleRate", output.SampleRate))
+	return engine, nil
 }
 
-func registerPreset(engineRegistry *tts.EngineRegistry, presetRegistry *preset.PresetRegistry, identifier string, presetConfig ttsbot.PresetConfig) error {
+// registerPreset registers the preset configured under the identifier.
+// Presets that only set a language get the default voice of the engine for it.
+func registerPreset(engineRegistry *tts.EngineRegistry, voiceCatalog *tts.VoiceCatalog, presetRegistry *preset.PresetRegistry, identifier string, presetConfig ttsbot.PresetConfig) error {
 	if presetConfig.Engine == "" {
 		return fmt.Errorf("preset %s does not have an engine specified", identifier)
 	}
 
-	_, ok := engineRegistry.Get(presetConfig.Engine)
+	engine, ok := engineRegistry.Get(presetConfig.Engine)
 	if !ok {
 		return fmt.Errorf("preset %s references unknown engine %s", identifier, presetConfig.Engine)
 	}
 
+	speakingRate, clamped := tts.ClampSpeakingRate(engine, presetConfig.SpeakingRate)
+	if clamped {
+		slog.Warn("Speaking rate of preset is out of the range supported by the engine, clamping it", "preset", identifier, "engine", presetConfig.Engine, "speakingRate", presetConfig.SpeakingRate, "clampedTo", speakingRate)
+	}
+
+	pitch, volumeGainDb := presetConfig.Pitch, presetConfig.VolumeGainDb
+	if (pitch != 0 || volumeGainDb != 0) && !tts.SupportsProsody(engine) {
+		slog.Warn("Engine does not adjust the pitch and volume gain, ignoring them", "preset", identifier, "engine", presetConfig.Engine, "pitch", pitch, "volumeGainDb", volumeGainDb)
+	}
+	if clampedPitch, clamped := tts.ClampPitch(engine, pitch); clamped {
+		slog.Warn("Pitch of preset is out of the range supported by the engine, clamping it", "preset", identifier, "engine", presetConfig.Engine, "pitch", pitch, "clampedTo", clampedPitch)
+		pitch = clampedPitch
+	}
+	if clampedGain, clamped := tts.ClampVolumeGain(engine, volumeGainDb); clamped {
+		slog.Warn("Volume gain of preset is out of the range supported by the engine, clamping it", "preset", identifier, "engine", presetConfig.Engine, "volumeGainDb", volumeGainDb, "clampedTo", clampedGain)
+		volumeGainDb = clampedGain
+	}
+
+	language, voiceName := presetConfig.Language, presetConfig.VoiceName
+	// multi-voice presets speak announcements with their first voice.
+	if voiceName == "" && len(presetConfig.Voices) > 0 {
+		voiceName = presetConfig.Voices[0]
+	}
+	voiceRotation := preset.VoiceRotation(presetConfig.VoiceRotation)
+	if voiceRotation == "" {
+		voiceRotation = preset.VoiceRotationRoundRobin
+	}
+
+	if voiceName == "" && language != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		voice, languageCode, err := voiceCatalog.DefaultVoice(ctx, presetConfig.Engine, language)
+		cancel()
+		switch {
+		case errors.Is(err, tts.ErrVoiceListingUnsupported):
+			// let the engine pick the voice on every request.
+		case err != nil:
+			return fmt.Errorf("failed to pick a voice for preset %s: %w", identifier, err)
+		default:
+			language, voiceName = languageCode, voice.Name
+		}
+	}
+
+	// engines that can not pick a voice would fail every request of the preset.
+	if capabilities, ok := tts.CapabilitiesOf(engine); ok && capabilities.RequiresVoice && voiceName == "" {
+		return fmt.Errorf("preset %s needs a voice_name, as engine %s can not pick a voice for it", identifier, presetConfig.Engine)
+	}
+
+	fallbackVoiceName, err := fallbackVoice(engineRegistry, voiceCatalog, identifier, presetConfig, language)
+	if err != nil {
+		return err
+	}
+
+	var messageSSML, announcementSSML *preset.SSMLTemplate
+	if presetConfig.MessageSSML != "" || presetConfig.AnnouncementSSML != "" {
+		if !tts.SupportsSSML(engine) {
+			return fmt.Errorf("preset %s uses SSML templates, but engine %s does not support SSML", identifier, presetConfig.Engine)
+		}
+		var err error
+		if messageSSML, err = parseSSMLTemplate(presetConfig.MessageSSML); err != nil {
+			return fmt.Errorf("preset %s has an invalid message_ssml: %w", identifier, err)
+		}
+		if announcementSSML, err = parseSSMLTemplate(presetConfig.AnnouncementSSML); err != nil {
+			return fmt.Errorf("preset %s has an invalid announcement_ssml: %w", identifier, err)
+		}
+	}
+
 	preset := preset.Preset{
-		Identifier: preset.PresetID(identifier),
-		Engine:     presetConfig.Engine,
-		Language:   presetConfig.Language,
-		VoiceName:  presetConfig.VoiceName,
+		Identifier:        preset.PresetID(identifier),
+		Engine:            presetConfig.Engine,
+		Language:          language,
+		VoiceName:         voiceName,
+		SpeakingRate:      speakingRate,
+		Pitch:             pitch,
+		VolumeGainDb:      volumeGainDb,
+		FallbackEngine:    presetConfig.FallbackEngine,
+		FallbackVoiceName: fallbackVoiceName,
+		Voices:            presetConfig.Voices,
+		VoiceRotation:     voiceRotation,
+		MessageSSML:       messageSSML,
+		AnnouncementSSML:  announcementSSML,
 	}
 	if err := presetRegistry.Register(preset); err != nil {
 		return err
 	}
 
-	slog.Info("Registered preset", "preset", identifier, "engine", presetConfig.Engine, "language", presetConfig.Language, "voiceName", presetConfig.VoiceName)
+	slog.Info("Registered preset", "preset", identifier, "engine", presetConfig.Engine, "language", language, "voiceName", voiceName, "voices", presetConfig.Voices)
 	return nil
 }
 
-func createSessionRestorationListener(redisClient *redis.Client, engineRegistry *tts.EngineRegistry, presetResolver preset.PresetResolver, sessionManager session.SessionManager, trs *i18n.TextResources, vrs *i18n.VoiceResources) bot.EventListener {
+// fallbackVoice validates the fallback engine of the preset and returns the voice it reads with,
+// picking one for the language if the preset does not name one.
+func fallbackVoice(engineRegistry *tts.EngineRegistry, voiceCatalog *tts.VoiceCatalog, identifier string, presetConfig ttsbot.PresetConfig, language string) (string, error) {
+	if presetConfig.FallbackEngine == "" {
+		return "", nil
+	}
+	if presetConfig.FallbackEngine == presetConfig.Engine {
+		return "", fmt.Errorf("preset %s falls back to its own engine %s", identifier, presetConfig.Engine)
+	}
+	engine, ok := engineRegistry.Get(presetConfig.FallbackEngine)
+	if !ok {
+		return "", fmt.Errorf("preset %s references unknown fallback engine %s", identifier, presetConfig.FallbackEngine)
+	}
+
+	voiceName := presetConfig.FallbackVoiceName
+	if voiceName == "" && language != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		voice, _, err := voiceCatalog.DefaultVoice(ctx, presetConfig.FallbackEngine, language)
+		cancel()
+		switch {
+		case errors.Is(err, tts.ErrVoiceListingUnsupported):
+			// let the engine pick the voice on every request.
+		case err != nil:
+			return "", fmt.Errorf("failed to pick a fallback voice for preset %s: %w", identifier, err)
+		default:
+			voiceName = voice.Name
+		}
+	}
+	if capabilities, ok := tts.CapabilitiesOf(engine); ok && capabilities.RequiresVoice && voiceName == "" {
+		return "", fmt.Errorf("preset %s needs a fallback_voice_name, as engine %s can not pick a voice for it", identifier, presetConfig.FallbackEngine)
+	}
+	if (presetConfig.MessageSSML != "" || presetConfig.AnnouncementSSML != "") && !tts.SupportsSSML(engine) {
+		slog.Warn("Fallback engine does not support SSML, so the SSML of the preset does not fall back", "preset", identifier, "fallbackEngine", presetConfig.FallbackEngine)
+	}
+	return voiceName, nil
+}
+
+// parseSSMLTemplate parses the SSML template of a preset. An empty source returns nil, which reads plain text.
+func parseSSMLTemplate(source string) (*preset.SSMLTemplate, error) {
+	if source == "" {
+		return nil, nil
+	}
+	return preset.ParseSSMLTemplate(source)
+}
+
+// validatePresetVoices checks the language and voice name of the presets against the voices offered by their engines.
+// Engines that can not list their voices are skipped.
+func validatePresetVoices(voiceCatalog *tts.VoiceCatalog, presets []preset.Preset) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var errs []error
+	for _, preset := range presets {
+		voices, err := voiceCatalog.Voices(ctx, preset.Engine)
+		if errors.Is(err, tts.ErrVoiceListingUnsupported) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list voices of engine %s: %w", preset.Engine, err)
+		}
+
+		for _, voiceName := range preset.VoiceNames() {
+			if err := tts.CheckVoice(voices, preset.Language, voiceName); err != nil {
+				errs = append(errs, fmt.Errorf("preset %s: %w", preset.Identifier, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// buildSettingsPolicy applies the operator-wide guild settings config over the default policy.
+func buildSettingsPolicy(guildsConfig ttsbot.GuildsConfig) settings.Policy {
+	policy := settings.DefaultPolicy()
+	defaults := guildsConfig.Defaults
+	if defaults.MaxMessageLength != nil {
+		policy.Defaults.MaxMessageLength = *defaults.MaxMessageLength
+	}
+	if defaults.AnnounceJoin != nil {
+		policy.Defaults.AnnounceJoin = *defaults.AnnounceJoin
+	}
+	if defaults.AnnounceLeave != nil {
+		policy.Defaults.AnnounceLeave = *defaults.AnnounceLeave
+	}
+	if defaults.AnnounceLaunch != nil {
+		policy.Defaults.AnnounceLaunch = *defaults.AnnounceLaunch
+	}
+	if defaults.AnnounceFarewell != nil {
+		policy.Defaults.AnnounceFarewell = *defaults.AnnounceFarewell
+	}
+	if defaults.AnnounceVoiceActivity != nil {
+		policy.Defaults.AnnounceVoiceActivity = *defaults.AnnounceVoiceActivity
+	}
+	if defaults.SelfDeaf != nil {
+		policy.Defaults.SelfDeaf = *defaults.SelfDeaf
+	}
+	if defaults.SelfMute != nil {
+		policy.Defaults.SelfMute = *defaults.SelfMute
+	}
+	if defaults.EphemeralResponses != nil {
+		policy.Defaults.EphemeralResponses = *defaults.EphemeralResponses
+	}
+	policy.Limits = settings.Limits{
+		MaxMessageLength:  guildsConfig.Limits.MaxMessageLength,
+		LockAnnouncements: guildsConfig.Limits.LockAnnouncements,
+	}
+	return policy
+}
+
+// buildEntitlementChecker creates the checker of the premium guilds, which is nil if no guild can be premium.
+// The discord provider is returned too if configured, as it can only look up entitlements once the bot has started.
+func buildEntitlementChecker(entitlementsConfig ttsbot.EntitlementsConfig) (*entitlement.Checker, *entitlement.DiscordProvider) {
+	var (
+		providers           entitlement.Any
+		discordEntitlements *entitlement.DiscordProvider
+	)
+	if len(entitlementsConfig.GuildIDs) > 0 {
+		providers = append(providers, entitlement.NewStatic(entitlementsConfig.GuildIDs...))
+	}
+	if len(entitlementsConfig.SKUIDs) > 0 {
+		discordEntitlements = entitlement.NewDiscordProvider(entitlementsConfig.SKUIDs)
+		providers = append(providers, discordEntitlements)
+	}
+	if len(providers) == 0 {
+		return nil, nil
+	}
+	cacheTTL := entitlementsConfig.CacheTTL
+	if cacheTTL <= 0 {
+		cacheTTL = 10 * time.Minute
+	}
+	return entitlement.NewChecker(providers, cacheTTL), discordEntitlements
+}
+
+// buildRestrictions converts the restrictions config, keyed by guild ID, "premium" or "default", to preset restrictions.
+// The premium restriction applies to the guilds the checker entitles.
+func buildRestrictions(restrictionConfigs map[string]ttsbot.RestrictionConfig, entitlementChecker *entitlement.Checker) (*preset.Restrictions, error) {
+	var (
+		defaultRestriction preset.Restriction
+		premiumRestriction *preset.Restriction
+	)
+	guilds := make(map[snowflake.ID]preset.Restriction, len(restrictionConfigs))
+	for key, restrictionConfig := range restrictionConfigs {
+		for _, pattern := range restrictionConfig.DeniedVoices {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("restriction %s has an invalid voice pattern %q: %w", key, pattern, err)
+			}
+		}
+		restriction := preset.Restriction{
+			DeniedEngines: restrictionConfig.DeniedEngines,
+			DeniedVoices:  restrictionConfig.DeniedVoices,
+		}
+		switch key {
+		case "default":
+			defaultRestriction = restriction
+			continue
+		case "premium":
+			premiumRestriction = &restriction
+			continue
+		}
+		guildID, err := snowflake.Parse(key)
+		if err != nil {
+			return nil, fmt.Errorf("restriction key %s is neither a guild ID, \"premium\" nor \"default\": %w", key, err)
+		}
+		guilds[guildID] = restriction
+	}
+	restrictions := preset.NewRestrictions(defaultRestriction, guilds)
+	if premiumRestriction != nil {
+		restrictions = restrictions.WithPremium(*premiumRestriction, entitlementChecker.Premium)
+	}
+	return restrictions, nil
+}
+
+// createGuildLeaveListener closes the sessions of guilds the bot is removed from,
+// so that they are not restored from persistence either.
+func createGuildLeaveListener(sessionManager session.SessionManager) bot.EventListener {
+	return bot.NewListenerFunc(func(e *events.GuildLeave) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, s := range sessionManager.GetByGuild(e.GuildID) {
+			s.Close(ctx, session.CloseReasonGuildRemoved)
+			sessionManager.Delete(e.GuildID, s.VoiceChannelID())
+		}
+	})
+}
+
+func createSessionRestorationListener(redisClient *redis.Client, engineRegistry *tts.EngineRegistry, presetResolver preset.PresetResolver, sessionManager session.SessionManager, settingsRepository settings.GuildSettingsRepository, voiceDiagnostics *session.VoiceDiagnostics, rejoinGuard *session.RejoinGuard, memberResolver *session.MemberResolver, decoder session.Decoder, frameCache *session.OpusFrameCache, maxQueuedAudio int64, usageTracker *tts.UsageTracker, notifier alert.Notifier, trs *i18n.TextResources, vrs *i18n.VoiceResources) bot.EventListener {
 	return bot.NewListenerFunc(func(r *events.Ready) {
 		slog.Info("Restoring sessions from persistence")
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -301,7 +1066,14 @@ func createSessionRestorationListener(redisClient *redis.Client, engineRegistry
 
 		persistenceManager.StartHeartbeatLoop()
 		sessionManager.AddObserver(persistenceManager)
-		persistenceManager.Restore(ctx, sessionManager, func(guildID, voiceChannelID, readingChannelID snowflake.ID) (*session.Session, error) {
+		err := persistenceManager.Restore(ctx, sessionManager, func(guildID, voiceChannelID, readingChannelID snowflake.ID) (*session.Session, error) {
+			if ok, allowedAt := rejoinGuard.CanAutoJoin(guildID); !ok {
+				if allowedAt.IsZero() {
+					return nil, fmt.Errorf("automatic joins to guild %s are disabled until a manual join", guildID)
+				}
+				return nil, fmt.Errorf("automatic joins to guild %s are backed off until %s", guildID, allowedAt.Format(time.RFC3339))
+			}
+
 			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 			defer cancel()
 			conn := r.Client().VoiceManager().GetConn(guildID)
@@ -309,25 +1081,42 @@ func createSessionRestorationListener(redisClient *redis.Client, engineRegistry
 				conn = r.Client().VoiceManager().CreateConn(guildID)
 			}
 
-			err := conn.Open(ctx, voiceChannelID, false, true)
+			guildSettings, err := settings.FindOrDefault(ctx, settingsRepository, guildID)
+			if err != nil {
+				slog.Warn("Failed to fetch guild settings, restoring with the default voice state", slog.Any("err", err), slog.String("guildID", guildID.String()))
+				guildSettings = settings.DefaultGuildSettings(guildID)
+			}
+
+			err = conn.Open(ctx, voiceChannelID, guildSettings.SelfMute, guildSettings.SelfDeaf)
 			if err != nil {
+				voiceDiagnostics.RecordFailure(guildID, err)
 				slog.Error("Failed to open voice connection", slog.Any("err", err), slog.String("guildID", guildID.String()), slog.String("voiceChannelID", voiceChannelID.String()))
+				notifier.Notify(alert.Alert{Kind: alert.KindRestoreFailed, Message: fmt.Sprintf("Failed to rejoin voice channel %s of guild %s.", voiceChannelID, guildID), Err: err})
 				return nil, err
 			}
+			voiceDiagnostics.RecordSuccess(guildID)
 
 			// we may not use fallback but there is no way to get the text resource from the session currently.
 			// however, it is just fallback, so it does not matter much.
 			tr := trs.GetFallback()
-			session, err := session.New(engineRegistry, presetResolver, readingChannelID, conn, &tr, vrs)
+			session, err := session.New(engineRegistry, presetResolver, settingsRepository, memberResolver, decoder, frameCache, maxQueuedAudio, usageTracker, readingChannelID, conn, r.Client().Rest(), r.Client().Caches(), &tr, vrs)
 			if err != nil {
 				slog.Error("Failed to create session from persistence", slog.Any("err", err), slog.String("readingChannelID", readingChannelID.String()))
+				notifier.Notify(alert.Alert{Kind: alert.KindRestoreFailed, Message: fmt.Sprintf("Failed to restore the session reading channel %s of guild %s.", readingChannelID, guildID), Err: err})
 				return nil, err
 			}
+			if voiceState, ok := r.Client().Caches().VoiceState(guildID, r.Client().ID()); ok {
+				session.SyncVoiceState(voiceState)
+			}
 
 			slog.Info("Restored session from persistence", slog.String("readingChannelID", readingChannelID.String()), slog.String("voiceChannelID", voiceChannelID.String()))
 			return session, nil
 		})
 
+		if err != nil {
+			notifier.Notify(alert.Alert{Kind: alert.KindRedisUnavailable, Message: "Sessions could not be restored from Redis.", Err: err})
+		}
+
 		slog.Info("Persistence manager started", slog.String("applicationID", r.Application.ID.String()), slog.Duration("heartbeatInterval", heartbeatInterval))
 	})
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+const migrationsDir = "migrations"
+
+// runMigrate implements the "migrate" subcommand: ttsbot migrate [-config path] up|down|status
+func runMigrate(args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	path := flags.String("config", "config.toml", "path to config")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ttsbot migrate [-config path] up|down|status")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := ttsbot.LoadConfig(*path)
+	if err != nil {
+		slog.Error("Failed to read config", slog.Any("err", err))
+		return 1
+	}
+	setupLogger(cfg.Log)
+
+	db, err := database.Open(cfg.Database.Driver, cfg.Database.Dsn, databaseOptions(cfg.Database))
+	if err != nil {
+		slog.Error("Failed to connect to database", slog.Any("err", err))
+		return 1
+	}
+	defer db.Close()
+
+	if err := setupGoose(cfg.Database.Driver); err != nil {
+		slog.Error("Failed to set up migrations", slog.Any("err", err))
+		return 1
+	}
+
+	switch command := flags.Arg(0); command {
+	case "up":
+		err = goose.Up(db.DB, migrationsDir)
+	case "down":
+		err = goose.Down(db.DB, migrationsDir)
+	case "status":
+		err = goose.Status(db.DB, migrationsDir)
+	default:
+		flags.Usage()
+		return 2
+	}
+	if err != nil {
+		slog.Error("Migration failed", slog.Any("err", err))
+		return 1
+	}
+	return 0
+}
+
+// setupGoose configures goose to use the embedded migrations with the dialect of the driver.
+func setupGoose(driverName string) error {
+	goose.SetBaseFS(migrationsFS)
+	return goose.SetDialect(gooseDialect(driverName))
+}
+
+// gooseDialect maps a database/sql driver name to the goose dialect name.
+func gooseDialect(driverName string) string {
+	switch driverName {
+	case "sqlite":
+		return "sqlite3"
+	case "pgx":
+		return "postgres"
+	default:
+		return driverName
+	}
+}
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE guild_settings ADD COLUMN announce_voice_activity BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE guild_settings DROP COLUMN announce_voice_activity;
+-- +goose StatementEnd
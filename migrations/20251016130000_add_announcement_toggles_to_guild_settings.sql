@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE guild_settings ADD COLUMN announce_join BOOLEAN NOT NULL DEFAULT TRUE;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE guild_settings ADD COLUMN announce_leave BOOLEAN NOT NULL DEFAULT TRUE;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE guild_settings ADD COLUMN announce_launch BOOLEAN NOT NULL DEFAULT TRUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE guild_settings DROP COLUMN announce_launch;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE guild_settings DROP COLUMN announce_leave;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE guild_settings DROP COLUMN announce_join;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE guild_settings ADD COLUMN max_message_length INTEGER NOT NULL DEFAULT 300;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE guild_settings DROP COLUMN max_message_length;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE guild_settings ADD COLUMN code_block_mode VARCHAR(32) NOT NULL DEFAULT 'announce';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE guild_settings DROP COLUMN code_block_mode;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE guild_settings ADD COLUMN name_source VARCHAR(32) NOT NULL DEFAULT 'nickname';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE guild_settings ADD COLUMN strip_name_decorations BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE guild_settings DROP COLUMN strip_name_decorations;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE guild_settings DROP COLUMN name_source;
+-- +goose StatementEnd
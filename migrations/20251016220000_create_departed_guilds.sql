@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE departed_guilds (
+    guild_id BIGINT NOT NULL,
+    departed_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (guild_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE departed_guilds;
+-- +goose StatementEnd
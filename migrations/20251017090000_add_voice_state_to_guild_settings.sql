@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE guild_settings ADD COLUMN self_deaf BOOLEAN NOT NULL DEFAULT TRUE;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE guild_settings ADD COLUMN self_mute BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE guild_settings DROP COLUMN self_mute;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE guild_settings DROP COLUMN self_deaf;
+-- +goose StatementEnd
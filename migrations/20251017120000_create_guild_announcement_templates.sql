@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE guild_announcement_templates (
+    guild_id BIGINT NOT NULL,
+    announcement_key VARCHAR(32) NOT NULL,
+    template VARCHAR(1024) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (guild_id, announcement_key)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE guild_announcement_templates;
+-- +goose StatementEnd
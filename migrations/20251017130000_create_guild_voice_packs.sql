@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE guild_voice_packs (
+    guild_id BIGINT NOT NULL,
+    pack_id VARCHAR(64) NOT NULL,
+    position INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (guild_id, pack_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE guild_voice_packs;
+-- +goose StatementEnd
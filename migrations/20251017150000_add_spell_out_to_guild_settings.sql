@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE guild_settings ADD COLUMN spell_out BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE guild_spell_out_patterns (
+    guild_id BIGINT NOT NULL,
+    pattern VARCHAR(400) NOT NULL,
+    position INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (guild_id, position)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE guild_spell_out_patterns;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE guild_settings DROP COLUMN spell_out;
+-- +goose StatementEnd
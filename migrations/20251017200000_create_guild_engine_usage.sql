@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE guild_engine_usage (
+    guild_id BIGINT NOT NULL,
+    engine VARCHAR(64) NOT NULL,
+    period VARCHAR(7) NOT NULL,
+    characters BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (guild_id, engine, period)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE guild_engine_usage;
+-- +goose StatementEnd
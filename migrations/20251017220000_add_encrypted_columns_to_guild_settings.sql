@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE guild_settings ADD COLUMN webhook_url_encrypted VARCHAR(1024) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE guild_settings ADD COLUMN transcript_channel_id_encrypted VARCHAR(128) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE guild_settings DROP COLUMN transcript_channel_id_encrypted;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE guild_settings DROP COLUMN webhook_url_encrypted;
+-- +goose StatementEnd
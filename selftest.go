@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/disgoorg/disgo/rest"
+	"github.com/pressly/goose/v3"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+// errSkipped marks a check that does not apply to the configuration, e.g. Redis when it is disabled.
+var errSkipped = errors.New("skipped")
+
+// selftestCheck is a single line of the self-test. run returns a short detail on success,
+// errSkipped (wrapped with the reason) if the check does not apply, or the error it failed with.
+type selftestCheck struct {
+	name string
+	run  func(ctx context.Context) (string, error)
+}
+
+// runSelftest implements the "selftest" subcommand: ttsbot selftest [-config path] [-timeout 30s]
+// It checks everything the bot depends on and prints a table of the results.
+// The exit code is 1 if any check failed, so that it can gate deployments.
+func runSelftest(args []string) int {
+	flags := flag.NewFlagSet("selftest", flag.ExitOnError)
+	path := flags.String("config", "config.toml", "path to config")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of each check")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ttsbot selftest [-config path] [-timeout 30s]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	cfg, err := ttsbot.LoadConfig(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read config: %v\n", err)
+		return 1
+	}
+	// only the table is printed; the checks log at warn and above.
+	setupLogger(ttsbot.LogConfig{Level: slog.LevelWarn, Format: "text"})
+
+	checks := []selftestCheck{
+		{name: "locales", run: checkLocales},
+		{name: "database", run: func(ctx context.Context) (string, error) { return checkDatabase(ctx, cfg.Database) }},
+		{name: "redis", run: func(ctx context.Context) (string, error) { return checkRedis(ctx, cfg.Redis) }},
+		{name: "discord token", run: func(ctx context.Context) (string, error) { return checkDiscordToken(ctx, cfg.Bot.Token) }},
+	}
+	checks = append(checks, engineChecks(cfg)...)
+
+	if failed := printSelftest(os.Stdout, checks, *timeout); failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printSelftest runs the checks, prints their results as a table and returns the number of failed checks.
+func printSelftest(w io.Writer, checks []selftestCheck, timeout time.Duration) int {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tRESULT\tDETAIL")
+	failed := 0
+	for _, check := range checks {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		detail, err := check.run(ctx)
+		cancel()
+
+		result := "PASS"
+		switch {
+		case errors.Is(err, errSkipped):
+			result, detail = "SKIP", err.Error()
+		case err != nil:
+			result, detail = "FAIL", err.Error()
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", check.name, result, strings.ReplaceAll(detail, "\n", " "))
+	}
+	tw.Flush()
+	return failed
+}
+
+func checkLocales(ctx context.Context) (string, error) {
+	trs, err := i18n.LoadTextResources("./locales/text/", "en-US")
+	if err != nil {
+		return "", err
+	}
+	vrs, err := i18n.LoadVoiceResources("./locales/voice/")
+	if err != nil {
+		return "", err
+	}
+	if err := vrs.LoadPacks("./locales/packs/"); err != nil {
+		return "", err
+	}
+	if errs := append(trs.Verify(), vrs.Verify()...); len(errs) > 0 {
+		return "", fmt.Errorf("%d missing or mistranslated entries, e.g. %w", len(errs), errs[0])
+	}
+	return "text and voice resources are complete", nil
+}
+
+func checkDatabase(ctx context.Context, cfg ttsbot.DatabaseConfig) (string, error) {
+	if cfg.Driver == "none" {
+		return "", fmt.Errorf("%w: running without a database", errSkipped)
+	}
+	db, err := database.Open(cfg.Driver, cfg.Dsn, databaseOptions(cfg))
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	if err := setupGoose(cfg.Driver); err != nil {
+		return "", err
+	}
+
+	if ExpectedMigrationVersion == "" {
+		version, err := goose.GetDBVersionContext(ctx, db.DB)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("schema version %d (no expected version built in)", version), nil
+	}
+	if err := checkDBVersion(ctx, db); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("schema version %s", ExpectedMigrationVersion), nil
+}
+
+func checkRedis(ctx context.Context, cfg ttsbot.RedisConfig) (string, error) {
+	if !cfg.Enabled {
+		return "", fmt.Errorf("%w: redis is disabled", errSkipped)
+	}
+	option, err := redis.ParseURL(cfg.Url)
+	if err != nil {
+		return "", err
+	}
+	client := redis.NewClient(option)
+	defer client.Close()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return "", err
+	}
+	return "ping succeeded", nil
+}
+
+func checkDiscordToken(ctx context.Context, token string) (string, error) {
+	client := rest.New(rest.NewClient(token))
+	defer client.Close(ctx)
+	application, err := client.GetBotApplicationInfo(rest.WithCtx(ctx))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("application %s (%s)", application.Name, application.ID), nil
+}
+
+// engineChecks returns a check synthesizing a short phrase with every engine, using the first preset of the engine.
+// The speech is also decoded, so that the decoder of the deployment is checked with it.
+func engineChecks(cfg *ttsbot.Config) []selftestCheck {
+	registry := tts.NewEngineRegistry()
+	if err := registerDefaultEngines(registry, cfg.Engines); err != nil {
+		return []selftestCheck{{name: "engines", run: func(ctx context.Context) (string, error) { return "", err }}}
+	}
+	decoder, err := buildDecoder(cfg.Audio)
+	if err != nil {
+		return []selftestCheck{{name: "decoder", run: func(ctx context.Context) (string, error) { return "", err }}}
+	}
+
+	var checks []selftestCheck
+	for _, identifier := range registry.Identifiers() {
+		engine := registry.MustGet(identifier)
+		checks = append(checks, selftestCheck{
+			name: "engine " + identifier,
+			run: func(ctx context.Context) (string, error) {
+				presetID, presetConfig, ok := firstPresetOf(cfg.Presets, identifier)
+				if !ok {
+					return "", fmt.Errorf("%w: no preset uses the engine", errSkipped)
+				}
+				voiceName := presetConfig.VoiceName
+				if voiceName == "" && len(presetConfig.Voices) > 0 {
+					voiceName = presetConfig.Voices[0]
+				}
+
+				start := time.Now()
+				resp, err := engine.GenerateSpeech(ctx, tts.SpeechRequest{
+					Text:         "test",
+					LanguageCode: presetConfig.Language,
+					VoiceName:    voiceName,
+					SpeakingRate: presetConfig.SpeakingRate,
+					Pitch:        presetConfig.Pitch,
+					VolumeGainDb: presetConfig.VolumeGainDb,
+				})
+				if err != nil {
+					return "", fmt.Errorf("preset %s: %w", presetID, err)
+				}
+				elapsed := time.Since(start)
+
+				provider, err := decoder.Decode(resp)
+				if err != nil {
+					return "", fmt.Errorf("preset %s: failed to decode %s: %w", presetID, resp.Format, err)
+				}
+				defer provider.Close()
+				// the audio is decoded as it is read, so it is played through to tell whether it decodes.
+				var frames int
+				for {
+					_, err := provider.ProvidePCMFrame()
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					if err != nil {
+						return "", fmt.Errorf("preset %s: failed to decode %s: %w", presetID, resp.Format, err)
+					}
+					frames++
+				}
+				return fmt.Sprintf("preset %s: %s of %s in %s", presetID, time.Duration(frames)*20*time.Millisecond, resp.Format, elapsed.Round(time.Millisecond)), nil
+			},
+		})
+	}
+	return checks
+}
+
+// firstPresetOf returns the preset of the engine with the lowest identifier.
+func firstPresetOf(presets map[string]ttsbot.PresetConfig, engine string) (string, ttsbot.PresetConfig, bool) {
+	for _, identifier := range slices.Sorted(maps.Keys(presets)) {
+		if presets[identifier].Engine == engine {
+			return identifier, presets[identifier], true
+		}
+	}
+	return "", ttsbot.PresetConfig{}, false
+}
@@ -0,0 +1,163 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+// Kind identifies what went wrong. Alerts of the same kind and message are not repeated within the interval of the notifier.
+type Kind string
+
+const (
+	// KindEngineUnavailable means a speech engine failed repeatedly and requests to it are rejected for a while.
+	KindEngineUnavailable Kind = "engine_unavailable"
+	KindRedisUnavailable  Kind = "redis_unavailable"
+	// KindMigrationMismatch means the database schema no longer matches the version the bot was built for,
+	// e.g. because another instance migrated it.
+	KindMigrationMismatch Kind = "migration_mismatch"
+	KindRestoreFailed     Kind = "restore_failed"
+)
+
+// Alert is a problem operators should look into.
+type Alert struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+// Notifier tells operators about alerts. Notify never blocks.
+type Notifier interface {
+	Notify(alert Alert)
+}
+
+// LogNotifier only logs alerts. It is used when no operator channel is configured.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(alert Alert) {
+	slog.Error("Operator alert", slog.String("kind", string(alert.Kind)), slog.String("message", alert.Message), slog.Any("err", alert.Err))
+}
+
+var _ Notifier = (*ChannelNotifier)(nil)
+
+// ChannelNotifier posts alerts to a discord channel of the operators, besides logging them.
+// Alerts raised before Start are posted once the bot has started.
+type ChannelNotifier struct {
+	channelID snowflake.ID
+	interval  time.Duration
+	queue     chan Alert
+
+	mu sync.Mutex
+	// lastSent is when an alert of the kind and message was last posted.
+	lastSent map[string]time.Time
+	clock    clock.Clock
+}
+
+// NewChannelNotifier creates a notifier posting to the channel, repeating the same alert at most once per interval.
+func NewChannelNotifier(channelID snowflake.ID, interval time.Duration) *ChannelNotifier {
+	return &ChannelNotifier{
+		channelID: channelID,
+		interval:  interval,
+		queue:     make(chan Alert, 32),
+		lastSent:  make(map[string]time.Time),
+		clock:     clock.Real,
+	}
+}
+
+func (n *ChannelNotifier) Notify(alert Alert) {
+	LogNotifier{}.Notify(alert)
+	if !n.due(alert) {
+		return
+	}
+	select {
+	case n.queue <- alert:
+	default:
+		slog.Warn("Operator alert queue is full, dropping alert", slog.String("kind", string(alert.Kind)))
+	}
+}
+
+// due reports whether the alert should be posted, and records it as posted if so.
+func (n *ChannelNotifier) due(alert Alert) bool {
+	key := string(alert.Kind) + "\x00" + alert.Message
+	now := n.clock.Now()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if last, ok := n.lastSent[key]; ok && now.Sub(last) < n.interval {
+		return false
+	}
+	n.lastSent[key] = now
+	return true
+}
+
+// Start posts queued alerts with the client until the context is done.
+func (n *ChannelNotifier) Start(ctx context.Context, channels rest.Channels) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case alert := <-n.queue:
+				sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+				if _, err := channels.CreateMessage(n.channelID, buildAlertMessage(alert), rest.WithCtx(sendCtx)); err != nil {
+					slog.Error("Failed to post operator alert", slog.Any("err", err), slog.String("kind", string(alert.Kind)))
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+// colorAlert is the red of alert embeds.
+const colorAlert = 0xed4245
+
+func buildAlertMessage(alert Alert) discord.MessageCreate {
+	embed := discord.NewEmbedBuilder().
+		SetTitle(fmt.Sprintf("🚨 %s", alert.Kind)).
+		SetDescription(alert.Message).
+		SetColor(colorAlert).
+		SetTimestamp(time.Now())
+	if alert.Err != nil {
+		embed.AddField("Error", fmt.Sprintf("```\n%s\n```", truncate(alert.Err.Error(), 1000)), false)
+	}
+	return discord.NewMessageCreateBuilder().
+		AddEmbeds(embed.Build()).
+		SetAllowedMentions(&discord.AllowedMentions{}).
+		Build()
+}
+
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit]) + "…"
+}
+
+// StartCheckLoop runs the check every interval, and raises an alert of the kind whenever it starts failing.
+// The recovery is logged, so that it is clear from the logs when the problem ended.
+func StartCheckLoop(notifier Notifier, kind Kind, message string, interval time.Duration, check func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		failing := false
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), interval/2)
+			err := check(ctx)
+			cancel()
+			switch {
+			case err != nil && !failing:
+				notifier.Notify(Alert{Kind: kind, Message: message, Err: err})
+			case err == nil && failing:
+				slog.Info("Operator alert resolved", slog.String("kind", string(kind)))
+			}
+			failing = err != nil
+		}
+	}()
+}
@@ -0,0 +1,36 @@
+package alert
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+func TestChannelNotifierRepeats(t *testing.T) {
+	notifier := NewChannelNotifier(1, 10*time.Minute)
+	clk := clock.NewFake(time.Unix(0, 0))
+	notifier.clock = clk
+
+	redis := Alert{Kind: KindRedisUnavailable, Message: "Redis is unreachable", Err: errors.New("dial tcp: connection refused")}
+	notifier.Notify(redis)
+	notifier.Notify(redis)
+	require.Len(t, notifier.queue, 1, "the same alert is posted once per interval")
+
+	notifier.Notify(Alert{Kind: KindRestoreFailed, Message: "Failed to restore"})
+	require.Len(t, notifier.queue, 2, "other alerts are posted right away")
+
+	clk.Advance(10 * time.Minute)
+	notifier.Notify(redis)
+	require.Len(t, notifier.queue, 3)
+}
+
+func TestBuildAlertMessage(t *testing.T) {
+	message := buildAlertMessage(Alert{Kind: KindEngineUnavailable, Message: "Requests to google are paused", Err: errors.New("deadline exceeded")})
+	require.Len(t, message.Embeds, 1)
+	require.Equal(t, "🚨 engine_unavailable", message.Embeds[0].Title)
+	require.Contains(t, message.Embeds[0].Fields[0].Value, "deadline exceeded")
+}
@@ -39,7 +39,7 @@ func (b *Bot) SetupBot(listeners ...bot.EventListener) error {
 			gateway.IntentMessageContent,
 			gateway.IntentGuildVoiceStates,
 		)),
-		bot.WithCacheConfigOpts(cache.WithCaches(cache.FlagGuilds, cache.FlagVoiceStates)),
+		bot.WithCacheConfigOpts(cache.WithCaches(cache.FlagGuilds, cache.FlagChannels, cache.FlagRoles, cache.FlagMembers, cache.FlagVoiceStates)),
 		bot.WithEventListeners(b.Paginator),
 		bot.WithEventListeners(listeners...),
 	)
@@ -0,0 +1,57 @@
+// Package clock abstracts the time, so that time-based behavior like cooldowns, heartbeats and backoffs
+// can be tested deterministically with a Fake clock.
+package clock
+
+import "time"
+
+// Clock tells the time and schedules work after a duration.
+type Clock interface {
+	Now() time.Time
+	// After returns a channel receiving the time once the duration has passed.
+	After(d time.Duration) <-chan time.Time
+	// AfterFunc calls f in its own goroutine once the duration has passed.
+	AfterFunc(d time.Duration, f func()) Timer
+	// NewTicker returns a ticker sending the time every period. Ticks are dropped for slow receivers.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is scheduled work, which can be cancelled.
+type Timer interface {
+	// Stop cancels the work and reports whether it was cancelled before running.
+	Stop() bool
+}
+
+// Ticker sends the time periodically until stopped.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Real is the clock of the system.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
@@ -0,0 +1,151 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+var _ Clock = (*Fake)(nil)
+
+// Fake is a clock that only moves when told to, for tests.
+// Timers and tickers fire in order of their deadlines while the clock is advanced past them,
+// and functions of AfterFunc are called synchronously by Advance, so that a test sees their effects right after it.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	// period is the interval of a ticker, and zero for timers.
+	period time.Duration
+	ch     chan time.Time
+	f      func()
+}
+
+// NewFake returns a fake clock showing the time.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (c *Fake) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Fake) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.schedule(&fakeWaiter{at: c.Now().Add(d), ch: ch})
+	return ch
+}
+
+func (c *Fake) AfterFunc(d time.Duration, f func()) Timer {
+	w := &fakeWaiter{at: c.Now().Add(d), f: f}
+	c.schedule(w)
+	return fakeTimer{clock: c, waiter: w}
+}
+
+func (c *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	w := &fakeWaiter{at: c.Now().Add(d), period: d, ch: make(chan time.Time, 1)}
+	c.schedule(w)
+	return fakeTicker{clock: c, waiter: w}
+}
+
+// Advance moves the clock forward by the duration, firing every timer and ticker due on the way.
+func (c *Fake) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	for {
+		w := c.next(target)
+		if w == nil {
+			break
+		}
+		c.now = w.at
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			c.remove(w)
+		}
+
+		if w.f != nil {
+			c.mu.Unlock()
+			w.f()
+			c.mu.Lock()
+			continue
+		}
+		select {
+		case w.ch <- c.now:
+		default:
+			// like time.Ticker, ticks are dropped while the receiver is behind.
+		}
+	}
+	c.now = target
+	c.mu.Unlock()
+}
+
+// Waiters returns the number of timers and tickers waiting to fire.
+// Tests use it to wait until a goroutine has scheduled its work before advancing the clock.
+func (c *Fake) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+func (c *Fake) schedule(w *fakeWaiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waiters = append(c.waiters, w)
+}
+
+// next returns the waiter due first, if it is due by the target time. Waiters due at the same time fire in the order they were scheduled.
+func (c *Fake) next(target time.Time) *fakeWaiter {
+	var next *fakeWaiter
+	for _, w := range c.waiters {
+		if !w.at.After(target) && (next == nil || w.at.Before(next.at)) {
+			next = w
+		}
+	}
+	return next
+}
+
+// remove unschedules the waiter and reports whether it was scheduled.
+func (c *Fake) remove(w *fakeWaiter) bool {
+	for i, scheduled := range c.waiters {
+		if scheduled == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock  *Fake
+	waiter *fakeWaiter
+}
+
+func (t fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.remove(t.waiter)
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	waiter *fakeWaiter
+}
+
+func (t fakeTicker) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.clock.remove(t.waiter)
+}
@@ -0,0 +1,53 @@
+package clock
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFakeAdvance(t *testing.T) {
+	start := time.Date(2025, 10, 17, 12, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	var fired []string
+	c.AfterFunc(2*time.Second, func() { fired = append(fired, "timer") })
+	stopped := c.AfterFunc(time.Second, func() { fired = append(fired, "stopped") })
+	after := c.After(3 * time.Second)
+	ticker := c.NewTicker(time.Second)
+
+	if !stopped.Stop() {
+		t.Error("Stop of a pending timer must report true")
+	}
+
+	c.Advance(1500 * time.Millisecond)
+	if len(fired) != 0 {
+		t.Errorf("nothing should have fired yet, got %v", fired)
+	}
+	select {
+	case tick := <-ticker.C():
+		if want := start.Add(time.Second); !tick.Equal(want) {
+			t.Errorf("tick = %v, want %v", tick, want)
+		}
+	default:
+		t.Error("the ticker did not tick")
+	}
+
+	c.Advance(2 * time.Second)
+	if !slices.Equal(fired, []string{"timer"}) {
+		t.Errorf("fired = %v, want [timer]", fired)
+	}
+	select {
+	case <-after:
+	default:
+		t.Error("After did not fire")
+	}
+	if got, want := c.Now(), start.Add(3500*time.Millisecond); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+
+	ticker.Stop()
+	if c.Waiters() != 0 {
+		t.Errorf("Waiters() = %d, want 0", c.Waiters())
+	}
+}
@@ -0,0 +1,299 @@
+package commands
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/json"
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+func adminCmd(trs *i18n.TextResources) discord.SlashCommandCreate {
+	return discord.SlashCommandCreate{
+		Name:        "admin",
+		Description: "Manage the bot (bot owners only)",
+		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+			return tr.Commands.Admin.Description
+		}),
+		DefaultMemberPermissions: json.NewNullablePtr(discord.PermissionAdministrator),
+		Contexts:                 []discord.InteractionContextType{discord.InteractionContextTypeGuild},
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "sync",
+				Description: "Sync the commands of the bot with discord",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Admin.Sync.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionString{
+						Name:        "scope",
+						Description: "Where to sync the commands",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Admin.Sync.Scope
+						}),
+						Required: true,
+						Choices: []discord.ApplicationCommandOptionChoiceString{
+							{Name: "guild", Value: "guild"},
+							{Name: "global", Value: "global"},
+						},
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "usage",
+				Description: "Show the characters every server had read in a month, with their estimated cost",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Admin.Usage.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionString{
+						Name:        "month",
+						Description: "The month to show, e.g. 2025-10. Defaults to this month",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Admin.Usage.Month
+						}),
+						MinLength: json.Ptr(7),
+						MaxLength: json.Ptr(7),
+					},
+				},
+			},
+		},
+	}
+}
+
+// adminUsageGuilds is the most servers listed by /admin usage, those costing the most first.
+const adminUsageGuilds = 15
+
+// Owners resolves the owners of the application, who are allowed to run /admin.
+// The owners are fetched from discord once and cached.
+type Owners struct {
+	mu      sync.Mutex
+	ids     []snowflake.ID
+	fetched bool
+}
+
+func NewOwners() *Owners {
+	return &Owners{}
+}
+
+// IsOwner reports whether the user owns the application, or is a member of the team owning it.
+func (o *Owners) IsOwner(ctx context.Context, client rest.OAuth2, userID snowflake.ID) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.fetched {
+		application, err := client.GetBotApplicationInfo(rest.WithCtx(ctx))
+		if err != nil {
+			return false, fmt.Errorf("failed to fetch application info: %w", err)
+		}
+		o.ids = applicationOwners(application)
+		o.fetched = true
+	}
+	return slices.Contains(o.ids, userID), nil
+}
+
+func applicationOwners(application *discord.Application) []snowflake.ID {
+	var ids []snowflake.ID
+	if application.Owner != nil {
+		ids = append(ids, application.Owner.ID)
+	}
+	if application.Team != nil {
+		ids = append(ids, application.Team.OwnerID)
+		for _, member := range application.Team.Members {
+			if member.MembershipState == discord.MembershipStateAccepted {
+				ids = append(ids, member.User.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func AdminHandler(owners *Owners, syncer *CommandSyncer, usageTracker *tts.UsageTracker, pricing tts.Pricing) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		tr := localized(e.Ctx)
+		if ok := checkOwner(e.Ctx, owners, e.Client().Rest(), e.User().ID); !ok {
+			return e.CreateMessage(adminMessage(tr.Commands.Admin.ErrorNotOwner))
+		}
+
+		data := e.SlashCommandInteractionData()
+		if err := e.DeferCreateMessage(true); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if data.SubCommandName != nil && *data.SubCommandName == "usage" {
+			month := data.String("month")
+			if _, err := time.Parse("2006-01", month); month != "" && err != nil {
+				_, err = e.UpdateInteractionResponse(adminMessageUpdate(tr.Commands.Admin.Usage.ErrorInvalidMonth))
+				return err
+			}
+			report, err := usageTracker.Report(ctx, month)
+			if err != nil {
+				slog.ErrorContext(e.Ctx, "Failed to report usage", slog.Any("err", err))
+				_, err = e.UpdateInteractionResponse(adminMessageUpdate(fmt.Sprintf(tr.Commands.Admin.Usage.ErrorLoad, err)))
+				return err
+			}
+			_, err = e.UpdateInteractionResponse(adminMessageUpdate(usageReport(tr, report, pricing, func(guildID snowflake.ID) string {
+				if guild, ok := e.Client().Caches().Guild(guildID); ok {
+					return guild.Name
+				}
+				return guildID.String()
+			})))
+			return err
+		}
+
+		switch data.String("scope") {
+		case "guild":
+			guildID := *e.GuildID()
+			diff, err := syncer.Sync(ctx, e.Client(), guildID, false)
+			if err != nil {
+				slog.ErrorContext(e.Ctx, "Failed to sync guild commands", slog.Any("err", err), slog.String("guildID", guildID.String()))
+				_, err = e.UpdateInteractionResponse(adminMessageUpdate(fmt.Sprintf(tr.Commands.Admin.Sync.ErrorGuild, err)))
+				return err
+			}
+			slog.InfoContext(e.Ctx, "Synced guild commands", slog.String("guildID", guildID.String()), slog.String("diff", diff.String()))
+			_, err = e.UpdateInteractionResponse(adminMessageUpdate(fmt.Sprintf(tr.Commands.Admin.Sync.Guild, diff)))
+			return err
+		case "global":
+			// global commands are shown in every guild, so the changes are confirmed before they are made.
+			diff, err := syncer.Diff(ctx, e.Client(), 0)
+			if err != nil {
+				slog.ErrorContext(e.Ctx, "Failed to compare global commands", slog.Any("err", err))
+				_, err = e.UpdateInteractionResponse(adminMessageUpdate(fmt.Sprintf(tr.Commands.Admin.Sync.ErrorFetchGlobal, err)))
+				return err
+			}
+			if diff.IsEmpty() {
+				_, err = e.UpdateInteractionResponse(adminMessageUpdate(tr.Commands.Admin.Sync.UpToDate))
+				return err
+			}
+			_, err = e.UpdateInteractionResponse(discord.NewMessageUpdateBuilder().
+				SetContent(fmt.Sprintf(tr.Commands.Admin.Sync.ConfirmGlobal, diff)).
+				AddActionRow(
+					discord.NewDangerButton(tr.Commands.Admin.Sync.ConfirmButton, "/admin/sync/global/"+e.User().ID.String()),
+				).
+				Build())
+			return err
+		}
+		return nil
+	}
+}
+
+// AdminSyncGlobalHandler handles the confirmation of a global command sync.
+func AdminSyncGlobalHandler(owners *Owners, syncer *CommandSyncer) handler.ComponentHandler {
+	return func(e *handler.ComponentEvent) error {
+		tr := localized(e.Ctx)
+		if e.Vars["userID"] != e.User().ID.String() || !checkOwner(e.Ctx, owners, e.Client().Rest(), e.User().ID) {
+			return e.CreateMessage(adminMessage(tr.Commands.Admin.Sync.ErrorNotConfirmer))
+		}
+
+		if err := e.DeferUpdateMessage(); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		var content string
+		diff, err := syncer.Sync(ctx, e.Client(), 0, false)
+		if err != nil {
+			slog.ErrorContext(e.Ctx, "Failed to sync global commands", slog.Any("err", err))
+			content = fmt.Sprintf(tr.Commands.Admin.Sync.ErrorGlobal, err)
+		} else {
+			slog.InfoContext(e.Ctx, "Synced global commands", slog.String("userID", e.User().ID.String()), slog.String("diff", diff.String()))
+			content = fmt.Sprintf(tr.Commands.Admin.Sync.Global, diff)
+		}
+
+		_, err = e.UpdateInteractionResponse(discord.NewMessageUpdateBuilder().
+			SetContent(content).
+			ClearContainerComponents().
+			Build())
+		return err
+	}
+}
+
+// usageReport lists the servers that had the most read in the report, with the estimated cost of what they had read.
+// Servers are sorted by cost, and by characters for engines without a price.
+func usageReport(tr i18n.TextResource, report map[snowflake.ID]tts.Usage, pricing tts.Pricing, guildName func(snowflake.ID) string) string {
+	if len(report) == 0 {
+		return tr.Commands.Admin.Usage.Empty
+	}
+
+	type guildUsage struct {
+		guildID    snowflake.ID
+		usage      tts.Usage
+		characters int64
+		cost       float64
+	}
+	var (
+		guilds     []guildUsage
+		characters int64
+		cost       float64
+		period     string
+	)
+	for guildID, usage := range report {
+		guild := guildUsage{guildID: guildID, usage: usage, characters: usage.Total(), cost: pricing.Estimate(usage.Engines)}
+		guilds = append(guilds, guild)
+		characters += guild.characters
+		cost += guild.cost
+		period = usage.Period
+	}
+	slices.SortFunc(guilds, func(a, b guildUsage) int {
+		return cmp.Or(cmp.Compare(b.cost, a.cost), cmp.Compare(b.characters, a.characters), cmp.Compare(a.guildID, b.guildID))
+	})
+
+	lines := []string{fmt.Sprintf(tr.Commands.Admin.Usage.Summary, period, characters, len(guilds), fmt.Sprintf("%.2f", cost))}
+	for i, guild := range guilds[:min(len(guilds), adminUsageGuilds)] {
+		engines := make([]string, 0, len(guild.usage.Engines))
+		for _, engine := range slices.Sorted(maps.Keys(guild.usage.Engines)) {
+			engines = append(engines, fmt.Sprintf("%s %d", engine, guild.usage.Engines[engine]))
+		}
+		line := fmt.Sprintf(tr.Commands.Admin.Usage.Guild, i+1, guildName(guild.guildID), guild.characters, strings.Join(engines, ", "), fmt.Sprintf("%.2f", guild.cost))
+		if guild.usage.Budget > 0 {
+			line += fmt.Sprintf(tr.Commands.Admin.Usage.GuildBudget, guild.characters*100/guild.usage.Budget)
+		}
+		lines = append(lines, line)
+	}
+	if len(guilds) > adminUsageGuilds {
+		lines = append(lines, fmt.Sprintf(tr.Commands.Admin.Usage.More, len(guilds)-adminUsageGuilds))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func checkOwner(ctx context.Context, owners *Owners, client rest.OAuth2, userID snowflake.ID) bool {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	ok, err := owners.IsOwner(ctx, client, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to check the owners of the bot", slog.Any("err", err))
+		return false
+	}
+	return ok
+}
+
+func adminMessage(content string) discord.MessageCreate {
+	return discord.NewMessageCreateBuilder().
+		SetContent(content).
+		SetEphemeral(true).
+		Build()
+}
+
+func adminMessageUpdate(content string) discord.MessageUpdate {
+	return discord.NewMessageUpdateBuilder().
+		SetContent(content).
+		Build()
+}
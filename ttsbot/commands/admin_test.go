@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+func TestUsageReport(t *testing.T) {
+	trs, err := i18n.LoadTextResources("../../locales/text/", "en-US")
+	require.NoError(t, err)
+	tr := trs.GetFallback()
+	report := map[snowflake.ID]tts.Usage{
+		1: {Period: "2025-10", Engines: map[string]int64{"voicevox": 3_000_000}},
+		2: {Period: "2025-10", Engines: map[string]int64{"google": 500_000, "voicevox": 100}, Budget: 1_000_000},
+	}
+	pricing := tts.Pricing{"google": 16}
+
+	// servers are listed by cost, so the free engine comes last despite reading more.
+	require.Equal(t, "Usage in 2025-10: 3500100 characters in 2 servers, estimated cost 8.00\n"+
+		"1. Guild 2: 500100 characters (google 500000, voicevox 100), 8.00, 50% of its budget\n"+
+		"2. Guild 1: 3000000 characters (voicevox 3000000), 0.00",
+		usageReport(tr, report, pricing, func(guildID snowflake.ID) string {
+			return "Guild " + guildID.String()
+		}))
+
+	require.Equal(t, "Nothing was read in this month.", usageReport(tr, nil, pricing, nil))
+}
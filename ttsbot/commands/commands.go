@@ -10,6 +10,14 @@ func Commands(trs *i18n.TextResources) []discord.ApplicationCommandCreate {
 		joinCmd(trs),
 		leaveCmd(trs),
 		presetCmd(trs),
+		settingsCmd(trs),
+		setupCmd(trs),
+		mydataCmd(trs),
 		versionCmd(trs),
+		debugCmd(trs),
+		adminCmd(trs),
+		previewCmd(trs),
+		voicesCmd(trs),
+		usageCmd(trs),
 	}
 }
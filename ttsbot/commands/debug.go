@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+	"github.com/disgoorg/json"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/session"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+func debugCmd(trs *i18n.TextResources) discord.SlashCommandCreate {
+	return discord.SlashCommandCreate{
+		Name:        "debug",
+		Description: "Show diagnostic information for this server",
+		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+			return tr.Commands.Debug.Description
+		}),
+		DefaultMemberPermissions: json.NewNullablePtr(discord.PermissionManageGuild),
+		Contexts:                 []discord.InteractionContextType{discord.InteractionContextTypeGuild},
+	}
+}
+
+func DebugHandler(manager session.SessionManager, diagnostics *session.VoiceDiagnostics, latency *tts.LatencyRecorder, timings *CommandTimings) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		tr := localized(e.Ctx)
+		guildID := *e.GuildID()
+
+		embed := discord.NewEmbedBuilder().
+			SetTitle(tr.Commands.Debug.Title).
+			AddField(tr.Commands.Debug.Sessions, strconv.Itoa(len(manager.GetByGuild(guildID))), true)
+
+		for _, s := range manager.GetByGuild(guildID) {
+			region := tr.Commands.Debug.RegionAutomatic
+			if channel, ok := e.Client().Caches().GuildAudioChannel(s.VoiceChannelID()); ok && channel.RTCRegion() != "" {
+				region = channel.RTCRegion()
+			}
+			value := fmt.Sprintf(tr.Commands.Debug.SessionValue,
+				discord.ChannelMention(s.TextChannelID()),
+				discord.ChannelMention(s.VoiceChannelID()),
+				region,
+			)
+			if health, ok := s.PlaybackHealth(); ok {
+				value += "\n" + playbackValue(health)
+			}
+			embed.AddField(tr.Commands.Debug.Session, value, false)
+		}
+
+		if diagnostic, ok := diagnostics.Get(guildID); ok {
+			embed.AddField(tr.Commands.Debug.VoiceEndpoint, orNone(diagnostic.Endpoint), true).
+				AddField(tr.Commands.Debug.VoiceRegion, orNone(diagnostic.Region()), true).
+				AddField(tr.Commands.Debug.ConsecutiveFailures, strconv.Itoa(diagnostic.ConsecutiveFailures), true)
+			if !diagnostic.LastFailureAt.IsZero() {
+				embed.AddField(tr.Commands.Debug.LastFailure, fmt.Sprintf("%s\n```%s```", discord.FormattedTimestampMention(diagnostic.LastFailureAt.Unix(), discord.TimestampStyleRelative), diagnostic.LastError), false)
+			}
+		}
+
+		if snapshot := latency.Snapshot(); len(snapshot) > 0 {
+			embed.AddField(tr.Commands.Debug.SynthesisLatency, latencyValue(snapshot), false)
+		}
+		if snapshot := timings.Snapshot(); len(snapshot) > 0 {
+			embed.AddField(tr.Commands.Debug.CommandLatency, timingValue(snapshot), false)
+		}
+
+		return e.CreateMessage(discord.NewMessageCreateBuilder().
+			AddEmbeds(embed.Build()).
+			SetEphemeral(true).
+			Build())
+	}
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// playbackValue formats the frame and underrun counters of a session.
+func playbackValue(health session.PlaybackHealth) string {
+	value := fmt.Sprintf("frames=%d late=%d empty=%d gaps=%d",
+		health.Frames, health.LateFrames, health.EmptyFrames, health.SenderGaps)
+	if !health.LastUnderrunAt.IsZero() {
+		value += " last underrun " + discord.FormattedTimestampMention(health.LastUnderrunAt.Unix(), discord.TimestampStyleRelative)
+	}
+	return value
+}
+
+// latencyValue formats the latency percentiles of every engine and voice, one per line.
+func latencyValue(snapshot map[tts.LatencyKey]tts.LatencySummary) string {
+	keys := make([]tts.LatencyKey, 0, len(snapshot))
+	for key := range snapshot {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b tts.LatencyKey) int {
+		return cmp.Or(cmp.Compare(a.Engine, b.Engine), cmp.Compare(a.Voice, b.Voice))
+	})
+
+	var sb strings.Builder
+	for _, key := range keys {
+		summary := snapshot[key]
+		line := fmt.Sprintf("`%s/%s` n=%d p50=%s p95=%s p99=%s max=%s\n",
+			key.Engine, key.Voice, summary.Count,
+			summary.P50.Round(time.Millisecond), summary.P95.Round(time.Millisecond),
+			summary.P99.Round(time.Millisecond), summary.Max.Round(time.Millisecond))
+		// embed field values are limited to 1024 characters.
+		if sb.Len()+len(line) > 1024 {
+			break
+		}
+		sb.WriteString(line)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func timingValue(snapshot map[string]CommandTiming) string {
+	names := slices.Sorted(maps.Keys(snapshot))
+
+	var sb strings.Builder
+	for _, name := range names {
+		timing := snapshot[name]
+		line := fmt.Sprintf("`%s` n=%d errors=%d mean=%s max=%s\n",
+			name, timing.Count, timing.Errors,
+			timing.Mean.Round(time.Millisecond), timing.Max.Round(time.Millisecond))
+		// embed field values are limited to 1024 characters.
+		if sb.Len()+len(line) > 1024 {
+			break
+		}
+		sb.WriteString(line)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -3,15 +3,21 @@ package commands
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"time"
 
+	"github.com/disgoorg/disgo/bot"
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/handler"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/disgo/voice"
+	"github.com/disgoorg/snowflake/v2"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/session"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
 )
 
@@ -22,30 +28,32 @@ func joinCmd(trs *i18n.TextResources) discord.SlashCommandCreate {
 		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
 			return tr.Commands.Join.Description
 		}),
+		Contexts: []discord.InteractionContextType{discord.InteractionContextTypeGuild},
 	}
 }
 
-func JoinHandler(engineRegistry *tts.EngineRegistry, presetResolver preset.PresetResolver, manager session.SessionManager, trs *i18n.TextResources, vrs *i18n.VoiceResources) handler.CommandHandler {
+func JoinHandler(engineRegistry *tts.EngineRegistry, presetResolver preset.PresetResolver, manager session.SessionManager, settingsRepository settings.GuildSettingsRepository, diagnostics *session.VoiceDiagnostics, members *session.MemberResolver, decoder session.Decoder, frameCache *session.OpusFrameCache, maxQueuedAudio int64, usage *tts.UsageTracker, vrs *i18n.VoiceResources) handler.CommandHandler {
 	return func(e *handler.CommandEvent) error {
-		tr, ok := trs.Get(e.Locale())
-		if !ok {
-			slog.Warn("text resource not found for locale", "locale", e.Locale())
-			tr = trs.GetFallback()
-		}
+		tr := localized(e.Ctx)
 
 		voiceChannelID, err := SafeGetVoiceChannelID(e, tr)
 		var friendlyErr *FriendlyError
 		if ok := errors.As(err, &friendlyErr); ok {
-			slog.Warn("Failed to get voice channel ID", "error", friendlyErr.err)
+			slog.WarnContext(e.Ctx, "Failed to get voice channel ID", "error", friendlyErr.err)
 			return e.CreateMessage(friendlyErr.Message())
 		}
 
 		guildID := *e.GuildID()
 
-		voiceManager := e.Client().VoiceManager()
-		conn := voiceManager.GetConn(guildID)
-		connected := conn != nil
-		if connected && *conn.ChannelID() == *voiceChannelID {
+		if err := checkJoinPermissions(e.Client(), tr, guildID, *voiceChannelID, e.Channel().ID()); err != nil {
+			if errors.As(err, &friendlyErr) {
+				slog.WarnContext(e.Ctx, "Permission preflight failed", "error", friendlyErr.err)
+				return e.CreateMessage(friendlyErr.Message())
+			}
+			return err
+		}
+
+		if _, ok := manager.GetByVoiceChannel(*voiceChannelID); ok {
 			return e.CreateMessage(discord.NewMessageCreateBuilder().
 				AddEmbeds(message.BuildErrorEmbed(tr).
 					SetDescription(tr.Commands.Join.ErrorAlreadyStarted).
@@ -53,9 +61,34 @@ func JoinHandler(engineRegistry *tts.EngineRegistry, presetResolver preset.Prese
 				Build())
 		}
 
-		if !connected {
-			slog.Info("Creating voice connection", "guildID", guildID, "channelID", *voiceChannelID)
-			conn = voiceManager.CreateConn(guildID)
+		// the guild already has a session in another voice channel.
+		// what to do with it is decided by the guild settings.
+		if running := manager.GetByGuild(guildID); len(running) > 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			guildSettings, err := settings.FindOrDefault(ctx, settingsRepository, guildID)
+			if err != nil {
+				slog.ErrorContext(e.Ctx, "Failed to fetch guild settings, using defaults", slog.Any("err", err), slog.String("guildID", guildID.String()))
+				guildSettings = settings.DefaultGuildSettings(guildID)
+			}
+
+			runningVoiceChannel := discord.ChannelMention(running[0].VoiceChannelID())
+			switch guildSettings.TakeoverPolicy {
+			case settings.TakeoverPolicyRefuse:
+				return e.CreateMessage(discord.NewMessageCreateBuilder().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescriptionf(tr.Commands.Join.ErrorAlreadyRunning, runningVoiceChannel).
+						Build()).
+					Build())
+			case settings.TakeoverPolicyConfirm:
+				return e.CreateMessage(discord.NewMessageCreateBuilder().
+					AddEmbeds(message.BuildTakeoverConfirmEmbed(tr, runningVoiceChannel, discord.ChannelMention(*voiceChannelID)).Build()).
+					AddActionRow(
+						discord.NewPrimaryButton(tr.Commands.Join.Takeover.Move, "/join/takeover/"+e.User().ID.String()+"/"+voiceChannelID.String()),
+						discord.NewSecondaryButton(tr.Commands.Join.Takeover.Cancel, "/join/cancel/"+e.User().ID.String()),
+					).
+					Build())
+			}
 		}
 
 		err = e.DeferCreateMessage(false)
@@ -66,48 +99,215 @@ func JoinHandler(engineRegistry *tts.EngineRegistry, presetResolver preset.Prese
 		// Connect to the voice channel in go routine
 		// Why? To establish the connection, we need to wait for the voice state update event
 		// and waiting for it in the same goroutine would block the response from server.
+		go startSession(e.Ctx, e.Client(), e, engineRegistry, presetResolver, manager, settingsRepository, diagnostics, members, decoder, frameCache, maxQueuedAudio, usage, tr, vrs, guildID, *voiceChannelID, e.Channel().ID())
 
-		go func() {
-			slog.Info("Connecting to voice channel", "guildID", guildID, "channelID", voiceChannelID)
+		return nil
+	}
+}
 
-			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-			defer cancel()
-			if err := conn.Open(ctx, *voiceChannelID, false, true); err != nil {
-				slog.Warn("Failed to connect to voice channel", "error", err)
-				e.UpdateInteractionResponse(discord.NewMessageUpdateBuilder().
-					SetContent("Failed to connect to voice channel: " + err.Error()).Build(),
-				)
-				return
-			}
+// JoinTakeoverHandler handles the "Move" button of the takeover confirmation.
+func JoinTakeoverHandler(engineRegistry *tts.EngineRegistry, presetResolver preset.PresetResolver, manager session.SessionManager, settingsRepository settings.GuildSettingsRepository, diagnostics *session.VoiceDiagnostics, members *session.MemberResolver, decoder session.Decoder, frameCache *session.OpusFrameCache, maxQueuedAudio int64, usage *tts.UsageTracker, vrs *i18n.VoiceResources) handler.ComponentHandler {
+	return func(e *handler.ComponentEvent) error {
+		tr := localized(e.Ctx)
 
-			slog.Info("Connected to voice channel", "guildID", guildID, "channelID", *voiceChannelID)
+		if e.Vars["userID"] != e.User().ID.String() {
+			return e.CreateMessage(discord.NewMessageCreateBuilder().
+				AddEmbeds(message.BuildErrorEmbed(tr).
+					SetDescription(tr.Commands.Join.Takeover.ErrorNotInvoker).
+					Build()).
+				SetEphemeral(true).
+				Build())
+		}
 
-			textChannel := e.Channel().ID()
+		voiceChannelID, err := snowflake.Parse(e.Vars["voiceChannelID"])
+		if err != nil {
+			slog.ErrorContext(e.Ctx, "Invalid voice channel ID in takeover button", slog.Any("err", err), slog.String("customID", e.Data.CustomID()))
+			return err
+		}
 
-			session, err := session.New(engineRegistry, presetResolver, textChannel, conn, &tr, vrs)
-			if err != nil {
-				slog.Error("Failed to create session", slog.Any("err", err), slog.String("textChannelID", textChannel.String()))
-				e.UpdateInteractionResponse(discord.NewMessageUpdateBuilder().
-					SetContent("Failed to create session: " + err.Error()).Build(),
-				)
-				conn.Close(context.Background())
-				return
+		// permissions may have changed while the confirmation was shown.
+		if err := checkJoinPermissions(e.Client(), tr, *e.GuildID(), voiceChannelID, e.Channel().ID()); err != nil {
+			var friendlyErr *FriendlyError
+			if errors.As(err, &friendlyErr) {
+				slog.WarnContext(e.Ctx, "Permission preflight failed", "error", friendlyErr.err)
+				return e.CreateMessage(friendlyErr.Message())
 			}
+			return err
+		}
+
+		if err := e.DeferUpdateMessage(); err != nil {
+			return err
+		}
+
+		go startSession(e.Ctx, e.Client(), e, engineRegistry, presetResolver, manager, settingsRepository, diagnostics, members, decoder, frameCache, maxQueuedAudio, usage, tr, vrs, *e.GuildID(), voiceChannelID, e.Channel().ID())
+
+		return nil
+	}
+}
+
+// JoinCancelHandler handles the "Cancel" button of the takeover confirmation.
+func JoinCancelHandler() handler.ComponentHandler {
+	return func(e *handler.ComponentEvent) error {
+		tr := localized(e.Ctx)
+
+		if e.Vars["userID"] != e.User().ID.String() {
+			return e.CreateMessage(discord.NewMessageCreateBuilder().
+				AddEmbeds(message.BuildErrorEmbed(tr).
+					SetDescription(tr.Commands.Join.Takeover.ErrorNotInvoker).
+					Build()).
+				SetEphemeral(true).
+				Build())
+		}
+
+		return e.UpdateMessage(discord.NewMessageUpdateBuilder().
+			SetEmbeds(message.BuildErrorEmbed(tr).
+				SetDescription(tr.Commands.Join.Takeover.Cancelled).
+				Build()).
+			ClearContainerComponents().
+			Build())
+	}
+}
+
+type interactionResponseUpdater interface {
+	UpdateInteractionResponse(messageUpdate discord.MessageUpdate, opts ...rest.RequestOpt) (*discord.Message, error)
+}
+
+// startSession closes any session running in the guild, connects to the voice channel and starts a new session.
+// It blocks until the voice connection is established, so it must be called in a separate goroutine.
+// Only one session can be started in a guild at a time, since the guild has a single voice connection.
+func startSession(ctx context.Context, client bot.Client, responder interactionResponseUpdater, engineRegistry *tts.EngineRegistry, presetResolver preset.PresetResolver, manager session.SessionManager, settingsRepository settings.GuildSettingsRepository, diagnostics *session.VoiceDiagnostics, members *session.MemberResolver, decoder session.Decoder, frameCache *session.OpusFrameCache, maxQueuedAudio int64, usage *tts.UsageTracker, tr i18n.TextResource, vrs *i18n.VoiceResources, guildID, voiceChannelID, textChannelID snowflake.ID) {
+	if err := manager.Reserve(guildID); err != nil {
+		slog.InfoContext(ctx, "Another session is starting in the guild", "guildID", guildID, "channelID", voiceChannelID)
+		respondGuildBusy(responder, tr)
+		return
+	}
+
+	for _, running := range manager.GetByGuild(guildID) {
+		slog.InfoContext(ctx, "Taking over session", "guildID", guildID, "from", running.VoiceChannelID(), "to", voiceChannelID)
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		runningVoiceChannelID := running.VoiceChannelID()
+		running.Close(closeCtx, session.CloseReasonTakeover)
+		cancel()
+		manager.Delete(guildID, runningVoiceChannelID)
 
-			if _, err := e.UpdateInteractionResponse(discord.NewMessageUpdateBuilder().
-				AddEmbeds(
-					message.BuildJoinEmbed(tr, discord.ChannelMention(textChannel), discord.ChannelMention(*voiceChannelID)).
-						Build(),
-				).
+		if _, err := client.Rest().CreateMessage(running.TextChannelID(), discord.NewMessageCreateBuilder().
+			AddEmbeds(message.BuildLeaveEmbed(tr, session.CloseReasonTakeover.Description(tr), running.Summary()).Build()).
+			Build(),
+		); err != nil {
+			slog.WarnContext(ctx, "Failed to send leave message", "error", err, "textChannelID", running.TextChannelID())
+		}
+	}
+
+	voiceManager := client.VoiceManager()
+	conn := voiceManager.GetConn(guildID)
+	if conn == nil {
+		slog.InfoContext(ctx, "Creating voice connection", "guildID", guildID, "channelID", voiceChannelID)
+		conn = voiceManager.CreateConn(guildID)
+	}
+
+	slog.InfoContext(ctx, "Connecting to voice channel", "guildID", guildID, "channelID", voiceChannelID)
+
+	if err := openVoiceConn(conn, diagnostics, settingsRepository, guildID, voiceChannelID); err != nil {
+		slog.WarnContext(ctx, "Failed to connect to voice channel", "error", err)
+		manager.Fail(guildID, voiceChannelID, textChannelID, err)
+		respondError(responder, classifyError(ctx, fmt.Errorf("failed to connect to voice channel: %w", err), tr))
+		return
+	}
+
+	slog.InfoContext(ctx, "Connected to voice channel", "guildID", guildID, "channelID", voiceChannelID)
+
+	s, err := session.New(engineRegistry, presetResolver, settingsRepository, members, decoder, frameCache, maxQueuedAudio, usage, textChannelID, conn, client.Rest(), client.Caches(), &tr, vrs)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create session", slog.Any("err", err), slog.String("textChannelID", textChannelID.String()))
+		manager.Fail(guildID, voiceChannelID, textChannelID, err)
+		respondError(responder, classifyError(ctx, fmt.Errorf("failed to create session: %w", err), tr))
+		conn.Close(context.Background())
+		return
+	}
+	if voiceState, ok := client.Caches().VoiceState(guildID, client.ID()); ok {
+		s.SyncVoiceState(voiceState)
+	}
+
+	if err := manager.Add(guildID, voiceChannelID, textChannelID, s); err != nil {
+		slog.WarnContext(ctx, "Failed to add session", "error", err, "guildID", guildID, "voiceChannelID", voiceChannelID)
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		s.Close(closeCtx, session.CloseReasonDuplicate)
+		cancel()
+		respondGuildBusy(responder, tr)
+		return
+	}
+
+	if _, err := responder.UpdateInteractionResponse(discord.NewMessageUpdateBuilder().
+		SetEmbeds(
+			message.BuildJoinEmbed(tr, discord.ChannelMention(textChannelID), discord.ChannelMention(voiceChannelID)).
 				Build(),
-			); err != nil {
-				slog.Warn("Failed to update interaction response", "error", err)
-			}
+		).
+		ClearContainerComponents().
+		Build(),
+	); err != nil {
+		slog.WarnContext(ctx, "Failed to update interaction response", "error", err)
+	}
+
+	slog.InfoContext(ctx, "Session created", "textChannelID", textChannelID, "voiceChannelID", voiceChannelID)
+}
 
-			slog.Info("Session created", "textChannelID", textChannel, "voiceChannelID", voiceChannelID)
-			manager.Add(guildID, *voiceChannelID, textChannel, session)
-		}()
+// respondGuildBusy tells the invoker that the guild already has a session starting or running in another voice channel.
+func respondGuildBusy(responder interactionResponseUpdater, tr i18n.TextResource) {
+	if _, err := responder.UpdateInteractionResponse(discord.NewMessageUpdateBuilder().
+		SetEmbeds(message.BuildErrorEmbed(tr).
+			SetDescription(tr.Commands.Join.ErrorGuildBusy).
+			Build()).
+		ClearContainerComponents().
+		Build(),
+	); err != nil {
+		slog.Warn("Failed to update interaction response", "error", err)
+	}
+}
 
+// respondError replaces the deferred response with the message of the error.
+func respondError(responder interactionResponseUpdater, friendlyErr *FriendlyError) {
+	if _, err := responder.UpdateInteractionResponse(discord.NewMessageUpdateBuilder().
+		SetEmbeds(friendlyErr.Message().Embeds...).
+		ClearContainerComponents().
+		Build(),
+	); err != nil {
+		slog.Warn("Failed to update interaction response", "error", err)
+	}
+}
+
+// openVoiceConn opens the voice connection and records the result in diagnostics.
+// If the connection fails, it waits briefly for discord to assign another voice server
+// (e.g. during a region outage) and retries once before giving up.
+// The bot joins with the voice state (self-deaf and self-mute) set by the guild.
+func openVoiceConn(conn voice.Conn, diagnostics *session.VoiceDiagnostics, settingsRepository settings.GuildSettingsRepository, guildID, voiceChannelID snowflake.ID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	guildSettings, err := settings.FindOrDefault(ctx, settingsRepository, guildID)
+	if err != nil {
+		slog.Warn("Failed to fetch guild settings, joining with the default voice state", "error", err, "guildID", guildID)
+		guildSettings = settings.DefaultGuildSettings(guildID)
+	}
+
+	err = conn.Open(ctx, voiceChannelID, guildSettings.SelfMute, guildSettings.SelfDeaf)
+	if err == nil {
+		diagnostics.RecordSuccess(guildID)
 		return nil
 	}
+	diagnostics.RecordFailure(guildID, err)
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer waitCancel()
+	if !diagnostics.WaitForServerChange(waitCtx, guildID) {
+		return err
+	}
+
+	slog.Info("Voice server changed, retrying voice connection", "guildID", guildID, "channelID", voiceChannelID)
+	retryCtx, retryCancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer retryCancel()
+	if err := conn.Open(retryCtx, voiceChannelID, guildSettings.SelfMute, guildSettings.SelfDeaf); err != nil {
+		diagnostics.RecordFailure(guildID, err)
+		return err
+	}
+	diagnostics.RecordSuccess(guildID)
+	return nil
 }
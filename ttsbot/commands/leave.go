@@ -1,8 +1,10 @@
 package commands
 
 import (
+	"context"
 	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/handler"
@@ -18,28 +20,37 @@ func leaveCmd(trs *i18n.TextResources) discord.SlashCommandCreate {
 		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
 			return tr.Commands.Leave.Description
 		}),
+		Contexts: []discord.InteractionContextType{discord.InteractionContextTypeGuild},
 	}
 }
 
-func LeaveHandler(manager session.SessionManager, trs *i18n.TextResources) handler.CommandHandler {
+func LeaveHandler(manager session.SessionManager) handler.CommandHandler {
 	return func(e *handler.CommandEvent) error {
-		tr, ok := trs.Get(e.Locale())
-		if !ok {
-			slog.Warn("text resource not found for locale", "locale", e.Locale())
-			tr = trs.GetFallback()
-		}
+		tr := localized(e.Ctx)
 
+		var (
+			running *session.Session
+			found   bool
+		)
 		voiceChannelID, err := SafeGetVoiceChannelID(e, tr)
-		var friendlyErr *FriendlyError
-		if ok := errors.As(err, &friendlyErr); ok {
-			slog.Warn("Failed to get voice channel ID", "error", friendlyErr.err)
-			return e.CreateMessage(friendlyErr.Message())
+		if err == nil {
+			running, found = manager.GetByVoiceChannel(*voiceChannelID)
 		}
 
-		guildID := *e.GuildID()
-		session, ok := manager.GetByVoiceChannel(*voiceChannelID)
-		if !ok {
-			slog.Warn("No active session found for voice channel", "channelID", *voiceChannelID)
+		// users who are not in the voice channel (e.g. after being disconnected) can still stop
+		// the session from the channel being read, as long as they can manage channels.
+		if !found && e.Context() == discord.InteractionContextTypeGuild && hasPermission(e, discord.PermissionManageChannels) {
+			running, found = manager.GetByReadingChannel(e.Channel().ID())
+		}
+
+		if !found {
+			var friendlyErr *FriendlyError
+			if errors.As(err, &friendlyErr) {
+				slog.WarnContext(e.Ctx, "Failed to get voice channel ID", "error", friendlyErr.err)
+				return e.CreateMessage(friendlyErr.Message())
+			}
+
+			slog.WarnContext(e.Ctx, "No active session found for voice channel", "channelID", voiceChannelID, "textChannelID", e.Channel().ID())
 			return e.CreateMessage(discord.NewMessageCreateBuilder().
 				AddEmbeds(message.BuildErrorEmbed(tr).
 					SetDescription(tr.Commands.Leave.ErrorNotStarted).
@@ -47,13 +58,19 @@ func LeaveHandler(manager session.SessionManager, trs *i18n.TextResources) handl
 				Build())
 		}
 
-		// to prevent deadlock, close the session in a separate goroutine
+		guildID := *e.GuildID()
+		sessionVoiceChannelID := running.VoiceChannelID()
+
+		// to prevent deadlock, and to reply while the farewell is played, close the session in a separate goroutine
 		go func() {
-			session.Close(e.Ctx)
-			manager.Delete(guildID, *voiceChannelID)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			running.Farewell(ctx)
+			running.Close(ctx, session.CloseReasonLeave)
+			manager.Delete(guildID, sessionVoiceChannelID)
 		}()
 		return e.CreateMessage(discord.NewMessageCreateBuilder().
-			AddEmbeds(message.BuildLeaveEmbed(tr).Build()).
+			AddEmbeds(message.BuildLeaveEmbed(tr, session.CloseReasonLeave.Description(tr), running.Summary()).Build()).
 			Build())
 	}
 }
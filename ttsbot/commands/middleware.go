@@ -0,0 +1,236 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/logging"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+)
+
+type textResourceKey struct{}
+
+// Correlate gives every interaction a correlation ID, which is added to the logs of the interaction and shown with errors.
+// It should be installed first, so that the other middlewares log with the ID.
+func Correlate() handler.Middleware {
+	return func(next handler.Handler) handler.Handler {
+		return func(e *handler.InteractionEvent) error {
+			e.Ctx = logging.WithCorrelationID(e.Ctx, logging.NewCorrelationID())
+			return next(e)
+		}
+	}
+}
+
+// correlate returns the correlation ID of the context, deriving a context with a new ID if it has none.
+func correlate(ctx context.Context) (context.Context, string) {
+	if id := logging.CorrelationID(ctx); id != "" {
+		return ctx, id
+	}
+	id := logging.NewCorrelationID()
+	return logging.WithCorrelationID(ctx, id), id
+}
+
+// Localize resolves the text resource of the locale of the invoker, falling back to the default locale.
+// Handlers get it with localized.
+func Localize(trs *i18n.TextResources) handler.Middleware {
+	return func(next handler.Handler) handler.Handler {
+		return func(e *handler.InteractionEvent) error {
+			tr, ok := trs.Get(e.Locale())
+			if !ok {
+				slog.WarnContext(e.Ctx, "text resource not found for locale", "locale", e.Locale())
+				tr = trs.GetFallback()
+			}
+			e.Ctx = context.WithValue(e.Ctx, textResourceKey{}, tr)
+			return next(e)
+		}
+	}
+}
+
+// localized returns the text resource resolved by the Localize middleware.
+// It panics if the middleware is not installed, which is a programming error.
+func localized(ctx context.Context) i18n.TextResource {
+	tr, ok := ctx.Value(textResourceKey{}).(i18n.TextResource)
+	if !ok {
+		panic("commands: the Localize middleware is not installed")
+	}
+	return tr
+}
+
+// GuildOnly rejects interactions outside of guilds. It must be installed after Localize.
+func GuildOnly() handler.Middleware {
+	return func(next handler.Handler) handler.Handler {
+		return func(e *handler.InteractionEvent) error {
+			if e.Context() != discord.InteractionContextTypeGuild || e.GuildID() == nil {
+				tr := localized(e.Ctx)
+				return e.CreateMessage(discord.NewMessageCreateBuilder().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(tr.Commands.Generic.ErrorNotInGuild).
+						Build()).
+					SetEphemeral(true).
+					Build())
+			}
+			return next(e)
+		}
+	}
+}
+
+// RequirePermission rejects interactions of members without the permission, e.g. components of a message every member can see.
+// The description of the error is taken from the text resource. It must be installed after Localize.
+func RequirePermission(permission discord.Permissions, description func(tr i18n.TextResource) string) handler.Middleware {
+	return func(next handler.Handler) handler.Handler {
+		return func(e *handler.InteractionEvent) error {
+			if !hasPermission(e, permission) {
+				tr := localized(e.Ctx)
+				return e.CreateMessage(discord.NewMessageCreateBuilder().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(description(tr)).
+						Build()).
+					SetEphemeral(true).
+					Build())
+			}
+			return next(e)
+		}
+	}
+}
+
+// Recover turns a panic of a handler into an error, so that a bug in one command does not take the bot down.
+// The invoker is told that something went wrong, unless the interaction was already responded to.
+// It must be installed after Localize.
+func Recover() handler.Middleware {
+	return func(next handler.Handler) handler.Handler {
+		return func(e *handler.InteractionEvent) (err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				ctx, errorID := correlate(e.Ctx)
+				slog.ErrorContext(ctx, "Recovered from a panic in an interaction handler", slog.Any("panic", r), slog.String("interactionID", e.ID().String()), slog.String("stack", string(debug.Stack())))
+				err = fmt.Errorf("panic in interaction handler: %v", r)
+
+				tr := localized(e.Ctx)
+				if respondErr := e.CreateMessage(discord.NewMessageCreateBuilder().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescriptionf(tr.Commands.Generic.ErrorUnexpected, errorID).
+						Build()).
+					SetEphemeral(true).
+					Build()); respondErr != nil {
+					slog.DebugContext(ctx, "Failed to respond after a panic", slog.Any("err", respondErr))
+				}
+			}()
+			return next(e)
+		}
+	}
+}
+
+// Timing records how long handlers take in the timings, and warns about handlers slower than the threshold.
+// Discord expects a response within 3 seconds, so handlers should defer before that.
+func Timing(timings *CommandTimings, slowThreshold time.Duration) handler.Middleware {
+	return func(next handler.Handler) handler.Handler {
+		return func(e *handler.InteractionEvent) error {
+			start := time.Now()
+			err := next(e)
+			elapsed := time.Since(start)
+
+			name := interactionName(e)
+			timings.Observe(name, elapsed, err != nil)
+			if slowThreshold > 0 && elapsed > slowThreshold {
+				slog.WarnContext(e.Ctx, "Slow interaction handler", "interaction", name, "duration", elapsed, "threshold", slowThreshold)
+			} else {
+				slog.DebugContext(e.Ctx, "Interaction handled", "interaction", name, "duration", elapsed)
+			}
+			return err
+		}
+	}
+}
+
+// interactionName returns the name timings are recorded under: the command path for commands,
+// and the first two segments of the custom ID for components, e.g. "/join/takeover" for "/join/takeover/{userID}/{voiceChannelID}".
+func interactionName(e *handler.InteractionEvent) string {
+	switch i := e.Interaction.(type) {
+	case discord.ApplicationCommandInteraction:
+		if data, ok := i.Data.(discord.SlashCommandInteractionData); ok {
+			return data.CommandPath()
+		}
+		return "/" + i.Data.CommandName()
+	case discord.ComponentInteraction:
+		return componentName(i.Data.CustomID())
+	case discord.ModalSubmitInteraction:
+		return componentName(i.Data.CustomID)
+	}
+	return "unknown"
+}
+
+// componentName trims a custom ID to its first two segments, since the rest usually holds IDs.
+func componentName(customID string) string {
+	parts := strings.SplitN(strings.TrimPrefix(customID, "/"), "/", 3)
+	return "/" + strings.Join(parts[:min(len(parts), 2)], "/")
+}
+
+// CommandTiming is a summary of the timings of an interaction.
+type CommandTiming struct {
+	Count  int
+	Errors int
+	Mean   time.Duration
+	Max    time.Duration
+}
+
+// CommandTimings records how long interaction handlers take, keyed by interaction name.
+type CommandTimings struct {
+	mu      sync.Mutex
+	timings map[string]*commandTiming
+}
+
+type commandTiming struct {
+	count  int
+	errors int
+	sum    time.Duration
+	max    time.Duration
+}
+
+func NewCommandTimings() *CommandTimings {
+	return &CommandTimings{
+		timings: make(map[string]*commandTiming),
+	}
+}
+
+func (t *CommandTimings) Observe(name string, d time.Duration, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timing, ok := t.timings[name]
+	if !ok {
+		timing = &commandTiming{}
+		t.timings[name] = timing
+	}
+	timing.count++
+	if failed {
+		timing.errors++
+	}
+	timing.sum += d
+	timing.max = max(timing.max, d)
+}
+
+// Snapshot returns the timings of every interaction handled so far.
+func (t *CommandTimings) Snapshot() map[string]CommandTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	snapshot := make(map[string]CommandTiming, len(t.timings))
+	for name, timing := range t.timings {
+		snapshot[name] = CommandTiming{
+			Count:  timing.count,
+			Errors: timing.errors,
+			Mean:   timing.sum / time.Duration(timing.count),
+			Max:    timing.max,
+		}
+	}
+	return snapshot
+}
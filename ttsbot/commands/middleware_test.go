@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComponentName(t *testing.T) {
+	tests := []struct {
+		customID string
+		want     string
+	}{
+		{"/join/takeover/123/456", "/join/takeover"},
+		{"/setup/announcements", "/setup/announcements"},
+		{"/join", "/join"},
+	}
+
+	for _, tt := range tests {
+		if got := componentName(tt.customID); got != tt.want {
+			t.Errorf("componentName(%q) = %q, want %q", tt.customID, got, tt.want)
+		}
+	}
+}
+
+func TestCommandTimings(t *testing.T) {
+	timings := NewCommandTimings()
+	timings.Observe("/join", 100*time.Millisecond, false)
+	timings.Observe("/join", 300*time.Millisecond, true)
+	timings.Observe("/leave", 50*time.Millisecond, false)
+
+	snapshot := timings.Snapshot()
+	join := snapshot["/join"]
+	if join.Count != 2 || join.Errors != 1 || join.Mean != 200*time.Millisecond || join.Max != 300*time.Millisecond {
+		t.Errorf("unexpected /join timing: %+v", join)
+	}
+	if leave := snapshot["/leave"]; leave.Count != 1 || leave.Errors != 0 {
+		t.Errorf("unexpected /leave timing: %+v", leave)
+	}
+}
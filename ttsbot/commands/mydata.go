@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+)
+
+func mydataCmd(trs *i18n.TextResources) discord.SlashCommandCreate {
+	return discord.SlashCommandCreate{
+		Name:        "mydata",
+		Description: "Export or delete the data the bot stores about you",
+		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+			return tr.Commands.Mydata.Description
+		}),
+		// users must be able to reach their data wherever they are, like /preset user.
+		IntegrationTypes: []discord.ApplicationIntegrationType{discord.ApplicationIntegrationTypeGuildInstall, discord.ApplicationIntegrationTypeUserInstall},
+		Contexts:         []discord.InteractionContextType{discord.InteractionContextTypeGuild, discord.InteractionContextTypeBotDM, discord.InteractionContextTypePrivateChannel},
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "export",
+				Description: "Download the data the bot stores about you as JSON",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Mydata.Export.Description
+				}),
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "delete",
+				Description: "Delete the data the bot stores about you",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Mydata.Delete.Description
+				}),
+			},
+		},
+	}
+}
+
+// userData is everything the bot stores about a user.
+type userData struct {
+	UserID   snowflake.ID    `json:"user_id"`
+	PresetID preset.PresetID `json:"preset_id,omitempty"`
+}
+
+func collectUserData(ctx context.Context, presetIDRepository preset.PresetIDRepository, userID snowflake.ID) (userData, error) {
+	data := userData{UserID: userID}
+
+	presetID, err := presetIDRepository.Find(ctx, preset.ScopeUser, userID)
+	if err != nil && !errors.Is(err, preset.ErrNotFound) {
+		return userData{}, fmt.Errorf("failed to fetch user preset: %w", err)
+	}
+	data.PresetID = presetID
+
+	return data, nil
+}
+
+func deleteUserData(ctx context.Context, presetIDRepository preset.PresetIDRepository, userID snowflake.ID) error {
+	if err := presetIDRepository.Delete(ctx, preset.ScopeUser, userID); err != nil {
+		return fmt.Errorf("failed to delete user preset: %w", err)
+	}
+	return nil
+}
+
+func MydataHandler(presetIDRepository preset.PresetIDRepository) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		tr := localized(e.Ctx)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		userID := e.User().ID
+
+		data := e.SlashCommandInteractionData()
+		switch *data.SubCommandName {
+		case "export":
+			collected, err := collectUserData(ctx, presetIDRepository, userID)
+			if err != nil {
+				slog.ErrorContext(e.Ctx, "failed to collect user data", slog.String("userID", userID.String()), slog.Any("err", err))
+				return e.CreateMessage(discord.NewMessageCreateBuilder().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(tr.Commands.Mydata.Export.Error).
+						Build()).
+					SetEphemeral(true).
+					Build())
+			}
+
+			content, err := json.MarshalIndent(collected, "", "  ")
+			if err != nil {
+				return err
+			}
+			return e.CreateMessage(discord.NewMessageCreateBuilder().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescription(tr.Commands.Mydata.Export.Success).
+					Build()).
+				AddFile("mydata.json", "", bytes.NewReader(content)).
+				SetEphemeral(true).
+				Build())
+
+		case "delete":
+			if err := deleteUserData(ctx, presetIDRepository, userID); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to delete user data", slog.String("userID", userID.String()), slog.Any("err", err))
+				return e.CreateMessage(discord.NewMessageCreateBuilder().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(tr.Commands.Mydata.Delete.Error).
+						Build()).
+					SetEphemeral(true).
+					Build())
+			}
+
+			slog.InfoContext(e.Ctx, "Deleted user data on request", slog.String("userID", userID.String()))
+			return e.CreateMessage(discord.NewMessageCreateBuilder().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescription(tr.Commands.Mydata.Delete.Success).
+					Build()).
+				SetEphemeral(true).
+				Build())
+		}
+
+		slog.ErrorContext(e.Ctx, "unknown mydata command", "command", *data.SubCommandName)
+		return e.CreateMessage(discord.NewMessageCreateBuilder().
+			SetContent("Developer Error: Unsupported subcommand").
+			Build())
+	}
+}
@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserData(t *testing.T) {
+	ctx := context.Background()
+	presetIDRepository := preset.NewMemoryPresetIDRepository()
+	require.NoError(t, presetIDRepository.Save(ctx, preset.ScopeUser, 1, "default"))
+	// a guild sharing the ID of the user must not be exported or deleted with the user.
+	require.NoError(t, presetIDRepository.Save(ctx, preset.ScopeGuild, 1, "guild"))
+
+	data, err := collectUserData(ctx, presetIDRepository, 1)
+	require.NoError(t, err)
+	content, err := json.Marshal(data)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"user_id": "1", "preset_id": "default"}`, string(content))
+
+	require.NoError(t, deleteUserData(ctx, presetIDRepository, 1))
+	data, err = collectUserData(ctx, presetIDRepository, 1)
+	require.NoError(t, err)
+	content, err = json.Marshal(data)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"user_id": "1"}`, string(content))
+
+	guildPresetID, err := presetIDRepository.Find(ctx, preset.ScopeGuild, 1)
+	require.NoError(t, err)
+	require.Equal(t, preset.PresetID("guild"), guildPresetID)
+}
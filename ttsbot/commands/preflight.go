@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+)
+
+var (
+	// permissions the bot needs in the voice channel to read aloud.
+	voicePermissions = discord.PermissionViewChannel | discord.PermissionConnect | discord.PermissionSpeak
+	// stage channels additionally require the bot to request to speak.
+	stagePermissions = voicePermissions | discord.PermissionRequestToSpeak
+	// permissions the bot needs in the reading channel to receive messages.
+	readingPermissions = discord.PermissionViewChannel
+)
+
+// checkJoinPermissions verifies the bot has the permissions required to start a session
+// before opening the voice connection, so that users get a specific error instead of a connection timeout.
+// If the cache does not have enough information to decide, the check is skipped.
+func checkJoinPermissions(client bot.Client, tr i18n.TextResource, guildID, voiceChannelID, textChannelID snowflake.ID) error {
+	caches := client.Caches()
+	self, ok := caches.SelfMember(guildID)
+	if !ok {
+		slog.Debug("Self member not found in cache, skipping permission preflight", "guildID", guildID)
+		return nil
+	}
+
+	checks := []struct {
+		channelID snowflake.ID
+		required  func(channel discord.GuildChannel) discord.Permissions
+	}{
+		{
+			channelID: voiceChannelID,
+			required: func(channel discord.GuildChannel) discord.Permissions {
+				if channel.Type() == discord.ChannelTypeGuildStageVoice {
+					return stagePermissions
+				}
+				return voicePermissions
+			},
+		},
+		{
+			channelID: textChannelID,
+			required: func(discord.GuildChannel) discord.Permissions {
+				return readingPermissions
+			},
+		},
+	}
+
+	for _, check := range checks {
+		channel, ok := caches.Channel(check.channelID)
+		if !ok {
+			slog.Debug("Channel not found in cache, skipping permission preflight", "channelID", check.channelID)
+			continue
+		}
+
+		missing := missingPermissions(caches.MemberPermissionsInChannel(channel, self), check.required(channel))
+		if missing == discord.PermissionsNone {
+			continue
+		}
+
+		return newFriendlyError(
+			fmt.Errorf("missing permissions %s in channel %s", missing, check.channelID),
+			discord.NewMessageCreateBuilder().
+				AddEmbeds(message.BuildErrorEmbed(tr).
+					SetDescriptionf(tr.Commands.Join.ErrorMissingPermissions, discord.ChannelMention(check.channelID), permissionNames(missing, tr)).
+					Build()).
+				Build(),
+		)
+	}
+
+	return nil
+}
+
+// missingPermissions returns the permissions in required that are not granted.
+func missingPermissions(granted, required discord.Permissions) discord.Permissions {
+	return required &^ granted
+}
+
+// permissionNames returns the localized, comma separated names of the given permissions.
+func permissionNames(permissions discord.Permissions, tr i18n.TextResource) string {
+	names := []struct {
+		permission discord.Permissions
+		name       string
+	}{
+		{discord.PermissionViewChannel, tr.Generic.Permissions.ViewChannel},
+		{discord.PermissionConnect, tr.Generic.Permissions.Connect},
+		{discord.PermissionSpeak, tr.Generic.Permissions.Speak},
+		{discord.PermissionRequestToSpeak, tr.Generic.Permissions.RequestToSpeak},
+	}
+
+	result := make([]string, 0, len(names))
+	for _, n := range names {
+		if permissions.Has(n.permission) {
+			result = append(result, n.name)
+		}
+	}
+	return strings.Join(result, ", ")
+}
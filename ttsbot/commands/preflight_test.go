@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+)
+
+func TestMissingPermissions(t *testing.T) {
+	testcases := []struct {
+		name     string
+		granted  discord.Permissions
+		required discord.Permissions
+		want     discord.Permissions
+	}{
+		{
+			name:     "all granted",
+			granted:  discord.PermissionsAll,
+			required: stagePermissions,
+			want:     discord.PermissionsNone,
+		},
+		{
+			name:     "missing speak",
+			granted:  discord.PermissionViewChannel | discord.PermissionConnect,
+			required: voicePermissions,
+			want:     discord.PermissionSpeak,
+		},
+		{
+			name:     "missing request to speak on stage",
+			granted:  voicePermissions,
+			required: stagePermissions,
+			want:     discord.PermissionRequestToSpeak,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := missingPermissions(tc.granted, tc.required); got != tc.want {
+				t.Errorf("missingPermissions() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPermissionNames(t *testing.T) {
+	var tr i18n.TextResource
+	tr.Generic.Permissions.Connect = "Connect"
+	tr.Generic.Permissions.Speak = "Speak"
+
+	got := permissionNames(discord.PermissionConnect|discord.PermissionSpeak, tr)
+	if got != "Connect, Speak" {
+		t.Errorf("permissionNames() = %q, want %q", got, "Connect, Speak")
+	}
+}
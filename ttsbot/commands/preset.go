@@ -13,6 +13,7 @@ import (
 	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
 )
 
 func presetCmd(trs *i18n.TextResources) discord.SlashCommandCreate {
@@ -22,6 +23,10 @@ func presetCmd(trs *i18n.TextResources) discord.SlashCommandCreate {
 		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
 			return tr.Commands.Preset.Description
 		}),
+		// installed to a user, the command is available in every server and DM, so users carry their presets across servers.
+		// only the user scope is available where the bot is not added to the server.
+		IntegrationTypes: []discord.ApplicationIntegrationType{discord.ApplicationIntegrationTypeGuildInstall, discord.ApplicationIntegrationTypeUserInstall},
+		Contexts:         []discord.InteractionContextType{discord.InteractionContextTypeGuild, discord.InteractionContextTypeBotDM, discord.InteractionContextTypePrivateChannel},
 		Options: []discord.ApplicationCommandOption{
 			discord.ApplicationCommandOptionSubCommandGroup{
 				Name:        "guild",
@@ -112,32 +117,45 @@ func presetCmd(trs *i18n.TextResources) discord.SlashCommandCreate {
 	}
 }
 
-func PresetHandler(presetRegistry *preset.PresetRegistry, presetResolver preset.PresetResolver, presetIDRepository preset.PresetIDRepository, trs *i18n.TextResources) func(*handler.CommandEvent) error {
+func PresetHandler(presetRegistry *preset.PresetRegistry, presetResolver preset.PresetResolver, presetIDRepository preset.PresetIDRepository, restrictions *preset.Restrictions, settingsRepository settings.GuildSettingsRepository) func(*handler.CommandEvent) error {
 	return func(e *handler.CommandEvent) error {
 		data := e.SlashCommandInteractionData()
 
+		response := findResponseBuilder(settingsRepository, interactionGuildID(e))
+
 		groupName := data.SubCommandGroupName
 		if groupName != nil {
-			return processPresetGroupCommand(e, presetRegistry, presetIDRepository, *groupName, trs)
+			return processPresetGroupCommand(e, response, presetRegistry, presetIDRepository, restrictions, *groupName)
 		}
 
-		return processPresetCommand(e, presetRegistry, trs)
+		return processPresetCommand(e, response, presetRegistry, restrictions)
 	}
 }
 
-func processPresetGroupCommand(e *handler.CommandEvent, presetRegistry *preset.PresetRegistry, presetIDRepository preset.PresetIDRepository, groupName string, trs *i18n.TextResources) error {
-	tr, ok := trs.Get(e.Locale())
-
-	if !ok {
-		slog.Error("failed to get localization for locale", "locale", e.Locale())
-		tr = trs.GetFallback()
-	}
+func processPresetGroupCommand(e *handler.CommandEvent, response responseBuilder, presetRegistry *preset.PresetRegistry, presetIDRepository preset.PresetIDRepository, restrictions *preset.Restrictions, groupName string) error {
+	tr := localized(e.Ctx)
 
 	var scope preset.Scope
 	var id snowflake.ID
 	var generic string
 	switch groupName {
 	case "guild":
+		if e.Context() != discord.InteractionContextTypeGuild {
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildErrorEmbed(tr).
+					SetDescription(tr.Commands.Generic.ErrorNotInGuild).
+					Build()).
+				SetEphemeral(true).
+				Build())
+		}
+		if !isGuildInstalled(e) {
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildErrorEmbed(tr).
+					SetDescription(tr.Commands.Generic.ErrorNotInstalled).
+					Build()).
+				SetEphemeral(true).
+				Build())
+		}
 		scope = preset.ScopeGuild
 		generic = tr.Generic.Guild
 		id = *e.GuildID()
@@ -146,8 +164,8 @@ func processPresetGroupCommand(e *handler.CommandEvent, presetRegistry *preset.P
 		generic = tr.Generic.User
 		id = e.User().ID
 	default:
-		slog.Error("unknown preset group", "group", groupName)
-		return e.CreateMessage(discord.NewMessageCreateBuilder().
+		slog.ErrorContext(e.Ctx, "unknown preset group", "group", groupName)
+		return e.CreateMessage(response.Message().
 			AddEmbeds(message.BuildErrorEmbed(tr).
 				SetDescription("Developer Error: Unsupported subcommand").
 				Build()).
@@ -161,24 +179,31 @@ func processPresetGroupCommand(e *handler.CommandEvent, presetRegistry *preset.P
 	case "set":
 		preset, ok := presetRegistry.Get(preset.PresetID(data.String("name")))
 		if !ok {
-			return e.CreateMessage(discord.NewMessageCreateBuilder().
+			return e.CreateMessage(response.Message().
 				AddEmbeds(message.BuildErrorEmbed(tr).
 					SetDescriptionf(tr.Commands.Preset.Generic.Set.ErrorNotFound, data.String("name")).
 					Build()).
 				Build())
 		}
+		if !restrictions.Allows(interactionGuildID(e), preset) {
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildErrorEmbed(tr).
+					SetDescriptionf(tr.Commands.Preset.Generic.Set.ErrorRestricted, preset.Identifier).
+					Build()).
+				Build())
+		}
 
 		err := presetIDRepository.Save(ctx, scope, id, preset.Identifier)
 		if err != nil {
-			slog.Error("failed to save preset ID", "error", err)
-			return e.CreateMessage(discord.NewMessageCreateBuilder().
+			slog.ErrorContext(e.Ctx, "failed to save preset ID", "error", err)
+			return e.CreateMessage(response.Message().
 				AddEmbeds(message.BuildErrorEmbed(tr).
 					SetDescriptionf(tr.Commands.Preset.Generic.Set.ErrorSave, generic, err).
 					Build()).
 				Build())
 		}
 
-		return e.CreateMessage(discord.NewMessageCreateBuilder().
+		return e.CreateMessage(response.Message().
 			AddEmbeds(message.BuildSuccessEmbed(tr).
 				SetDescriptionf(tr.Commands.Preset.Generic.Set.Success, generic, preset.Identifier).
 				Build(),
@@ -188,14 +213,14 @@ func processPresetGroupCommand(e *handler.CommandEvent, presetRegistry *preset.P
 	case "unset":
 		err := presetIDRepository.Delete(ctx, scope, id)
 		if err != nil {
-			slog.Error("failed to delete preset ID", "error", err)
-			return e.CreateMessage(discord.NewMessageCreateBuilder().
+			slog.ErrorContext(e.Ctx, "failed to delete preset ID", "error", err)
+			return e.CreateMessage(response.Message().
 				AddEmbeds(message.BuildErrorEmbed(tr).
 					SetDescription(tr.Commands.Preset.Generic.Unset.ErrorDelete).
 					Build()).
 				Build())
 		}
-		return e.CreateMessage(discord.NewMessageCreateBuilder().
+		return e.CreateMessage(response.Message().
 			AddEmbeds(message.BuildSuccessEmbed(tr).
 				SetDescriptionf(tr.Commands.Preset.Generic.Unset.Success, generic).
 				Build()).
@@ -205,15 +230,15 @@ func processPresetGroupCommand(e *handler.CommandEvent, presetRegistry *preset.P
 		presetID, err := presetIDRepository.Find(ctx, scope, id)
 		if err != nil {
 			if errors.Is(err, preset.ErrNotFound) {
-				return e.CreateMessage(discord.NewMessageCreateBuilder().
+				return e.CreateMessage(response.Message().
 					AddEmbeds(message.BuildErrorEmbed(tr).
 						SetDescriptionf(tr.Commands.Preset.Generic.Show.None, generic).
 						Build(),
 					).
 					Build())
 			}
-			slog.Error("failed to find preset ID", "error", err)
-			return e.CreateMessage(discord.NewMessageCreateBuilder().
+			slog.ErrorContext(e.Ctx, "failed to find preset ID", "error", err)
+			return e.CreateMessage(response.Message().
 				AddEmbeds(message.BuildErrorEmbed(tr).
 					SetDescription(tr.Commands.Preset.Generic.Show.ErrorFetch).
 					Build()).
@@ -222,14 +247,14 @@ func processPresetGroupCommand(e *handler.CommandEvent, presetRegistry *preset.P
 
 		preset, ok := presetRegistry.Get(presetID)
 		if !ok {
-			slog.Error("failed to resolve preset", "error", err)
-			return e.CreateMessage(discord.NewMessageCreateBuilder().
+			slog.ErrorContext(e.Ctx, "failed to resolve preset", "error", err)
+			return e.CreateMessage(response.Message().
 				AddEmbeds(message.BuildErrorEmbed(tr).
 					SetDescription(tr.Commands.Preset.Generic.Show.ErrorInvalid).
 					Build()).
 				Build())
 		}
-		return e.CreateMessage(discord.NewMessageCreateBuilder().
+		return e.CreateMessage(response.Message().
 			AddEmbeds(
 				message.BuildPresetEmbed(preset, tr).
 					SetDescriptionf(tr.Commands.Preset.Generic.Show.Current, generic).
@@ -238,30 +263,26 @@ func processPresetGroupCommand(e *handler.CommandEvent, presetRegistry *preset.P
 			Build())
 	}
 
-	return e.CreateMessage(discord.NewMessageCreateBuilder().
+	return e.CreateMessage(response.Message().
 		SetContent("Developer Error: Unsupported subcommand").
 		Build())
 }
 
-func processPresetCommand(e *handler.CommandEvent, presetRegistry *preset.PresetRegistry, trs *i18n.TextResources) error {
+func processPresetCommand(e *handler.CommandEvent, response responseBuilder, presetRegistry *preset.PresetRegistry, restrictions *preset.Restrictions) error {
 	data := e.SlashCommandInteractionData()
-	tr, ok := trs.Get(e.Locale())
-	if !ok {
-		slog.Error("failed to get localization for locale", "locale", e.Locale())
-		tr = trs.GetFallback()
-	}
+	tr := localized(e.Ctx)
 
 	switch *data.SubCommandName {
 	case "list":
-		presets := presetRegistry.List()
+		presets := restrictions.Filter(interactionGuildID(e), presetRegistry.List())
 
-		return e.CreateMessage(discord.NewMessageCreateBuilder().
+		return e.CreateMessage(response.Message().
 			SetEmbeds(message.BuildPresetListEmbed(presets, tr).Build()).
 			Build())
 	}
 
-	slog.Error("unknown preset command", "command", *data.SubCommandName)
-	return e.CreateMessage(discord.NewMessageCreateBuilder().
+	slog.ErrorContext(e.Ctx, "unknown preset command", "command", *data.SubCommandName)
+	return e.CreateMessage(response.Message().
 		SetContent("Developer Error: Unsupported subcommand").
 		Build())
 }
@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+	"github.com/disgoorg/json"
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/session"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+// maxPreviewLength keeps the preview within the 4096 characters of an embed description.
+const maxPreviewLength = 4000
+
+func previewCmd(trs *i18n.TextResources) discord.SlashCommandCreate {
+	return discord.SlashCommandCreate{
+		Name:        "preview",
+		Description: "Show how a message would be read, without reading it",
+		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+			return tr.Commands.Preview.Description
+		}),
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionString{
+				Name:        "text",
+				Description: "The message to preview",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Preview.Text
+				}),
+				Required: true,
+			},
+		},
+		DefaultMemberPermissions: json.NewNullablePtr(discord.PermissionManageGuild),
+		Contexts:                 []discord.InteractionContextType{discord.InteractionContextTypeGuild},
+	}
+}
+
+// PreviewHandler runs the text through the pipeline messages are read with, and shows the result to the invoker.
+// Nothing is synthesized, so rules like the markdown and length settings can be checked without a session.
+func PreviewHandler(presetResolver preset.PresetResolver, settingsRepository settings.GuildSettingsRepository, members *session.MemberResolver, vrs *i18n.VoiceResources) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		tr := localized(e.Ctx)
+		data := e.SlashCommandInteractionData()
+		text := data.String("text")
+		guildID := *e.GuildID()
+
+		ctx, cancel := context.WithTimeout(e.Ctx, 5*time.Second)
+		defer cancel()
+
+		guildSettings, err := settings.FindOrDefault(ctx, settingsRepository, guildID)
+		if err != nil {
+			return e.CreateMessage(classifyError(e.Ctx, fmt.Errorf("failed to fetch guild settings: %w", err), tr).Message())
+		}
+
+		var roleIDs []snowflake.ID
+		if member := e.Member(); member != nil {
+			roleIDs = member.RoleIDs
+		}
+		resolved, err := presetResolver.ResolveContext(ctx, preset.ResolutionContext{
+			GuildID:   guildID,
+			ChannelID: e.Channel().ID(),
+			UserID:    e.User().ID,
+			RoleIDs:   roleIDs,
+		})
+		if err != nil {
+			return e.CreateMessage(classifyError(e.Ctx, fmt.Errorf("failed to resolve preset: %w", err), tr).Message())
+		}
+		resolved, text = session.ApplyVoiceTag(presetResolver, guildSettings, resolved, text)
+
+		// the preview reads like the session does today, including the voice pack that is active.
+		now := time.Now()
+		vr, ok := vrs.GetThemed(resolved.Language, guildSettings.VoicePacks, now.In(guildSettings.Location()))
+		if !ok {
+			slog.WarnContext(e.Ctx, "Voice resources not found for locale", "locale", resolved.Language)
+		}
+
+		mentions := session.MentionNames(e.Client(), members, guildSettings, mentionedUsers(e, data, text))
+		transformed := session.TransformMessage(text, guildSettings, vr, mentions, now)
+
+		embed := message.BuildPreviewEmbed(tr).
+			AddField(tr.Commands.Preview.Preset, fmt.Sprintf("`%s`", resolved.Identifier), true)
+		if len(transformed.Segments) == 0 {
+			embed.SetDescription(tr.Commands.Preview.Empty)
+		} else {
+			embed.SetDescription(fmt.Sprintf("**%s**\n%s", tr.Commands.Preview.Segments, previewSegments(transformed.Segments)))
+		}
+		if transformed.Truncated {
+			embed.SetFooterText(tr.Commands.Preview.Truncated)
+		}
+
+		return e.CreateMessage(discord.NewMessageCreateBuilder().
+			AddEmbeds(embed.Build()).
+			SetEphemeral(true).
+			Build())
+	}
+}
+
+// mentionedUsers returns the users mentioned in the text. Unlike messages, the text of an option has no mentions attached,
+// so they are looked up in the resolved data of the interaction and the caches. Unknown users are left as mentions.
+func mentionedUsers(e *handler.CommandEvent, data discord.SlashCommandInteractionData, text string) []discord.User {
+	var users []discord.User
+	for _, match := range discord.MentionTypeUser.FindAllStringSubmatch(text, -1) {
+		userID, err := snowflake.Parse(match[1])
+		if err != nil {
+			continue
+		}
+		if user, ok := data.Resolved.Users[userID]; ok {
+			users = append(users, user)
+		} else if member, ok := e.Client().Caches().Member(*e.GuildID(), userID); ok {
+			users = append(users, member.User)
+		}
+	}
+	return users
+}
+
+// previewSegments formats the segments one per line in a code block, so that the exact text is shown.
+func previewSegments(segments []string) string {
+	preview := strings.Join(segments, "\n")
+	if runes := []rune(preview); len(runes) > maxPreviewLength {
+		preview = string(runes[:maxPreviewLength]) + "…"
+	}
+	// a code fence in the text would end the block early.
+	preview = strings.ReplaceAll(preview, "```", "`\u200b``")
+	return "```\n" + preview + "\n```"
+}
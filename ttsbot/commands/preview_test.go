@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreviewSegments(t *testing.T) {
+	if got, want := previewSegments([]string{"Hello.", "World."}), "```\nHello.\nWorld.\n```"; got != want {
+		t.Errorf("previewSegments() = %q, want %q", got, want)
+	}
+
+	got := previewSegments([]string{"before ``` after"})
+	if strings.Count(got, "```") != 2 {
+		t.Errorf("a code fence in the text must not end the block: %q", got)
+	}
+
+	got = previewSegments([]string{strings.Repeat("a", maxPreviewLength+10)})
+	if !strings.Contains(got, "…") || len([]rune(got)) > maxPreviewLength+10 {
+		t.Errorf("long previews must be cut, got %d characters", len([]rune(got)))
+	}
+}
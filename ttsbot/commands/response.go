@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+// responseBuilder creates the responses of commands whose visibility the guild can choose.
+// Messages that only concern the invoker, e.g. invalid input, stay ephemeral regardless of the guild setting.
+type responseBuilder struct {
+	ephemeral bool
+}
+
+func newResponseBuilder(guildSettings settings.GuildSettings) responseBuilder {
+	return responseBuilder{ephemeral: guildSettings.EphemeralResponses}
+}
+
+// findResponseBuilder looks up the guild settings for the response builder.
+// Outside of guilds, or if the settings cannot be fetched, responses are visible to everyone.
+func findResponseBuilder(settingsRepository settings.GuildSettingsRepository, guildID snowflake.ID) responseBuilder {
+	if guildID == 0 {
+		return responseBuilder{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	guildSettings, err := settings.FindOrDefault(ctx, settingsRepository, guildID)
+	if err != nil {
+		slog.Warn("failed to fetch guild settings, responding publicly", "error", err, "guildID", guildID)
+		return responseBuilder{}
+	}
+	return newResponseBuilder(guildSettings)
+}
+
+// Message starts a response message.
+func (r responseBuilder) Message() *discord.MessageCreateBuilder {
+	return discord.NewMessageCreateBuilder().SetEphemeral(r.ephemeral)
+}
@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindResponseBuilder(t *testing.T) {
+	repo := settings.NewMemoryGuildSettingsRepository()
+
+	guildSettings := settings.DefaultGuildSettings(12345)
+	guildSettings.EphemeralResponses = true
+	require.NoError(t, repo.Save(context.Background(), guildSettings))
+
+	isEphemeral := func(response responseBuilder) bool {
+		return response.Message().Build().Flags.Has(discord.MessageFlagEphemeral)
+	}
+
+	require.True(t, isEphemeral(findResponseBuilder(repo, 12345)))
+	require.False(t, isEphemeral(findResponseBuilder(repo, 67890)), "guilds without settings respond publicly")
+	require.False(t, isEphemeral(findResponseBuilder(repo, 0)), "responses outside of guilds are public")
+}
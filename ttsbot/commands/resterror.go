@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+)
+
+// JSON error codes of discord that are explained to the invoker.
+// See https://discord.com/developers/docs/topics/opcodes-and-status-codes#json.
+const (
+	errorCodeUnknownVoiceState  rest.JSONErrorCode = 10065
+	errorCodeMissingPermissions rest.JSONErrorCode = 50013
+)
+
+// restErrorDescriptions maps the known error codes to the localized descriptions shown to the invoker.
+var restErrorDescriptions = map[rest.JSONErrorCode]func(tr i18n.TextResource) string{
+	errorCodeUnknownVoiceState: func(tr i18n.TextResource) string {
+		return tr.Commands.Generic.ErrorNotInVoiceChannel
+	},
+	errorCodeMissingPermissions: func(tr i18n.TextResource) string {
+		return tr.Commands.Generic.ErrorInsufficientPermissions
+	},
+}
+
+// classifyError converts an error of a discord request into a FriendlyError.
+// Errors with a known code are explained to the invoker. Any other error is logged with the correlation ID of the context,
+// and the invoker is shown the ID only, so that a report can be matched to the log without leaking details.
+func classifyError(ctx context.Context, err error, tr i18n.TextResource) *FriendlyError {
+	var restErr rest.Error
+	if errors.As(err, &restErr) {
+		if description, ok := restErrorDescriptions[restErr.Code]; ok {
+			return newFriendlyError(err, discord.NewMessageCreateBuilder().
+				AddEmbeds(message.BuildErrorEmbed(tr).
+					SetDescription(description(tr)).
+					Build()).
+				Build())
+		}
+	}
+
+	ctx, errorID := correlate(ctx)
+	slog.ErrorContext(ctx, "Unexpected error", slog.Any("err", err))
+	return newFriendlyError(err, discord.NewMessageCreateBuilder().
+		AddEmbeds(message.BuildErrorEmbed(tr).
+			SetDescriptionf(tr.Commands.Generic.ErrorUnexpected, errorID).
+			Build()).
+		Build())
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/disgoorg/disgo/rest"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/logging"
+)
+
+func TestClassifyError(t *testing.T) {
+	trs, err := i18n.LoadTextResources("../../locales/text/", "en-US")
+	if err != nil {
+		t.Fatalf("failed to load text resources: %v", err)
+	}
+	tr := trs.GetFallback()
+
+	description := func(friendlyErr *FriendlyError) string {
+		embeds := friendlyErr.Message().Embeds
+		if len(embeds) != 1 {
+			t.Fatalf("expected one embed, got %d", len(embeds))
+		}
+		return embeds[0].Description
+	}
+
+	t.Run("known code", func(t *testing.T) {
+		cause := fmt.Errorf("failed to get voice state: %w", rest.Error{Code: errorCodeUnknownVoiceState})
+		friendlyErr := classifyError(context.Background(), cause, tr)
+		if got := description(friendlyErr); got != tr.Commands.Generic.ErrorNotInVoiceChannel {
+			t.Errorf("unexpected description %q", got)
+		}
+		if !errors.Is(friendlyErr, cause) {
+			t.Error("expected the friendly error to wrap the cause")
+		}
+	})
+
+	t.Run("unknown error", func(t *testing.T) {
+		got := description(classifyError(context.Background(), errors.New("connection reset"), tr))
+		if strings.Contains(got, "connection reset") {
+			t.Errorf("the cause must not be shown to the invoker: %q", got)
+		}
+		prefix, _, _ := strings.Cut(tr.Commands.Generic.ErrorUnexpected, "%[1]s")
+		if !strings.HasPrefix(got, prefix) {
+			t.Errorf("expected the generic description, got %q", got)
+		}
+	})
+
+	t.Run("correlation ID", func(t *testing.T) {
+		ctx := logging.WithCorrelationID(context.Background(), "ab12cd34")
+		got := description(classifyError(ctx, errors.New("connection reset"), tr))
+		if !strings.Contains(got, "ab12cd34") {
+			t.Errorf("expected the correlation ID of the context to be shown, got %q", got)
+		}
+	})
+}
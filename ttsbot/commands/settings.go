@@ -0,0 +1,1236 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+	"github.com/disgoorg/json"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+func settingsCmd(trs *i18n.TextResources) discord.SlashCommandCreate {
+	policyChoices := make([]discord.ApplicationCommandOptionChoiceString, 0, len(settings.TakeoverPolicies))
+	fallback := trs.GetFallback()
+	for _, policy := range settings.TakeoverPolicies {
+		policyChoices = append(policyChoices, discord.ApplicationCommandOptionChoiceString{
+			Name: message.TakeoverPolicyName(policy, fallback),
+			NameLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+				return message.TakeoverPolicyName(policy, tr)
+			}),
+			Value: policy.String(),
+		})
+	}
+	nameSourceChoices := make([]discord.ApplicationCommandOptionChoiceString, 0, len(settings.NameSources))
+	for _, source := range settings.NameSources {
+		nameSourceChoices = append(nameSourceChoices, discord.ApplicationCommandOptionChoiceString{
+			Name: message.NameSourceName(source, fallback),
+			NameLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+				return message.NameSourceName(source, tr)
+			}),
+			Value: source.String(),
+		})
+	}
+	codeBlockModeChoices := make([]discord.ApplicationCommandOptionChoiceString, 0, len(settings.CodeBlockModes))
+	for _, mode := range settings.CodeBlockModes {
+		codeBlockModeChoices = append(codeBlockModeChoices, discord.ApplicationCommandOptionChoiceString{
+			Name: message.CodeBlockModeName(mode, fallback),
+			NameLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+				return message.CodeBlockModeName(mode, tr)
+			}),
+			Value: mode.String(),
+		})
+	}
+	bargeInChoices := make([]discord.ApplicationCommandOptionChoiceString, 0, len(settings.BargeIns))
+	for _, bargeIn := range settings.BargeIns {
+		bargeInChoices = append(bargeInChoices, discord.ApplicationCommandOptionChoiceString{
+			Name: message.BargeInName(bargeIn, fallback),
+			NameLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+				return message.BargeInName(bargeIn, tr)
+			}),
+			Value: bargeIn.String(),
+		})
+	}
+	announcementKeyChoices := make([]discord.ApplicationCommandOptionChoiceString, 0, len(settings.AnnouncementKeys))
+	for _, key := range settings.AnnouncementKeys {
+		announcementKeyChoices = append(announcementKeyChoices, discord.ApplicationCommandOptionChoiceString{
+			Name: message.AnnouncementKeyName(key, fallback),
+			NameLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+				return message.AnnouncementKeyName(key, tr)
+			}),
+			Value: key.String(),
+		})
+	}
+
+	return discord.SlashCommandCreate{
+		Name:        "settings",
+		Description: "Manage guild settings",
+		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+			return tr.Commands.Settings.Description
+		}),
+		DefaultMemberPermissions: json.NewNullablePtr(discord.PermissionManageGuild),
+		Contexts:                 []discord.InteractionContextType{discord.InteractionContextTypeGuild},
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "show",
+				Description: "Show the current guild settings",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.Show.Description
+				}),
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "takeover",
+				Description: "Set what happens when /join is run for another voice channel",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.Takeover.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionString{
+						Name:        "policy",
+						Description: "What to do with the running session",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Takeover.Policy
+						}),
+						Required: true,
+						Choices:  policyChoices,
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "voice-activity",
+				Description: "Set whether to announce streaming and stage changes",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.VoiceActivity.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionBool{
+						Name:        "enabled",
+						Description: "Whether to announce",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.VoiceActivity.Enabled
+						}),
+						Required: true,
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "announcements",
+				Description: "Set which announcements are spoken",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.Announcements.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionBool{
+						Name:        "join",
+						Description: "Announce members joining the voice channel",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Announcements.Join
+						}),
+					},
+					discord.ApplicationCommandOptionBool{
+						Name:        "leave",
+						Description: "Announce members leaving the voice channel",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Announcements.Leave
+						}),
+					},
+					discord.ApplicationCommandOptionBool{
+						Name:        "launch",
+						Description: "Announce that text-to-speech is ready",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Announcements.Launch
+						}),
+					},
+					discord.ApplicationCommandOptionBool{
+						Name:        "farewell",
+						Description: "Say farewell before leaving the voice channel",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Announcements.Farewell
+						}),
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "skip-reaction",
+				Description: "Set the emoji reacted to messages that were not read",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.SkipReaction.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionString{
+						Name:        "emoji",
+						Description: "The emoji to react with, leave empty to disable",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.SkipReaction.Emoji
+						}),
+						MaxLength: json.Ptr(255),
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "code-block",
+				Description: "Set how code blocks are read",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.CodeBlock.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionString{
+						Name:        "mode",
+						Description: "How to read code blocks",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.CodeBlock.Mode
+						}),
+						Required: true,
+						Choices:  codeBlockModeChoices,
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "strikethrough",
+				Description: "Set whether struck-through text is skipped",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.Strikethrough.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionBool{
+						Name:        "omit",
+						Description: "Whether to skip struck-through text",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Strikethrough.Omit
+						}),
+						Required: true,
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "markdown",
+				Description: "Set whether quotes and headings are announced",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.Markdown.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionBool{
+						Name:        "announce",
+						Description: "Whether to announce quotes and headings",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Markdown.Announce
+						}),
+						Required: true,
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "timezone",
+				Description: "Set the timezone timestamps are read in",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.Timezone.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionString{
+						Name:        "timezone",
+						Description: "IANA timezone name, e.g. Asia/Tokyo",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Timezone.Timezone
+						}),
+						Required:  true,
+						MaxLength: json.Ptr(64),
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "speaker-name",
+				Description: "Set how speaker names are read",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.SpeakerName.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionString{
+						Name:        "source",
+						Description: "Which name to read",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.SpeakerName.Source
+						}),
+						Choices: nameSourceChoices,
+					},
+					discord.ApplicationCommandOptionBool{
+						Name:        "strip-decorations",
+						Description: "Remove emojis and decorations from names",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.SpeakerName.StripDecorations
+						}),
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "command-prefix",
+				Description: "Set the prefix of text commands in the reading channel",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.CommandPrefix.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionString{
+						Name:        "prefix",
+						Description: "The prefix, e.g. ;, leave empty to disable",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.CommandPrefix.Prefix
+						}),
+						MaxLength: json.Ptr(settings.MaxCommandPrefixLength),
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "voice-state",
+				Description: "Set how the bot appears in the voice channel",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.VoiceState.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionBool{
+						Name:        "deaf",
+						Description: "Deafen the bot, so it visibly does not listen",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.VoiceState.Deaf
+						}),
+					},
+					discord.ApplicationCommandOptionBool{
+						Name:        "mute",
+						Description: "Show the bot as muted; it still speaks",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.VoiceState.Mute
+						}),
+					},
+					discord.ApplicationCommandOptionBool{
+						Name:        "yield-to-bots",
+						Description: "Hold back reading while another bot, e.g. a music bot, is playing",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.VoiceState.YieldToBots
+						}),
+					},
+					discord.ApplicationCommandOptionString{
+						Name:        "barge-in",
+						Description: "What reading does while a member speaks",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.VoiceState.BargeIn
+						}),
+						Choices: bargeInChoices,
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "max-length",
+				Description: "Set the maximum number of characters read from a message",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.MaxLength.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionInt{
+						Name:        "length",
+						Description: "The maximum number of characters",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.MaxLength.Length
+						}),
+						Required: true,
+						MinValue: json.Ptr(settings.MinMaxMessageLength),
+						MaxValue: json.Ptr(settings.MaxMaxMessageLength),
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "webhook",
+				Description: "Send events of this server to a webhook",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.Webhook.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionString{
+						Name:        "url",
+						Description: "The HTTPS URL, e.g. a Discord webhook, leave empty to remove",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Webhook.URL
+						}),
+						MaxLength: json.Ptr(settings.MaxWebhookURLLength),
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "ephemeral",
+				Description: "Set whether responses to /preset and /settings are only shown to the invoker",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.Ephemeral.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionBool{
+						Name:        "enabled",
+						Description: "Whether to show responses only to the invoker",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Ephemeral.Enabled
+						}),
+						Required: true,
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "voice-tags",
+				Description: "Set whether a tag like [en] or [voice:anna] at the start of a message changes its voice",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.VoiceTags.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionBool{
+						Name:        "enabled",
+						Description: "Whether to read tagged messages with the voice of the tag",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.VoiceTags.Enabled
+						}),
+						Required: true,
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "code-switch",
+				Description: "Set whether words in another script, e.g. English in Japanese, are read in their language",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.CodeSwitch.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionBool{
+						Name:        "enabled",
+						Description: "Whether to read each script of a message with a voice speaking its language",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.CodeSwitch.Enabled
+						}),
+						Required: true,
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "asides",
+				Description: "Set whether text in parentheses or spoilers is read softly, like an aside",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.Asides.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionBool{
+						Name:        "enabled",
+						Description: "Whether to read asides softly",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Asides.Enabled
+						}),
+						Required: true,
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "transcript",
+				Description: "Post what the bot speaks into a text channel",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.Transcript.Description
+				}),
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionChannel{
+						Name:        "channel",
+						Description: "The channel to post into, leave empty to stop posting",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Transcript.Channel
+						}),
+						ChannelTypes: []discord.ChannelType{discord.ChannelTypeGuildText, discord.ChannelTypeGuildVoice},
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommandGroup{
+				Name:        "silent-role",
+				Description: "Manage roles whose joins and leaves are not announced",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.SilentRole.Description
+				}),
+				Options: []discord.ApplicationCommandOptionSubCommand{
+					{
+						Name:        "add",
+						Description: "Stop announcing joins and leaves of members with the role",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.SilentRole.Add.Description
+						}),
+						Options: []discord.ApplicationCommandOption{
+							silentRoleOption(trs),
+						},
+					},
+					{
+						Name:        "remove",
+						Description: "Announce joins and leaves of members with the role again",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.SilentRole.Remove.Description
+						}),
+						Options: []discord.ApplicationCommandOption{
+							silentRoleOption(trs),
+						},
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommandGroup{
+				Name:        "voice-pack",
+				Description: "Manage themed voice packs, e.g. seasonal greetings",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.VoicePack.Description
+				}),
+				Options: []discord.ApplicationCommandOptionSubCommand{
+					{
+						Name:        "enable",
+						Description: "Speak the voice pack while its date range lasts",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.VoicePack.Enable.Description
+						}),
+						Options: []discord.ApplicationCommandOption{
+							voicePackOption(trs),
+						},
+					},
+					{
+						Name:        "disable",
+						Description: "Stop speaking the voice pack",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.VoicePack.Disable.Description
+						}),
+						Options: []discord.ApplicationCommandOption{
+							voicePackOption(trs),
+						},
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommandGroup{
+				Name:        "spell-out",
+				Description: "Spell out IDs, invite codes and other confusing tokens character by character",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.SpellOut.Description
+				}),
+				Options: []discord.ApplicationCommandOptionSubCommand{
+					{
+						Name:        "enable",
+						Description: "Spell out IDs, invite codes, hex strings and tokens matching the patterns",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.SpellOut.Enable.Description
+						}),
+					},
+					{
+						Name:        "disable",
+						Description: "Stop spelling out tokens",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.SpellOut.Disable.Description
+						}),
+					},
+					{
+						Name:        "add",
+						Description: "Also spell out tokens matching a pattern",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.SpellOut.Add.Description
+						}),
+						Options: []discord.ApplicationCommandOption{
+							spellOutPatternOption(trs),
+						},
+					},
+					{
+						Name:        "remove",
+						Description: "Stop spelling out tokens matching a pattern",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.SpellOut.Remove.Description
+						}),
+						Options: []discord.ApplicationCommandOption{
+							spellOutPatternOption(trs),
+						},
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommandGroup{
+				Name:        "announcement",
+				Description: "Override the text of announcements",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Settings.Announcement.Description
+				}),
+				Options: []discord.ApplicationCommandOptionSubCommand{
+					{
+						Name:        "set",
+						Description: "Speak custom text for the announcement",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Announcement.Set.Description
+						}),
+						Options: []discord.ApplicationCommandOption{
+							announcementKeyOption(trs, announcementKeyChoices),
+							discord.ApplicationCommandOptionString{
+								Name:        "text",
+								Description: "The text; {name} is replaced with the names in join and leave announcements",
+								DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+									return tr.Commands.Settings.Announcement.Set.Text
+								}),
+								Required:  true,
+								MaxLength: json.Ptr(settings.MaxAnnouncementTemplateLength),
+							},
+						},
+					},
+					{
+						Name:        "reset",
+						Description: "Restore the default text of the announcement",
+						DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+							return tr.Commands.Settings.Announcement.Reset.Description
+						}),
+						Options: []discord.ApplicationCommandOption{
+							announcementKeyOption(trs, announcementKeyChoices),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func announcementKeyOption(trs *i18n.TextResources, choices []discord.ApplicationCommandOptionChoiceString) discord.ApplicationCommandOptionString {
+	return discord.ApplicationCommandOptionString{
+		Name:        "key",
+		Description: "The announcement to override",
+		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+			return tr.Commands.Settings.Announcement.Key
+		}),
+		Required: true,
+		Choices:  choices,
+	}
+}
+
+func voicePackOption(trs *i18n.TextResources) discord.ApplicationCommandOptionString {
+	return discord.ApplicationCommandOptionString{
+		Name:        "name",
+		Description: "The ID of the voice pack",
+		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+			return tr.Commands.Settings.VoicePack.Name
+		}),
+		Required:  true,
+		MaxLength: json.Ptr(settings.MaxVoicePackIDLength),
+	}
+}
+
+func spellOutPatternOption(trs *i18n.TextResources) discord.ApplicationCommandOptionString {
+	return discord.ApplicationCommandOptionString{
+		Name:        "pattern",
+		Description: "A regular expression; if it has a group, only the group is spelled out",
+		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+			return tr.Commands.Settings.SpellOut.Pattern
+		}),
+		Required:  true,
+		MaxLength: json.Ptr(settings.MaxSpellOutPatternLength),
+	}
+}
+
+func silentRoleOption(trs *i18n.TextResources) discord.ApplicationCommandOptionRole {
+	return discord.ApplicationCommandOptionRole{
+		Name:        "role",
+		Description: "The role to configure",
+		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+			return tr.Commands.Settings.SilentRole.Role
+		}),
+		Required: true,
+	}
+}
+
+func SettingsHandler(settingsRepository settings.GuildSettingsRepository, vrs *i18n.VoiceResources) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		tr := localized(e.Ctx)
+
+		data := e.SlashCommandInteractionData()
+		guildID := *e.GuildID()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		guildSettings, err := settings.FindOrDefault(ctx, settingsRepository, guildID)
+		if err != nil {
+			slog.ErrorContext(e.Ctx, "failed to fetch guild settings", "error", err, "guildID", guildID)
+			return e.CreateMessage(discord.NewMessageCreateBuilder().
+				AddEmbeds(message.BuildErrorEmbed(tr).
+					SetDescription(tr.Commands.Settings.ErrorFetch).
+					Build()).
+				Build())
+		}
+		response := newResponseBuilder(guildSettings)
+
+		if data.SubCommandGroupName != nil && *data.SubCommandGroupName == "silent-role" {
+			role := data.Role("role")
+			var description string
+			switch *data.SubCommandName {
+			case "add":
+				if !slices.Contains(guildSettings.SilentRoleIDs, role.ID) {
+					guildSettings.SilentRoleIDs = append(guildSettings.SilentRoleIDs, role.ID)
+				}
+				description = tr.Commands.Settings.SilentRole.Add.Success
+			case "remove":
+				guildSettings.SilentRoleIDs = slices.DeleteFunc(guildSettings.SilentRoleIDs, func(roleID snowflake.ID) bool {
+					return roleID == role.ID
+				})
+				description = tr.Commands.Settings.SilentRole.Remove.Success
+			}
+
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(description, discord.RoleMention(role.ID)).
+					Build()).
+				SetAllowedMentions(&discord.AllowedMentions{}).
+				Build())
+		}
+
+		if data.SubCommandGroupName != nil && *data.SubCommandGroupName == "voice-pack" {
+			pack, ok := vrs.Pack(data.String("name"))
+			if !ok {
+				available := tr.Generic.Settings.None
+				if packIDs := vrs.PackIDs(); len(packIDs) > 0 {
+					available = strings.Join(packIDs, ", ")
+				}
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescriptionf(tr.Commands.Settings.VoicePack.ErrorUnknown, available).
+						Build()).
+					SetEphemeral(true).
+					Build())
+			}
+
+			var description string
+			switch *data.SubCommandName {
+			case "enable":
+				if !slices.Contains(guildSettings.VoicePacks, pack.ID) {
+					guildSettings.VoicePacks = append(guildSettings.VoicePacks, pack.ID)
+				}
+				description = fmt.Sprintf(tr.Commands.Settings.VoicePack.Enable.Success, pack.Name, pack.Start, pack.End)
+			case "disable":
+				guildSettings.VoicePacks = slices.DeleteFunc(guildSettings.VoicePacks, func(packID string) bool {
+					return packID == pack.ID
+				})
+				description = fmt.Sprintf(tr.Commands.Settings.VoicePack.Disable.Success, pack.Name)
+			}
+
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescription(description).
+					Build()).
+				Build())
+		}
+
+		if data.SubCommandGroupName != nil && *data.SubCommandGroupName == "spell-out" {
+			pattern := strings.TrimSpace(data.String("pattern"))
+			var description string
+			switch *data.SubCommandName {
+			case "enable":
+				guildSettings.SpellOut = true
+				description = tr.Commands.Settings.SpellOut.Enable.Success
+			case "disable":
+				guildSettings.SpellOut = false
+				description = tr.Commands.Settings.SpellOut.Disable.Success
+			case "add":
+				if err := settings.ValidateSpellOutPattern(pattern); err != nil {
+					return e.CreateMessage(response.Message().
+						AddEmbeds(message.BuildErrorEmbed(tr).
+							SetDescriptionf(tr.Commands.Settings.SpellOut.ErrorInvalid, settings.MaxSpellOutPatternLength).
+							Build()).
+						SetEphemeral(true).
+						Build())
+				}
+				if !slices.Contains(guildSettings.SpellOutPatterns, pattern) {
+					if len(guildSettings.SpellOutPatterns) >= settings.MaxSpellOutPatterns {
+						return e.CreateMessage(response.Message().
+							AddEmbeds(message.BuildErrorEmbed(tr).
+								SetDescriptionf(tr.Commands.Settings.SpellOut.ErrorTooMany, settings.MaxSpellOutPatterns).
+								Build()).
+							SetEphemeral(true).
+							Build())
+					}
+					guildSettings.SpellOutPatterns = append(guildSettings.SpellOutPatterns, pattern)
+				}
+				description = fmt.Sprintf(tr.Commands.Settings.SpellOut.Add.Success, "`"+pattern+"`")
+			case "remove":
+				guildSettings.SpellOutPatterns = slices.DeleteFunc(guildSettings.SpellOutPatterns, func(p string) bool {
+					return p == pattern
+				})
+				description = fmt.Sprintf(tr.Commands.Settings.SpellOut.Remove.Success, "`"+pattern+"`")
+			}
+
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescription(description).
+					Build()).
+				Build())
+		}
+
+		if data.SubCommandGroupName != nil && *data.SubCommandGroupName == "announcement" {
+			key := settings.AnnouncementKey(data.String("key"))
+			// the map is cloned, as it may be shared with the defaults of the policy.
+			templates := maps.Clone(guildSettings.AnnouncementTemplates)
+			if templates == nil {
+				templates = make(map[settings.AnnouncementKey]string)
+			}
+			var description string
+			switch *data.SubCommandName {
+			case "set":
+				text := strings.TrimSpace(data.String("text"))
+				if err := settings.ValidateAnnouncementTemplate(key, text); err != nil {
+					return e.CreateMessage(response.Message().
+						AddEmbeds(message.BuildErrorEmbed(tr).
+							SetDescriptionf(tr.Commands.Settings.Announcement.Set.ErrorInvalid, settings.MaxAnnouncementTemplateLength).
+							Build()).
+						SetEphemeral(true).
+						Build())
+				}
+				templates[key] = text
+				description = fmt.Sprintf(tr.Commands.Settings.Announcement.Set.Success, message.AnnouncementKeyName(key, tr), text)
+			case "reset":
+				delete(templates, key)
+				description = fmt.Sprintf(tr.Commands.Settings.Announcement.Reset.Success, message.AnnouncementKeyName(key, tr))
+			}
+			guildSettings.AnnouncementTemplates = templates
+
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescription(description).
+					Build()).
+				SetAllowedMentions(&discord.AllowedMentions{}).
+				Build())
+		}
+
+		switch *data.SubCommandName {
+		case "show":
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSettingsEmbed(guildSettings, tr).Build()).
+				Build())
+
+		case "takeover":
+			guildSettings.TakeoverPolicy = settings.TakeoverPolicy(data.String("policy"))
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(tr.Commands.Settings.Takeover.Success, message.TakeoverPolicyName(guildSettings.TakeoverPolicy, tr)).
+					Build()).
+				Build())
+
+		case "voice-activity":
+			guildSettings.AnnounceVoiceActivity = data.Bool("enabled")
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(tr.Commands.Settings.VoiceActivity.Success, message.EnabledName(guildSettings.AnnounceVoiceActivity, tr)).
+					Build()).
+				Build())
+		case "announcements":
+			if join, ok := data.OptBool("join"); ok {
+				guildSettings.AnnounceJoin = join
+			}
+			if leave, ok := data.OptBool("leave"); ok {
+				guildSettings.AnnounceLeave = leave
+			}
+			if launch, ok := data.OptBool("launch"); ok {
+				guildSettings.AnnounceLaunch = launch
+			}
+			if farewell, ok := data.OptBool("farewell"); ok {
+				guildSettings.AnnounceFarewell = farewell
+			}
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSettingsEmbed(guildSettings, tr).Build()).
+				Build())
+		case "skip-reaction":
+			guildSettings.SkipReaction = normalizeReactionEmoji(data.String("emoji"))
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(tr.Commands.Settings.SkipReaction.Success, message.SkipReactionName(guildSettings.SkipReaction, tr)).
+					Build()).
+				Build())
+		case "code-block":
+			guildSettings.CodeBlockMode = settings.CodeBlockMode(data.String("mode"))
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(tr.Commands.Settings.CodeBlock.Success, message.CodeBlockModeName(guildSettings.CodeBlockMode, tr)).
+					Build()).
+				Build())
+		case "strikethrough":
+			guildSettings.OmitStrikethrough = data.Bool("omit")
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(tr.Commands.Settings.Strikethrough.Success, message.EnabledName(guildSettings.OmitStrikethrough, tr)).
+					Build()).
+				Build())
+		case "markdown":
+			guildSettings.AnnounceMarkdown = data.Bool("announce")
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(tr.Commands.Settings.Markdown.Success, message.EnabledName(guildSettings.AnnounceMarkdown, tr)).
+					Build()).
+				Build())
+		case "timezone":
+			timezone := strings.TrimSpace(data.String("timezone"))
+			if _, err := time.LoadLocation(timezone); err != nil || timezone == "" {
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescriptionf(tr.Commands.Settings.Timezone.ErrorInvalid, timezone).
+						Build()).
+					SetEphemeral(true).
+					Build())
+			}
+			guildSettings.Timezone = timezone
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(tr.Commands.Settings.Timezone.Success, guildSettings.Timezone).
+					Build()).
+				Build())
+		case "speaker-name":
+			if source, ok := data.OptString("source"); ok {
+				guildSettings.NameSource = settings.NameSource(source)
+			}
+			if strip, ok := data.OptBool("strip-decorations"); ok {
+				guildSettings.StripNameDecorations = strip
+			}
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSettingsEmbed(guildSettings, tr).Build()).
+				Build())
+		case "command-prefix":
+			prefix := data.String("prefix")
+			if !settings.IsValidCommandPrefix(prefix) {
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescriptionf(tr.Commands.Settings.CommandPrefix.ErrorInvalid, settings.MaxCommandPrefixLength).
+						Build()).
+					SetEphemeral(true).
+					Build())
+			}
+			guildSettings.CommandPrefix = prefix
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(tr.Commands.Settings.CommandPrefix.Success, message.CommandPrefixName(guildSettings.CommandPrefix, tr)).
+					Build()).
+				Build())
+		case "voice-state":
+			if deaf, ok := data.OptBool("deaf"); ok {
+				guildSettings.SelfDeaf = deaf
+			}
+			if mute, ok := data.OptBool("mute"); ok {
+				guildSettings.SelfMute = mute
+			}
+			if yield, ok := data.OptBool("yield-to-bots"); ok {
+				guildSettings.YieldToBots = yield
+				// the bot has to hear the other bots to yield to them, so it is undeafened unless told otherwise.
+				if _, ok := data.OptBool("deaf"); yield && !ok {
+					guildSettings.SelfDeaf = false
+				}
+			}
+			if bargeIn, ok := data.OptString("barge-in"); ok {
+				guildSettings.BargeIn = settings.BargeIn(bargeIn)
+				// the bot has to hear the members to make way for them, likewise.
+				if _, ok := data.OptBool("deaf"); guildSettings.BargeIn != settings.BargeInOff && !ok {
+					guildSettings.SelfDeaf = false
+				}
+			}
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			// apply the voice state right away if the bot is in a voice channel of the guild.
+			if conn := e.Client().VoiceManager().GetConn(guildID); conn != nil && conn.ChannelID() != nil {
+				if err := e.Client().UpdateVoiceState(ctx, guildID, conn.ChannelID(), guildSettings.SelfMute, guildSettings.SelfDeaf); err != nil {
+					slog.WarnContext(e.Ctx, "failed to update voice state", "error", err, "guildID", guildID)
+				}
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSettingsEmbed(guildSettings, tr).Build()).
+				Build())
+		case "max-length":
+			guildSettings.MaxMessageLength = data.Int("length")
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(tr.Commands.Settings.MaxLength.Success, fmt.Sprintf(tr.Generic.Settings.Characters, guildSettings.MaxMessageLength)).
+					Build()).
+				Build())
+		case "ephemeral":
+			guildSettings.EphemeralResponses = data.Bool("enabled")
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			// the confirmation already follows the new setting.
+			return e.CreateMessage(newResponseBuilder(guildSettings).Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(tr.Commands.Settings.Ephemeral.Success, message.EnabledName(guildSettings.EphemeralResponses, tr)).
+					Build()).
+				Build())
+		case "voice-tags":
+			guildSettings.VoiceTags = data.Bool("enabled")
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(tr.Commands.Settings.VoiceTags.Success, message.EnabledName(guildSettings.VoiceTags, tr)).
+					Build()).
+				Build())
+		case "code-switch":
+			guildSettings.CodeSwitch = data.Bool("enabled")
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(tr.Commands.Settings.CodeSwitch.Success, message.EnabledName(guildSettings.CodeSwitch, tr)).
+					Build()).
+				Build())
+		case "asides":
+			guildSettings.SoftenAsides = data.Bool("enabled")
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescriptionf(tr.Commands.Settings.Asides.Success, message.EnabledName(guildSettings.SoftenAsides, tr)).
+					Build()).
+				Build())
+		case "transcript":
+			guildSettings.TranscriptChannelID = 0
+			if channel, ok := data.OptChannel("channel"); ok {
+				guildSettings.TranscriptChannelID = channel.ID
+			}
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					Build())
+			}
+
+			description := tr.Commands.Settings.Transcript.Removed
+			if guildSettings.TranscriptChannelID != 0 {
+				description = fmt.Sprintf(tr.Commands.Settings.Transcript.Success, discord.ChannelMention(guildSettings.TranscriptChannelID))
+			}
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescription(description).
+					Build()).
+				Build())
+		case "webhook":
+			// the URL usually contains a token, so it is neither shown to the channel nor logged.
+			webhookURL := strings.TrimSpace(data.String("url"))
+			if !settings.IsValidWebhookURL(webhookURL) {
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescriptionf(tr.Commands.Settings.Webhook.ErrorInvalid, settings.MaxWebhookURLLength).
+						Build()).
+					SetEphemeral(true).
+					Build())
+			}
+			guildSettings.WebhookURL = webhookURL
+			if err := settingsRepository.Save(ctx, guildSettings); err != nil {
+				slog.ErrorContext(e.Ctx, "failed to save guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(response.Message().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(saveErrorDescription(err, tr)).
+						Build()).
+					SetEphemeral(true).
+					Build())
+			}
+
+			description := tr.Commands.Settings.Webhook.Success
+			if webhookURL == "" {
+				description = tr.Commands.Settings.Webhook.Removed
+			}
+			return e.CreateMessage(response.Message().
+				AddEmbeds(message.BuildSuccessEmbed(tr).
+					SetDescription(description).
+					Build()).
+				SetEphemeral(true).
+				Build())
+		}
+
+		slog.ErrorContext(e.Ctx, "unknown settings command", "command", *data.SubCommandName)
+		return e.CreateMessage(response.Message().
+			SetContent("Developer Error: Unsupported subcommand").
+			Build())
+	}
+}
+
+// normalizeReactionEmoji converts a custom emoji mention such as "<:name:id>" or "<a:name:id>"
+// into the "name:id" form accepted by the reaction endpoint. Unicode emojis are returned as is.
+func normalizeReactionEmoji(emoji string) string {
+	emoji = strings.TrimSpace(emoji)
+	if !strings.HasPrefix(emoji, "<") || !strings.HasSuffix(emoji, ">") {
+		return emoji
+	}
+	emoji = strings.TrimSuffix(strings.TrimPrefix(emoji, "<"), ">")
+	emoji = strings.TrimPrefix(emoji, "a:")
+	return strings.TrimPrefix(emoji, ":")
+}
+
+// saveErrorDescription returns the description of an error saving guild settings.
+func saveErrorDescription(err error, tr i18n.TextResource) string {
+	if errors.Is(err, settings.ErrLimitExceeded) {
+		return tr.Commands.Settings.ErrorLimit
+	}
+	return tr.Commands.Settings.ErrorSave
+}
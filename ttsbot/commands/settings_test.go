@@ -0,0 +1,21 @@
+package commands
+
+import "testing"
+
+func TestNormalizeReactionEmoji(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"⏭️", "⏭️"},
+		{" ⏭️ ", "⏭️"},
+		{"<:skip:123456>", "skip:123456"},
+		{"<a:skip:123456>", "skip:123456"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeReactionEmoji(tt.input); got != tt.want {
+			t.Errorf("normalizeReactionEmoji(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/disgo/handler"
+	"github.com/disgoorg/json"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+// maxSelectOptions is the maximum number of options of a select menu.
+const maxSelectOptions = 25
+
+func setupCmd(trs *i18n.TextResources) discord.SlashCommandCreate {
+	return discord.SlashCommandCreate{
+		Name:        "setup",
+		Description: "Set up the bot for this server step by step",
+		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+			return tr.Commands.Setup.Description
+		}),
+		DefaultMemberPermissions: json.NewNullablePtr(discord.PermissionManageGuild),
+		Contexts:                 []discord.InteractionContextType{discord.InteractionContextTypeGuild},
+	}
+}
+
+// setupWizard builds the setup wizard from the current configuration of a guild.
+// Each select menu saves its value as soon as it is changed, so the wizard can be left at any time.
+type setupWizard struct {
+	presetRegistry     *preset.PresetRegistry
+	presetIDRepository preset.PresetIDRepository
+	restrictions       *preset.Restrictions
+	settingsRepository settings.GuildSettingsRepository
+}
+
+func (w setupWizard) components(ctx context.Context, guildID snowflake.ID, tr i18n.TextResource) ([]discord.ContainerComponent, error) {
+	guildSettings, err := settings.FindOrDefault(ctx, w.settingsRepository, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch guild settings: %w", err)
+	}
+	guildPresetID, err := w.presetIDRepository.Find(ctx, preset.ScopeGuild, guildID)
+	if err != nil && !errors.Is(err, preset.ErrNotFound) {
+		return nil, fmt.Errorf("failed to fetch guild preset: %w", err)
+	}
+
+	presets := w.restrictions.Filter(guildID, w.presetRegistry.List())
+	presetOptions := make([]discord.StringSelectMenuOption, 0, min(len(presets), maxSelectOptions))
+	for _, p := range presets[:min(len(presets), maxSelectOptions)] {
+		presetOptions = append(presetOptions, discord.NewStringSelectMenuOption(string(p.Identifier), string(p.Identifier)).
+			WithDescription(fmt.Sprintf("%s · %s", p.Language, p.VoiceName)).
+			WithDefault(p.Identifier == guildPresetID))
+	}
+
+	announcementOptions := []discord.StringSelectMenuOption{
+		discord.NewStringSelectMenuOption(tr.Generic.Settings.AnnounceJoin, "join").WithDefault(guildSettings.AnnounceJoin),
+		discord.NewStringSelectMenuOption(tr.Generic.Settings.AnnounceLeave, "leave").WithDefault(guildSettings.AnnounceLeave),
+		discord.NewStringSelectMenuOption(tr.Generic.Settings.AnnounceLaunch, "launch").WithDefault(guildSettings.AnnounceLaunch),
+		discord.NewStringSelectMenuOption(tr.Generic.Settings.AnnounceFarewell, "farewell").WithDefault(guildSettings.AnnounceFarewell),
+		discord.NewStringSelectMenuOption(tr.Generic.Settings.VoiceActivity, "voice-activity").WithDefault(guildSettings.AnnounceVoiceActivity),
+	}
+
+	readingOptions := []discord.StringSelectMenuOption{
+		discord.NewStringSelectMenuOption(tr.Generic.Settings.OmitStrikethrough, "omit-strikethrough").WithDefault(guildSettings.OmitStrikethrough),
+		discord.NewStringSelectMenuOption(tr.Generic.Settings.AnnounceMarkdown, "announce-markdown").WithDefault(guildSettings.AnnounceMarkdown),
+		discord.NewStringSelectMenuOption(tr.Generic.Settings.StripNameDecorations, "strip-name-decorations").WithDefault(guildSettings.StripNameDecorations),
+	}
+
+	codeBlockOptions := make([]discord.StringSelectMenuOption, 0, len(settings.CodeBlockModes))
+	for _, mode := range settings.CodeBlockModes {
+		codeBlockOptions = append(codeBlockOptions, discord.NewStringSelectMenuOption(message.CodeBlockModeName(mode, tr), mode.String()).
+			WithDefault(mode == guildSettings.CodeBlockMode))
+	}
+
+	components := make([]discord.ContainerComponent, 0, 5)
+	if len(presetOptions) > 0 {
+		components = append(components, discord.NewActionRow(discord.NewStringSelectMenu("/setup/preset", tr.Commands.Setup.Preset, presetOptions...)))
+	}
+	return append(components,
+		discord.NewActionRow(discord.NewStringSelectMenu("/setup/announcements", tr.Commands.Setup.Announcements, announcementOptions...).
+			WithMinValues(0).
+			WithMaxValues(len(announcementOptions))),
+		discord.NewActionRow(discord.NewStringSelectMenu("/setup/reading", tr.Commands.Setup.Reading, readingOptions...).
+			WithMinValues(0).
+			WithMaxValues(len(readingOptions))),
+		discord.NewActionRow(discord.NewStringSelectMenu("/setup/code-block", tr.Commands.Setup.CodeBlock, codeBlockOptions...)),
+		discord.NewActionRow(discord.NewPrimaryButton(tr.Commands.Setup.Done, "/setup/done")),
+	), nil
+}
+
+// SetupHandler shows the setup wizard.
+func SetupHandler(presetRegistry *preset.PresetRegistry, presetIDRepository preset.PresetIDRepository, restrictions *preset.Restrictions, settingsRepository settings.GuildSettingsRepository) handler.CommandHandler {
+	wizard := setupWizard{presetRegistry: presetRegistry, presetIDRepository: presetIDRepository, restrictions: restrictions, settingsRepository: settingsRepository}
+	return func(e *handler.CommandEvent) error {
+		tr := localized(e.Ctx)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		components, err := wizard.components(ctx, *e.GuildID(), tr)
+		if err != nil {
+			slog.ErrorContext(e.Ctx, "failed to build setup wizard", "error", err, "guildID", *e.GuildID())
+			return e.CreateMessage(discord.NewMessageCreateBuilder().
+				AddEmbeds(message.BuildErrorEmbed(tr).
+					SetDescription(tr.Commands.Settings.ErrorFetch).
+					Build()).
+				Build())
+		}
+
+		return e.CreateMessage(discord.NewMessageCreateBuilder().
+			AddEmbeds(message.BuildSetupEmbed(tr).Build()).
+			AddContainerComponents(components...).
+			Build())
+	}
+}
+
+// SetupComponentHandler saves the value picked in the setup wizard, e.g. "/setup/announcements", and refreshes the wizard.
+// The wizard posted on join can be seen by every member, so the route must require the Manage Server permission.
+func SetupComponentHandler(presetRegistry *preset.PresetRegistry, presetIDRepository preset.PresetIDRepository, restrictions *preset.Restrictions, settingsRepository settings.GuildSettingsRepository) handler.ComponentHandler {
+	wizard := setupWizard{presetRegistry: presetRegistry, presetIDRepository: presetIDRepository, restrictions: restrictions, settingsRepository: settingsRepository}
+	return func(e *handler.ComponentEvent) error {
+		tr := localized(e.Ctx)
+
+		guildID := *e.GuildID()
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		if e.Vars["step"] == "done" {
+			guildSettings, err := settings.FindOrDefault(ctx, settingsRepository, guildID)
+			if err != nil {
+				slog.ErrorContext(e.Ctx, "failed to fetch guild settings", "error", err, "guildID", guildID)
+				return e.CreateMessage(discord.NewMessageCreateBuilder().
+					AddEmbeds(message.BuildErrorEmbed(tr).
+						SetDescription(tr.Commands.Settings.ErrorFetch).
+						Build()).
+					SetEphemeral(true).
+					Build())
+			}
+			return e.UpdateMessage(discord.NewMessageUpdateBuilder().
+				SetEmbeds(message.BuildSettingsEmbed(guildSettings, tr).Build()).
+				ClearContainerComponents().
+				Build())
+		}
+
+		if err := wizard.save(ctx, guildID, e.Vars["step"], e.StringSelectMenuInteractionData().Values); err != nil {
+			slog.ErrorContext(e.Ctx, "failed to save setup step", "error", err, "guildID", guildID, "step", e.Vars["step"])
+			return e.CreateMessage(discord.NewMessageCreateBuilder().
+				AddEmbeds(message.BuildErrorEmbed(tr).
+					SetDescription(saveErrorDescription(err, tr)).
+					Build()).
+				SetEphemeral(true).
+				Build())
+		}
+
+		components, err := wizard.components(ctx, guildID, tr)
+		if err != nil {
+			slog.ErrorContext(e.Ctx, "failed to build setup wizard", "error", err, "guildID", guildID)
+			return e.DeferUpdateMessage()
+		}
+		return e.UpdateMessage(discord.NewMessageUpdateBuilder().
+			SetContainerComponents(components...).
+			Build())
+	}
+}
+
+// save saves the values picked in a step of the wizard.
+func (w setupWizard) save(ctx context.Context, guildID snowflake.ID, step string, values []string) error {
+	if step == "preset" {
+		if len(values) == 0 {
+			return nil
+		}
+		p, ok := w.presetRegistry.Get(preset.PresetID(values[0]))
+		if !ok {
+			return fmt.Errorf("preset %s not found", values[0])
+		}
+		if !w.restrictions.Allows(guildID, p) {
+			return fmt.Errorf("preset %s is restricted in the guild", p.Identifier)
+		}
+		return w.presetIDRepository.Save(ctx, preset.ScopeGuild, guildID, p.Identifier)
+	}
+
+	guildSettings, err := settings.FindOrDefault(ctx, w.settingsRepository, guildID)
+	if err != nil {
+		return err
+	}
+	switch step {
+	case "announcements":
+		guildSettings.AnnounceJoin = slices.Contains(values, "join")
+		guildSettings.AnnounceLeave = slices.Contains(values, "leave")
+		guildSettings.AnnounceLaunch = slices.Contains(values, "launch")
+		guildSettings.AnnounceFarewell = slices.Contains(values, "farewell")
+		guildSettings.AnnounceVoiceActivity = slices.Contains(values, "voice-activity")
+	case "reading":
+		guildSettings.OmitStrikethrough = slices.Contains(values, "omit-strikethrough")
+		guildSettings.AnnounceMarkdown = slices.Contains(values, "announce-markdown")
+		guildSettings.StripNameDecorations = slices.Contains(values, "strip-name-decorations")
+	case "code-block":
+		if len(values) == 0 {
+			return nil
+		}
+		guildSettings.CodeBlockMode = settings.CodeBlockMode(values[0])
+	default:
+		return fmt.Errorf("unknown setup step: %s", step)
+	}
+	return w.settingsRepository.Save(ctx, guildSettings)
+}
+
+// SetupGuildJoinListener posts the setup wizard to the system channel of guilds the bot is added to.
+func SetupGuildJoinListener(presetRegistry *preset.PresetRegistry, presetIDRepository preset.PresetIDRepository, restrictions *preset.Restrictions, settingsRepository settings.GuildSettingsRepository, trs *i18n.TextResources) bot.EventListener {
+	wizard := setupWizard{presetRegistry: presetRegistry, presetIDRepository: presetIDRepository, restrictions: restrictions, settingsRepository: settingsRepository}
+	return bot.NewListenerFunc(func(event *events.GuildJoin) {
+		if event.Guild.SystemChannelID == nil {
+			return
+		}
+
+		tr, ok := trs.Get(discord.Locale(event.Guild.PreferredLocale))
+		if !ok {
+			tr = trs.GetFallback()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		components, err := wizard.components(ctx, event.GuildID, tr)
+		if err != nil {
+			slog.Error("failed to build setup wizard", "error", err, "guildID", event.GuildID)
+			return
+		}
+
+		if _, err := event.Client().Rest().CreateMessage(*event.Guild.SystemChannelID, discord.NewMessageCreateBuilder().
+			AddEmbeds(message.BuildSetupEmbed(tr).Build()).
+			AddContainerComponents(components...).
+			Build(),
+		); err != nil {
+			// the bot may not be allowed to send messages to the system channel.
+			slog.Warn("failed to post setup wizard", "error", err, "guildID", event.GuildID)
+		}
+	})
+}
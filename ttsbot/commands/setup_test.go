@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveSetupStep(t *testing.T) {
+	ctx := context.Background()
+	registry := preset.NewPresetRegistry()
+	require.NoError(t, registry.Register(preset.Preset{Identifier: "default", Engine: "test_engine"}))
+	presetIDRepository := preset.NewMemoryPresetIDRepository()
+	settingsRepository := settings.NewMemoryGuildSettingsRepository()
+	wizard := setupWizard{presetRegistry: registry, presetIDRepository: presetIDRepository, settingsRepository: settingsRepository}
+
+	require.NoError(t, wizard.save(ctx, 1, "preset", []string{"default"}))
+	presetID, err := presetIDRepository.Find(ctx, preset.ScopeGuild, 1)
+	require.NoError(t, err)
+	require.Equal(t, preset.PresetID("default"), presetID)
+	require.Error(t, wizard.save(ctx, 1, "preset", []string{"unknown"}))
+
+	require.NoError(t, wizard.save(ctx, 1, "announcements", []string{"leave", "voice-activity"}))
+	require.NoError(t, wizard.save(ctx, 1, "reading", []string{"announce-markdown"}))
+	require.NoError(t, wizard.save(ctx, 1, "code-block", []string{"skip"}))
+	require.Error(t, wizard.save(ctx, 1, "unknown", nil))
+
+	found, err := settingsRepository.Find(ctx, 1)
+	require.NoError(t, err)
+	require.False(t, found.AnnounceJoin)
+	require.True(t, found.AnnounceLeave)
+	require.False(t, found.AnnounceLaunch)
+	require.True(t, found.AnnounceVoiceActivity)
+	require.False(t, found.OmitStrikethrough)
+	require.True(t, found.AnnounceMarkdown)
+	require.Equal(t, settings.CodeBlockModeSkip, found.CodeBlockMode)
+}
@@ -0,0 +1,242 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// SyncMode selects how the commands of the bot are synced with discord on startup.
+type SyncMode string
+
+const (
+	// SyncModeOff does not sync commands.
+	SyncModeOff SyncMode = "off"
+	// SyncModeAlways overwrites the commands of the dev guilds, or the global commands if there are none.
+	SyncModeAlways SyncMode = "always"
+	// SyncModeDiff is like SyncModeAlways, but only overwrites commands that differ from the registered ones.
+	SyncModeDiff SyncMode = "diff"
+	// SyncModeGuild syncs the commands of the dev guilds only, and never touches the global commands.
+	SyncModeGuild SyncMode = "guild"
+	// SyncModeGlobal syncs the global commands even if dev guilds are configured. It asks for confirmation first.
+	SyncModeGlobal SyncMode = "global"
+)
+
+// ParseSyncMode parses a sync mode. "true" and "false" are accepted for compatibility with the former boolean flag.
+func ParseSyncMode(s string) (SyncMode, error) {
+	switch strings.ToLower(s) {
+	case "", "false", "off":
+		return SyncModeOff, nil
+	case "true", "always":
+		return SyncModeAlways, nil
+	case "diff":
+		return SyncModeDiff, nil
+	case "guild":
+		return SyncModeGuild, nil
+	case "global":
+		return SyncModeGlobal, nil
+	}
+	return "", fmt.Errorf("unknown sync mode %q: must be one of off, always, diff, guild or global", s)
+}
+
+// CommandDiff lists the names of the commands that differ between discord and the bot.
+type CommandDiff struct {
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+func (d CommandDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+func (d CommandDiff) String() string {
+	if d.IsEmpty() {
+		return "no changes"
+	}
+	var parts []string
+	if len(d.Added) > 0 {
+		parts = append(parts, "added: "+strings.Join(d.Added, ", "))
+	}
+	if len(d.Changed) > 0 {
+		parts = append(parts, "changed: "+strings.Join(d.Changed, ", "))
+	}
+	if len(d.Removed) > 0 {
+		parts = append(parts, "removed: "+strings.Join(d.Removed, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// CommandSyncer compares and syncs the commands of the bot with the ones registered in discord.
+// A guild ID of 0 stands for the global commands.
+type CommandSyncer struct {
+	commands []discord.ApplicationCommandCreate
+}
+
+func NewCommandSyncer(commands []discord.ApplicationCommandCreate) *CommandSyncer {
+	return &CommandSyncer{commands: commands}
+}
+
+// Diff returns the changes a sync of the guild would make.
+func (s *CommandSyncer) Diff(ctx context.Context, client bot.Client, guildID snowflake.ID) (CommandDiff, error) {
+	var (
+		registered []discord.ApplicationCommand
+		err        error
+	)
+	if guildID == 0 {
+		registered, err = client.Rest().GetGlobalCommands(client.ApplicationID(), true, rest.WithCtx(ctx))
+	} else {
+		registered, err = client.Rest().GetGuildCommands(client.ApplicationID(), guildID, true, rest.WithCtx(ctx))
+	}
+	if err != nil {
+		return CommandDiff{}, fmt.Errorf("failed to fetch registered commands: %w", err)
+	}
+	return diffCommands(registered, s.commands)
+}
+
+// Sync overwrites the commands of the guild.
+// Unless force is set, nothing is sent to discord when the registered commands are already up to date.
+// It returns the changes that were made.
+func (s *CommandSyncer) Sync(ctx context.Context, client bot.Client, guildID snowflake.ID, force bool) (CommandDiff, error) {
+	diff, err := s.Diff(ctx, client, guildID)
+	if err != nil {
+		return CommandDiff{}, err
+	}
+	if diff.IsEmpty() && !force {
+		return diff, nil
+	}
+
+	if guildID == 0 {
+		_, err = client.Rest().SetGlobalCommands(client.ApplicationID(), s.commands, rest.WithCtx(ctx))
+	} else {
+		_, err = client.Rest().SetGuildCommands(client.ApplicationID(), guildID, s.commands, rest.WithCtx(ctx))
+	}
+	if err != nil {
+		return CommandDiff{}, fmt.Errorf("failed to set commands: %w", err)
+	}
+	return diff, nil
+}
+
+type commandKey struct {
+	Type discord.ApplicationCommandType
+	Name string
+}
+
+// serverFields are set by discord and never sent by the bot.
+var serverFields = []string{"id", "application_id", "guild_id", "version", "name_localized", "description_localized", "dm_permission"}
+
+// defaultedFields are filled in by discord when a command is created without them.
+var defaultedFields = []string{"integration_types", "contexts", "nsfw"}
+
+func diffCommands(registered []discord.ApplicationCommand, desired []discord.ApplicationCommandCreate) (CommandDiff, error) {
+	registeredByKey := make(map[commandKey]map[string]any, len(registered))
+	for _, command := range registered {
+		normalized, err := normalizeCommand(command)
+		if err != nil {
+			return CommandDiff{}, err
+		}
+		registeredByKey[commandKey{command.Type(), command.Name()}] = normalized
+	}
+
+	var diff CommandDiff
+	for _, command := range desired {
+		key := commandKey{command.Type(), command.CommandName()}
+		want, err := normalizeCommand(command)
+		if err != nil {
+			return CommandDiff{}, err
+		}
+
+		got, ok := registeredByKey[key]
+		delete(registeredByKey, key)
+		if !ok {
+			diff.Added = append(diff.Added, key.Name)
+			continue
+		}
+		for _, field := range defaultedFields {
+			if _, ok := want[field]; !ok {
+				delete(got, field)
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			diff.Changed = append(diff.Changed, key.Name)
+		}
+	}
+
+	// keep the order of the registered commands, so the diff is stable.
+	for _, command := range registered {
+		if _, ok := registeredByKey[commandKey{command.Type(), command.Name()}]; ok {
+			diff.Removed = append(diff.Removed, command.Name())
+		}
+	}
+	return diff, nil
+}
+
+// normalizeCommand converts a command into its JSON form without server fields and zero values,
+// so that registered and desired commands can be compared.
+func normalizeCommand(command any) (map[string]any, error) {
+	data, err := json.Marshal(command)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal command: %w", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal command: %w", err)
+	}
+	for _, field := range serverFields {
+		delete(fields, field)
+	}
+	// registered commands report unset permissions as "0", so an explicit 0 cannot be told apart from unset.
+	if fields["default_member_permissions"] == "0" {
+		delete(fields, "default_member_permissions")
+	}
+	pruned, _ := prune(fields).(map[string]any)
+	if pruned == nil {
+		pruned = map[string]any{}
+	}
+	return pruned, nil
+}
+
+// prune removes nulls, false, empty strings and empty collections, which discord and the bot use interchangeably.
+func prune(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if pruned := prune(child); pruned == nil {
+				delete(v, key)
+			} else {
+				v[key] = pruned
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+		return v
+	case []any:
+		items := v[:0]
+		for _, child := range v {
+			if pruned := prune(child); pruned != nil {
+				items = append(items, pruned)
+			}
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return items
+	case bool:
+		if !v {
+			return nil
+		}
+	case string:
+		if v == "" {
+			return nil
+		}
+	}
+	return value
+}
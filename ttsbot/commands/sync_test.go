@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+)
+
+// registeredCommand returns the command as discord returns it after it was created,
+// with server fields and defaults filled in.
+func registeredCommand(t *testing.T, command discord.ApplicationCommandCreate, edit func(fields map[string]any)) discord.ApplicationCommand {
+	t.Helper()
+
+	data, err := json.Marshal(command)
+	if err != nil {
+		t.Fatalf("failed to marshal command: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("failed to unmarshal command: %v", err)
+	}
+	fields["id"] = "1"
+	fields["application_id"] = "2"
+	fields["version"] = "3"
+	fields["dm_permission"] = true
+	fields["nsfw"] = false
+	if _, ok := fields["integration_types"]; !ok {
+		fields["integration_types"] = []int{0}
+	}
+	if _, ok := fields["default_member_permissions"]; !ok {
+		fields["default_member_permissions"] = nil
+	}
+	if edit != nil {
+		edit(fields)
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		t.Fatalf("failed to marshal registered command: %v", err)
+	}
+	var registered discord.UnmarshalApplicationCommand
+	if err := json.Unmarshal(data, &registered); err != nil {
+		t.Fatalf("failed to unmarshal registered command: %v", err)
+	}
+	return registered.ApplicationCommand
+}
+
+func TestDiffCommands(t *testing.T) {
+	trs, err := i18n.LoadTextResources("../../locales/text/", "en-US")
+	if err != nil {
+		t.Fatalf("failed to load text resources: %v", err)
+	}
+	desired := Commands(trs)
+
+	t.Run("up to date", func(t *testing.T) {
+		registered := make([]discord.ApplicationCommand, 0, len(desired))
+		for _, command := range desired {
+			registered = append(registered, registeredCommand(t, command, nil))
+		}
+
+		diff, err := diffCommands(registered, desired)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !diff.IsEmpty() {
+			t.Errorf("expected no changes, got %s", diff)
+		}
+	})
+
+	t.Run("added, changed and removed", func(t *testing.T) {
+		registered := []discord.ApplicationCommand{
+			registeredCommand(t, discord.SlashCommandCreate{Name: "old", Description: "Removed command"}, nil),
+		}
+		for _, command := range desired[1:] {
+			registered = append(registered, registeredCommand(t, command, func(fields map[string]any) {
+				if fields["name"] == "leave" {
+					fields["description"] = "Outdated description"
+				}
+			}))
+		}
+
+		diff, err := diffCommands(registered, desired)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := CommandDiff{
+			Added:   []string{desired[0].CommandName()},
+			Changed: []string{"leave"},
+			Removed: []string{"old"},
+		}
+		if !reflect.DeepEqual(diff, want) {
+			t.Errorf("expected %s, got %s", want, diff)
+		}
+	})
+}
+
+func TestParseSyncMode(t *testing.T) {
+	testcases := map[string]SyncMode{
+		"":       SyncModeOff,
+		"false":  SyncModeOff,
+		"true":   SyncModeAlways,
+		"always": SyncModeAlways,
+		"Diff":   SyncModeDiff,
+		"guild":  SyncModeGuild,
+		"global": SyncModeGlobal,
+	}
+	for input, want := range testcases {
+		got, err := ParseSyncMode(input)
+		if err != nil {
+			t.Errorf("ParseSyncMode(%q) returned error: %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseSyncMode(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	if _, err := ParseSyncMode("sometimes"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
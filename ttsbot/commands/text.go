@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/session"
+)
+
+// text commands are typed in the reading channel, e.g. ";skip", for users who cannot use slash commands.
+
+// SkipTextCommandHandler skips the message being read.
+func SkipTextCommandHandler() session.TextCommandHandler {
+	return func(e *session.TextCommandEvent) error {
+		tr := e.TextResource
+		if !inSessionVoiceChannel(e) {
+			return replyTextCommand(e, message.BuildErrorEmbed(tr).
+				SetDescription(tr.Commands.Generic.ErrorNotInVoiceChannel).
+				Build())
+		}
+
+		if !e.Session.Skip() {
+			return replyTextCommand(e, message.BuildErrorEmbed(tr).
+				SetDescription(tr.Commands.Text.Skip.ErrorNotPlaying).
+				Build())
+		}
+		return replyTextCommand(e, message.BuildSuccessEmbed(tr).
+			SetDescription(tr.Commands.Text.Skip.Success).
+			Build())
+	}
+}
+
+// LeaveTextCommandHandler stops the session, like /leave.
+func LeaveTextCommandHandler(manager session.SessionManager) session.TextCommandHandler {
+	return func(e *session.TextCommandEvent) error {
+		tr := e.TextResource
+		if !inSessionVoiceChannel(e) {
+			return replyTextCommand(e, message.BuildErrorEmbed(tr).
+				SetDescription(tr.Commands.Generic.ErrorNotInVoiceChannel).
+				Build())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		voiceChannelID := e.Session.VoiceChannelID()
+		e.Session.Farewell(ctx)
+		e.Session.Close(ctx, session.CloseReasonLeave)
+		manager.Delete(e.Session.GuildID(), voiceChannelID)
+		return replyTextCommand(e, message.BuildLeaveEmbed(tr, session.CloseReasonLeave.Description(tr), e.Session.Summary()).Build())
+	}
+}
+
+// PresetTextCommandHandler shows the preset of the author, or sets it if a preset name is given.
+func PresetTextCommandHandler(presetRegistry *preset.PresetRegistry, presetIDRepository preset.PresetIDRepository, restrictions *preset.Restrictions) session.TextCommandHandler {
+	return func(e *session.TextCommandEvent) error {
+		tr := e.TextResource
+		userID := e.Message.Author.ID
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		if len(e.Command.Args) == 0 {
+			presetID, err := presetIDRepository.Find(ctx, preset.ScopeUser, userID)
+			if err != nil {
+				if errors.Is(err, preset.ErrNotFound) {
+					return replyTextCommand(e, message.BuildErrorEmbed(tr).
+						SetDescriptionf(tr.Commands.Preset.Generic.Show.None, tr.Generic.User).
+						Build())
+				}
+				slog.Error("failed to find preset ID", "error", err)
+				return replyTextCommand(e, message.BuildErrorEmbed(tr).
+					SetDescription(tr.Commands.Preset.Generic.Show.ErrorFetch).
+					Build())
+			}
+
+			p, ok := presetRegistry.Get(presetID)
+			if !ok {
+				return replyTextCommand(e, message.BuildErrorEmbed(tr).
+					SetDescription(tr.Commands.Preset.Generic.Show.ErrorInvalid).
+					Build())
+			}
+			return replyTextCommand(e, message.BuildPresetEmbed(p, tr).
+				SetDescriptionf(tr.Commands.Preset.Generic.Show.Current, tr.Generic.User).
+				Build())
+		}
+
+		name := e.Command.Args[0]
+		p, ok := presetRegistry.Get(preset.PresetID(name))
+		if !ok {
+			return replyTextCommand(e, message.BuildErrorEmbed(tr).
+				SetDescriptionf(tr.Commands.Preset.Generic.Set.ErrorNotFound, name).
+				Build())
+		}
+		if !restrictions.Allows(e.Session.GuildID(), p) {
+			return replyTextCommand(e, message.BuildErrorEmbed(tr).
+				SetDescriptionf(tr.Commands.Preset.Generic.Set.ErrorRestricted, p.Identifier).
+				Build())
+		}
+
+		if err := presetIDRepository.Save(ctx, preset.ScopeUser, userID, p.Identifier); err != nil {
+			slog.Error("failed to save preset ID", "error", err)
+			return replyTextCommand(e, message.BuildErrorEmbed(tr).
+				SetDescriptionf(tr.Commands.Preset.Generic.Set.ErrorSave, tr.Generic.User, err).
+				Build())
+		}
+		return replyTextCommand(e, message.BuildSuccessEmbed(tr).
+			SetDescriptionf(tr.Commands.Preset.Generic.Set.Success, tr.Generic.User, p.Identifier).
+			Build())
+	}
+}
+
+// inSessionVoiceChannel reports whether the author of the text command is in the voice channel of the session.
+func inSessionVoiceChannel(e *session.TextCommandEvent) bool {
+	voiceState, ok := e.Client().Caches().VoiceState(e.Session.GuildID(), e.Message.Author.ID)
+	return ok && voiceState.ChannelID != nil && *voiceState.ChannelID == e.Session.VoiceChannelID()
+}
+
+// replyTextCommand replies to the message of the text command.
+func replyTextCommand(e *session.TextCommandEvent, embed discord.Embed) error {
+	_, err := e.Client().Rest().CreateMessage(e.ChannelID, discord.NewMessageCreateBuilder().
+		SetMessageReferenceByID(e.MessageID).
+		SetAllowedMentions(&discord.AllowedMentions{}).
+		AddEmbeds(embed).
+		Build())
+	return err
+}
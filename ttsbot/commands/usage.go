@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+func usageCmd(trs *i18n.TextResources) discord.SlashCommandCreate {
+	return discord.SlashCommandCreate{
+		Name:        "usage",
+		Description: "Show the characters this server had read this month",
+		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+			return tr.Commands.Usage.Description
+		}),
+		Contexts: []discord.InteractionContextType{discord.InteractionContextTypeGuild},
+	}
+}
+
+// UsageHandler shows the characters the guild had read this month per engine, along with its monthly budget.
+func UsageHandler(usageTracker *tts.UsageTracker) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		tr := localized(e.Ctx)
+		usage, err := usageTracker.Usage(e.Ctx, *e.GuildID())
+		if err != nil {
+			return e.CreateMessage(classifyError(e.Ctx, fmt.Errorf("failed to find usage: %w", err), tr).Message())
+		}
+		return e.CreateMessage(discord.NewMessageCreateBuilder().
+			AddEmbeds(message.BuildUsageEmbed(tr, usage).Build()).
+			SetEphemeral(true).
+			Build())
+	}
+}
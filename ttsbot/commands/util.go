@@ -1,13 +1,10 @@
 package commands
 
 import (
-	"errors"
 	"fmt"
-	"log/slog"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/handler"
-	"github.com/disgoorg/disgo/rest"
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
@@ -55,40 +52,8 @@ func SafeGetVoiceChannelID(e *handler.CommandEvent, tr i18n.TextResource) (*snow
 
 	// user must be in a voice channel to use this command
 	voiceState, err := e.Client().Rest().GetUserVoiceState(*guildID, e.User().ID)
-	var restErr rest.Error
-	if ok := errors.As(err, &restErr); ok {
-		switch restErr.Code {
-		case 10065:
-			return nil, newFriendlyError(
-				fmt.Errorf("user not in a voice channel"),
-				discord.NewMessageCreateBuilder().
-					AddEmbeds(message.BuildErrorEmbed(tr).
-						SetDescription(tr.Commands.Generic.ErrorNotInVoiceChannel).
-						Build()).
-					Build(),
-			)
-		case 50013:
-			return nil, newFriendlyError(
-				fmt.Errorf("missing permissions to get voice state"),
-				discord.NewMessageCreateBuilder().
-					AddEmbeds(message.BuildErrorEmbed(tr).
-						SetDescription(tr.Commands.Generic.ErrorInsufficientPermissions).
-						Build()).
-					Build(),
-			)
-		}
-	}
-
 	if err != nil {
-		slog.Warn("failed to get voice state", "error", err)
-		return nil, newFriendlyError(
-			fmt.Errorf("failed to get voice state: %w", err),
-			discord.NewMessageCreateBuilder().
-				AddEmbeds(message.BuildErrorEmbed(tr).
-					SetDescription("Failed to get voice state").
-					Build()).
-				Build(),
-		)
+		return nil, classifyError(e.Ctx, fmt.Errorf("failed to get voice state: %w", err), tr)
 	}
 
 	if voiceState.ChannelID == nil {
@@ -104,3 +69,32 @@ func SafeGetVoiceChannelID(e *handler.CommandEvent, tr i18n.TextResource) (*snow
 
 	return voiceState.ChannelID, nil
 }
+
+// isGuildInstalled reports whether the bot is added to the guild the command was invoked in,
+// as opposed to a command of a user-installed app used in a guild without the bot.
+func isGuildInstalled(e *handler.CommandEvent) bool {
+	guildID, ok := e.AuthorizingIntegrationOwners()[discord.ApplicationIntegrationTypeGuildInstall]
+	return ok && guildID != 0
+}
+
+// interactionGuildID returns the ID of the guild the command was invoked in, or 0 outside of guilds.
+func interactionGuildID(e *handler.CommandEvent) snowflake.ID {
+	if guildID := e.GuildID(); guildID != nil {
+		return *guildID
+	}
+	return 0
+}
+
+// memberInteraction is an interaction event invoked by a guild member, e.g. a command or a component.
+type memberInteraction interface {
+	Member() *discord.ResolvedMember
+}
+
+// hasPermission reports whether the member who invoked the interaction has the given permission in the channel.
+func hasPermission(e memberInteraction, permission discord.Permissions) bool {
+	member := e.Member()
+	if member == nil {
+		return false
+	}
+	return member.Permissions.Has(permission)
+}
@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+// voicesPerPage is the number of voices listed on a page of /voices.
+const voicesPerPage = 20
+
+// voicesLanguagePattern matches language codes such as "ja" or "en-US". It also keeps the custom IDs of the page buttons parseable.
+var voicesLanguagePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(?:-[a-zA-Z0-9]{2,8})*$`)
+
+func voicesCmd(trs *i18n.TextResources) discord.SlashCommandCreate {
+	return discord.SlashCommandCreate{
+		Name:        "voices",
+		Description: "List the voices of an engine for a language",
+		DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+			return tr.Commands.Voices.Description
+		}),
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionString{
+				Name:        "engine",
+				Description: "The engine to list the voices of, e.g. google",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Voices.Engine
+				}),
+				Required: true,
+			},
+			discord.ApplicationCommandOptionString{
+				Name:        "language",
+				Description: "The language the voices speak, e.g. ja or en-US",
+				DescriptionLocalizations: trs.Localizations(func(tr i18n.TextResource) string {
+					return tr.Commands.Voices.Language
+				}),
+				Required: true,
+			},
+		},
+	}
+}
+
+// VoicesHandler lists the voices of an engine for a language, so that the voice_name of presets can be found without the docs of the engine.
+// The list is only shown to the invoker.
+func VoicesHandler(engineRegistry *tts.EngineRegistry, voiceCatalog *tts.VoiceCatalog) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		tr := localized(e.Ctx)
+		data := e.SlashCommandInteractionData()
+
+		embed, components, err := voicesPage(e.Ctx, engineRegistry, voiceCatalog, tr, data.String("engine"), data.String("language"), 0)
+		if err != nil {
+			return e.CreateMessage(voicesErrorMessage(e.Ctx, err, tr).Message())
+		}
+		return e.CreateMessage(discord.NewMessageCreateBuilder().
+			AddEmbeds(embed.Build()).
+			AddContainerComponents(components...).
+			SetEphemeral(true).
+			Build())
+	}
+}
+
+// VoicesPageHandler handles the page buttons of /voices.
+func VoicesPageHandler(engineRegistry *tts.EngineRegistry, voiceCatalog *tts.VoiceCatalog) handler.ComponentHandler {
+	return func(e *handler.ComponentEvent) error {
+		tr := localized(e.Ctx)
+		page, err := strconv.Atoi(e.Vars["page"])
+		if err != nil {
+			return fmt.Errorf("invalid page in voices button %q: %w", e.Data.CustomID(), err)
+		}
+
+		embed, components, err := voicesPage(e.Ctx, engineRegistry, voiceCatalog, tr, e.Vars["engine"], e.Vars["language"], page)
+		if err != nil {
+			return e.CreateMessage(voicesErrorMessage(e.Ctx, err, tr).Message())
+		}
+		return e.UpdateMessage(discord.NewMessageUpdateBuilder().
+			SetEmbeds(embed.Build()).
+			SetContainerComponents(components...).
+			Build())
+	}
+}
+
+var (
+	errVoicesUnknownEngine   = errors.New("unknown engine")
+	errVoicesInvalidLanguage = errors.New("invalid language")
+)
+
+// voicesPage builds the page of the voices of the engine speaking the language, along with its page buttons.
+func voicesPage(ctx context.Context, engineRegistry *tts.EngineRegistry, voiceCatalog *tts.VoiceCatalog, tr i18n.TextResource, engineID, language string, page int) (*discord.EmbedBuilder, []discord.ContainerComponent, error) {
+	if _, ok := engineRegistry.Get(engineID); !ok {
+		return nil, nil, fmt.Errorf("%w: %s", errVoicesUnknownEngine, engineID)
+	}
+	if !voicesLanguagePattern.MatchString(language) {
+		return nil, nil, fmt.Errorf("%w: %s", errVoicesInvalidLanguage, language)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	voices, err := voiceCatalog.LanguageVoices(ctx, engineID, language)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	engineName := engineID
+	if name, ok := tr.Generic.Engines[engineID]; ok {
+		engineName = name
+	}
+	embed := message.BuildVoicesEmbed(tr, engineName, language)
+	if len(voices) == 0 {
+		return embed.SetDescription(tr.Commands.Voices.Empty), nil, nil
+	}
+
+	pages := (len(voices) + voicesPerPage - 1) / voicesPerPage
+	page = min(max(page, 0), pages-1)
+	embed.SetDescription(voicesPageDescription(voices[page*voicesPerPage : min((page+1)*voicesPerPage, len(voices))])).
+		SetFooterText(fmt.Sprintf(tr.Commands.Voices.Page, page+1, pages, len(voices)))
+	if pages == 1 {
+		return embed, nil, nil
+	}
+
+	customID := func(page int) string {
+		return "/voices/" + engineID + "/" + language + "/" + strconv.Itoa(page)
+	}
+	return embed, []discord.ContainerComponent{
+		discord.NewActionRow(
+			discord.NewSecondaryButton(tr.Commands.Voices.Previous, customID(page-1)).WithDisabled(page == 0),
+			discord.NewSecondaryButton(tr.Commands.Voices.Next, customID(page+1)).WithDisabled(page == pages-1),
+		),
+	}, nil
+}
+
+// voicesPageDescription lists the voices one per line, with the language codes they speak.
+func voicesPageDescription(voices []tts.Voice) string {
+	lines := make([]string, 0, len(voices))
+	for _, voice := range voices {
+		lines = append(lines, fmt.Sprintf("`%s` %s", voice.Name, strings.Join(voice.LanguageCodes, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// voicesErrorMessage describes why the voices could not be listed.
+func voicesErrorMessage(ctx context.Context, err error, tr i18n.TextResource) *FriendlyError {
+	var description string
+	switch {
+	case errors.Is(err, errVoicesUnknownEngine):
+		description = tr.Commands.Voices.ErrorUnknownEngine
+	case errors.Is(err, errVoicesInvalidLanguage):
+		description = tr.Commands.Voices.ErrorInvalidLanguage
+	case errors.Is(err, tts.ErrVoiceListingUnsupported):
+		description = tr.Commands.Voices.ErrorUnsupported
+	default:
+		return classifyError(ctx, fmt.Errorf("failed to list voices: %w", err), tr)
+	}
+	return newFriendlyError(err, discord.NewMessageCreateBuilder().
+		AddEmbeds(message.BuildErrorEmbed(tr).
+			SetDescription(description).
+			Build()).
+		SetEphemeral(true).
+		Build())
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+type listingEngine struct {
+	voices []tts.Voice
+}
+
+func (e listingEngine) Name() string { return "listing" }
+
+func (e listingEngine) GenerateSpeech(ctx context.Context, request tts.SpeechRequest) (*tts.SpeechResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e listingEngine) ListVoices(ctx context.Context, languageCode string) ([]tts.Voice, error) {
+	return e.voices, nil
+}
+
+func TestVoicesPage(t *testing.T) {
+	var voices []tts.Voice
+	for i := range 45 {
+		voices = append(voices, tts.Voice{Name: fmt.Sprintf("ja-JP-Voice-%02d", i), LanguageCodes: []string{"ja-JP"}})
+	}
+	voices = append(voices, tts.Voice{Name: "en-US-Voice", LanguageCodes: []string{"en-US"}})
+	registry := tts.NewEngineRegistry()
+	registry.Register("listing", listingEngine{voices: voices})
+	catalog := tts.NewVoiceCatalog(registry, time.Hour)
+
+	var tr i18n.TextResource
+	embed, components, err := voicesPage(context.Background(), registry, catalog, tr, "listing", "ja", 2)
+	if err != nil {
+		t.Fatalf("voicesPage() error = %v", err)
+	}
+	if got := strings.Count(embed.Description, "\n") + 1; got != 5 {
+		t.Errorf("the last page lists %d voices, want 5", got)
+	}
+	if len(components) != 1 {
+		t.Errorf("voicesPage() returned %d rows of buttons, want 1", len(components))
+	}
+
+	if _, _, err := voicesPage(context.Background(), registry, catalog, tr, "missing", "ja", 0); !errors.Is(err, errVoicesUnknownEngine) {
+		t.Errorf("voicesPage() error = %v, want %v", err, errVoicesUnknownEngine)
+	}
+	if _, _, err := voicesPage(context.Background(), registry, catalog, tr, "listing", "ja/1", 0); !errors.Is(err, errVoicesInvalidLanguage) {
+		t.Errorf("voicesPage() error = %v, want %v", err, errVoicesInvalidLanguage)
+	}
+}
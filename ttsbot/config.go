@@ -11,6 +11,8 @@ import (
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
 )
 
 func LoadConfig(path string) (*Config, error) {
@@ -41,16 +43,28 @@ func LoadConfig(path string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	if err := cfg.Guilds.validate(); err != nil {
+		return nil, fmt.Errorf("invalid guilds config: %w", err)
+	}
 
 	return &cfg, nil
 }
 
 type Config struct {
-	Log      LogConfig               `mapstructure:"log"`
-	Bot      BotConfig               `mapstructure:"bot"`
-	Presets  map[string]PresetConfig `mapstructure:"presets"`
-	Database DatabaseConfig          `mapstructure:"database"`
-	Redis    RedisConfig             `mapstructure:"redis"`
+	Log LogConfig `mapstructure:"log"`
+	Bot BotConfig `mapstructure:"bot"`
+	// Engines are keyed by engine identifier, e.g. "google".
+	Engines map[string]EngineConfig `mapstructure:"engines"`
+	Audio   AudioConfig             `mapstructure:"audio"`
+	Presets map[string]PresetConfig `mapstructure:"presets"`
+	// Restrictions are keyed by guild ID, "premium" for premium guilds without their own entry, or "default" for other guilds.
+	Restrictions map[string]RestrictionConfig `mapstructure:"restrictions"`
+	Guilds       GuildsConfig                 `mapstructure:"guilds"`
+	Entitlements EntitlementsConfig           `mapstructure:"entitlements"`
+	Retention    RetentionConfig              `mapstructure:"retention"`
+	Database     DatabaseConfig               `mapstructure:"database"`
+	Redis        RedisConfig                  `mapstructure:"redis"`
+	Operator     OperatorConfig               `mapstructure:"operator"`
 }
 
 type BotConfig struct {
@@ -58,12 +72,81 @@ type BotConfig struct {
 	Token            string         `mapstructure:"token"`
 	Language         string         `mapstructure:"default_lang"`
 	FallbackPresetID string         `mapstructure:"fallback_preset_id"`
+	// SlowSynthesisThreshold is the engine response duration above which a warning is logged. Zero disables the warning.
+	SlowSynthesisThreshold time.Duration `mapstructure:"slow_synthesis_threshold"`
+	// VoiceValidation checks the voices of presets against the engines on startup: "off" (default), "warn" or "fail".
+	VoiceValidation string `mapstructure:"voice_validation"`
 }
 
 type LogConfig struct {
-	Level     slog.Level `mapstructure:"level"`
-	Format    string     `mapstructure:"format"`
-	AddSource bool       `mapstructure:"add_source"`
+	Level     slog.Level                   `mapstructure:"level"`
+	Format    string                       `mapstructure:"format"`
+	AddSource bool                         `mapstructure:"add_source"`
+	Sampling  map[string]LogSamplingConfig `mapstructure:"sampling"`
+	// Output is where logs are written: "stdout" (default), "file" or "both".
+	Output string        `mapstructure:"output"`
+	File   LogFileConfig `mapstructure:"file"`
+}
+
+type LogFileConfig struct {
+	Path string `mapstructure:"path"`
+	// MaxSize is the size in megabytes after which the file is rotated.
+	MaxSize    int           `mapstructure:"max_size"`
+	MaxAge     time.Duration `mapstructure:"max_age"`
+	MaxBackups int           `mapstructure:"max_backups"`
+}
+
+// LogSamplingConfig limits the records of a log component, e.g. "synthesis".
+// Warnings and errors are never sampled.
+type LogSamplingConfig struct {
+	Level slog.Level `mapstructure:"level"`
+	Every int        `mapstructure:"every"`
+}
+
+// EngineConfig is the configuration of a speech engine.
+type EngineConfig struct {
+	// AudioEncoding is the audio the engine is asked for: "mp3" (default), "linear16" or "ogg_opus".
+	AudioEncoding string `mapstructure:"audio_encoding"`
+	// SampleRate is the sample rate of the audio in hertz, 48000 by default. Other rates are resampled for discord.
+	SampleRate int `mapstructure:"sample_rate"`
+	// Disabled skips registering the engine, e.g. the google engine on hosts without Google Cloud credentials.
+	Disabled bool `mapstructure:"disabled"`
+	// Region is the AWS region of the polly engine. Empty uses the region of the AWS environment or profile.
+	Region string `mapstructure:"region"`
+	// VoiceEngine is the voice engine of the polly engine: "neural" (default), "standard", "long-form" or "generative".
+	VoiceEngine string `mapstructure:"voice_engine"`
+	// URL is the endpoint of the voicevox engine, e.g. "http://localhost:50021". The engine is registered when it is set.
+	// For the openai and elevenlabs engines, it overrides the endpoint of their API, e.g. for a compatible proxy.
+	URL string `mapstructure:"url"`
+	// APIKey is the API key of the openai and elevenlabs engines. Each engine is registered when its key is set.
+	APIKey string `mapstructure:"api_key"`
+	// Model is the model of the openai engine used for voices that do not name one: "tts-1" (default) or "tts-1-hd".
+	// For the elevenlabs engine, it is the model ID, "eleven_multilingual_v2" by default.
+	Model string `mapstructure:"model"`
+	// Stream makes the elevenlabs, openai and polly engines play speech while it is synthesized, so that long messages start sooner.
+	Stream bool `mapstructure:"stream"`
+	// RequestsPerMinute and CharactersPerMinute keep the requests to the engine under the quota of its API,
+	// shared by every guild. Requests over them wait, and fail if they would wait too long. Zero does not limit them.
+	RequestsPerMinute   int `mapstructure:"requests_per_minute"`
+	CharactersPerMinute int `mapstructure:"characters_per_minute"`
+	// CostPerMillionCharacters is the price of a million characters synthesized with the engine, used to estimate
+	// the cost of the usage of each guild in /admin usage. Zero counts the engine as free.
+	CostPerMillionCharacters float64 `mapstructure:"cost_per_million_characters"`
+}
+
+// AudioConfig selects how synthesized speech is decoded for discord.
+type AudioConfig struct {
+	// Decoder is "native" (default), decoding in process, or "ffmpeg", piping audio through an ffmpeg process.
+	Decoder string `mapstructure:"decoder"`
+	// FFmpegPath is the ffmpeg executable used by the "ffmpeg" decoder. Empty looks it up in PATH.
+	FFmpegPath string `mapstructure:"ffmpeg_path"`
+	// PrebufferFrames is the number of 20ms frames decoded before a track starts playing. 0 decodes while playing.
+	PrebufferFrames int `mapstructure:"prebuffer_frames"`
+	// AnnouncementCacheSize is the number of announcements whose opus frames are kept in memory. 0 disables the cache.
+	AnnouncementCacheSize int `mapstructure:"announcement_cache_size"`
+	// MaxQueuedAudioMB is the megabytes of synthesized speech a session queues for playback before it stops synthesizing
+	// until playback catches up. 0 does not limit it.
+	MaxQueuedAudioMB int `mapstructure:"max_queued_audio_mb"`
 }
 
 type PresetConfig struct {
@@ -71,11 +154,118 @@ type PresetConfig struct {
 	Language     string  `mapstructure:"language"`
 	VoiceName    string  `mapstructure:"voice_name"`
 	SpeakingRate float64 `mapstructure:"speaking_rate"`
+	// Pitch is in semitones and VolumeGainDb in decibels from the normal voice. Only the google engine applies them.
+	Pitch        float64 `mapstructure:"pitch"`
+	VolumeGainDb float64 `mapstructure:"volume_gain_db"`
+	// FallbackEngine reads with FallbackVoiceName once the engine fails or times out, e.g. during an outage of its provider.
+	// The fallback voice may be left out to pick one for the language.
+	FallbackEngine    string `mapstructure:"fallback_engine"`
+	FallbackVoiceName string `mapstructure:"fallback_voice_name"`
+	// Voices are rotated among the speakers instead of using a single voice name.
+	Voices []string `mapstructure:"voices"`
+	// VoiceRotation is how voices are assigned to speakers: "round_robin" (default) or "hash".
+	VoiceRotation string `mapstructure:"voice_rotation"`
+	// MessageSSML and AnnouncementSSML are SSML templates wrapping messages and announcements, e.g. `<speak>{{.Text}}</speak>`.
+	MessageSSML      string `mapstructure:"message_ssml"`
+	AnnouncementSSML string `mapstructure:"announcement_ssml"`
+}
+
+// RestrictionConfig denies engines or voices to a guild.
+type RestrictionConfig struct {
+	DeniedEngines []string `mapstructure:"denied_engines"`
+	// DeniedVoices are glob patterns of voice names, e.g. "*-Neural2-*".
+	DeniedVoices []string `mapstructure:"denied_voices"`
+}
+
+// GuildsConfig is the operator-wide configuration of guild settings.
+type GuildsConfig struct {
+	Defaults GuildDefaultsConfig `mapstructure:"defaults"`
+	Limits   GuildLimitsConfig   `mapstructure:"limits"`
+}
+
+// validate rejects max message lengths guilds could not set themselves, which would silence or break reading.
+func (c GuildsConfig) validate() error {
+	if length := c.Defaults.MaxMessageLength; length != nil && !isMaxMessageLength(*length) {
+		return fmt.Errorf("defaults.max_message_length must be between %d and %d, got %d", settings.MinMaxMessageLength, settings.MaxMaxMessageLength, *length)
+	}
+	// zero leaves the max message length uncapped.
+	if length := c.Limits.MaxMessageLength; length != 0 && !isMaxMessageLength(length) {
+		return fmt.Errorf("limits.max_message_length must be 0 or between %d and %d, got %d", settings.MinMaxMessageLength, settings.MaxMaxMessageLength, length)
+	}
+	return nil
+}
+
+func isMaxMessageLength(length int) bool {
+	return length >= settings.MinMaxMessageLength && length <= settings.MaxMaxMessageLength
+}
+
+// GuildDefaultsConfig seeds the settings of new guilds. Unset values keep the built-in defaults.
+type GuildDefaultsConfig struct {
+	MaxMessageLength      *int  `mapstructure:"max_message_length"`
+	AnnounceJoin          *bool `mapstructure:"announce_join"`
+	AnnounceLeave         *bool `mapstructure:"announce_leave"`
+	AnnounceLaunch        *bool `mapstructure:"announce_launch"`
+	AnnounceFarewell      *bool `mapstructure:"announce_farewell"`
+	AnnounceVoiceActivity *bool `mapstructure:"announce_voice_activity"`
+	SelfDeaf              *bool `mapstructure:"self_deaf"`
+	SelfMute              *bool `mapstructure:"self_mute"`
+	EphemeralResponses    *bool `mapstructure:"ephemeral_responses"`
+}
+
+// GuildLimitsConfig are hard caps that guild admins cannot exceed.
+type GuildLimitsConfig struct {
+	// MaxMessageLength caps the max message length of guilds. Zero leaves it uncapped.
+	MaxMessageLength int `mapstructure:"max_message_length"`
+	// LockAnnouncements keeps the announcement toggles of every guild at the defaults.
+	LockAnnouncements bool `mapstructure:"lock_announcements"`
+	// MonthlyCharacters is the most characters each guild may have read per month. Zero leaves it unlimited.
+	MonthlyCharacters int64 `mapstructure:"monthly_characters"`
+	// PremiumMonthlyCharacters replaces MonthlyCharacters for premium guilds. Zero leaves them unlimited.
+	PremiumMonthlyCharacters int64 `mapstructure:"premium_monthly_characters"`
+}
+
+// EntitlementsConfig decides which guilds are premium. Premium guilds get their own monthly budget and restriction.
+type EntitlementsConfig struct {
+	// GuildIDs are premium guilds, e.g. of donors.
+	GuildIDs []snowflake.ID `mapstructure:"guild_ids"`
+	// SKUIDs make guilds subscribed to any of these discord SKUs of the bot premium.
+	SKUIDs []snowflake.ID `mapstructure:"sku_ids"`
+	// CacheTTL is how long the entitlement of a guild is kept before it is looked up again, 10 minutes by default.
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+}
+
+// RetentionConfig controls how long the data of guilds that removed the bot is kept.
+type RetentionConfig struct {
+	// DepartedGuilds is how long the presets and settings of a guild are kept after it removed the bot. Zero disables the cleanup.
+	DepartedGuilds time.Duration `mapstructure:"departed_guilds"`
+	// PurgeInterval is how often the data of departed guilds is checked for deletion.
+	PurgeInterval time.Duration `mapstructure:"purge_interval"`
 }
 
 type DatabaseConfig struct {
-	Driver string `mapstructure:"driver"`
-	Dsn    string `mapstructure:"dsn"`
+	Driver          string               `mapstructure:"driver"`
+	Dsn             string               `mapstructure:"dsn"`
+	MaxOpenConns    int                  `mapstructure:"max_open_conns"`
+	MaxIdleConns    int                  `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration        `mapstructure:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration        `mapstructure:"conn_max_idle_time"`
+	SQLite          SQLiteDatabaseConfig `mapstructure:"sqlite"`
+	// EncryptionKeyFile is the secret file of the keys encrypting the webhook URL and transcript channel of guilds at rest.
+	// Each line is "<key ID>:<base64 encoded key>", the first key encrypting and the others only decrypting. Empty stores them as plaintext.
+	EncryptionKeyFile string `mapstructure:"encryption_key_file"`
+}
+
+type SQLiteDatabaseConfig struct {
+	JournalMode string        `mapstructure:"journal_mode"`
+	BusyTimeout time.Duration `mapstructure:"busy_timeout"`
+}
+
+// OperatorConfig is where the operators of the bot are told about critical problems.
+type OperatorConfig struct {
+	// ChannelID is the discord channel critical alerts are posted to. Zero only logs them.
+	ChannelID snowflake.ID `mapstructure:"channel_id"`
+	// AlertInterval is how long the same alert is not posted again, 10 minutes by default.
+	AlertInterval time.Duration `mapstructure:"alert_interval"`
 }
 
 type RedisConfig struct {
@@ -111,11 +301,12 @@ func stringToSlogLevelHookFunc() mapstructure.DecodeHookFunc {
 
 func stringToSnowflakeIDSliceHookFunc() mapstructure.DecodeHookFunc {
 	return func(
-		f reflect.Kind,
-		t reflect.Kind,
+		f reflect.Type,
+		t reflect.Type,
 		data interface{},
 	) (interface{}, error) {
-		if f != reflect.Slice || t != reflect.TypeOf([]snowflake.ID{}).Kind() {
+		// compare the types rather than their kinds, so that other slices (e.g. []string) are left alone.
+		if f.Kind() != reflect.Slice || t != reflect.TypeOf([]snowflake.ID{}) {
 			return data, nil
 		}
 		var ids []snowflake.ID
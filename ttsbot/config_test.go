@@ -32,21 +32,79 @@ func TestLoadConfig(t *testing.T) {
 	assert.Equal(t, slog.LevelWarn, cfg.Log.Level)
 	assert.Equal(t, "json", cfg.Log.Format)
 	assert.Equal(t, true, cfg.Log.AddSource)
+	assert.Equal(t, LogSamplingConfig{Level: slog.LevelInfo, Every: 10}, cfg.Log.Sampling["synthesis"])
 
 	assert.Equal(t, []snowflake.ID{12345, 67890}, cfg.Bot.DevGuilds)
 	assert.Equal(t, "env_bot_token", cfg.Bot.Token)
 	assert.Equal(t, "en-US", cfg.Bot.Language)
 	assert.Equal(t, "test-preset", cfg.Bot.FallbackPresetID)
+	assert.Equal(t, 3*time.Second, cfg.Bot.SlowSynthesisThreshold)
+	assert.Equal(t, "warn", cfg.Bot.VoiceValidation)
 
+	assert.Equal(t, "linear16", cfg.Engines["google"].AudioEncoding)
+	assert.Equal(t, 24000, cfg.Engines["google"].SampleRate)
+	assert.Equal(t, EngineConfig{Region: "ap-northeast-1", VoiceEngine: "standard", Disabled: true}, cfg.Engines["polly"])
+	assert.Equal(t, "http://localhost:50021", cfg.Engines["voicevox"].URL)
+	assert.Equal(t, EngineConfig{APIKey: "sk-test", Model: "tts-1-hd"}, cfg.Engines["openai"])
+	assert.Equal(t, EngineConfig{APIKey: "xi-test", Stream: true}, cfg.Engines["elevenlabs"])
+	assert.Equal(t, "ffmpeg", cfg.Audio.Decoder)
+	assert.Equal(t, "/usr/bin/ffmpeg", cfg.Audio.FFmpegPath)
+	assert.Equal(t, 10, cfg.Audio.PrebufferFrames)
+	assert.Equal(t, 64, cfg.Audio.AnnouncementCacheSize)
+	assert.Equal(t, 8, cfg.Audio.MaxQueuedAudioMB)
 	assert.Equal(t, "google", cfg.Presets["test-preset"].Engine)
 	assert.Equal(t, "en-US", cfg.Presets["test-preset"].Language)
 	assert.Equal(t, "en-US-Wavenet-A", cfg.Presets["test-preset"].VoiceName)
 	assert.Equal(t, 1.0, cfg.Presets["test-preset"].SpeakingRate)
 
+	assert.Equal(t, []string{"*-Neural2-*"}, cfg.Restrictions["default"].DeniedVoices)
+	assert.Equal(t, []string{"polly"}, cfg.Restrictions["12345"].DeniedEngines)
+
+	if assert.NotNil(t, cfg.Guilds.Defaults.MaxMessageLength) {
+		assert.Equal(t, 80, *cfg.Guilds.Defaults.MaxMessageLength)
+	}
+	if assert.NotNil(t, cfg.Guilds.Defaults.AnnounceLaunch) {
+		assert.Equal(t, false, *cfg.Guilds.Defaults.AnnounceLaunch)
+	}
+	assert.Nil(t, cfg.Guilds.Defaults.AnnounceJoin)
+	assert.Equal(t, 200, cfg.Guilds.Limits.MaxMessageLength)
+	assert.Equal(t, true, cfg.Guilds.Limits.LockAnnouncements)
+
+	assert.Equal(t, 720*time.Hour, cfg.Retention.DepartedGuilds)
+	assert.Equal(t, time.Hour, cfg.Retention.PurgeInterval)
+
 	assert.Equal(t, "sqlite3", cfg.Database.Driver)
 	assert.Equal(t, "./test.db", cfg.Database.Dsn)
+	assert.Equal(t, 10, cfg.Database.MaxOpenConns)
+	assert.Equal(t, 0, cfg.Database.MaxIdleConns)
+	assert.Equal(t, 30*time.Minute, cfg.Database.ConnMaxLifetime)
+	assert.Equal(t, "wal", cfg.Database.SQLite.JournalMode)
+	assert.Equal(t, 5*time.Second, cfg.Database.SQLite.BusyTimeout)
 
 	assert.Equal(t, true, cfg.Redis.Enabled)
 	assert.Equal(t, "redis://localhost:6379/2", cfg.Redis.Url)
 	assert.Equal(t, 2*time.Hour, cfg.Redis.TTL)
+	assert.Equal(t, snowflake.ID(123456789012345678), cfg.Operator.ChannelID)
+	assert.Equal(t, 5*time.Minute, cfg.Operator.AlertInterval)
+}
+
+func TestLoadConfig_InvalidMaxMessageLength(t *testing.T) {
+	testcases := []struct {
+		name   string
+		config string
+	}{
+		{"negative default", "[guilds.defaults]\nmax_message_length = -1\n"},
+		{"zero default", "[guilds.defaults]\nmax_message_length = 0\n"},
+		{"default over the maximum", "[guilds.defaults]\nmax_message_length = 5000\n"},
+		{"limit below the minimum", "[guilds.limits]\nmax_message_length = 10\n"},
+		{"negative limit", "[guilds.limits]\nmax_message_length = -5\n"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.toml")
+			assert.NoError(t, os.WriteFile(configPath, []byte(tc.config), 0o600))
+			_, err := LoadConfig(configPath)
+			assert.ErrorContains(t, err, "max_message_length")
+		})
+	}
 }
@@ -0,0 +1,59 @@
+// Package databasetest opens migrated databases for repository integration tests.
+package databasetest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pressly/goose/v3"
+)
+
+// Database describes a database driver to run integration tests against.
+type Database struct {
+	Name         string
+	Driver       string
+	GooseDialect string
+	// DsnEnv is the environment variable holding the DSN. Empty means the DSN is fixed.
+	DsnEnv string
+	Dsn    string
+}
+
+// Databases are the databases repository tests run against.
+// SQLite always runs in memory, the others only run when their DSN is set in the environment.
+var Databases = []Database{
+	{Name: "sqlite", Driver: "sqlite", GooseDialect: "sqlite3", Dsn: "file::memory:?cache=shared"},
+	{Name: "postgres", Driver: "postgres", GooseDialect: "postgres", DsnEnv: "TTSBOT_TEST_POSTGRES_DSN"},
+	{Name: "mysql", Driver: "mysql", GooseDialect: "mysql", DsnEnv: "TTSBOT_TEST_MYSQL_DSN"},
+}
+
+// Open connects to the database and migrates it to the latest schema.
+// It skips the test if the DSN of the database is not configured.
+func (d Database) Open(t *testing.T, migrationsDir string) *sqlx.DB {
+	t.Helper()
+
+	dsn := d.Dsn
+	if d.DsnEnv != "" {
+		dsn = os.Getenv(d.DsnEnv)
+	}
+	if dsn == "" {
+		t.Skipf("%s is not set, skipping %s tests", d.DsnEnv, d.Name)
+	}
+
+	db, err := sqlx.Connect(d.Driver, dsn)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", d.Name, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// always use the latest schema
+	goose.SetBaseFS(nil)
+	if err := goose.SetDialect(d.GooseDialect); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	if err := goose.Up(db.DB, migrationsDir); err != nil {
+		t.Fatalf("failed to migrate %s: %v", d.Name, err)
+	}
+
+	return db
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect builds statements in the SQL flavor of a database driver.
+type Dialect interface {
+	// StatementBuilder returns a squirrel statement builder using the placeholder format of the driver.
+	StatementBuilder() squirrel.StatementBuilderType
+	// Upsert turns the insert statement into an upsert that overwrites updateColumns
+	// when a row with the same conflictColumns already exists.
+	Upsert(insert squirrel.InsertBuilder, conflictColumns []string, updateColumns []string) squirrel.InsertBuilder
+}
+
+// DialectOf returns the dialect matching the driver of the database.
+func DialectOf(db *sqlx.DB) Dialect {
+	return DialectFor(db.DriverName())
+}
+
+// DialectFor returns the dialect of the given driver name.
+// Unknown drivers fall back to the SQLite dialect, which uses question mark placeholders.
+func DialectFor(driverName string) Dialect {
+	switch driverName {
+	case "postgres", "pgx":
+		return postgresDialect{}
+	case "mysql":
+		return mysqlDialect{}
+	default:
+		return sqliteDialect{}
+	}
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) StatementBuilder() squirrel.StatementBuilderType {
+	return squirrel.StatementBuilder.PlaceholderFormat(squirrel.Question)
+}
+
+func (sqliteDialect) Upsert(insert squirrel.InsertBuilder, conflictColumns []string, updateColumns []string) squirrel.InsertBuilder {
+	return insert.Suffix(onConflictDoUpdate(conflictColumns, updateColumns))
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) StatementBuilder() squirrel.StatementBuilderType {
+	return squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+}
+
+func (postgresDialect) Upsert(insert squirrel.InsertBuilder, conflictColumns []string, updateColumns []string) squirrel.InsertBuilder {
+	return insert.Suffix(onConflictDoUpdate(conflictColumns, updateColumns))
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) StatementBuilder() squirrel.StatementBuilderType {
+	return squirrel.StatementBuilder.PlaceholderFormat(squirrel.Question)
+}
+
+func (mysqlDialect) Upsert(insert squirrel.InsertBuilder, _ []string, updateColumns []string) squirrel.InsertBuilder {
+	sets := make([]string, 0, len(updateColumns))
+	for _, column := range updateColumns {
+		sets = append(sets, fmt.Sprintf("%[1]s = VALUES(%[1]s)", column))
+	}
+	return insert.Suffix("ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", "))
+}
+
+// onConflictDoUpdate builds the upsert clause shared by SQLite and PostgreSQL.
+func onConflictDoUpdate(conflictColumns []string, updateColumns []string) string {
+	sets := make([]string, 0, len(updateColumns))
+	for _, column := range updateColumns {
+		sets = append(sets, fmt.Sprintf("%[1]s = excluded.%[1]s", column))
+	}
+	return fmt.Sprintf("ON CONFLICT(%s) DO UPDATE SET %s", strings.Join(conflictColumns, ", "), strings.Join(sets, ", "))
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestDialectUpsert(t *testing.T) {
+	testcases := []struct {
+		driver    string
+		wantQuery string
+	}{
+		{
+			driver:    "sqlite",
+			wantQuery: "INSERT INTO t (a,b,c) VALUES (?,?,?) ON CONFLICT(a) DO UPDATE SET b = excluded.b, c = excluded.c",
+		},
+		{
+			driver:    "postgres",
+			wantQuery: "INSERT INTO t (a,b,c) VALUES ($1,$2,$3) ON CONFLICT(a) DO UPDATE SET b = excluded.b, c = excluded.c",
+		},
+		{
+			driver:    "mysql",
+			wantQuery: "INSERT INTO t (a,b,c) VALUES (?,?,?) ON DUPLICATE KEY UPDATE b = VALUES(b), c = VALUES(c)",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.driver, func(t *testing.T) {
+			dialect := DialectFor(tc.driver)
+			insert := dialect.StatementBuilder().Insert("t").
+				Columns("a", "b", "c").
+				Values(1, 2, 3)
+			query, args, err := dialect.Upsert(insert, []string{"a"}, []string{"b", "c"}).ToSql()
+			if err != nil {
+				t.Fatalf("ToSql() error = %v", err)
+			}
+			if query != tc.wantQuery {
+				t.Errorf("ToSql() query = %q, want %q", query, tc.wantQuery)
+			}
+			if len(args) != 3 {
+				t.Errorf("ToSql() args = %v, want 3 args", args)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// encryptedPrefix marks values encrypted by a KeyRing, so plaintext values written
+// before encryption was enabled can still be read.
+const encryptedPrefix = "enc:v1:"
+
+var (
+	ErrUnknownKey       = errors.New("unknown encryption key")
+	ErrInvalidEncrypted = errors.New("invalid encrypted value")
+)
+
+// KeyRing encrypts designated columns at rest with AES-GCM.
+// Values are always encrypted with the primary key, and decrypted with the key they were encrypted with,
+// so keys can be rotated by adding a new primary key and re-encrypting values that NeedsRotation reports.
+type KeyRing struct {
+	primaryID string
+	aeads     map[string]cipher.AEAD
+}
+
+// NewKeyRing creates a key ring from AES keys (16, 24 or 32 bytes) indexed by key ID.
+func NewKeyRing(primaryID string, keys map[string][]byte) (*KeyRing, error) {
+	if _, ok := keys[primaryID]; !ok {
+		return nil, fmt.Errorf("primary key %q not found", primaryID)
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key ID %q", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", id, err)
+		}
+		aeads[id] = aead
+	}
+
+	return &KeyRing{
+		primaryID: primaryID,
+		aeads:     aeads,
+	}, nil
+}
+
+// LoadKeyRingFile loads a key ring from a secret file.
+// Each line is "<key ID>:<base64 encoded key>", the first key is the primary key,
+// and empty lines and lines starting with "#" are ignored.
+func LoadKeyRingFile(path string) (*KeyRing, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var primaryID string
+	keys := make(map[string][]byte)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, encoded, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid key line, expected <key ID>:<base64 key>")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", id, err)
+		}
+		if primaryID == "" {
+			primaryID = id
+		}
+		keys[id] = key
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewKeyRing(primaryID, keys)
+}
+
+// Encrypt encrypts the value with the primary key.
+func (k *KeyRing) Encrypt(plaintext string) (string, error) {
+	aead := k.aeads[k.primaryID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.primaryID))
+	return encryptedPrefix + k.primaryID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value returned by Encrypt. Values without the encryption prefix are returned as is.
+func (k *KeyRing) Decrypt(value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return value, nil
+	}
+
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", ErrInvalidEncrypted
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidEncrypted
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidEncrypted, err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether the value is plaintext or encrypted with a key other than the primary key.
+func (k *KeyRing) NeedsRotation(value string) bool {
+	rest, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return true
+	}
+	id, _, _ := strings.Cut(rest, ":")
+	return id != k.primaryID
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyRing(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	old, err := NewKeyRing("old", map[string][]byte{"old": oldKey})
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+	encrypted, err := old.Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if encrypted == "secret" {
+		t.Fatal("Encrypt() returned the plaintext")
+	}
+
+	rotated, err := NewKeyRing("new", map[string][]byte{"old": oldKey, "new": newKey})
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+	if !rotated.NeedsRotation(encrypted) {
+		t.Error("NeedsRotation() = false for a value encrypted with the old key")
+	}
+	decrypted, err := rotated.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if decrypted != "secret" {
+		t.Errorf("Decrypt() = %q, want %q", decrypted, "secret")
+	}
+
+	reencrypted, err := rotated.Encrypt(decrypted)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if rotated.NeedsRotation(reencrypted) {
+		t.Error("NeedsRotation() = true for a value encrypted with the primary key")
+	}
+
+	if _, err := old.Decrypt(reencrypted); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt() error = %v, want %v", err, ErrUnknownKey)
+	}
+
+	plaintext, err := rotated.Decrypt("written before encryption")
+	if err != nil || plaintext != "written before encryption" {
+		t.Errorf("Decrypt() = %q, %v, want plaintext to pass through", plaintext, err)
+	}
+	if !rotated.NeedsRotation("written before encryption") {
+		t.Error("NeedsRotation() = false for plaintext")
+	}
+}
+
+func TestKeyRingTampered(t *testing.T) {
+	keyRing, err := NewKeyRing("k", map[string][]byte{"k": bytes.Repeat([]byte{1}, 16)})
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+	encrypted, err := keyRing.Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	tampered := encrypted[:len(encrypted)-2] + "AA"
+	if _, err := keyRing.Decrypt(tampered); !errors.Is(err, ErrInvalidEncrypted) {
+		t.Errorf("Decrypt() error = %v, want %v", err, ErrInvalidEncrypted)
+	}
+}
+
+func TestLoadKeyRingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	content := "# rotated on 2025-10-16\n" +
+		"new:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32)) + "\n" +
+		"\n" +
+		"old:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)) + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+
+	keyRing, err := LoadKeyRingFile(path)
+	if err != nil {
+		t.Fatalf("LoadKeyRingFile() error = %v", err)
+	}
+	if keyRing.primaryID != "new" {
+		t.Errorf("primary key = %q, want %q", keyRing.primaryID, "new")
+	}
+	if len(keyRing.aeads) != 2 {
+		t.Errorf("loaded %d keys, want 2", len(keyRing.aeads))
+	}
+}
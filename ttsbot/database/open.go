@@ -0,0 +1,87 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Options tunes the connection pool and driver specific settings of a database.
+// Zero values keep the defaults of database/sql and the driver.
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	SQLite          SQLiteOptions
+}
+
+// SQLiteOptions are the pragmas applied to every SQLite connection.
+type SQLiteOptions struct {
+	// JournalMode is the journal mode, e.g. "wal". WAL lets readers run alongside a writer.
+	JournalMode string
+	// BusyTimeout is how long a connection waits for a lock before failing with "database is locked".
+	BusyTimeout time.Duration
+}
+
+// Open connects to the database and applies the options.
+// SQLite pragmas are added to the DSN, since they must be set on each connection of the pool.
+func Open(driverName, dsn string, opts Options) (*sqlx.DB, error) {
+	dsn = sqliteDSN(driverName, dsn, opts.SQLite)
+
+	db, err := sqlx.Connect(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	if opts.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
+
+	return db, nil
+}
+
+// sqliteDSN appends the pragmas to the DSN in the format of the SQLite driver.
+// DSNs of other drivers are returned as is.
+func sqliteDSN(driverName, dsn string, opts SQLiteOptions) string {
+	params := url.Values{}
+	switch driverName {
+	case "sqlite": // modernc.org/sqlite
+		if opts.JournalMode != "" {
+			params.Add("_pragma", fmt.Sprintf("journal_mode(%s)", opts.JournalMode))
+		}
+		if opts.BusyTimeout > 0 {
+			params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", opts.BusyTimeout.Milliseconds()))
+		}
+	case "sqlite3": // github.com/mattn/go-sqlite3
+		if opts.JournalMode != "" {
+			params.Set("_journal_mode", opts.JournalMode)
+		}
+		if opts.BusyTimeout > 0 {
+			params.Set("_busy_timeout", fmt.Sprint(opts.BusyTimeout.Milliseconds()))
+		}
+	default:
+		return dsn
+	}
+
+	if len(params) == 0 {
+		return dsn
+	}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + params.Encode()
+}
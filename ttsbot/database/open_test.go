@@ -0,0 +1,63 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLiteDSN(t *testing.T) {
+	opts := SQLiteOptions{JournalMode: "wal", BusyTimeout: 5 * time.Second}
+	testcases := []struct {
+		driver string
+		dsn    string
+		opts   SQLiteOptions
+		want   string
+	}{
+		{"sqlite", "./test.db", opts, "./test.db?_pragma=journal_mode%28wal%29&_pragma=busy_timeout%285000%29"},
+		{"sqlite", "file:test.db?cache=shared", opts, "file:test.db?cache=shared&_pragma=journal_mode%28wal%29&_pragma=busy_timeout%285000%29"},
+		{"sqlite3", "./test.db", opts, "./test.db?_busy_timeout=5000&_journal_mode=wal"},
+		{"sqlite", "./test.db", SQLiteOptions{}, "./test.db"},
+		{"postgres", "postgres://localhost/db", opts, "postgres://localhost/db"},
+	}
+
+	for _, tc := range testcases {
+		if got := sqliteDSN(tc.driver, tc.dsn, tc.opts); got != tc.want {
+			t.Errorf("sqliteDSN(%q, %q) = %q, want %q", tc.driver, tc.dsn, got, tc.want)
+		}
+	}
+}
+
+func TestOpenSQLite(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open("sqlite", dsn, Options{
+		MaxOpenConns: 4,
+		SQLite:       SQLiteOptions{JournalMode: "wal", BusyTimeout: 5 * time.Second},
+	})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	var journalMode string
+	if err := db.Get(&journalMode, "PRAGMA journal_mode"); err != nil {
+		t.Fatalf("failed to query journal_mode: %v", err)
+	}
+	if journalMode != "wal" {
+		t.Errorf("journal_mode = %q, want %q", journalMode, "wal")
+	}
+
+	var busyTimeout int
+	if err := db.Get(&busyTimeout, "PRAGMA busy_timeout"); err != nil {
+		t.Fatalf("failed to query busy_timeout: %v", err)
+	}
+	if busyTimeout != 5000 {
+		t.Errorf("busy_timeout = %d, want %d", busyTimeout, 5000)
+	}
+
+	if got := db.Stats().MaxOpenConnections; got != 4 {
+		t.Errorf("MaxOpenConnections = %d, want %d", got, 4)
+	}
+}
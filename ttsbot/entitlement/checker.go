@@ -0,0 +1,107 @@
+package entitlement
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+const (
+	// lookupTimeout is how long a lookup waits for the provider.
+	lookupTimeout = 5 * time.Second
+	// retryAfter is how long a failed lookup is not retried, so that a failing provider is not asked for every message.
+	retryAfter = time.Minute
+)
+
+// Checker caches the entitlements of guilds, so that quota enforcement and feature gates, which run for every message,
+// do not wait for the provider each time. A nil *Checker entitles no guild.
+type Checker struct {
+	provider Provider
+	ttl      time.Duration
+	clock    clock.Clock
+
+	mu     sync.Mutex
+	guilds map[snowflake.ID]cachedEntitlement
+}
+
+type cachedEntitlement struct {
+	entitled  bool
+	expiresAt time.Time
+}
+
+// NewChecker creates a checker asking the provider again once an entitlement is older than ttl.
+func NewChecker(provider Provider, ttl time.Duration) *Checker {
+	return &Checker{
+		provider: provider,
+		ttl:      ttl,
+		clock:    clock.Real,
+		guilds:   make(map[snowflake.ID]cachedEntitlement),
+	}
+}
+
+// Premium reports whether the guild is entitled to premium. Guilds not cached wait for the provider, at most lookupTimeout.
+// A failed lookup keeps the last known entitlement of the guild, or none.
+func (c *Checker) Premium(guildID snowflake.ID) bool {
+	if c == nil || guildID == 0 {
+		return false
+	}
+	now := c.clock.Now()
+	c.mu.Lock()
+	cached, ok := c.guilds[guildID]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.entitled
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	entitled, err := c.provider.Entitled(ctx, guildID)
+	if err != nil {
+		slog.Warn("Failed to look up the entitlement of the guild", slog.String("guildID", guildID.String()), slog.Any("err", err))
+		cached = cachedEntitlement{entitled: ok && cached.entitled, expiresAt: now.Add(retryAfter)}
+	} else {
+		cached = cachedEntitlement{entitled: entitled, expiresAt: now.Add(c.ttl)}
+	}
+	c.mu.Lock()
+	c.guilds[guildID] = cached
+	c.mu.Unlock()
+	return cached.entitled
+}
+
+// Forget drops the cached entitlement of the guild, so that it is looked up again, e.g. once it subscribed.
+func (c *Checker) Forget(guildID snowflake.ID) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.guilds, guildID)
+}
+
+// CreateEntitlementHandler forgets the entitlements of guilds whose discord entitlements changed,
+// so that subscriptions and cancellations apply without waiting for the cache to expire.
+func (c *Checker) CreateEntitlementHandler() bot.EventListener {
+	forget := func(event *events.GenericEntitlementEvent) {
+		if event.GuildID != nil {
+			c.Forget(*event.GuildID)
+		}
+	}
+	return &events.ListenerAdapter{
+		OnEntitlementCreate: func(event *events.EntitlementCreate) {
+			forget(event.GenericEntitlementEvent)
+		},
+		OnEntitlementUpdate: func(event *events.EntitlementUpdate) {
+			forget(event.GenericEntitlementEvent)
+		},
+		OnEntitlementDelete: func(event *events.EntitlementDelete) {
+			forget(event.GenericEntitlementEvent)
+		},
+	}
+}
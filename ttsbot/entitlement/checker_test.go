@@ -0,0 +1,100 @@
+package entitlement
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+// fakeProvider entitles the guilds of entitled, and fails while err is set.
+type fakeProvider struct {
+	entitled Static
+	err      error
+	lookups  int
+}
+
+func (p *fakeProvider) Entitled(ctx context.Context, guildID snowflake.ID) (bool, error) {
+	p.lookups++
+	if p.err != nil {
+		return false, p.err
+	}
+	return p.entitled.Entitled(ctx, guildID)
+}
+
+func newTestChecker(provider Provider) (*Checker, *clock.Fake) {
+	checker := NewChecker(provider, 10*time.Minute)
+	clk := clock.NewFake(time.Date(2025, 10, 17, 12, 0, 0, 0, time.UTC))
+	checker.clock = clk
+	return checker, clk
+}
+
+func TestChecker_Premium(t *testing.T) {
+	provider := &fakeProvider{entitled: NewStatic(1)}
+	checker, clk := newTestChecker(provider)
+
+	assert.True(t, checker.Premium(1))
+	assert.False(t, checker.Premium(2))
+	assert.False(t, checker.Premium(0), "guild ID 0 is outside of guilds")
+	assert.Equal(t, 2, provider.lookups)
+
+	// cached entitlements are not looked up again until they expire.
+	delete(provider.entitled, 1)
+	assert.True(t, checker.Premium(1))
+	assert.Equal(t, 2, provider.lookups)
+	clk.Advance(10 * time.Minute)
+	assert.False(t, checker.Premium(1))
+	assert.Equal(t, 3, provider.lookups)
+
+	provider.entitled[2] = struct{}{}
+	checker.Forget(2)
+	assert.True(t, checker.Premium(2))
+}
+
+func TestChecker_Premium_Failure(t *testing.T) {
+	provider := &fakeProvider{entitled: NewStatic(1)}
+	checker, clk := newTestChecker(provider)
+	assert.True(t, checker.Premium(1))
+
+	// a failing provider keeps the last known entitlement, and is retried after a while.
+	provider.err = errors.New("provider is down")
+	clk.Advance(10 * time.Minute)
+	assert.True(t, checker.Premium(1))
+	assert.False(t, checker.Premium(2))
+	assert.Equal(t, 3, provider.lookups)
+	assert.True(t, checker.Premium(1))
+	assert.Equal(t, 3, provider.lookups)
+
+	provider.err = nil
+	clk.Advance(retryAfter)
+	assert.False(t, checker.Premium(2))
+	assert.Equal(t, 4, provider.lookups)
+}
+
+func TestChecker_Nil(t *testing.T) {
+	var checker *Checker
+	assert.False(t, checker.Premium(1))
+	checker.Forget(1)
+}
+
+func TestAny(t *testing.T) {
+	failing := &fakeProvider{err: errors.New("provider is down")}
+	providers := Any{failing, NewStatic(1)}
+
+	entitled, err := providers.Entitled(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.True(t, entitled)
+
+	entitled, err = providers.Entitled(context.Background(), 2)
+	assert.Error(t, err)
+	assert.False(t, entitled)
+
+	entitled, err = Any{NewStatic(1)}.Entitled(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.False(t, entitled)
+}
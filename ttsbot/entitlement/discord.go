@@ -0,0 +1,60 @@
+package entitlement
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+var _ Provider = (*DiscordProvider)(nil)
+
+// errClientNotSet is returned by lookups made before the bot has started.
+var errClientNotSet = errors.New("discord entitlements are looked up before the client is set")
+
+// DiscordProvider entitles guilds with an active discord entitlement to one of the SKUs of the bot,
+// i.e. guilds subscribed to it through the app directory.
+// The client is only known once the bot has started, so guilds are not entitled before SetClient is called.
+type DiscordProvider struct {
+	skuIDs []snowflake.ID
+
+	mu            sync.RWMutex
+	applicationID snowflake.ID
+	applications  rest.Applications
+}
+
+// NewDiscordProvider creates a provider entitling guilds subscribed to any of the SKUs.
+func NewDiscordProvider(skuIDs []snowflake.ID) *DiscordProvider {
+	return &DiscordProvider{skuIDs: skuIDs}
+}
+
+// SetClient sets the application whose entitlements are looked up.
+func (p *DiscordProvider) SetClient(applicationID snowflake.ID, applications rest.Applications) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.applicationID = applicationID
+	p.applications = applications
+}
+
+func (p *DiscordProvider) Entitled(ctx context.Context, guildID snowflake.ID) (bool, error) {
+	p.mu.RLock()
+	applicationID, applications := p.applicationID, p.applications
+	p.mu.RUnlock()
+	if applications == nil {
+		return false, errClientNotSet
+	}
+
+	entitlements, err := applications.GetEntitlements(applicationID, rest.GetEntitlementsParams{
+		GuildID:        guildID,
+		SkuIDs:         p.skuIDs,
+		Limit:          1,
+		ExcludeEnded:   true,
+		ExcludeDeleted: true,
+	}, rest.WithCtx(ctx))
+	if err != nil {
+		return false, err
+	}
+	return len(entitlements) > 0, nil
+}
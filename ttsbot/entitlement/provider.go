@@ -0,0 +1,53 @@
+package entitlement
+
+import (
+	"context"
+	"errors"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// Provider tells whether a guild is entitled to premium, e.g. because it subscribed to the bot or its owner donated.
+// Operators plug in their own monetization by implementing it.
+type Provider interface {
+	Entitled(ctx context.Context, guildID snowflake.ID) (bool, error)
+}
+
+var _ Provider = Static(nil)
+
+// Static entitles a fixed set of guilds, e.g. listed by the operator in the config.
+type Static map[snowflake.ID]struct{}
+
+func NewStatic(guildIDs ...snowflake.ID) Static {
+	guilds := make(Static, len(guildIDs))
+	for _, guildID := range guildIDs {
+		guilds[guildID] = struct{}{}
+	}
+	return guilds
+}
+
+func (s Static) Entitled(_ context.Context, guildID snowflake.ID) (bool, error) {
+	_, ok := s[guildID]
+	return ok, nil
+}
+
+var _ Provider = Any(nil)
+
+// Any entitles guilds entitled by any of the providers, asked in order.
+// Providers that fail are only reported if no other provider entitles the guild.
+type Any []Provider
+
+func (a Any) Entitled(ctx context.Context, guildID snowflake.ID) (bool, error) {
+	var errs []error
+	for _, provider := range a {
+		entitled, err := provider.Entitled(ctx, guildID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if entitled {
+			return true, nil
+		}
+	}
+	return false, errors.Join(errs...)
+}
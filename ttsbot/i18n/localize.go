@@ -60,6 +60,8 @@ func load[S ~string, T any, U genericResources[S, T]](directory string, resource
 		}
 
 		locale := strings.TrimSuffix(entry.Name(), ".toml")
+		// decode each file into a fresh value, so that slices are not shared between locales.
+		var resource T
 
 		filePath := path.Join(directory, entry.Name())
 
@@ -0,0 +1,199 @@
+package i18n
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// voicePackMetadataFile is the file of a voice pack directory holding its name and date range.
+const voicePackMetadataFile = "pack.toml"
+
+// VoicePack is a themed set of voice resources, e.g. seasonal greetings, spoken instead of the base voice resources
+// while the date is within its range. Each locale of the pack only sets the entries it changes.
+type VoicePack struct {
+	ID   string
+	Name string
+	// Start and End are the first and the last day of the pack every year. The range may wrap the end of the year.
+	Start MonthDay
+	End   MonthDay
+
+	resources genericResources[string, VoiceResource]
+}
+
+// MonthDay is a day of the year, e.g. "12-24" for December 24.
+type MonthDay struct {
+	Month time.Month
+	Day   int
+}
+
+// ParseMonthDay parses a day of the year in the form "MM-DD".
+func ParseMonthDay(s string) (MonthDay, error) {
+	t, err := time.Parse("01-02", s)
+	if err != nil {
+		return MonthDay{}, fmt.Errorf("invalid day of the year %q, expected MM-DD: %w", s, err)
+	}
+	return MonthDay{Month: t.Month(), Day: t.Day()}, nil
+}
+
+func (d MonthDay) String() string {
+	return fmt.Sprintf("%02d-%02d", int(d.Month), d.Day)
+}
+
+// before reports whether d comes before other in a year.
+func (d MonthDay) before(other MonthDay) bool {
+	return d.Month < other.Month || d.Month == other.Month && d.Day < other.Day
+}
+
+// ActiveAt reports whether the day of t is within the date range of the pack.
+func (p VoicePack) ActiveAt(t time.Time) bool {
+	day := MonthDay{Month: t.Month(), Day: t.Day()}
+	if p.End.before(p.Start) {
+		return !day.before(p.Start) || !p.End.before(day)
+	}
+	return !day.before(p.Start) && !p.End.before(day)
+}
+
+// LoadPacks loads every voice pack in a subdirectory of the directory, named by the ID of the pack.
+// A pack directory holds pack.toml with the name and the date range of the pack, e.g.
+//
+//	name = "New Year"
+//	start = "01-01"
+//	end = "01-03"
+//
+// and a TOML file per locale, which is layered over the voice resource of that locale.
+// A missing directory loads no packs, as packs are optional.
+func (vrs *VoiceResources) LoadPacks(directory string) error {
+	entries, err := os.ReadDir(directory)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read voice packs directory: %w", err)
+	}
+
+	packs := make(map[string]VoicePack)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		pack, err := vrs.loadPack(path.Join(directory, entry.Name()), entry.Name())
+		if err != nil {
+			return err
+		}
+		packs[pack.ID] = pack
+		slog.Info("Loaded the voice pack", "pack", pack.ID, "start", pack.Start.String(), "end", pack.End.String())
+	}
+	vrs.packs = packs
+	return nil
+}
+
+func (vrs *VoiceResources) loadPack(directory, id string) (VoicePack, error) {
+	var metadata struct {
+		Name  string `toml:"name"`
+		Start string `toml:"start"`
+		End   string `toml:"end"`
+	}
+	metadataPath := path.Join(directory, voicePackMetadataFile)
+	decoded, err := toml.DecodeFile(metadataPath, &metadata)
+	if err != nil {
+		return VoicePack{}, fmt.Errorf("failed to decode voice pack file %s: %w", metadataPath, err)
+	}
+	if len(decoded.Undecoded()) > 0 {
+		return VoicePack{}, fmt.Errorf("voice pack file %s contains undecoded fields: %v", metadataPath, decoded.Undecoded())
+	}
+
+	pack := VoicePack{
+		ID:        id,
+		Name:      metadata.Name,
+		resources: make(genericResources[string, VoiceResource]),
+	}
+	if pack.Name == "" {
+		pack.Name = id
+	}
+	if pack.Start, err = ParseMonthDay(metadata.Start); err != nil {
+		return VoicePack{}, fmt.Errorf("voice pack %s: %w", id, err)
+	}
+	if pack.End, err = ParseMonthDay(metadata.End); err != nil {
+		return VoicePack{}, fmt.Errorf("voice pack %s: %w", id, err)
+	}
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return VoicePack{}, fmt.Errorf("failed to read voice pack directory %s: %w", directory, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".toml") || entry.Name() == voicePackMetadataFile {
+			continue
+		}
+		locale := strings.TrimSuffix(entry.Name(), ".toml")
+		base, ok := vrs.Get(locale)
+		if !ok {
+			return VoicePack{}, fmt.Errorf("voice pack %s has locale %s, which has no voice resource to layer it over", id, locale)
+		}
+		resource, err := layerVoiceResource(base, path.Join(directory, entry.Name()))
+		if err != nil {
+			return VoicePack{}, err
+		}
+		pack.resources[locale] = resource
+	}
+	return pack, nil
+}
+
+// layerVoiceResource returns the base resource with the entries of the file decoded over it.
+func layerVoiceResource(base VoiceResource, filePath string) (VoiceResource, error) {
+	// the base is copied through TOML, as decoding over a shallow copy would write into the lists of the base.
+	var encoded bytes.Buffer
+	if err := toml.NewEncoder(&encoded).Encode(base); err != nil {
+		return VoiceResource{}, err
+	}
+	var resource VoiceResource
+	if _, err := toml.Decode(encoded.String(), &resource); err != nil {
+		return VoiceResource{}, err
+	}
+
+	decoded, err := toml.DecodeFile(filePath, &resource)
+	if err != nil {
+		return VoiceResource{}, fmt.Errorf("failed to decode voice pack file %s: %w", filePath, err)
+	}
+	if len(decoded.Undecoded()) > 0 {
+		return VoiceResource{}, fmt.Errorf("voice pack file %s contains undecoded fields: %v", filePath, decoded.Undecoded())
+	}
+	return resource, nil
+}
+
+// Pack returns the voice pack with the ID.
+func (vrs *VoiceResources) Pack(id string) (VoicePack, bool) {
+	pack, ok := vrs.packs[id]
+	return pack, ok
+}
+
+// PackIDs returns the IDs of the loaded voice packs in order.
+func (vrs *VoiceResources) PackIDs() []string {
+	return slices.Sorted(maps.Keys(vrs.packs))
+}
+
+// GetThemed returns the resource of the locale like GetOrGeneric,
+// from the first of the enabled packs that is active at t and has the locale.
+// Without such a pack, the base resource is returned.
+func (vrs *VoiceResources) GetThemed(locale string, packIDs []string, t time.Time) (VoiceResource, bool) {
+	for _, id := range packIDs {
+		pack, ok := vrs.packs[id]
+		if !ok || !pack.ActiveAt(t) {
+			continue
+		}
+		if resource, ok := pack.resources.GetOrGeneric(locale); ok {
+			return resource, true
+		}
+	}
+	return vrs.GetOrGeneric(locale)
+}
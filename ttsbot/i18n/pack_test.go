@@ -0,0 +1,117 @@
+package i18n
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestVoiceResources_LoadPacks(t *testing.T) {
+	vrs, err := LoadVoiceResources("../../locales/voice/")
+	if err != nil {
+		t.Fatalf("Failed to load voice resources: %v", err)
+	}
+	base, _ := vrs.Get("en")
+	january := base.Timestamp.Months[0]
+
+	if err := vrs.LoadPacks("testdata/packs"); err != nil {
+		t.Fatalf("Failed to load voice packs: %v", err)
+	}
+	if ids := vrs.PackIDs(); !slices.Equal(ids, []string{"winter"}) {
+		t.Fatalf("Expected pack winter, got %v", ids)
+	}
+	if pack, _ := vrs.Pack("winter"); pack.Name != "Winter" {
+		t.Errorf("Expected name Winter, got %q", pack.Name)
+	}
+	for _, err := range vrs.Verify() {
+		t.Error(err)
+	}
+
+	christmas := time.Date(2025, time.December, 25, 12, 0, 0, 0, time.UTC)
+	themed, ok := vrs.GetThemed("en-US", []string{"unknown", "winter"}, christmas)
+	if !ok {
+		t.Fatal("No voice resource for en-US")
+	}
+	if themed.Session.Launch != "happy holidays, text-to-speech is ready" || themed.Timestamp.Months[0] != "Jan" {
+		t.Errorf("Expected the entries of the pack, got %q and %q", themed.Session.Launch, themed.Timestamp.Months[0])
+	}
+	if themed.Session.Farewell != base.Session.Farewell {
+		t.Errorf("Expected the farewell of the base, got %q", themed.Session.Farewell)
+	}
+	if unchanged, _ := vrs.Get("en"); unchanged.Timestamp.Months[0] != january {
+		t.Errorf("Layering the pack changed the base to %q", unchanged.Timestamp.Months[0])
+	}
+
+	tests := []struct {
+		name    string
+		locale  string
+		packIDs []string
+		t       time.Time
+	}{
+		{"not enabled", "en", nil, christmas},
+		{"out of range", "en", []string{"winter"}, christmas.AddDate(0, 3, 0)},
+		{"other locale", "ja", []string{"winter"}, christmas},
+	}
+	for _, tt := range tests {
+		want, _ := vrs.Get(tt.locale)
+		if got, _ := vrs.GetThemed(tt.locale, tt.packIDs, tt.t); got.Session.Launch != want.Session.Launch {
+			t.Errorf("%s: expected the base launch %q, got %q", tt.name, want.Session.Launch, got.Session.Launch)
+		}
+	}
+
+	if err := vrs.LoadPacks("testdata/missing"); err != nil {
+		t.Errorf("Expected no error for a missing directory, got %v", err)
+	}
+}
+
+func TestVoiceResources_LoadShippedPacks(t *testing.T) {
+	vrs, err := LoadVoiceResources("../../locales/voice/")
+	if err != nil {
+		t.Fatalf("Failed to load voice resources: %v", err)
+	}
+	if err := vrs.LoadPacks("../../locales/packs/"); err != nil {
+		t.Fatalf("Failed to load voice packs: %v", err)
+	}
+	if len(vrs.PackIDs()) == 0 {
+		t.Error("No voice packs loaded")
+	}
+	for _, err := range vrs.Verify() {
+		t.Error(err)
+	}
+}
+
+func TestVoicePack_ActiveAt(t *testing.T) {
+	winter := VoicePack{Start: MonthDay{time.December, 20}, End: MonthDay{time.January, 10}}
+	halloween := VoicePack{Start: MonthDay{time.October, 25}, End: MonthDay{time.October, 31}}
+	tests := []struct {
+		pack  VoicePack
+		month time.Month
+		day   int
+		want  bool
+	}{
+		{winter, time.December, 19, false},
+		{winter, time.December, 20, true},
+		{winter, time.December, 31, true},
+		{winter, time.January, 10, true},
+		{winter, time.January, 11, false},
+		{winter, time.July, 1, false},
+		{halloween, time.October, 31, true},
+		{halloween, time.November, 1, false},
+	}
+	for _, tt := range tests {
+		if got := tt.pack.ActiveAt(time.Date(2025, tt.month, tt.day, 12, 0, 0, 0, time.UTC)); got != tt.want {
+			t.Errorf("ActiveAt(%s %d) of %s to %s = %v, want %v", tt.month, tt.day, tt.pack.Start, tt.pack.End, got, tt.want)
+		}
+	}
+}
+
+func TestParseMonthDay(t *testing.T) {
+	if day, err := ParseMonthDay("02-29"); err != nil || day != (MonthDay{time.February, 29}) {
+		t.Errorf("ParseMonthDay(02-29) = %v, %v", day, err)
+	}
+	for _, s := range []string{"13-01", "Dec 24", ""} {
+		if _, err := ParseMonthDay(s); err == nil {
+			t.Errorf("ParseMonthDay(%q) succeeded", s)
+		}
+	}
+}
@@ -24,6 +24,8 @@ type TextResource struct {
 			Language     string `toml:"language"`      // format: "Language"
 			VoiceName    string `toml:"voice_name"`    // format: "Voice Name"
 			SpeakingRate string `toml:"speaking_rate"` // format: "Speaking Rate"
+			Pitch        string `toml:"pitch"`         // format: "Pitch"
+			VolumeGain   string `toml:"volume_gain"`   // format: "Volume Gain"
 		} `toml:"preset"`
 		TTS struct {
 			Ready         string `toml:"ready"`           // format: "Text-to-Speech Ready"
@@ -31,18 +33,124 @@ type TextResource struct {
 			VoiceChannel  string `toml:"voice_channel"`   // format: "Voice Channel"
 			End           string `toml:"end"`             // format: "Text-to-Speech Ended"
 			Thanks        string `toml:"thanks"`          // format: "Thank you for using the Text-to-Speech service!"
+			Paused        string `toml:"paused"`          // format: "Reading Paused"
+			PausedMuted   string `toml:"paused_muted"`    // format: "The bot was server-muted, so messages are not read until it is unmuted."
+			Resumed       string `toml:"resumed"`         // format: "Reading Resumed"
+			ResumedMuted  string `toml:"resumed_muted"`   // format: "The bot was unmuted, so messages are read again."
+			Moved         string `toml:"moved"`           // format: "Voice Channel Changed"
+			MovedTo       string `toml:"moved_to"`        // format: "The bot was moved to %[1]s and keeps reading there."
+			Lagging       string `toml:"lagging"`         // format: "Reading Lags Behind"
+			LaggingQueued string `toml:"lagging_queued"`  // format: "Too much speech is waiting to be played, so new messages are read once it has been played."
+			Summary       struct {
+				Duration        string `toml:"duration"`         // format: "Duration"
+				DurationHours   string `toml:"duration_hours"`   // format: "%[1]d h %[2]d min"
+				DurationMinutes string `toml:"duration_minutes"` // format: "%[1]d min %[2]d s"
+				Messages        string `toml:"messages"`         // format: "Messages Read"
+				Characters      string `toml:"characters"`       // format: "Characters Read"
+				TopSpeakers     string `toml:"top_speakers"`     // format: "Top Speakers"
+				Speaker         string `toml:"speaker"`          // format: "%[1]s: %[2]d messages"
+			} `toml:"summary"`
+			CloseReason struct {
+				Leave        string `toml:"leave"`         // format: "Stopped by a member"
+				Empty        string `toml:"empty"`         // format: "Everyone left the voice channel"
+				Takeover     string `toml:"takeover"`      // format: "Moved to another voice channel"
+				GuildRemoved string `toml:"guild_removed"` // format: "The bot was removed from the server"
+				Disconnected string `toml:"disconnected"`  // format: "The bot was disconnected from the voice channel"
+				Duplicate    string `toml:"duplicate"`     // format: "The bot is already reading in another voice channel of the server"
+			} `toml:"close_reason"`
 		} `toml:"tts"`
-		Engines map[string]string `toml:"engines"` // format: "engine_name": "Engine Display Name"
+		Engines  map[string]string `toml:"engines"` // format: "engine_name": "Engine Display Name"
+		Settings struct {
+			Self             string `toml:"self"`               // format: "Settings"
+			None             string `toml:"none"`               // format: "None"
+			Enabled          string `toml:"enabled"`            // format: "Enabled"
+			Disabled         string `toml:"disabled"`           // format: "Disabled"
+			TakeoverPolicy   string `toml:"takeover_policy"`    // format: "Takeover Policy"
+			SilentRoles      string `toml:"silent_roles"`       // format: "Silent Roles"
+			VoiceActivity    string `toml:"voice_activity"`     // format: "Voice Activity Announcements"
+			AnnounceJoin     string `toml:"announce_join"`      // format: "Join Announcements"
+			AnnounceLeave    string `toml:"announce_leave"`     // format: "Leave Announcements"
+			AnnounceLaunch   string `toml:"announce_launch"`    // format: "Launch Phrase"
+			AnnounceFarewell string `toml:"announce_farewell"`  // format: "Farewell Phrase"
+			SkipReaction     string `toml:"skip_reaction"`      // format: "Skipped Message Reaction"
+			MaxMessageLength string `toml:"max_message_length"` // format: "Max Message Length"
+			Characters       string `toml:"characters"`         // format: "%[1]d characters"
+			TakeoverPolicies struct {
+				Confirm string `toml:"confirm"` // format: "Ask for confirmation"
+				Move    string `toml:"move"`    // format: "Move immediately"
+				Refuse  string `toml:"refuse"`  // format: "Refuse"
+			} `toml:"takeover_policies"`
+			CodeBlockMode     string `toml:"code_block_mode"`    // format: "Code Blocks"
+			OmitStrikethrough string `toml:"omit_strikethrough"` // format: "Skip Strikethrough"
+			AnnounceMarkdown  string `toml:"announce_markdown"`  // format: "Announce Quotes and Headings"
+			Timezone          string `toml:"timezone"`           // format: "Timezone"
+			NameSource        string `toml:"name_source"`        // format: "Speaker Name"
+			NameSources       struct {
+				Nickname    string `toml:"nickname"`     // format: "Server nickname"
+				DisplayName string `toml:"display_name"` // format: "Display name"
+				Username    string `toml:"username"`     // format: "Username"
+			} `toml:"name_sources"`
+			StripNameDecorations string `toml:"strip_name_decorations"` // format: "Strip Name Decorations"
+			CommandPrefix        string `toml:"command_prefix"`         // format: "Text Command Prefix"
+			SelfDeaf             string `toml:"self_deaf"`              // format: "Deafened"
+			SelfMute             string `toml:"self_mute"`              // format: "Shown as Muted"
+			YieldToBots          string `toml:"yield_to_bots"`          // format: "Yield to Bots"
+			BargeIn              string `toml:"barge_in"`               // format: "When Members Speak"
+			VoiceState           string `toml:"voice_state"`            // format: "Voice State"
+			Webhook              string `toml:"webhook"`                // format: "Webhook"
+			EphemeralResponses   string `toml:"ephemeral_responses"`    // format: "Private Responses"
+			Announcements        string `toml:"announcements"`          // format: "Custom Announcements"
+			VoicePacks           string `toml:"voice_packs"`            // format: "Voice Packs"
+			TranscriptChannel    string `toml:"transcript_channel"`     // format: "Transcript"
+			SpellOut             string `toml:"spell_out"`              // format: "Spell Out"
+			VoiceTags            string `toml:"voice_tags"`             // format: "Voice Tags"
+			CodeSwitch           string `toml:"code_switch"`            // format: "Code-Switching"
+			SoftenAsides         string `toml:"soften_asides"`          // format: "Soft Asides"
+			VoiceSwitching       string `toml:"voice_switching"`        // format: "Voice Changes"
+			AnnouncementKeys     struct {
+				Launch string `toml:"launch"` // format: "Launch phrase"
+				Join   string `toml:"join"`   // format: "Join"
+				Leave  string `toml:"leave"`  // format: "Leave"
+			} `toml:"announcement_keys"`
+			CodeBlockModes struct {
+				Announce  string `toml:"announce"`   // format: "Read the language only"
+				Skip      string `toml:"skip"`       // format: "Skip"
+				FirstLine string `toml:"first_line"` // format: "Read the first line"
+			} `toml:"code_block_modes"`
+			BargeIns struct {
+				Off   string `toml:"off"`   // format: "Keep reading"
+				Duck  string `toml:"duck"`  // format: "Lower the volume"
+				Pause string `toml:"pause"` // format: "Pause"
+			} `toml:"barge_ins"`
+		} `toml:"settings"`
+		Permissions struct {
+			ViewChannel    string `toml:"view_channel"`     // format: "View Channel"
+			Connect        string `toml:"connect"`          // format: "Connect"
+			Speak          string `toml:"speak"`            // format: "Speak"
+			RequestToSpeak string `toml:"request_to_speak"` // format: "Request to Speak"
+		} `toml:"permissions"`
 	} `toml:"generic"`
 	Commands struct {
 		Generic struct {
 			ErrorNotInGuild              string `toml:"error_not_in_guild"`             // format: "You must use this command in a guild"
 			ErrorNotInVoiceChannel       string `toml:"error_not_in_voice_channel"`     // format: "You must be in a voice channel to use this command"
 			ErrorInsufficientPermissions string `toml:"error_insufficient_permissions"` // format: "Bot has insufficient permissions."
+			ErrorNotInstalled            string `toml:"error_not_installed"`            // format: "The bot must be added to this server to use this command"
+			ErrorUnexpected              string `toml:"error_unexpected"`               // format: "Something went wrong. If it keeps happening, report the error ID `%[1]s`"
 		} `toml:"generic"`
 		Join struct {
-			Description         string `toml:"description"`           // format: "Start text-to-speech in text channels"
-			ErrorAlreadyStarted string `toml:"error_already_started"` // format: "Text-to-speech has already been started"
+			Description             string `toml:"description"`               // format: "Start text-to-speech in text channels"
+			ErrorAlreadyStarted     string `toml:"error_already_started"`     // format: "Text-to-speech has already been started"
+			ErrorAlreadyRunning     string `toml:"error_already_running"`     // format: "Text-to-speech is already running in %[1]s"
+			ErrorGuildBusy          string `toml:"error_guild_busy"`          // format: "Text-to-speech is being started in another voice channel of this server"
+			ErrorMissingPermissions string `toml:"error_missing_permissions"` // format: "Bot is missing the following permissions in %[1]s: %[2]s"
+			Takeover                struct {
+				Confirm         string `toml:"confirm"`           // format: "Text-to-speech is already running in %[1]s. Move it to %[2]s?"
+				Move            string `toml:"move"`              // format: "Move"
+				Cancel          string `toml:"cancel"`            // format: "Cancel"
+				Cancelled       string `toml:"cancelled"`         // format: "Text-to-speech was not moved"
+				ErrorNotInvoker string `toml:"error_not_invoker"` // format: "Only the user who ran the command can answer this"
+			} `toml:"takeover"`
 		} `toml:"join"`
 		Leave struct {
 			Description     string `toml:"description"`       // format: "Stop text-to-speech in text channels"
@@ -51,16 +159,90 @@ type TextResource struct {
 		Version struct {
 			Description string `toml:"description"` // format: "Show bot version information"
 		} `toml:"version"`
+		Debug struct {
+			Description         string `toml:"description"`          // format: "Show diagnostic information for this server"
+			Title               string `toml:"title"`                // format: "Diagnostics"
+			Sessions            string `toml:"sessions"`             // format: "Sessions"
+			Session             string `toml:"session"`              // format: "Session"
+			SessionValue        string `toml:"session_value"`        // format: "%[1]s → %[2]s (region: %[3]s)"
+			RegionAutomatic     string `toml:"region_automatic"`     // format: "automatic"
+			VoiceEndpoint       string `toml:"voice_endpoint"`       // format: "Voice Endpoint"
+			VoiceRegion         string `toml:"voice_region"`         // format: "Voice Region"
+			ConsecutiveFailures string `toml:"consecutive_failures"` // format: "Consecutive Failures"
+			LastFailure         string `toml:"last_failure"`         // format: "Last Failure"
+			SynthesisLatency    string `toml:"synthesis_latency"`    // format: "Synthesis Latency"
+			CommandLatency      string `toml:"command_latency"`      // format: "Command Latency"
+		} `toml:"debug"`
+		Admin struct {
+			Description   string `toml:"description"`     // format: "Manage the bot (bot owners only)"
+			ErrorNotOwner string `toml:"error_not_owner"` // format: "Only the owners of the bot can use this command."
+			Sync          struct {
+				Description       string `toml:"description"`         // format: "Sync the commands of the bot with discord"
+				Scope             string `toml:"scope"`               // format: "Where to sync the commands"
+				Guild             string `toml:"guild"`               // format: "Synced the commands of this server: %[1]s"
+				Global            string `toml:"global"`              // format: "Synced the global commands: %[1]s"
+				UpToDate          string `toml:"up_to_date"`          // format: "The global commands are up to date."
+				ConfirmGlobal     string `toml:"confirm_global"`      // format: "Sync the global commands? This changes the commands in every server.\n%[1]s"
+				ConfirmButton     string `toml:"confirm_button"`      // format: "Sync globally"
+				ErrorNotConfirmer string `toml:"error_not_confirmer"` // format: "Only the owner who ran the command can confirm the sync."
+				ErrorGuild        string `toml:"error_guild"`         // format: "Failed to sync the commands of this server: %[1]s"
+				ErrorFetchGlobal  string `toml:"error_fetch_global"`  // format: "Failed to fetch the global commands: %[1]s"
+				ErrorGlobal       string `toml:"error_global"`        // format: "Failed to sync the global commands: %[1]s"
+			} `toml:"sync"`
+			Usage struct {
+				Description       string `toml:"description"`         // format: "Show the characters every server had read in a month, with their estimated cost"
+				Month             string `toml:"month"`               // format: "The month to show, e.g. 2025-10. Defaults to this month"
+				Summary           string `toml:"summary"`             // format: "Usage in %[1]s: %[2]d characters in %[3]d servers, estimated cost %[4]s"
+				Guild             string `toml:"guild"`               // format: "%[1]d. %[2]s: %[3]d characters (%[4]s), %[5]s"
+				GuildBudget       string `toml:"guild_budget"`        // format: ", %[1]d%% of its budget"
+				More              string `toml:"more"`                // format: "… and %[1]d more servers"
+				Empty             string `toml:"empty"`               // format: "Nothing was read in this month."
+				ErrorInvalidMonth string `toml:"error_invalid_month"` // format: "Enter the month as YYYY-MM, e.g. 2025-10."
+				ErrorLoad         string `toml:"error_load"`          // format: "Failed to load the usage: %[1]s"
+			} `toml:"usage"`
+		} `toml:"admin"`
+		Voices struct {
+			Description          string `toml:"description"`            // format: "List the voices of an engine for a language"
+			Engine               string `toml:"engine"`                 // format: "The engine to list the voices of, e.g. google"
+			Language             string `toml:"language"`               // format: "The language the voices speak, e.g. ja or en-US"
+			Title                string `toml:"title"`                  // format: "Voices of %[1]s for %[2]s"
+			Page                 string `toml:"page"`                   // format: "Page %[1]d of %[2]d, %[3]d voices"
+			Previous             string `toml:"previous"`               // format: "Previous"
+			Next                 string `toml:"next"`                   // format: "Next"
+			Empty                string `toml:"empty"`                  // format: "No voice speaks this language"
+			ErrorUnknownEngine   string `toml:"error_unknown_engine"`   // format: "This engine is not available"
+			ErrorInvalidLanguage string `toml:"error_invalid_language"` // format: "Enter a language code such as ja or en-US"
+			ErrorUnsupported     string `toml:"error_unsupported"`      // format: "This engine can not list its voices"
+		} `toml:"voices"`
+		Usage struct {
+			Description string `toml:"description"` // format: "Show the characters this server had read this month"
+			Title       string `toml:"title"`       // format: "Usage for %[1]s"
+			Total       string `toml:"total"`       // format: "Total"
+			Budget      string `toml:"budget"`      // format: "Monthly Budget"
+			Unlimited   string `toml:"unlimited"`   // format: "Unlimited"
+			Characters  string `toml:"characters"`  // format: "%[1]d characters"
+			Empty       string `toml:"empty"`       // format: "Nothing has been read this month"
+		} `toml:"usage"`
+		Preview struct {
+			Description string `toml:"description"` // format: "Show how a message would be read, without reading it"
+			Text        string `toml:"text"`        // format: "The message to preview"
+			Title       string `toml:"title"`       // format: "Preview"
+			Preset      string `toml:"preset"`      // format: "Preset"
+			Segments    string `toml:"segments"`    // format: "Read As"
+			Empty       string `toml:"empty"`       // format: "Nothing would be read"
+			Truncated   string `toml:"truncated"`   // format: "The message is cut at the max message length of the server"
+		} `toml:"preview"`
 		Preset struct {
 			Description string `toml:"description"` // format: "Manage presets for text-to-speech"
 			Generic     struct {
 				Description string `toml:"description"` // format: "Manage %[1]s presets"
 				Set         struct {
-					Description   string `toml:"description"`     // format: "Set a preset for the %[1]s"
-					Name          string `toml:"name"`            // format: "Name of the preset to set"
-					Success       string `toml:"success"`         // format: "Preset for %[1]s has been set to %[2]s"
-					ErrorNotFound string `toml:"error_not_found"` // format: "Preset %[1]s not found"
-					ErrorSave     string `toml:"error_save"`      // format: "Failed to save preset ID"
+					Description     string `toml:"description"`      // format: "Set a preset for the %[1]s"
+					Name            string `toml:"name"`             // format: "Name of the preset to set"
+					Success         string `toml:"success"`          // format: "Preset for %[1]s has been set to %[2]s"
+					ErrorNotFound   string `toml:"error_not_found"`  // format: "Preset %[1]s not found"
+					ErrorRestricted string `toml:"error_restricted"` // format: "Preset %[1]s is not available in this server"
+					ErrorSave       string `toml:"error_save"`       // format: "Failed to save preset ID"
 				} `toml:"set"`
 				Unset struct {
 					Description string `toml:"description"`  // format: "Unset a preset for the %[1]s"
@@ -79,6 +261,205 @@ type TextResource struct {
 				Description string `toml:"description"` // format: "List all presets"
 			} `toml:"list"`
 		} `toml:"preset"`
+		Settings struct {
+			Description string `toml:"description"` // format: "Manage guild settings"
+			ErrorFetch  string `toml:"error_fetch"` // format: "Failed to fetch guild settings"
+			ErrorSave   string `toml:"error_save"`  // format: "Failed to save guild settings"
+			ErrorLimit  string `toml:"error_limit"` // format: "This exceeds a limit set by the bot operator"
+			Show        struct {
+				Description string `toml:"description"` // format: "Show the current guild settings"
+			} `toml:"show"`
+			Takeover struct {
+				Description string `toml:"description"` // format: "Set what happens when /join is run for another voice channel"
+				Policy      string `toml:"policy"`      // format: "What to do with the running session"
+				Success     string `toml:"success"`     // format: "Takeover policy has been set to %[1]s"
+			} `toml:"takeover"`
+			VoiceActivity struct {
+				Description string `toml:"description"` // format: "Set whether to announce streaming and stage changes"
+				Enabled     string `toml:"enabled"`     // format: "Whether to announce"
+				Success     string `toml:"success"`     // format: "Voice activity announcements: %[1]s"
+			} `toml:"voice_activity"`
+			Announcements struct {
+				Description string `toml:"description"` // format: "Set which announcements are spoken"
+				Join        string `toml:"join"`        // format: "Announce members joining the voice channel"
+				Leave       string `toml:"leave"`       // format: "Announce members leaving the voice channel"
+				Launch      string `toml:"launch"`      // format: "Announce that text-to-speech is ready"
+				Farewell    string `toml:"farewell"`    // format: "Say farewell before leaving the voice channel"
+			} `toml:"announcements"`
+			SkipReaction struct {
+				Description string `toml:"description"` // format: "Set the emoji reacted to messages that were not read"
+				Emoji       string `toml:"emoji"`       // format: "The emoji to react with, leave empty to disable"
+				Success     string `toml:"success"`     // format: "Skipped message reaction: %[1]s"
+			} `toml:"skip_reaction"`
+			CodeBlock struct {
+				Description string `toml:"description"` // format: "Set how code blocks are read"
+				Mode        string `toml:"mode"`        // format: "How to read code blocks"
+				Success     string `toml:"success"`     // format: "Code blocks: %[1]s"
+			} `toml:"code_block"`
+			Strikethrough struct {
+				Description string `toml:"description"` // format: "Set whether struck-through text is skipped"
+				Omit        string `toml:"omit"`        // format: "Whether to skip struck-through text"
+				Success     string `toml:"success"`     // format: "Skip strikethrough: %[1]s"
+			} `toml:"strikethrough"`
+			Markdown struct {
+				Description string `toml:"description"` // format: "Set whether quotes and headings are announced"
+				Announce    string `toml:"announce"`    // format: "Whether to announce quotes and headings"
+				Success     string `toml:"success"`     // format: "Announce quotes and headings: %[1]s"
+			} `toml:"markdown"`
+			Timezone struct {
+				Description  string `toml:"description"`   // format: "Set the timezone timestamps are read in"
+				Timezone     string `toml:"timezone"`      // format: "IANA timezone name, e.g. Asia/Tokyo"
+				Success      string `toml:"success"`       // format: "Timezone has been set to %[1]s"
+				ErrorInvalid string `toml:"error_invalid"` // format: "%[1]s is not a valid timezone"
+			} `toml:"timezone"`
+			SpeakerName struct {
+				Description      string `toml:"description"`       // format: "Set how speaker names are read"
+				Source           string `toml:"source"`            // format: "Which name to read"
+				StripDecorations string `toml:"strip_decorations"` // format: "Remove emojis and decorations from names"
+			} `toml:"speaker_name"`
+			CommandPrefix struct {
+				Description  string `toml:"description"`   // format: "Set the prefix of text commands in the reading channel"
+				Prefix       string `toml:"prefix"`        // format: "The prefix, e.g. ;, leave empty to disable"
+				Success      string `toml:"success"`       // format: "Text command prefix: %[1]s"
+				ErrorInvalid string `toml:"error_invalid"` // format: "The prefix must be at most %[1]d characters without spaces"
+			} `toml:"command_prefix"`
+			VoiceState struct {
+				Description string `toml:"description"`   // format: "Set how the bot appears in the voice channel"
+				Deaf        string `toml:"deaf"`          // format: "Deafen the bot, so it visibly does not listen"
+				Mute        string `toml:"mute"`          // format: "Show the bot as muted; it still speaks"
+				YieldToBots string `toml:"yield_to_bots"` // format: "Hold back reading while another bot, e.g. a music bot, is playing"
+				BargeIn     string `toml:"barge_in"`      // format: "What reading does while a member speaks"
+			} `toml:"voice_state"`
+			MaxLength struct {
+				Description string `toml:"description"` // format: "Set the maximum number of characters read from a message"
+				Length      string `toml:"length"`      // format: "The maximum number of characters"
+				Success     string `toml:"success"`     // format: "Max message length: %[1]s"
+			} `toml:"max_length"`
+			Webhook struct {
+				Description  string `toml:"description"`   // format: "Send events of this server to a webhook"
+				URL          string `toml:"url"`           // format: "The HTTPS URL, e.g. a Discord webhook, leave empty to remove"
+				Success      string `toml:"success"`       // format: "Events of this server are sent to the webhook from now on."
+				Removed      string `toml:"removed"`       // format: "The webhook was removed."
+				ErrorInvalid string `toml:"error_invalid"` // format: "The URL must be a public HTTPS URL of at most %[1]d characters"
+			} `toml:"webhook"`
+			Ephemeral struct {
+				Description string `toml:"description"` // format: "Set whether responses to /preset and /settings are only shown to the invoker"
+				Enabled     string `toml:"enabled"`     // format: "Whether to show responses only to the invoker"
+				Success     string `toml:"success"`     // format: "Private responses: %[1]s"
+			} `toml:"ephemeral"`
+			VoiceTags struct {
+				Description string `toml:"description"` // format: "Set whether a tag like [en] or [voice:anna] at the start of a message changes its voice"
+				Enabled     string `toml:"enabled"`     // format: "Whether to read tagged messages with the voice of the tag"
+				Success     string `toml:"success"`     // format: "Voice tags: %[1]s"
+			} `toml:"voice_tags"`
+			CodeSwitch struct {
+				Description string `toml:"description"` // format: "Set whether words in another script, e.g. English in Japanese, are read in their language"
+				Enabled     string `toml:"enabled"`     // format: "Whether to read each script of a message with a voice speaking its language"
+				Success     string `toml:"success"`     // format: "Code-switching: %[1]s"
+			} `toml:"code_switch"`
+			Asides struct {
+				Description string `toml:"description"` // format: "Set whether text in parentheses or spoilers is read softly, like an aside"
+				Enabled     string `toml:"enabled"`     // format: "Whether to read asides softly"
+				Success     string `toml:"success"`     // format: "Soft asides: %[1]s"
+			} `toml:"asides"`
+			Transcript struct {
+				Description string `toml:"description"` // format: "Post what the bot speaks into a text channel"
+				Channel     string `toml:"channel"`     // format: "The channel to post into, leave empty to stop posting"
+				Success     string `toml:"success"`     // format: "What the bot speaks is posted in %[1]s from now on."
+				Removed     string `toml:"removed"`     // format: "What the bot speaks is no longer posted."
+			} `toml:"transcript"`
+			SilentRole struct {
+				Description string `toml:"description"` // format: "Manage roles whose joins and leaves are not announced"
+				Role        string `toml:"role"`        // format: "The role to configure"
+				Add         struct {
+					Description string `toml:"description"` // format: "Stop announcing joins and leaves of members with the role"
+					Success     string `toml:"success"`     // format: "Joins and leaves of %[1]s will no longer be announced"
+				} `toml:"add"`
+				Remove struct {
+					Description string `toml:"description"` // format: "Announce joins and leaves of members with the role again"
+					Success     string `toml:"success"`     // format: "Joins and leaves of %[1]s will be announced again"
+				} `toml:"remove"`
+			} `toml:"silent_role"`
+			Announcement struct {
+				Description string `toml:"description"` // format: "Override the text of announcements"
+				Key         string `toml:"key"`         // format: "The announcement to override"
+				Set         struct {
+					Description  string `toml:"description"`   // format: "Speak custom text for the announcement"
+					Text         string `toml:"text"`          // format: "The text; {name} is replaced with the names in join and leave announcements"
+					Success      string `toml:"success"`       // format: "%[1]s announcement: %[2]s"
+					ErrorInvalid string `toml:"error_invalid"` // format: "The text must be at most %[1]d characters, and only join and leave announcements may contain {name}"
+				} `toml:"set"`
+				Reset struct {
+					Description string `toml:"description"` // format: "Restore the default text of the announcement"
+					Success     string `toml:"success"`     // format: "%[1]s announcement is back to the default"
+				} `toml:"reset"`
+			} `toml:"announcement"`
+			VoicePack struct {
+				Description  string `toml:"description"`   // format: "Manage themed voice packs, e.g. seasonal greetings"
+				Name         string `toml:"name"`          // format: "The ID of the voice pack"
+				ErrorUnknown string `toml:"error_unknown"` // format: "Unknown voice pack. Available voice packs: %[1]s"
+				Enable       struct {
+					Description string `toml:"description"` // format: "Speak the voice pack while its date range lasts"
+					Success     string `toml:"success"`     // format: "%[1]s will be spoken from %[2]s to %[3]s every year"
+				} `toml:"enable"`
+				Disable struct {
+					Description string `toml:"description"` // format: "Stop speaking the voice pack"
+					Success     string `toml:"success"`     // format: "%[1]s will no longer be spoken"
+				} `toml:"disable"`
+			} `toml:"voice_pack"`
+			SpellOut struct {
+				Description  string `toml:"description"`    // format: "Spell out IDs, invite codes and other confusing tokens character by character"
+				Pattern      string `toml:"pattern"`        // format: "A regular expression; if it has a group, only the group is spelled out"
+				ErrorInvalid string `toml:"error_invalid"`  // format: "The pattern must be a valid regular expression of at most %[1]d characters"
+				ErrorTooMany string `toml:"error_too_many"` // format: "A server can have at most %[1]d patterns"
+				Enable       struct {
+					Description string `toml:"description"` // format: "Spell out IDs, invite codes, hex strings and tokens matching the patterns"
+					Success     string `toml:"success"`     // format: "Confusing tokens are spelled out from now on."
+				} `toml:"enable"`
+				Disable struct {
+					Description string `toml:"description"` // format: "Stop spelling out tokens"
+					Success     string `toml:"success"`     // format: "Tokens are no longer spelled out."
+				} `toml:"disable"`
+				Add struct {
+					Description string `toml:"description"` // format: "Also spell out tokens matching a pattern"
+					Success     string `toml:"success"`     // format: "Tokens matching %[1]s are spelled out while spell-out is enabled."
+				} `toml:"add"`
+				Remove struct {
+					Description string `toml:"description"` // format: "Stop spelling out tokens matching a pattern"
+					Success     string `toml:"success"`     // format: "Tokens matching %[1]s are no longer spelled out."
+				} `toml:"remove"`
+			} `toml:"spell_out"`
+		} `toml:"settings"`
+		Setup struct {
+			Description     string `toml:"description"`      // format: "Set up the bot for this server step by step"
+			Title           string `toml:"title"`            // format: "Setup"
+			Welcome         string `toml:"welcome"`          // format: "Choose how the bot reads this server. Changes are saved as soon as you pick them."
+			Preset          string `toml:"preset"`           // format: "Default voice preset"
+			Announcements   string `toml:"announcements"`    // format: "Announcements"
+			Reading         string `toml:"reading"`          // format: "Reading behavior"
+			CodeBlock       string `toml:"code_block"`       // format: "How to read code blocks"
+			Done            string `toml:"done"`             // format: "Done"
+			ErrorPermission string `toml:"error_permission"` // format: "You need the Manage Server permission to change the setup"
+		} `toml:"setup"`
+		Mydata struct {
+			Description string `toml:"description"` // format: "Export or delete the data the bot stores about you"
+			Export      struct {
+				Description string `toml:"description"` // format: "Download the data the bot stores about you as JSON"
+				Success     string `toml:"success"`     // format: "Here is everything the bot stores about you"
+				Error       string `toml:"error"`       // format: "Failed to export your data"
+			} `toml:"export"`
+			Delete struct {
+				Description string `toml:"description"` // format: "Delete the data the bot stores about you"
+				Success     string `toml:"success"`     // format: "Deleted everything the bot stored about you"
+				Error       string `toml:"error"`       // format: "Failed to delete your data"
+			} `toml:"delete"`
+		} `toml:"mydata"`
+		Text struct {
+			Skip struct {
+				Success         string `toml:"success"`           // format: "Skipped the message being read"
+				ErrorNotPlaying string `toml:"error_not_playing"` // format: "Nothing is being read"
+			} `toml:"skip"`
+		} `toml:"text"`
 	} `toml:"commands"`
 }
 
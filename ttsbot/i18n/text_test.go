@@ -18,6 +18,9 @@ func TestLoadTextResources(t *testing.T) {
 	for locale, resource := range trs.genericResources {
 		t.Run(fmt.Sprintf("locale_%s", locale), func(t *testing.T) {
 			errs := verifyCompleteness(resource, "TextResource")
+			if reference, ok := trs.Get(trs.fallbackLocale); ok && locale != trs.fallbackLocale {
+				errs = append(errs, verifyFormatVerbs(resource, reference, "TextResource")...)
+			}
 			if len(errs) > 0 {
 				for _, e := range errs {
 					t.Error(e)
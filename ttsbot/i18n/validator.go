@@ -2,9 +2,52 @@ package i18n
 
 import (
 	"fmt"
+	"maps"
 	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 )
 
+// Verify returns an error for every empty entry of every locale,
+// and for every entry whose format verbs do not match the fallback locale.
+func (trs *TextResources) Verify() []error {
+	return verifyLocales(trs.genericResources, "TextResource", trs.fallbackLocale)
+}
+
+// Verify returns an error for every empty entry of every locale,
+// and for every entry whose format verbs do not match the English resource.
+// Voice packs are verified against the resource of the locale they are layered over.
+func (vrs *VoiceResources) Verify() []error {
+	errs := verifyLocales(vrs.genericResources, "VoiceResource", voiceReferenceLocale)
+	for _, id := range vrs.PackIDs() {
+		pack := vrs.packs[id]
+		for _, locale := range slices.Sorted(maps.Keys(pack.resources)) {
+			base, _ := vrs.Get(locale)
+			for _, err := range verifyFormatVerbs(pack.resources[locale], base, "VoiceResource") {
+				errs = append(errs, fmt.Errorf("pack %s: %s: %w", id, locale, err))
+			}
+		}
+	}
+	return errs
+}
+
+// verifyLocales verifies the completeness of every locale, and its format verbs against the reference locale if it exists.
+func verifyLocales[S ~string, T any](resources genericResources[S, T], root string, reference S) []error {
+	var errs []error
+	referenceResource, hasReference := resources[reference]
+	for _, locale := range slices.Sorted(maps.Keys(resources)) {
+		localeErrs := verifyCompleteness(resources[locale], root)
+		if hasReference && locale != reference {
+			localeErrs = append(localeErrs, verifyFormatVerbs(resources[locale], referenceResource, root)...)
+		}
+		for _, err := range localeErrs {
+			errs = append(errs, fmt.Errorf("%s: %w", locale, err))
+		}
+	}
+	return errs
+}
+
 func verifyCompleteness(s interface{}, path string) []error {
 	var errs []error
 	v := reflect.ValueOf(s)
@@ -31,6 +74,15 @@ func verifyCompleteness(s interface{}, path string) []error {
 		case reflect.Struct:
 			nestedErrs := verifyCompleteness(fieldValue.Interface(), currentPath)
 			errs = append(errs, nestedErrs...)
+		case reflect.Slice:
+			if fieldValue.Len() == 0 {
+				errs = append(errs, fmt.Errorf("field %s is an empty list", currentPath))
+			}
+			for j := 0; j < fieldValue.Len(); j++ {
+				if item := fieldValue.Index(j); item.Kind() == reflect.String && item.String() == "" {
+					errs = append(errs, fmt.Errorf("field %s[%d] is an empty string", currentPath, j))
+				}
+			}
 		case reflect.Ptr:
 			if !fieldValue.IsNil() && fieldValue.Elem().Kind() == reflect.Struct {
 				nestedErrs := verifyCompleteness(fieldValue.Interface(), currentPath)
@@ -41,3 +93,74 @@ func verifyCompleteness(s interface{}, path string) []error {
 
 	return errs
 }
+
+// formatVerbPattern matches a format verb along with its flags, width and explicit argument index, e.g. "%02[2]d".
+var formatVerbPattern = regexp.MustCompile(`%[-+# 0]*\d*(?:\[(\d+)\])?\d*(?:\.\d+)?([a-zA-Z%])`)
+
+// formatArgs returns the verb of every argument the format uses, keyed by the 1-based argument index.
+func formatArgs(format string) map[int]string {
+	args := make(map[int]string)
+	next := 1
+	for _, match := range formatVerbPattern.FindAllStringSubmatch(format, -1) {
+		if match[2] == "%" {
+			continue
+		}
+		index := next
+		if match[1] != "" {
+			index, _ = strconv.Atoi(match[1])
+		}
+		args[index] = match[2]
+		next = index + 1
+	}
+	return args
+}
+
+// verifyFormatVerbs returns an error for every string of the resource whose format verbs can not be given
+// the arguments of the reference string, and for every list whose length differs from the reference list.
+// Translations may reorder or leave out arguments, but must not use more arguments than the reference, nor other verbs for them.
+func verifyFormatVerbs(resource, reference interface{}, path string) []error {
+	var errs []error
+	v, r := reflect.ValueOf(resource), reflect.ValueOf(reference)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		fieldValue, referenceValue := v.Field(i), r.Field(i)
+		currentPath := fmt.Sprintf("%s.%s", path, t.Field(i).Name)
+
+		switch fieldValue.Kind() {
+		case reflect.String:
+			if fieldValue.String() == "" {
+				// reported by verifyCompleteness.
+				continue
+			}
+			args, referenceArgs := formatArgs(fieldValue.String()), formatArgs(referenceValue.String())
+			if len(args) == 0 && len(referenceArgs) > 0 {
+				errs = append(errs, fmt.Errorf("field %s uses none of the arguments of %q", currentPath, referenceValue.String()))
+			}
+			argCount := 0
+			if len(referenceArgs) > 0 {
+				argCount = slices.Max(slices.Collect(maps.Keys(referenceArgs)))
+			}
+			for _, index := range slices.Sorted(maps.Keys(args)) {
+				referenceVerb, ok := referenceArgs[index]
+				switch {
+				case index > argCount:
+					errs = append(errs, fmt.Errorf("field %s uses argument %d, but %q takes %d", currentPath, index, referenceValue.String(), argCount))
+				case ok && args[index] != referenceVerb:
+					errs = append(errs, fmt.Errorf("field %s formats argument %d with %%%s instead of %%%s", currentPath, index, args[index], referenceVerb))
+				}
+			}
+		case reflect.Struct:
+			errs = append(errs, verifyFormatVerbs(fieldValue.Interface(), referenceValue.Interface(), currentPath)...)
+		case reflect.Slice:
+			if fieldValue.Len() > 0 && referenceValue.Len() > 0 && fieldValue.Len() != referenceValue.Len() {
+				errs = append(errs, fmt.Errorf("field %s has %d items instead of %d", currentPath, fieldValue.Len(), referenceValue.Len()))
+			}
+		}
+	}
+
+	return errs
+}
@@ -99,3 +99,79 @@ func TestVerifyCompleteness(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyLocales(t *testing.T) {
+	resources := genericResources[string, resourceA]{
+		"en": {Field1: "a", Field2: "b"},
+		"ja": {Field1: "a"},
+	}
+	errs := verifyLocales(resources, "Root", "")
+	if len(errs) != 1 {
+		t.Fatalf("len(errs) = %d, expected 1", len(errs))
+	}
+	if want := "ja: field Root.Field2 is an empty string"; errs[0].Error() != want {
+		t.Errorf("errs[0] = %q, expected %q", errs[0], want)
+	}
+}
+
+func TestVerifyCompletenessLists(t *testing.T) {
+	type resource struct {
+		Items []string
+	}
+	if errs := verifyCompleteness(resource{Items: []string{"a", "b"}}, "Root"); len(errs) != 0 {
+		t.Errorf("len(errs) = %d, expected 0", len(errs))
+	}
+	if errs := verifyCompleteness(resource{}, "Root"); len(errs) != 1 {
+		t.Errorf("len(errs) = %d, expected 1 for an empty list", len(errs))
+	}
+	if errs := verifyCompleteness(resource{Items: []string{"a", ""}}, "Root"); len(errs) != 1 {
+		t.Errorf("len(errs) = %d, expected 1 for an empty item", len(errs))
+	}
+}
+
+func TestVerifyFormatVerbs(t *testing.T) {
+	type resource struct {
+		Format string
+		Items  []string
+	}
+	tests := []struct {
+		name       string
+		resource   resource
+		reference  resource
+		wantErrors int
+	}{
+		{"same verbs", resource{Format: "%[1]sが参加しました"}, resource{Format: "%[1]s has joined"}, 0},
+		{"reordered arguments", resource{Format: "%[2]s%[1]s"}, resource{Format: "%[1]s at %[2]s"}, 0},
+		{"left out argument", resource{Format: "%[1]d年%[3]d日"}, resource{Format: "%[4]s %[3]d, %[1]d"}, 0},
+		{"flags before index", resource{Format: "%[1]d時%02[2]d分"}, resource{Format: "%[1]d:%02[2]d"}, 0},
+		{"escaped percent", resource{Format: "%[1]d%%"}, resource{Format: "%[1]d percent"}, 0},
+		{"missing arguments", resource{Format: "参加しました"}, resource{Format: "%[1]s has joined"}, 1},
+		{"extra argument", resource{Format: "%[1]s %[2]s"}, resource{Format: "%[1]s"}, 1},
+		{"argument of a plain string", resource{Format: "%[1]s"}, resource{Format: "code block"}, 1},
+		{"other verb", resource{Format: "%[1]s件"}, resource{Format: "%[1]d attachments"}, 1},
+		{"implicit index", resource{Format: "%s"}, resource{Format: "%[1]d"}, 1},
+		{"list length", resource{Format: "a", Items: []string{"a"}}, resource{Format: "a", Items: []string{"a", "b"}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := verifyFormatVerbs(tt.resource, tt.reference, "Root")
+			if len(errs) != tt.wantErrors {
+				t.Errorf("len(errs) = %d, expected %d: %v", len(errs), tt.wantErrors, errs)
+			}
+		})
+	}
+}
+
+func TestVerifyLocalesAgainstReference(t *testing.T) {
+	resources := genericResources[string, resourceA]{
+		"en": {Field1: "%[1]s", Field2: "b"},
+		"ja": {Field1: "a", Field2: "b"},
+	}
+	errs := verifyLocales(resources, "Root", "en")
+	if len(errs) != 1 {
+		t.Fatalf("len(errs) = %d, expected 1", len(errs))
+	}
+	if want := `ja: field Root.Field1 uses none of the arguments of "%[1]s"`; errs[0].Error() != want {
+		t.Errorf("errs[0] = %q, expected %q", errs[0], want)
+	}
+}
@@ -1,7 +1,18 @@
 package i18n
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// voiceReferenceLocale is the locale the format verbs of the other voice resources are verified against.
+const voiceReferenceLocale = "en"
+
 type VoiceResources struct {
 	genericResources[string, VoiceResource]
+	// packs are the themed voice resources loaded by LoadPacks, keyed by their ID.
+	packs map[string]VoicePack
 }
 
 type VoiceResource struct {
@@ -10,11 +21,145 @@ type VoiceResource struct {
 		Name     string `toml:"name"`     // format: "English"
 	} `toml:"metadata"`
 	Session struct {
-		Launch      string `toml:"launch"`      // "Ready to start text-to-speech in this channel."
-		UserJoin    string `toml:"user_join"`   // "%[1]s has joined the voice channel."
-		UserLeave   string `toml:"user_leave"`  // "%[1]s has left the voice channel."
-		Attachments string `toml:"attachments"` // "%[1]d attachments"
+		Launch      string `toml:"launch"`       // "Ready to start text-to-speech in this channel."
+		Farewell    string `toml:"farewell"`     // "Text-to-speech has ended. See you next time."
+		UserJoin    string `toml:"user_join"`    // "%[1]s has joined the voice channel."
+		UserLeave   string `toml:"user_leave"`   // "%[1]s has left the voice channel."
+		UsersJoin   string `toml:"users_join"`   // "%[1]s have joined the voice channel."
+		UsersLeave  string `toml:"users_leave"`  // "%[1]s have left the voice channel."
+		Attachments string `toml:"attachments"`  // "%[1]d attachments"
+		StreamStart string `toml:"stream_start"` // "%[1]s has started streaming"
+		StreamStop  string `toml:"stream_stop"`  // "%[1]s has stopped streaming"
+		StageSpeak  string `toml:"stage_speak"`  // "%[1]s is now speaking on stage"
+		StageListen string `toml:"stage_listen"` // "%[1]s has moved to the audience"
+		CodeBlock   string `toml:"code_block"`   // "code block"
+		// CodeBlockLanguage is read in place of a code block with a language, e.g. "Go code block".
+		CodeBlockLanguage string `toml:"code_block_language"` // "%[1]s code block"
 	} `toml:"session"`
+	// Cue is spoken to tell listeners about the session itself rather than a message.
+	Cue struct {
+		Moved     string `toml:"moved"`     // "text-to-speech has moved to this channel"
+		Truncated string `toml:"truncated"` // "the rest is omitted"
+		Lagging   string `toml:"lagging"`   // "reading is behind, some messages may be delayed"
+		QuotaLow  string `toml:"quota_low"` // "%[1]d%% of the reading quota of this server is used"
+		Shutdown  string `toml:"shutdown"`  // "text-to-speech is restarting, please wait a moment"
+	} `toml:"cue"`
+	Markdown struct {
+		Quote   string `toml:"quote"`   // "quote, %[1]s"
+		Heading string `toml:"heading"` // "heading, %[1]s"
+		Pause   string `toml:"pause"`   // "."
+	} `toml:"markdown"`
+	List struct {
+		Separator     string `toml:"separator"`      // ", "
+		LastSeparator string `toml:"last_separator"` // " and "
+	} `toml:"list"`
+	Timestamp struct {
+		Date     string   `toml:"date"`      // "%[4]s %[3]d, %[1]d" (year, month, day, month name)
+		Time     string   `toml:"time"`      // "%[1]d:%02[2]d %[4]s" (12-hour hour, minute, 24-hour hour, AM or PM)
+		DateTime string   `toml:"date_time"` // "%[1]s at %[2]s" (date, time)
+		Weekday  string   `toml:"weekday"`   // "%[1]s, %[2]s" (weekday name, date and time)
+		AM       string   `toml:"am"`        // "AM"
+		PM       string   `toml:"pm"`        // "PM"
+		Months   []string `toml:"months"`    // ["January", ..., "December"]
+		Weekdays []string `toml:"weekdays"`  // ["Sunday", ..., "Saturday"]
+		Future   string   `toml:"future"`    // "in %[1]s"
+		Past     string   `toml:"past"`      // "%[1]s ago"
+		Units    struct {
+			Second  string `toml:"second"`  // "%[1]d second"
+			Seconds string `toml:"seconds"` // "%[1]d seconds"
+			Minute  string `toml:"minute"`  // "%[1]d minute"
+			Minutes string `toml:"minutes"` // "%[1]d minutes"
+			Hour    string `toml:"hour"`    // "%[1]d hour"
+			Hours   string `toml:"hours"`   // "%[1]d hours"
+			Day     string `toml:"day"`     // "%[1]d day"
+			Days    string `toml:"days"`    // "%[1]d days"
+			Month   string `toml:"month"`   // "%[1]d month"
+			Months  string `toml:"months"`  // "%[1]d months"
+			Year    string `toml:"year"`    // "%[1]d year"
+			Years   string `toml:"years"`   // "%[1]d years"
+		} `toml:"units"`
+	} `toml:"timestamp"`
+}
+
+// JoinList joins the items into a localized list, e.g. "A, B and C".
+func (vr VoiceResource) JoinList(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], vr.List.Separator) + vr.List.LastSeparator + items[len(items)-1]
+}
+
+// FormatDate returns the spoken date, e.g. "January 2, 2006".
+func (vr VoiceResource) FormatDate(t time.Time) string {
+	monthName := t.Month().String()
+	if len(vr.Timestamp.Months) == 12 {
+		monthName = vr.Timestamp.Months[t.Month()-1]
+	}
+	return fmt.Sprintf(vr.Timestamp.Date, t.Year(), int(t.Month()), t.Day(), monthName)
+}
+
+// FormatTime returns the spoken time of day, e.g. "3:04 PM".
+func (vr VoiceResource) FormatTime(t time.Time) string {
+	hour12 := t.Hour() % 12
+	if hour12 == 0 {
+		hour12 = 12
+	}
+	period := vr.Timestamp.AM
+	if t.Hour() >= 12 {
+		period = vr.Timestamp.PM
+	}
+	return fmt.Sprintf(vr.Timestamp.Time, hour12, t.Minute(), t.Hour(), period)
+}
+
+// FormatDateTime returns the spoken date and time, optionally with the weekday.
+func (vr VoiceResource) FormatDateTime(t time.Time, withWeekday bool) string {
+	dateTime := fmt.Sprintf(vr.Timestamp.DateTime, vr.FormatDate(t), vr.FormatTime(t))
+	if !withWeekday {
+		return dateTime
+	}
+	weekdayName := t.Weekday().String()
+	if len(vr.Timestamp.Weekdays) == 7 {
+		weekdayName = vr.Timestamp.Weekdays[t.Weekday()]
+	}
+	return fmt.Sprintf(vr.Timestamp.Weekday, weekdayName, dateTime)
+}
+
+// FormatRelative returns the spoken duration from now, e.g. "in 3 days" or "2 hours ago".
+// Like discord, it uses the largest unit that fits.
+func (vr VoiceResource) FormatRelative(d time.Duration) string {
+	format := vr.Timestamp.Future
+	if d < 0 {
+		format = vr.Timestamp.Past
+		d = -d
+	}
+
+	units := vr.Timestamp.Units
+	var amount string
+	switch {
+	case d < time.Minute:
+		amount = plural(int(d/time.Second), units.Second, units.Seconds)
+	case d < time.Hour:
+		amount = plural(int(d/time.Minute), units.Minute, units.Minutes)
+	case d < 24*time.Hour:
+		amount = plural(int(d/time.Hour), units.Hour, units.Hours)
+	case d < 30*24*time.Hour:
+		amount = plural(int(d/(24*time.Hour)), units.Day, units.Days)
+	case d < 365*24*time.Hour:
+		amount = plural(int(d/(30*24*time.Hour)), units.Month, units.Months)
+	default:
+		amount = plural(int(d/(365*24*time.Hour)), units.Year, units.Years)
+	}
+	return fmt.Sprintf(format, amount)
+}
+
+func plural(n int, one, other string) string {
+	if n == 1 {
+		return fmt.Sprintf(one, n)
+	}
+	return fmt.Sprintf(other, n)
 }
 
 func LoadVoiceResources(directory string) (*VoiceResources, error) {
@@ -18,6 +18,9 @@ func TestLoadVoiceResources(t *testing.T) {
 	for locale, resource := range trs.genericResources {
 		t.Run(fmt.Sprintf("locale_%s", locale), func(t *testing.T) {
 			errs := verifyCompleteness(resource, "VoiceResource")
+			if reference, ok := trs.Get(voiceReferenceLocale); ok && locale != voiceReferenceLocale {
+				errs = append(errs, verifyFormatVerbs(resource, reference, "VoiceResource")...)
+			}
 			if len(errs) > 0 {
 				for _, e := range errs {
 					t.Error(e)
@@ -29,3 +32,24 @@ func TestLoadVoiceResources(t *testing.T) {
 		})
 	}
 }
+
+func TestVoiceResourceJoinList(t *testing.T) {
+	var vr VoiceResource
+	vr.List.Separator = ", "
+	vr.List.LastSeparator = " and "
+
+	tests := []struct {
+		items []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"A"}, "A"},
+		{[]string{"A", "B"}, "A and B"},
+		{[]string{"A", "B", "C"}, "A, B and C"},
+	}
+	for _, tt := range tests {
+		if got := vr.JoinList(tt.items); got != tt.want {
+			t.Errorf("JoinList(%v) = %q, want %q", tt.items, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package fake
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/session"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+// Default IDs of the sessions built by SessionBuilder.
+const (
+	GuildID        snowflake.ID = 1
+	VoiceChannelID snowflake.ID = 10
+	TextChannelID  snowflake.ID = 11
+)
+
+// EngineName is the engine identifier the engine of a SessionBuilder is registered under.
+const EngineName = "fake"
+
+// DefaultPreset is the preset sessions are read with, unless another one is given.
+var DefaultPreset = preset.Preset{
+	Identifier:   "fake",
+	Engine:       EngineName,
+	Language:     "en-US",
+	VoiceName:    "fake-voice",
+	SpeakingRate: 1,
+}
+
+// SessionBuilder builds sessions backed by a fake connection and a scripted engine.
+// The sessions are closed when the test ends.
+type SessionBuilder struct {
+	t              testing.TB
+	engine         *Engine
+	conn           *Conn
+	preset         preset.Preset
+	settings       settings.GuildSettingsRepository
+	members        *session.MemberResolver
+	maxQueuedAudio int64
+	usage          *tts.UsageTracker
+	textChannelID  snowflake.ID
+}
+
+// NewSessionBuilder returns a builder of a session reading TextChannelID in VoiceChannelID of GuildID,
+// with the default settings and DefaultPreset.
+func NewSessionBuilder(t testing.TB) *SessionBuilder {
+	return &SessionBuilder{
+		t:             t,
+		engine:        NewEngine(EngineName),
+		conn:          NewConn(GuildID, VoiceChannelID),
+		preset:        DefaultPreset,
+		settings:      settings.NewMemoryGuildSettingsRepository(),
+		members:       session.NewMemberResolver(time.Minute, 30*time.Second),
+		textChannelID: TextChannelID,
+	}
+}
+
+func (b *SessionBuilder) WithEngine(engine *Engine) *SessionBuilder {
+	b.engine = engine
+	return b
+}
+
+func (b *SessionBuilder) WithConn(conn *Conn) *SessionBuilder {
+	b.conn = conn
+	return b
+}
+
+func (b *SessionBuilder) WithPreset(p preset.Preset) *SessionBuilder {
+	b.preset = p
+	return b
+}
+
+// WithGuildSettings stores the settings of the guild of the connection before the session is built.
+func (b *SessionBuilder) WithGuildSettings(guildSettings settings.GuildSettings) *SessionBuilder {
+	guildSettings.GuildID = b.conn.GuildID()
+	if err := b.settings.Save(context.Background(), guildSettings); err != nil {
+		b.t.Fatalf("failed to save guild settings: %v", err)
+	}
+	return b
+}
+
+// WithSettingsRepository replaces the settings repository, e.g. to share it between sessions.
+func (b *SessionBuilder) WithSettingsRepository(repository settings.GuildSettingsRepository) *SessionBuilder {
+	b.settings = repository
+	return b
+}
+
+// WithMaxQueuedAudio limits the bytes of speech the session queues for playback.
+func (b *SessionBuilder) WithMaxQueuedAudio(bytes int64) *SessionBuilder {
+	b.maxQueuedAudio = bytes
+	return b
+}
+
+// WithUsageTracker counts the characters the session synthesizes in the tracker.
+func (b *SessionBuilder) WithUsageTracker(usage *tts.UsageTracker) *SessionBuilder {
+	b.usage = usage
+	return b
+}
+
+func (b *SessionBuilder) WithTextChannelID(textChannelID snowflake.ID) *SessionBuilder {
+	b.textChannelID = textChannelID
+	return b
+}
+
+// Engine returns the engine the session is read with.
+func (b *SessionBuilder) Engine() *Engine {
+	return b.engine
+}
+
+// Conn returns the connection the session speaks in.
+func (b *SessionBuilder) Conn() *Conn {
+	return b.conn
+}
+
+// Build creates the session. It fails the test if the session cannot be created.
+func (b *SessionBuilder) Build() *session.Session {
+	b.t.Helper()
+
+	engineRegistry := tts.NewEngineRegistry()
+	engineRegistry.Register(b.preset.Engine, b.engine)
+
+	presetRegistry := preset.NewPresetRegistry()
+	if err := presetRegistry.Register(b.preset); err != nil {
+		b.t.Fatalf("failed to register preset: %v", err)
+	}
+	presetResolver, err := preset.NewPresetResolver(presetRegistry, preset.NewMemoryPresetIDRepository(), nil, b.preset.Identifier)
+	if err != nil {
+		b.t.Fatalf("failed to create preset resolver: %v", err)
+	}
+
+	trs, err := i18n.LoadTextResources(localesDir("text"), "en-US")
+	if err != nil {
+		b.t.Fatalf("failed to load text resources: %v", err)
+	}
+	tr := trs.GetFallback()
+	vrs, err := i18n.LoadVoiceResources(localesDir("voice"))
+	if err != nil {
+		b.t.Fatalf("failed to load voice resources: %v", err)
+	}
+
+	s, err := session.New(engineRegistry, presetResolver, b.settings, b.members, session.NativeDecoder{}, nil, b.maxQueuedAudio, b.usage, b.textChannelID, b.conn, nil, nil, &tr, vrs)
+	if err != nil {
+		b.t.Fatalf("failed to create session: %v", err)
+	}
+	b.t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.Close(ctx, session.CloseReasonLeave)
+	})
+	return s
+}
+
+// localesDir returns the directory of the locales of the repository, which tests of any package can load.
+func localesDir(kind string) string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "..", "locales", kind)
+}
@@ -0,0 +1,131 @@
+// Package fake provides test doubles of the voice connection, the speech engine and the session persistence,
+// and a builder of sessions using them, so that sessions can be tested without discord or a speech API.
+package fake
+
+import (
+	"context"
+	"sync"
+
+	botgateway "github.com/disgoorg/disgo/gateway"
+	"github.com/disgoorg/disgo/voice"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+var _ voice.Conn = (*Conn)(nil)
+
+// Conn is a voice connection that never talks to discord.
+// It records how it is used, and lets the test pull the opus frames the session would send.
+type Conn struct {
+	guildID snowflake.ID
+
+	// OpenErr is returned by Open, if not nil.
+	OpenErr error
+	// CloseWait is waited for by Close, if not nil, like a disconnect that takes a while.
+	CloseWait <-chan struct{}
+
+	mu        sync.Mutex
+	channelID *snowflake.ID
+	selfMute  bool
+	selfDeaf  bool
+	closed    bool
+	provider  voice.OpusFrameProvider
+}
+
+// NewConn returns a connection of the guild, already open in the voice channel.
+// A voiceChannelID of 0 returns a connection that is not open.
+func NewConn(guildID, voiceChannelID snowflake.ID) *Conn {
+	conn := &Conn{guildID: guildID}
+	if voiceChannelID != 0 {
+		conn.channelID = &voiceChannelID
+	}
+	return conn
+}
+
+func (c *Conn) Gateway() voice.Gateway {
+	return nil
+}
+
+func (c *Conn) UDP() voice.UDPConn {
+	return nil
+}
+
+func (c *Conn) ChannelID() *snowflake.ID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.channelID
+}
+
+func (c *Conn) GuildID() snowflake.ID {
+	return c.guildID
+}
+
+func (c *Conn) UserIDBySSRC(ssrc uint32) snowflake.ID {
+	return 0
+}
+
+func (c *Conn) SetSpeaking(ctx context.Context, flags voice.SpeakingFlags) error {
+	return nil
+}
+
+func (c *Conn) SetOpusFrameProvider(handler voice.OpusFrameProvider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.provider = handler
+}
+
+func (c *Conn) SetOpusFrameReceiver(handler voice.OpusFrameReceiver) {}
+
+func (c *Conn) SetEventHandlerFunc(eventHandlerFunc voice.EventHandlerFunc) {}
+
+func (c *Conn) Open(ctx context.Context, channelID snowflake.ID, selfMute bool, selfDeaf bool) error {
+	if c.OpenErr != nil {
+		return c.OpenErr
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.channelID = &channelID
+	c.selfMute = selfMute
+	c.selfDeaf = selfDeaf
+	c.closed = false
+	return nil
+}
+
+func (c *Conn) Close(ctx context.Context) {
+	if c.CloseWait != nil {
+		<-c.CloseWait
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.channelID = nil
+	c.closed = true
+}
+
+func (c *Conn) HandleVoiceStateUpdate(update botgateway.EventVoiceStateUpdate) {}
+
+func (c *Conn) HandleVoiceServerUpdate(update botgateway.EventVoiceServerUpdate) {}
+
+// Closed reports whether Close was called since the connection was last opened.
+func (c *Conn) Closed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// SelfState returns the self mute and self deaf flags the connection was last opened with.
+func (c *Conn) SelfState() (selfMute, selfDeaf bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.selfMute, c.selfDeaf
+}
+
+// ProvideOpusFrame pulls the next frame from the provider set by the session, as the audio sender of discord would.
+// It returns nil if no provider is set.
+func (c *Conn) ProvideOpusFrame() ([]byte, error) {
+	c.mu.Lock()
+	provider := c.provider
+	c.mu.Unlock()
+	if provider == nil {
+		return nil, nil
+	}
+	return provider.ProvideOpusFrame()
+}
@@ -0,0 +1,120 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+var _ tts.Engine = (*Engine)(nil)
+
+// Step scripts the response to one request of an Engine.
+type Step struct {
+	// Delay is waited before responding, unless the context is done first.
+	Delay time.Duration
+	// Err fails the request, if not nil.
+	Err error
+	// Response is returned if Err is nil. Nil returns a short silence.
+	Response *tts.SpeechResponse
+}
+
+// Engine is a speech engine answering requests with scripted steps, in order.
+// Once the steps run out, every request is answered with a short silence.
+// It records every request, so that tests can check what would have been read.
+type Engine struct {
+	name string
+
+	mu       sync.Mutex
+	steps    []Step
+	requests []tts.SpeechRequest
+	// notify is signalled on every request, to wake up WaitForRequests.
+	notify chan struct{}
+}
+
+func NewEngine(name string, steps ...Step) *Engine {
+	return &Engine{
+		name:   name,
+		steps:  steps,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (e *Engine) Name() string {
+	return e.name
+}
+
+func (e *Engine) GenerateSpeech(ctx context.Context, request tts.SpeechRequest) (*tts.SpeechResponse, error) {
+	e.mu.Lock()
+	e.requests = append(e.requests, request)
+	var step Step
+	if len(e.steps) > 0 {
+		step, e.steps = e.steps[0], e.steps[1:]
+	}
+	e.mu.Unlock()
+
+	select {
+	case e.notify <- struct{}{}:
+	default:
+	}
+
+	if step.Delay > 0 {
+		select {
+		case <-time.After(step.Delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if step.Err != nil {
+		return nil, step.Err
+	}
+	if step.Response != nil {
+		return step.Response, nil
+	}
+	return Silence(20 * time.Millisecond), nil
+}
+
+// Script appends steps to the script.
+func (e *Engine) Script(steps ...Step) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.steps = append(e.steps, steps...)
+}
+
+// Requests returns the requests received so far.
+func (e *Engine) Requests() []tts.SpeechRequest {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]tts.SpeechRequest(nil), e.requests...)
+}
+
+// WaitForRequests waits until at least n requests were received, and returns them.
+// It returns false with the requests received so far if the timeout passes first.
+func (e *Engine) WaitForRequests(n int, timeout time.Duration) ([]tts.SpeechRequest, bool) {
+	deadline := time.After(timeout)
+	for {
+		if requests := e.Requests(); len(requests) >= n {
+			return requests, true
+		}
+		select {
+		case <-e.notify:
+		case <-deadline:
+			return e.Requests(), false
+		}
+	}
+}
+
+// Silence returns mono LINEAR16 silence of the duration at the sample rate of discord.
+func Silence(d time.Duration) *tts.SpeechResponse {
+	const sampleRate = 48000
+	samples := int(d.Seconds() * sampleRate)
+	return &tts.SpeechResponse{
+		Format:     tts.AudioFormatLinear16,
+		SampleRate: sampleRate,
+		Channels:   1,
+		Audio:      bytes.NewReader(make([]byte, samples*2)),
+		Size:       int64(samples * 2),
+	}
+}
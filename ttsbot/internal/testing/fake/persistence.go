@@ -0,0 +1,105 @@
+package fake
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/session"
+)
+
+var _ session.SessionPersistence = (*Persistence)(nil)
+
+// PersistedSession is a session kept by Persistence.
+type PersistedSession struct {
+	GuildID          snowflake.ID
+	VoiceChannelID   snowflake.ID
+	ReadingChannelID snowflake.ID
+}
+
+// Persistence keeps the sessions in memory, keyed by voice channel like the redis persistence.
+// Unlike the redis persistence, Restore restores the sessions synchronously.
+type Persistence struct {
+	mu       sync.Mutex
+	sessions map[snowflake.ID]PersistedSession
+}
+
+// NewPersistence returns a persistence holding the sessions, as if they were persisted before a restart.
+func NewPersistence(sessions ...PersistedSession) *Persistence {
+	p := &Persistence{sessions: make(map[snowflake.ID]PersistedSession, len(sessions))}
+	for _, s := range sessions {
+		p.sessions[s.VoiceChannelID] = s
+	}
+	return p
+}
+
+func (p *Persistence) OnCreated(e session.SessionCreatedEvent) {
+	p.persist(PersistedSession{GuildID: e.GuildID, VoiceChannelID: e.VoiceChannelID, ReadingChannelID: e.ReadingChannelID})
+}
+
+func (p *Persistence) OnUpdated(e session.SessionUpdatedEvent) {
+	if e.Previous.VoiceChannelID != e.VoiceChannelID {
+		p.forget(e.Previous.VoiceChannelID)
+	}
+	p.persist(PersistedSession{GuildID: e.GuildID, VoiceChannelID: e.VoiceChannelID, ReadingChannelID: e.ReadingChannelID})
+}
+
+func (p *Persistence) OnRestored(e session.SessionRestoredEvent) {
+	p.persist(PersistedSession{GuildID: e.GuildID, VoiceChannelID: e.VoiceChannelID, ReadingChannelID: e.ReadingChannelID})
+}
+
+func (p *Persistence) OnFailed(e session.SessionFailedEvent) {
+	p.forget(e.VoiceChannelID)
+}
+
+func (p *Persistence) OnDeleted(e session.SessionDeletedEvent) {
+	p.forget(e.VoiceChannelID)
+}
+
+func (p *Persistence) Restore(ctx context.Context, sessionManager session.SessionManager, sessionRestoreFunc session.SessionRestoreFunc) error {
+	for _, persisted := range p.Sessions() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := sessionManager.Reserve(persisted.GuildID); err != nil {
+			continue
+		}
+		s, err := sessionRestoreFunc(persisted.GuildID, persisted.VoiceChannelID, persisted.ReadingChannelID)
+		if err != nil {
+			sessionManager.Fail(persisted.GuildID, persisted.VoiceChannelID, persisted.ReadingChannelID, err)
+			continue
+		}
+		if err := sessionManager.Restore(persisted.GuildID, persisted.VoiceChannelID, persisted.ReadingChannelID, s); err != nil {
+			closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			s.Close(closeCtx, session.CloseReasonDuplicate)
+			cancel()
+		}
+	}
+	return nil
+}
+
+// Sessions returns the persisted sessions, ordered by voice channel.
+func (p *Persistence) Sessions() []PersistedSession {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.SortedFunc(maps.Values(p.sessions), func(a, b PersistedSession) int {
+		return cmp.Compare(a.VoiceChannelID, b.VoiceChannelID)
+	})
+}
+
+func (p *Persistence) persist(s PersistedSession) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sessions[s.VoiceChannelID] = s
+}
+
+func (p *Persistence) forget(voiceChannelID snowflake.ID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.sessions, voiceChannelID)
+}
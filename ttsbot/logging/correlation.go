@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+// CorrelationIDKey is the attribute key of the correlation ID of a log record.
+const CorrelationIDKey = "correlationID"
+
+type correlationIDKey struct{}
+
+// NewCorrelationID returns a short random ID, which is shown to users with errors so that a report can be matched to the logs.
+func NewCorrelationID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithCorrelationID returns a context carrying the correlation ID.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID of the context, or an empty string if it has none.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// NewCorrelationHandler wraps the handler to add the correlation ID of the context to records logged with a context,
+// e.g. by slog.InfoContext.
+func NewCorrelationHandler(next slog.Handler) slog.Handler {
+	return &correlationHandler{next: next}
+}
+
+type correlationHandler struct {
+	next slog.Handler
+}
+
+func (h *correlationHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *correlationHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := CorrelationID(ctx); id != "" {
+		record = record.Clone()
+		record.AddAttrs(slog.String(CorrelationIDKey, id))
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *correlationHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &correlationHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *correlationHandler) WithGroup(name string) slog.Handler {
+	return &correlationHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestCorrelationHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewCorrelationHandler(slog.NewTextHandler(&buf, nil))).With("guildID", "1")
+
+	ctx := WithCorrelationID(context.Background(), "ab12cd34")
+	logger.InfoContext(ctx, "correlated")
+	logger.Info("uncorrelated")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two records, got %d", len(lines))
+	}
+	if !strings.Contains(lines[0], "correlationID=ab12cd34") || !strings.Contains(lines[0], "guildID=1") {
+		t.Errorf("record logged with the context lacks the correlation ID: %s", lines[0])
+	}
+	if strings.Contains(lines[1], CorrelationIDKey) {
+		t.Errorf("record logged without a context has a correlation ID: %s", lines[1])
+	}
+}
+
+func TestNewCorrelationID(t *testing.T) {
+	id := NewCorrelationID()
+	if len(id) != 8 {
+		t.Errorf("unexpected correlation ID %q", id)
+	}
+	if id == NewCorrelationID() {
+		t.Error("correlation IDs must differ")
+	}
+}
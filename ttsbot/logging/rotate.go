@@ -0,0 +1,168 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp inserted into the names of rotated log files.
+// It sorts lexically in chronological order.
+const backupTimeFormat = "20060102T150405.000"
+
+// RotatingFileOptions configures when a RotatingFile rotates and which backups it keeps.
+// Zero values disable the corresponding limit.
+type RotatingFileOptions struct {
+	// MaxSize is the size in bytes after which the file is rotated.
+	MaxSize int64
+	// MaxAge removes backups older than this.
+	MaxAge time.Duration
+	// MaxBackups is the number of backups to keep.
+	MaxBackups int
+}
+
+// RotatingFile is an io.WriteCloser that writes to a file and rotates it once it grows beyond MaxSize.
+// Rotated files are renamed to "<name>-<timestamp><ext>" next to the original file.
+type RotatingFile struct {
+	mu   sync.Mutex
+	path string
+	opts RotatingFileOptions
+	file *os.File
+	size int64
+	now  func() time.Time
+	// closed is set once Close is called, after which nothing is written.
+	closed bool
+}
+
+// OpenRotatingFile opens the file for appending, creating the directory if needed.
+func OpenRotatingFile(path string, opts RotatingFileOptions) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path: path,
+		opts: opts,
+		now:  time.Now,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	if f.opts.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.opts.MaxSize {
+		if err := f.rotate(); err != nil {
+			// logs are still written to the file if it could be reopened, and rotating it is tried again on the next write.
+			fmt.Fprintf(os.Stderr, "failed to rotate log file %s: %v\n", f.path, err)
+		}
+	}
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens the log file. The caller must hold the lock, or own f exclusively.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate renames the current file to a backup and opens a new one. The caller must hold the lock.
+// If either fails, the file at the original path is opened again, and f.file is nil only if that fails too.
+func (f *RotatingFile) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	if err == nil {
+		ext := filepath.Ext(f.path)
+		backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(f.path, ext), f.now().Format(backupTimeFormat), ext)
+		err = os.Rename(f.path, backup)
+	}
+	if openErr := f.open(); openErr != nil || err != nil {
+		return errors.Join(err, openErr)
+	}
+
+	f.removeOldBackups()
+	return nil
+}
+
+// removeOldBackups enforces MaxBackups and MaxAge. The caller must hold the lock.
+func (f *RotatingFile) removeOldBackups() {
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(filepath.Base(f.path), ext) + "-"
+	dir := filepath.Dir(f.path)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		name      string
+		timestamp time.Time
+	}
+	backups := make([]backup, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		timestamp, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext), time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{name, timestamp})
+	}
+
+	// newest first
+	slices.SortFunc(backups, func(a, b backup) int {
+		return b.timestamp.Compare(a.timestamp)
+	})
+
+	for i, b := range backups {
+		expired := f.opts.MaxAge > 0 && f.now().Sub(b.timestamp) > f.opts.MaxAge
+		excess := f.opts.MaxBackups > 0 && i >= f.opts.MaxBackups
+		if expired || excess {
+			os.Remove(filepath.Join(dir, b.name))
+		}
+	}
+}
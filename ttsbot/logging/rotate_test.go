@@ -0,0 +1,134 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot.log")
+
+	f, err := OpenRotatingFile(path, RotatingFileOptions{MaxSize: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile() error = %v", err)
+	}
+	defer f.Close()
+
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.Local)
+	f.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for range 5 {
+		if _, err := f.Write([]byte("123456789\n")); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	backups := 0
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "bot-") {
+			backups++
+		}
+	}
+	if backups != 2 {
+		t.Errorf("backups = %d, want 2", backups)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(content) != "123456789\n" {
+		t.Errorf("current file = %q, want a single line", content)
+	}
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot.log")
+
+	stale := filepath.Join(dir, "bot-"+time.Now().Add(-48*time.Hour).Format(backupTimeFormat)+".log")
+	if err := os.WriteFile(stale, []byte("old\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	f, err := OpenRotatingFile(path, RotatingFileOptions{MaxSize: 4, MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile() error = %v", err)
+	}
+	defer f.Close()
+
+	for range 2 {
+		if _, err := f.Write([]byte("new\n")); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale backup was not removed: %v", err)
+	}
+}
+
+func TestRotatingFileRotationFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot.log")
+
+	f, err := OpenRotatingFile(path, RotatingFileOptions{MaxSize: 10})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile() error = %v", err)
+	}
+	defer f.Close()
+
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.Local)
+	f.now = func() time.Time { return now }
+
+	// a directory in the way of the backup fails the rename, after which logs still go to the original file.
+	blocker := filepath.Join(dir, "bot-"+now.Format(backupTimeFormat)+".log")
+	if err := os.MkdirAll(filepath.Join(blocker, "busy"), 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	for range 2 {
+		if _, err := f.Write([]byte("123456789\n")); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(content) != "123456789\n123456789\n" {
+		t.Errorf("current file = %q, want both lines", content)
+	}
+
+	// rotating is tried again on the next write.
+	if err := os.RemoveAll(blocker); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if _, err := f.Write([]byte("123456789\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	content, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(content) != "123456789\n" {
+		t.Errorf("current file = %q, want a single line", content)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := f.Write([]byte("123456789\n")); err == nil {
+		t.Error("Write() after Close() succeeded")
+	}
+}
@@ -0,0 +1,108 @@
+// Package logging provides slog helpers shared by the bot.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// ComponentKey is the attribute key naming the component that emitted a log record.
+const ComponentKey = "component"
+
+// ComponentSynthesis is the component of the per-message synthesis path, which logs on every message.
+const ComponentSynthesis = "synthesis"
+
+// Component returns the attribute naming the component of a log record.
+func Component(name string) slog.Attr {
+	return slog.String(ComponentKey, name)
+}
+
+// SamplingRule limits the records of a component. Warnings and errors are never sampled.
+type SamplingRule struct {
+	// Level drops records of the component below this level.
+	Level slog.Level
+	// Every keeps one of every N remaining records. Values below 2 keep every record.
+	Every int
+}
+
+type sampler struct {
+	rule    SamplingRule
+	counter atomic.Uint64
+}
+
+func (s *sampler) keep(level slog.Level) bool {
+	if level >= slog.LevelWarn {
+		return true
+	}
+	if level < s.rule.Level {
+		return false
+	}
+	if s.rule.Every < 2 {
+		return true
+	}
+	return (s.counter.Add(1)-1)%uint64(s.rule.Every) == 0
+}
+
+// NewSamplingHandler wraps the handler to sample records by component, as configured by the rules.
+// The component is taken from a ComponentKey attribute on the logger or on the record.
+func NewSamplingHandler(next slog.Handler, rules map[string]SamplingRule) slog.Handler {
+	samplers := make(map[string]*sampler, len(rules))
+	for component, rule := range rules {
+		samplers[component] = &sampler{rule: rule}
+	}
+	return &samplingHandler{
+		next:     next,
+		samplers: samplers,
+	}
+}
+
+type samplingHandler struct {
+	next     slog.Handler
+	samplers map[string]*sampler
+	// component is set when the logger was derived with a ComponentKey attribute.
+	component *sampler
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	s := h.component
+	if s == nil && len(h.samplers) > 0 {
+		record.Attrs(func(attr slog.Attr) bool {
+			if attr.Key == ComponentKey {
+				s = h.samplers[attr.Value.String()]
+				return false
+			}
+			return true
+		})
+	}
+	if s != nil && !s.keep(record.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	component := h.component
+	for _, attr := range attrs {
+		if attr.Key == ComponentKey {
+			component = h.samplers[attr.Value.String()]
+		}
+	}
+	return &samplingHandler{
+		next:      h.next.WithAttrs(attrs),
+		samplers:  h.samplers,
+		component: component,
+	}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{
+		next:      h.next.WithGroup(name),
+		samplers:  h.samplers,
+		component: h.component,
+	}
+}
@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSamplingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewSamplingHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), map[string]SamplingRule{
+		ComponentSynthesis: {Level: slog.LevelInfo, Every: 3},
+	})
+	logger := slog.New(handler)
+	synthesis := logger.With(Component(ComponentSynthesis))
+
+	for range 6 {
+		synthesis.Info("sampled")
+	}
+	synthesis.Debug("dropped")
+	synthesis.Error("kept")
+	logger.Info("record attribute", Component(ComponentSynthesis))
+	logger.Debug("other component")
+
+	output := buf.String()
+	if got := strings.Count(output, "msg=sampled"); got != 2 {
+		t.Errorf("sampled records = %d, want 2", got)
+	}
+	if strings.Contains(output, "msg=dropped") {
+		t.Error("record below the rule level was not dropped")
+	}
+	if !strings.Contains(output, "msg=kept") {
+		t.Error("error record was sampled")
+	}
+	if !strings.Contains(output, "msg=\"other component\"") {
+		t.Error("record without a rule was dropped")
+	}
+}
@@ -0,0 +1,39 @@
+package message
+
+import (
+	"regexp"
+	"strings"
+)
+
+// asidePattern matches the text in parentheses, full-width parentheses or spoiler markers.
+var asidePattern = regexp.MustCompile(`\(([^()]*)\)|（([^（）]*)）|\|\|(.+?)\|\|`)
+
+// AsideRun is a run of text, which is either an aside or the surrounding text.
+type AsideRun struct {
+	Text  string
+	Aside bool
+}
+
+// SplitAsides splits the content into the asides, i.e. the text in parentheses or spoiler markers, and the text around them.
+// The parentheses and markers are dropped from the asides, and runs with only spaces are dropped.
+func SplitAsides(content string) []AsideRun {
+	var runs []AsideRun
+	appendRun := func(text string, aside bool) {
+		if strings.TrimSpace(text) != "" {
+			runs = append(runs, AsideRun{Text: text, Aside: aside})
+		}
+	}
+
+	last := 0
+	for _, match := range asidePattern.FindAllStringSubmatchIndex(content, -1) {
+		appendRun(content[last:match[0]], false)
+		for group := 1; group < len(match)/2; group++ {
+			if match[2*group] >= 0 {
+				appendRun(strings.TrimSpace(content[match[2*group]:match[2*group+1]]), true)
+			}
+		}
+		last = match[1]
+	}
+	appendRun(content[last:], false)
+	return runs
+}
@@ -0,0 +1,24 @@
+package message
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitAsides(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []AsideRun
+	}{
+		{"no asides here", []AsideRun{{"no asides here", false}}},
+		{"I'm back (finally)", []AsideRun{{"I'm back ", false}, {"finally", true}}},
+		{"明日（たぶん）行く", []AsideRun{{"明日", false}, {"たぶん", true}, {"行く", false}}},
+		{"the culprit is ||the butler||!", []AsideRun{{"the culprit is ", false}, {"the butler", true}, {"!", false}}},
+		{"empty () parentheses", []AsideRun{{"empty ", false}, {" parentheses", false}}},
+	}
+	for _, tt := range tests {
+		if got := SplitAsides(tt.content); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitAsides(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
@@ -2,10 +2,18 @@ package message
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
 )
 
 var (
@@ -22,10 +30,16 @@ func BuildPresetEmbed(preset preset.Preset, tr i18n.TextResource) *discord.Embed
 		AddField(tr.Generic.Preset.Language, preset.Language, true).
 		AddField(tr.Generic.Preset.Engine, tr.Generic.Engines[preset.Engine], true).
 		AddField(" ", " ", true). // dummy field for alignment
-		AddField(tr.Generic.Preset.VoiceName, preset.VoiceName, true)
+		AddField(tr.Generic.Preset.VoiceName, strings.Join(preset.VoiceNames(), ", "), true)
 
 	if preset.SpeakingRate != 0 {
-		embedBuilder.AddField("Speaking Rate", fmt.Sprintf("%.2f", preset.SpeakingRate), true)
+		embedBuilder.AddField(tr.Generic.Preset.SpeakingRate, fmt.Sprintf("%.2f", preset.SpeakingRate), true)
+	}
+	if preset.Pitch != 0 {
+		embedBuilder.AddField(tr.Generic.Preset.Pitch, fmt.Sprintf("%+.1f", preset.Pitch), true)
+	}
+	if preset.VolumeGainDb != 0 {
+		embedBuilder.AddField(tr.Generic.Preset.VolumeGain, fmt.Sprintf("%+.1f dB", preset.VolumeGainDb), true)
 	}
 
 	return embedBuilder
@@ -39,13 +53,279 @@ func BuildJoinEmbed(tr i18n.TextResource, channelToRead, voiceChannel string) *d
 		SetColor(colorInfo)
 }
 
-func BuildLeaveEmbed(tr i18n.TextResource) *discord.EmbedBuilder {
-	return discord.NewEmbedBuilder().
+// SessionSummary is what a session read, shown when it is closed.
+type SessionSummary struct {
+	Duration time.Duration
+	// Messages are the messages read, and Characters the characters synthesized, announcements included.
+	Messages   int
+	Characters int
+	// TopSpeakers are the members who had the most messages read, most first.
+	TopSpeakers []SpeakerCount
+}
+
+// SpeakerCount is the number of messages of a member that were read.
+type SpeakerCount struct {
+	UserID   snowflake.ID
+	Messages int
+}
+
+// BuildLeaveEmbed builds the embed sent when a session is closed, with the description of why it was closed.
+// The summary of the session is added unless it read no messages.
+func BuildLeaveEmbed(tr i18n.TextResource, reason string, summary SessionSummary) *discord.EmbedBuilder {
+	embed := discord.NewEmbedBuilder().
 		SetTitle(tr.Generic.TTS.End).
-		SetDescription(tr.Generic.TTS.Thanks).
+		SetDescriptionf("%s\n%s", reason, tr.Generic.TTS.Thanks).
+		SetColor(colorInfo)
+	if summary.Messages == 0 {
+		return embed
+	}
+
+	embed.AddField(tr.Generic.TTS.Summary.Duration, sessionDurationValue(summary.Duration, tr), true).
+		AddField(tr.Generic.TTS.Summary.Messages, strconv.Itoa(summary.Messages), true).
+		AddField(tr.Generic.TTS.Summary.Characters, strconv.Itoa(summary.Characters), true)
+	if len(summary.TopSpeakers) > 0 {
+		lines := make([]string, 0, len(summary.TopSpeakers))
+		for i, speaker := range summary.TopSpeakers {
+			lines = append(lines, fmt.Sprintf("%d. %s", i+1, fmt.Sprintf(tr.Generic.TTS.Summary.Speaker, discord.UserMention(speaker.UserID), speaker.Messages)))
+		}
+		embed.AddField(tr.Generic.TTS.Summary.TopSpeakers, strings.Join(lines, "\n"), false)
+	}
+	return embed
+}
+
+func sessionDurationValue(d time.Duration, tr i18n.TextResource) string {
+	d = d.Round(time.Second)
+	if d >= time.Hour {
+		return fmt.Sprintf(tr.Generic.TTS.Summary.DurationHours, int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf(tr.Generic.TTS.Summary.DurationMinutes, int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// BuildMutedEmbed builds the embed sent when reading is paused or resumed because the bot was server-muted or unmuted.
+func BuildMutedEmbed(tr i18n.TextResource, muted bool) *discord.EmbedBuilder {
+	if muted {
+		return discord.NewEmbedBuilder().
+			SetTitle(tr.Generic.TTS.Paused).
+			SetDescription(tr.Generic.TTS.PausedMuted).
+			SetColor(colorInfo)
+	}
+	return discord.NewEmbedBuilder().
+		SetTitle(tr.Generic.TTS.Resumed).
+		SetDescription(tr.Generic.TTS.ResumedMuted).
+		SetColor(colorInfo)
+}
+
+// BuildLaggingEmbed builds the embed sent when so much speech is queued that reading falls behind.
+func BuildLaggingEmbed(tr i18n.TextResource) *discord.EmbedBuilder {
+	return discord.NewEmbedBuilder().
+		SetTitle(tr.Generic.TTS.Lagging).
+		SetDescription(tr.Generic.TTS.LaggingQueued).
+		SetColor(colorInfo)
+}
+
+// BuildMovedEmbed builds the embed sent when the bot was moved to another voice channel.
+func BuildMovedEmbed(tr i18n.TextResource, voiceChannel string) *discord.EmbedBuilder {
+	return discord.NewEmbedBuilder().
+		SetTitle(tr.Generic.TTS.Moved).
+		SetDescriptionf(tr.Generic.TTS.MovedTo, voiceChannel).
+		SetColor(colorInfo)
+}
+
+func BuildTakeoverConfirmEmbed(tr i18n.TextResource, currentVoiceChannel, newVoiceChannel string) *discord.EmbedBuilder {
+	return discord.NewEmbedBuilder().
+		SetDescriptionf(tr.Commands.Join.Takeover.Confirm, currentVoiceChannel, newVoiceChannel).
+		SetColor(colorInfo)
+}
+
+func BuildSettingsEmbed(guildSettings settings.GuildSettings, tr i18n.TextResource) *discord.EmbedBuilder {
+	return discord.NewEmbedBuilder().
+		SetTitle(tr.Generic.Settings.Self).
+		AddField(tr.Generic.Settings.TakeoverPolicy, TakeoverPolicyName(guildSettings.TakeoverPolicy, tr), true).
+		AddField(tr.Generic.Settings.SilentRoles, silentRolesValue(guildSettings.SilentRoleIDs, tr), true).
+		AddField(tr.Generic.Settings.VoiceActivity, EnabledName(guildSettings.AnnounceVoiceActivity, tr), true).
+		AddField(tr.Generic.Settings.AnnounceJoin, EnabledName(guildSettings.AnnounceJoin, tr), true).
+		AddField(tr.Generic.Settings.AnnounceLeave, EnabledName(guildSettings.AnnounceLeave, tr), true).
+		AddField(tr.Generic.Settings.AnnounceLaunch, EnabledName(guildSettings.AnnounceLaunch, tr), true).
+		AddField(tr.Generic.Settings.AnnounceFarewell, EnabledName(guildSettings.AnnounceFarewell, tr), true).
+		AddField(tr.Generic.Settings.SkipReaction, SkipReactionName(guildSettings.SkipReaction, tr), true).
+		AddField(tr.Generic.Settings.MaxMessageLength, fmt.Sprintf(tr.Generic.Settings.Characters, guildSettings.MaxMessageLength), true).
+		AddField(tr.Generic.Settings.CodeBlockMode, CodeBlockModeName(guildSettings.CodeBlockMode, tr), true).
+		AddField(tr.Generic.Settings.OmitStrikethrough, EnabledName(guildSettings.OmitStrikethrough, tr), true).
+		AddField(tr.Generic.Settings.AnnounceMarkdown, EnabledName(guildSettings.AnnounceMarkdown, tr), true).
+		AddField(tr.Generic.Settings.Timezone, guildSettings.Location().String(), true).
+		AddField(tr.Generic.Settings.NameSource, NameSourceName(guildSettings.NameSource, tr), true).
+		AddField(tr.Generic.Settings.StripNameDecorations, EnabledName(guildSettings.StripNameDecorations, tr), true).
+		AddField(tr.Generic.Settings.CommandPrefix, CommandPrefixName(guildSettings.CommandPrefix, tr), true).
+		AddField(tr.Generic.Settings.VoiceState, voiceStateValue(guildSettings, tr), true).
+		AddField(tr.Generic.Settings.Webhook, EnabledName(guildSettings.WebhookURL != "", tr), true).
+		AddField(tr.Generic.Settings.EphemeralResponses, EnabledName(guildSettings.EphemeralResponses, tr), true).
+		AddField(tr.Generic.Settings.Announcements, announcementTemplatesValue(guildSettings.AnnouncementTemplates, tr), false).
+		AddField(tr.Generic.Settings.VoicePacks, voicePacksValue(guildSettings.VoicePacks, tr), true).
+		AddField(tr.Generic.Settings.VoiceSwitching, voiceSwitchingValue(guildSettings, tr), true).
+		AddField(tr.Generic.Settings.SpellOut, spellOutValue(guildSettings.SpellOut, guildSettings.SpellOutPatterns, tr), true).
+		AddField(tr.Generic.Settings.TranscriptChannel, TranscriptChannelName(guildSettings.TranscriptChannelID, tr), true).
+		SetColor(colorInfo)
+}
+
+// EnabledName returns the localized display name of a toggle.
+func EnabledName(enabled bool, tr i18n.TextResource) string {
+	if enabled {
+		return tr.Generic.Settings.Enabled
+	}
+	return tr.Generic.Settings.Disabled
+}
+
+// TranscriptChannelName returns the mention of the transcript channel, or a localized placeholder if it is disabled.
+func TranscriptChannelName(channelID snowflake.ID, tr i18n.TextResource) string {
+	if channelID == 0 {
+		return tr.Generic.Settings.Disabled
+	}
+	return discord.ChannelMention(channelID)
+}
+
+// SkipReactionName returns the skip reaction emoji, or a localized placeholder if it is disabled.
+func SkipReactionName(emoji string, tr i18n.TextResource) string {
+	if emoji == "" {
+		return tr.Generic.Settings.Disabled
+	}
+	return emoji
+}
+
+// CommandPrefixName returns the command prefix as inline code, or a localized placeholder if it is disabled.
+func CommandPrefixName(prefix string, tr i18n.TextResource) string {
+	if prefix == "" {
+		return tr.Generic.Settings.Disabled
+	}
+	return "`" + prefix + "`"
+}
+
+func silentRolesValue(roleIDs []snowflake.ID, tr i18n.TextResource) string {
+	if len(roleIDs) == 0 {
+		return tr.Generic.Settings.None
+	}
+	mentions := make([]string, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		mentions = append(mentions, discord.RoleMention(roleID))
+	}
+	return strings.Join(mentions, " ")
+}
+
+// TakeoverPolicyName returns the localized display name of the takeover policy.
+func TakeoverPolicyName(policy settings.TakeoverPolicy, tr i18n.TextResource) string {
+	switch policy {
+	case settings.TakeoverPolicyConfirm:
+		return tr.Generic.Settings.TakeoverPolicies.Confirm
+	case settings.TakeoverPolicyMove:
+		return tr.Generic.Settings.TakeoverPolicies.Move
+	case settings.TakeoverPolicyRefuse:
+		return tr.Generic.Settings.TakeoverPolicies.Refuse
+	default:
+		return policy.String()
+	}
+}
+
+// AnnouncementKeyName returns the localized display name of the announcement.
+func AnnouncementKeyName(key settings.AnnouncementKey, tr i18n.TextResource) string {
+	switch key {
+	case settings.AnnouncementKeyLaunch:
+		return tr.Generic.Settings.AnnouncementKeys.Launch
+	case settings.AnnouncementKeyJoin:
+		return tr.Generic.Settings.AnnouncementKeys.Join
+	case settings.AnnouncementKeyLeave:
+		return tr.Generic.Settings.AnnouncementKeys.Leave
+	default:
+		return key.String()
+	}
+}
+
+// CodeBlockModeName returns the localized display name of the code block mode.
+func CodeBlockModeName(mode settings.CodeBlockMode, tr i18n.TextResource) string {
+	switch mode {
+	case settings.CodeBlockModeAnnounce:
+		return tr.Generic.Settings.CodeBlockModes.Announce
+	case settings.CodeBlockModeSkip:
+		return tr.Generic.Settings.CodeBlockModes.Skip
+	case settings.CodeBlockModeFirstLine:
+		return tr.Generic.Settings.CodeBlockModes.FirstLine
+	default:
+		return mode.String()
+	}
+}
+
+// BargeInName returns the localized display name of the barge-in.
+func BargeInName(bargeIn settings.BargeIn, tr i18n.TextResource) string {
+	switch bargeIn {
+	case "", settings.BargeInOff:
+		return tr.Generic.Settings.BargeIns.Off
+	case settings.BargeInDuck:
+		return tr.Generic.Settings.BargeIns.Duck
+	case settings.BargeInPause:
+		return tr.Generic.Settings.BargeIns.Pause
+	default:
+		return bargeIn.String()
+	}
+}
+
+// NameSourceName returns the localized display name of the name source.
+func NameSourceName(source settings.NameSource, tr i18n.TextResource) string {
+	switch source {
+	case settings.NameSourceNickname:
+		return tr.Generic.Settings.NameSources.Nickname
+	case settings.NameSourceDisplayName:
+		return tr.Generic.Settings.NameSources.DisplayName
+	case settings.NameSourceUsername:
+		return tr.Generic.Settings.NameSources.Username
+	default:
+		return source.String()
+	}
+}
+
+// BuildSetupEmbed builds the embed of the setup wizard.
+func BuildSetupEmbed(tr i18n.TextResource) *discord.EmbedBuilder {
+	return discord.NewEmbedBuilder().
+		SetTitle(tr.Commands.Setup.Title).
+		SetDescription(tr.Commands.Setup.Welcome).
+		SetColor(colorInfo)
+}
+
+func BuildPreviewEmbed(tr i18n.TextResource) *discord.EmbedBuilder {
+	return discord.NewEmbedBuilder().
+		SetTitle(tr.Commands.Preview.Title).
 		SetColor(colorInfo)
 }
 
+// BuildVoicesEmbed builds the embed listing the voices of an engine for a language.
+func BuildVoicesEmbed(tr i18n.TextResource, engineName, language string) *discord.EmbedBuilder {
+	return discord.NewEmbedBuilder().
+		SetTitlef(tr.Commands.Voices.Title, engineName, language).
+		SetColor(colorInfo)
+}
+
+// BuildUsageEmbed builds the embed of the characters a guild had read in a month, per engine.
+// The engines are listed by their display names, or by their IDs if they have none.
+func BuildUsageEmbed(tr i18n.TextResource, usage tts.Usage) *discord.EmbedBuilder {
+	embed := discord.NewEmbedBuilder().
+		SetTitlef(tr.Commands.Usage.Title, usage.Period).
+		SetColor(colorInfo)
+	if len(usage.Engines) == 0 {
+		embed.SetDescription(tr.Commands.Usage.Empty)
+	}
+	for _, engine := range slices.Sorted(maps.Keys(usage.Engines)) {
+		name := engine
+		if displayName, ok := tr.Generic.Engines[engine]; ok {
+			name = displayName
+		}
+		embed.AddField(name, fmt.Sprintf(tr.Commands.Usage.Characters, usage.Engines[engine]), true)
+	}
+
+	budget := tr.Commands.Usage.Unlimited
+	if usage.Budget > 0 {
+		budget = fmt.Sprintf(tr.Commands.Usage.Characters, usage.Budget)
+	}
+	return embed.AddField(tr.Commands.Usage.Total, fmt.Sprintf(tr.Commands.Usage.Characters, usage.Total()), false).
+		AddField(tr.Commands.Usage.Budget, budget, false)
+}
+
 func BuildSuccessEmbed(tr i18n.TextResource) *discord.EmbedBuilder {
 	return discord.NewEmbedBuilder().
 		SetTitle(tr.Generic.Success).
@@ -74,9 +354,52 @@ func BuildPresetListEmbed(presets []preset.Preset, tr i18n.TextResource) *discor
 			"1. %s\n2. %s\n3. %s",
 			tr.Generic.Engines[p.Engine],
 			p.Language,
-			p.VoiceName,
+			strings.Join(p.VoiceNames(), ", "),
 		), true)
 	}
 
 	return embedBuilder
 }
+
+func announcementTemplatesValue(templates map[settings.AnnouncementKey]string, tr i18n.TextResource) string {
+	lines := make([]string, 0, len(templates))
+	for _, key := range settings.AnnouncementKeys {
+		if template, ok := templates[key]; ok {
+			lines = append(lines, fmt.Sprintf("%s: %s", AnnouncementKeyName(key, tr), template))
+		}
+	}
+	if len(lines) == 0 {
+		return tr.Generic.Settings.None
+	}
+	return strings.Join(lines, "\n")
+}
+
+func voicePacksValue(packIDs []string, tr i18n.TextResource) string {
+	if len(packIDs) == 0 {
+		return tr.Generic.Settings.None
+	}
+	return strings.Join(packIDs, ", ")
+}
+
+// voiceSwitchingValue shows the settings changing the voice within a message in a single field,
+// as an embed has at most 25 fields.
+func voiceSwitchingValue(guildSettings settings.GuildSettings, tr i18n.TextResource) string {
+	return tr.Generic.Settings.VoiceTags + ": " + EnabledName(guildSettings.VoiceTags, tr) + "\n" +
+		tr.Generic.Settings.CodeSwitch + ": " + EnabledName(guildSettings.CodeSwitch, tr) + "\n" +
+		tr.Generic.Settings.SoftenAsides + ": " + EnabledName(guildSettings.SoftenAsides, tr)
+}
+
+func voiceStateValue(guildSettings settings.GuildSettings, tr i18n.TextResource) string {
+	return tr.Generic.Settings.SelfDeaf + ": " + EnabledName(guildSettings.SelfDeaf, tr) + "\n" +
+		tr.Generic.Settings.SelfMute + ": " + EnabledName(guildSettings.SelfMute, tr) + "\n" +
+		tr.Generic.Settings.YieldToBots + ": " + EnabledName(guildSettings.YieldToBots, tr) + "\n" +
+		tr.Generic.Settings.BargeIn + ": " + BargeInName(guildSettings.BargeIn, tr)
+}
+
+func spellOutValue(enabled bool, patterns []string, tr i18n.TextResource) string {
+	lines := []string{EnabledName(enabled, tr)}
+	for _, pattern := range patterns {
+		lines = append(lines, "`"+pattern+"`")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/snowflake/v2"
@@ -11,8 +12,11 @@ import (
 
 var (
 	urlRegex      = regexp.MustCompile(`https?://[^\s]+`)
-	headingsRegex = regexp.MustCompile(`^ *#{1,3}`)
+	headingsRegex = regexp.MustCompile(`^ *(#{1,3}|-#) +`)
+	quoteRegex    = regexp.MustCompile(`^ *>(>>)? +`)
+	listRegex     = regexp.MustCompile(`^ *([-*+]|\d+\.) +`)
 	emojiRegex    = regexp.MustCompile(`<a?:(\w+):\d+>`)
+	strikeRegex   = regexp.MustCompile(`~~.+?~~`)
 )
 
 func ReplaceUserMentions(content string, mentions map[snowflake.ID]string) string {
@@ -38,27 +42,102 @@ func ReplaceEmojis(content string) string {
 	return content
 }
 
-func ConvertMarkdownToPlainText(content string) string {
+// CodeBlockFormatter returns the text read in place of a code block, or an empty string to skip it.
+// language is the normalized language name of the code block, e.g. "Go", and is empty if it was not specified.
+type CodeBlockFormatter func(language string, lines []string) string
+
+// MarkdownOptions configures how markdown is converted to plain text.
+type MarkdownOptions struct {
+	FormatCodeBlock CodeBlockFormatter
+	// OmitStrikethrough removes ~~struck-through~~ text instead of reading it.
+	OmitStrikethrough bool
+	// QuoteFormat and HeadingFormat announce quotes and headings, e.g. "quote, %[1]s".
+	// Empty formats read the text without announcing it.
+	QuoteFormat   string
+	HeadingFormat string
+	// ListItemPause is appended to list items that do not end with punctuation,
+	// so that the engine pauses between items, e.g. "." or "。".
+	ListItemPause string
+}
+
+func ConvertMarkdownToPlainText(content string, opts MarkdownOptions) string {
 	lines := strings.Split(content, "\n")
-	lines = removeCodeBlocks(lines)
+	lines = replaceCodeBlocks(lines, opts.FormatCodeBlock)
+	inQuote := false
+	// ">>> " quotes the rest of the message.
+	inMultilineQuote := false
 	for i, line := range lines {
 		// Remove markdown formatting
-		line = removeHeadings(line)
+		line, heading := removeHeadings(line)
+		line, quote := removeQuote(line)
+		quote = quote || inMultilineQuote
+		inMultilineQuote = inMultilineQuote || strings.HasPrefix(strings.TrimSpace(lines[i]), ">>> ")
+		line, listItem := removeListMarker(line)
+		if opts.OmitStrikethrough {
+			line = removeStrikethrough(line)
+		}
 		line = replaceWithSkippingInlineCode(line, "**", "")
 		line = replaceWithSkippingInlineCode(line, "__", "")
 		line = replaceWithSkippingInlineCode(line, "*", "")
 		line = replaceWithSkippingInlineCode(line, "_", " ")
 		line = replaceWithSkippingInlineCode(line, "~~", "")
 		line = strings.ReplaceAll(line, "`", "")
+
+		if listItem && opts.ListItemPause != "" && !endsWithPunctuation(line) {
+			line += opts.ListItemPause
+		}
+		if heading && opts.HeadingFormat != "" {
+			line = fmt.Sprintf(opts.HeadingFormat, line)
+		}
+		// announce only the first line of consecutive quoted lines.
+		if quote && !inQuote && opts.QuoteFormat != "" {
+			line = fmt.Sprintf(opts.QuoteFormat, line)
+		}
+		inQuote = quote
 		lines[i] = line
 	}
 	return strings.Join(lines, "\n")
 }
 
-func removeHeadings(line string) string {
-	// Remove headings (e.g. "# Heading", "## Subheading")
-	// This regex matches headings with 1 to 6 hashes at the start of the line.
-	return headingsRegex.ReplaceAllString(line, "")
+func removeHeadings(line string) (string, bool) {
+	// Remove headings (e.g. "# Heading", "## Subheading") and subtext (e.g. "-# small text")
+	// This regex matches headings with 1 to 3 hashes at the start of the line.
+	// Only headings are reported, as subtext is small print rather than something to announce.
+	match := headingsRegex.FindStringSubmatch(line)
+	if match == nil {
+		return line, false
+	}
+	return line[len(match[0]):], match[1] != "-#"
+}
+
+func removeQuote(line string) (string, bool) {
+	// Remove quotes (e.g. "> quoted", ">>> quoted until the end")
+	removed := quoteRegex.ReplaceAllString(line, "")
+	return removed, removed != line
+}
+
+func removeListMarker(line string) (string, bool) {
+	// Remove list markers (e.g. "- item", "* item", "1. item")
+	removed := listRegex.ReplaceAllString(line, "")
+	return removed, removed != line
+}
+
+func endsWithPunctuation(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return true
+	}
+	last, _ := utf8.DecodeLastRuneInString(line)
+	return strings.ContainsRune(".,!?:;。、！？", last)
+}
+
+func removeStrikethrough(line string) string {
+	// same as replaceWithSkippingInlineCode, but removes the struck-through text as well.
+	parts := strings.Split(line, "`")
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = strikeRegex.ReplaceAllString(parts[i], "")
+	}
+	return strings.Join(parts, "`")
 }
 
 func replaceWithSkippingInlineCode(line string, replaced, replacement string) string {
@@ -71,33 +150,104 @@ func replaceWithSkippingInlineCode(line string, replaced, replacement string) st
 	return strings.Join(parts, "`")
 }
 
-func removeCodeBlocks(lines []string) []string {
+func replaceCodeBlocks(lines []string, formatCodeBlock CodeBlockFormatter) []string {
 	var result []string
 	inCodeBlock := false
+	var language string
+	var code []string
+
+	flush := func() {
+		if formatted := formatCodeBlock(language, code); formatted != "" {
+			result = append(result, formatted)
+		}
+		code = nil
+	}
 
 	for _, line := range lines {
 		if strings.HasPrefix(line, "```") {
 			inCodeBlock = !inCodeBlock // Toggle code block state
 			if inCodeBlock {
-				// If we are entering a code block, we don't add the line to the result
-				kind := strings.TrimPrefix(line, "```")
-				result = append(result, fmt.Sprintf("code block: %s", kind))
+				language = CodeLanguageName(strings.TrimPrefix(line, "```"))
+			} else {
+				flush()
 			}
 			continue
 		}
-		if !inCodeBlock {
+		if inCodeBlock {
+			code = append(code, line)
+		} else {
 			result = append(result, line)
 		}
 	}
+	// discord renders an unclosed code block until the end of the message.
+	if inCodeBlock {
+		flush()
+	}
 
 	return result
 }
 
+var codeLanguageNames = map[string]string{
+	"go":         "Go",
+	"golang":     "Go",
+	"js":         "JavaScript",
+	"javascript": "JavaScript",
+	"ts":         "TypeScript",
+	"typescript": "TypeScript",
+	"py":         "Python",
+	"python":     "Python",
+	"rs":         "Rust",
+	"rust":       "Rust",
+	"c":          "C",
+	"cpp":        "C++",
+	"c++":        "C++",
+	"cs":         "C#",
+	"csharp":     "C#",
+	"java":       "Java",
+	"kt":         "Kotlin",
+	"kotlin":     "Kotlin",
+	"rb":         "Ruby",
+	"ruby":       "Ruby",
+	"php":        "PHP",
+	"swift":      "Swift",
+	"sh":         "Shell",
+	"bash":       "Shell",
+	"zsh":        "Shell",
+	"shell":      "Shell",
+	"ps1":        "PowerShell",
+	"powershell": "PowerShell",
+	"sql":        "SQL",
+	"html":       "HTML",
+	"css":        "CSS",
+	"json":       "JSON",
+	"yml":        "YAML",
+	"yaml":       "YAML",
+	"toml":       "TOML",
+	"xml":        "XML",
+	"md":         "Markdown",
+	"markdown":   "Markdown",
+	"diff":       "diff",
+}
+
+// CodeLanguageName returns the display name of the language tag of a code block, e.g. "Go" for "golang".
+// Unknown tags are returned as is.
+func CodeLanguageName(tag string) string {
+	tag = strings.TrimSpace(tag)
+	if name, ok := codeLanguageNames[strings.ToLower(tag)]; ok {
+		return name
+	}
+	return tag
+}
+
 func ReplaceUrlsWithPlaceholders(content string) string {
 	return urlRegex.ReplaceAllString(content, "[URL]")
 }
 
+// LimitContentLength cuts the content at max runes. A max of zero or less does not limit it.
 func LimitContentLength(content string, max int) string {
+	if max <= 0 {
+		return content
+	}
 	runes := []rune(content)
 	if len(runes) <= max {
 		return content
@@ -1,6 +1,8 @@
 package message
 
 import (
+	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/disgoorg/snowflake/v2"
@@ -81,8 +83,160 @@ func TestReplaceEmojis(t *testing.T) {
 }
 
 func TestConvertMarkdownToPlainText(t *testing.T) {
-	// No test because this function will be changed near future.
-	t.Skip("ConvertMarkdownToPlainText is not implemented yet")
+	opts := MarkdownOptions{
+		FormatCodeBlock: func(language string, lines []string) string { return "code" },
+		QuoteFormat:     "quote, %[1]s",
+		HeadingFormat:   "heading, %[1]s",
+		ListItemPause:   ".",
+	}
+
+	type testCase struct {
+		name     string
+		content  string
+		opts     MarkdownOptions
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Heading",
+			content:  "## Release notes",
+			opts:     opts,
+			expected: "heading, Release notes",
+		},
+		{
+			name:     "Heading without announcement",
+			content:  "# Title",
+			opts:     MarkdownOptions{FormatCodeBlock: opts.FormatCodeBlock},
+			expected: "Title",
+		},
+		{
+			name:     "Subtext",
+			content:  "-# small print",
+			opts:     MarkdownOptions{FormatCodeBlock: opts.FormatCodeBlock},
+			expected: "small print",
+		},
+		{
+			name:     "Subtext is not announced",
+			content:  "-# small print",
+			opts:     opts,
+			expected: "small print",
+		},
+		{
+			name:     "Consecutive quote lines",
+			content:  "> first\n> second\nreply",
+			opts:     opts,
+			expected: "quote, first\nsecond\nreply",
+		},
+		{
+			name:     "Multiline quote",
+			content:  ">>> first\nsecond",
+			opts:     opts,
+			expected: "quote, first\nsecond",
+		},
+		{
+			name:     "Lists",
+			content:  "- apples\n* oranges!\n1. **bold** pears",
+			opts:     opts,
+			expected: "apples.\noranges!\nbold pears.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ConvertMarkdownToPlainText(tc.content, tc.opts)
+			if result != tc.expected {
+				t.Errorf("ConvertMarkdownToPlainText(%q) = %q, want %q", tc.content, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRemoveStrikethrough(t *testing.T) {
+	type testCase struct {
+		name     string
+		line     string
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Single strikethrough",
+			line:     "I ~~hate~~ love it",
+			expected: "I  love it",
+		},
+		{
+			name:     "Multiple strikethroughs",
+			line:     "~~a~~b~~c~~",
+			expected: "b",
+		},
+		{
+			name:     "Inside inline code",
+			line:     "run `~~a~~` now",
+			expected: "run `~~a~~` now",
+		},
+		{
+			name:     "Unclosed",
+			line:     "~~not closed",
+			expected: "~~not closed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := removeStrikethrough(tc.line)
+			if result != tc.expected {
+				t.Errorf("removeStrikethrough(%q) = %q, want %q", tc.line, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestReplaceCodeBlocks(t *testing.T) {
+	format := func(language string, lines []string) string {
+		if language == "skip" {
+			return ""
+		}
+		return fmt.Sprintf("[%s:%d]", language, len(lines))
+	}
+
+	type testCase struct {
+		name     string
+		lines    []string
+		expected []string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Normalized language",
+			lines:    []string{"before", "```golang", "package main", "```", "after"},
+			expected: []string{"before", "[Go:1]", "after"},
+		},
+		{
+			name:     "No language",
+			lines:    []string{"```", "a", "b", "```"},
+			expected: []string{"[:2]"},
+		},
+		{
+			name:     "Skipped",
+			lines:    []string{"before", "```skip", "a", "```"},
+			expected: []string{"before"},
+		},
+		{
+			name:     "Unclosed",
+			lines:    []string{"```py", "print()"},
+			expected: []string{"[Python:1]"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := replaceCodeBlocks(tc.lines, format)
+			if !slices.Equal(result, tc.expected) {
+				t.Errorf("replaceCodeBlocks(%q) = %q, want %q", tc.lines, result, tc.expected)
+			}
+		})
+	}
 }
 
 func TestReplaceUrlsWithPlaceholders(t *testing.T) {
@@ -152,6 +306,12 @@ func TestLimitContentLength(t *testing.T) {
 			maxLen:   10,
 			expected: "abcdefghij",
 		},
+		{
+			name:     "No limit",
+			content:  "abcdefghijklmnopqrstuvwxyz",
+			maxLen:   0,
+			expected: "abcdefghijklmnopqrstuvwxyz",
+		},
 	}
 
 	for _, tc := range testCases {
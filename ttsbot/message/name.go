@@ -0,0 +1,37 @@
+package message
+
+import (
+	"strings"
+	"unicode"
+)
+
+// nameSeparators split a name from a decorative suffix, e.g. "Alice | streaming".
+const nameSeparators = "|｜/／"
+
+// StripNameDecorations removes emojis, decorative symbols and suffixes after a separator from the name,
+// e.g. "✨Alice✨ | streaming" becomes "Alice". The name is returned as is if nothing readable is left.
+func StripNameDecorations(name string) string {
+	if i := strings.IndexAny(name, nameSeparators); i > 0 {
+		name = name[:i]
+	}
+
+	stripped := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.In(r, unicode.So, unicode.Sk, unicode.Cf, unicode.Co, unicode.Variation_Selector):
+			return -1
+		case unicode.Is(unicode.Mn, r) && r >= 0x20d0 && r <= 0x20ff:
+			// combining marks for symbols, e.g. keycap
+			return -1
+		}
+		return r
+	}, name)
+	stripped = strings.Join(strings.Fields(stripped), " ")
+	stripped = strings.TrimFunc(stripped, func(r rune) bool {
+		return (unicode.IsPunct(r) || unicode.IsSymbol(r)) && !strings.ContainsRune("()[]（）「」【】", r)
+	})
+
+	if strings.TrimSpace(stripped) == "" {
+		return name
+	}
+	return stripped
+}
@@ -0,0 +1,58 @@
+package message
+
+import "testing"
+
+func TestStripNameDecorations(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    string
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Plain name",
+			input:    "Alice",
+			expected: "Alice",
+		},
+		{
+			name:     "Emoji decorations",
+			input:    "✨Alice✨",
+			expected: "Alice",
+		},
+		{
+			name:     "Emoji with variation selector and ZWJ",
+			input:    "Bob ❤️‍🔥",
+			expected: "Bob",
+		},
+		{
+			name:     "Suffix after separator",
+			input:    "Carol | streaming",
+			expected: "Carol",
+		},
+		{
+			name:     "Decorative punctuation",
+			input:    "~*Dave*~",
+			expected: "Dave",
+		},
+		{
+			name:     "Japanese name with brackets",
+			input:    "【配信中】たろう",
+			expected: "【配信中】たろう",
+		},
+		{
+			name:     "Only emoji",
+			input:    "🍣",
+			expected: "🍣",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := StripNameDecorations(tc.input)
+			if result != tc.expected {
+				t.Errorf("StripNameDecorations(%q) = %q, want %q", tc.input, result, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package message
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ScriptRun is a run of text written in the script of a single language.
+type ScriptRun struct {
+	Text string
+	// Language is the language the run is written in, e.g. "en", or "" if the run has no letters, e.g. "123!".
+	Language string
+}
+
+// minLatinRunLetters is the fewest letters a Latin run needs to be read in English,
+// so that e.g. the "w" of Japanese laughter is read with the surrounding text.
+const minLatinRunLetters = 2
+
+// SplitScripts splits the content into runs written in a single script, e.g. Japanese and English.
+// Digits, spaces and punctuation join the run before them, or the first run if the content starts with them.
+// Han characters are read as Japanese if the content has kana or the fallback language is Japanese, and as Chinese otherwise.
+func SplitScripts(content string, fallbackLanguage string) []ScriptRun {
+	hanLanguage := "zh"
+	if strings.EqualFold(primaryLanguage(fallbackLanguage), "ja") || strings.ContainsFunc(content, isKana) {
+		hanLanguage = "ja"
+	}
+
+	var (
+		runs    []ScriptRun
+		builder strings.Builder
+		current string
+		letters int
+	)
+	flush := func() {
+		if builder.Len() == 0 {
+			return
+		}
+		language := current
+		if language == "en" && letters < minLatinRunLetters {
+			language = ""
+		}
+		runs = appendRun(runs, ScriptRun{Text: builder.String(), Language: language})
+		builder.Reset()
+		current, letters = "", 0
+	}
+	for _, r := range content {
+		language := runeLanguage(r, hanLanguage)
+		if language != "" && current != "" && language != current {
+			flush()
+		}
+		if language != "" {
+			current = language
+			letters++
+		}
+		builder.WriteRune(r)
+	}
+	flush()
+	return runs
+}
+
+// appendRun appends the run, joining it with the last run if either has no language or both have the same one.
+func appendRun(runs []ScriptRun, run ScriptRun) []ScriptRun {
+	if len(runs) == 0 {
+		return append(runs, run)
+	}
+	last := &runs[len(runs)-1]
+	switch {
+	case run.Language == "" || run.Language == last.Language:
+		last.Text += run.Text
+	case last.Language == "":
+		last.Text += run.Text
+		last.Language = run.Language
+	default:
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// runeLanguage returns the language a letter is written in, or "" if r is not a letter of a known script.
+func runeLanguage(r rune, hanLanguage string) string {
+	switch {
+	case isKana(r):
+		return "ja"
+	case unicode.Is(unicode.Han, r):
+		return hanLanguage
+	case unicode.Is(unicode.Hangul, r):
+		return "ko"
+	case unicode.Is(unicode.Latin, r):
+		return "en"
+	default:
+		return ""
+	}
+}
+
+func isKana(r rune) bool {
+	return unicode.In(r, unicode.Hiragana, unicode.Katakana)
+}
+
+func primaryLanguage(language string) string {
+	primary, _, _ := strings.Cut(language, "-")
+	return primary
+}
@@ -0,0 +1,26 @@
+package message
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitScripts(t *testing.T) {
+	tests := []struct {
+		content  string
+		fallback string
+		want     []ScriptRun
+	}{
+		{"今日は meeting です", "ja-JP", []ScriptRun{{"今日は ", "ja"}, {"meeting ", "en"}, {"です", "ja"}}},
+		{"hello, 세계!", "en-US", []ScriptRun{{"hello, ", "en"}, {"세계!", "ko"}}},
+		{"123 草", "en-US", []ScriptRun{{"123 草", "zh"}}},
+		{"草 www", "ja-JP", []ScriptRun{{"草 ", "ja"}, {"www", "en"}}},
+		{"いいね w", "ja-JP", []ScriptRun{{"いいね w", "ja"}}},
+		{"!?", "ja-JP", []ScriptRun{{"!?", ""}}},
+	}
+	for _, tt := range tests {
+		if got := SplitScripts(tt.content, tt.fallback); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitScripts(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package message
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SplitSentences splits the content into segments of at most budget runes.
+// Segments are split at sentence boundaries where possible, so that each engine request reads naturally;
+// a sentence longer than the budget is split at the last space within the budget, or hard at the budget.
+func SplitSentences(content string, budget int) []string {
+	var segments []string
+	var current []rune
+	flush := func() {
+		if segment := strings.TrimSpace(string(current)); segment != "" {
+			segments = append(segments, segment)
+		}
+		current = current[:0]
+	}
+
+	for _, sentence := range sentences(content) {
+		if len(current)+len(sentence) > budget {
+			flush()
+		}
+		for len(sentence) > budget {
+			// look one rune past the budget, so that a word ending exactly at the budget is kept.
+			cut := min(lastSpace(sentence[:budget+1]), budget)
+			if cut <= 0 {
+				cut = budget
+			}
+			current = append(current, sentence[:cut]...)
+			flush()
+			sentence = sentence[cut:]
+		}
+		current = append(current, sentence...)
+	}
+	flush()
+
+	return segments
+}
+
+// sentences splits the content into sentences, keeping the trailing whitespace of each sentence.
+func sentences(content string) [][]rune {
+	runes := []rune(content)
+	var result [][]rune
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		if !endsSentence(runes, i) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && unicode.IsSpace(runes[end]) {
+			end++
+		}
+		result = append(result, runes[start:end])
+		start = end
+		i = end - 1
+	}
+	if start < len(runes) {
+		result = append(result, runes[start:])
+	}
+	return result
+}
+
+func endsSentence(runes []rune, i int) bool {
+	switch runes[i] {
+	case '\n', '。', '！', '？', '!', '?':
+		return true
+	case '.':
+		// "3.14" or "example.com" does not end a sentence.
+		return i+1 == len(runes) || unicode.IsSpace(runes[i+1])
+	}
+	return false
+}
+
+func lastSpace(runes []rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			return i + 1
+		}
+	}
+	return -1
+}
@@ -0,0 +1,69 @@
+package message
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitSentences(t *testing.T) {
+	type testCase struct {
+		name     string
+		content  string
+		budget   int
+		expected []string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Fits in budget",
+			content:  "Hello. How are you?",
+			budget:   100,
+			expected: []string{"Hello. How are you?"},
+		},
+		{
+			name:     "Split at sentence boundary",
+			content:  "Hello there. How are you? I am fine.",
+			budget:   20,
+			expected: []string{"Hello there.", "How are you?", "I am fine."},
+		},
+		{
+			name:     "Decimal point is not a boundary",
+			content:  "Pi is 3.14 roughly. Yes.",
+			budget:   20,
+			expected: []string{"Pi is 3.14 roughly.", "Yes."},
+		},
+		{
+			name:     "Japanese sentences",
+			content:  "こんにちは。元気ですか？元気です。",
+			budget:   12,
+			expected: []string{"こんにちは。元気ですか？", "元気です。"},
+		},
+		{
+			name:     "Long sentence split at space",
+			content:  "one two three four five",
+			budget:   10,
+			expected: []string{"one two", "three four", "five"},
+		},
+		{
+			name:     "Long sentence without spaces",
+			content:  "abcdefghij",
+			budget:   4,
+			expected: []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name:     "Empty content",
+			content:  "  ",
+			budget:   10,
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := SplitSentences(tc.content, tc.budget)
+			if !slices.Equal(result, tc.expected) {
+				t.Errorf("SplitSentences(%q, %d) = %q, want %q", tc.content, tc.budget, result, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package message
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultSpellOutPatterns match the tokens engines read as nonsense words:
+// Discord IDs, the codes of invite links and hex strings such as hashes and colors.
+var DefaultSpellOutPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b\d{17,20}\b`),
+	regexp.MustCompile(`(?i)\bdiscord(?:\.gg|(?:app)?\.com/invite)/([\w-]+)`),
+	regexp.MustCompile(`(?i)\b(?:0x)?[0-9a-f]{8,}\b`),
+	regexp.MustCompile(`(?i)#([0-9a-f]{6})\b`),
+}
+
+// SpellOut spells out the tokens matching the patterns character by character, e.g. "a b 1 2",
+// so that the engine reads each character instead of guessing a word.
+// If a pattern has a capturing group, only the group is spelled out, e.g. the code of an invite link.
+func SpellOut(content string, patterns []*regexp.Regexp) string {
+	for _, pattern := range patterns {
+		content = spellOutMatches(content, pattern)
+	}
+	return content
+}
+
+func spellOutMatches(content string, pattern *regexp.Regexp) string {
+	var builder strings.Builder
+	last := 0
+	for _, match := range pattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := match[0], match[1]
+		if len(match) >= 4 && match[2] >= 0 {
+			start, end = match[2], match[3]
+		}
+		if start == end {
+			continue
+		}
+		builder.WriteString(content[last:start])
+		builder.WriteString(strings.Join(strings.Split(content[start:end], ""), " "))
+		last = end
+	}
+	builder.WriteString(content[last:])
+	return builder.String()
+}
@@ -0,0 +1,22 @@
+package message
+
+import "testing"
+
+func TestSpellOut(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"my id is 123456789012345678", "my id is 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8"},
+		{"join discord.gg/aBc-1 now", "join discord.gg/a B c - 1 now"},
+		{"see discord.com/invite/xyz", "see discord.com/invite/x y z"},
+		{"commit 3f9a2c1d", "commit 3 f 9 a 2 c 1 d"},
+		{"color #ff00AA", "color #f f 0 0 A A"},
+		{"a short word and 1234", "a short word and 1234"},
+	}
+	for _, tt := range tests {
+		if got := SpellOut(tt.content, DefaultSpellOutPatterns); got != tt.want {
+			t.Errorf("SpellOut(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package message
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+)
+
+var timestampRegex = regexp.MustCompile(`<t:(-?\d+)(?::([tTdDfFR]))?>`)
+
+// ReplaceTimestamps replaces timestamp tags like <t:1700000000:R> with the spoken date or time in loc.
+// Relative timestamps are read relative to now.
+func ReplaceTimestamps(content string, loc *time.Location, now time.Time, vr i18n.VoiceResource) string {
+	return timestampRegex.ReplaceAllStringFunc(content, func(tag string) string {
+		match := timestampRegex.FindStringSubmatch(tag)
+		unix, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return tag
+		}
+		t := time.Unix(unix, 0).In(loc)
+
+		switch match[2] {
+		case "t", "T":
+			return vr.FormatTime(t)
+		case "d", "D":
+			return vr.FormatDate(t)
+		case "F":
+			return vr.FormatDateTime(t, true)
+		case "R":
+			return vr.FormatRelative(t.Sub(now))
+		default:
+			return vr.FormatDateTime(t, false)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package message
+
+import (
+	"testing"
+	"time"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+)
+
+func TestReplaceTimestamps(t *testing.T) {
+	vrs, err := i18n.LoadVoiceResources("../../locales/voice/")
+	if err != nil {
+		t.Fatalf("Failed to load voice resources: %v", err)
+	}
+	en, _ := vrs.Get("en")
+	ja, _ := vrs.Get("ja")
+
+	tokyo := time.FixedZone("Asia/Tokyo", 9*60*60)
+	// 2023-11-14 22:13:20 UTC, 2023-11-15 07:13:20 in Tokyo
+	now := time.Unix(1700000000, 0)
+
+	type testCase struct {
+		name     string
+		content  string
+		loc      *time.Location
+		vr       i18n.VoiceResource
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Default style",
+			content:  "Meet at <t:1700000000>",
+			loc:      time.UTC,
+			vr:       en,
+			expected: "Meet at November 14, 2023 at 10:13 PM",
+		},
+		{
+			name:     "Guild timezone",
+			content:  "<t:1700000000:d>",
+			loc:      tokyo,
+			vr:       en,
+			expected: "November 15, 2023",
+		},
+		{
+			name:     "Full with weekday",
+			content:  "<t:1700000000:F>",
+			loc:      time.UTC,
+			vr:       en,
+			expected: "Tuesday, November 14, 2023 at 10:13 PM",
+		},
+		{
+			name:     "Relative future",
+			content:  "<t:1700259200:R>",
+			loc:      time.UTC,
+			vr:       en,
+			expected: "in 3 days",
+		},
+		{
+			name:     "Relative past singular",
+			content:  "<t:1699996400:R>",
+			loc:      time.UTC,
+			vr:       en,
+			expected: "1 hour ago",
+		},
+		{
+			name:     "Japanese time",
+			content:  "<t:1700000000:t>に集合",
+			loc:      tokyo,
+			vr:       ja,
+			expected: "7時13分に集合",
+		},
+		{
+			name:     "Not a timestamp",
+			content:  "<t:abc:R>",
+			loc:      time.UTC,
+			vr:       en,
+			expected: "<t:abc:R>",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ReplaceTimestamps(tc.content, tc.loc, now, tc.vr)
+			if result != tc.expected {
+				t.Errorf("ReplaceTimestamps(%q) = %q, want %q", tc.content, result, tc.expected)
+			}
+		})
+	}
+}
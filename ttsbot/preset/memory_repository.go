@@ -0,0 +1,84 @@
+package preset
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// NewMemoryPresetIDRepository returns a PresetIDRepository that keeps preset IDs in memory.
+// It is used when the bot runs without a database, so preset IDs are lost on restart.
+func NewMemoryPresetIDRepository() PresetIDRepository {
+	return &memoryPresetIDRepository{
+		entries: make(map[scopedID]ScopedPresetID),
+	}
+}
+
+type memoryPresetIDRepository struct {
+	mu      sync.RWMutex
+	entries map[scopedID]ScopedPresetID
+}
+
+func (r *memoryPresetIDRepository) Find(ctx context.Context, scope Scope, ID snowflake.ID) (PresetID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.entries[scopedID{scope, ID}]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return entry.PresetID, nil
+}
+
+func (r *memoryPresetIDRepository) FindMany(ctx context.Context, scope Scope, IDs ...snowflake.ID) (map[snowflake.ID]PresetID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	presetIDs := make(map[snowflake.ID]PresetID, len(IDs))
+	for _, ID := range IDs {
+		if entry, ok := r.entries[scopedID{scope, ID}]; ok {
+			presetIDs[ID] = entry.PresetID
+		}
+	}
+	return presetIDs, nil
+}
+
+func (r *memoryPresetIDRepository) Save(ctx context.Context, scope Scope, ID snowflake.ID, presetID PresetID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.save(scope, ID, presetID, time.Now())
+	return nil
+}
+
+// save stores the preset ID. The caller must hold the lock.
+func (r *memoryPresetIDRepository) save(scope Scope, ID snowflake.ID, presetID PresetID, now time.Time) {
+	key := scopedID{scope, ID}
+	entry, ok := r.entries[key]
+	if !ok {
+		entry = ScopedPresetID{Scope: scope, ID: ID, CreatedAt: now}
+	}
+	entry.PresetID = presetID
+	entry.UpdatedAt = now
+	r.entries[key] = entry
+}
+
+func (r *memoryPresetIDRepository) Delete(ctx context.Context, scope Scope, ID snowflake.ID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, scopedID{scope, ID})
+	return nil
+}
+
+func (r *memoryPresetIDRepository) SaveAll(ctx context.Context, entries []ScopedPresetID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.PresetID == "" {
+			delete(r.entries, scopedID{entry.Scope, entry.ID})
+		} else {
+			r.save(entry.Scope, entry.ID, entry.PresetID, now)
+		}
+	}
+	return nil
+}
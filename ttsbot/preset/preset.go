@@ -2,6 +2,7 @@ package preset
 
 import (
 	"fmt"
+	"strings"
 )
 
 type PresetID string
@@ -12,6 +13,41 @@ type Preset struct {
 	Language     string
 	VoiceName    string
 	SpeakingRate float64
+	// Pitch is in semitones and VolumeGainDb in decibels from the normal voice, see tts.SpeechRequest.
+	Pitch        float64
+	VolumeGainDb float64
+	// FallbackEngine reads with FallbackVoiceName once Engine fails or times out. Empty does not fall back.
+	// An empty voice name lets the fallback engine pick a voice for the language.
+	FallbackEngine    string
+	FallbackVoiceName string
+	// Voices are the voices assigned to speakers of a multi-voice preset, see VoiceAssigner.
+	// VoiceName is the first of them, and is used for announcements.
+	Voices        []string
+	VoiceRotation VoiceRotation
+	// MessageSSML and AnnouncementSSML wrap messages and announcements in SSML. Nil reads them as plain text.
+	MessageSSML      *SSMLTemplate
+	AnnouncementSSML *SSMLTemplate
+}
+
+// VoiceNames returns every voice the preset may speak with.
+func (p Preset) VoiceNames() []string {
+	if len(p.Voices) > 0 {
+		return p.Voices
+	}
+	return []string{p.VoiceName}
+}
+
+// Speaks reports whether the preset speaks the language. A language without a region, e.g. "en",
+// matches every region of it, e.g. "en-US", while a language with a region only matches that region.
+func (p Preset) Speaks(language string) bool {
+	if language == "" {
+		return false
+	}
+	if strings.EqualFold(p.Language, language) {
+		return true
+	}
+	primary, _, _ := strings.Cut(p.Language, "-")
+	return !strings.Contains(language, "-") && strings.EqualFold(primary, language)
 }
 
 func (p Preset) validate() error {
@@ -21,6 +57,9 @@ func (p Preset) validate() error {
 	if p.Engine == "" {
 		return fmt.Errorf("preset engine cannot be empty")
 	}
+	if len(p.Voices) > 0 && p.VoiceRotation != VoiceRotationRoundRobin && p.VoiceRotation != VoiceRotationHash {
+		return fmt.Errorf("unknown voice rotation %q", p.VoiceRotation)
+	}
 	return nil
 }
 
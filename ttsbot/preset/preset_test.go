@@ -36,6 +36,16 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "unknown voice rotation",
+			preset: Preset{
+				Identifier:    "test_preset",
+				Engine:        "test_engine",
+				Voices:        []string{"a", "b"},
+				VoiceRotation: "random",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tc := range testcases {
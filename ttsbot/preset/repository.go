@@ -4,11 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/jmoiron/sqlx"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database"
 )
 
 type Scope string
@@ -28,20 +31,29 @@ var (
 
 type PresetIDRepository interface {
 	Find(ctx context.Context, scope Scope, ID snowflake.ID) (PresetID, error)
+	// FindMany returns the preset IDs of the given IDs in a single query.
+	// IDs without a preset ID are omitted from the result.
+	FindMany(ctx context.Context, scope Scope, IDs ...snowflake.ID) (map[snowflake.ID]PresetID, error)
 	Save(ctx context.Context, scope Scope, ID snowflake.ID, presetID PresetID) error
 	Delete(ctx context.Context, scope Scope, ID snowflake.ID) error
+	// SaveAll saves all scoped preset IDs in a single transaction, so either all or none of them are stored.
+	// An entry with an empty PresetID deletes the preset ID of the scope.
+	SaveAll(ctx context.Context, entries []ScopedPresetID) error
 }
 
 func NewPresetIDRepository(db *sqlx.DB) PresetIDRepository {
+	dialect := database.DialectOf(db)
 	return &presetIDRepositoryImpl{
-		db:   db,
-		psql: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Question),
+		db:      db,
+		psql:    dialect.StatementBuilder(),
+		dialect: dialect,
 	}
 }
 
 type presetIDRepositoryImpl struct {
-	db   *sqlx.DB
-	psql squirrel.StatementBuilderType
+	db      *sqlx.DB
+	psql    squirrel.StatementBuilderType
+	dialect database.Dialect
 }
 
 type ScopedPresetID struct {
@@ -71,22 +83,56 @@ func (r *presetIDRepositoryImpl) Find(ctx context.Context, scope Scope, ID snowf
 	return presetID, nil
 }
 
+func (r *presetIDRepositoryImpl) FindMany(ctx context.Context, scope Scope, IDs ...snowflake.ID) (map[snowflake.ID]PresetID, error) {
+	presetIDs := make(map[snowflake.ID]PresetID, len(IDs))
+	if len(IDs) == 0 {
+		return presetIDs, nil
+	}
+
+	query, args, err := r.psql.Select("id", "preset_id").
+		From("scoped_preset_ids").
+		Where(squirrel.Eq{"scope": scope, "id": IDs}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID       snowflake.ID `db:"id"`
+		PresetID PresetID     `db:"preset_id"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		presetIDs[row.ID] = row.PresetID
+	}
+	return presetIDs, nil
+}
+
 func (r *presetIDRepositoryImpl) Save(ctx context.Context, scope Scope, ID snowflake.ID, presetID PresetID) error {
-	now := time.Now()
-	query, args, err := r.psql.Insert("scoped_preset_ids").
+	return r.save(ctx, r.db, scope, ID, presetID, time.Now())
+}
+
+func (r *presetIDRepositoryImpl) save(ctx context.Context, exec sqlx.ExecerContext, scope Scope, ID snowflake.ID, presetID PresetID, now time.Time) error {
+	insert := r.psql.Insert("scoped_preset_ids").
 		Columns("scope", "id", "preset_id", "created_at", "updated_at").
-		Values(scope, ID, presetID, now, now).
-		Suffix("ON CONFLICT(scope, id) DO UPDATE SET preset_id = ?, updated_at = ?", presetID, now).
+		Values(scope, ID, presetID, now, now)
+	query, args, err := r.dialect.Upsert(insert, []string{"scope", "id"}, []string{"preset_id", "updated_at"}).
 		ToSql()
 	if err != nil {
 		return err
 	}
 
-	_, err = r.db.ExecContext(ctx, query, args...)
+	_, err = exec.ExecContext(ctx, query, args...)
 	return err
 }
 
 func (r *presetIDRepositoryImpl) Delete(ctx context.Context, scope Scope, ID snowflake.ID) error {
+	return r.delete(ctx, r.db, scope, ID)
+}
+
+func (r *presetIDRepositoryImpl) delete(ctx context.Context, exec sqlx.ExecerContext, scope Scope, ID snowflake.ID) error {
 	query, args, err := r.psql.Delete("scoped_preset_ids").
 		Where(squirrel.Eq{"scope": scope, "id": ID}).
 		ToSql()
@@ -94,10 +140,32 @@ func (r *presetIDRepositoryImpl) Delete(ctx context.Context, scope Scope, ID sno
 		return err
 	}
 
-	_, err = r.db.ExecContext(ctx, query, args...)
+	_, err = exec.ExecContext(ctx, query, args...)
 	return err
 }
 
+func (r *presetIDRepositoryImpl) SaveAll(ctx context.Context, entries []ScopedPresetID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.PresetID == "" {
+			err = r.delete(ctx, tx, entry.Scope, entry.ID)
+		} else {
+			err = r.save(ctx, tx, entry.Scope, entry.ID, entry.PresetID, now)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save preset ID for %s %s: %w", entry.Scope, entry.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
 type MockPresetIDRepository struct {
 }
 
@@ -105,6 +173,10 @@ func (m *MockPresetIDRepository) Find(ctx context.Context, scope Scope, ID snowf
 	return "", ErrNotFound
 }
 
+func (m *MockPresetIDRepository) FindMany(ctx context.Context, scope Scope, IDs ...snowflake.ID) (map[snowflake.ID]PresetID, error) {
+	return map[snowflake.ID]PresetID{}, nil
+}
+
 func (m *MockPresetIDRepository) Save(ctx context.Context, scope Scope, ID snowflake.ID, presetID PresetID) error {
 	return nil
 }
@@ -112,3 +184,7 @@ func (m *MockPresetIDRepository) Save(ctx context.Context, scope Scope, ID snowf
 func (m *MockPresetIDRepository) Delete(ctx context.Context, scope Scope, ID snowflake.ID) error {
 	return nil
 }
+
+func (m *MockPresetIDRepository) SaveAll(ctx context.Context, entries []ScopedPresetID) error {
+	return nil
+}
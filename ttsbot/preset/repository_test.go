@@ -5,23 +5,27 @@ import (
 	"testing"
 
 	"github.com/disgoorg/snowflake/v2"
-	"github.com/jmoiron/sqlx"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
 	_ "modernc.org/sqlite"
 
-	"github.com/pressly/goose/v3"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database/databasetest"
 	"github.com/stretchr/testify/require"
 )
 
 func TestPresetIDRepository(t *testing.T) {
-	db, err := sqlx.Connect("sqlite", "file::memory:?cache=shared")
-	require.NoError(t, err)
+	for _, database := range databasetest.Databases {
+		t.Run(database.Name, func(t *testing.T) {
+			testPresetIDRepository(t, NewPresetIDRepository(database.Open(t, "../../migrations")))
+		})
+	}
+}
 
-	// always use the latest schema
-	goose.SetBaseFS(nil)
-	require.NoError(t, goose.SetDialect("sqlite3"))
-	require.NoError(t, goose.Up(db.DB, "../../migrations"))
+func TestMemoryPresetIDRepository(t *testing.T) {
+	testPresetIDRepository(t, NewMemoryPresetIDRepository())
+}
 
-	repo := NewPresetIDRepository(db)
+func testPresetIDRepository(t *testing.T, repo PresetIDRepository) {
 	ctx := context.Background()
 
 	t.Run("Save and Find", func(t *testing.T) {
@@ -76,4 +80,43 @@ func TestPresetIDRepository(t *testing.T) {
 		_, err = repo.Find(ctx, scope, scopeID)
 		require.ErrorIs(t, err, ErrNotFound)
 	})
+
+	t.Run("SaveAll", func(t *testing.T) {
+		require.NoError(t, repo.Save(ctx, ScopeUser, 111, "test-preset-a"))
+
+		err := repo.SaveAll(ctx, []ScopedPresetID{
+			{Scope: ScopeGuild, ID: 222, PresetID: "test-preset-b"},
+			{Scope: ScopeUser, ID: 333, PresetID: "test-preset-c"},
+			{Scope: ScopeUser, ID: 111},
+		})
+		require.NoError(t, err)
+
+		found, err := repo.Find(ctx, ScopeGuild, 222)
+		require.NoError(t, err)
+		require.Equal(t, PresetID("test-preset-b"), found)
+
+		found, err = repo.Find(ctx, ScopeUser, 333)
+		require.NoError(t, err)
+		require.Equal(t, PresetID("test-preset-c"), found)
+
+		_, err = repo.Find(ctx, ScopeUser, 111)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("FindMany", func(t *testing.T) {
+		require.NoError(t, repo.Save(ctx, ScopeUser, 555, "test-preset-a"))
+		require.NoError(t, repo.Save(ctx, ScopeUser, 666, "test-preset-b"))
+		require.NoError(t, repo.Save(ctx, ScopeGuild, 777, "test-preset-c"))
+
+		found, err := repo.FindMany(ctx, ScopeUser, 555, 666, 777, 888)
+		require.NoError(t, err)
+		require.Equal(t, map[snowflake.ID]PresetID{
+			555: "test-preset-a",
+			666: "test-preset-b",
+		}, found)
+
+		found, err = repo.FindMany(ctx, ScopeUser)
+		require.NoError(t, err)
+		require.Empty(t, found)
+	})
 }
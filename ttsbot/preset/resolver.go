@@ -9,13 +9,44 @@ import (
 	"github.com/disgoorg/snowflake/v2"
 )
 
+// ResolutionContext describes where a preset is needed, e.g. the message being read.
+// Zero values are ignored, so callers only fill in what they know.
+type ResolutionContext struct {
+	GuildID   snowflake.ID
+	ChannelID snowflake.ID
+	UserID    snowflake.ID
+	RoleIDs   []snowflake.ID
+}
+
+type scopedID struct {
+	scope Scope
+	id    snowflake.ID
+}
+
+// candidates returns the scoped IDs to look up, in order of priority.
+// New scopes (e.g. channel or role) only need to be added here.
+func (rc ResolutionContext) candidates() []scopedID {
+	candidates := make([]scopedID, 0, 2)
+	if rc.UserID != 0 {
+		candidates = append(candidates, scopedID{ScopeUser, rc.UserID})
+	}
+	if rc.GuildID != 0 {
+		candidates = append(candidates, scopedID{ScopeGuild, rc.GuildID})
+	}
+	return candidates
+}
+
 // PresetResolver defines the interface for resolving presets based on user and guild IDs.
 type PresetResolver interface {
-	// Resolve returns the preset for the given guild and user.
-	// Resolve tries to find a preset in the following order:
+	// ResolveContext returns the preset for the given resolution context.
+	// It tries to find a preset in the following order:
 	// 1. User-specific preset (ScopeUser).
 	// 2. Guild-specific preset (ScopeGuild).
-	// 3. If no user or guild preset is found, it returns the fallback preset.
+	// 3. If no preset is found, it returns the fallback preset.
+	ResolveContext(ctx context.Context, rc ResolutionContext) (Preset, error)
+
+	// Resolve returns the preset for the given guild and user.
+	// It is a shorthand for ResolveContext with only the guild and user IDs.
 	Resolve(ctx context.Context, guildID, userID snowflake.ID) (Preset, error)
 
 	// ResolveGuildPreset returns the preset for the given guild.
@@ -24,9 +55,18 @@ type PresetResolver interface {
 	// 1. Guild-specific preset (ScopeGuild).
 	// 2. If no guild preset is found, it returns the fallback preset.
 	ResolveGuildPreset(ctx context.Context, guildID snowflake.ID) (Preset, error)
+
+	// Lookup returns the preset with the identifier, if the guild may use it.
+	Lookup(guildID snowflake.ID, presetID PresetID) (Preset, bool)
+
+	// ResolveLanguage returns a preset the guild may use speaking the language, e.g. "en" or "en-US".
+	// It prefers current, then presets of the engine of current, so that switching languages keeps a similar voice.
+	ResolveLanguage(guildID snowflake.ID, language string, current Preset) (Preset, bool)
 }
 
-func NewPresetResolver(registry *PresetRegistry, repository PresetIDRepository, fallbackPresetID PresetID) (PresetResolver, error) {
+// NewPresetResolver creates a resolver which skips presets restricted in the guild being resolved for.
+// restrictions may be nil to allow every preset. The fallback preset is never restricted.
+func NewPresetResolver(registry *PresetRegistry, repository PresetIDRepository, restrictions *Restrictions, fallbackPresetID PresetID) (PresetResolver, error) {
 	// Validate the fallback preset ID exists in the registry
 	if _, ok := registry.Get(fallbackPresetID); !ok {
 		return nil, fmt.Errorf("fallback preset ID %s not found in registry", fallbackPresetID)
@@ -35,6 +75,7 @@ func NewPresetResolver(registry *PresetRegistry, repository PresetIDRepository,
 	return &presetResolverImpl{
 		registry:         registry,
 		repository:       repository,
+		restrictions:     restrictions,
 		fallbackPresetID: fallbackPresetID,
 	}, nil
 }
@@ -42,51 +83,57 @@ func NewPresetResolver(registry *PresetRegistry, repository PresetIDRepository,
 type presetResolverImpl struct {
 	registry         *PresetRegistry
 	repository       PresetIDRepository
+	restrictions     *Restrictions
 	fallbackPresetID PresetID
 }
 
 func (r *presetResolverImpl) Resolve(ctx context.Context, guildID, userID snowflake.ID) (Preset, error) {
-	presetID, err := r.resolveID(ctx, guildID, userID)
+	return r.ResolveContext(ctx, ResolutionContext{GuildID: guildID, UserID: userID})
+}
+
+func (r *presetResolverImpl) ResolveContext(ctx context.Context, rc ResolutionContext) (Preset, error) {
+	presetID, err := r.resolveID(ctx, rc)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
 			// just log the error to notify about the issue, but use the fallback preset ID
-			slog.Warn("failed to resolve preset ID", "guildID", guildID, "userID", userID, "error", err)
+			slog.Warn("failed to resolve preset ID", "guildID", rc.GuildID, "userID", rc.UserID, "error", err)
 		}
 		presetID = r.fallbackPresetID
 	}
 	preset, ok := r.registry.Get(presetID)
 	if !ok {
-		slog.Error("preset not found in registry", "presetID", presetID, "guildID", guildID, "userID", userID)
+		slog.Error("preset not found in registry", "presetID", presetID, "guildID", rc.GuildID, "userID", rc.UserID)
 		return Preset{}, fmt.Errorf("preset not found for ID %s", presetID)
 	}
 
 	return preset, nil
 }
 
-func (r *presetResolverImpl) resolveID(ctx context.Context, guildID, userID snowflake.ID) (PresetID, error) {
-	presetID, err := r.repository.Find(ctx, ScopeUser, userID)
-	if err == nil {
-		return presetID, nil
-	}
-	if !errors.Is(err, ErrNotFound) {
-		return "", err
-	}
-
-	// If no user-specific preset is found, fall back to guild-specific preset
-	presetID, err = r.repository.Find(ctx, ScopeGuild, guildID)
-	if err == nil {
-		return presetID, nil
-	}
-	if !errors.Is(err, ErrNotFound) {
-		return "", err
+func (r *presetResolverImpl) resolveID(ctx context.Context, rc ResolutionContext) (PresetID, error) {
+	for _, candidate := range rc.candidates() {
+		presetID, err := r.repository.Find(ctx, candidate.scope, candidate.id)
+		if err == nil {
+			if !r.allows(rc.GuildID, presetID) {
+				slog.Info("preset is restricted in the guild, skipping", "presetID", presetID, "guildID", rc.GuildID, "scope", candidate.scope)
+				continue
+			}
+			return presetID, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
 	}
 
-	// If no user or guild preset is found, return an error
+	// If no preset is found in any scope, return an error
 	return "", ErrNotFound
 }
 
 func (r *presetResolverImpl) ResolveGuildPreset(ctx context.Context, guildID snowflake.ID) (Preset, error) {
 	presetID, err := r.repository.Find(ctx, ScopeGuild, guildID)
+	if err == nil && !r.allows(guildID, presetID) {
+		slog.Info("guild preset is restricted, using the fallback preset", "presetID", presetID, "guildID", guildID)
+		presetID = r.fallbackPresetID
+	}
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
 			// just log the error to notify about the issue, but use the fallback preset ID
@@ -103,3 +150,39 @@ func (r *presetResolverImpl) ResolveGuildPreset(ctx context.Context, guildID sno
 
 	return preset, nil
 }
+
+func (r *presetResolverImpl) Lookup(guildID snowflake.ID, presetID PresetID) (Preset, bool) {
+	preset, ok := r.registry.Get(presetID)
+	if !ok || !r.restrictions.Allows(guildID, preset) {
+		return Preset{}, false
+	}
+	return preset, true
+}
+
+func (r *presetResolverImpl) ResolveLanguage(guildID snowflake.ID, language string, current Preset) (Preset, bool) {
+	if current.Speaks(language) {
+		return current, true
+	}
+	var found *Preset
+	for _, preset := range r.registry.List() {
+		if !preset.Speaks(language) || !r.restrictions.Allows(guildID, preset) {
+			continue
+		}
+		if preset.Engine == current.Engine {
+			return preset, true
+		}
+		if found == nil {
+			found = &preset
+		}
+	}
+	if found == nil {
+		return Preset{}, false
+	}
+	return *found, true
+}
+
+// allows reports whether the guild may use the preset. Unknown presets are reported as not found by the caller instead.
+func (r *presetResolverImpl) allows(guildID snowflake.ID, presetID PresetID) bool {
+	preset, ok := r.registry.Get(presetID)
+	return !ok || r.restrictions.Allows(guildID, preset)
+}
@@ -47,7 +47,7 @@ func TestNewPresetResolver(t *testing.T) {
 			repo := struct {
 				PresetIDRepository
 			}{}
-			_, err := NewPresetResolver(registry, repo, tc.fallbackID)
+			_, err := NewPresetResolver(registry, repo, nil, tc.fallbackID)
 
 			if (err != nil) != tc.wantErr {
 				t.Errorf("NewPresetResolver() error = %v, wantErr %v", err, tc.wantErr)
@@ -84,7 +84,7 @@ func TestResolve(t *testing.T) {
 	}
 
 	repo := &FindStub{}
-	resolver, err := NewPresetResolver(registry, repo, "fallback_preset")
+	resolver, err := NewPresetResolver(registry, repo, nil, "fallback_preset")
 	if err != nil {
 		t.Fatalf("failed to create resolver: %v", err)
 	}
@@ -128,3 +128,186 @@ func TestResolve(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveContext(t *testing.T) {
+	registry := NewPresetRegistry()
+	presets := []Preset{
+		{Identifier: "sample_user_preset", Engine: "test_engine"},
+		{Identifier: "sample_guild_preset", Engine: "test_engine"},
+		{Identifier: "fallback_preset", Engine: "test_engine"},
+	}
+	for _, preset := range presets {
+		if err := registry.Register(preset); err != nil {
+			t.Fatalf("failed to register preset: %v", err)
+		}
+	}
+
+	resolver, err := NewPresetResolver(registry, &FindStub{}, nil, "fallback_preset")
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+
+	testcases := []struct {
+		name   string
+		rc     ResolutionContext
+		wantID PresetID
+	}{
+		{
+			name:   "user preset takes priority over guild preset",
+			rc:     ResolutionContext{GuildID: 20, ChannelID: 30, UserID: 10, RoleIDs: []snowflake.ID{40}},
+			wantID: "sample_user_preset",
+		},
+		{
+			name:   "guild preset when user has none",
+			rc:     ResolutionContext{GuildID: 20, ChannelID: 30, UserID: 11},
+			wantID: "sample_guild_preset",
+		},
+		{
+			name:   "user preset when guild has none",
+			rc:     ResolutionContext{GuildID: 21, UserID: 10},
+			wantID: "sample_user_preset",
+		},
+		{
+			name:   "fallback preset for empty context",
+			rc:     ResolutionContext{},
+			wantID: "fallback_preset",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			preset, err := resolver.ResolveContext(context.Background(), tc.rc)
+			if err != nil {
+				t.Errorf("ResolveContext() error = %v, no error expected", err)
+				return
+			}
+			if preset.Identifier != tc.wantID {
+				t.Errorf("ResolveContext() got = %v, want %v", preset.Identifier, tc.wantID)
+			}
+		})
+	}
+}
+
+func TestResolveContextRestricted(t *testing.T) {
+	registry := NewPresetRegistry()
+	presets := []Preset{
+		{Identifier: "sample_user_preset", Engine: "test_engine", VoiceName: "ja-JP-Neural2-B"},
+		{Identifier: "sample_guild_preset", Engine: "test_engine", VoiceName: "ja-JP-Standard-A"},
+		{Identifier: "fallback_preset", Engine: "test_engine"},
+	}
+	for _, preset := range presets {
+		if err := registry.Register(preset); err != nil {
+			t.Fatalf("failed to register preset: %v", err)
+		}
+	}
+
+	restrictions := NewRestrictions(Restriction{DeniedVoices: []string{"*-Neural2-*"}}, map[snowflake.ID]Restriction{
+		21: {}, // premium guild without restrictions
+		20: {DeniedEngines: []string{"test_engine"}, DeniedVoices: []string{"*-Neural2-*"}},
+	})
+	resolver, err := NewPresetResolver(registry, &FindStub{}, restrictions, "fallback_preset")
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+
+	testcases := []struct {
+		name   string
+		rc     ResolutionContext
+		wantID PresetID
+	}{
+		{
+			name:   "restricted user preset is skipped",
+			rc:     ResolutionContext{GuildID: 22, UserID: 10},
+			wantID: "fallback_preset",
+		},
+		{
+			name:   "unrestricted guild uses the user preset",
+			rc:     ResolutionContext{GuildID: 21, UserID: 10},
+			wantID: "sample_user_preset",
+		},
+		{
+			name:   "fallback preset is never restricted",
+			rc:     ResolutionContext{GuildID: 20, UserID: 10},
+			wantID: "fallback_preset",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			preset, err := resolver.ResolveContext(context.Background(), tc.rc)
+			if err != nil {
+				t.Errorf("ResolveContext() error = %v, no error expected", err)
+				return
+			}
+			if preset.Identifier != tc.wantID {
+				t.Errorf("ResolveContext() got = %v, want %v", preset.Identifier, tc.wantID)
+			}
+		})
+	}
+
+	preset, err := resolver.ResolveGuildPreset(context.Background(), 20)
+	if err != nil {
+		t.Fatalf("ResolveGuildPreset() error = %v, no error expected", err)
+	}
+	if preset.Identifier != "fallback_preset" {
+		t.Errorf("ResolveGuildPreset() got = %v, want fallback_preset", preset.Identifier)
+	}
+}
+
+func TestResolveLanguage(t *testing.T) {
+	registry := NewPresetRegistry()
+	presets := []Preset{
+		{Identifier: "ja_google", Engine: "google", Language: "ja-JP", VoiceName: "ja-JP-Standard-A"},
+		{Identifier: "en_polly", Engine: "polly", Language: "en-US", VoiceName: "Joanna"},
+		{Identifier: "en_google", Engine: "google", Language: "en-US", VoiceName: "en-US-Neural2-A"},
+		{Identifier: "en_gb_google", Engine: "google", Language: "en-GB", VoiceName: "en-GB-Standard-A"},
+	}
+	for _, preset := range presets {
+		if err := registry.Register(preset); err != nil {
+			t.Fatalf("failed to register preset: %v", err)
+		}
+	}
+
+	restrictions := NewRestrictions(Restriction{}, map[snowflake.ID]Restriction{
+		20: {DeniedVoices: []string{"*-Neural2-*"}},
+	})
+	resolver, err := NewPresetResolver(registry, &FindStub{}, restrictions, "ja_google")
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+	current, _ := registry.Get("ja_google")
+
+	testcases := []struct {
+		name     string
+		guildID  snowflake.ID
+		language string
+		current  Preset
+		wantID   PresetID
+		wantOK   bool
+	}{
+		{name: "same engine is preferred", guildID: 21, language: "en", current: current, wantID: "en_google", wantOK: true},
+		{name: "region narrows the presets", guildID: 21, language: "en-GB", current: current, wantID: "en_gb_google", wantOK: true},
+		{name: "current preset is kept", guildID: 21, language: "JA", current: current, wantID: "ja_google", wantOK: true},
+		{name: "restricted presets are skipped", guildID: 20, language: "en-US", current: current, wantID: "en_polly", wantOK: true},
+		{name: "unknown language", guildID: 21, language: "fr", current: current, wantOK: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			preset, ok := resolver.ResolveLanguage(tc.guildID, tc.language, tc.current)
+			if ok != tc.wantOK {
+				t.Fatalf("ResolveLanguage() ok = %v, want %v", ok, tc.wantOK)
+			}
+			if preset.Identifier != tc.wantID {
+				t.Errorf("ResolveLanguage() got = %v, want %v", preset.Identifier, tc.wantID)
+			}
+		})
+	}
+
+	if _, ok := resolver.Lookup(20, "en_google"); ok {
+		t.Errorf("Lookup() found a restricted preset")
+	}
+	if preset, ok := resolver.Lookup(21, "en_google"); !ok || preset.Identifier != "en_google" {
+		t.Errorf("Lookup() got = %v, %v, want en_google", preset.Identifier, ok)
+	}
+}
@@ -0,0 +1,89 @@
+package preset
+
+import (
+	"path"
+	"slices"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// Restriction limits the engines and voices a guild may use, e.g. to keep expensive voices for premium guilds.
+type Restriction struct {
+	DeniedEngines []string
+	// DeniedVoices are path.Match patterns of voice names, e.g. "*-Neural2-*".
+	DeniedVoices []string
+}
+
+// Allows reports whether the preset uses neither a denied engine nor a denied voice.
+// A multi-voice preset is denied if any of its voices is.
+func (r Restriction) Allows(preset Preset) bool {
+	if slices.Contains(r.DeniedEngines, preset.Engine) {
+		return false
+	}
+	for _, pattern := range r.DeniedVoices {
+		for _, voiceName := range preset.VoiceNames() {
+			if matched, _ := path.Match(pattern, voiceName); matched {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Restrictions holds the restriction of each guild.
+// Guilds without their own restriction use the premium one if they are premium, and the default one otherwise.
+// A nil *Restrictions allows every preset.
+type Restrictions struct {
+	defaultRestriction Restriction
+	guilds             map[snowflake.ID]Restriction
+	premiumRestriction Restriction
+	// premium reports whether a guild is premium. Nil makes no guild premium.
+	premium func(guildID snowflake.ID) bool
+}
+
+func NewRestrictions(defaultRestriction Restriction, guilds map[snowflake.ID]Restriction) *Restrictions {
+	return &Restrictions{
+		defaultRestriction: defaultRestriction,
+		guilds:             guilds,
+	}
+}
+
+// WithPremium returns a copy of the restrictions applying the premium restriction to premium guilds without their own restriction,
+// e.g. to allow them the expensive voices denied by the default restriction.
+func (r *Restrictions) WithPremium(premiumRestriction Restriction, premium func(guildID snowflake.ID) bool) *Restrictions {
+	restrictions := &Restrictions{}
+	if r != nil {
+		*restrictions = *r
+	}
+	restrictions.premiumRestriction = premiumRestriction
+	restrictions.premium = premium
+	return restrictions
+}
+
+// For returns the restriction of the guild.
+func (r *Restrictions) For(guildID snowflake.ID) Restriction {
+	if r == nil {
+		return Restriction{}
+	}
+	if restriction, ok := r.guilds[guildID]; ok {
+		return restriction
+	}
+	if guildID != 0 && r.premium != nil && r.premium(guildID) {
+		return r.premiumRestriction
+	}
+	return r.defaultRestriction
+}
+
+// Allows reports whether the guild may use the preset.
+// Presets used outside of guilds (guildID 0) are only limited by the default restriction.
+func (r *Restrictions) Allows(guildID snowflake.ID, preset Preset) bool {
+	return r.For(guildID).Allows(preset)
+}
+
+// Filter returns the presets the guild may use.
+func (r *Restrictions) Filter(guildID snowflake.ID, presets []Preset) []Preset {
+	restriction := r.For(guildID)
+	return slices.DeleteFunc(slices.Clone(presets), func(preset Preset) bool {
+		return !restriction.Allows(preset)
+	})
+}
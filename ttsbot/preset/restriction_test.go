@@ -0,0 +1,78 @@
+package preset
+
+import (
+	"testing"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+func TestRestriction(t *testing.T) {
+	neural := Preset{Identifier: "neural", Engine: "google", VoiceName: "ja-JP-Neural2-B"}
+	standard := Preset{Identifier: "standard", Engine: "google", VoiceName: "ja-JP-Standard-A"}
+	polly := Preset{Identifier: "polly", Engine: "polly", VoiceName: "Mizuki"}
+
+	restrictions := NewRestrictions(Restriction{DeniedVoices: []string{"*-Neural2-*"}}, map[snowflake.ID]Restriction{
+		1: {},
+		2: {DeniedEngines: []string{"polly"}},
+	})
+
+	testcases := []struct {
+		name    string
+		guildID snowflake.ID
+		preset  Preset
+		want    bool
+	}{
+		{"default denies voice", 3, neural, false},
+		{"default allows other voices", 3, standard, true},
+		{"guild without restrictions", 1, neural, true},
+		{"guild restriction replaces default", 2, neural, true},
+		{"guild denies engine", 2, polly, false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := restrictions.Allows(tc.guildID, tc.preset); got != tc.want {
+				t.Errorf("Allows() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	filtered := restrictions.Filter(3, []Preset{neural, standard, polly})
+	if len(filtered) != 2 || filtered[0].Identifier != "standard" || filtered[1].Identifier != "polly" {
+		t.Errorf("Filter() = %v, want [standard polly]", filtered)
+	}
+
+	var none *Restrictions
+	if !none.Allows(3, neural) {
+		t.Errorf("nil restrictions should allow every preset")
+	}
+}
+
+func TestRestrictionsWithPremium(t *testing.T) {
+	neural := Preset{Identifier: "neural", Engine: "google", VoiceName: "ja-JP-Neural2-B"}
+	polly := Preset{Identifier: "polly", Engine: "polly", VoiceName: "Mizuki"}
+
+	restrictions := NewRestrictions(Restriction{DeniedVoices: []string{"*-Neural2-*"}}, map[snowflake.ID]Restriction{
+		2: {DeniedEngines: []string{"polly"}},
+	}).WithPremium(Restriction{}, func(guildID snowflake.ID) bool {
+		return guildID <= 2
+	})
+
+	testcases := []struct {
+		name    string
+		guildID snowflake.ID
+		preset  Preset
+		want    bool
+	}{
+		{"premium guild", 1, neural, true},
+		{"guild restriction precedes premium", 2, polly, false},
+		{"guild that is not premium", 3, neural, false},
+		{"outside of guilds", 0, neural, false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := restrictions.Allows(tc.guildID, tc.preset); got != tc.want {
+				t.Errorf("Allows() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
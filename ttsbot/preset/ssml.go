@@ -0,0 +1,64 @@
+package preset
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// SSMLTemplate wraps the text being read in SSML, e.g. `<speak><prosody rate="90%">{{.Text}}</prosody></speak>`.
+// Text is XML-escaped before it is inserted.
+type SSMLTemplate struct {
+	source string
+	tmpl   *template.Template
+}
+
+// ParseSSMLTemplate parses the template and checks that it renders well-formed SSML.
+func ParseSSMLTemplate(source string) (*SSMLTemplate, error) {
+	tmpl, err := template.New("ssml").Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSML template: %w", err)
+	}
+
+	t := &SSMLTemplate{source: source, tmpl: tmpl}
+	rendered, err := t.Render("text")
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(strings.TrimSpace(rendered), "<speak") {
+		return nil, fmt.Errorf("invalid SSML template: must have <speak> as the root element")
+	}
+	decoder := xml.NewDecoder(strings.NewReader(rendered))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("invalid SSML template: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// Render returns the SSML of the text.
+func (t *SSMLTemplate) Render(text string) (string, error) {
+	var escaped strings.Builder
+	if err := xml.EscapeText(&escaped, []byte(text)); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, struct{ Text string }{escaped.String()}); err != nil {
+		return "", fmt.Errorf("failed to render SSML template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// String returns the source of the template.
+func (t *SSMLTemplate) String() string {
+	return t.source
+}
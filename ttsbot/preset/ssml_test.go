@@ -0,0 +1,41 @@
+package preset
+
+import (
+	"testing"
+)
+
+func TestSSMLTemplate(t *testing.T) {
+	tmpl, err := ParseSSMLTemplate(`<speak><prosody rate="90%">{{.Text}}</prosody></speak>`)
+	if err != nil {
+		t.Fatalf("ParseSSMLTemplate() error = %v", err)
+	}
+
+	got, err := tmpl.Render(`a < b & "c"`)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := `<speak><prosody rate="90%">a &lt; b &amp; &#34;c&#34;</prosody></speak>`
+	if got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestParseSSMLTemplateErrors(t *testing.T) {
+	testcases := []struct {
+		name   string
+		source string
+	}{
+		{name: "template syntax", source: `<speak>{{.Text</speak>`},
+		{name: "unknown field", source: `<speak>{{.Voice}}</speak>`},
+		{name: "no speak root", source: `<prosody rate="90%">{{.Text}}</prosody>`},
+		{name: "unclosed element", source: `<speak><emphasis>{{.Text}}</speak>`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseSSMLTemplate(tc.source); err == nil {
+				t.Errorf("ParseSSMLTemplate(%q) error = nil, want an error", tc.source)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package preset
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// VoiceRotation decides how the voices of a multi-voice preset are assigned to speakers.
+type VoiceRotation string
+
+const (
+	// VoiceRotationRoundRobin gives each new speaker of a session the next voice of the preset.
+	VoiceRotationRoundRobin VoiceRotation = "round_robin"
+	// VoiceRotationHash picks the voice from the speaker's user ID, so a speaker keeps the same voice across sessions.
+	VoiceRotationHash VoiceRotation = "hash"
+)
+
+// VoiceAssigner assigns the voices of multi-voice presets to speakers.
+// A speaker keeps the assigned voice for as long as the assigner lives, which is usually a session.
+type VoiceAssigner struct {
+	mu       sync.Mutex
+	assigned map[PresetID]map[snowflake.ID]string
+	next     map[PresetID]int
+}
+
+func NewVoiceAssigner() *VoiceAssigner {
+	return &VoiceAssigner{
+		assigned: make(map[PresetID]map[snowflake.ID]string),
+		next:     make(map[PresetID]int),
+	}
+}
+
+// Assign returns the preset with the voice of the speaker as its voice name.
+// Presets with a single voice are returned as is.
+func (a *VoiceAssigner) Assign(preset Preset, speakerID snowflake.ID) Preset {
+	if len(preset.Voices) < 2 {
+		return preset
+	}
+
+	if preset.VoiceRotation == VoiceRotationHash {
+		h := fnv.New32a()
+		h.Write([]byte(strconv.FormatUint(uint64(speakerID), 10)))
+		preset.VoiceName = preset.Voices[h.Sum32()%uint32(len(preset.Voices))]
+		return preset
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	speakers, ok := a.assigned[preset.Identifier]
+	if !ok {
+		speakers = make(map[snowflake.ID]string)
+		a.assigned[preset.Identifier] = speakers
+	}
+	voiceName, ok := speakers[speakerID]
+	if !ok {
+		voiceName = preset.Voices[a.next[preset.Identifier]%len(preset.Voices)]
+		a.next[preset.Identifier]++
+		speakers[speakerID] = voiceName
+	}
+	preset.VoiceName = voiceName
+	return preset
+}
@@ -0,0 +1,52 @@
+package preset
+
+import (
+	"testing"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+func TestVoiceAssigner(t *testing.T) {
+	roundRobin := Preset{
+		Identifier:    "mix",
+		Engine:        "test_engine",
+		VoiceName:     "a",
+		Voices:        []string{"a", "b"},
+		VoiceRotation: VoiceRotationRoundRobin,
+	}
+
+	t.Run("round robin", func(t *testing.T) {
+		assigner := NewVoiceAssigner()
+		got := []string{
+			assigner.Assign(roundRobin, 1).VoiceName,
+			assigner.Assign(roundRobin, 2).VoiceName,
+			assigner.Assign(roundRobin, 3).VoiceName,
+			assigner.Assign(roundRobin, 1).VoiceName,
+		}
+		want := []string{"a", "b", "a", "a"}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("Assign() voices = %v, want %v", got, want)
+				break
+			}
+		}
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		hashed := roundRobin
+		hashed.VoiceRotation = VoiceRotationHash
+		first := NewVoiceAssigner().Assign(hashed, 12345).VoiceName
+		for i := 0; i < 3; i++ {
+			if got := NewVoiceAssigner().Assign(hashed, 12345).VoiceName; got != first {
+				t.Errorf("Assign() = %q, want the same voice %q for the same speaker", got, first)
+			}
+		}
+	})
+
+	t.Run("single voice", func(t *testing.T) {
+		single := Preset{Identifier: "single", Engine: "test_engine", VoiceName: "a"}
+		if got := NewVoiceAssigner().Assign(single, snowflake.ID(1)).VoiceName; got != "a" {
+			t.Errorf("Assign() = %q, want %q", got, "a")
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+// PurgeFunc deletes the data a departed guild left behind, e.g. its settings.
+type PurgeFunc func(ctx context.Context, guildID snowflake.ID) error
+
+// Cleaner deletes the data of guilds that removed the bot once the retention window has passed.
+// A guild that adds the bot back within the window keeps its data.
+type Cleaner struct {
+	departures DepartureRepository
+	retention  time.Duration
+	purgers    []PurgeFunc
+	clock      clock.Clock
+}
+
+func NewCleaner(departures DepartureRepository, retention time.Duration, purgers ...PurgeFunc) *Cleaner {
+	return &Cleaner{
+		departures: departures,
+		retention:  retention,
+		purgers:    purgers,
+		clock:      clock.Real,
+	}
+}
+
+// Depart schedules the data of the guild for deletion.
+func (c *Cleaner) Depart(ctx context.Context, guildID snowflake.ID, departedAt time.Time) error {
+	return c.departures.Save(ctx, guildID, departedAt)
+}
+
+// Return cancels the scheduled deletion of the data of the guild.
+func (c *Cleaner) Return(ctx context.Context, guildID snowflake.ID) error {
+	return c.departures.Delete(ctx, guildID)
+}
+
+// Purge deletes the data of the guilds that departed more than the retention window before now,
+// and returns the number of guilds purged. A guild whose data could not be deleted entirely is retried on the next purge.
+func (c *Cleaner) Purge(ctx context.Context, now time.Time) (int, error) {
+	guildIDs, err := c.departures.FindDepartedBefore(ctx, now.Add(-c.retention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find departed guilds: %w", err)
+	}
+
+	var (
+		purged int
+		errs   []error
+	)
+	for _, guildID := range guildIDs {
+		if err := c.purge(ctx, guildID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to purge guild %s: %w", guildID, err))
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(errs...)
+}
+
+func (c *Cleaner) purge(ctx context.Context, guildID snowflake.ID) error {
+	for _, purge := range c.purgers {
+		if err := purge(ctx, guildID); err != nil {
+			return err
+		}
+	}
+	return c.departures.Delete(ctx, guildID)
+}
+
+// StartPurgeLoop purges departed guilds every interval.
+func (c *Cleaner) StartPurgeLoop(interval time.Duration) {
+	ticker := c.clock.NewTicker(interval)
+	go func() {
+		for now := range ticker.C() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			purged, err := c.Purge(ctx, now)
+			cancel()
+			if err != nil {
+				slog.Error("Failed to purge departed guilds", slog.Any("err", err))
+			}
+			if purged > 0 {
+				slog.Info("Purged data of departed guilds", slog.Int("guilds", purged))
+			}
+		}
+	}()
+}
+
+// CreateGuildHandler creates an event listener that schedules the deletion of the data of guilds the bot leaves,
+// and cancels it for guilds the bot joins or finds itself in on startup.
+func (c *Cleaner) CreateGuildHandler() bot.EventListener {
+	return &events.ListenerAdapter{
+		OnGuildLeave: func(event *events.GuildLeave) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := c.Depart(ctx, event.GuildID, c.clock.Now()); err != nil {
+				slog.Error("Failed to schedule the deletion of departed guild data", slog.String("guildID", event.GuildID.String()), slog.Any("err", err))
+				return
+			}
+			slog.Info("Scheduled the deletion of departed guild data", slog.String("guildID", event.GuildID.String()), slog.Duration("retention", c.retention))
+		},
+		OnGuildJoin: func(event *events.GuildJoin) {
+			c.onGuildAvailable(event.GuildID)
+		},
+		OnGuildReady: func(event *events.GuildReady) {
+			c.onGuildAvailable(event.GuildID)
+		},
+	}
+}
+
+func (c *Cleaner) onGuildAvailable(guildID snowflake.ID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Return(ctx, guildID); err != nil {
+		slog.Error("Failed to cancel the deletion of guild data", slog.String("guildID", guildID.String()), slog.Any("err", err))
+	}
+}
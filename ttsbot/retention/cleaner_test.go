@@ -0,0 +1,55 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleaner(t *testing.T) {
+	ctx := context.Background()
+	departedAt := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	retention := 24 * time.Hour
+
+	var purged []snowflake.ID
+	failing := map[snowflake.ID]bool{}
+	cleaner := NewCleaner(NewMemoryDepartureRepository(), retention, func(ctx context.Context, guildID snowflake.ID) error {
+		if failing[guildID] {
+			return errors.New("purge failed")
+		}
+		purged = append(purged, guildID)
+		return nil
+	})
+
+	require.NoError(t, cleaner.Depart(ctx, 1, departedAt))
+	require.NoError(t, cleaner.Depart(ctx, 2, departedAt))
+	require.NoError(t, cleaner.Depart(ctx, 3, departedAt))
+	require.NoError(t, cleaner.Return(ctx, 2))
+	failing[3] = true
+
+	t.Run("within retention", func(t *testing.T) {
+		n, err := cleaner.Purge(ctx, departedAt.Add(retention-time.Minute))
+		require.NoError(t, err)
+		require.Zero(t, n)
+		require.Empty(t, purged)
+	})
+
+	t.Run("after retention", func(t *testing.T) {
+		n, err := cleaner.Purge(ctx, departedAt.Add(retention+time.Minute))
+		require.Error(t, err)
+		require.Equal(t, 1, n)
+		require.Equal(t, []snowflake.ID{1}, purged)
+	})
+
+	t.Run("failed purge is retried", func(t *testing.T) {
+		failing[3] = false
+		n, err := cleaner.Purge(ctx, departedAt.Add(retention+time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+		require.Equal(t, []snowflake.ID{1, 3}, purged)
+	})
+}
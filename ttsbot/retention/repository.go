@@ -0,0 +1,120 @@
+package retention
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database"
+)
+
+// DepartureRepository keeps track of the guilds the bot has been removed from.
+type DepartureRepository interface {
+	// Save records that the bot left the guild at departedAt.
+	Save(ctx context.Context, guildID snowflake.ID, departedAt time.Time) error
+	// Delete forgets the departure of the guild, e.g. when the bot is added back.
+	Delete(ctx context.Context, guildID snowflake.ID) error
+	// FindDepartedBefore returns the guilds the bot left before the given time.
+	FindDepartedBefore(ctx context.Context, before time.Time) ([]snowflake.ID, error)
+}
+
+func NewDepartureRepository(db *sqlx.DB) DepartureRepository {
+	dialect := database.DialectOf(db)
+	return &departureRepositoryImpl{
+		db:      db,
+		psql:    dialect.StatementBuilder(),
+		dialect: dialect,
+	}
+}
+
+type departureRepositoryImpl struct {
+	db      *sqlx.DB
+	psql    squirrel.StatementBuilderType
+	dialect database.Dialect
+}
+
+func (r *departureRepositoryImpl) Save(ctx context.Context, guildID snowflake.ID, departedAt time.Time) error {
+	insert := r.psql.Insert("departed_guilds").
+		Columns("guild_id", "departed_at").
+		Values(guildID, departedAt)
+	query, args, err := r.dialect.Upsert(insert, []string{"guild_id"}, []string{"departed_at"}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *departureRepositoryImpl) Delete(ctx context.Context, guildID snowflake.ID) error {
+	query, args, err := r.psql.Delete("departed_guilds").
+		Where(squirrel.Eq{"guild_id": guildID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *departureRepositoryImpl) FindDepartedBefore(ctx context.Context, before time.Time) ([]snowflake.ID, error) {
+	query, args, err := r.psql.Select("guild_id").
+		From("departed_guilds").
+		Where(squirrel.Lt{"departed_at": before}).
+		OrderBy("departed_at", "guild_id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var guildIDs []snowflake.ID
+	if err := r.db.SelectContext(ctx, &guildIDs, query, args...); err != nil {
+		return nil, err
+	}
+	return guildIDs, nil
+}
+
+// NewMemoryDepartureRepository returns a DepartureRepository that keeps departures in memory.
+// It is used when the bot runs without a database, so departures are lost on restart.
+func NewMemoryDepartureRepository() DepartureRepository {
+	return &memoryDepartureRepository{
+		departures: make(map[snowflake.ID]time.Time),
+	}
+}
+
+type memoryDepartureRepository struct {
+	mu         sync.RWMutex
+	departures map[snowflake.ID]time.Time
+}
+
+func (r *memoryDepartureRepository) Save(ctx context.Context, guildID snowflake.ID, departedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.departures[guildID] = departedAt
+	return nil
+}
+
+func (r *memoryDepartureRepository) Delete(ctx context.Context, guildID snowflake.ID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.departures, guildID)
+	return nil
+}
+
+func (r *memoryDepartureRepository) FindDepartedBefore(ctx context.Context, before time.Time) ([]snowflake.ID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var guildIDs []snowflake.ID
+	for guildID, departedAt := range r.departures {
+		if departedAt.Before(before) {
+			guildIDs = append(guildIDs, guildID)
+		}
+	}
+	return guildIDs, nil
+}
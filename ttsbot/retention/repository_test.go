@@ -0,0 +1,50 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database/databasetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepartureRepository(t *testing.T) {
+	for _, database := range databasetest.Databases {
+		t.Run(database.Name, func(t *testing.T) {
+			testDepartureRepository(t, NewDepartureRepository(database.Open(t, "../../migrations")))
+		})
+	}
+}
+
+func TestMemoryDepartureRepository(t *testing.T) {
+	testDepartureRepository(t, NewMemoryDepartureRepository())
+}
+
+func testDepartureRepository(t *testing.T, repo DepartureRepository) {
+	ctx := context.Background()
+	departedAt := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, repo.Save(ctx, 1, departedAt))
+	require.NoError(t, repo.Save(ctx, 2, departedAt.Add(time.Hour)))
+
+	guildIDs, err := repo.FindDepartedBefore(ctx, departedAt.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []snowflake.ID{1}, guildIDs)
+
+	// departing again moves the departure forward.
+	require.NoError(t, repo.Save(ctx, 1, departedAt.Add(2*time.Hour)))
+	guildIDs, err = repo.FindDepartedBefore(ctx, departedAt.Add(time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, guildIDs)
+
+	require.NoError(t, repo.Delete(ctx, 2))
+	guildIDs, err = repo.FindDepartedBefore(ctx, departedAt.Add(3*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []snowflake.ID{1}, guildIDs)
+}
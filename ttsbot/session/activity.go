@@ -0,0 +1,69 @@
+package session
+
+import (
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/voice"
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+var _ voice.OpusFrameReceiver = (*voiceActivity)(nil)
+
+// botQuietAfter is how long a bot has to stop transmitting before reading resumes,
+// so that the short gaps of a song do not let the session talk over the next bars.
+const botQuietAfter = time.Second
+
+// memberQuietAfter is how long a member has to stop speaking before reading is turned back up,
+// so that the pauses between their words do not let reading cut in.
+const memberQuietAfter = 700 * time.Millisecond
+
+// voiceActivity records when each user in the voice channel last transmitted audio, from the opus frames the bot receives.
+// Nothing is received while the bot is deafened.
+type voiceActivity struct {
+	clock clock.Clock
+
+	mu    sync.Mutex
+	heard map[snowflake.ID]time.Time
+}
+
+func newVoiceActivity(clock clock.Clock) *voiceActivity {
+	return &voiceActivity{
+		clock: clock,
+		heard: make(map[snowflake.ID]time.Time),
+	}
+}
+
+func (a *voiceActivity) ReceiveOpusFrame(userID snowflake.ID, packet *voice.Packet) error {
+	// frames arrive before the speaking event tells whose they are.
+	if userID == 0 {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.heard[userID] = a.clock.Now()
+	return nil
+}
+
+func (a *voiceActivity) CleanupUser(userID snowflake.ID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.heard, userID)
+}
+
+func (a *voiceActivity) Close() {}
+
+// transmitting reports whether a user matching the filter transmitted audio within the duration.
+func (a *voiceActivity) transmitting(within time.Duration, filter func(userID snowflake.ID) bool) bool {
+	now := a.clock.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for userID, heardAt := range a.heard {
+		if now.Sub(heardAt) < within && filter(userID) {
+			return true
+		}
+	}
+	return false
+}
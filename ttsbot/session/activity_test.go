@@ -0,0 +1,56 @@
+package session
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+func TestVoiceActivity(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	activity := newVoiceActivity(clk)
+	isBot := func(userID snowflake.ID) bool { return userID == 1 }
+
+	require.False(t, activity.transmitting(botQuietAfter, isBot))
+
+	require.NoError(t, activity.ReceiveOpusFrame(2, nil))
+	require.False(t, activity.transmitting(botQuietAfter, isBot), "humans are not yielded to")
+
+	require.NoError(t, activity.ReceiveOpusFrame(1, nil))
+	require.True(t, activity.transmitting(botQuietAfter, isBot))
+
+	clk.Advance(botQuietAfter / 2)
+	require.True(t, activity.transmitting(botQuietAfter, isBot), "short gaps keep the bot transmitting")
+
+	clk.Advance(botQuietAfter)
+	require.False(t, activity.transmitting(botQuietAfter, isBot))
+
+	require.NoError(t, activity.ReceiveOpusFrame(1, nil))
+	activity.CleanupUser(1)
+	require.False(t, activity.transmitting(botQuietAfter, isBot), "users who left are forgotten")
+
+	// frames of an unknown SSRC are not attributed to anyone.
+	require.NoError(t, activity.ReceiveOpusFrame(0, nil))
+	require.False(t, activity.transmitting(botQuietAfter, func(snowflake.ID) bool { return true }))
+}
+
+func TestSessionBargedIn(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	s := &Session{activity: newVoiceActivity(clk), logger: slog.Default()}
+
+	require.NoError(t, s.activity.ReceiveOpusFrame(2, nil))
+	require.False(t, s.bargedIn(settings.BargeInPause), "guilds without a barge-in keep reading")
+
+	s.bargeIn.Store(settings.BargeInPause)
+	require.True(t, s.bargedIn(settings.BargeInPause))
+	require.False(t, s.bargedIn(settings.BargeInDuck))
+
+	clk.Advance(memberQuietAfter)
+	require.False(t, s.bargedIn(settings.BargeInPause), "reading resumes once the member stops")
+}
@@ -0,0 +1,70 @@
+package session
+
+import (
+	"sync"
+	"time"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+type announcementKind int
+
+const (
+	announcementJoin announcementKind = iota
+	announcementLeave
+)
+
+// announcementCoalescer collects join/leave cues within a short window,
+// so that a burst of users joining or leaving is announced as a single sentence.
+type announcementCoalescer struct {
+	mu      sync.Mutex
+	window  time.Duration
+	pending map[announcementKind][]string
+	timers  map[announcementKind]clock.Timer
+	flush   func(kind announcementKind, names []string)
+	clock   clock.Clock
+}
+
+func newAnnouncementCoalescer(clk clock.Clock, window time.Duration, flush func(kind announcementKind, names []string)) *announcementCoalescer {
+	return &announcementCoalescer{
+		window:  window,
+		pending: make(map[announcementKind][]string),
+		timers:  make(map[announcementKind]clock.Timer),
+		flush:   flush,
+		clock:   clk,
+	}
+}
+
+// add queues the name for the announcement. The first cue of a kind starts the window,
+// and all cues of the same kind added before the window ends are flushed together.
+func (c *announcementCoalescer) add(kind announcementKind, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending[kind] = append(c.pending[kind], name)
+	if _, ok := c.timers[kind]; ok {
+		return
+	}
+	c.timers[kind] = c.clock.AfterFunc(c.window, func() {
+		c.mu.Lock()
+		names := c.pending[kind]
+		delete(c.pending, kind)
+		delete(c.timers, kind)
+		c.mu.Unlock()
+
+		if len(names) > 0 {
+			c.flush(kind, names)
+		}
+	})
+}
+
+// stop discards all pending cues.
+func (c *announcementCoalescer) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for kind, timer := range c.timers {
+		timer.Stop()
+		delete(c.timers, kind)
+		delete(c.pending, kind)
+	}
+}
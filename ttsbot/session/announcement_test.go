@@ -0,0 +1,53 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+func TestAnnouncementCoalescer(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	got := map[announcementKind][]string{}
+	c := newAnnouncementCoalescer(clk, 20*time.Millisecond, func(kind announcementKind, names []string) {
+		got[kind] = names
+	})
+
+	c.add(announcementJoin, "A")
+	c.add(announcementJoin, "B")
+	c.add(announcementLeave, "C")
+	clk.Advance(10 * time.Millisecond)
+	c.add(announcementJoin, "D")
+
+	clk.Advance(9 * time.Millisecond)
+	if len(got) != 0 {
+		t.Fatalf("flushed %v before the window passed", got)
+	}
+
+	clk.Advance(time.Millisecond)
+	want := map[announcementKind][]string{
+		announcementJoin:  {"A", "B", "D"},
+		announcementLeave: {"C"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("flushed %v, want %v", got, want)
+	}
+}
+
+func TestAnnouncementCoalescerStop(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	flushed := false
+	c := newAnnouncementCoalescer(clk, 10*time.Millisecond, func(kind announcementKind, names []string) {
+		flushed = true
+	})
+
+	c.add(announcementJoin, "A")
+	c.stop()
+	clk.Advance(time.Second)
+
+	if flushed {
+		t.Error("flush called after stop")
+	}
+}
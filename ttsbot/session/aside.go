@@ -0,0 +1,70 @@
+package session
+
+import (
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+// asideSSML reads asides softer and a little slower, like a whisper. Both Google TTS and Amazon Polly accept the prosody.
+var asideSSML = mustParseSSMLTemplate(`<speak><prosody volume="-6dB" rate="90%">{{.Text}}</prosody></speak>`)
+
+// SoftenAsides splits the asides, i.e. the text in parentheses or spoiler markers, out of the segments if the guild enabled it,
+// so that they are read softer than the text around them. presets are the presets of the segments, or nil to read them with current.
+// Asides are read with the message SSML of their preset replaced, and segments of engines without SSML are read as they are.
+func SoftenAsides(engineRegistry *tts.EngineRegistry, guildSettings settings.GuildSettings, current preset.Preset, segments []string, presets []preset.Preset) ([]string, []preset.Preset) {
+	if !guildSettings.SoftenAsides {
+		return segments, presets
+	}
+
+	var (
+		softened        []string
+		softenedPresets []preset.Preset
+		changed         bool
+	)
+	for i, segment := range segments {
+		segmentPreset := current
+		if i < len(presets) {
+			segmentPreset = presets[i]
+		}
+		engine, ok := engineRegistry.Get(segmentPreset.Engine)
+		runs := message.SplitAsides(segment)
+		if !ok || !tts.SupportsSSML(engine) || !hasAside(runs) {
+			softened = append(softened, segment)
+			softenedPresets = append(softenedPresets, segmentPreset)
+			continue
+		}
+
+		changed = true
+		for _, run := range runs {
+			runPreset := segmentPreset
+			if run.Aside {
+				runPreset.MessageSSML = asideSSML
+			}
+			softened = append(softened, run.Text)
+			softenedPresets = append(softenedPresets, runPreset)
+		}
+	}
+	if !changed {
+		return segments, presets
+	}
+	return softened, softenedPresets
+}
+
+func hasAside(runs []message.AsideRun) bool {
+	for _, run := range runs {
+		if run.Aside {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParseSSMLTemplate(source string) *preset.SSMLTemplate {
+	template, err := preset.ParseSSMLTemplate(source)
+	if err != nil {
+		panic(err)
+	}
+	return template
+}
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"context"
+	"testing"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+	"github.com/stretchr/testify/require"
+)
+
+type ssmlEngine struct {
+	ssml bool
+}
+
+func (e ssmlEngine) Name() string { return "ssml" }
+
+func (e ssmlEngine) GenerateSpeech(ctx context.Context, request tts.SpeechRequest) (*tts.SpeechResponse, error) {
+	return &tts.SpeechResponse{}, nil
+}
+
+func (e ssmlEngine) SupportsSSML() bool { return e.ssml }
+
+func TestSoftenAsides(t *testing.T) {
+	registry := tts.NewEngineRegistry()
+	registry.Register("google", ssmlEngine{ssml: true})
+	registry.Register("openai", ssmlEngine{})
+	current := preset.Preset{Identifier: "default", Engine: "google", Language: "en-US"}
+	enabled := settings.GuildSettings{GuildID: 1, SoftenAsides: true}
+
+	segments, presets := SoftenAsides(registry, enabled, current, []string{"I'm back (finally)", "hello"}, nil)
+	require.Equal(t, []string{"I'm back ", "finally", "hello"}, segments)
+	require.Len(t, presets, 3)
+	require.Nil(t, presets[0].MessageSSML)
+	require.Same(t, asideSSML, presets[1].MessageSSML)
+	require.Equal(t, current, presets[2])
+
+	// engines without SSML read the asides as they are.
+	openai := preset.Preset{Identifier: "nova", Engine: "openai"}
+	segments, presets = SoftenAsides(registry, enabled, openai, []string{"I'm back (finally)"}, nil)
+	require.Equal(t, []string{"I'm back (finally)"}, segments)
+	require.Nil(t, presets)
+
+	segments, presets = SoftenAsides(registry, settings.GuildSettings{GuildID: 1}, current, []string{"I'm back (finally)"}, nil)
+	require.Equal(t, []string{"I'm back (finally)"}, segments)
+	require.Nil(t, presets)
+}
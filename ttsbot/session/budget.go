@@ -0,0 +1,83 @@
+package session
+
+import "sync"
+
+// audioBudget bounds the bytes of synthesized speech a session queues for playback,
+// so that a guild pasting novels does not balloon the memory of the process.
+// Synthesis waits while the budget is used up, and the reading channel is told once per time it falls behind.
+type audioBudget struct {
+	limit int64
+
+	mu   sync.Mutex
+	used int64
+	// freed is closed and replaced whenever queued speech is released, to wake up wait.
+	freed chan struct{}
+	// exhausted is set once synthesis waits for the budget, until the queued speech falls under the limit again.
+	exhausted bool
+	// noticed is set once the reading channel was told that reading lags behind, while the budget is exhausted.
+	noticed bool
+}
+
+// newAudioBudget creates a budget of the given bytes. A limit of zero or less does not limit anything.
+func newAudioBudget(limit int64) *audioBudget {
+	return &audioBudget{limit: limit, freed: make(chan struct{})}
+}
+
+// wait blocks until the queued speech is under the limit, and also reports whether it had to wait for it.
+// It returns false if stop is closed first.
+func (b *audioBudget) wait(stop <-chan struct{}) (ok, waited bool) {
+	for {
+		b.mu.Lock()
+		if b.limit <= 0 || b.used < b.limit {
+			b.mu.Unlock()
+			return true, waited
+		}
+		b.exhausted = true
+		freed := b.freed
+		b.mu.Unlock()
+
+		waited = true
+		select {
+		case <-freed:
+		case <-stop:
+			return false, waited
+		}
+	}
+}
+
+// add counts the bytes of speech queued for playback.
+func (b *audioBudget) add(n int64) {
+	if n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used += n
+}
+
+// release uncounts the bytes of speech that was played or dropped.
+func (b *audioBudget) release(n int64) {
+	if n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used = max(b.used-n, 0)
+	if b.used < b.limit {
+		b.exhausted, b.noticed = false, false
+	}
+	close(b.freed)
+	b.freed = make(chan struct{})
+}
+
+// takeNotice reports whether the reading channel should be told that reading lags behind:
+// true once per time the budget is exhausted.
+func (b *audioBudget) takeNotice() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.exhausted || b.noticed {
+		return false
+	}
+	b.noticed = true
+	return true
+}
@@ -0,0 +1,57 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudioBudget(t *testing.T) {
+	stop := make(chan struct{})
+
+	t.Run("unlimited", func(t *testing.T) {
+		budget := newAudioBudget(0)
+		budget.add(1 << 30)
+		ok, waited := budget.wait(stop)
+		require.True(t, ok)
+		require.False(t, waited)
+		require.False(t, budget.takeNotice())
+	})
+
+	t.Run("waits for playback", func(t *testing.T) {
+		budget := newAudioBudget(100)
+		budget.add(60)
+		ok, waited := budget.wait(stop)
+		require.True(t, ok, "speech under the limit is synthesized right away")
+		require.False(t, waited)
+
+		budget.add(60)
+		require.False(t, budget.takeNotice(), "nothing lags behind until synthesis waits")
+
+		done := make(chan bool)
+		go func() {
+			_, waited := budget.wait(stop)
+			done <- waited
+		}()
+		require.Eventually(t, budget.takeNotice, time.Second, time.Millisecond, "the reading channel is told once synthesis waits")
+		require.False(t, budget.takeNotice(), "the reading channel is told only once")
+
+		budget.release(60)
+		select {
+		case waited := <-done:
+			require.True(t, waited)
+		case <-time.After(time.Second):
+			t.Fatal("releasing played speech did not resume synthesis")
+		}
+	})
+
+	t.Run("stops waiting when stopped", func(t *testing.T) {
+		budget := newAudioBudget(10)
+		budget.add(10)
+		stop := make(chan struct{})
+		close(stop)
+		ok, _ := budget.wait(stop)
+		require.False(t, ok)
+	})
+}
@@ -0,0 +1,23 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+)
+
+func TestCloseReasonDescription(t *testing.T) {
+	var tr i18n.TextResource
+	tr.Generic.TTS.CloseReason.Leave = "leave"
+	tr.Generic.TTS.CloseReason.Empty = "empty"
+	tr.Generic.TTS.CloseReason.Takeover = "takeover"
+	tr.Generic.TTS.CloseReason.GuildRemoved = "guild removed"
+
+	require.Equal(t, "leave", CloseReasonLeave.Description(tr))
+	require.Equal(t, "empty", CloseReasonEmpty.Description(tr))
+	require.Equal(t, "takeover", CloseReasonTakeover.Description(tr))
+	require.Equal(t, "guild removed", CloseReasonGuildRemoved.Description(tr))
+	require.Equal(t, "unknown", CloseReason("unknown").Description(tr))
+}
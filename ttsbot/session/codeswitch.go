@@ -0,0 +1,45 @@
+package session
+
+import (
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+// CodeSwitch splits the segments into the runs of each script if the guild enabled code-switching,
+// e.g. "今日は meeting です" into "今日は ", "meeting " and "です", so that each run is read with a preset speaking its language.
+// It returns the segments and the preset of each of them, or nil presets if every segment is read with the current preset.
+// Runs whose language no preset the guild may use speaks are read with the current preset.
+func CodeSwitch(resolver preset.PresetResolver, guildSettings settings.GuildSettings, current preset.Preset, segments []string) ([]string, []preset.Preset) {
+	if !guildSettings.CodeSwitch {
+		return segments, nil
+	}
+
+	var (
+		switched []string
+		presets  []preset.Preset
+		changed  bool
+	)
+	for _, segment := range segments {
+		start := len(switched)
+		for _, run := range message.SplitScripts(segment, current.Language) {
+			runPreset := current
+			if run.Language != "" && !current.Speaks(run.Language) {
+				if resolved, ok := resolver.ResolveLanguage(guildSettings.GuildID, run.Language, current); ok {
+					runPreset, changed = resolved, true
+				}
+			}
+			// consecutive runs of a segment read with the same preset are synthesized at once.
+			if n := len(presets); n > start && presets[n-1].Identifier == runPreset.Identifier {
+				switched[n-1] += run.Text
+				continue
+			}
+			switched = append(switched, run.Text)
+			presets = append(presets, runPreset)
+		}
+	}
+	if !changed {
+		return segments, nil
+	}
+	return switched, presets
+}
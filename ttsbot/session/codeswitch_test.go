@@ -0,0 +1,43 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeSwitch(t *testing.T) {
+	japanese := preset.Preset{Identifier: "default", Engine: "google", Language: "ja-JP"}
+	english := preset.Preset{Identifier: "anna", Engine: "google", Language: "en-US"}
+	resolver := voiceTagResolver{presets: []preset.Preset{english}}
+	enabled := settings.GuildSettings{GuildID: 1, CodeSwitch: true}
+
+	segments, presets := CodeSwitch(resolver, enabled, japanese, []string{"今日は meeting です", "はい"})
+	require.Equal(t, []string{"今日は ", "meeting ", "です", "はい"}, segments)
+	require.Equal(t, []preset.Preset{japanese, english, japanese, japanese}, presets)
+
+	// a language no preset speaks is read with the current preset.
+	segments, presets = CodeSwitch(resolver, enabled, japanese, []string{"안녕 です"})
+	require.Equal(t, []string{"안녕 です"}, segments)
+	require.Nil(t, presets)
+
+	segments, presets = CodeSwitch(resolver, settings.GuildSettings{GuildID: 1}, japanese, []string{"今日は meeting です"})
+	require.Equal(t, []string{"今日は meeting です"}, segments)
+	require.Nil(t, presets)
+}
+
+func TestSpeechTaskPresetOf(t *testing.T) {
+	japanese := preset.Preset{Identifier: "default", Language: "ja-JP"}
+	english := preset.Preset{Identifier: "anna", Language: "en-US"}
+	task := NewSpeechTask([]string{"今日は ", "meeting"}, japanese, WithSpeaker("Alice", 1), WithSegmentPresets([]preset.Preset{japanese, english}))
+
+	var prefixer speakerPrefixer
+	task = prefixer.apply(task)
+	require.Equal(t, []string{"Alice", "今日は ", "meeting"}, task.Segments)
+	require.Equal(t, japanese, task.presetOf(0))
+	require.Equal(t, japanese, task.presetOf(1))
+	require.Equal(t, english, task.presetOf(2))
+	require.Equal(t, japanese, task.presetOf(3))
+}
@@ -0,0 +1,233 @@
+package session
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/disgoorg/audio/pcm"
+)
+
+// discordSampleRate is the sample rate of the audio sent to discord.
+const discordSampleRate = 48000
+
+// frameSize returns the number of samples in a 20ms frame.
+func frameSize(sampleRate, channels int) int {
+	return sampleRate / 50 * channels
+}
+
+// newLinear16FrameProvider returns a provider decoding 16-bit little-endian PCM from the audio as it is played.
+// If the audio has a WAV header, the sample rate and channels of the header are returned instead of the given ones.
+func newLinear16FrameProvider(audio io.Reader, sampleRate, channels int) (*linear16FrameProvider, int, int, error) {
+	head := make([]byte, 12)
+	n, err := io.ReadFull(audio, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, 0, 0, err
+	}
+
+	var data io.Reader
+	if n == len(head) && string(head[0:4]) == "RIFF" && string(head[8:12]) == "WAVE" {
+		data, sampleRate, channels, err = readWAVHeader(audio)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+	} else {
+		// audio without a header is raw PCM, starting with what was read looking for one.
+		data = io.MultiReader(bytes.NewReader(head[:n]), audio)
+	}
+
+	size := frameSize(sampleRate, channels)
+	return &linear16FrameProvider{
+		audio: audio,
+		data:  data,
+		buf:   make([]byte, size*2),
+		frame: frameSamples.get(size),
+	}, sampleRate, channels, nil
+}
+
+// readWAVHeader reads the chunks of a WAV file following the RIFF header up to the data chunk,
+// and returns a reader of the data chunk and its format.
+func readWAVHeader(r io.Reader) (io.Reader, int, int, error) {
+	var (
+		sampleRate, channels int
+		header               = make([]byte, 8)
+	)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, 0, 0, errors.New("WAV data chunk not found")
+		}
+		id := string(header[0:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+		// chunks are padded to an even size.
+		skip := size + size%2
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, 0, 0, errors.New("invalid WAV fmt chunk")
+			}
+			chunk := make([]byte, 16)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return nil, 0, 0, errors.New("invalid WAV fmt chunk")
+			}
+			if format := binary.LittleEndian.Uint16(chunk[0:2]); format != 1 {
+				return nil, 0, 0, fmt.Errorf("unsupported WAV format: %d", format)
+			}
+			if bits := binary.LittleEndian.Uint16(chunk[14:16]); bits != 16 {
+				return nil, 0, 0, fmt.Errorf("unsupported WAV bits per sample: %d", bits)
+			}
+			channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+			skip -= int64(len(chunk))
+		case "data":
+			if sampleRate == 0 {
+				return nil, 0, 0, errors.New("WAV data chunk before fmt chunk")
+			}
+			// some encoders leave the size of a streamed data chunk unset, so it is read to the end if it is larger.
+			return io.LimitReader(r, size), sampleRate, channels, nil
+		}
+		if _, err := io.CopyN(io.Discard, r, skip); err != nil {
+			return nil, 0, 0, errors.New("WAV data chunk not found")
+		}
+	}
+}
+
+// linear16FrameProvider decodes 16-bit little-endian PCM in frames as they are played. The last frame is padded with silence.
+// It reuses its frame, which is returned to frameSamples once the provider is closed, together with the audio.
+type linear16FrameProvider struct {
+	audio io.Reader
+	data  io.Reader
+	buf   []byte
+	frame []int16
+	ended bool
+}
+
+func (p *linear16FrameProvider) ProvidePCMFrame() ([]int16, error) {
+	if p.ended {
+		return nil, io.EOF
+	}
+	n, err := io.ReadFull(p.data, p.buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		p.ended = true
+		// a trailing odd byte is not a whole sample, so it is dropped.
+		n &^= 1
+		if n == 0 {
+			return nil, io.EOF
+		}
+		clear(p.buf[n:])
+	} else if err != nil {
+		return nil, err
+	}
+
+	for i := range p.frame {
+		p.frame[i] = int16(binary.LittleEndian.Uint16(p.buf[i*2:]))
+	}
+	return p.frame, nil
+}
+
+func (p *linear16FrameProvider) Close() {
+	if closer, ok := p.audio.(io.Closer); ok {
+		closer.Close()
+	}
+	frameSamples.put(p.frame)
+	p.frame = nil
+}
+
+// resampleFrameProvider converts mono frames to another sample rate with linear interpolation.
+// It is meant for speech, where the loss of quality is not noticeable.
+type resampleFrameProvider struct {
+	provider  pcm.FrameProvider
+	step      float64 // input samples per output sample
+	frameSize int
+	frame     []int16 // reused for every frame provided
+	pending   []int16
+	pos       float64 // position of the next output sample in pending
+	ended     bool
+}
+
+func newResampleFrameProvider(provider pcm.FrameProvider, inputRate, outputRate int) *resampleFrameProvider {
+	return &resampleFrameProvider{
+		provider:  provider,
+		step:      float64(inputRate) / float64(outputRate),
+		frameSize: frameSize(outputRate, 1),
+		frame:     make([]int16, 0, frameSize(outputRate, 1)),
+	}
+}
+
+func (p *resampleFrameProvider) ProvidePCMFrame() ([]int16, error) {
+	frame := p.frame[:0]
+	for len(frame) < p.frameSize {
+		i := int(p.pos)
+		if i+1 >= len(p.pending) {
+			if p.ended {
+				break
+			}
+			input, err := p.provider.ProvidePCMFrame()
+			if errors.Is(err, io.EOF) {
+				p.ended = true
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			// drop the samples before the current position, keeping the one being interpolated from.
+			// the rest is moved to the front, so that pending reuses its buffer.
+			drop := min(i, len(p.pending))
+			kept := copy(p.pending, p.pending[drop:])
+			p.pending = append(p.pending[:kept], input...)
+			p.pos -= float64(drop)
+			continue
+		}
+		frac := p.pos - float64(i)
+		frame = append(frame, int16(float64(p.pending[i])*(1-frac)+float64(p.pending[i+1])*frac))
+		p.pos += p.step
+	}
+	if len(frame) == 0 {
+		return nil, io.EOF
+	}
+	// pad the last frame with silence.
+	n := len(frame)
+	frame = frame[:p.frameSize]
+	clear(frame[n:])
+	return frame, nil
+}
+
+func (p *resampleFrameProvider) Close() {
+	p.provider.Close()
+}
+
+// stereoFrameProvider converts mono frames to stereo by playing each sample on both channels.
+// Unlike the channel converter of disgoorg/audio, it closes the source when closed, which releases its decoder and buffers.
+type stereoFrameProvider struct {
+	source pcm.FrameProvider
+	frame  []int16
+}
+
+func newStereoFrameProvider(source pcm.FrameProvider) *stereoFrameProvider {
+	return &stereoFrameProvider{source: source}
+}
+
+func (p *stereoFrameProvider) ProvidePCMFrame() ([]int16, error) {
+	mono, err := p.source.ProvidePCMFrame()
+	if err != nil {
+		return nil, err
+	}
+	if cap(p.frame) < len(mono)*2 {
+		frameSamples.put(p.frame)
+		p.frame = frameSamples.get(len(mono) * 2)
+	}
+	frame := p.frame[:len(mono)*2]
+	for i, sample := range mono {
+		frame[i*2] = sample
+		frame[i*2+1] = sample
+	}
+	return frame, nil
+}
+
+func (p *stereoFrameProvider) Close() {
+	p.source.Close()
+	frameSamples.put(p.frame)
+	p.frame = nil
+}
@@ -0,0 +1,108 @@
+package session
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func encodePCM(samples []int16) []byte {
+	data := make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(sample))
+	}
+	return data
+}
+
+func buildWAV(samples []int16, sampleRate, channels int) []byte {
+	data := encodePCM(samples)
+
+	wav := []byte("RIFF\x00\x00\x00\x00WAVE")
+	wav = append(wav, "fmt "...)
+	wav = binary.LittleEndian.AppendUint32(wav, 16)
+	wav = binary.LittleEndian.AppendUint16(wav, 1)
+	wav = binary.LittleEndian.AppendUint16(wav, uint16(channels))
+	wav = binary.LittleEndian.AppendUint32(wav, uint32(sampleRate))
+	wav = binary.LittleEndian.AppendUint32(wav, uint32(sampleRate*channels*2))
+	wav = binary.LittleEndian.AppendUint16(wav, uint16(channels*2))
+	wav = binary.LittleEndian.AppendUint16(wav, 16)
+	wav = append(wav, "data"...)
+	wav = binary.LittleEndian.AppendUint32(wav, uint32(len(data)))
+	return append(wav, data...)
+}
+
+// closeRecorder is audio that records whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestLinear16FrameProvider(t *testing.T) {
+	audio := &closeRecorder{Reader: iotest.OneByteReader(bytes.NewReader(buildWAV([]int16{1, -2, 3}, 24000, 1)))}
+	provider, sampleRate, channels, err := newLinear16FrameProvider(audio, 48000, 2)
+	require.NoError(t, err)
+	require.Equal(t, 24000, sampleRate, "the header overrides the given format")
+	require.Equal(t, 1, channels)
+	frames := decodeFrames(t, provider)
+	require.Len(t, frames, 1)
+	require.Len(t, frames[0], 480, "the last frame is padded with silence")
+	require.Equal(t, []int16{1, -2, 3, 0}, frames[0][:4])
+	require.True(t, audio.closed, "the audio is closed with the provider")
+
+	provider, sampleRate, _, err = newLinear16FrameProvider(bytes.NewReader([]byte{1, 0, 0xff, 0xff}), 16000, 1)
+	require.NoError(t, err)
+	require.Equal(t, 16000, sampleRate)
+	frames = decodeFrames(t, provider)
+	require.Len(t, frames, 1)
+	require.Equal(t, []int16{1, -1, 0}, frames[0][:3], "content without a header is raw PCM")
+
+	_, _, _, err = newLinear16FrameProvider(bytes.NewReader([]byte("RIFF\x00\x00\x00\x00WAVE")), 48000, 1)
+	require.Error(t, err)
+}
+
+func TestResampleFrameProvider(t *testing.T) {
+	// 30ms of a ramp at 24kHz is provided in two 20ms frames, the last one padded with silence,
+	// so 40ms are resampled into two frames at 48kHz.
+	samples := make([]int16, 720)
+	for i := range samples {
+		samples[i] = int16(i * 2)
+	}
+	source, _, _, err := newLinear16FrameProvider(bytes.NewReader(encodePCM(samples)), 24000, 1)
+	require.NoError(t, err)
+	provider := newResampleFrameProvider(source, 24000, 48000)
+
+	var frames [][]int16
+	for {
+		frame, err := provider.ProvidePCMFrame()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Len(t, frame, 960)
+		// the provider reuses its buffer, so the frame is copied.
+		frames = append(frames, append([]int16(nil), frame...))
+	}
+	require.Len(t, frames, 2)
+	require.Equal(t, []int16{0, 1, 2, 3}, frames[0][:4], "samples in between are interpolated")
+}
+
+func TestStereoFrameProvider(t *testing.T) {
+	source := &countingFrameProvider{remaining: 2, buf: make([]int16, 2)}
+	provider := newStereoFrameProvider(source)
+
+	frame, err := provider.ProvidePCMFrame()
+	require.NoError(t, err)
+	require.Equal(t, []int16{1, 1, 0, 0}, frame, "each sample is played on both channels")
+
+	provider.Close()
+	require.True(t, source.closed.Load(), "closing releases the source")
+}
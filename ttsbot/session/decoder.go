@@ -0,0 +1,218 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strconv"
+
+	"github.com/disgoorg/audio/mp3"
+	"github.com/disgoorg/audio/pcm"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+// Decoder decodes synthesized speech into frames of stereo PCM at 48kHz, as sent to discord.
+// It is chosen per deployment, so that hosts where a decoding library misbehaves can use another one.
+// The decoder takes over the response: the audio is closed with the frame provider, or right away if decoding fails.
+type Decoder interface {
+	Decode(resp *tts.SpeechResponse) (pcm.FrameProvider, error)
+}
+
+var (
+	_ Decoder = NativeDecoder{}
+	_ Decoder = (*FFmpegDecoder)(nil)
+)
+
+// NativeDecoder decodes MP3 with mpg123, Ogg Opus with libopus and LINEAR16 in Go, resampling and converting channels in process.
+type NativeDecoder struct{}
+
+func (NativeDecoder) Decode(resp *tts.SpeechResponse) (pcm.FrameProvider, error) {
+	provider, err := decodeNative(resp)
+	if err != nil {
+		resp.Close()
+		return nil, err
+	}
+	return provider, nil
+}
+
+func decodeNative(resp *tts.SpeechResponse) (pcm.FrameProvider, error) {
+	sampleRate := resp.SampleRate
+	if sampleRate == 0 {
+		sampleRate = discordSampleRate
+	}
+
+	var (
+		provider pcm.FrameProvider
+		channels = 1
+	)
+	switch resp.Format {
+	case tts.AudioFormatMp3:
+		mp3Provider, w, err := mp3.NewCustomPCMFrameProvider(nil, sampleRate, 1)
+		if err != nil {
+			return nil, err
+		}
+		// mpg123 keeps what it is fed until it is decoded, so the audio is fed as it is read, without a copy in between.
+		if _, err := io.Copy(w, resp.Audio); err != nil {
+			mp3Provider.Close()
+			return nil, err
+		}
+		resp.Close()
+		provider = mp3Provider
+	case tts.AudioFormatLinear16:
+		channels = max(resp.Channels, 1)
+		linear16Provider, rate, wavChannels, err := newLinear16FrameProvider(resp.Audio, sampleRate, channels)
+		if err != nil {
+			return nil, err
+		}
+		sampleRate, channels = rate, wavChannels
+		provider = linear16Provider
+	case tts.AudioFormatOggOpus:
+		// opus is decoded at the sample rate and channels of discord, whatever it was encoded at.
+		opusProvider, err := newOpusFrameProvider(resp.Audio)
+		if err != nil {
+			return nil, err
+		}
+		sampleRate, channels = discordSampleRate, 2
+		provider = opusProvider
+	default:
+		return nil, fmt.Errorf("unsupported audio format: %v", resp.Format)
+	}
+
+	if sampleRate != discordSampleRate {
+		if channels != 1 {
+			provider.Close()
+			return nil, fmt.Errorf("resampling %d channels is not supported", channels)
+		}
+		provider = newResampleFrameProvider(provider, sampleRate, discordSampleRate)
+	}
+	if channels == 1 {
+		provider = newStereoFrameProvider(provider)
+	}
+	return provider, nil
+}
+
+// FFmpegDecoder decodes speech by piping it through an ffmpeg process, which also resamples and converts channels.
+// It decodes every format ffmpeg understands, at the cost of a process per track.
+type FFmpegDecoder struct {
+	path string
+}
+
+// NewFFmpegDecoder creates a decoder running the ffmpeg executable at the path, or found in PATH if the path is empty.
+func NewFFmpegDecoder(path string) (*FFmpegDecoder, error) {
+	if path == "" {
+		path = "ffmpeg"
+	}
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+	return &FFmpegDecoder{path: resolved}, nil
+}
+
+func (d *FFmpegDecoder) Decode(resp *tts.SpeechResponse) (pcm.FrameProvider, error) {
+	provider, err := d.decode(resp)
+	if err != nil {
+		resp.Close()
+		return nil, err
+	}
+	return provider, nil
+}
+
+func (d *FFmpegDecoder) decode(resp *tts.SpeechResponse) (pcm.FrameProvider, error) {
+	// the audio is piped to ffmpeg as it is read, starting with the head read to tell whether it has a WAV header.
+	head := make([]byte, 4)
+	n, err := io.ReadFull(resp.Audio, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	head = head[:n]
+
+	args := []string{"-hide_banner", "-loglevel", "error"}
+	switch resp.Format {
+	case tts.AudioFormatMp3:
+		args = append(args, "-f", "mp3")
+	case tts.AudioFormatOggOpus:
+		args = append(args, "-f", "ogg")
+	case tts.AudioFormatLinear16:
+		if string(head) != "RIFF" {
+			sampleRate := resp.SampleRate
+			if sampleRate == 0 {
+				sampleRate = discordSampleRate
+			}
+			args = append(args, "-f", "s16le", "-ar", strconv.Itoa(sampleRate), "-ac", strconv.Itoa(max(resp.Channels, 1)))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported audio format: %v", resp.Format)
+	}
+	args = append(args, "-i", "pipe:0", "-f", "s16le", "-ar", strconv.Itoa(discordSampleRate), "-ac", "2", "pipe:1")
+
+	cmd := exec.Command(d.path, args...)
+	cmd.Stdin = io.MultiReader(bytes.NewReader(head), resp.Audio)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+	return &ffmpegFrameProvider{
+		resp:   resp,
+		cmd:    cmd,
+		stdout: stdout,
+		stderr: &stderr,
+		buf:    make([]byte, frameSize(discordSampleRate, 2)*2),
+		frame:  frameSamples.get(frameSize(discordSampleRate, 2)),
+	}, nil
+}
+
+// ffmpegFrameProvider reads the PCM written by an ffmpeg process. The last frame is padded with silence.
+type ffmpegFrameProvider struct {
+	resp   *tts.SpeechResponse
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr *bytes.Buffer
+	buf    []byte
+	// frame is reused for every frame provided, and returned to frameSamples once closed.
+	frame []int16
+	ended bool
+}
+
+func (p *ffmpegFrameProvider) ProvidePCMFrame() ([]int16, error) {
+	if p.ended {
+		return nil, io.EOF
+	}
+	n, err := io.ReadFull(p.stdout, p.buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		p.ended = true
+		if waitErr := p.cmd.Wait(); waitErr != nil {
+			slog.Warn("ffmpeg exited with an error", slog.Any("err", waitErr), slog.String("stderr", p.stderr.String()))
+		}
+		if n == 0 {
+			return nil, io.EOF
+		}
+		clear(p.buf[n:])
+	} else if err != nil {
+		return nil, err
+	}
+
+	for i := range p.frame {
+		p.frame[i] = int16(uint16(p.buf[i*2]) | uint16(p.buf[i*2+1])<<8)
+	}
+	return p.frame, nil
+}
+
+func (p *ffmpegFrameProvider) Close() {
+	if !p.ended {
+		p.ended = true
+		p.cmd.Process.Kill()
+		p.cmd.Wait()
+	}
+	p.resp.Close()
+	frameSamples.put(p.frame)
+	p.frame = nil
+}
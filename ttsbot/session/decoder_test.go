@@ -0,0 +1,102 @@
+package session
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"testing"
+
+	"github.com/disgoorg/audio/pcm"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+func decodeFrames(t *testing.T, provider pcm.FrameProvider) [][]int16 {
+	t.Helper()
+	defer provider.Close()
+	var frames [][]int16
+	for {
+		frame, err := provider.ProvidePCMFrame()
+		if err == io.EOF {
+			return frames
+		}
+		require.NoError(t, err)
+		frames = append(frames, append([]int16(nil), frame...))
+	}
+}
+
+func TestNativeDecoder(t *testing.T) {
+	speech := &tts.SpeechResponse{
+		Format:   tts.AudioFormatLinear16,
+		Channels: 1,
+		Audio:    bytes.NewReader(buildWAV(make([]int16, 480), 24000, 1)),
+	}
+
+	provider, err := NativeDecoder{}.Decode(speech)
+	require.NoError(t, err)
+	frames := decodeFrames(t, provider)
+	require.Len(t, frames, 1, "20ms at 24kHz is resampled into a single 20ms frame")
+	require.Len(t, frames[0], 1920, "frames are stereo at 48kHz")
+
+	_, err = NativeDecoder{}.Decode(&tts.SpeechResponse{Format: tts.AudioFormatUnknown})
+	require.Error(t, err)
+}
+
+func TestFFmpegDecoder(t *testing.T) {
+	_, err := NewFFmpegDecoder("/nonexistent/ffmpeg")
+	require.Error(t, err)
+
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg is not installed")
+	}
+	decoder, err := NewFFmpegDecoder("")
+	require.NoError(t, err)
+
+	provider, err := decoder.Decode(&tts.SpeechResponse{
+		Format: tts.AudioFormatLinear16,
+		Audio:  bytes.NewReader(buildWAV(make([]int16, 480), 24000, 1)),
+	})
+	require.NoError(t, err)
+	frames := decodeFrames(t, provider)
+	require.NotEmpty(t, frames)
+	require.Len(t, frames[0], 1920)
+}
+
+func BenchmarkNativeDecoder(b *testing.B) {
+	// five seconds of a tone, as a typical utterance.
+	samples := make([]int16, 5*24000)
+	for i := range samples {
+		samples[i] = int16(i % 256 * 64)
+	}
+
+	for _, bc := range []struct {
+		name       string
+		sampleRate int
+	}{
+		{"linear16 24kHz", 24000},
+		{"linear16 48kHz", 48000},
+	} {
+		content := buildWAV(samples, bc.sampleRate, 1)
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(content)))
+			for b.Loop() {
+				provider, err := NativeDecoder{}.Decode(&tts.SpeechResponse{
+					Format:   tts.AudioFormatLinear16,
+					Channels: 1,
+					Audio:    bytes.NewReader(content),
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+				for {
+					if _, err := provider.ProvidePCMFrame(); err != nil {
+						break
+					}
+				}
+				provider.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+// VoiceDiagnostic is a snapshot of the voice connection health of a guild.
+type VoiceDiagnostic struct {
+	// Endpoint is the last voice server endpoint received from discord, e.g. "japan1234.discord.media:443".
+	Endpoint string
+	// ConsecutiveFailures is the number of voice connection failures since the last success.
+	ConsecutiveFailures int
+	LastError           string
+	LastFailureAt       time.Time
+	LastServerUpdateAt  time.Time
+}
+
+// Region returns the region hint derived from the endpoint, e.g. "japan" for "japan1234.discord.media:443".
+func (d VoiceDiagnostic) Region() string {
+	host, _, _ := strings.Cut(d.Endpoint, ".")
+	return strings.TrimRight(host, "0123456789")
+}
+
+// VoiceDiagnostics tracks voice connection failures and voice server changes per guild,
+// so that repeated failures (e.g. a voice region outage) are surfaced instead of being dropped silently.
+type VoiceDiagnostics struct {
+	mu sync.Mutex
+	// failureThreshold is the number of consecutive failures after which a guild is reported as unhealthy.
+	failureThreshold int
+	guilds           map[snowflake.ID]*VoiceDiagnostic
+	serverUpdates    map[snowflake.ID][]chan struct{}
+	clock            clock.Clock
+}
+
+func NewVoiceDiagnostics(failureThreshold int) *VoiceDiagnostics {
+	return &VoiceDiagnostics{
+		failureThreshold: failureThreshold,
+		guilds:           make(map[snowflake.ID]*VoiceDiagnostic),
+		serverUpdates:    make(map[snowflake.ID][]chan struct{}),
+		clock:            clock.Real,
+	}
+}
+
+// Get returns the diagnostic of the guild.
+func (d *VoiceDiagnostics) Get(guildID snowflake.ID) (VoiceDiagnostic, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	diagnostic, ok := d.guilds[guildID]
+	if !ok {
+		return VoiceDiagnostic{}, false
+	}
+	return *diagnostic, true
+}
+
+// RecordFailure records a voice connection failure and returns the number of consecutive failures.
+func (d *VoiceDiagnostics) RecordFailure(guildID snowflake.ID, err error) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	diagnostic := d.getOrCreate(guildID)
+	diagnostic.ConsecutiveFailures++
+	diagnostic.LastError = err.Error()
+	diagnostic.LastFailureAt = d.clock.Now()
+
+	attrs := []any{
+		slog.String("guildID", guildID.String()),
+		slog.String("endpoint", diagnostic.Endpoint),
+		slog.String("region", diagnostic.Region()),
+		slog.Int("consecutiveFailures", diagnostic.ConsecutiveFailures),
+		slog.Any("err", err),
+	}
+	if diagnostic.ConsecutiveFailures >= d.failureThreshold {
+		slog.Error("Repeated voice connection failures, the voice region may be having an outage", attrs...)
+	} else {
+		slog.Warn("Voice connection failed", attrs...)
+	}
+	return diagnostic.ConsecutiveFailures
+}
+
+// RecordSuccess resets the consecutive failure count of the guild.
+func (d *VoiceDiagnostics) RecordSuccess(guildID snowflake.ID) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if diagnostic, ok := d.guilds[guildID]; ok {
+		diagnostic.ConsecutiveFailures = 0
+	}
+}
+
+// WaitForServerChange blocks until discord assigns a new voice server to the guild or ctx is done.
+// It reports whether a new voice server was received.
+func (d *VoiceDiagnostics) WaitForServerChange(ctx context.Context, guildID snowflake.ID) bool {
+	ch := make(chan struct{})
+	d.mu.Lock()
+	d.serverUpdates[guildID] = append(d.serverUpdates[guildID], ch)
+	d.mu.Unlock()
+
+	select {
+	case <-ch:
+		return true
+	case <-ctx.Done():
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		for i, waiter := range d.serverUpdates[guildID] {
+			if waiter == ch {
+				d.serverUpdates[guildID] = append(d.serverUpdates[guildID][:i], d.serverUpdates[guildID][i+1:]...)
+				break
+			}
+		}
+		return false
+	}
+}
+
+func (d *VoiceDiagnostics) onVoiceServerUpdate(guildID snowflake.ID, endpoint *string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	diagnostic := d.getOrCreate(guildID)
+	previous := diagnostic.Endpoint
+	if endpoint != nil {
+		diagnostic.Endpoint = *endpoint
+	}
+	diagnostic.LastServerUpdateAt = d.clock.Now()
+
+	if previous != "" && previous != diagnostic.Endpoint {
+		slog.Info("Voice server changed", "guildID", guildID, "from", previous, "to", diagnostic.Endpoint)
+	}
+
+	for _, waiter := range d.serverUpdates[guildID] {
+		close(waiter)
+	}
+	delete(d.serverUpdates, guildID)
+}
+
+// getOrCreate returns the diagnostic of the guild. The caller must hold the lock.
+func (d *VoiceDiagnostics) getOrCreate(guildID snowflake.ID) *VoiceDiagnostic {
+	diagnostic, ok := d.guilds[guildID]
+	if !ok {
+		diagnostic = &VoiceDiagnostic{}
+		d.guilds[guildID] = diagnostic
+	}
+	return diagnostic
+}
+
+// CreateVoiceServerUpdateHandler creates an event listener for voice server update events.
+func (d *VoiceDiagnostics) CreateVoiceServerUpdateHandler() bot.EventListener {
+	return bot.NewListenerFunc(func(event *events.VoiceServerUpdate) {
+		d.onVoiceServerUpdate(event.GuildID, event.Endpoint)
+	})
+}
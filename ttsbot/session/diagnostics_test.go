@@ -0,0 +1,73 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+func TestVoiceDiagnosticRegion(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"japan1234.discord.media:443", "japan"},
+		{"us-east42.discord.media:443", "us-east"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := (VoiceDiagnostic{Endpoint: tt.endpoint}).Region(); got != tt.want {
+			t.Errorf("Region(%q) = %q, want %q", tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+func TestVoiceDiagnosticsFailures(t *testing.T) {
+	d := NewVoiceDiagnostics(2)
+	guildID := snowflake.ID(1)
+
+	if got := d.RecordFailure(guildID, errors.New("timeout")); got != 1 {
+		t.Errorf("RecordFailure() = %d, want 1", got)
+	}
+	if got := d.RecordFailure(guildID, errors.New("timeout")); got != 2 {
+		t.Errorf("RecordFailure() = %d, want 2", got)
+	}
+	d.RecordSuccess(guildID)
+
+	diagnostic, ok := d.Get(guildID)
+	if !ok {
+		t.Fatal("Get() returned no diagnostic")
+	}
+	if diagnostic.ConsecutiveFailures != 0 {
+		t.Errorf("ConsecutiveFailures = %d, want 0", diagnostic.ConsecutiveFailures)
+	}
+	if diagnostic.LastError != "timeout" {
+		t.Errorf("LastError = %q, want %q", diagnostic.LastError, "timeout")
+	}
+}
+
+func TestVoiceDiagnosticsWaitForServerChange(t *testing.T) {
+	d := NewVoiceDiagnostics(3)
+	guildID := snowflake.ID(1)
+	endpoint := "japan1.discord.media:443"
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		d.onVoiceServerUpdate(guildID, &endpoint)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if !d.WaitForServerChange(ctx, guildID) {
+		t.Fatal("WaitForServerChange() = false, want true")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if d.WaitForServerChange(ctx, guildID) {
+		t.Error("WaitForServerChange() = true after timeout, want false")
+	}
+}
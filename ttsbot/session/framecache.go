@@ -0,0 +1,87 @@
+package session
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+)
+
+// OpusFrameCache keeps the opus frames of recently played announcements in memory, shared by every session.
+// A cached announcement is played again without synthesis, decoding or encoding,
+// which saves CPU for join and leave cues that are read over and over in large deployments.
+type OpusFrameCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // of *frameCacheEntry, most recently used first
+}
+
+type frameCacheEntry struct {
+	key    string
+	frames [][]byte
+}
+
+// NewOpusFrameCache creates a cache of the given number of announcements, evicting the least recently used.
+func NewOpusFrameCache(capacity int) *OpusFrameCache {
+	return &OpusFrameCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the frames of the announcement. A nil cache has no entries.
+func (c *OpusFrameCache) Get(key string) ([][]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*frameCacheEntry).frames, true
+}
+
+// Put stores the frames of the announcement. The frames must not be modified afterwards.
+func (c *OpusFrameCache) Put(key string, frames [][]byte) {
+	if c == nil || c.capacity <= 0 || len(frames) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*frameCacheEntry).frames = frames
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&frameCacheEntry{key: key, frames: frames})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*frameCacheEntry).key)
+	}
+}
+
+// Len returns the number of cached announcements.
+func (c *OpusFrameCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// frameCacheKey identifies the audio of a segment read with the preset and SSML template.
+func frameCacheKey(p preset.Preset, ssml *preset.SSMLTemplate, segment string) string {
+	var ssmlSource string
+	if ssml != nil {
+		ssmlSource = ssml.String()
+	}
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%g\x00%g\x00%g\x00%s\x00%s", p.Engine, p.Language, p.VoiceName, p.SpeakingRate, p.Pitch, p.VolumeGainDb, ssmlSource, segment)
+}
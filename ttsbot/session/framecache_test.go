@@ -0,0 +1,59 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+)
+
+func TestOpusFrameCache(t *testing.T) {
+	cache := NewOpusFrameCache(2)
+	cache.Put("a", [][]byte{{1}})
+	cache.Put("b", [][]byte{{2}})
+
+	// reading "a" makes "b" the least recently used, so "b" is evicted.
+	_, ok := cache.Get("a")
+	require.True(t, ok)
+	cache.Put("c", [][]byte{{3}})
+
+	_, ok = cache.Get("b")
+	require.False(t, ok)
+	frames, ok := cache.Get("a")
+	require.True(t, ok)
+	require.Equal(t, [][]byte{{1}}, frames)
+	require.Equal(t, 2, cache.Len())
+
+	// nothing is stored for empty audio.
+	cache.Put("d", nil)
+	_, ok = cache.Get("d")
+	require.False(t, ok)
+
+	var disabled *OpusFrameCache
+	disabled.Put("a", [][]byte{{1}})
+	_, ok = disabled.Get("a")
+	require.False(t, ok)
+}
+
+func TestFrameCacheKey(t *testing.T) {
+	p := preset.Preset{Engine: "google", Language: "ja-JP", VoiceName: "ja-JP-Wavenet-A", SpeakingRate: 1.0}
+	require.Equal(t, frameCacheKey(p, nil, "hello"), frameCacheKey(p, nil, "hello"))
+	require.NotEqual(t, frameCacheKey(p, nil, "hello"), frameCacheKey(p, nil, "bye"))
+
+	faster := p
+	faster.SpeakingRate = 1.5
+	require.NotEqual(t, frameCacheKey(p, nil, "hello"), frameCacheKey(faster, nil, "hello"))
+
+	higher := p
+	higher.Pitch = 2
+	require.NotEqual(t, frameCacheKey(p, nil, "hello"), frameCacheKey(higher, nil, "hello"))
+
+	louder := p
+	louder.VolumeGainDb = 6
+	require.NotEqual(t, frameCacheKey(p, nil, "hello"), frameCacheKey(louder, nil, "hello"))
+
+	ssml, err := preset.ParseSSMLTemplate(`<speak><prosody pitch="+2st">{{.Text}}</prosody></speak>`)
+	require.NoError(t, err)
+	require.NotEqual(t, frameCacheKey(p, nil, "hello"), frameCacheKey(p, ssml, "hello"))
+}
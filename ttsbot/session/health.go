@@ -0,0 +1,123 @@
+package session
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/voice"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+const (
+	// framePeriod is how often discord expects an opus frame.
+	framePeriod = 20 * time.Millisecond
+	// senderGapThreshold is the interval between two frame requests above which the audio sender is considered stalled.
+	senderGapThreshold = 2 * framePeriod
+	// underrunLogInterval limits the underrun warnings of a session, so that a struggling host is not flooded with logs.
+	underrunLogInterval = 10 * time.Second
+)
+
+// PlaybackHealth counts the frames played by a session and the underruns among them.
+type PlaybackHealth struct {
+	// Frames is the number of opus frames provided while playing.
+	Frames uint64
+	// LateFrames took longer than a frame period to decode and encode, so the listener hears a stutter.
+	LateFrames uint64
+	// EmptyFrames were requested in the middle of a track but had no audio.
+	EmptyFrames uint64
+	// SenderGaps are frame requests that came late, e.g. because the host was starved of CPU.
+	SenderGaps uint64
+	// LastUnderrunAt is when the last late frame, empty frame or sender gap happened.
+	LastUnderrunAt time.Time
+}
+
+// Underruns returns the number of late frames, empty frames and sender gaps.
+func (h PlaybackHealth) Underruns() uint64 {
+	return h.LateFrames + h.EmptyFrames + h.SenderGaps
+}
+
+// playbackMonitor wraps the opus frame provider of a session and detects underruns.
+type playbackMonitor struct {
+	provider voice.OpusFrameProvider
+	// playing reports whether a track is being played and not paused, i.e. whether a frame is expected.
+	playing func() bool
+	logger  *slog.Logger
+	clock   clock.Clock
+
+	mu            sync.Mutex
+	health        PlaybackHealth
+	lastRequestAt time.Time
+	lastHadFrame  bool
+	lastLoggedAt  time.Time
+	loggedCount   uint64
+}
+
+func newPlaybackMonitor(provider voice.OpusFrameProvider, playing func() bool, logger *slog.Logger) *playbackMonitor {
+	return &playbackMonitor{
+		provider: provider,
+		playing:  playing,
+		logger:   logger,
+		clock:    clock.Real,
+	}
+}
+
+func (m *playbackMonitor) ProvideOpusFrame() ([]byte, error) {
+	start := m.clock.Now()
+	frame, err := m.provider.ProvideOpusFrame()
+	elapsed := m.clock.Now().Sub(start)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	gap := m.lastHadFrame && start.Sub(m.lastRequestAt) > senderGapThreshold
+	m.lastRequestAt = start
+	m.lastHadFrame = len(frame) > 0
+
+	switch {
+	case len(frame) > 0:
+		m.health.Frames++
+		if gap {
+			m.recordUnderrun(start, "sender gap", &m.health.SenderGaps)
+		}
+		if elapsed > framePeriod {
+			m.recordUnderrun(start, "late frame", &m.health.LateFrames)
+		}
+	case err == nil && m.playing():
+		m.recordUnderrun(start, "empty frame", &m.health.EmptyFrames)
+	}
+	return frame, err
+}
+
+// recordUnderrun counts the underrun and logs a warning, at most once per underrunLogInterval.
+// The caller must hold the lock.
+func (m *playbackMonitor) recordUnderrun(at time.Time, kind string, counter *uint64) {
+	*counter++
+	m.health.LastUnderrunAt = at
+
+	if at.Sub(m.lastLoggedAt) < underrunLogInterval {
+		return
+	}
+	underruns := m.health.Underruns()
+	m.logger.Warn("Playback underrun",
+		slog.String("kind", kind),
+		slog.Uint64("sinceLastWarning", underruns-m.loggedCount),
+		slog.Uint64("frames", m.health.Frames),
+		slog.Uint64("lateFrames", m.health.LateFrames),
+		slog.Uint64("emptyFrames", m.health.EmptyFrames),
+		slog.Uint64("senderGaps", m.health.SenderGaps),
+	)
+	m.lastLoggedAt = at
+	m.loggedCount = underruns
+}
+
+// Health returns the counters so far.
+func (m *playbackMonitor) Health() PlaybackHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.health
+}
+
+func (m *playbackMonitor) Close() {
+	m.provider.Close()
+}
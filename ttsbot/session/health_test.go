@@ -0,0 +1,69 @@
+package session
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+// scriptedProvider returns the frames in order, advancing the clock by the duration of each.
+type scriptedProvider struct {
+	clock  *clock.Fake
+	frames []scriptedFrame
+}
+
+type scriptedFrame struct {
+	frame []byte
+	took  time.Duration
+}
+
+func (p *scriptedProvider) ProvideOpusFrame() ([]byte, error) {
+	next := p.frames[0]
+	p.frames = p.frames[1:]
+	p.clock.Advance(next.took)
+	return next.frame, nil
+}
+
+func (p *scriptedProvider) Close() {}
+
+func TestPlaybackMonitor(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	frame := []byte{1}
+	provider := &scriptedProvider{clock: clk, frames: []scriptedFrame{
+		{frame: frame, took: time.Millisecond},
+		{frame: frame, took: 30 * time.Millisecond}, // late
+		{frame: nil, took: time.Millisecond},        // empty while playing
+		{frame: frame, took: time.Millisecond},
+		{frame: frame, took: time.Millisecond}, // requested after a gap
+	}}
+	playing := true
+	monitor := newPlaybackMonitor(provider, func() bool { return playing }, slog.Default())
+	monitor.clock = clk
+
+	for i := 0; i < 4; i++ {
+		_, err := monitor.ProvideOpusFrame()
+		require.NoError(t, err)
+		clk.Advance(framePeriod)
+	}
+	clk.Advance(100 * time.Millisecond)
+	_, err := monitor.ProvideOpusFrame()
+	require.NoError(t, err)
+
+	health := monitor.Health()
+	require.Equal(t, uint64(4), health.Frames)
+	require.Equal(t, uint64(1), health.LateFrames)
+	require.Equal(t, uint64(1), health.EmptyFrames)
+	require.Equal(t, uint64(1), health.SenderGaps)
+	require.Equal(t, uint64(3), health.Underruns())
+
+	// empty frames between tracks are not underruns.
+	playing = false
+	provider.frames = []scriptedFrame{{frame: nil}}
+	_, err = monitor.ProvideOpusFrame()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), monitor.Health().EmptyFrames)
+}
@@ -0,0 +1,210 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/internal/testing/fake"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/session"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+func TestSessionAnnouncesLaunch(t *testing.T) {
+	builder := fake.NewSessionBuilder(t)
+	builder.Build()
+
+	requests, ok := builder.Engine().WaitForRequests(1, 2*time.Second)
+	require.True(t, ok, "the launch phrase was not synthesized")
+	require.NotEmpty(t, requests[0].Text)
+	require.Equal(t, fake.DefaultPreset.VoiceName, requests[0].VoiceName)
+	require.Equal(t, fake.DefaultPreset.Language, requests[0].LanguageCode)
+}
+
+func TestSessionWithoutLaunchAnnouncement(t *testing.T) {
+	guildSettings := settings.DefaultGuildSettings(fake.GuildID)
+	guildSettings.AnnounceLaunch = false
+	builder := fake.NewSessionBuilder(t).WithGuildSettings(guildSettings)
+	builder.Build()
+
+	requests, ok := builder.Engine().WaitForRequests(1, 200*time.Millisecond)
+	require.False(t, ok, "nothing should be synthesized, got %v", requests)
+}
+
+func TestSessionSurvivesEngineFailure(t *testing.T) {
+	guildSettings := settings.DefaultGuildSettings(fake.GuildID)
+	guildSettings.AnnounceFarewell = true
+	engine := fake.NewEngine(fake.EngineName, fake.Step{Err: errors.New("quota exceeded")})
+	builder := fake.NewSessionBuilder(t).WithEngine(engine).WithGuildSettings(guildSettings)
+	s := builder.Build()
+
+	_, ok := engine.WaitForRequests(1, 2*time.Second)
+	require.True(t, ok, "the launch phrase was not requested")
+
+	// the farewell is never played, since nothing pulls the frames, so it times out.
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	s.Farewell(ctx)
+
+	requests, ok := engine.WaitForRequests(2, 2*time.Second)
+	require.True(t, ok, "the farewell was not synthesized after the launch phrase failed")
+	require.NotEqual(t, requests[0].Text, requests[1].Text)
+}
+
+func TestSessionWaitsForQueuedSpeech(t *testing.T) {
+	guildSettings := settings.DefaultGuildSettings(fake.GuildID)
+	guildSettings.AnnounceFarewell = true
+	// nothing pulls the frames, so the launch phrase stays queued and uses up the budget.
+	builder := fake.NewSessionBuilder(t).WithGuildSettings(guildSettings).WithMaxQueuedAudio(1)
+	s := builder.Build()
+
+	_, ok := builder.Engine().WaitForRequests(1, 2*time.Second)
+	require.True(t, ok, "the launch phrase was not synthesized")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	s.Farewell(ctx)
+
+	requests, ok := builder.Engine().WaitForRequests(2, 200*time.Millisecond)
+	require.False(t, ok, "nothing more should be synthesized until the queued speech is played, got %v", requests)
+}
+
+func TestSessionCloseWhileWaitingForQueuedSpeech(t *testing.T) {
+	guildSettings := settings.DefaultGuildSettings(fake.GuildID)
+	guildSettings.AnnounceFarewell = true
+	builder := fake.NewSessionBuilder(t).WithGuildSettings(guildSettings).WithMaxQueuedAudio(1)
+	s := builder.Build()
+
+	_, ok := builder.Engine().WaitForRequests(1, 2*time.Second)
+	require.True(t, ok, "the launch phrase was not synthesized")
+
+	// the farewell waits for the budget, and is let go once the session closes instead of waiting for its timeout.
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		s.Close(context.Background(), session.CloseReasonLeave)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	started := time.Now()
+	s.Farewell(ctx)
+	require.Less(t, time.Since(started), 2*time.Second, "the farewell was waited for after the session closed")
+}
+
+func TestSessionCreatedMuted(t *testing.T) {
+	guildSettings := settings.DefaultGuildSettings(fake.GuildID)
+	guildSettings.AnnounceLaunch = false
+	guildSettings.AnnounceFarewell = true
+	builder := fake.NewSessionBuilder(t).WithGuildSettings(guildSettings)
+	s := builder.Build()
+	s.SyncVoiceState(discord.VoiceState{GuildID: fake.GuildID, GuildMute: true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	s.Farewell(ctx)
+
+	requests, ok := builder.Engine().WaitForRequests(1, 200*time.Millisecond)
+	require.False(t, ok, "nothing should be read while the bot is muted, got %v", requests)
+}
+
+func TestSessionClose(t *testing.T) {
+	builder := fake.NewSessionBuilder(t)
+	s := builder.Build()
+	require.Equal(t, fake.VoiceChannelID, s.VoiceChannelID())
+
+	s.Close(context.Background(), session.CloseReasonLeave)
+	require.True(t, builder.Conn().Closed())
+	require.Zero(t, s.VoiceChannelID())
+}
+
+// deletedReasons records the reasons of deleted sessions.
+type deletedReasons struct {
+	session.NoOpSessionLifecycleObserver
+	mu      sync.Mutex
+	reasons []session.CloseReason
+}
+
+func (r *deletedReasons) OnDeleted(event session.SessionDeletedEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reasons = append(r.reasons, event.Reason)
+}
+
+func TestManagerDeleteConcurrentClose(t *testing.T) {
+	manager := session.NewSessionManager()
+	observer := &deletedReasons{}
+	manager.AddObserver(observer)
+	disconnected := make(chan struct{})
+	conn := fake.NewConn(fake.GuildID, fake.VoiceChannelID)
+	conn.CloseWait = disconnected
+	s := fake.NewSessionBuilder(t).WithConn(conn).Build()
+	require.NoError(t, manager.Add(fake.GuildID, fake.VoiceChannelID, fake.TextChannelID, s))
+
+	// e.g. /leave and the bot being disconnected at once: while the first close is still disconnecting,
+	// the other one deletes the session, and reports the reason it was closed with.
+	done := make(chan struct{}, 2)
+	for _, reason := range []session.CloseReason{session.CloseReasonLeave, session.CloseReasonDisconnected} {
+		go func() {
+			s.Close(context.Background(), reason)
+			manager.Delete(fake.GuildID, fake.VoiceChannelID)
+			done <- struct{}{}
+		}()
+	}
+	<-done
+	close(disconnected)
+	<-done
+
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	require.Len(t, observer.reasons, 2)
+	require.Contains(t, []session.CloseReason{session.CloseReasonLeave, session.CloseReasonDisconnected}, observer.reasons[0])
+}
+
+func TestManagerPersistsSessions(t *testing.T) {
+	manager := session.NewSessionManager()
+	persistence := fake.NewPersistence()
+	manager.AddObserver(persistence)
+
+	s := fake.NewSessionBuilder(t).Build()
+	require.NoError(t, manager.Add(fake.GuildID, fake.VoiceChannelID, fake.TextChannelID, s))
+	require.Equal(t, []fake.PersistedSession{{GuildID: fake.GuildID, VoiceChannelID: fake.VoiceChannelID, ReadingChannelID: fake.TextChannelID}}, persistence.Sessions())
+
+	require.True(t, manager.Update(fake.GuildID, fake.VoiceChannelID, 20, 21))
+	require.Equal(t, []fake.PersistedSession{{GuildID: fake.GuildID, VoiceChannelID: 20, ReadingChannelID: 21}}, persistence.Sessions())
+
+	manager.Delete(fake.GuildID, 20)
+	require.Empty(t, persistence.Sessions())
+}
+
+func TestPersistenceRestore(t *testing.T) {
+	manager := session.NewSessionManager()
+	persistence := fake.NewPersistence(
+		fake.PersistedSession{GuildID: fake.GuildID, VoiceChannelID: fake.VoiceChannelID, ReadingChannelID: fake.TextChannelID},
+		fake.PersistedSession{GuildID: 2, VoiceChannelID: 30, ReadingChannelID: 31},
+	)
+	manager.AddObserver(persistence)
+
+	err := persistence.Restore(context.Background(), manager, func(guildID, voiceChannelID, readingChannelID snowflake.ID) (*session.Session, error) {
+		if guildID == 2 {
+			return nil, errors.New("voice connection timed out")
+		}
+		return fake.NewSessionBuilder(t).
+			WithConn(fake.NewConn(guildID, voiceChannelID)).
+			WithTextChannelID(readingChannelID).
+			Build(), nil
+	})
+	require.NoError(t, err)
+
+	restored, ok := manager.GetByVoiceChannel(fake.VoiceChannelID)
+	require.True(t, ok)
+	require.Equal(t, fake.TextChannelID, restored.TextChannelID())
+
+	_, ok = manager.GetByVoiceChannel(30)
+	require.False(t, ok)
+	require.Equal(t, []fake.PersistedSession{{GuildID: fake.GuildID, VoiceChannelID: fake.VoiceChannelID, ReadingChannelID: fake.TextChannelID}}, persistence.Sessions(), "failed sessions are forgotten")
+}
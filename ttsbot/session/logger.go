@@ -0,0 +1,21 @@
+package session
+
+import (
+	"log/slog"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// newSessionLogger derives a logger that tags every record with the session metadata,
+// so the logs of a single session can be filtered by sessionID.
+func newSessionLogger(base *slog.Logger, sessionID string, guildID snowflake.ID, voiceChannelID *snowflake.ID, readingChannelID snowflake.ID) *slog.Logger {
+	attrs := []any{
+		slog.String("sessionID", sessionID),
+		slog.String("guildID", guildID.String()),
+		slog.String("readingChannelID", readingChannelID.String()),
+	}
+	if voiceChannelID != nil {
+		attrs = append(attrs, slog.String("voiceChannelID", voiceChannelID.String()))
+	}
+	return base.With(attrs...)
+}
@@ -0,0 +1,34 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+func TestNewSessionLogger(t *testing.T) {
+	var buf bytes.Buffer
+	base := slog.New(slog.NewJSONHandler(&buf, nil))
+	voiceChannelID := snowflake.ID(2)
+
+	newSessionLogger(base, "session-1", 1, &voiceChannelID, 3).Info("hello")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to decode log record: %v", err)
+	}
+	want := map[string]string{
+		"sessionID":        "session-1",
+		"guildID":          "1",
+		"voiceChannelID":   "2",
+		"readingChannelID": "3",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("record[%q] = %v, want %q", key, record[key], value)
+		}
+	}
+}
@@ -2,24 +2,47 @@ package session
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/disgoorg/disgo/bot"
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
 	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
 	"github.com/samber/lo"
 )
 
+// ErrGuildHasSession is returned when a session is started in a guild that already has one in another voice channel.
+// A bot has a single voice connection per guild, so it can only read in one voice channel of a guild at a time.
+var ErrGuildHasSession = errors.New("the guild already has a session")
+
 type SessionManager interface {
 	// GetByVoiceChannel retrieves a session by its voice channel ID.
 	GetByVoiceChannel(voiceChannelID snowflake.ID) (*Session, bool)
 	// GetByReadingChannel retrieves a session by its reading channel ID.
 	GetByReadingChannel(readingChannelID snowflake.ID) (*Session, bool)
+	// GetByGuild retrieves all sessions in the given guild.
+	GetByGuild(guildID snowflake.ID) []*Session
+	// Reserve marks a session as starting in the guild until Add, Restore or Fail is called for the guild.
+	// It returns ErrGuildHasSession if another session is already starting in the guild.
+	Reserve(guildID snowflake.ID) error
 	// Add adds a new session with the given voice and reading channel IDs.
-	Add(guildID, voiceChannelID, readingChannelID snowflake.ID, session *Session)
+	// It returns ErrGuildHasSession if the guild already has a session in another voice channel.
+	Add(guildID, voiceChannelID, readingChannelID snowflake.ID, session *Session) error
+	// Restore adds a session recovered from persistence with the given voice and reading channel IDs.
+	// It returns ErrGuildHasSession if the guild already has a session in another voice channel.
+	Restore(guildID, voiceChannelID, readingChannelID snowflake.ID, session *Session) error
+	// Update re-indexes the session in the given voice channel under new voice and reading channel IDs.
+	// It returns false if there is no session in the voice channel.
+	Update(guildID, voiceChannelID, newVoiceChannelID, newReadingChannelID snowflake.ID) bool
+	// Fail notifies observers that a session could not be started or restored.
+	Fail(guildID, voiceChannelID, readingChannelID snowflake.ID, err error)
 	// Delete removes a session by its voice channel ID.
 	Delete(guildID, voiceChannelID snowflake.ID)
 
@@ -28,25 +51,31 @@ type SessionManager interface {
 	// RemoveObserver removes an observer from listening for session lifecycle events.
 	RemoveObserver(observer SessionLifecycleObserver)
 
+	// HandleTextCommand registers the handler of the text command with the given name, e.g. "skip".
+	// Text commands are only handled in guilds that set a command prefix.
+	HandleTextCommand(name string, handler TextCommandHandler)
+
 	// CreateMessageHandler creates an event listener for message creation events.
 	CreateMessageHandler() bot.EventListener
 	// CreateVoiceStateHandler creates an event listener for voice state update events.
 	CreateVoiceStateHandler() bot.EventListener
-	// GetByVoiceChannel retrieves a session by its voice channel ID.
 }
 
 type SessionLifecycleObserver interface {
 	OnCreated(event SessionCreatedEvent)
+	OnUpdated(event SessionUpdatedEvent)
+	OnRestored(event SessionRestoredEvent)
+	OnFailed(event SessionFailedEvent)
 	OnDeleted(event SessionDeletedEvent)
 }
 
 type NoOpSessionLifecycleObserver struct{}
 
-func (NoOpSessionLifecycleObserver) OnCreated(event SessionCreatedEvent) {}
-func (NoOpSessionLifecycleObserver) OnDeleted(event SessionDeletedEvent) {}
-
-type sessionEvent interface {
-}
+func (NoOpSessionLifecycleObserver) OnCreated(event SessionCreatedEvent)   {}
+func (NoOpSessionLifecycleObserver) OnUpdated(event SessionUpdatedEvent)   {}
+func (NoOpSessionLifecycleObserver) OnRestored(event SessionRestoredEvent) {}
+func (NoOpSessionLifecycleObserver) OnFailed(event SessionFailedEvent)     {}
+func (NoOpSessionLifecycleObserver) OnDeleted(event SessionDeletedEvent)   {}
 
 type sessionState struct {
 	GuildID          snowflake.ID
@@ -58,53 +87,134 @@ type SessionCreatedEvent struct {
 	sessionState
 }
 
+// SessionUpdatedEvent is emitted when a session moves to another voice channel
+// or starts reading another text channel.
+type SessionUpdatedEvent struct {
+	sessionState
+	Previous sessionState
+}
+
+// SessionRestoredEvent is emitted when a session is recovered from persistence.
+type SessionRestoredEvent struct {
+	sessionState
+}
+
+// SessionFailedEvent is emitted when a session could not be started or restored.
+type SessionFailedEvent struct {
+	sessionState
+	Err error
+}
+
 type SessionDeletedEvent struct {
 	sessionState
+	// Reason is why the session was closed, or empty if it was not closed.
+	Reason CloseReason
 }
 
 var _ SessionManager = (*managerImpl)(nil)
 
+// managerShards is the number of shards the sessions are locked in, by guild.
+const managerShards = 64
+
+// managerImpl indexes the sessions in shards by guild, so that a slow observer of one guild
+// only stalls the guilds of its shard. Messages and voice states are routed through lock-free indexes.
 type managerImpl struct {
+	shards [managerShards]managerShard
+
+	// sessions and readingToVoice are read on every message and voice state update, so they are read without locking.
+	// They are written under the lock of the shard of the guild of the session.
+	sessions       sync.Map // voice channel ID -> *Session
+	readingToVoice sync.Map // reading channel ID -> voice channel ID
+
+	// observers is replaced as a whole when changed, so that events are dispatched without locking the manager.
+	observersMu sync.Mutex
+	observers   atomic.Pointer[[]SessionLifecycleObserver]
+
+	commandsMu   sync.RWMutex
+	textCommands map[string]TextCommandHandler
+}
+
+// managerShard holds the indexes of the guilds of a shard, and serializes the changes to their sessions
+// together with the dispatch of their events, so that observers see the events of a guild in order.
+type managerShard struct {
 	mu             sync.Mutex
-	sessions       map[snowflake.ID]*Session
-	readingToVoice map[snowflake.ID]snowflake.ID
 	voiceToReading map[snowflake.ID]snowflake.ID
-
-	observers []SessionLifecycleObserver
+	guildToVoices  map[snowflake.ID][]snowflake.ID
+	// reserved holds the guilds where a session is starting.
+	reserved map[snowflake.ID]struct{}
 }
 
 func NewSessionManager() SessionManager {
-	return &managerImpl{
-		mu:             sync.Mutex{},
-		sessions:       make(map[snowflake.ID]*Session),
-		readingToVoice: make(map[snowflake.ID]snowflake.ID),
-		voiceToReading: make(map[snowflake.ID]snowflake.ID),
-		observers:      make([]SessionLifecycleObserver, 0),
+	m := &managerImpl{
+		textCommands: make(map[string]TextCommandHandler),
 	}
+	for i := range m.shards {
+		m.shards[i] = managerShard{
+			voiceToReading: make(map[snowflake.ID]snowflake.ID),
+			guildToVoices:  make(map[snowflake.ID][]snowflake.ID),
+			reserved:       make(map[snowflake.ID]struct{}),
+		}
+	}
+	m.observers.Store(&[]SessionLifecycleObserver{})
+	return m
+}
+
+// shard returns the shard of the guild. Snowflakes of guilds created in the same millisecond are rare,
+// so the low bits of the timestamp spread the guilds evenly.
+func (r *managerImpl) shard(guildID snowflake.ID) *managerShard {
+	return &r.shards[uint64(guildID>>22)%managerShards]
 }
 
 func (r *managerImpl) GetByVoiceChannel(voiceChannelID snowflake.ID) (*Session, bool) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	session, ok := r.sessions[voiceChannelID]
-	return session, ok
+	session, ok := r.sessions.Load(voiceChannelID)
+	if !ok {
+		return nil, false
+	}
+	return session.(*Session), true
 }
 
 func (r *managerImpl) GetByReadingChannel(readingChannelID snowflake.ID) (*Session, bool) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if voiceChannelID, ok := r.readingToVoice[readingChannelID]; ok {
-		return r.sessions[voiceChannelID], true
+	voiceChannelID, ok := r.readingToVoice.Load(readingChannelID)
+	if !ok {
+		return nil, false
 	}
-	return nil, false
+	return r.GetByVoiceChannel(voiceChannelID.(snowflake.ID))
 }
 
-func (r *managerImpl) Add(guildID, voiceChannelID, readingChannelID snowflake.ID, session *Session) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.sessions[voiceChannelID] = session
-	r.readingToVoice[readingChannelID] = voiceChannelID
-	r.voiceToReading[voiceChannelID] = readingChannelID
+func (r *managerImpl) GetByGuild(guildID snowflake.ID) []*Session {
+	shard := r.shard(guildID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	voiceChannelIDs := shard.guildToVoices[guildID]
+	sessions := make([]*Session, 0, len(voiceChannelIDs))
+	for _, voiceChannelID := range voiceChannelIDs {
+		if session, ok := r.GetByVoiceChannel(voiceChannelID); ok {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions
+}
+
+func (r *managerImpl) Reserve(guildID snowflake.ID) error {
+	shard := r.shard(guildID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if _, ok := shard.reserved[guildID]; ok {
+		return ErrGuildHasSession
+	}
+	shard.reserved[guildID] = struct{}{}
+	return nil
+}
+
+func (r *managerImpl) Add(guildID, voiceChannelID, readingChannelID snowflake.ID, session *Session) error {
+	shard := r.shard(guildID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	delete(shard.reserved, guildID)
+	if shard.hasOtherSession(guildID, voiceChannelID) {
+		return ErrGuildHasSession
+	}
+	r.add(shard, guildID, voiceChannelID, readingChannelID, session)
 
 	event := SessionCreatedEvent{
 		sessionState: sessionState{
@@ -113,18 +223,96 @@ func (r *managerImpl) Add(guildID, voiceChannelID, readingChannelID snowflake.ID
 			ReadingChannelID: readingChannelID,
 		},
 	}
-	for _, observer := range r.observers {
+	for _, observer := range *r.observers.Load() {
 		observer.OnCreated(event)
 	}
+	return nil
+}
+
+func (r *managerImpl) Restore(guildID, voiceChannelID, readingChannelID snowflake.ID, session *Session) error {
+	shard := r.shard(guildID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	delete(shard.reserved, guildID)
+	if shard.hasOtherSession(guildID, voiceChannelID) {
+		return ErrGuildHasSession
+	}
+	r.add(shard, guildID, voiceChannelID, readingChannelID, session)
+
+	event := SessionRestoredEvent{
+		sessionState: sessionState{
+			GuildID:          guildID,
+			VoiceChannelID:   voiceChannelID,
+			ReadingChannelID: readingChannelID,
+		},
+	}
+	for _, observer := range *r.observers.Load() {
+		observer.OnRestored(event)
+	}
+	return nil
+}
+
+func (r *managerImpl) Update(guildID, voiceChannelID, newVoiceChannelID, newReadingChannelID snowflake.ID) bool {
+	shard := r.shard(guildID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	session, ok := r.GetByVoiceChannel(voiceChannelID)
+	if !ok {
+		return false
+	}
+	readingChannelID := shard.voiceToReading[voiceChannelID]
+	r.remove(shard, guildID, voiceChannelID)
+	r.add(shard, guildID, newVoiceChannelID, newReadingChannelID, session)
+
+	event := SessionUpdatedEvent{
+		sessionState: sessionState{
+			GuildID:          guildID,
+			VoiceChannelID:   newVoiceChannelID,
+			ReadingChannelID: newReadingChannelID,
+		},
+		Previous: sessionState{
+			GuildID:          guildID,
+			VoiceChannelID:   voiceChannelID,
+			ReadingChannelID: readingChannelID,
+		},
+	}
+	for _, observer := range *r.observers.Load() {
+		observer.OnUpdated(event)
+	}
+	return true
+}
+
+func (r *managerImpl) Fail(guildID, voiceChannelID, readingChannelID snowflake.ID, err error) {
+	shard := r.shard(guildID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	delete(shard.reserved, guildID)
+
+	event := SessionFailedEvent{
+		sessionState: sessionState{
+			GuildID:          guildID,
+			VoiceChannelID:   voiceChannelID,
+			ReadingChannelID: readingChannelID,
+		},
+		Err: err,
+	}
+	for _, observer := range *r.observers.Load() {
+		observer.OnFailed(event)
+	}
 }
 
 func (r *managerImpl) Delete(guildID, voiceChannelID snowflake.ID) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	delete(r.sessions, voiceChannelID)
-	readingChannelID := r.voiceToReading[voiceChannelID]
-	delete(r.readingToVoice, readingChannelID)
-	delete(r.voiceToReading, voiceChannelID)
+	shard := r.shard(guildID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	readingChannelID := shard.voiceToReading[voiceChannelID]
+	var reason CloseReason
+	if session, ok := r.GetByVoiceChannel(voiceChannelID); ok && session != nil {
+		if closeReason := session.closeReason.Load(); closeReason != nil {
+			reason = *closeReason
+		}
+	}
+	r.remove(shard, guildID, voiceChannelID)
 
 	event := SessionDeletedEvent{
 		sessionState: sessionState{
@@ -132,36 +320,88 @@ func (r *managerImpl) Delete(guildID, voiceChannelID snowflake.ID) {
 			VoiceChannelID:   voiceChannelID,
 			ReadingChannelID: readingChannelID,
 		},
+		Reason: reason,
 	}
-	for _, observer := range r.observers {
+	for _, observer := range *r.observers.Load() {
 		observer.OnDeleted(event)
 	}
 }
 
+// add indexes the session. The caller must hold the lock of the shard of the guild.
+func (r *managerImpl) add(shard *managerShard, guildID, voiceChannelID, readingChannelID snowflake.ID, session *Session) {
+	r.sessions.Store(voiceChannelID, session)
+	r.readingToVoice.Store(readingChannelID, voiceChannelID)
+	shard.voiceToReading[voiceChannelID] = readingChannelID
+	if !lo.Contains(shard.guildToVoices[guildID], voiceChannelID) {
+		shard.guildToVoices[guildID] = append(shard.guildToVoices[guildID], voiceChannelID)
+	}
+}
+
+// remove drops the session from every index. The caller must hold the lock of the shard of the guild.
+func (r *managerImpl) remove(shard *managerShard, guildID, voiceChannelID snowflake.ID) {
+	r.sessions.Delete(voiceChannelID)
+	readingChannelID := shard.voiceToReading[voiceChannelID]
+	r.readingToVoice.Delete(readingChannelID)
+	delete(shard.voiceToReading, voiceChannelID)
+	shard.guildToVoices[guildID] = lo.Without(shard.guildToVoices[guildID], voiceChannelID)
+	if len(shard.guildToVoices[guildID]) == 0 {
+		delete(shard.guildToVoices, guildID)
+	}
+}
+
+// hasOtherSession reports whether the guild has a session in a voice channel other than the given one.
+// The caller must hold the lock of the shard.
+func (s *managerShard) hasOtherSession(guildID, voiceChannelID snowflake.ID) bool {
+	return lo.ContainsBy(s.guildToVoices[guildID], func(id snowflake.ID) bool {
+		return id != voiceChannelID
+	})
+}
+
 func (m *managerImpl) AddObserver(observer SessionLifecycleObserver) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.observers = append(m.observers, observer)
+	m.observersMu.Lock()
+	defer m.observersMu.Unlock()
+	observers := append(slices.Clone(*m.observers.Load()), observer)
+	m.observers.Store(&observers)
 }
 
 func (m *managerImpl) RemoveObserver(observer SessionLifecycleObserver) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.observers = lo.Reject(m.observers, func(o SessionLifecycleObserver, _ int) bool {
+	m.observersMu.Lock()
+	defer m.observersMu.Unlock()
+	observers := lo.Reject(*m.observers.Load(), func(o SessionLifecycleObserver, _ int) bool {
 		return o == observer
 	})
+	m.observers.Store(&observers)
+}
+
+func (m *managerImpl) HandleTextCommand(name string, handler TextCommandHandler) {
+	m.commandsMu.Lock()
+	defer m.commandsMu.Unlock()
+	m.textCommands[name] = handler
+}
+
+func (m *managerImpl) textCommandHandler(name string) (TextCommandHandler, bool) {
+	m.commandsMu.RLock()
+	defer m.commandsMu.RUnlock()
+	handler, ok := m.textCommands[name]
+	return handler, ok
 }
 
 func (m *managerImpl) CreateMessageHandler() bot.EventListener {
 	return bot.NewListenerFunc(func(event *events.MessageCreate) {
 		if session, ok := m.GetByReadingChannel(event.ChannelID); ok {
-			session.onMessageCreate(event)
+			session.onMessageCreate(event, m.textCommandHandler)
 		}
 	})
 }
 
 func (m *managerImpl) CreateVoiceStateHandler() bot.EventListener {
 	return bot.NewListenerFunc(func(event *events.GuildVoiceStateUpdate) {
+		if event.VoiceState.UserID == event.Client().ID() && event.OldVoiceState.ChannelID != nil &&
+			(event.VoiceState.ChannelID == nil || *event.OldVoiceState.ChannelID != *event.VoiceState.ChannelID) {
+			m.handleOwnVoiceChannelChange(event)
+			return
+		}
+
 		if event.OldVoiceState.ChannelID == nil {
 			m.handleJoinVoiceChannel(event)
 			return
@@ -175,10 +415,62 @@ func (m *managerImpl) CreateVoiceStateHandler() bot.EventListener {
 		if *event.OldVoiceState.ChannelID != *event.VoiceState.ChannelID {
 			m.handleLeaveVoiceChannel(event)
 			m.handleJoinVoiceChannel(event)
+			return
 		}
+
+		m.handleVoiceStateChange(event)
 	})
 }
 
+// handleOwnVoiceChannelChange keeps the session in step with the bot being moved or disconnected, e.g. by a moderator.
+// A moved session is rebound to the new voice channel, and a disconnected one is closed.
+func (m *managerImpl) handleOwnVoiceChannelChange(event *events.GuildVoiceStateUpdate) {
+	guildID, oldVoiceChannelID := event.OldVoiceState.GuildID, *event.OldVoiceState.ChannelID
+	session, ok := m.GetByVoiceChannel(oldVoiceChannelID)
+	// the session is already closed if the bot left on its own, e.g. with /leave.
+	if !ok || session.closeReason.Load() != nil {
+		return
+	}
+
+	if event.VoiceState.ChannelID == nil {
+		session.logger.Warn("Bot was disconnected from the voice channel")
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			session.Close(ctx, CloseReasonDisconnected)
+			m.Delete(guildID, oldVoiceChannelID)
+			if _, err := event.Client().Rest().CreateMessage(session.textChannelID, discord.NewMessageCreateBuilder().
+				AddEmbeds(message.BuildLeaveEmbed(*session.textResource, CloseReasonDisconnected.Description(*session.textResource), session.Summary()).Build()).
+				Build(),
+			); err != nil {
+				session.logger.Warn("Failed to send leave message", slog.Any("err", err))
+			}
+		}()
+		return
+	}
+
+	newVoiceChannelID := *event.VoiceState.ChannelID
+	session.logger.Info("Bot was moved to another voice channel", slog.String("to", newVoiceChannelID.String()))
+	m.Update(guildID, oldVoiceChannelID, newVoiceChannelID, session.textChannelID)
+	// the bot may be muted differently in the new voice channel, e.g. when moved into a stage.
+	session.onOwnVoiceStateChange(event)
+	go session.announceCue(func(vr i18n.VoiceResource) string { return vr.Cue.Moved })
+	go func() {
+		if _, err := event.Client().Rest().CreateMessage(session.textChannelID, discord.NewMessageCreateBuilder().
+			AddEmbeds(message.BuildMovedEmbed(*session.textResource, discord.ChannelMention(newVoiceChannelID)).Build()).
+			Build(),
+		); err != nil {
+			session.logger.Warn("Failed to send moved message", slog.Any("err", err))
+		}
+	}()
+}
+
+func (m *managerImpl) handleVoiceStateChange(event *events.GuildVoiceStateUpdate) {
+	if session, ok := m.GetByVoiceChannel(*event.VoiceState.ChannelID); ok {
+		session.onVoiceStateChange(event)
+	}
+}
+
 func (m *managerImpl) handleJoinVoiceChannel(event *events.GuildVoiceStateUpdate) {
 	if session, ok := m.GetByVoiceChannel(*event.VoiceState.ChannelID); ok {
 		session.onJoinVoiceChannel(event)
@@ -189,17 +481,21 @@ func (m *managerImpl) handleLeaveVoiceChannel(event *events.GuildVoiceStateUpdat
 	if session, ok := m.GetByVoiceChannel(*event.OldVoiceState.ChannelID); ok {
 		result := session.onLeaveVoiceChannel(event)
 		if result == LeaveResultClose {
-			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-			defer cancel()
-			session.Close(ctx)
-			m.Delete(event.OldVoiceState.GuildID, *event.OldVoiceState.ChannelID)
-			_, err := event.Client().Rest().CreateMessage(session.textChannelID, discord.NewMessageCreateBuilder().
-				AddEmbeds(message.BuildLeaveEmbed(*session.textResource).Build()).
-				Build(),
-			)
-			if err != nil {
-				event.Client().Logger().Error("Failed to send leave message", "error", err, "textChannelID", session.textChannelID)
-			}
+			// the farewell takes a while, so do not block the event handler.
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), farewellTimeout+time.Second)
+				defer cancel()
+				session.Farewell(ctx)
+				session.Close(ctx, CloseReasonEmpty)
+				m.Delete(event.OldVoiceState.GuildID, *event.OldVoiceState.ChannelID)
+				_, err := event.Client().Rest().CreateMessage(session.textChannelID, discord.NewMessageCreateBuilder().
+					AddEmbeds(message.BuildLeaveEmbed(*session.textResource, CloseReasonEmpty.Description(*session.textResource), session.Summary()).Build()).
+					Build(),
+				)
+				if err != nil {
+					event.Client().Logger().Error("Failed to send leave message", "error", err, "textChannelID", session.textChannelID)
+				}
+			}()
 		}
 	}
 }
@@ -0,0 +1,190 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerGuildIndex(t *testing.T) {
+	manager := NewSessionManager()
+
+	guildID := snowflake.ID(1)
+	sessionA := &Session{guildID: guildID, textChannelID: 11}
+	sessionB := &Session{guildID: 2, textChannelID: 21}
+
+	require.NoError(t, manager.Add(guildID, 10, 11, sessionA))
+	require.NoError(t, manager.Add(2, 20, 21, sessionB))
+
+	require.Equal(t, []*Session{sessionA}, manager.GetByGuild(guildID))
+	require.Equal(t, []*Session{sessionB}, manager.GetByGuild(2))
+	require.Empty(t, manager.GetByGuild(3))
+
+	manager.Delete(guildID, 10)
+	require.Empty(t, manager.GetByGuild(guildID))
+	require.Equal(t, []*Session{sessionB}, manager.GetByGuild(2))
+}
+
+func TestManagerOneSessionPerGuild(t *testing.T) {
+	manager := NewSessionManager()
+
+	guildID := snowflake.ID(1)
+	sessionA := &Session{guildID: guildID, textChannelID: 11}
+	sessionB := &Session{guildID: guildID, textChannelID: 21}
+
+	require.NoError(t, manager.Reserve(guildID))
+	require.ErrorIs(t, manager.Reserve(guildID), ErrGuildHasSession, "another session is starting")
+	require.NoError(t, manager.Reserve(2), "other guilds are not affected")
+
+	require.NoError(t, manager.Add(guildID, 10, 11, sessionA))
+	require.NoError(t, manager.Reserve(guildID), "adding the session releases the reservation")
+
+	require.ErrorIs(t, manager.Add(guildID, 20, 21, sessionB), ErrGuildHasSession)
+	require.ErrorIs(t, manager.Restore(guildID, 20, 21, sessionB), ErrGuildHasSession)
+	require.Equal(t, []*Session{sessionA}, manager.GetByGuild(guildID))
+	_, ok := manager.GetByVoiceChannel(20)
+	require.False(t, ok)
+
+	require.NoError(t, manager.Reserve(guildID))
+	manager.Fail(guildID, 20, 21, errors.New("failed"))
+	require.NoError(t, manager.Reserve(guildID), "failing releases the reservation")
+
+	manager.Delete(guildID, 10)
+	require.NoError(t, manager.Add(guildID, 20, 21, sessionB))
+	require.Equal(t, []*Session{sessionB}, manager.GetByGuild(guildID))
+}
+
+type recordingObserver struct {
+	NoOpSessionLifecycleObserver
+	updated  []SessionUpdatedEvent
+	restored []SessionRestoredEvent
+	failed   []SessionFailedEvent
+}
+
+func (o *recordingObserver) OnUpdated(event SessionUpdatedEvent) {
+	o.updated = append(o.updated, event)
+}
+
+func (o *recordingObserver) OnRestored(event SessionRestoredEvent) {
+	o.restored = append(o.restored, event)
+}
+
+func (o *recordingObserver) OnFailed(event SessionFailedEvent) {
+	o.failed = append(o.failed, event)
+}
+
+func TestManagerLifecycleEvents(t *testing.T) {
+	manager := NewSessionManager()
+	observer := &recordingObserver{}
+	manager.AddObserver(observer)
+
+	guildID := snowflake.ID(1)
+	session := &Session{guildID: guildID, textChannelID: 11}
+
+	require.NoError(t, manager.Restore(guildID, 10, 11, session))
+	require.Len(t, observer.restored, 1)
+	require.Equal(t, snowflake.ID(10), observer.restored[0].VoiceChannelID)
+
+	require.True(t, manager.Update(guildID, 10, 20, 21))
+	require.Len(t, observer.updated, 1)
+	require.Equal(t, snowflake.ID(10), observer.updated[0].Previous.VoiceChannelID)
+	require.Equal(t, snowflake.ID(20), observer.updated[0].VoiceChannelID)
+	require.Equal(t, snowflake.ID(21), observer.updated[0].ReadingChannelID)
+
+	_, ok := manager.GetByVoiceChannel(10)
+	require.False(t, ok)
+	got, ok := manager.GetByReadingChannel(21)
+	require.True(t, ok)
+	require.Same(t, session, got)
+
+	require.False(t, manager.Update(guildID, 10, 30, 31))
+	require.Len(t, observer.updated, 1)
+
+	manager.Fail(guildID, 40, 41, errors.New("failed"))
+	require.Len(t, observer.failed, 1)
+	require.EqualError(t, observer.failed[0].Err, "failed")
+}
+
+// blockingObserver blocks the events of a guild until released.
+type blockingObserver struct {
+	NoOpSessionLifecycleObserver
+	guildID snowflake.ID
+	release chan struct{}
+}
+
+func (o *blockingObserver) OnCreated(event SessionCreatedEvent) {
+	if event.GuildID == o.guildID {
+		<-o.release
+	}
+}
+
+func TestManagerSlowObserver(t *testing.T) {
+	manager := NewSessionManager()
+	observer := &blockingObserver{guildID: 1, release: make(chan struct{})}
+	manager.AddObserver(observer)
+
+	sessionA := &Session{guildID: 2, textChannelID: 21}
+	require.NoError(t, manager.Add(2, 20, 21, sessionA))
+
+	added := make(chan struct{})
+	go func() {
+		manager.Add(1, 10, 11, &Session{guildID: 1, textChannelID: 11})
+		close(added)
+	}()
+
+	// routing does not lock, and guilds created in other milliseconds than guild 1 are in other shards.
+	var (
+		routed *Session
+		err    error
+	)
+	done := make(chan struct{})
+	go func() {
+		routed, _ = manager.GetByReadingChannel(21)
+		err = manager.Add(3<<22, 30, 31, &Session{guildID: 3 << 22, textChannelID: 31})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a slow observer of another guild stalled the manager")
+	}
+	require.Same(t, sessionA, routed)
+	require.NoError(t, err)
+
+	close(observer.release)
+	<-added
+}
+
+func BenchmarkManagerRouting(b *testing.B) {
+	const sessions = 10000
+	manager := NewSessionManager()
+	manager.AddObserver(NoOpSessionLifecycleObserver{})
+	for i := range sessions {
+		guildID := snowflake.ID(i+1) << 22
+		voiceChannelID, readingChannelID := snowflake.ID(2*i+1), snowflake.ID(2*i+2)
+		if err := manager.Add(guildID, voiceChannelID, readingChannelID, &Session{guildID: guildID, textChannelID: readingChannelID}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			readingChannelID := snowflake.ID(2*(i%sessions) + 2)
+			if _, ok := manager.GetByReadingChannel(readingChannelID); !ok {
+				b.Fatalf("no session reads %s", readingChannelID)
+			}
+			// sessions keep coming and going while messages are routed.
+			if i%100 == 0 {
+				guildID := snowflake.ID(sessions+1+i%sessions) << 22
+				manager.Add(guildID, snowflake.ID(1<<40+i), snowflake.ID(1<<41+i), &Session{guildID: guildID})
+				manager.Delete(guildID, snowflake.ID(1<<40+i))
+			}
+			i++
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+// MemberFetcher fetches a guild member, usually through the REST API.
+type MemberFetcher func(guildID, userID snowflake.ID) (*discord.Member, error)
+
+type memberKey struct {
+	guildID snowflake.ID
+	userID  snowflake.ID
+}
+
+type cachedMember struct {
+	member    discord.Member
+	fetchedAt time.Time
+}
+
+// MemberResolver resolves guild members for speaker names and mentions.
+// Members are cached in process, so that busy channels do not send a REST request per message,
+// and concurrent lookups of the same member share a single request.
+type MemberResolver struct {
+	mu sync.Mutex
+	// ttl is how long a cached member is used before it is fetched again.
+	ttl time.Duration
+	// refreshAfter is the age after which a cached member is refreshed in the background while still being served.
+	// Zero disables the background refresh.
+	refreshAfter time.Duration
+	members      map[memberKey]cachedMember
+	inflight     map[memberKey]*memberCall
+	clock        clock.Clock
+}
+
+func NewMemberResolver(ttl, refreshAfter time.Duration) *MemberResolver {
+	return &MemberResolver{
+		ttl:          ttl,
+		refreshAfter: refreshAfter,
+		members:      make(map[memberKey]cachedMember),
+		inflight:     make(map[memberKey]*memberCall),
+		clock:        clock.Real,
+	}
+}
+
+// Put caches the member, e.g. the partial member attached to a message create event.
+func (r *MemberResolver) Put(member discord.Member) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.storeLocked(memberKey{member.GuildID, member.User.ID}, member)
+}
+
+// sweepEvery is the number of cached members after which expired members are removed.
+const sweepEvery = 1024
+
+// storeLocked caches the member, removing expired members every now and then. The caller must hold the lock.
+func (r *MemberResolver) storeLocked(key memberKey, member discord.Member) {
+	now := r.clock.Now()
+	r.members[key] = cachedMember{member: member, fetchedAt: now}
+	if len(r.members)%sweepEvery != 0 {
+		return
+	}
+	for key, cached := range r.members {
+		if now.Sub(cached.fetchedAt) > r.ttl {
+			delete(r.members, key)
+		}
+	}
+}
+
+// Cached returns the cached member without fetching it.
+func (r *MemberResolver) Cached(guildID, userID snowflake.ID) (discord.Member, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cached, ok := r.members[memberKey{guildID, userID}]
+	if !ok || r.clock.Now().Sub(cached.fetchedAt) > r.ttl {
+		return discord.Member{}, false
+	}
+	return cached.member, true
+}
+
+// Resolve returns the member from the cache, or fetches it if it is missing or expired.
+func (r *MemberResolver) Resolve(ctx context.Context, fetch MemberFetcher, guildID, userID snowflake.ID) (discord.Member, error) {
+	key := memberKey{guildID, userID}
+
+	r.mu.Lock()
+	if cached, ok := r.members[key]; ok {
+		age := r.clock.Now().Sub(cached.fetchedAt)
+		if age <= r.ttl {
+			if r.refreshAfter > 0 && age > r.refreshAfter {
+				r.fetchLocked(key, fetch)
+			}
+			r.mu.Unlock()
+			return cached.member, nil
+		}
+	}
+	call := r.fetchLocked(key, fetch)
+	r.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.member, call.err
+	case <-ctx.Done():
+		return discord.Member{}, ctx.Err()
+	}
+}
+
+// memberCall is a fetch in flight, shared by every lookup of the same member.
+type memberCall struct {
+	done   chan struct{}
+	member discord.Member
+	err    error
+}
+
+// fetchLocked starts fetching the member unless it is already being fetched. The caller must hold the lock.
+func (r *MemberResolver) fetchLocked(key memberKey, fetch MemberFetcher) *memberCall {
+	if call, ok := r.inflight[key]; ok {
+		return call
+	}
+
+	call := &memberCall{done: make(chan struct{})}
+	r.inflight[key] = call
+	go func() {
+		member, err := fetch(key.guildID, key.userID)
+
+		r.mu.Lock()
+		delete(r.inflight, key)
+		if err == nil {
+			call.member = *member
+			r.storeLocked(key, *member)
+		} else {
+			call.err = err
+			slog.Warn("Failed to fetch member", slog.Any("err", err), slog.String("guildID", key.guildID.String()), slog.String("userID", key.userID.String()))
+		}
+		r.mu.Unlock()
+		close(call.done)
+	}()
+	return call
+}
@@ -0,0 +1,108 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+func TestMemberResolver(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(0, 0))
+	resolver := NewMemberResolver(time.Hour, 0)
+	resolver.clock = clk
+
+	var calls atomic.Int32
+	fetch := func(guildID, userID snowflake.ID) (*discord.Member, error) {
+		calls.Add(1)
+		nick := "nick"
+		return &discord.Member{GuildID: guildID, User: discord.User{ID: userID}, Nick: &nick}, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := resolver.Resolve(ctx, fetch, 1, 2); err != nil {
+				t.Errorf("Resolve() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("fetch called %d times, want 1", calls.Load())
+	}
+
+	if _, ok := resolver.Cached(1, 2); !ok {
+		t.Error("Cached() = false, want true")
+	}
+
+	clk.Advance(2 * time.Hour)
+	if _, ok := resolver.Cached(1, 2); ok {
+		t.Error("Cached() after ttl = true, want false")
+	}
+	if _, err := resolver.Resolve(ctx, fetch, 1, 2); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("fetch called %d times after ttl, want 2", calls.Load())
+	}
+}
+
+func TestMemberResolverPut(t *testing.T) {
+	resolver := NewMemberResolver(time.Hour, 0)
+	resolver.Put(discord.Member{GuildID: 1, User: discord.User{ID: 2}})
+
+	member, err := resolver.Resolve(context.Background(), func(guildID, userID snowflake.ID) (*discord.Member, error) {
+		return nil, errors.New("should not be called")
+	}, 1, 2)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if member.User.ID != 2 {
+		t.Errorf("Resolve() = %v, want user 2", member.User.ID)
+	}
+}
+
+func TestMemberResolverBackgroundRefresh(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	resolver := NewMemberResolver(time.Hour, time.Minute)
+	resolver.clock = clk
+	resolver.Put(discord.Member{GuildID: 1, User: discord.User{ID: 2}})
+
+	refreshed := make(chan struct{})
+	clk.Advance(2 * time.Minute)
+	member, err := resolver.Resolve(context.Background(), func(guildID, userID snowflake.ID) (*discord.Member, error) {
+		defer close(refreshed)
+		nick := "new"
+		return &discord.Member{GuildID: guildID, User: discord.User{ID: userID}, Nick: &nick}, nil
+	}, 1, 2)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if member.Nick != nil {
+		t.Errorf("Resolve() returned the refreshed member, want the cached one")
+	}
+
+	<-refreshed
+	// the refresh stores the member shortly after the fetch returns.
+	deadline := time.Now().Add(time.Second)
+	for {
+		if member, _ := resolver.Cached(1, 2); member.Nick != nil && *member.Nick == "new" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Cached() did not return the refreshed member")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
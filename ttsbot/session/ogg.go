@@ -0,0 +1,309 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/disgoorg/audio/opus"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+// opusFrameDuration is the audio in each opus frame sent to discord, which sends a frame every 20ms.
+const opusFrameDuration = 20 * time.Millisecond
+
+// opusFrameSamples is the samples per channel at 48kHz in each opus frame sent to discord.
+const opusFrameSamples = int(opusFrameDuration * discordSampleRate / time.Second)
+
+// oggPageHeaderSize is the size of the header of an Ogg page, up to its segment table.
+const oggPageHeaderSize = 27
+
+// oggOpusReader reads the opus packets of an Ogg Opus stream as it is played.
+// Checksums are not verified, as the stream comes from an engine over a reliable connection.
+type oggOpusReader struct {
+	r      io.Reader
+	header [oggPageHeaderSize]byte
+	// lacing are the sizes of the segments of the current page not read yet.
+	lacing    []byte
+	lacingBuf [255]byte
+	// packet is reused for every packet read.
+	packet []byte
+	// preSkip is the number of samples per channel at 48kHz the decoder drops from the start, as told by the OpusHead header.
+	preSkip int
+}
+
+// newOggOpusReader reads the OpusHead header of the stream. Only mono and stereo streams are supported, as discord plays no more.
+// The stream is buffered, as pages are read in small pieces.
+func newOggOpusReader(r io.Reader) (*oggOpusReader, error) {
+	reader := &oggOpusReader{r: bufio.NewReader(r)}
+	head, err := reader.nextPacket()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Ogg Opus header: %w", err)
+	}
+	if len(head) < 19 || string(head[:8]) != "OpusHead" {
+		return nil, errors.New("not an Ogg Opus stream")
+	}
+	if family := head[18]; family != 0 {
+		return nil, fmt.Errorf("unsupported Opus channel mapping family: %d", family)
+	}
+	reader.preSkip = int(binary.LittleEndian.Uint16(head[10:12]))
+	return reader, nil
+}
+
+// readPacket returns the next audio packet, which is only valid until the next call. It returns io.EOF at the end of the stream.
+func (o *oggOpusReader) readPacket() ([]byte, error) {
+	for {
+		packet, err := o.nextPacket()
+		if err != nil {
+			return nil, err
+		}
+		// comments and the headers of chained streams are not audio.
+		if len(packet) == 0 || bytes.HasPrefix(packet, []byte("OpusHead")) || bytes.HasPrefix(packet, []byte("OpusTags")) {
+			continue
+		}
+		return packet, nil
+	}
+}
+
+// nextPacket returns the next packet of the stream, reading the pages it spans.
+func (o *oggOpusReader) nextPacket() ([]byte, error) {
+	o.packet = o.packet[:0]
+	for {
+		if len(o.lacing) == 0 {
+			if err := o.readPageHeader(); err != nil {
+				if errors.Is(err, io.EOF) && len(o.packet) > 0 {
+					return nil, io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			continue
+		}
+		size := int(o.lacing[0])
+		o.lacing = o.lacing[1:]
+		start := len(o.packet)
+		o.packet = slices.Grow(o.packet, size)[:start+size]
+		if _, err := io.ReadFull(o.r, o.packet[start:]); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		// a segment shorter than 255 bytes ends the packet, while a packet may go on over the next page.
+		if size < 255 {
+			return o.packet, nil
+		}
+	}
+}
+
+func (o *oggOpusReader) readPageHeader() error {
+	if _, err := io.ReadFull(o.r, o.header[:]); err != nil {
+		return err
+	}
+	if string(o.header[:4]) != "OggS" {
+		return errors.New("invalid Ogg page")
+	}
+	o.lacing = o.lacingBuf[:o.header[26]]
+	if _, err := io.ReadFull(o.r, o.lacing); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// opusPacketDuration returns the audio in the opus packet, as told by its TOC byte (RFC 6716, section 3.1), or zero if it is malformed.
+func opusPacketDuration(packet []byte) time.Duration {
+	if len(packet) == 0 {
+		return 0
+	}
+	toc := packet[0]
+	config := toc >> 3
+	var frame time.Duration
+	switch {
+	case config < 12: // SILK
+		frame = [...]time.Duration{10, 20, 40, 60}[config%4] * time.Millisecond
+	case config < 16: // hybrid
+		frame = [...]time.Duration{10, 20}[config%2] * time.Millisecond
+	default: // CELT
+		frame = [...]time.Duration{2500, 5000, 10000, 20000}[config%4] * time.Microsecond
+	}
+	switch toc & 3 {
+	case 0:
+		return frame
+	case 1, 2:
+		return 2 * frame
+	default:
+		if len(packet) < 2 {
+			return 0
+		}
+		return time.Duration(packet[1]&0x3f) * frame
+	}
+}
+
+// oggOpusFrameProvider sends the opus packets of Ogg Opus speech to discord as they are, without decoding and encoding them again.
+// Every packet must hold 20ms of audio, as discord is sent a frame every 20ms; the speech fails at a packet that does not.
+//
+// Packets wholly within the pre-skip of the stream are dropped, like the decoder drops their samples. What is left of it,
+// less than a packet, is played and cached as it is, since a packet can not be cut without decoding it. It is the priming
+// of the encoder, a few milliseconds of near silence before the speech, e.g. 6.5ms for the usual pre-skip of 312 samples.
+type oggOpusFrameProvider struct {
+	resp   *tts.SpeechResponse
+	reader *oggOpusReader
+	// first is the packet read to tell whether the speech can be passed through, provided before the rest.
+	first []byte
+	// skip are the samples per channel of the pre-skip not dropped yet.
+	skip int
+}
+
+// passthroughOggOpus returns a provider sending the opus packets of the speech as they are, if its first packet holds 20ms of audio.
+// Otherwise the speech is left to the decoder, with what was read looking at it put back in front of its audio.
+func passthroughOggOpus(resp *tts.SpeechResponse) (*oggOpusFrameProvider, bool) {
+	var head bytes.Buffer
+	recorder := &recordingReader{r: resp.Audio, record: &head}
+	reader, err := newOggOpusReader(recorder)
+	var first []byte
+	if err == nil {
+		first, err = reader.readPacket()
+	}
+	if err != nil || opusPacketDuration(first) != opusFrameDuration {
+		resp.Audio = &replayedAudio{Reader: io.MultiReader(&head, resp.Audio), audio: resp.Audio}
+		return nil, false
+	}
+	recorder.record = nil
+	return &oggOpusFrameProvider{resp: resp, reader: reader, first: first, skip: reader.preSkip}, true
+}
+
+// ProvideOpusFrame returns the next packet after the pre-skip, which is only valid until the next call.
+// It returns io.EOF at the end of the speech.
+func (p *oggOpusFrameProvider) ProvideOpusFrame() ([]byte, error) {
+	for {
+		packet, err := p.nextPacket()
+		if err != nil {
+			return nil, err
+		}
+		if p.skip < opusFrameSamples {
+			return packet, nil
+		}
+		p.skip -= opusFrameSamples
+	}
+}
+
+func (p *oggOpusFrameProvider) nextPacket() ([]byte, error) {
+	if first := p.first; first != nil {
+		p.first = nil
+		return first, nil
+	}
+	packet, err := p.reader.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	if duration := opusPacketDuration(packet); duration != opusFrameDuration {
+		return nil, fmt.Errorf("opus packet of %s can not be passed through", duration)
+	}
+	return packet, nil
+}
+
+func (p *oggOpusFrameProvider) Close() {
+	p.resp.Close()
+}
+
+// recordingReader writes what is read to record while it is not nil.
+type recordingReader struct {
+	r      io.Reader
+	record *bytes.Buffer
+}
+
+func (r *recordingReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if r.record != nil {
+		r.record.Write(b[:n])
+	}
+	return n, err
+}
+
+// replayedAudio replays what was read from audio before reading the rest of it, and closes audio once closed.
+type replayedAudio struct {
+	io.Reader
+	audio io.Reader
+}
+
+func (a *replayedAudio) Close() error {
+	if closer, ok := a.audio.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// maxOpusPacketSamples is the most samples per channel an opus packet holds, i.e. 120ms at 48kHz.
+const maxOpusPacketSamples = 5760
+
+// opusFrameProvider decodes the packets of Ogg Opus speech into stereo frames at 48kHz, for speech that can not be passed through,
+// e.g. with packets of other durations. The last frame is padded with silence.
+type opusFrameProvider struct {
+	audio   io.Reader
+	reader  *oggOpusReader
+	decoder *opus.Decoder
+	buf     []int16
+	// decoded are the samples decoded but not provided yet, and skip the samples still to be dropped from the start.
+	decoded []int16
+	skip    int
+	frame   []int16
+	ended   bool
+}
+
+func newOpusFrameProvider(audio io.Reader) (*opusFrameProvider, error) {
+	reader, err := newOggOpusReader(audio)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := opus.NewDecoder(discordSampleRate, 2)
+	if err != nil {
+		return nil, err
+	}
+	return &opusFrameProvider{
+		audio:   audio,
+		reader:  reader,
+		decoder: decoder,
+		buf:     make([]int16, maxOpusPacketSamples*2),
+		skip:    reader.preSkip * 2,
+		frame:   frameSamples.get(frameSize(discordSampleRate, 2)),
+	}, nil
+}
+
+func (p *opusFrameProvider) ProvidePCMFrame() ([]int16, error) {
+	for len(p.decoded) < len(p.frame) && !p.ended {
+		packet, err := p.reader.readPacket()
+		if errors.Is(err, io.EOF) {
+			p.ended = true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		n, err := p.decoder.Decode(packet, p.buf, false)
+		if err != nil {
+			return nil, err
+		}
+		samples := p.buf[:n*2]
+		drop := min(p.skip, len(samples))
+		p.skip -= drop
+		p.decoded = append(p.decoded, samples[drop:]...)
+	}
+	if len(p.decoded) == 0 {
+		return nil, io.EOF
+	}
+	n := copy(p.frame, p.decoded)
+	clear(p.frame[n:])
+	p.decoded = p.decoded[:copy(p.decoded, p.decoded[n:])]
+	return p.frame, nil
+}
+
+func (p *opusFrameProvider) Close() {
+	if closer, ok := p.audio.(io.Closer); ok {
+		closer.Close()
+	}
+	p.decoder.Destroy()
+	frameSamples.put(p.frame)
+	p.frame = nil
+}
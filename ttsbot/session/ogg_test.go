@@ -0,0 +1,131 @@
+package session
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+// toc20ms and toc60ms are the TOC bytes of single-frame CELT packets of 20ms and SILK packets of 60ms.
+const (
+	toc20ms = 31 << 3
+	toc60ms = 3 << 3
+)
+
+// oggOpusStream builds an Ogg Opus stream with a page per packet, after the OpusHead and OpusTags headers.
+func oggOpusStream(packets ...[]byte) []byte {
+	return oggOpusStreamWithPreSkip(312, packets...)
+}
+
+// oggOpusStreamWithPreSkip builds an Ogg Opus stream like oggOpusStream, whose OpusHead tells the given pre-skip.
+func oggOpusStreamWithPreSkip(preSkip int, packets ...[]byte) []byte {
+	head := append([]byte("OpusHead"), 1, 1, 0, 0, 0x80, 0xbb, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint16(head[10:12], uint16(preSkip))
+	var stream bytes.Buffer
+	for _, packet := range append([][]byte{head, []byte("OpusTags")}, packets...) {
+		var lacing []byte
+		for size := len(packet); ; size -= 255 {
+			lacing = append(lacing, byte(min(size, 255)))
+			if size < 255 {
+				break
+			}
+		}
+		header := make([]byte, oggPageHeaderSize)
+		copy(header, "OggS")
+		header[26] = byte(len(lacing))
+		stream.Write(header)
+		stream.Write(lacing)
+		stream.Write(packet)
+	}
+	return stream.Bytes()
+}
+
+func TestOggOpusReader(t *testing.T) {
+	large := append([]byte{toc20ms}, bytes.Repeat([]byte{7}, 600)...)
+	reader, err := newOggOpusReader(bytes.NewReader(oggOpusStream([]byte{toc20ms, 1}, large)))
+	require.NoError(t, err)
+	require.Equal(t, 312, reader.preSkip)
+
+	// the comments are skipped, and packets continue over segments of 255 bytes.
+	packet, err := reader.readPacket()
+	require.NoError(t, err)
+	require.Equal(t, []byte{toc20ms, 1}, packet)
+	packet, err = reader.readPacket()
+	require.NoError(t, err)
+	require.Equal(t, large, packet)
+	_, err = reader.readPacket()
+	require.ErrorIs(t, err, io.EOF)
+
+	_, err = newOggOpusReader(bytes.NewReader([]byte("ID3 not ogg at all, but long enough")))
+	require.Error(t, err)
+}
+
+func TestOpusPacketDuration(t *testing.T) {
+	testcases := []struct {
+		name   string
+		packet []byte
+		want   time.Duration
+	}{
+		{"celt 20ms", []byte{toc20ms}, 20 * time.Millisecond},
+		{"silk 60ms", []byte{toc60ms}, 60 * time.Millisecond},
+		{"two celt 10ms frames", []byte{30<<3 | 1}, 20 * time.Millisecond},
+		{"eight celt 2.5ms frames", []byte{28<<3 | 3, 8}, 20 * time.Millisecond},
+		{"missing frame count", []byte{28<<3 | 3}, 0},
+		{"empty", nil, 0},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, opusPacketDuration(tc.packet))
+		})
+	}
+}
+
+func TestPassthroughOggOpus(t *testing.T) {
+	stream := oggOpusStream([]byte{toc20ms, 1}, []byte{toc20ms, 2})
+	provider, ok := passthroughOggOpus(&tts.SpeechResponse{Format: tts.AudioFormatOggOpus, Audio: bytes.NewReader(stream)})
+	require.True(t, ok)
+	for _, expected := range [][]byte{{toc20ms, 1}, {toc20ms, 2}} {
+		frame, err := provider.ProvideOpusFrame()
+		require.NoError(t, err)
+		require.Equal(t, expected, frame)
+	}
+	_, err := provider.ProvideOpusFrame()
+	require.ErrorIs(t, err, io.EOF)
+
+	// packets wholly within the pre-skip are dropped, while the rest of it is played.
+	stream = oggOpusStreamWithPreSkip(2*opusFrameSamples+312, []byte{toc20ms, 1}, []byte{toc20ms, 2}, []byte{toc20ms, 3}, []byte{toc20ms, 4})
+	provider, ok = passthroughOggOpus(&tts.SpeechResponse{Format: tts.AudioFormatOggOpus, Audio: bytes.NewReader(stream)})
+	require.True(t, ok)
+	for _, expected := range [][]byte{{toc20ms, 3}, {toc20ms, 4}} {
+		frame, err := provider.ProvideOpusFrame()
+		require.NoError(t, err)
+		require.Equal(t, expected, frame)
+	}
+	_, err = provider.ProvideOpusFrame()
+	require.ErrorIs(t, err, io.EOF)
+
+	// a later packet of another duration fails the speech.
+	stream = oggOpusStream([]byte{toc20ms, 1}, []byte{toc60ms, 2})
+	provider, ok = passthroughOggOpus(&tts.SpeechResponse{Format: tts.AudioFormatOggOpus, Audio: bytes.NewReader(stream)})
+	require.True(t, ok)
+	_, err = provider.ProvideOpusFrame()
+	require.NoError(t, err)
+	_, err = provider.ProvideOpusFrame()
+	require.Error(t, err)
+	require.NotErrorIs(t, err, io.EOF)
+
+	// speech of other durations is left to the decoder, which reads it from the start.
+	stream = oggOpusStream([]byte{toc60ms, 1})
+	resp := &tts.SpeechResponse{Format: tts.AudioFormatOggOpus, Audio: bytes.NewReader(stream)}
+	_, ok = passthroughOggOpus(resp)
+	require.False(t, ok)
+	audio, err := io.ReadAll(resp.Audio)
+	require.NoError(t, err)
+	require.Equal(t, stream, audio)
+}
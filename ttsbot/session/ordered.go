@@ -0,0 +1,42 @@
+package session
+
+// orderedPool runs jobs concurrently, at most limit at once, and delivers their results in the order the jobs were run.
+// Results are delivered on the goroutine calling run and wait, so deliver needs no locking.
+type orderedPool[T any] struct {
+	limit   int
+	deliver func(T)
+	// pending are the results of the jobs not delivered yet, oldest first.
+	pending []chan T
+}
+
+func newOrderedPool[T any](limit int, deliver func(T)) *orderedPool[T] {
+	return &orderedPool[T]{
+		limit:   max(limit, 1),
+		deliver: deliver,
+	}
+}
+
+// run starts the job. If limit jobs are running, the result of the oldest is delivered first.
+func (p *orderedPool[T]) run(job func() T) {
+	if len(p.pending) >= p.limit {
+		p.deliverOldest()
+	}
+	result := make(chan T, 1)
+	p.pending = append(p.pending, result)
+	go func() {
+		result <- job()
+	}()
+}
+
+// wait delivers the results of every job run so far.
+func (p *orderedPool[T]) wait() {
+	for len(p.pending) > 0 {
+		p.deliverOldest()
+	}
+}
+
+func (p *orderedPool[T]) deliverOldest() {
+	result := <-p.pending[0]
+	p.pending = p.pending[1:]
+	p.deliver(result)
+}
@@ -0,0 +1,42 @@
+package session
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderedPool(t *testing.T) {
+	var delivered []int
+	pool := newOrderedPool(2, func(n int) {
+		delivered = append(delivered, n)
+	})
+
+	var running, most atomic.Int32
+	job := func(n int, delay time.Duration) func() int {
+		return func() int {
+			current := running.Add(1)
+			for {
+				previous := most.Load()
+				if current <= previous || most.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			time.Sleep(delay)
+			running.Add(-1)
+			return n
+		}
+	}
+
+	// later jobs finishing first still wait for the jobs before them.
+	pool.run(job(1, 50*time.Millisecond))
+	pool.run(job(2, 0))
+	pool.run(job(3, 10*time.Millisecond))
+	pool.run(job(4, 0))
+	pool.wait()
+
+	require.Equal(t, []int{1, 2, 3, 4}, delivered)
+	require.LessOrEqual(t, most.Load(), int32(2), "more jobs ran at once than the limit")
+}
@@ -6,15 +6,26 @@ import (
 	"encoding/binary"
 	"fmt"
 	"log/slog"
+	"maps"
+	"sync"
 	"time"
 
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/redis/go-redis/v9"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
 )
 
 type SessionRestoreFunc func(guildID, voiceChannelID, readingChannelID snowflake.ID) (*Session, error)
 
-var _ SessionLifecycleObserver = (*PersistenceManager)(nil)
+// SessionPersistence keeps track of the running sessions as a lifecycle observer, so that they can be restored after a restart.
+type SessionPersistence interface {
+	SessionLifecycleObserver
+	// Restore restores the persisted sessions into the manager, creating each with sessionRestoreFunc.
+	Restore(ctx context.Context, sessionManager SessionManager, sessionRestoreFunc SessionRestoreFunc) error
+}
+
+var _ SessionPersistence = (*PersistenceManager)(nil)
 
 type PersistenceManager struct {
 	NoOpSessionLifecycleObserver
@@ -22,10 +33,14 @@ type PersistenceManager struct {
 	// applicationID for the persistence manager in the redis store.
 	// If multiple instances of the bot are running, they should have different identifiers.
 	// recommended to use the bot's application ID but it can be any unique.
-	applicationID      snowflake.ID
-	redisClient        *redis.Client
+	applicationID     snowflake.ID
+	redisClient       *redis.Client
+	heartbeatInterval time.Duration
+	clock             clock.Clock
+
+	// mu guards persistentSessions, as observers of guilds in different shards of the manager run concurrently.
+	mu                 sync.Mutex
 	persistentSessions map[sessionID]persistentSession // guildID:voiceChannelID -> readingChannelID
-	heartbeatInterval  time.Duration
 }
 
 const (
@@ -79,22 +94,49 @@ func NewPersistenceManager(applicationID snowflake.ID, redisClient *redis.Client
 		applicationID:      applicationID,
 		persistentSessions: make(map[sessionID]persistentSession),
 		heartbeatInterval:  heatbeatInterval,
+		clock:              clock.Real,
 	}
 }
 
 func (p *PersistenceManager) OnCreated(e SessionCreatedEvent) {
+	p.persist(e.sessionState)
+}
+
+func (p *PersistenceManager) OnRestored(e SessionRestoredEvent) {
+	p.persist(e.sessionState)
+}
+
+func (p *PersistenceManager) OnUpdated(e SessionUpdatedEvent) {
+	if e.Previous.VoiceChannelID != e.VoiceChannelID {
+		p.forget(e.Previous)
+	}
+	p.persist(e.sessionState)
+}
+
+func (p *PersistenceManager) OnFailed(e SessionFailedEvent) {
+	// a session that failed to start or restore must not be restored again.
+	p.forget(e.sessionState)
+}
+
+func (p *PersistenceManager) OnDeleted(e SessionDeletedEvent) {
+	p.forget(e.sessionState)
+}
+
+func (p *PersistenceManager) persist(state sessionState) {
 	key := sessionID{
 		applicationID:  p.applicationID,
-		voiceChannelID: e.VoiceChannelID,
+		voiceChannelID: state.VoiceChannelID,
 	}
 
 	session := persistentSession{
 		applicationID:    p.applicationID,
-		guildID:          e.GuildID,
-		voiceChannelID:   e.VoiceChannelID,
-		readingChannelID: e.ReadingChannelID,
+		guildID:          state.GuildID,
+		voiceChannelID:   state.VoiceChannelID,
+		readingChannelID: state.ReadingChannelID,
 	}
+	p.mu.Lock()
 	p.persistentSessions[key] = session
+	p.mu.Unlock()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -103,29 +145,33 @@ func (p *PersistenceManager) OnCreated(e SessionCreatedEvent) {
 	}
 }
 
-func (p *PersistenceManager) OnDeleted(e SessionDeletedEvent) {
-	delete(p.persistentSessions, sessionID{
+func (p *PersistenceManager) forget(state sessionState) {
+	key := sessionID{
 		applicationID:  p.applicationID,
-		voiceChannelID: e.VoiceChannelID,
-	})
+		voiceChannelID: state.VoiceChannelID,
+	}
+	p.mu.Lock()
+	delete(p.persistentSessions, key)
+	p.mu.Unlock()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := p.redisClient.Del(ctx, sessionID{
-		applicationID:  p.applicationID,
-		voiceChannelID: e.VoiceChannelID,
-	}.generateKey()).Err(); err != nil {
-		slog.Error("Failed to delete session from Redis", slog.Any("sessionKey", e.VoiceChannelID), slog.Any("error", err))
+	if err := p.redisClient.Del(ctx, key.generateKey()).Err(); err != nil {
+		slog.Error("Failed to delete session from Redis", slog.Any("sessionKey", state.VoiceChannelID), slog.Any("error", err))
 	}
-	slog.Debug("Deleted session from Redis", slog.Any("voiceChannelID", e.VoiceChannelID))
+	slog.Debug("Deleted session from Redis", slog.Any("voiceChannelID", state.VoiceChannelID))
 }
 
 func (p *PersistenceManager) StartHeartbeatLoop() {
-	ticker := time.NewTicker(p.heartbeatInterval)
+	ticker := p.clock.NewTicker(p.heartbeatInterval)
 	ttl := p.ttl()
 	go func() {
-		for range ticker.C {
-			for key, session := range p.persistentSessions {
+		for range ticker.C() {
+			// the sessions are copied, so that sessions are persisted and forgotten while redis is written.
+			p.mu.Lock()
+			sessions := maps.Clone(p.persistentSessions)
+			p.mu.Unlock()
+			for key, session := range sessions {
 				sessionKey := key.generateKey()
 				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 				if err := p.redisClient.Set(ctx, sessionKey, &session, ttl).Err(); err != nil {
@@ -167,15 +213,28 @@ func (p *PersistenceManager) Restore(ctx context.Context, sessionManager Session
 				continue
 			}
 
+			// a guild has a single voice connection, so only one session per guild can be restored.
+			if err := sessionManager.Reserve(session.guildID); err != nil {
+				slog.Warn("Skipping session of a guild that is already being restored", slog.Any("session", session))
+				continue
+			}
+
 			// conn.Open() blocks until the voice state update event is received...
 			// so we need to restore the session in a separate goroutine
 			go func() {
 				s, err := sessionRestoreFunc(session.guildID, session.voiceChannelID, session.readingChannelID)
 				if err != nil {
 					slog.Error("Failed to restore session", slog.Any("session", session), slog.Any("error", err))
+					sessionManager.Fail(session.guildID, session.voiceChannelID, session.readingChannelID, err)
+					return
+				}
+				if err := sessionManager.Restore(session.guildID, session.voiceChannelID, session.readingChannelID, s); err != nil {
+					slog.Error("Failed to add restored session", slog.Any("session", session), slog.Any("error", err))
+					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					s.Close(ctx, CloseReasonDuplicate)
+					cancel()
 					return
 				}
-				sessionManager.Add(session.guildID, session.voiceChannelID, session.readingChannelID, s)
 				slog.Info("Restored session from Redis", "session", session)
 			}()
 		}
@@ -0,0 +1,53 @@
+package session
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+func TestPersistenceManagerConcurrentShards(t *testing.T) {
+	// nothing listens on the address, so every write to redis fails at once and is only logged.
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 10 * time.Millisecond})
+	t.Cleanup(func() { client.Close() })
+	persistence := NewPersistenceManager(1, client, time.Minute)
+	clk := clock.NewFake(time.Date(2025, 10, 17, 12, 0, 0, 0, time.UTC))
+	persistence.clock = clk
+	persistence.StartHeartbeatLoop()
+
+	manager := NewSessionManager()
+	manager.AddObserver(persistence)
+
+	// guilds created in other milliseconds are in other shards, whose observers run concurrently.
+	var wg sync.WaitGroup
+	for i := range 4 {
+		guildID := snowflake.ID(i+1) << 22
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 20 {
+				voiceChannelID, readingChannelID := snowflake.ID(1000*(i+1)+2*j), snowflake.ID(1000*(i+1)+2*j+1)
+				require.NoError(t, manager.Add(guildID, voiceChannelID, readingChannelID, &Session{guildID: guildID, textChannelID: readingChannelID}))
+				manager.Delete(guildID, voiceChannelID)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			clk.Advance(time.Minute)
+		}
+	}()
+	wg.Wait()
+
+	persistence.mu.Lock()
+	defer persistence.mu.Unlock()
+	require.Empty(t, persistence.persistentSessions)
+}
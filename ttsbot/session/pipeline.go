@@ -0,0 +1,64 @@
+package session
+
+import (
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+// TransformedMessage is the content of a message as it is read aloud.
+type TransformedMessage struct {
+	// Segments are synthesized one by one, in order.
+	Segments []string
+	// Truncated reports whether the content was cut at the max message length of the guild.
+	Truncated bool
+}
+
+// TransformMessage runs the content of a message through the same pipeline the session reads messages with:
+// emojis, URLs, mentions, timestamps and markdown are made readable, confusing tokens are spelled out if the guild enabled it,
+// and the result is limited and split into segments.
+// mentions maps the mentioned users to the names read in their place.
+func TransformMessage(content string, guildSettings settings.GuildSettings, vr i18n.VoiceResource, mentions map[snowflake.ID]string, now time.Time) TransformedMessage {
+	markdownOptions := message.MarkdownOptions{
+		FormatCodeBlock:   codeBlockFormatter(guildSettings.CodeBlockMode, vr),
+		OmitStrikethrough: guildSettings.OmitStrikethrough,
+		ListItemPause:     vr.Markdown.Pause,
+	}
+	if guildSettings.AnnounceMarkdown {
+		markdownOptions.QuoteFormat = vr.Markdown.Quote
+		markdownOptions.HeadingFormat = vr.Markdown.Heading
+	}
+
+	content = message.ReplaceEmojis(content)
+	content = message.ReplaceUrlsWithPlaceholders(content)
+	content = message.ReplaceUserMentions(content, mentions)
+	content = message.ReplaceTimestamps(content, guildSettings.Location(), now, vr)
+	content = message.ConvertMarkdownToPlainText(content, markdownOptions)
+	if guildSettings.SpellOut {
+		content = message.SpellOut(content, spellOutPatterns(guildSettings.SpellOutPatterns))
+	}
+	limited := message.LimitContentLength(content, guildSettings.MaxMessageLength)
+
+	return TransformedMessage{
+		Segments:  message.SplitSentences(limited, maxSegmentLength),
+		Truncated: limited != content,
+	}
+}
+
+// spellOutPatterns returns the built-in spell-out patterns followed by the patterns of the guild.
+// Patterns were validated when they were saved, so a pattern that does not compile is skipped.
+func spellOutPatterns(guildPatterns []string) []*regexp.Regexp {
+	patterns := slices.Clone(message.DefaultSpellOutPatterns)
+	for _, pattern := range guildPatterns {
+		if compiled, err := regexp.Compile(pattern); err == nil {
+			patterns = append(patterns, compiled)
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,68 @@
+package session
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+func TestTransformMessage(t *testing.T) {
+	vrs, err := i18n.LoadVoiceResources("../../locales/voice/")
+	if err != nil {
+		t.Fatalf("failed to load voice resources: %v", err)
+	}
+	vr, ok := vrs.GetOrGeneric("en-US")
+	if !ok {
+		t.Fatal("voice resources for en-US not found")
+	}
+
+	guildSettings := settings.DefaultGuildSettings(1)
+	mentions := map[snowflake.ID]string{2: "Alice"}
+
+	transformed := TransformMessage("Hi <@2>, look at **this** <:wave:123>", guildSettings, vr, mentions, time.Now())
+	if want := []string{"Hi @Alice, look at this wave"}; !slices.Equal(transformed.Segments, want) {
+		t.Errorf("Segments = %q, want %q", transformed.Segments, want)
+	}
+	if transformed.Truncated {
+		t.Error("short content must not be truncated")
+	}
+
+	guildSettings.SpellOut = true
+	guildSettings.SpellOutPatterns = []string{`order-(\d+)`}
+	transformed = TransformMessage("join discord.gg/Ab3 for order-42", guildSettings, vr, nil, time.Now())
+	if want := []string{"join discord.gg/A b 3 for order-4 2"}; !slices.Equal(transformed.Segments, want) {
+		t.Errorf("Segments = %q, want %q", transformed.Segments, want)
+	}
+
+	guildSettings.MaxMessageLength = 5
+	if transformed := TransformMessage("a long message", guildSettings, vr, nil, time.Now()); !transformed.Truncated {
+		t.Errorf("content over the max message length must be truncated, got %q", transformed.Segments)
+	}
+}
+
+func BenchmarkTransformMessage(b *testing.B) {
+	vrs, err := i18n.LoadVoiceResources("../../locales/voice/")
+	if err != nil {
+		b.Fatalf("failed to load voice resources: %v", err)
+	}
+	vr, ok := vrs.GetOrGeneric("en-US")
+	if !ok {
+		b.Fatal("voice resources for en-US not found")
+	}
+
+	guildSettings := settings.DefaultGuildSettings(1)
+	mentions := map[snowflake.ID]string{2: "Alice", 3: "Bob"}
+	content := "Hey <@2> and <@3>, **look** at https://example.com/some/long/path?query=1 <:wave:123> before <t:1700000000:R>. " +
+		"It is `important`, so read it ~~now~~ soon! Thanks."
+	now := time.Now()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		TransformMessage(content, guildSettings, vr, mentions, now)
+	}
+}
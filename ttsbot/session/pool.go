@@ -0,0 +1,31 @@
+package session
+
+import "sync"
+
+// frameSamples recycles the buffers of single frames of decoded PCM, since every utterance would otherwise allocate them anew.
+// It holds buffers of up to a 20ms stereo frame at 48kHz.
+var frameSamples = &samplePool{max: frameSize(discordSampleRate, 2)}
+
+// samplePool is a pool of sample buffers of up to max samples.
+type samplePool struct {
+	pool sync.Pool
+	max  int
+}
+
+// get returns a buffer of n samples. The samples are not cleared.
+func (p *samplePool) get(n int) []int16 {
+	if buf, ok := p.pool.Get().(*[]int16); ok && cap(*buf) >= n {
+		return (*buf)[:n]
+	}
+	// a buffer too small is dropped rather than put back, so that the pool settles on the common sizes.
+	return make([]int16, n)
+}
+
+// put returns the buffer to the pool. It must not be used afterwards.
+func (p *samplePool) put(buf []int16) {
+	if cap(buf) == 0 || cap(buf) > p.max {
+		return
+	}
+	buf = buf[:0]
+	p.pool.Put(&buf)
+}
@@ -0,0 +1,117 @@
+package session
+
+import (
+	"io"
+	"sync"
+
+	"github.com/disgoorg/audio/pcm"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+var _ Decoder = (*PrebufferingDecoder)(nil)
+
+// PrebufferingDecoder decodes ahead of playback in the background, and returns a track
+// only once the given number of frames is ready or the track is fully decoded.
+// Without it, frames are decoded as they are sent, and a slow start of decoding is heard as stutter.
+type PrebufferingDecoder struct {
+	decoder Decoder
+	frames  int
+}
+
+// NewPrebufferingDecoder wraps the decoder to buffer the given number of 20ms frames before playback.
+func NewPrebufferingDecoder(decoder Decoder, frames int) *PrebufferingDecoder {
+	return &PrebufferingDecoder{decoder: decoder, frames: frames}
+}
+
+func (d *PrebufferingDecoder) Decode(resp *tts.SpeechResponse) (pcm.FrameProvider, error) {
+	provider, err := d.decoder.Decode(resp)
+	if err != nil {
+		return nil, err
+	}
+	buffered := newBufferedFrameProvider(provider, d.frames)
+	<-buffered.ready
+	return buffered, nil
+}
+
+// bufferedFrameProvider reads frames of the source in the background, keeping up to the buffer size ahead of playback.
+// The copies of the frames are taken from frameSamples, and returned once the next frame is provided.
+type bufferedFrameProvider struct {
+	source pcm.FrameProvider
+	frames chan []int16
+	// last is the frame provided last, which the caller may use until it asks for the next one.
+	last []int16
+	// ready is closed once the buffer has been filled for the first time, or the source has ended.
+	ready chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+	// err is the error the source ended with. It is set before frames is closed.
+	err       error
+	closeOnce sync.Once
+}
+
+func newBufferedFrameProvider(source pcm.FrameProvider, size int) *bufferedFrameProvider {
+	p := &bufferedFrameProvider{
+		source: source,
+		frames: make(chan []int16, size),
+		ready:  make(chan struct{}),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go p.fill(size)
+	return p
+}
+
+func (p *bufferedFrameProvider) fill(size int) {
+	defer close(p.done)
+	var readyOnce sync.Once
+	markReady := func() { readyOnce.Do(func() { close(p.ready) }) }
+	defer markReady()
+	defer close(p.frames)
+
+	for buffered := 0; ; buffered++ {
+		if buffered >= size {
+			markReady()
+		}
+		frame, err := p.source.ProvidePCMFrame()
+		if err == nil && len(frame) == 0 {
+			err = io.EOF
+		}
+		if err != nil {
+			p.err = err
+			return
+		}
+		// sources reuse their buffers, so the frame is copied before the next one is read.
+		buffered := frameSamples.get(len(frame))
+		copy(buffered, frame)
+		select {
+		case p.frames <- buffered:
+		case <-p.stop:
+			frameSamples.put(buffered)
+			return
+		}
+	}
+}
+
+func (p *bufferedFrameProvider) ProvidePCMFrame() ([]int16, error) {
+	frameSamples.put(p.last)
+	p.last = nil
+	frame, ok := <-p.frames
+	if !ok {
+		return nil, p.err
+	}
+	p.last = frame
+	return frame, nil
+}
+
+func (p *bufferedFrameProvider) Close() {
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		<-p.done
+		p.source.Close()
+		for frame := range p.frames {
+			frameSamples.put(frame)
+		}
+		frameSamples.put(p.last)
+		p.last = nil
+	})
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/audio/pcm"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+// countingFrameProvider provides frames of the given count, reusing its buffer like the real decoders.
+type countingFrameProvider struct {
+	remaining int
+	buf       []int16
+	read      atomic.Int32
+	closed    atomic.Bool
+}
+
+func (p *countingFrameProvider) ProvidePCMFrame() ([]int16, error) {
+	if p.remaining == 0 {
+		return nil, io.EOF
+	}
+	p.remaining--
+	p.buf[0] = int16(p.read.Add(1))
+	return p.buf, nil
+}
+
+func (p *countingFrameProvider) Close() {
+	p.closed.Store(true)
+}
+
+type stubDecoder struct {
+	provider pcm.FrameProvider
+	err      error
+}
+
+func (d stubDecoder) Decode(*tts.SpeechResponse) (pcm.FrameProvider, error) {
+	return d.provider, d.err
+}
+
+func TestPrebufferingDecoder(t *testing.T) {
+	t.Run("buffers before playback", func(t *testing.T) {
+		source := &countingFrameProvider{remaining: 10, buf: make([]int16, 4)}
+		provider, err := NewPrebufferingDecoder(stubDecoder{provider: source}, 3).Decode(&tts.SpeechResponse{})
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, source.read.Load(), int32(3), "playback starts once the buffer is filled")
+
+		frames := decodeFrames(t, provider)
+		require.Len(t, frames, 10)
+		for i, frame := range frames {
+			require.Equal(t, int16(i+1), frame[0], "buffered frames are not overwritten by the source")
+		}
+		require.True(t, source.closed.Load())
+	})
+
+	t.Run("short tracks are played once decoded", func(t *testing.T) {
+		source := &countingFrameProvider{remaining: 2, buf: make([]int16, 4)}
+		provider, err := NewPrebufferingDecoder(stubDecoder{provider: source}, 50).Decode(&tts.SpeechResponse{})
+		require.NoError(t, err)
+		require.Len(t, decodeFrames(t, provider), 2)
+	})
+
+	t.Run("closing stops decoding", func(t *testing.T) {
+		source := &countingFrameProvider{remaining: 1000, buf: make([]int16, 4)}
+		provider, err := NewPrebufferingDecoder(stubDecoder{provider: source}, 2).Decode(&tts.SpeechResponse{})
+		require.NoError(t, err)
+
+		closed := make(chan struct{})
+		go func() {
+			provider.Close()
+			close(closed)
+		}()
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Fatal("closing did not stop decoding")
+		}
+		require.True(t, source.closed.Load())
+		require.Less(t, source.read.Load(), int32(1000))
+	})
+
+	t.Run("decode errors are returned", func(t *testing.T) {
+		decodeErr := errors.New("broken")
+		_, err := NewPrebufferingDecoder(stubDecoder{err: decodeErr}, 2).Decode(&tts.SpeechResponse{})
+		require.ErrorIs(t, err, decodeErr)
+	})
+}
+
+func BenchmarkPrebufferingDecoder(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		// five seconds of stereo frames at 48kHz.
+		source := &countingFrameProvider{remaining: 250, buf: make([]int16, 1920)}
+		provider, err := NewPrebufferingDecoder(stubDecoder{provider: source}, 10).Decode(&tts.SpeechResponse{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		for {
+			if _, err := provider.ProvidePCMFrame(); err != nil {
+				break
+			}
+		}
+		provider.Close()
+	}
+}
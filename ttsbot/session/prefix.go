@@ -0,0 +1,40 @@
+package session
+
+import (
+	"sync/atomic"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+)
+
+// speakerPrefixer is the queue stage deciding whether the speaker name is read before a task.
+// The name is read when the speaker changes, so consecutive messages of the same member are read without it.
+type speakerPrefixer struct {
+	// lastSpeakerID is only written by the worker, but read by snapshots.
+	lastSpeakerID atomic.Uint64
+}
+
+func (p *speakerPrefixer) apply(task SpeechTask) SpeechTask {
+	if !task.ContainsSpeaker {
+		// system announcements have no speaker, but they interrupt the conversation,
+		// so the next message reads its speaker again.
+		p.lastSpeakerID.Store(0)
+		return task
+	}
+	if uint64(task.SpeakerID) == p.lastSpeakerID.Load() {
+		return task
+	}
+
+	p.lastSpeakerID.Store(uint64(task.SpeakerID))
+	task.Segments = append([]string{task.SpeakerName}, task.Segments...)
+	if task.SegmentPresets != nil {
+		// the name is read with the preset of the task.
+		task.SegmentPresets = append([]preset.Preset{{}}, task.SegmentPresets...)
+	}
+	return task
+}
+
+// lastSpeaker returns the speaker of the last message, or 0 if an announcement was read since.
+func (p *speakerPrefixer) lastSpeaker() snowflake.ID {
+	return snowflake.ID(p.lastSpeakerID.Load())
+}
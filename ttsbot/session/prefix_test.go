@@ -0,0 +1,63 @@
+package session
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+)
+
+func TestSpeakerPrefixer(t *testing.T) {
+	message := func(name string, id uint64, text string) SpeechTask {
+		return NewSpeechTask([]string{text}, preset.Preset{}, WithSpeaker(name, snowflake.ID(id)))
+	}
+	announcement := func(text string) SpeechTask {
+		return NewSpeechTask([]string{text}, preset.Preset{})
+	}
+
+	tests := []struct {
+		name  string
+		tasks []SpeechTask
+		want  [][]string
+	}{
+		{
+			name:  "Consecutive messages of the same speaker",
+			tasks: []SpeechTask{message("Alice", 1, "hi"), message("Alice", 1, "again")},
+			want:  [][]string{{"Alice", "hi"}, {"again"}},
+		},
+		{
+			name:  "Speaker changes",
+			tasks: []SpeechTask{message("Alice", 1, "hi"), message("Bob", 2, "yo"), message("Alice", 1, "back")},
+			want:  [][]string{{"Alice", "hi"}, {"Bob", "yo"}, {"Alice", "back"}},
+		},
+		{
+			name:  "Consecutive announcements",
+			tasks: []SpeechTask{announcement("ready"), announcement("Bob joined")},
+			want:  [][]string{{"ready"}, {"Bob joined"}},
+		},
+		{
+			name:  "Announcement between messages of the same speaker",
+			tasks: []SpeechTask{message("Alice", 1, "hi"), announcement("Bob joined"), message("Alice", 1, "welcome")},
+			want:  [][]string{{"Alice", "hi"}, {"Bob joined"}, {"Alice", "welcome"}},
+		},
+		{
+			name:  "Announcement before the first message",
+			tasks: []SpeechTask{announcement("ready"), message("Alice", 1, "hi"), message("Alice", 1, "again")},
+			want:  [][]string{{"ready"}, {"Alice", "hi"}, {"again"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prefixer speakerPrefixer
+			for i, task := range tt.tasks {
+				got := prefixer.apply(task).Segments
+				if !slices.Equal(got, tt.want[i]) {
+					t.Errorf("task %d: segments = %q, want %q", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package session
+
+import (
+	"sync"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// taskQueue queues the speech tasks of a session, taking turns between the channels they came from,
+// so that a busy reading channel can not hold back the announcements or the messages of another channel.
+// Tasks of the same channel are read in the order they were queued.
+type taskQueue struct {
+	// capacity is the most tasks queued per channel.
+	capacity int
+
+	mu      sync.Mutex
+	pending map[snowflake.ID][]SpeechTask
+	// turns are the channels with pending tasks, in the order they are read from.
+	turns  []snowflake.ID
+	closed bool
+	// ready is signaled while tasks are pending.
+	ready chan struct{}
+}
+
+// newTaskQueue creates a queue holding up to capacity tasks of each channel.
+func newTaskQueue(capacity int) *taskQueue {
+	return &taskQueue{
+		capacity: capacity,
+		pending:  make(map[snowflake.ID][]SpeechTask),
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// push queues the task, and reports false if the channel of the task has too many tasks queued or the queue is closed.
+func (q *taskQueue) push(task SpeechTask) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed || len(q.pending[task.Source]) >= q.capacity {
+		return false
+	}
+	if len(q.pending[task.Source]) == 0 {
+		q.turns = append(q.turns, task.Source)
+	}
+	q.pending[task.Source] = append(q.pending[task.Source], task)
+	q.signal()
+	return true
+}
+
+// pop takes the next task of the channel whose turn it is, and reports false if no task is pending.
+func (q *taskQueue) pop() (SpeechTask, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.turns) == 0 {
+		return SpeechTask{}, false
+	}
+
+	source := q.turns[0]
+	q.turns = q.turns[1:]
+	tasks := q.pending[source]
+	task := tasks[0]
+	if len(tasks) == 1 {
+		delete(q.pending, source)
+	} else {
+		q.pending[source] = tasks[1:]
+		// the channel takes its next turn after the others.
+		q.turns = append(q.turns, source)
+	}
+	if len(q.turns) > 0 {
+		q.signal()
+	}
+	return task, true
+}
+
+// len returns the number of pending tasks.
+func (q *taskQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, tasks := range q.pending {
+		n += len(tasks)
+	}
+	return n
+}
+
+// close stops queueing tasks.
+func (q *taskQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+}
+
+func (q *taskQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
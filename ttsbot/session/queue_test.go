@@ -0,0 +1,42 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskQueue(t *testing.T) {
+	queue := newTaskQueue(3)
+	for _, text := range []string{"a1", "a2", "a3"} {
+		require.True(t, queue.push(NewSpeechTask([]string{text}, preset.Preset{}, WithSource(1))))
+	}
+	require.False(t, queue.push(NewSpeechTask([]string{"a4"}, preset.Preset{}, WithSource(1))), "a channel holds up to the capacity")
+	require.True(t, queue.push(NewSpeechTask([]string{"b1"}, preset.Preset{}, WithSource(2))), "the capacity is per channel")
+	require.True(t, queue.push(NewSpeechTask([]string{"joined"}, preset.Preset{})))
+	require.Equal(t, 5, queue.len())
+
+	// the channels take turns, in the order their first task was queued.
+	var read []string
+	for {
+		select {
+		case <-queue.ready:
+		default:
+			t.Fatal("ready should be signaled while tasks are pending")
+		}
+		task, ok := queue.pop()
+		require.True(t, ok)
+		read = append(read, task.Segments[0])
+		if queue.len() == 0 {
+			break
+		}
+	}
+	require.Equal(t, []string{"a1", "b1", "joined", "a2", "a3"}, read)
+
+	_, ok := queue.pop()
+	require.False(t, ok)
+
+	queue.close()
+	require.False(t, queue.push(NewSpeechTask([]string{"late"}, preset.Preset{})))
+}
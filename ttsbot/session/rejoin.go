@@ -0,0 +1,98 @@
+package session
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+var _ SessionLifecycleObserver = (*RejoinGuard)(nil)
+
+// RejoinGuard keeps automatic joins, e.g. restoring sessions, from fighting moderators who keep disconnecting the bot.
+// Every disconnect in a row backs automatic joins to the guild off exponentially,
+// and after disableAfter disconnects they are disabled until a member starts a session manually with /join.
+type RejoinGuard struct {
+	NoOpSessionLifecycleObserver
+
+	mu           sync.Mutex
+	baseDelay    time.Duration
+	maxDelay     time.Duration
+	disableAfter int
+	guilds       map[snowflake.ID]rejoinState
+	clock        clock.Clock
+}
+
+type rejoinState struct {
+	disconnects      int
+	lastDisconnectAt time.Time
+}
+
+func NewRejoinGuard(baseDelay, maxDelay time.Duration, disableAfter int) *RejoinGuard {
+	return &RejoinGuard{
+		baseDelay:    baseDelay,
+		maxDelay:     maxDelay,
+		disableAfter: disableAfter,
+		guilds:       make(map[snowflake.ID]rejoinState),
+		clock:        clock.Real,
+	}
+}
+
+// CanAutoJoin reports whether the bot may join the guild automatically.
+// If it may not, it returns when it may, or the zero time if automatic joins are disabled until a manual join.
+func (g *RejoinGuard) CanAutoJoin(guildID snowflake.ID) (bool, time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	state, ok := g.guilds[guildID]
+	if !ok {
+		return true, time.Time{}
+	}
+	if state.disconnects >= g.disableAfter {
+		return false, time.Time{}
+	}
+
+	delay := g.baseDelay << (state.disconnects - 1)
+	if delay <= 0 || delay > g.maxDelay {
+		delay = g.maxDelay
+	}
+	allowedAt := state.lastDisconnectAt.Add(delay)
+	if g.clock.Now().Before(allowedAt) {
+		return false, allowedAt
+	}
+	return true, time.Time{}
+}
+
+// RecordDisconnect records that the bot was disconnected from a voice channel of the guild.
+func (g *RejoinGuard) RecordDisconnect(guildID snowflake.ID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	state := g.guilds[guildID]
+	state.disconnects++
+	state.lastDisconnectAt = g.clock.Now()
+	g.guilds[guildID] = state
+
+	if state.disconnects >= g.disableAfter {
+		slog.Warn("Bot was disconnected repeatedly, disabling automatic joins until a manual join", "guildID", guildID, "disconnects", state.disconnects)
+	}
+}
+
+// Reset allows automatic joins to the guild again.
+func (g *RejoinGuard) Reset(guildID snowflake.ID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.guilds, guildID)
+}
+
+// OnCreated resets the guild, as a session created with /join is a manual join.
+func (g *RejoinGuard) OnCreated(event SessionCreatedEvent) {
+	g.Reset(event.GuildID)
+}
+
+func (g *RejoinGuard) OnDeleted(event SessionDeletedEvent) {
+	if event.Reason == CloseReasonDisconnected {
+		g.RecordDisconnect(event.GuildID)
+	}
+}
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+func TestRejoinGuard(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 10, 17, 12, 0, 0, 0, time.UTC))
+	guard := NewRejoinGuard(time.Minute, 10*time.Minute, 3)
+	guard.clock = clk
+
+	ok, _ := guard.CanAutoJoin(1)
+	require.True(t, ok)
+
+	guard.OnDeleted(SessionDeletedEvent{sessionState: sessionState{GuildID: 1}, Reason: CloseReasonLeave})
+	ok, _ = guard.CanAutoJoin(1)
+	require.True(t, ok, "leaving on purpose does not back off")
+
+	guard.OnDeleted(SessionDeletedEvent{sessionState: sessionState{GuildID: 1}, Reason: CloseReasonDisconnected})
+	ok, allowedAt := guard.CanAutoJoin(1)
+	require.False(t, ok)
+	require.Equal(t, clk.Now().Add(time.Minute), allowedAt)
+
+	clk.Advance(time.Minute)
+	ok, _ = guard.CanAutoJoin(1)
+	require.True(t, ok)
+
+	guard.RecordDisconnect(1)
+	ok, allowedAt = guard.CanAutoJoin(1)
+	require.False(t, ok)
+	require.Equal(t, clk.Now().Add(2*time.Minute), allowedAt, "the delay doubles")
+
+	guard.RecordDisconnect(1)
+	ok, allowedAt = guard.CanAutoJoin(1)
+	require.False(t, ok)
+	require.True(t, allowedAt.IsZero(), "disabled until a manual join")
+
+	guard.OnCreated(SessionCreatedEvent{sessionState: sessionState{GuildID: 1}})
+	ok, _ = guard.CanAutoJoin(1)
+	require.True(t, ok)
+}
@@ -2,19 +2,28 @@ package session
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/disgoorg/disgo/bot"
 	"github.com/disgoorg/disgo/cache"
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/disgo/rest"
 	"github.com/disgoorg/disgo/voice"
 	"github.com/disgoorg/snowflake/v2"
+	"github.com/google/uuid"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/logging"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
 )
 
@@ -28,235 +37,784 @@ const (
 	LeaveResultClose
 )
 
+// CloseReason tells why a session was closed. It is logged and shown in the leave embed.
+type CloseReason string
+
+const (
+	// CloseReasonLeave means a member stopped the session, e.g. with /leave.
+	CloseReasonLeave CloseReason = "leave"
+	// CloseReasonEmpty means everyone left the voice channel.
+	CloseReasonEmpty CloseReason = "empty"
+	// CloseReasonTakeover means the session was replaced by one in another voice channel.
+	CloseReasonTakeover CloseReason = "takeover"
+	// CloseReasonGuildRemoved means the bot was removed from the guild.
+	CloseReasonGuildRemoved CloseReason = "guild_removed"
+	// CloseReasonDisconnected means the bot was disconnected from the voice channel, e.g. by a moderator.
+	CloseReasonDisconnected CloseReason = "disconnected"
+	// CloseReasonDuplicate means the guild already had a session in another voice channel.
+	CloseReasonDuplicate CloseReason = "duplicate"
+)
+
+// Description returns the localized description of the reason.
+func (r CloseReason) Description(tr i18n.TextResource) string {
+	switch r {
+	case CloseReasonLeave:
+		return tr.Generic.TTS.CloseReason.Leave
+	case CloseReasonEmpty:
+		return tr.Generic.TTS.CloseReason.Empty
+	case CloseReasonTakeover:
+		return tr.Generic.TTS.CloseReason.Takeover
+	case CloseReasonGuildRemoved:
+		return tr.Generic.TTS.CloseReason.GuildRemoved
+	case CloseReasonDisconnected:
+		return tr.Generic.TTS.CloseReason.Disconnected
+	case CloseReasonDuplicate:
+		return tr.Generic.TTS.CloseReason.Duplicate
+	default:
+		return string(r)
+	}
+}
+
 type Session struct {
-	engineRegistry *tts.EngineRegistry
-	presetResolver preset.PresetResolver
+	id     string
+	logger *slog.Logger
+	// synthesisLogger logs the per-message synthesis path, which is sampled separately.
+	synthesisLogger *slog.Logger
+	engineRegistry  *tts.EngineRegistry
+	presetResolver  preset.PresetResolver
+	settings        settings.GuildSettingsRepository
+	members         *MemberResolver
+	decoder         Decoder
+	// frameCache caches the opus frames of announcements. It is nil when caching is disabled.
+	frameCache *OpusFrameCache
+	// budget bounds the synthesized speech queued for playback.
+	budget *audioBudget
+	// usage counts the characters the guild synthesizes against its monthly budget. It is nil when usage is not tracked.
+	usage          *tts.UsageTracker
+	voiceAssigner  *preset.VoiceAssigner
+	guildID        snowflake.ID
 	textChannelID  snowflake.ID
 	conn           voice.Conn
 	voiceResources *i18n.VoiceResources
 	textResource   *i18n.TextResource
+	clock          clock.Clock
 
-	taskQueue  chan<- SpeechTask
-	stopWorker chan struct{}
+	taskQueue     *taskQueue
+	stopWorker    chan struct{}
+	announcements *announcementCoalescer
+	// transcript posts the spoken lines into the transcript channel of the guild. It is nil without a REST client.
+	transcript *transcript
+	player     atomic.Pointer[trackPlayer]
+	monitor    atomic.Pointer[playbackMonitor]
+	prefixer   speakerPrefixer
+	// closing is set once the session says farewell, so that no more messages are read.
+	closing atomic.Bool
+	// muted is set while the bot is server-muted, so that nothing is read into the void.
+	muted atomic.Bool
+	// memberCache tells the bots in the voice channel apart. It is nil without a gateway cache, which never yields to bots.
+	memberCache cache.MemberCache
+	// activity records who transmits in the voice channel.
+	activity *voiceActivity
+	// stats counts what the session read, for the summary shown when it is closed.
+	stats *sessionStats
+	// yieldToBots mirrors the setting of the guild, refreshed whenever the settings are fetched,
+	// as playback checks it every frame. yielding is set while playback is held back for a bot.
+	yieldToBots atomic.Bool
+	yielding    atomic.Bool
+	// bargeIn mirrors the barge-in of the guild likewise, holding a settings.BargeIn.
+	// bargingIn is set while a member speaks over playback.
+	bargeIn   atomic.Value
+	bargingIn atomic.Bool
+	// closeReason is set to the reason of the first call to Close, so that the session is closed only once.
+	// It is set before the session is torn down, as Delete reads it on the goroutine of any caller.
+	closeReason atomic.Pointer[CloseReason]
 }
 
-func New(engineRegistry *tts.EngineRegistry, presetResolver preset.PresetResolver, textChannelID snowflake.ID, conn voice.Conn, tr *i18n.TextResource, vrs *i18n.VoiceResources) (*Session, error) {
-	queue := make(chan SpeechTask, 10)
+// maxSegmentLength is the maximum number of characters synthesized in a single engine request.
+// Longer messages are split at sentence boundaries, which also lets playback start before the whole message is synthesized.
+const maxSegmentLength = 200
+
+// fallbackAfter is how long the engine of a preset may take before its fallback engine is tried instead,
+// leaving the rest of the timeout of a segment to the fallback.
+const fallbackAfter = 5 * time.Second
+
+// farewellTimeout is how long the farewell may take before the session is closed anyway.
+const farewellTimeout = 5 * time.Second
+
+// synthesisConcurrency is the most segments of a task synthesized at once.
+const synthesisConcurrency = 3
+
+// announcementWindow is how long join/leave cues are collected before being announced together.
+const announcementWindow = 1500 * time.Millisecond
+
+func New(engineRegistry *tts.EngineRegistry, presetResolver preset.PresetResolver, settingsRepository settings.GuildSettingsRepository, members *MemberResolver, decoder Decoder, frameCache *OpusFrameCache, maxQueuedAudio int64, usage *tts.UsageTracker, textChannelID snowflake.ID, conn voice.Conn, channels rest.Channels, memberCache cache.MemberCache, tr *i18n.TextResource, vrs *i18n.VoiceResources) (*Session, error) {
+	queue := newTaskQueue(10)
 	stopWorker := make(chan struct{})
+	id := uuid.NewString()
 	session := &Session{
+		id:             id,
+		logger:         newSessionLogger(slog.Default(), id, conn.GuildID(), conn.ChannelID(), textChannelID),
 		engineRegistry: engineRegistry,
 		presetResolver: presetResolver,
+		settings:       settingsRepository,
+		members:        members,
+		decoder:        decoder,
+		frameCache:     frameCache,
+		budget:         newAudioBudget(maxQueuedAudio),
+		usage:          usage,
+		voiceAssigner:  preset.NewVoiceAssigner(),
+		guildID:        conn.GuildID(),
 		textChannelID:  textChannelID,
 		conn:           conn,
 		voiceResources: vrs,
 		textResource:   tr,
 		taskQueue:      queue,
 		stopWorker:     stopWorker,
+		clock:          clock.Real,
+		memberCache:    memberCache,
 	}
+	session.activity = newVoiceActivity(session.clock)
+	session.stats = newSessionStats(session.clock.Now())
+
+	session.announcements = newAnnouncementCoalescer(session.clock, announcementWindow, session.announce)
+	if channels != nil {
+		session.transcript = newTranscript(session.clock, transcriptWindow, channels, session.transcriptChannelID, session.logger)
+	}
+
+	session.synthesisLogger = session.logger.With(logging.Component(logging.ComponentSynthesis))
 
 	go session.worker(queue, stopWorker)
 
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		preset, err := presetResolver.ResolveGuildPreset(ctx, conn.GuildID())
+		guildSettings, err := settings.FindOrDefault(ctx, settingsRepository, session.guildID)
+		if err != nil {
+			session.logger.Error("Failed to fetch guild settings", slog.Any("err", err))
+		} else {
+			session.yieldToBots.Store(guildSettings.YieldToBots)
+			session.bargeIn.Store(guildSettings.BargeIn)
+			if !guildSettings.AnnounceLaunch {
+				return
+			}
+		}
+
+		preset, err := presetResolver.ResolveGuildPreset(ctx, session.guildID)
 		if err != nil {
-			slog.Error("Failed to resolve preset for session", slog.Any("err", err), slog.String("guildID", conn.GuildID().String()))
+			session.logger.Error("Failed to resolve preset for session", slog.Any("err", err))
 			return
 		}
 
-		vr, ok := vrs.GetOrGeneric(preset.Language)
+		vr, ok := session.voiceResource(guildSettings, preset.Language)
 		if !ok {
-			slog.Warn("Voice resources not found for locale", "locale", preset.Language)
+			session.logger.Warn("Voice resources not found for locale", "locale", preset.Language)
 			return
 		}
 
-		segments := []string{vr.Session.Launch}
+		launch := vr.Session.Launch
+		if template, ok := guildSettings.Announcement(settings.AnnouncementKeyLaunch, ""); ok {
+			launch = template
+		}
+		segments := []string{launch}
 		session.enqueueSpeechTask(ctx, NewSpeechTask(segments, preset))
 	}()
 
 	return session, nil
 }
 
-func (s *Session) Close(ctx context.Context) {
+// ID returns the unique ID of the session, used to correlate its logs.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// GuildID returns the ID of the guild the session belongs to.
+func (s *Session) GuildID() snowflake.ID {
+	return s.guildID
+}
+
+// TextChannelID returns the ID of the text channel the session reads.
+func (s *Session) TextChannelID() snowflake.ID {
+	return s.textChannelID
+}
+
+// VoiceChannelID returns the ID of the voice channel the session speaks in.
+// It returns 0 if the voice connection is not open.
+func (s *Session) VoiceChannelID() snowflake.ID {
+	if channelID := s.conn.ChannelID(); channelID != nil {
+		return *channelID
+	}
+	return 0
+}
+
+// PlaybackHealth returns the frame and underrun counters of the session, or false if it is not playing yet.
+func (s *Session) PlaybackHealth() (PlaybackHealth, bool) {
+	monitor := s.monitor.Load()
+	if monitor == nil {
+		return PlaybackHealth{}, false
+	}
+	return monitor.Health(), true
+}
+
+// Summary summarizes what the session read so far, for the embed sent when it is closed.
+func (s *Session) Summary() message.SessionSummary {
+	return s.stats.summary(s.clock.Now())
+}
+
+// Skip stops reading the current message and reports whether one was being read.
+func (s *Session) Skip() bool {
+	player := s.player.Load()
+	if player == nil {
+		return false
+	}
+	s.logger.Info("Skipping current track")
+	return player.skip()
+}
+
+// Farewell speaks the farewell phrase if the guild enabled it, and waits until it has been played.
+// Messages received from then on are not read. Call it right before Close, so that the farewell is not cut off.
+func (s *Session) Farewell(ctx context.Context) {
+	if s.closing.Swap(true) || s.muted.Load() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, farewellTimeout)
+	defer cancel()
+	guildSettings, err := settings.FindOrDefault(ctx, s.settings, s.guildID)
+	if err != nil {
+		s.logger.Error("Failed to fetch guild settings", slog.Any("err", err))
+		return
+	}
+	if !guildSettings.AnnounceFarewell {
+		return
+	}
+
+	preset, err := s.presetResolver.ResolveGuildPreset(ctx, s.guildID)
+	if err != nil {
+		s.logger.Error("Failed to resolve preset for farewell", slog.Any("err", err))
+		return
+	}
+	vr, ok := s.voiceResource(guildSettings, preset.Language)
+	if !ok {
+		s.logger.Warn("Voice resources not found for locale", "locale", preset.Language)
+		return
+	}
+
+	task := NewSpeechTask([]string{vr.Session.Farewell}, preset)
+	task.played = make(chan struct{})
+	if !s.queueSpeechTask(ctx, task) {
+		return
+	}
+	select {
+	case <-task.played:
+		s.logger.Info("Played farewell")
+	case <-ctx.Done():
+		s.logger.Warn("Farewell was not played in time, closing anyway")
+	}
+}
+
+// Close disconnects the session and stops reading. The reason is logged.
+// Closing a session more than once has no effect.
+func (s *Session) Close(ctx context.Context, reason CloseReason) {
+	if !s.closeReason.CompareAndSwap(nil, &reason) {
+		return
+	}
+	s.logger.Info("Closing session", slog.String("reason", string(reason)))
+	s.announcements.stop()
+	if s.transcript != nil {
+		s.transcript.stop()
+	}
 	s.conn.Close(ctx)
 	close(s.stopWorker)
-	close(s.taskQueue)
+	s.taskQueue.close()
 }
 
-func (s *Session) worker(queue <-chan SpeechTask, stopWorker <-chan struct{}) {
+func (s *Session) worker(queue *taskQueue, stopWorker <-chan struct{}) {
 	trackClose := make(chan struct{})
-	audioQueue := make(chan *tts.SpeechResponse, 10)
-	trackPlayer, err := newTrackPlayer(s.conn, audioQueue, trackClose)
-	lastSpeakerID := snowflake.ID(0)
-	s.conn.SetOpusFrameProvider(trackPlayer)
+	audioQueue := make(chan track, 10)
+	trackPlayer, err := newTrackPlayer(s.conn, s.decoder, s.frameCache, s.budget, audioQueue, trackClose, s.logger)
 	if err != nil {
-		slog.Error("Failed to create track player", slog.Any("err", err))
+		s.logger.Error("Failed to create track player", slog.Any("err", err))
 		return
 	}
-	slog.Info("Session worker started", "textChannelID", s.textChannelID, "voiceChannelID", s.conn.ChannelID())
+	if s.transcript != nil {
+		trackPlayer.onPlay = s.transcript.add
+	}
+	trackPlayer.hold = func() bool {
+		return s.yieldsToBot() || s.bargedIn(settings.BargeInPause)
+	}
+	trackPlayer.duck = func() bool {
+		return s.bargedIn(settings.BargeInDuck)
+	}
+	s.conn.SetOpusFrameReceiver(s.activity)
+	monitor := newPlaybackMonitor(trackPlayer, func() bool {
+		return trackPlayer.playing.Load() && !trackPlayer.Paused() && !trackPlayer.held()
+	}, s.logger)
+	s.conn.SetOpusFrameProvider(monitor)
+	s.player.Store(trackPlayer)
+	// the bot may have been muted before the player was started.
+	trackPlayer.SetPaused(s.muted.Load())
+	s.monitor.Store(monitor)
+	s.logger.Info("Session worker started")
 	for {
 		select {
 		case <-stopWorker:
-			slog.Info("Stopping session worker")
+			s.logger.Info("Stopping session worker")
 			return
 
-		case task := <-queue:
-			if task.ContainsSpeaker && task.SpeakerID != lastSpeakerID {
-				task.Segments = append([]string{task.SpeakerName}, task.Segments...)
-				lastSpeakerID = task.SpeakerID
+		case <-queue.ready:
+			task, ok := queue.pop()
+			if !ok {
+				continue
+			}
+			if s.transcript != nil {
+				// the line is taken before the speaker name is prefixed, as the transcript always shows it.
+				task.transcript = transcriptLine(task)
 			}
-			s.processTask(task, audioQueue)
+			s.processTask(s.prefixer.apply(task), audioQueue)
+		}
+	}
+}
+
+// yieldsToBot reports whether playback is held back, as the guild yields to bots and one of them is transmitting.
+// It is called for every frame, so it only looks at the gateway cache.
+func (s *Session) yieldsToBot() bool {
+	yielding := s.memberCache != nil && s.yieldToBots.Load() && s.activity.transmitting(botQuietAfter, func(userID snowflake.ID) bool {
+		member, ok := s.memberCache.Member(s.guildID, userID)
+		return ok && member.User.Bot
+	})
+	if s.yielding.Swap(yielding) != yielding {
+		if yielding {
+			s.logger.Info("Another bot is transmitting, holding back playback")
+		} else {
+			s.logger.Info("Other bots went quiet, resuming playback")
+		}
+	}
+	return yielding
+}
+
+// bargedIn reports whether the guild makes way for speaking members with the barge-in and one of them is speaking.
+// Users missing from the gateway cache are taken for members, so that reading does not talk over them.
+func (s *Session) bargedIn(bargeIn settings.BargeIn) bool {
+	if current, _ := s.bargeIn.Load().(settings.BargeIn); current != bargeIn {
+		return false
+	}
+	speaking := s.activity.transmitting(memberQuietAfter, func(userID snowflake.ID) bool {
+		if s.memberCache == nil {
+			return true
+		}
+		member, ok := s.memberCache.Member(s.guildID, userID)
+		return !ok || !member.User.Bot
+	})
+	if s.bargingIn.Swap(speaking) != speaking {
+		if speaking {
+			s.logger.Info("A member is speaking, making way", slog.String("barge_in", bargeIn.String()))
+		} else {
+			s.logger.Info("Members went quiet, resuming playback")
 		}
 	}
+	return speaking
 }
 
-func (s *Session) processTask(task SpeechTask, audioQueue chan<- *tts.SpeechResponse) {
-	slog.Info("Processing speech task", "content", task.Segments, "preset", task.Preset.Identifier)
+func (s *Session) processTask(task SpeechTask, audioQueue chan<- track) {
+	taskCtx := logging.WithCorrelationID(context.Background(), task.ID)
+	s.synthesisLogger.InfoContext(taskCtx, "Processing speech task", "content", task.Segments, "preset", task.Preset.Identifier)
+
+	// the transcript line is posted once the first segment of the task starts playing.
+	line := task.transcript
+	// segments are synthesized concurrently, but played in order. segments that failed are passed over.
+	// speech is counted in the budget as it is queued for playback, so that speech waiting for the segments before it
+	// does not keep the budget from being freed. once the session stops, speech is closed instead, as nothing plays it.
+	stopped := false
+	pool := newOrderedPool(synthesisConcurrency, func(t *track) {
+		if t == nil {
+			return
+		}
+		if !stopped {
+			t.transcript, line = line, ""
+			s.budget.add(t.size)
+			select {
+			case audioQueue <- *t:
+				return
+			case <-s.stopWorker:
+				stopped = true
+				s.budget.release(t.size)
+			}
+		}
+		if t.speech != nil {
+			t.speech.Close()
+		}
+	})
+	for i, segment := range task.Segments {
+		// speakers of multi-voice presets are told apart by their voices. announcements keep the first voice.
+		preset := task.presetOf(i)
+		ssml := preset.AnnouncementSSML
+		if task.ContainsSpeaker {
+			preset = s.voiceAssigner.Assign(preset, task.SpeakerID)
+			ssml = preset.MessageSSML
+		}
 
-	for _, segment := range task.Segments {
 		if segment == "" {
-			slog.Warn("Skipping empty segment in speech task", "preset", task.Preset.Identifier)
+			s.synthesisLogger.WarnContext(taskCtx, "Skipping empty segment in speech task", "preset", task.Preset.Identifier)
 			continue
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
+		// synthesis waits while too much speech is queued, so that the memory it holds stays bounded.
+		ok, waited := s.budget.wait(s.stopWorker)
+		if !ok {
+			stopped = true
+			break
+		}
+		if waited {
+			s.synthesisLogger.WarnContext(taskCtx, "Synthesis waited for playback, since the queued speech exceeded its budget")
+		}
 
-		resp, err := s.performTextToSpeech(ctx, segment, task.Preset)
-		if err != nil {
-			slog.Error("Failed to perform text-to-speech", slog.Any("err", err), slog.String("content", segment))
-			continue
+		// announcements repeat the same few phrases, so their frames are played from the cache when possible.
+		var cacheKey string
+		if !task.ContainsSpeaker && s.frameCache != nil {
+			cacheKey = frameCacheKey(preset, ssml, segment)
+			if frames, ok := s.frameCache.Get(cacheKey); ok {
+				s.synthesisLogger.DebugContext(taskCtx, "Playing cached announcement", "content", segment)
+				pool.run(func() *track {
+					return &track{frames: frames}
+				})
+				continue
+			}
+		}
+
+		pool.run(func() *track {
+			ctx, cancel := context.WithTimeout(taskCtx, 10*time.Second)
+			defer cancel()
+			resp, err := s.performTextToSpeech(ctx, segment, preset, ssml)
+			if err != nil {
+				s.synthesisLogger.ErrorContext(ctx, "Failed to perform text-to-speech", slog.Any("err", err), slog.String("content", segment))
+				return nil
+			}
+
+			s.synthesisLogger.InfoContext(ctx, "Successfully synthesized speech for segment", "content", segment)
+			// the usage is charged to the engine whose audio is played.
+			engine := preset.Engine
+			if resp.Fallback != nil {
+				engine = preset.FallbackEngine
+			}
+			s.recordUsage(ctx, engine, segment)
+			s.stats.addCharacters(utf8.RuneCountInString(segment))
+			return &track{speech: resp, size: resp.Size, cacheKey: cacheKey}
+		})
+	}
+	pool.wait()
+
+	if task.played == nil {
+		return
+	}
+	if !stopped {
+		select {
+		case audioQueue <- track{played: task.played}:
+			return
+		case <-s.stopWorker:
 		}
+	}
+	// the task will never be played, so whoever waits for it is let go.
+	close(task.played)
+}
 
-		slog.Info("Successfully synthesized speech for segment", "content", segment)
-		audioQueue <- resp
+// recordUsage counts the characters of the segment towards the usage of the guild,
+// and tells the listeners once the guild has used most of its monthly budget.
+func (s *Session) recordUsage(ctx context.Context, engine, segment string) {
+	if s.usage == nil {
+		return
+	}
+	low, err := s.usage.Record(ctx, s.guildID, engine, utf8.RuneCountInString(segment))
+	if err != nil {
+		s.synthesisLogger.WarnContext(ctx, "Failed to record usage", slog.Any("err", err))
+		return
+	}
+	if low {
+		s.logger.Info("Guild has used most of its monthly character budget")
+		go s.announceCue(func(vr i18n.VoiceResource) string {
+			return fmt.Sprintf(vr.Cue.QuotaLow, int(tts.UsageWarningRatio*100))
+		})
 	}
 }
 
-func (s *Session) performTextToSpeech(ctx context.Context, content string, preset preset.Preset) (*tts.SpeechResponse, error) {
-	slog.Info("Request speech", "content", content)
-	start := time.Now()
+// performTextToSpeech synthesizes the content with the preset. The content is wrapped in the SSML template if it is not nil.
+func (s *Session) performTextToSpeech(ctx context.Context, content string, preset preset.Preset, ssml *preset.SSMLTemplate) (*tts.SpeechResponse, error) {
+	s.synthesisLogger.InfoContext(ctx, "Request speech", "content", content)
 	engine, ok := s.engineRegistry.Get(preset.Engine)
 
 	if !ok {
-		slog.Error("TTS engine not found", slog.String("engine", preset.Engine), slog.String("content", content))
+		s.synthesisLogger.ErrorContext(ctx, "TTS engine not found", slog.String("engine", preset.Engine), slog.String("content", content))
 		return nil, fmt.Errorf("TTS engine %s not found", preset.Engine)
 	}
 
+	if preset.FallbackEngine != "" {
+		if fallback, ok := s.engineRegistry.Get(preset.FallbackEngine); ok {
+			engine = tts.NewFallbackEngine(engine, fallbackAfter, tts.Fallback{Engine: fallback, VoiceName: preset.FallbackVoiceName})
+		}
+	}
+
+	// text the engine does not accept fails with a clear error, instead of the error of the engine's API.
+	if capabilities, ok := tts.CapabilitiesOf(engine); ok {
+		if err := capabilities.CheckText(content); err != nil {
+			s.synthesisLogger.ErrorContext(ctx, "Text is too long for the engine", slog.String("engine", preset.Engine), slog.Any("err", err))
+			return nil, err
+		}
+	}
+
+	// keep the rate in the range of the engine, so that an out of range value does not fail the request.
+	speakingRate, clamped := tts.ClampSpeakingRate(engine, preset.SpeakingRate)
+	if clamped {
+		s.synthesisLogger.WarnContext(ctx, "Speaking rate is out of the range supported by the engine, clamping it", slog.String("engine", preset.Engine), slog.Float64("speakingRate", preset.SpeakingRate), slog.Float64("clampedTo", speakingRate))
+	}
+
 	speechRequest := tts.SpeechRequest{
 		Text:         content,
+		InputKind:    tts.InputKindText,
 		LanguageCode: preset.Language,
 		VoiceName:    preset.VoiceName,
-		SpeakingRate: preset.SpeakingRate,
+		SpeakingRate: speakingRate,
+		Pitch:        preset.Pitch,
+		VolumeGainDb: preset.VolumeGainDb,
+	}
+	if ssml != nil {
+		rendered, err := ssml.Render(content)
+		if err != nil {
+			s.synthesisLogger.WarnContext(ctx, "Failed to render SSML, reading as plain text", slog.Any("err", err), slog.String("preset", string(preset.Identifier)))
+		} else {
+			speechRequest.Text = rendered
+			speechRequest.InputKind = tts.InputKindSSML
+		}
 	}
 
 	audioConent, err := engine.GenerateSpeech(ctx, speechRequest)
 
 	if err != nil {
-		slog.Error("Failed to synthesize speech", slog.Any("err", err), slog.String("content", content))
+		s.synthesisLogger.ErrorContext(ctx, "Failed to synthesize speech", slog.Any("err", err), slog.String("content", content))
 		return nil, fmt.Errorf("failed to synthesize speech: %w", err)
 	}
-	end := time.Now()
-	slog.Info("Successfully synthesized speech", "duration", end.Sub(start))
-	slog.Info("Playing audio in voice channel", "guildID", s.conn.GuildID(), "channelID", s.conn.ChannelID())
+	s.synthesisLogger.InfoContext(ctx, "Successfully synthesized speech")
+	s.synthesisLogger.InfoContext(ctx, "Playing audio in voice channel")
 
 	return audioConent, nil
 }
 
-func (s *Session) enqueueSpeechTask(ctx context.Context, task SpeechTask) {
+// enqueueSpeechTask queues the task for the worker and reports whether it was queued.
+// Tasks are no longer queued once the session said farewell.
+func (s *Session) enqueueSpeechTask(ctx context.Context, task SpeechTask) bool {
+	if s.closing.Load() {
+		s.synthesisLogger.Debug("Session is closing, not enqueuing task", slog.Any("segments", task.Segments))
+		return false
+	}
+	if s.muted.Load() {
+		s.synthesisLogger.Debug("Bot is server-muted, not enqueuing task", slog.Any("segments", task.Segments))
+		return false
+	}
+	return s.queueSpeechTask(ctx, task)
+}
+
+func (s *Session) queueSpeechTask(ctx context.Context, task SpeechTask) bool {
 	if len(task.Segments) == 0 {
-		slog.Warn("Skipping empty speech task", "preset", task.Preset.Identifier)
-		return
+		s.logger.Warn("Skipping empty speech task", "preset", task.Preset.Identifier)
+		return false
 	}
 
-	slog := slog.With(slog.Attr{Key: "segments", Value: slog.AnyValue(task.Segments)}, slog.Attr{Key: "preset", Value: slog.StringValue(string(task.Preset.Identifier))})
+	logger := s.synthesisLogger.With(slog.String(logging.CorrelationIDKey, task.ID), slog.Any("segments", task.Segments), slog.String("preset", string(task.Preset.Identifier)))
 	select {
 	case <-ctx.Done():
-		slog.Warn("Context cancelled, not enqueuing task")
-		return
+		logger.Warn("Context cancelled, not enqueuing task")
+		return false
 	case <-s.stopWorker:
-		slog.Warn("Session worker stopped, not enqueuing task")
-		return
+		logger.Warn("Session worker stopped, not enqueuing task")
+		return false
 	default:
 	}
 
-	select {
-	case s.taskQueue <- task:
-		slog.Debug("Enqueued speech task")
-	default:
-		slog.Warn("Task queue is full, dropping task")
+	if !s.taskQueue.push(task) {
+		logger.Warn("Task queue is full, dropping task")
+		return false
 	}
+	logger.Debug("Enqueued speech task")
+	return true
 }
 
-func (s *Session) onMessageCreate(event *events.MessageCreate) {
+func (s *Session) onMessageCreate(event *events.MessageCreate, textCommands func(name string) (TextCommandHandler, bool)) {
 	// ignore messages from other channels or from bots
 	if event.Message.Author.Bot {
 		return
 	}
 
-	slog.Debug("Received message for TTS", "messageID", event.Message.ID, "content", event.Message.Content)
+	s.synthesisLogger.Debug("Received message for TTS", "messageID", event.Message.ID, "content", event.Message.Content)
 
-	member, err := event.Client().Rest().GetMember(*event.GuildID, event.Message.Author.ID)
-	if err != nil {
-		slog.Error("Failed to get member for message author", slog.Any("err", err), slog.String("userID", event.Message.Author.ID.String()))
-		return
+	// the gateway attaches the author's member to the message, which saves a REST request.
+	if event.Message.Member != nil {
+		member := *event.Message.Member
+		member.User = event.Message.Author
+		s.members.Put(member)
 	}
 
-	mentions := createIdToNameMap(event.Client(), *event.GuildID, event.Message.Mentions)
-
-	// make the content safe and ready for TTS.
-	content := event.Message.Content
-	content = message.ReplaceUserMentions(content, mentions)
-	content = message.ReplaceEmojis(content)
-	content = message.ReplaceUrlsWithPlaceholders(content)
-	content = message.ConvertMarkdownToPlainText(content)
-	content = message.LimitContentLength(content, 300)
-
-	segments := make([]string, 0)
-	segments = append(segments, content)
-
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-		preset, err := s.presetResolver.Resolve(ctx, *event.GuildID, event.Message.Author.ID)
+		guildSettings, err := settings.FindOrDefault(ctx, s.settings, s.guildID)
 		if err != nil {
-			slog.Error("Failed to resolve preset", slog.Any("err", err), slog.String("content", content))
+			s.logger.Error("Failed to fetch guild settings", slog.Any("err", err))
 			return
 		}
+		s.yieldToBots.Store(guildSettings.YieldToBots)
+		s.bargeIn.Store(guildSettings.BargeIn)
 
-		segments = func() []string {
-			attachmentsCount := len(event.Message.Attachments)
-			if attachmentsCount == 0 {
-				return segments
+		// text commands are handled instead of being read.
+		if command, ok := ParseTextCommand(event.Message.Content, guildSettings.CommandPrefix); ok {
+			if handler, ok := textCommands(command.Name); ok {
+				s.logger.Info("Handling text command", "command", command.Name, "userID", event.Message.Author.ID)
+				if err := handler(&TextCommandEvent{MessageCreate: event, Session: s, Command: command, TextResource: *s.textResource}); err != nil {
+					s.logger.Error("Failed to handle text command", slog.Any("err", err), slog.String("command", command.Name))
+				}
+				return
 			}
-			vr, ok := s.voiceResources.GetOrGeneric(preset.Language)
-			if !ok {
-				slog.Warn("Voice resources not found for locale", "locale", preset.Language)
-				return segments
+		}
+
+		member, err := s.members.Resolve(ctx, func(guildID, userID snowflake.ID) (*discord.Member, error) {
+			return event.Client().Rest().GetMember(guildID, userID)
+		}, *event.GuildID, event.Message.Author.ID)
+		if err != nil {
+			s.logger.Error("Failed to get member for message author", slog.Any("err", err), slog.String("userID", event.Message.Author.ID.String()))
+			return
+		}
+
+		preset, err := s.presetResolver.ResolveContext(ctx, preset.ResolutionContext{
+			GuildID:   *event.GuildID,
+			ChannelID: event.ChannelID,
+			UserID:    event.Message.Author.ID,
+			RoleIDs:   member.RoleIDs,
+		})
+		if err != nil {
+			s.logger.Error("Failed to resolve preset", slog.Any("err", err), slog.String("content", event.Message.Content))
+			return
+		}
+		preset, content := ApplyVoiceTag(s.presetResolver, guildSettings, preset, event.Message.Content)
+
+		vr, ok := s.voiceResource(guildSettings, preset.Language)
+		if !ok {
+			s.logger.Warn("Voice resources not found for locale", "locale", preset.Language)
+		}
+
+		// make the content safe and ready for TTS.
+		mentions := MentionNames(event.Client(), s.members, guildSettings, event.Message.Mentions)
+		transformed := TransformMessage(content, guildSettings, vr, mentions, s.clock.Now())
+		segments, segmentPresets := CodeSwitch(s.presetResolver, guildSettings, preset, transformed.Segments)
+		segments, segmentPresets = SoftenAsides(s.engineRegistry, guildSettings, preset, segments, segmentPresets)
+
+		// append the number of attachments to the segments
+		if attachmentsCount := len(event.Message.Attachments); attachmentsCount > 0 && ok {
+			segments = append(segments, fmt.Sprintf(vr.Session.Attachments, attachmentsCount))
+		}
+		if transformed.Truncated && ok {
+			segments = append(segments, vr.Cue.Truncated)
+		}
+		if len(segments) == 0 {
+			// nothing readable, e.g. a message with only whitespace.
+			return
+		}
+		if s.usage != nil {
+			characters := 0
+			for _, segment := range segments {
+				characters += utf8.RuneCountInString(segment)
+			}
+			if err := s.usage.Check(ctx, s.guildID, characters); errors.Is(err, tts.ErrBudgetExceeded) {
+				s.notifySkipped(ctx, event.Client(), event.ChannelID, event.MessageID, skipReasonBudgetExceeded)
+				return
+			} else if err != nil {
+				s.logger.Warn("Failed to check the character budget, reading anyway", slog.Any("err", err))
 			}
-			// append the number of attachments to the segments
-			attachmentsMessage := fmt.Sprintf(vr.Session.Attachments, attachmentsCount)
-			return append(segments, attachmentsMessage)
-		}()
+		}
 
-		s.enqueueSpeechTask(ctx, NewSpeechTask(segments, preset, WithSpeaker(member.EffectiveName(), member.User.ID)))
-		slog.Info("Enqueued speech task", "content", content, "preset", preset.Identifier)
+		if !s.enqueueSpeechTask(ctx, NewSpeechTask(segments, preset, WithSpeaker(speakerName(member, guildSettings), member.User.ID), WithSegmentPresets(segmentPresets), WithSource(event.ChannelID))) {
+			s.notifySkipped(ctx, event.Client(), event.ChannelID, event.MessageID, skipReasonQueueFull)
+			return
+		}
+		s.synthesisLogger.Info("Enqueued speech task", "content", segments, "preset", preset.Identifier)
+		s.stats.addMessage(member.User.ID)
+		if transformed.Truncated {
+			s.notifySkipped(ctx, event.Client(), event.ChannelID, event.MessageID, skipReasonTooLong)
+		}
+		if s.budget.takeNotice() {
+			s.logger.Warn("Reading lags behind, since the queued speech exceeds its budget")
+			if _, err := event.Client().Rest().CreateMessage(s.textChannelID, discord.NewMessageCreateBuilder().
+				AddEmbeds(message.BuildLaggingEmbed(*s.textResource).Build()).
+				Build(),
+			); err != nil {
+				s.logger.Warn("Failed to send lagging message", slog.Any("err", err))
+			}
+		}
 	}()
 }
 
-func createIdToNameMap(client bot.Client, guildID snowflake.ID, users []discord.User) map[snowflake.ID]string {
+// codeBlockFormatter returns the formatter reading code blocks according to the guild's code block mode.
+func codeBlockFormatter(mode settings.CodeBlockMode, vr i18n.VoiceResource) message.CodeBlockFormatter {
+	return func(language string, lines []string) string {
+		switch mode {
+		case settings.CodeBlockModeSkip:
+			return ""
+		case settings.CodeBlockModeFirstLine:
+			for _, line := range lines {
+				if line = strings.TrimSpace(line); line != "" {
+					return line
+				}
+			}
+			return ""
+		default:
+			if language == "" {
+				return vr.Session.CodeBlock
+			}
+			return fmt.Sprintf(vr.Session.CodeBlockLanguage, language)
+		}
+	}
+}
+
+type skipReason string
+
+const (
+	skipReasonQueueFull skipReason = "queue_full"
+	skipReasonTooLong   skipReason = "too_long"
+	// skipReasonBudgetExceeded is a message not read as the guild used up its monthly character budget.
+	skipReasonBudgetExceeded skipReason = "budget_exceeded"
+)
+
+// notifySkipped reacts to the message with the guild's skip reaction,
+// so that the author knows the message was not (fully) read.
+func (s *Session) notifySkipped(ctx context.Context, client bot.Client, channelID, messageID snowflake.ID, reason skipReason) {
+	s.logger.Info("Message was not fully read", "messageID", messageID, "reason", reason)
+
+	guildSettings, err := settings.FindOrDefault(ctx, s.settings, s.guildID)
+	if err != nil {
+		s.logger.Error("Failed to fetch guild settings", slog.Any("err", err))
+		return
+	}
+	if guildSettings.SkipReaction == "" {
+		return
+	}
+
+	if err := client.Rest().AddReaction(channelID, messageID, guildSettings.SkipReaction); err != nil {
+		s.logger.Warn("Failed to react to skipped message", slog.Any("err", err), slog.String("messageID", messageID.String()))
+	}
+}
+
+// MentionNames maps the mentioned users to the names read in place of their mentions.
+func MentionNames(client bot.Client, members *MemberResolver, guildSettings settings.GuildSettings, users []discord.User) map[snowflake.ID]string {
+	guildID := guildSettings.GuildID
 	mentions := make(map[snowflake.ID]string, len(users))
 	for _, user := range users {
 		// we should fetch meber information to get the effective name
 		// but to avoid unnecessary API calls, we can use the member cache.
 		member, ok := client.Caches().Member(guildID, user.ID)
 		if !ok {
-			slog.Warn("Member not found in cache for mention", "mentionID", user.ID)
-			mentions[user.ID] = user.EffectiveName()
-		} else {
-			mentions[user.ID] = member.EffectiveName()
+			member, ok = members.Cached(guildID, user.ID)
 		}
+		if !ok {
+			slog.Debug("Member not found in cache for mention", "mentionID", user.ID)
+			member = discord.Member{User: user}
+		}
+		mentions[user.ID] = speakerName(member, guildSettings)
 	}
 	return mentions
 }
@@ -264,66 +822,223 @@ func createIdToNameMap(client bot.Client, guildID snowflake.ID, users []discord.
 func (s *Session) onJoinVoiceChannel(event *events.GuildVoiceStateUpdate) {
 	voiceState := event.VoiceState
 	// notify someone joined the voice channel
-	slog.Info("User joined voice channel", "userID", voiceState.UserID, "guildID", voiceState.GuildID, "channelID", *voiceState.ChannelID)
+	s.logger.Info("User joined voice channel", "userID", voiceState.UserID)
+
+	go s.announceMember(announcementJoin, event.Member)
+}
+
+func (s *Session) onLeaveVoiceChannel(event *events.GuildVoiceStateUpdate) LeaveResult {
+	voiceState := event.OldVoiceState
 
-	// TODO: remove hardcoded message
+	// notify someone left the voice channel
+	s.logger.Info("User left voice channel", "userID", voiceState.UserID)
+
+	if isVoiceChannelEmpty(event.Client().ID(), event.Client().Caches(), voiceState.GuildID, *voiceState.ChannelID, voiceState.UserID) {
+		s.logger.Info("Voice channel is empty, closing session")
+		return LeaveResultClose
+	}
+
+	go s.announceMember(announcementLeave, event.Member)
+
+	return LeaveResultKeepAlive
+}
+
+// onVoiceStateChange announces streaming and stage changes of a member who stays in the voice channel.
+func (s *Session) onVoiceStateChange(event *events.GuildVoiceStateUpdate) {
+	if event.VoiceState.UserID == event.Client().ID() {
+		s.onOwnVoiceStateChange(event)
+		return
+	}
+
+	oldState, newState := event.OldVoiceState, event.VoiceState
+	var format func(vr i18n.VoiceResource) string
+	switch {
+	case !oldState.SelfStream && newState.SelfStream:
+		format = func(vr i18n.VoiceResource) string { return vr.Session.StreamStart }
+	case oldState.SelfStream && !newState.SelfStream:
+		format = func(vr i18n.VoiceResource) string { return vr.Session.StreamStop }
+	case oldState.Suppress && !newState.Suppress:
+		format = func(vr i18n.VoiceResource) string { return vr.Session.StageSpeak }
+	case !oldState.Suppress && newState.Suppress:
+		format = func(vr i18n.VoiceResource) string { return vr.Session.StageListen }
+	default:
+		return
+	}
+
+	member := event.Member
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		preset, err := s.presetResolver.ResolveGuildPreset(ctx, event.Member.GuildID)
+		guildSettings, err := settings.FindOrDefault(ctx, s.settings, s.guildID)
 		if err != nil {
-			slog.Error("Failed to resolve preset", slog.Any("err", err))
+			s.logger.Error("Failed to fetch guild settings", slog.Any("err", err))
+			return
+		}
+		if !guildSettings.AnnounceVoiceActivity || guildSettings.IsSilent(member.RoleIDs) {
+			return
+		}
+
+		preset, err := s.presetResolver.ResolveGuildPreset(ctx, s.guildID)
+		if err != nil {
+			s.logger.Error("Failed to resolve preset", slog.Any("err", err))
 			return
 		}
 
-		vr, ok := s.voiceResources.GetOrGeneric(preset.Language)
+		vr, ok := s.voiceResource(guildSettings, preset.Language)
 		if !ok {
-			slog.Warn("Voice resources not found for locale", "locale", preset.Language)
+			s.logger.Warn("Voice resources not found for locale", "locale", preset.Language)
 			return
 		}
+
 		segments := []string{
-			fmt.Sprintf(vr.Session.UserJoin, event.Member.EffectiveName()),
+			fmt.Sprintf(format(vr), speakerName(member, guildSettings)),
 		}
 
 		s.enqueueSpeechTask(ctx, NewSpeechTask(segments, preset))
 	}()
 }
 
-func (s *Session) onLeaveVoiceChannel(event *events.GuildVoiceStateUpdate) LeaveResult {
-	voiceState := event.OldVoiceState
+// onOwnVoiceStateChange pauses reading while the bot is server-muted and resumes it once unmuted,
+// notifying the reading channel of both.
+func (s *Session) onOwnVoiceStateChange(event *events.GuildVoiceStateUpdate) {
+	muted := event.VoiceState.GuildMute
+	if !s.setMuted(muted) {
+		return
+	}
 
-	// notify someone left the voice channel
-	slog.Info("User left voice channel", "userID", voiceState.UserID, "guildID", voiceState.GuildID, "channelID", *voiceState.ChannelID)
+	go func() {
+		if _, err := event.Client().Rest().CreateMessage(s.textChannelID, discord.NewMessageCreateBuilder().
+			AddEmbeds(message.BuildMutedEmbed(*s.textResource, muted).Build()).
+			Build(),
+		); err != nil {
+			s.logger.Warn("Failed to send muted message", slog.Any("err", err))
+		}
+	}()
+}
 
-	if isVoiceChannelEmpty(event.Client().ID(), event.Client().Caches(), voiceState.GuildID, *voiceState.ChannelID, voiceState.UserID) {
-		slog.Info("Voice channel is empty, closing session", "guildID", voiceState.GuildID, "channelID", *voiceState.ChannelID)
-		return LeaveResultClose
+// SyncVoiceState pauses reading if the bot is server-muted in its voice state.
+// It is called with the voice state the session is created in, as only changes of it are told by voice state updates.
+func (s *Session) SyncVoiceState(voiceState discord.VoiceState) {
+	s.setMuted(voiceState.GuildMute)
+}
+
+// setMuted pauses or resumes reading, and reports whether the bot was not already in that state.
+func (s *Session) setMuted(muted bool) bool {
+	if s.muted.Swap(muted) == muted {
+		return false
 	}
 
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-		defer cancel()
+	if muted {
+		s.logger.Warn("Bot was server-muted, pausing reading")
+	} else {
+		s.logger.Info("Bot was unmuted, resuming reading")
+	}
+	if player := s.player.Load(); player != nil {
+		player.SetPaused(muted)
+	}
+	return true
+}
 
-		preset, err := s.presetResolver.ResolveGuildPreset(ctx, event.Member.GuildID)
-		if err != nil {
-			slog.Error("Failed to resolve preset", slog.Any("err", err))
-			return
-		}
+// announceMember queues the join/leave cue of the member
+// unless the announcement is disabled for the guild or one of the member's roles is silent.
+func (s *Session) announceMember(kind announcementKind, member discord.Member) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
-		vr, ok := s.voiceResources.GetOrGeneric(preset.Language)
-		if !ok {
-			slog.Warn("Voice resources not found for locale", "locale", preset.Language)
-			return
+	guildSettings, err := settings.FindOrDefault(ctx, s.settings, s.guildID)
+	if err != nil {
+		s.logger.Error("Failed to fetch guild settings", slog.Any("err", err))
+	} else if guildSettings.IsSilent(member.RoleIDs) {
+		s.logger.Debug("Skipping announcement for member with silent role", "userID", member.User.ID)
+		return
+	} else if kind == announcementJoin && !guildSettings.AnnounceJoin || kind == announcementLeave && !guildSettings.AnnounceLeave {
+		return
+	}
+
+	s.announcements.add(kind, speakerName(member, guildSettings))
+}
+
+// announce enqueues a single announcement for the coalesced join/leave cues.
+func (s *Session) announce(kind announcementKind, names []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	preset, err := s.presetResolver.ResolveGuildPreset(ctx, s.guildID)
+	if err != nil {
+		s.logger.Error("Failed to resolve preset", slog.Any("err", err))
+		return
+	}
+
+	// without the settings, the announcement is spoken as shipped.
+	guildSettings, err := settings.FindOrDefault(ctx, s.settings, s.guildID)
+	if err != nil {
+		s.logger.Error("Failed to fetch guild settings", slog.Any("err", err))
+	}
+
+	vr, ok := s.voiceResource(guildSettings, preset.Language)
+	if !ok {
+		s.logger.Warn("Voice resources not found for locale", "locale", preset.Language)
+		return
+	}
+
+	var (
+		format string
+		key    settings.AnnouncementKey
+	)
+	switch kind {
+	case announcementJoin:
+		format, key = vr.Session.UserJoin, settings.AnnouncementKeyJoin
+		if len(names) > 1 {
+			format = vr.Session.UsersJoin
 		}
-		segments := []string{
-			fmt.Sprintf(vr.Session.UserLeave, event.Member.EffectiveName()),
+	case announcementLeave:
+		format, key = vr.Session.UserLeave, settings.AnnouncementKeyLeave
+		if len(names) > 1 {
+			format = vr.Session.UsersLeave
 		}
+	}
 
-		s.enqueueSpeechTask(ctx, NewSpeechTask(segments, preset))
-	}()
+	announcement := fmt.Sprintf(format, vr.JoinList(names))
+	// the template of the guild takes precedence over the voice resources, whichever language the preset speaks.
+	if template, ok := guildSettings.Announcement(key, vr.JoinList(names)); ok {
+		announcement = template
+	}
 
-	return LeaveResultKeepAlive
+	segments := []string{announcement}
+
+	s.enqueueSpeechTask(ctx, NewSpeechTask(segments, preset))
+}
+
+// announceCue enqueues a cue about the session itself, e.g. that it moved to another voice channel.
+func (s *Session) announceCue(cue func(vr i18n.VoiceResource) string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	preset, err := s.presetResolver.ResolveGuildPreset(ctx, s.guildID)
+	if err != nil {
+		s.logger.Error("Failed to resolve preset", slog.Any("err", err))
+		return
+	}
+
+	guildSettings, err := settings.FindOrDefault(ctx, s.settings, s.guildID)
+	if err != nil {
+		s.logger.Error("Failed to fetch guild settings", slog.Any("err", err))
+	}
+
+	vr, ok := s.voiceResource(guildSettings, preset.Language)
+	if !ok {
+		s.logger.Warn("Voice resources not found for locale", "locale", preset.Language)
+		return
+	}
+
+	s.enqueueSpeechTask(ctx, NewSpeechTask([]string{cue(vr)}, preset))
+}
+
+// voiceResource returns the voice resource of the language, themed by the voice packs the guild enabled
+// if one of them is active on the current day of the guild.
+func (s *Session) voiceResource(guildSettings settings.GuildSettings, language string) (i18n.VoiceResource, bool) {
+	return s.voiceResources.GetThemed(language, guildSettings.VoicePacks, s.clock.Now().In(guildSettings.Location()))
 }
 
 func isVoiceChannelEmpty(
@@ -356,5 +1071,5 @@ func isVoiceChannelEmpty(
 }
 
 func (s *Session) String() string {
-	return fmt.Sprintf("Session(textChannelID: %s, voiceChannelID: %s)", s.textChannelID, s.conn.ChannelID())
+	return fmt.Sprintf("Session(id: %s, guildID: %s, textChannelID: %s, voiceChannelID: %s)", s.id, s.guildID, s.textChannelID, s.conn.ChannelID())
 }
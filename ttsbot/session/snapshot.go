@@ -0,0 +1,101 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+// snapshotVersion is the version of the snapshot format, raised on incompatible changes.
+const snapshotVersion = 1
+
+var (
+	ErrSnapshotVersion  = errors.New("unsupported session snapshot version")
+	ErrSnapshotMismatch = errors.New("session snapshot belongs to another session")
+)
+
+// Snapshot is the state of a session handed over to the process replacing this one, e.g. on an in-place upgrade.
+// Unlike the record persisted in Redis, it also carries what makes the handover unnoticeable to the members.
+// It is encoded as JSON, so it can be passed through a file or a pipe.
+type Snapshot struct {
+	Version        int          `json:"version"`
+	SessionID      string       `json:"session_id"`
+	GuildID        snowflake.ID `json:"guild_id"`
+	VoiceChannelID snowflake.ID `json:"voice_channel_id"`
+	TextChannelID  snowflake.ID `json:"text_channel_id"`
+	// Settings are the guild settings the session was reading with, without the webhook URL,
+	// as the URL usually contains a token and the snapshot may be passed through a file.
+	Settings settings.GuildSettings `json:"settings"`
+	Queue    QueueDigest            `json:"queue"`
+	// LastSpeakerID is the member whose name was read last, so that their next message is read without it.
+	LastSpeakerID snowflake.ID `json:"last_speaker_id,omitempty"`
+	TakenAt       time.Time    `json:"taken_at"`
+}
+
+// QueueDigest summarizes what the session had not finished reading when the snapshot was taken.
+// The messages themselves are not handed over.
+type QueueDigest struct {
+	PendingTasks int  `json:"pending_tasks"`
+	Playing      bool `json:"playing"`
+}
+
+// Snapshot captures the state of the session.
+func (s *Session) Snapshot(ctx context.Context) (Snapshot, error) {
+	guildSettings, err := settings.FindOrDefault(ctx, s.settings, s.guildID)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to fetch guild settings: %w", err)
+	}
+	guildSettings.WebhookURL = ""
+
+	queue := QueueDigest{PendingTasks: s.taskQueue.len()}
+	if player := s.player.Load(); player != nil {
+		queue.Playing = player.playing.Load()
+	}
+
+	return Snapshot{
+		Version:        snapshotVersion,
+		SessionID:      s.id,
+		GuildID:        s.guildID,
+		VoiceChannelID: s.VoiceChannelID(),
+		TextChannelID:  s.textChannelID,
+		Settings:       guildSettings,
+		Queue:          queue,
+		LastSpeakerID:  s.prefixer.lastSpeaker(),
+		TakenAt:        s.clock.Now(),
+	}, nil
+}
+
+// RestoreFromSnapshot continues the session from the snapshot of a session reading the same channel.
+// The settings of the snapshot are only stored if the guild has none, as stored settings may have changed since.
+// Settings stored this way have no webhook, as the snapshot does not carry its URL.
+func (s *Session) RestoreFromSnapshot(ctx context.Context, snapshot Snapshot) error {
+	if snapshot.Version != snapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, snapshot.Version)
+	}
+	if snapshot.GuildID != s.guildID || snapshot.TextChannelID != s.textChannelID {
+		return ErrSnapshotMismatch
+	}
+
+	if _, err := s.settings.Find(ctx, s.guildID); errors.Is(err, settings.ErrNotFound) {
+		if err := s.settings.Save(ctx, snapshot.Settings); err != nil {
+			return fmt.Errorf("failed to restore guild settings: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch guild settings: %w", err)
+	}
+
+	s.prefixer.lastSpeakerID.Store(uint64(snapshot.LastSpeakerID))
+	if snapshot.Queue.PendingTasks > 0 || snapshot.Queue.Playing {
+		s.logger.Warn("Messages were not read before the handover",
+			slog.Int("pendingTasks", snapshot.Queue.PendingTasks),
+			slog.Bool("playing", snapshot.Queue.Playing),
+		)
+	}
+	s.logger.Info("Restored session from snapshot", slog.String("previousSessionID", snapshot.SessionID))
+	return nil
+}
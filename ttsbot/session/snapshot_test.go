@@ -0,0 +1,91 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgo/voice"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+// stubConn is a voice connection that is only asked for its channel.
+type stubConn struct {
+	voice.Conn
+	channelID snowflake.ID
+}
+
+func (c stubConn) ChannelID() *snowflake.ID {
+	return &c.channelID
+}
+
+func TestSessionSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	source := settings.NewMemoryGuildSettingsRepository()
+	stored := settings.DefaultGuildSettings(1)
+	stored.MaxMessageLength = 142
+	stored.WebhookURL = "https://example.com/hooks/secret"
+	require.NoError(t, source.Save(ctx, stored))
+
+	takenAt := time.Date(2025, 10, 17, 12, 0, 0, 0, time.UTC)
+	old := &Session{id: "old", guildID: 1, textChannelID: 11, conn: stubConn{channelID: 21}, settings: source, taskQueue: newTaskQueue(2), logger: slog.Default(), clock: clock.NewFake(takenAt)}
+	old.prefixer.apply(NewSpeechTask([]string{"hi"}, preset.Preset{}, WithSpeaker("Alice", 7)))
+
+	snapshot, err := old.Snapshot(ctx)
+	require.NoError(t, err)
+	require.Equal(t, snowflake.ID(21), snapshot.VoiceChannelID)
+	require.Equal(t, takenAt, snapshot.TakenAt)
+	require.Equal(t, snowflake.ID(7), snapshot.LastSpeakerID)
+	require.Equal(t, 142, snapshot.Settings.MaxMessageLength)
+
+	// the snapshot is handed over as JSON.
+	data, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "secret", "the webhook URL is not handed over")
+	var decoded Snapshot
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	t.Run("restores the last speaker and missing settings", func(t *testing.T) {
+		target := settings.NewMemoryGuildSettingsRepository()
+		restored := &Session{guildID: 1, textChannelID: 11, settings: target, logger: slog.Default()}
+		require.NoError(t, restored.RestoreFromSnapshot(ctx, decoded))
+
+		task := restored.prefixer.apply(NewSpeechTask([]string{"again"}, preset.Preset{}, WithSpeaker("Alice", 7)))
+		require.Equal(t, []string{"again"}, task.Segments, "the name is not read again after the handover")
+
+		found, err := target.Find(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, 142, found.MaxMessageLength)
+	})
+
+	t.Run("keeps stored settings", func(t *testing.T) {
+		target := settings.NewMemoryGuildSettingsRepository()
+		newer := settings.DefaultGuildSettings(1)
+		newer.MaxMessageLength = 100
+		require.NoError(t, target.Save(ctx, newer))
+
+		restored := &Session{guildID: 1, textChannelID: 11, settings: target, logger: slog.Default()}
+		require.NoError(t, restored.RestoreFromSnapshot(ctx, decoded))
+		found, err := target.Find(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, 100, found.MaxMessageLength)
+	})
+
+	t.Run("rejects snapshots of other sessions", func(t *testing.T) {
+		other := &Session{guildID: 1, textChannelID: 12, settings: settings.NewMemoryGuildSettingsRepository(), logger: slog.Default()}
+		require.ErrorIs(t, other.RestoreFromSnapshot(ctx, decoded), ErrSnapshotMismatch)
+
+		unknown := decoded
+		unknown.Version = snapshotVersion + 1
+		restored := &Session{guildID: 1, textChannelID: 11, settings: settings.NewMemoryGuildSettingsRepository(), logger: slog.Default()}
+		require.ErrorIs(t, restored.RestoreFromSnapshot(ctx, unknown), ErrSnapshotVersion)
+	})
+}
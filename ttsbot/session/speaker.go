@@ -0,0 +1,26 @@
+package session
+
+import (
+	"github.com/disgoorg/disgo/discord"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+// speakerName returns the name read for the member according to the guild's name settings.
+func speakerName(member discord.Member, guildSettings settings.GuildSettings) string {
+	var name string
+	switch guildSettings.NameSource {
+	case settings.NameSourceUsername:
+		name = member.User.Username
+	case settings.NameSourceDisplayName:
+		name = member.User.EffectiveName()
+	default:
+		name = member.EffectiveName()
+	}
+
+	if guildSettings.StripNameDecorations {
+		name = message.StripNameDecorations(name)
+	}
+	return name
+}
@@ -0,0 +1,35 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+func TestSpeakerName(t *testing.T) {
+	nick := "✨Nick✨"
+	globalName := "Global"
+	member := discord.Member{
+		Nick: &nick,
+		User: discord.User{Username: "user", GlobalName: &globalName},
+	}
+
+	tests := []struct {
+		source settings.NameSource
+		strip  bool
+		want   string
+	}{
+		{settings.NameSourceNickname, false, "✨Nick✨"},
+		{settings.NameSourceNickname, true, "Nick"},
+		{settings.NameSourceDisplayName, false, "Global"},
+		{settings.NameSourceUsername, false, "user"},
+	}
+	for _, tt := range tests {
+		got := speakerName(member, settings.GuildSettings{NameSource: tt.source, StripNameDecorations: tt.strip})
+		if got != tt.want {
+			t.Errorf("speakerName(%s, strip=%v) = %q, want %q", tt.source, tt.strip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package session
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+)
+
+// topSpeakers is the number of speakers listed in the summary of a session.
+const topSpeakers = 3
+
+// sessionStats counts what a session read, to summarize it once the session is closed.
+type sessionStats struct {
+	startedAt time.Time
+
+	mu         sync.Mutex
+	messages   int
+	characters int
+	speakers   map[snowflake.ID]int
+}
+
+func newSessionStats(startedAt time.Time) *sessionStats {
+	return &sessionStats{
+		startedAt: startedAt,
+		speakers:  make(map[snowflake.ID]int),
+	}
+}
+
+// addMessage counts a message of the speaker queued to be read.
+func (st *sessionStats) addMessage(speakerID snowflake.ID) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.messages++
+	st.speakers[speakerID]++
+}
+
+// addCharacters counts characters that were synthesized.
+func (st *sessionStats) addCharacters(n int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.characters += n
+}
+
+// summary summarizes the session up to now. Speakers with as many messages are listed in the order of their IDs.
+func (st *sessionStats) summary(now time.Time) message.SessionSummary {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	speakers := make([]message.SpeakerCount, 0, len(st.speakers))
+	for userID, messages := range st.speakers {
+		speakers = append(speakers, message.SpeakerCount{UserID: userID, Messages: messages})
+	}
+	slices.SortFunc(speakers, func(a, b message.SpeakerCount) int {
+		return cmp.Or(cmp.Compare(b.Messages, a.Messages), cmp.Compare(a.UserID, b.UserID))
+	})
+	return message.SessionSummary{
+		Duration:    now.Sub(st.startedAt),
+		Messages:    st.messages,
+		Characters:  st.characters,
+		TopSpeakers: speakers[:min(len(speakers), topSpeakers)],
+	}
+}
@@ -0,0 +1,35 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/message"
+)
+
+func TestSessionStats(t *testing.T) {
+	startedAt := time.Unix(0, 0)
+	stats := newSessionStats(startedAt)
+	require.Equal(t, message.SessionSummary{Duration: time.Minute, TopSpeakers: []message.SpeakerCount{}}, stats.summary(startedAt.Add(time.Minute)))
+
+	for _, speakerID := range []int{3, 1, 2, 2, 4, 4, 4} {
+		stats.addMessage(snowflake.ID(speakerID))
+	}
+	stats.addCharacters(10)
+	stats.addCharacters(5)
+
+	// speakers with as many messages are ordered by their IDs, and only the top ones are listed.
+	require.Equal(t, message.SessionSummary{
+		Duration:   90 * time.Minute,
+		Messages:   7,
+		Characters: 15,
+		TopSpeakers: []message.SpeakerCount{
+			{UserID: 4, Messages: 3},
+			{UserID: 2, Messages: 2},
+			{UserID: 1, Messages: 1},
+		},
+	}, stats.summary(startedAt.Add(90*time.Minute)))
+}
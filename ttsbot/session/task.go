@@ -2,24 +2,40 @@ package session
 
 import (
 	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/logging"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
 )
 
 // SpeechTask represents a task for text-to-speech processing.
 type SpeechTask struct {
+	// ID correlates the logs of the task, from queueing to playback.
+	ID       string
 	Segments []string
 	Preset   preset.Preset
+	// SegmentPresets are the presets of each segment, e.g. of the runs of a code-switched message.
+	// Nil reads every segment with Preset, and so does a zero preset.
+	SegmentPresets []preset.Preset
+
+	// Source is the channel the task was read from, or zero for the announcements of the session itself.
+	// The session takes turns between the sources of its tasks.
+	Source snowflake.ID
 
 	// option: with speaker?
 	ContainsSpeaker bool
 	SpeakerName     string
 	SpeakerID       snowflake.ID
+
+	// played is closed once the task has been played, if not nil.
+	played chan struct{}
+	// transcript is the line the task is shown as in the transcript, if the session posts one.
+	transcript string
 }
 
 type SpeechTaskOpt func(s *SpeechTask)
 
 func NewSpeechTask(segments []string, preset preset.Preset, opts ...SpeechTaskOpt) SpeechTask {
 	task := &SpeechTask{
+		ID:       logging.NewCorrelationID(),
 		Segments: segments,
 		Preset:   preset,
 	}
@@ -33,6 +49,28 @@ func (s *SpeechTask) apply(opts ...SpeechTaskOpt) {
 	}
 }
 
+// presetOf returns the preset the i-th segment is read with.
+func (s SpeechTask) presetOf(i int) preset.Preset {
+	if i < len(s.SegmentPresets) && s.SegmentPresets[i].Identifier != "" {
+		return s.SegmentPresets[i]
+	}
+	return s.Preset
+}
+
+// WithSegmentPresets reads each segment with its preset instead of the preset of the task.
+func WithSegmentPresets(presets []preset.Preset) SpeechTaskOpt {
+	return func(s *SpeechTask) {
+		s.SegmentPresets = presets
+	}
+}
+
+// WithSource marks the task as read from the channel.
+func WithSource(channelID snowflake.ID) SpeechTaskOpt {
+	return func(s *SpeechTask) {
+		s.Source = channelID
+	}
+}
+
 func WithSpeaker(speakerName string, speakerID snowflake.ID) SpeechTaskOpt {
 	return func(s *SpeechTask) {
 		s.ContainsSpeaker = true
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"strings"
+
+	"github.com/disgoorg/disgo/events"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/i18n"
+)
+
+// TextCommand is a command typed in the reading channel, e.g. ";skip" or ";preset default".
+// It is a fallback for users who cannot use slash commands.
+type TextCommand struct {
+	// Name is the lower-cased command name without the prefix.
+	Name string
+	Args []string
+}
+
+// ParseTextCommand parses content as a text command with the given prefix.
+// It reports false if the prefix is empty or the content is not a command.
+func ParseTextCommand(content, prefix string) (TextCommand, bool) {
+	if prefix == "" {
+		return TextCommand{}, false
+	}
+	rest, ok := strings.CutPrefix(strings.TrimSpace(content), prefix)
+	if !ok {
+		return TextCommand{}, false
+	}
+	fields := strings.Fields(rest)
+	// the name must follow the prefix directly, so that e.g. "; )" is read as usual.
+	if len(fields) == 0 || !strings.HasPrefix(rest, fields[0]) {
+		return TextCommand{}, false
+	}
+	return TextCommand{
+		Name: strings.ToLower(fields[0]),
+		Args: fields[1:],
+	}, true
+}
+
+// TextCommandEvent is passed to a TextCommandHandler when a text command is sent in the reading channel of a session.
+type TextCommandEvent struct {
+	*events.MessageCreate
+	Session      *Session
+	Command      TextCommand
+	TextResource i18n.TextResource
+}
+
+// TextCommandHandler handles a text command. Returned errors are logged by the session.
+type TextCommandHandler func(e *TextCommandEvent) error
@@ -0,0 +1,36 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTextCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		prefix  string
+		want    TextCommand
+		wantOK  bool
+	}{
+		{name: "command", content: ";skip", prefix: ";", want: TextCommand{Name: "skip", Args: []string{}}, wantOK: true},
+		{name: "arguments", content: ";preset  default ", prefix: ";", want: TextCommand{Name: "preset", Args: []string{"default"}}, wantOK: true},
+		{name: "case insensitive", content: "!Leave", prefix: "!", want: TextCommand{Name: "leave", Args: []string{}}, wantOK: true},
+		{name: "multi-character prefix", content: "tts!skip", prefix: "tts!", want: TextCommand{Name: "skip", Args: []string{}}, wantOK: true},
+		{name: "disabled", content: ";skip", prefix: "", wantOK: false},
+		{name: "no prefix", content: "skip", prefix: ";", wantOK: false},
+		{name: "prefix only", content: ";", prefix: ";", wantOK: false},
+		{name: "space after prefix", content: "; skip", prefix: ";", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseTextCommand(tt.content, tt.prefix)
+			require.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				require.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
@@ -1,13 +1,12 @@
 package session
 
 import (
-	"bytes"
-	"fmt"
+	"errors"
 	"io"
 	"log/slog"
+	"sync/atomic"
 
 	"github.com/disgoorg/audio"
-	"github.com/disgoorg/audio/mp3"
 	"github.com/disgoorg/audio/pcm"
 	"github.com/disgoorg/disgo/voice"
 	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
@@ -15,20 +14,76 @@ import (
 
 type trackPlayer struct {
 	audio.Player
-	queue    <-chan *tts.SpeechResponse
+	queue    <-chan track
+	decoder  Decoder
+	cache    *OpusFrameCache
+	budget   *audioBudget
 	provider pcm.FrameProvider
 	conn     voice.Conn
 	close    <-chan struct{}
+	logger   *slog.Logger
+	// onPlay is called with the transcript line of a track when it starts playing, if not nil.
+	// It is called from the goroutine of the audio sender, so it must not block.
+	onPlay func(line string)
+	// hold reports whether playback is held back, if not nil. No frames are played while it does,
+	// and the track continues where it was held once it no longer does. It is called for every frame.
+	hold func() bool
+	// duck reports whether speech is played at duckGain, if not nil. Cached and passed through opus frames can not be turned down,
+	// so their tracks are held instead. It is called for every frame.
+	duck func() bool
+
+	// playing is set while a track is being played, and skipping while the current track should be dropped.
+	playing  atomic.Bool
+	skipping atomic.Bool
+
+	// cached are the opus frames of the track being played from the cache, and cachedPos the next one to play.
+	// They are only accessed from the goroutine of the audio sender.
+	cached    [][]byte
+	cachedPos int
+	// passthrough sends the opus packets of the Ogg Opus speech being played as they are. It is only accessed from the goroutine of the audio sender.
+	passthrough *oggOpusFrameProvider
+	// recording collects the opus frames of the track being played, to be cached once it has been played to the end.
+	recording *frameRecording
+	// size is the bytes of speech of the track being played, released from the budget once it ends.
+	size int64
+}
+
+// track is an item of the audio queue: synthesized speech, opus frames from the cache,
+// or a marker whose played channel is closed once every track queued before it has been played.
+type track struct {
+	speech *tts.SpeechResponse
+	// size is the bytes of the speech counted in the budget of the session.
+	size int64
+	// cacheKey caches the opus frames of the speech under the key once played, if not empty.
+	cacheKey string
+	frames   [][]byte
+	played   chan struct{}
+	// transcript is the line posted to the transcript once the track starts playing, if not empty.
+	transcript string
+}
+
+type frameRecording struct {
+	key    string
+	frames [][]byte
 }
 
-func newTrackPlayer(conn voice.Conn, queue <-chan *tts.SpeechResponse, close <-chan struct{}) (*trackPlayer, error) {
+func newTrackPlayer(conn voice.Conn, decoder Decoder, cache *OpusFrameCache, budget *audioBudget, queue <-chan track, close <-chan struct{}, logger *slog.Logger) (*trackPlayer, error) {
 	player := &trackPlayer{
-		queue: queue,
-		conn:  conn,
-		close: close,
+		queue:   queue,
+		decoder: decoder,
+		cache:   cache,
+		budget:  budget,
+		conn:    conn,
+		close:   close,
+		logger:  logger,
 	}
 	var err error
 	player.Player, err = audio.NewPlayer(func() pcm.FrameProvider {
+		if player.skipping.Swap(false) {
+			// a skipped track is incomplete, so it is not cached.
+			player.recording = nil
+			return endedFrameProvider{}
+		}
 		return player.provider
 	}, player)
 	if err != nil {
@@ -38,36 +93,190 @@ func newTrackPlayer(conn voice.Conn, queue <-chan *tts.SpeechResponse, close <-c
 }
 
 func (p *trackPlayer) next() {
-	select {
-	case <-p.close:
-		slog.Info("TrackPlayer closed, stopping playback")
-		return
-	case track := <-p.queue:
-		provider, err := convertToFrameProvider(track)
-		if err != nil {
-			slog.Error("Failed to convert track to frame provider", slog.Any("error", err))
+	for {
+		select {
+		case <-p.close:
+			p.logger.Info("TrackPlayer closed, stopping playback")
+			return
+		case track := <-p.queue:
+			if track.frames != nil {
+				if p.provider != nil {
+					p.provider.Close()
+					p.provider = nil
+				}
+				p.cached, p.cachedPos = track.frames, 0
+				p.playing.Store(true)
+				p.started(track)
+				return
+			}
+			if track.speech == nil {
+				close(track.played)
+				continue
+			}
+			if track.speech.Format == tts.AudioFormatOggOpus {
+				if passthrough, ok := passthroughOggOpus(track.speech); ok {
+					p.passthrough = passthrough
+					p.size = track.size
+					if track.cacheKey != "" && p.cache != nil {
+						p.recording = &frameRecording{key: track.cacheKey}
+					}
+					p.playing.Store(true)
+					p.started(track)
+					return
+				}
+			}
+			provider, err := p.decoder.Decode(track.speech)
+			if err != nil {
+				p.logger.Error("Failed to convert track to frame provider", slog.Any("error", err))
+				p.budget.release(track.size)
+				return
+			}
+			// the previous track may have been skipped before it ended, so release it here.
+			if p.provider != nil {
+				p.provider.Close()
+			}
+			p.provider = provider
+			if p.duck != nil {
+				p.provider = &duckingFrameProvider{FrameProvider: provider, duck: p.duck}
+			}
+			p.size = track.size
+			if track.cacheKey != "" && p.cache != nil {
+				p.recording = &frameRecording{key: track.cacheKey}
+			}
+			p.playing.Store(true)
+			p.started(track)
 			return
 		}
-		p.provider = provider
 	}
 }
 
-func convertToFrameProvider(resp *tts.SpeechResponse) (pcm.FrameProvider, error) {
-	switch resp.Format {
-	case tts.AudioFormatMp3:
-		provider, w, err := mp3.NewCustomPCMFrameProvider(nil, 48000, 1)
+// started reports the track that started playing to onPlay.
+func (p *trackPlayer) started(track track) {
+	if p.onPlay != nil && track.transcript != "" {
+		p.onPlay(track.transcript)
+	}
+}
+
+// ProvideOpusFrame plays the cached frames of the current track if it came from the cache, passes through Ogg Opus speech,
+// and otherwise encodes the decoded speech, recording the frames if the track is to be cached.
+func (p *trackPlayer) ProvideOpusFrame() ([]byte, error) {
+	if p.held() {
+		return nil, nil
+	}
+	if p.passthrough != nil {
+		if p.Paused() || p.ducked() {
+			return nil, nil
+		}
+		if p.skipping.Swap(false) {
+			p.endPassthrough(false)
+			return nil, nil
+		}
+		frame, err := p.passthrough.ProvideOpusFrame()
 		if err != nil {
-			return nil, err
+			if !errors.Is(err, io.EOF) {
+				p.logger.Error("Failed to pass through speech", slog.Any("error", err))
+			}
+			p.endPassthrough(errors.Is(err, io.EOF))
+			return nil, nil
+		}
+		if p.recording != nil {
+			// the reader reuses its buffer, so the frame is copied.
+			p.recording.frames = append(p.recording.frames, append([]byte(nil), frame...))
 		}
-		if _, err := io.Copy(w, bytes.NewReader(resp.AudioContent)); err != nil {
-			return nil, err
+		return frame, nil
+	}
+	if p.cached != nil {
+		if p.Paused() || p.ducked() {
+			return nil, nil
 		}
-		return pcm.NewPCMFrameChannelConverterProvider(provider, 48000, 1, 2), nil
-	default:
-		return nil, fmt.Errorf("unsupported audio format: %v", resp.Format)
+		if p.skipping.Swap(false) || p.cachedPos >= len(p.cached) {
+			p.cached = nil
+			p.playing.Store(false)
+			p.next()
+			return nil, nil
+		}
+		frame := p.cached[p.cachedPos]
+		p.cachedPos++
+		return frame, nil
+	}
+
+	if p.recording != nil && p.ducked() {
+		// ducked frames are quieter than the speech, so the track is not cached.
+		p.recording = nil
+	}
+	frame, err := p.Player.ProvideOpusFrame()
+	if p.recording != nil && len(frame) > 0 {
+		// the encoder reuses its buffer, so the frame is copied.
+		p.recording.frames = append(p.recording.frames, append([]byte(nil), frame...))
+	}
+	return frame, err
+}
+
+// endPassthrough ends the passed through track like OnEnd ends decoded ones, caching it only if it was played to the end.
+func (p *trackPlayer) endPassthrough(complete bool) {
+	p.passthrough.Close()
+	p.passthrough = nil
+	p.budget.release(p.size)
+	p.size = 0
+	if complete && p.recording != nil {
+		p.cache.Put(p.recording.key, p.recording.frames)
+	}
+	p.recording = nil
+	p.playing.Store(false)
+	p.next()
+}
+
+// held reports whether playback is held back by hold.
+func (p *trackPlayer) held() bool {
+	return p.hold != nil && p.hold()
+}
+
+// ducked reports whether speech is turned down by duck.
+func (p *trackPlayer) ducked() bool {
+	return p.duck != nil && p.duck()
+}
+
+// skip ends the track being played and reports whether there was one.
+func (p *trackPlayer) skip() bool {
+	if !p.playing.Load() {
+		return false
+	}
+	p.skipping.Store(true)
+	return true
+}
+
+// duckGain is the gain of ducked speech, about -12 dB.
+const duckGain = 0.25
+
+// duckingFrameProvider turns the frames of the provider down to duckGain while duck reports so.
+type duckingFrameProvider struct {
+	pcm.FrameProvider
+	duck func() bool
+	// frame is the buffer of the turned down frame, as the provider may reuse its own.
+	frame []int16
+}
+
+func (p *duckingFrameProvider) ProvidePCMFrame() ([]int16, error) {
+	frame, err := p.FrameProvider.ProvidePCMFrame()
+	if err != nil || !p.duck() {
+		return frame, err
+	}
+	p.frame = append(p.frame[:0], frame...)
+	for i, sample := range p.frame {
+		p.frame[i] = int16(float64(sample) * duckGain)
 	}
+	return p.frame, nil
 }
 
+// endedFrameProvider is played instead of a skipped track, ending it on the next frame.
+type endedFrameProvider struct{}
+
+func (endedFrameProvider) ProvidePCMFrame() ([]int16, error) {
+	return nil, io.EOF
+}
+
+func (endedFrameProvider) Close() {}
+
 func (p *trackPlayer) OnPause(player audio.Player) {}
 
 func (p *trackPlayer) OnResume(player audio.Player) {}
@@ -75,6 +284,14 @@ func (p *trackPlayer) OnResume(player audio.Player) {}
 func (p *trackPlayer) OnStart(player audio.Player) {}
 
 func (p *trackPlayer) OnEnd(player audio.Player) {
+	p.budget.release(p.size)
+	p.size = 0
+	if p.recording != nil {
+		p.cache.Put(p.recording.key, p.recording.frames)
+		p.recording = nil
+	}
+	p.playing.Store(false)
+	p.skipping.Store(false)
 	p.next()
 }
 
@@ -0,0 +1,224 @@
+package session
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/tts"
+)
+
+func TestTrackPlayerMarker(t *testing.T) {
+	queue := make(chan track, 1)
+	closed := make(chan struct{})
+	player := &trackPlayer{queue: queue, close: closed, logger: slog.Default()}
+
+	done := make(chan struct{})
+	go func() {
+		player.next()
+		close(done)
+	}()
+
+	// the marker is passed over without playing anything.
+	played := make(chan struct{})
+	queue <- track{played: played}
+	select {
+	case <-played:
+	case <-time.After(time.Second):
+		t.Fatal("marker was not closed")
+	}
+	require.False(t, player.playing.Load())
+
+	close(closed)
+	<-done
+}
+
+func TestTrackPlayerCachedFrames(t *testing.T) {
+	queue := make(chan track, 2)
+	closed := make(chan struct{})
+	player, err := newTrackPlayer(nil, NativeDecoder{}, NewOpusFrameCache(1), newAudioBudget(0), queue, closed, slog.Default())
+	require.NoError(t, err)
+
+	// the cached frames are played as they are, followed by the marker.
+	played := make(chan struct{})
+	queue <- track{frames: [][]byte{{1}, {2}}}
+	queue <- track{played: played}
+	player.next()
+	require.True(t, player.playing.Load())
+
+	for _, expected := range [][]byte{{1}, {2}} {
+		frame, err := player.ProvideOpusFrame()
+		require.NoError(t, err)
+		require.Equal(t, expected, frame)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		frame, err := player.ProvideOpusFrame()
+		require.NoError(t, err)
+		require.Nil(t, frame)
+		close(done)
+	}()
+	select {
+	case <-played:
+	case <-time.After(time.Second):
+		t.Fatal("marker was not closed")
+	}
+	require.False(t, player.playing.Load())
+
+	close(closed)
+	<-done
+}
+
+func TestTrackPlayerPassthrough(t *testing.T) {
+	queue := make(chan track, 2)
+	closed := make(chan struct{})
+	cache := NewOpusFrameCache(1)
+	player, err := newTrackPlayer(nil, NativeDecoder{}, cache, newAudioBudget(0), queue, closed, slog.Default())
+	require.NoError(t, err)
+
+	// the packets of Ogg Opus speech are played as they are, and cached once played to the end.
+	speech := &tts.SpeechResponse{Format: tts.AudioFormatOggOpus, Audio: bytes.NewReader(oggOpusStream([]byte{toc20ms, 1}, []byte{toc20ms, 2}))}
+	played := make(chan struct{})
+	queue <- track{speech: speech, cacheKey: "hello"}
+	queue <- track{played: played}
+	player.next()
+	require.True(t, player.playing.Load())
+
+	for _, expected := range [][]byte{{toc20ms, 1}, {toc20ms, 2}} {
+		frame, err := player.ProvideOpusFrame()
+		require.NoError(t, err)
+		require.Equal(t, expected, frame)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		frame, err := player.ProvideOpusFrame()
+		require.NoError(t, err)
+		require.Nil(t, frame)
+		close(done)
+	}()
+	select {
+	case <-played:
+	case <-time.After(time.Second):
+		t.Fatal("marker was not closed")
+	}
+	require.False(t, player.playing.Load())
+	frames, ok := cache.Get("hello")
+	require.True(t, ok)
+	require.Equal(t, [][]byte{{toc20ms, 1}, {toc20ms, 2}}, frames)
+
+	close(closed)
+	<-done
+}
+
+func TestTrackPlayerPassthroughFailure(t *testing.T) {
+	queue := make(chan track, 2)
+	closed := make(chan struct{})
+	cache := NewOpusFrameCache(1)
+	player, err := newTrackPlayer(nil, NativeDecoder{}, cache, newAudioBudget(0), queue, closed, slog.Default())
+	require.NoError(t, err)
+
+	// speech whose later packets are not 20ms ends at the first of them, and moves on instead of stalling the player.
+	speech := &tts.SpeechResponse{Format: tts.AudioFormatOggOpus, Audio: bytes.NewReader(oggOpusStream([]byte{toc20ms, 1}, []byte{toc60ms, 2}, []byte{toc20ms, 3}))}
+	played := make(chan struct{})
+	queue <- track{speech: speech, cacheKey: "hello"}
+	queue <- track{played: played}
+	player.next()
+	require.True(t, player.playing.Load())
+
+	frame, err := player.ProvideOpusFrame()
+	require.NoError(t, err)
+	require.Equal(t, []byte{toc20ms, 1}, frame)
+
+	done := make(chan struct{})
+	go func() {
+		frame, err := player.ProvideOpusFrame()
+		require.NoError(t, err)
+		require.Nil(t, frame)
+		close(done)
+	}()
+	select {
+	case <-played:
+	case <-time.After(time.Second):
+		t.Fatal("marker was not closed")
+	}
+	require.False(t, player.playing.Load())
+	_, ok := cache.Get("hello")
+	require.False(t, ok, "speech that failed should not be cached")
+
+	close(closed)
+	<-done
+}
+
+func TestTrackPlayerHold(t *testing.T) {
+	queue := make(chan track, 1)
+	closed := make(chan struct{})
+	player, err := newTrackPlayer(nil, NativeDecoder{}, NewOpusFrameCache(1), newAudioBudget(0), queue, closed, slog.Default())
+	require.NoError(t, err)
+	holding := true
+	player.hold = func() bool { return holding }
+
+	queue <- track{frames: [][]byte{{1}, {2}}}
+	player.next()
+
+	// nothing is played while held, and the track continues where it was held.
+	frame, err := player.ProvideOpusFrame()
+	require.NoError(t, err)
+	require.Nil(t, frame)
+
+	holding = false
+	frame, err = player.ProvideOpusFrame()
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, frame)
+}
+
+func TestTrackPlayerDuck(t *testing.T) {
+	queue := make(chan track, 1)
+	closed := make(chan struct{})
+	player, err := newTrackPlayer(nil, NativeDecoder{}, NewOpusFrameCache(1), newAudioBudget(0), queue, closed, slog.Default())
+	require.NoError(t, err)
+	ducking := true
+	player.duck = func() bool { return ducking }
+
+	// cached frames can not be turned down, so they are held.
+	queue <- track{frames: [][]byte{{1}}}
+	player.next()
+	frame, err := player.ProvideOpusFrame()
+	require.NoError(t, err)
+	require.Nil(t, frame)
+
+	ducking = false
+	frame, err = player.ProvideOpusFrame()
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, frame)
+}
+
+func TestDuckingFrameProvider(t *testing.T) {
+	source := []int16{400, -800}
+	ducking := true
+	provider := &duckingFrameProvider{FrameProvider: &staticFrameProvider{frame: source}, duck: func() bool { return ducking }}
+
+	frame, err := provider.ProvidePCMFrame()
+	require.NoError(t, err)
+	require.Equal(t, []int16{100, -200}, frame)
+	require.Equal(t, []int16{400, -800}, source, "the frame of the source is left as it is")
+
+	ducking = false
+	frame, err = provider.ProvidePCMFrame()
+	require.NoError(t, err)
+	require.Equal(t, []int16{400, -800}, frame)
+}
+
+type staticFrameProvider struct {
+	frame []int16
+}
+
+func (p *staticFrameProvider) ProvidePCMFrame() ([]int16, error) {
+	return p.frame, nil
+}
+
+func (p *staticFrameProvider) Close() {}
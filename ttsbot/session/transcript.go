@@ -0,0 +1,155 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+const (
+	// transcriptWindow is how long spoken lines are collected before the rolling message is edited,
+	// as Discord rate limits edits.
+	transcriptWindow = 2 * time.Second
+	// transcriptMaxLines is the number of latest lines kept in the rolling message.
+	transcriptMaxLines = 10
+	// transcriptMaxLineLength is the maximum number of characters of a line; longer lines are cut.
+	transcriptMaxLineLength = 300
+	// transcriptMaxLength is the maximum number of characters of a Discord message.
+	transcriptMaxLength = 2000
+)
+
+// transcript posts what the session spoke into a text channel, for members who cannot listen and for admins.
+// The latest lines are shown in a single rolling message, which is edited instead of posting a message per line.
+type transcript struct {
+	mu       sync.Mutex
+	window   time.Duration
+	clock    clock.Clock
+	channels rest.Channels
+	// channelID returns the channel the transcript is posted into, or 0 if the guild disabled it.
+	channelID func() snowflake.ID
+	logger    *slog.Logger
+
+	lines []string
+	timer clock.Timer
+
+	// postMu serializes the posts, so that the rolling message is created only once.
+	postMu sync.Mutex
+	// messageChannelID and messageID are the rolling message. A new one is posted once the channel changes or editing it fails.
+	messageChannelID snowflake.ID
+	messageID        snowflake.ID
+}
+
+func newTranscript(clk clock.Clock, window time.Duration, channels rest.Channels, channelID func() snowflake.ID, logger *slog.Logger) *transcript {
+	return &transcript{
+		window:    window,
+		clock:     clk,
+		channels:  channels,
+		channelID: channelID,
+		logger:    logger,
+	}
+}
+
+// add appends the line to the transcript. The first line added starts the window,
+// and every line added before the window ends is posted in the same edit.
+func (t *transcript) add(line string) {
+	if utf8.RuneCountInString(line) > transcriptMaxLineLength {
+		line = string([]rune(line)[:transcriptMaxLineLength-1]) + "…"
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = append(t.lines, line)
+	if len(t.lines) > transcriptMaxLines {
+		t.lines = t.lines[len(t.lines)-transcriptMaxLines:]
+	}
+	if t.timer != nil {
+		return
+	}
+	t.timer = t.clock.AfterFunc(t.window, t.post)
+}
+
+// stop posts the lines still waiting for the window in the background, e.g. the farewell.
+func (t *transcript) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer == nil {
+		return
+	}
+	t.timer.Stop()
+	t.timer = nil
+	go t.post()
+}
+
+// post edits the rolling message to show the latest lines, posting it first if there is none in the channel.
+func (t *transcript) post() {
+	t.mu.Lock()
+	t.timer = nil
+	content := renderTranscript(t.lines)
+	t.mu.Unlock()
+
+	channelID := t.channelID()
+	if channelID == 0 {
+		return
+	}
+
+	t.postMu.Lock()
+	defer t.postMu.Unlock()
+	if t.messageChannelID == channelID && t.messageID != 0 {
+		_, err := t.channels.UpdateMessage(channelID, t.messageID, discord.MessageUpdate{Content: &content})
+		if err == nil {
+			return
+		}
+		// the message may have been deleted, so a new one is posted.
+		t.logger.Warn("Failed to edit the transcript, posting a new one", slog.Any("err", err), slog.String("channelID", channelID.String()))
+	}
+
+	msg, err := t.channels.CreateMessage(channelID, discord.MessageCreate{
+		Content: content,
+		// the lines quote members, so they must not ping anyone again.
+		AllowedMentions: &discord.AllowedMentions{},
+	})
+	if err != nil {
+		t.logger.Error("Failed to post the transcript", slog.Any("err", err), slog.String("channelID", channelID.String()))
+		return
+	}
+	t.messageChannelID, t.messageID = channelID, msg.ID
+}
+
+// renderTranscript joins the lines into a message, dropping the oldest lines that do not fit.
+func renderTranscript(lines []string) string {
+	for len(lines) > 1 && utf8.RuneCountInString(strings.Join(lines, "\n")) > transcriptMaxLength {
+		lines = lines[1:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// transcriptLine returns the line the task is shown as in the transcript.
+// Messages are shown with the name of their speaker, even if it is not read.
+func transcriptLine(task SpeechTask) string {
+	line := strings.Join(task.Segments, " ")
+	if task.ContainsSpeaker {
+		line = "**" + task.SpeakerName + "**: " + line
+	}
+	return line
+}
+
+// transcriptChannelID returns the transcript channel of the guild, or 0 if the guild disabled the transcript.
+func (s *Session) transcriptChannelID() snowflake.ID {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	guildSettings, err := settings.FindOrDefault(ctx, s.settings, s.guildID)
+	if err != nil {
+		s.logger.Error("Failed to fetch guild settings", slog.Any("err", err))
+		return 0
+	}
+	return guildSettings.TranscriptChannelID
+}
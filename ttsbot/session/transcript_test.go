@@ -0,0 +1,89 @@
+package session
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+)
+
+// fakeChannels records the messages posted and edited by the transcript.
+type fakeChannels struct {
+	rest.Channels
+	created   []string
+	updated   []string
+	updateErr error
+}
+
+func (c *fakeChannels) CreateMessage(channelID snowflake.ID, messageCreate discord.MessageCreate, opts ...rest.RequestOpt) (*discord.Message, error) {
+	c.created = append(c.created, messageCreate.Content)
+	return &discord.Message{ID: snowflake.ID(len(c.created)), ChannelID: channelID}, nil
+}
+
+func (c *fakeChannels) UpdateMessage(channelID snowflake.ID, messageID snowflake.ID, messageUpdate discord.MessageUpdate, opts ...rest.RequestOpt) (*discord.Message, error) {
+	if c.updateErr != nil {
+		return nil, c.updateErr
+	}
+	c.updated = append(c.updated, *messageUpdate.Content)
+	return &discord.Message{ID: messageID, ChannelID: channelID}, nil
+}
+
+func TestTranscript(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	channels := &fakeChannels{}
+	channelID := snowflake.ID(1)
+	tr := newTranscript(clk, time.Second, channels, func() snowflake.ID { return channelID }, slog.Default())
+
+	tr.add("first")
+	tr.add("second")
+	clk.Advance(time.Second)
+	if want := []string{"first\nsecond"}; !slices.Equal(channels.created, want) {
+		t.Fatalf("created %q, want %q", channels.created, want)
+	}
+
+	tr.add("third")
+	clk.Advance(time.Second)
+	if want := []string{"first\nsecond\nthird"}; !slices.Equal(channels.updated, want) {
+		t.Errorf("updated %q, want %q", channels.updated, want)
+	}
+
+	channels.updateErr = errors.New("unknown message")
+	tr.add("fourth")
+	clk.Advance(time.Second)
+	if len(channels.created) != 2 {
+		t.Errorf("expected a new message once editing failed, created %q", channels.created)
+	}
+
+	channelID = 0
+	tr.add("fifth")
+	clk.Advance(time.Second)
+	if len(channels.created) != 2 {
+		t.Errorf("posted %q although the transcript was disabled", channels.created)
+	}
+}
+
+func TestRenderTranscript(t *testing.T) {
+	long := strings.Repeat("a", 999)
+	if got := renderTranscript([]string{"old", long, long}); got != long+"\n"+long {
+		t.Errorf("expected the oldest line to be dropped, got %d characters", len(got))
+	}
+}
+
+func TestTranscriptLine(t *testing.T) {
+	task := NewSpeechTask([]string{"hello.", "how are you?"}, preset.Preset{}, WithSpeaker("Alice", 1))
+	if got, want := transcriptLine(task), "**Alice**: hello. how are you?"; got != want {
+		t.Errorf("transcriptLine() = %q, want %q", got, want)
+	}
+	announcement := NewSpeechTask([]string{"Alice joined"}, preset.Preset{})
+	if got, want := transcriptLine(announcement), "Alice joined"; got != want {
+		t.Errorf("transcriptLine() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+package session
+
+import (
+	"regexp"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+// VoiceTag is a tag at the start of a message overriding the voice of just that message,
+// e.g. "[en] hello" or "[voice:anna] hello".
+type VoiceTag struct {
+	// Language is the language code of a language tag, e.g. "en" or "en-US".
+	Language string
+	// PresetID is the preset of a voice tag.
+	PresetID preset.PresetID
+}
+
+var voiceTagPattern = regexp.MustCompile(`^\s*\[(?:voice:([\w.-]+)|([a-zA-Z]{2,3}(?:-[a-zA-Z0-9]{2,8})?))\]\s*`)
+
+// ParseVoiceTag parses the voice tag at the start of content, and returns the content without it.
+// It reports false if content does not start with a voice tag.
+func ParseVoiceTag(content string) (VoiceTag, string, bool) {
+	match := voiceTagPattern.FindStringSubmatchIndex(content)
+	if match == nil {
+		return VoiceTag{}, content, false
+	}
+	var tag VoiceTag
+	if match[2] >= 0 {
+		tag.PresetID = preset.PresetID(content[match[2]:match[3]])
+	} else {
+		tag.Language = content[match[4]:match[5]]
+	}
+	return tag, content[match[1]:], true
+}
+
+// ApplyVoiceTag overrides the preset with the voice tag at the start of the content, if the guild enabled voice tags.
+// The tag is stripped only if the guild may use the voice it asks for; otherwise, e.g. for "[ok] sure",
+// the content is read as is with the preset. It returns the preset and the content to read.
+func ApplyVoiceTag(resolver preset.PresetResolver, guildSettings settings.GuildSettings, current preset.Preset, content string) (preset.Preset, string) {
+	if !guildSettings.VoiceTags {
+		return current, content
+	}
+	tag, rest, ok := ParseVoiceTag(content)
+	if !ok {
+		return current, content
+	}
+
+	var override preset.Preset
+	if tag.PresetID != "" {
+		override, ok = resolver.Lookup(guildSettings.GuildID, tag.PresetID)
+	} else {
+		override, ok = resolver.ResolveLanguage(guildSettings.GuildID, tag.Language, current)
+	}
+	if !ok {
+		return current, content
+	}
+	return override, rest
+}
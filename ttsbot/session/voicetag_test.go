@@ -0,0 +1,87 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/preset"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVoiceTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		want     VoiceTag
+		wantRest string
+		wantOK   bool
+	}{
+		{name: "language", content: "[en] hello", want: VoiceTag{Language: "en"}, wantRest: "hello", wantOK: true},
+		{name: "language with region", content: " [en-GB]hello", want: VoiceTag{Language: "en-GB"}, wantRest: "hello", wantOK: true},
+		{name: "voice", content: "[voice:anna] hello", want: VoiceTag{PresetID: "anna"}, wantRest: "hello", wantOK: true},
+		{name: "not at the start", content: "hello [en]", wantRest: "hello [en]", wantOK: false},
+		{name: "not a language", content: "[hello] world", wantRest: "[hello] world", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, ok := ParseVoiceTag(tt.content)
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantRest, rest)
+		})
+	}
+}
+
+// voiceTagResolver resolves the presets of the voice tags from a fixed list.
+type voiceTagResolver struct {
+	preset.PresetResolver
+	presets []preset.Preset
+}
+
+func (r voiceTagResolver) Lookup(_ snowflake.ID, presetID preset.PresetID) (preset.Preset, bool) {
+	for _, p := range r.presets {
+		if p.Identifier == presetID {
+			return p, true
+		}
+	}
+	return preset.Preset{}, false
+}
+
+func (r voiceTagResolver) ResolveLanguage(_ snowflake.ID, language string, current preset.Preset) (preset.Preset, bool) {
+	for _, p := range r.presets {
+		if p.Speaks(language) {
+			return p, true
+		}
+	}
+	return preset.Preset{}, false
+}
+
+func TestApplyVoiceTag(t *testing.T) {
+	english := preset.Preset{Identifier: "anna", Engine: "google", Language: "en-US"}
+	current := preset.Preset{Identifier: "default", Engine: "google", Language: "ja-JP"}
+	resolver := voiceTagResolver{presets: []preset.Preset{english}}
+	enabled := settings.GuildSettings{GuildID: 1, VoiceTags: true}
+
+	tests := []struct {
+		name          string
+		guildSettings settings.GuildSettings
+		content       string
+		want          preset.Preset
+		wantContent   string
+	}{
+		{name: "language", guildSettings: enabled, content: "[en] hello", want: english, wantContent: "hello"},
+		{name: "voice", guildSettings: enabled, content: "[voice:anna] hello", want: english, wantContent: "hello"},
+		{name: "unresolved tag is read", guildSettings: enabled, content: "[ok] sure", want: current, wantContent: "[ok] sure"},
+		{name: "disabled", guildSettings: settings.GuildSettings{GuildID: 1}, content: "[en] hello", want: current, wantContent: "[en] hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, content := ApplyVoiceTag(resolver, tt.guildSettings, current, tt.content)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantContent, content)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database"
+)
+
+var ErrNoEncryptionKey = errors.New("guild settings are encrypted, but no encryption key is configured")
+
+// sensitiveColumns are the columns of guild settings encrypted at rest once a key ring is configured.
+// Each value is kept either in its plaintext column or in its encrypted column, while the other one is left empty.
+type sensitiveColumns struct {
+	WebhookURL                   string       `db:"webhook_url"`
+	TranscriptChannelID          snowflake.ID `db:"transcript_channel_id"`
+	WebhookURLEncrypted          string       `db:"webhook_url_encrypted"`
+	TranscriptChannelIDEncrypted string       `db:"transcript_channel_id_encrypted"`
+}
+
+// encryptSensitiveColumns returns the sensitive columns of the settings, encrypted with the key ring unless it is nil.
+// Empty values are left empty, as they disable what they configure and tell nothing.
+func encryptSensitiveColumns(keyRing *database.KeyRing, settings GuildSettings) (sensitiveColumns, error) {
+	if keyRing == nil {
+		return sensitiveColumns{WebhookURL: settings.WebhookURL, TranscriptChannelID: settings.TranscriptChannelID}, nil
+	}
+
+	var columns sensitiveColumns
+	if settings.WebhookURL != "" {
+		encrypted, err := keyRing.Encrypt(settings.WebhookURL)
+		if err != nil {
+			return sensitiveColumns{}, fmt.Errorf("failed to encrypt webhook URL: %w", err)
+		}
+		columns.WebhookURLEncrypted = encrypted
+	}
+	if settings.TranscriptChannelID != 0 {
+		encrypted, err := keyRing.Encrypt(settings.TranscriptChannelID.String())
+		if err != nil {
+			return sensitiveColumns{}, fmt.Errorf("failed to encrypt transcript channel: %w", err)
+		}
+		columns.TranscriptChannelIDEncrypted = encrypted
+	}
+	return columns, nil
+}
+
+// decrypt returns the webhook URL and transcript channel of the columns, whether they are encrypted or not.
+func (c sensitiveColumns) decrypt(keyRing *database.KeyRing) (string, snowflake.ID, error) {
+	webhookURL, transcriptChannelID := c.WebhookURL, c.TranscriptChannelID
+	if c.WebhookURLEncrypted == "" && c.TranscriptChannelIDEncrypted == "" {
+		return webhookURL, transcriptChannelID, nil
+	}
+	if keyRing == nil {
+		return "", 0, ErrNoEncryptionKey
+	}
+
+	if c.WebhookURLEncrypted != "" {
+		decrypted, err := keyRing.Decrypt(c.WebhookURLEncrypted)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to decrypt webhook URL: %w", err)
+		}
+		webhookURL = decrypted
+	}
+	if c.TranscriptChannelIDEncrypted != "" {
+		decrypted, err := keyRing.Decrypt(c.TranscriptChannelIDEncrypted)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to decrypt transcript channel: %w", err)
+		}
+		transcriptChannelID, err = snowflake.Parse(decrypted)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to decrypt transcript channel: %w", err)
+		}
+	}
+	return webhookURL, transcriptChannelID, nil
+}
+
+// needsRotation reports whether a value of the columns is plaintext or encrypted with a key other than the primary key.
+func (c sensitiveColumns) needsRotation(keyRing *database.KeyRing) bool {
+	return c.WebhookURL != "" || c.TranscriptChannelID != 0 ||
+		(c.WebhookURLEncrypted != "" && keyRing.NeedsRotation(c.WebhookURLEncrypted)) ||
+		(c.TranscriptChannelIDEncrypted != "" && keyRing.NeedsRotation(c.TranscriptChannelIDEncrypted))
+}
+
+// ReencryptGuildSettings encrypts the sensitive columns of guild settings stored as plaintext or with a key other than
+// the primary key of the key ring, so that old keys can be dropped. It returns the number of guilds re-encrypted.
+// Guilds saved while it runs are left as they were saved.
+func ReencryptGuildSettings(ctx context.Context, db *sqlx.DB, keyRing *database.KeyRing) (int, error) {
+	psql := database.DialectOf(db).StatementBuilder()
+	query, args, err := psql.Select("guild_id", "webhook_url", "transcript_channel_id", "webhook_url_encrypted", "transcript_channel_id_encrypted").
+		From("guild_settings").
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var rows []struct {
+		GuildID snowflake.ID `db:"guild_id"`
+		sensitiveColumns
+	}
+	if err := db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return 0, err
+	}
+
+	reencrypted := 0
+	for _, row := range rows {
+		if !row.needsRotation(keyRing) {
+			continue
+		}
+		webhookURL, transcriptChannelID, err := row.decrypt(keyRing)
+		if err != nil {
+			return reencrypted, fmt.Errorf("guild %s: %w", row.GuildID, err)
+		}
+		columns, err := encryptSensitiveColumns(keyRing, GuildSettings{WebhookURL: webhookURL, TranscriptChannelID: transcriptChannelID})
+		if err != nil {
+			return reencrypted, fmt.Errorf("guild %s: %w", row.GuildID, err)
+		}
+
+		query, args, err := psql.Update("guild_settings").
+			Set("webhook_url", columns.WebhookURL).
+			Set("transcript_channel_id", columns.TranscriptChannelID).
+			Set("webhook_url_encrypted", columns.WebhookURLEncrypted).
+			Set("transcript_channel_id_encrypted", columns.TranscriptChannelIDEncrypted).
+			Where(squirrel.Eq{
+				"guild_id":                        row.GuildID,
+				"webhook_url":                     row.WebhookURL,
+				"transcript_channel_id":           row.TranscriptChannelID,
+				"webhook_url_encrypted":           row.WebhookURLEncrypted,
+				"transcript_channel_id_encrypted": row.TranscriptChannelIDEncrypted,
+			}).
+			ToSql()
+		if err != nil {
+			return reencrypted, err
+		}
+		result, err := db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return reencrypted, err
+		}
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			reencrypted++
+		}
+	}
+	return reencrypted, nil
+}
@@ -0,0 +1,86 @@
+package settings
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database/databasetest"
+)
+
+func TestEncryptedGuildSettingsRepository(t *testing.T) {
+	for _, db := range databasetest.Databases {
+		t.Run(db.Name, func(t *testing.T) {
+			testEncryptedGuildSettingsRepository(t, db.Open(t, "../../migrations"))
+		})
+	}
+}
+
+func testEncryptedGuildSettingsRepository(t *testing.T, db *sqlx.DB) {
+	ctx := context.Background()
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+	old, err := database.NewKeyRing("old", map[string][]byte{"old": oldKey})
+	require.NoError(t, err)
+	rotated, err := database.NewKeyRing("new", map[string][]byte{"old": oldKey, "new": newKey})
+	require.NoError(t, err)
+
+	settings := DefaultGuildSettings(97531)
+	settings.WebhookURL = "https://example.com/hooks/secret"
+	settings.TranscriptChannelID = 13579
+
+	sensitiveOf := func() sensitiveColumns {
+		var columns sensitiveColumns
+		require.NoError(t, db.GetContext(ctx, &columns, db.Rebind("SELECT webhook_url, transcript_channel_id, webhook_url_encrypted, transcript_channel_id_encrypted FROM guild_settings WHERE guild_id = ?"), settings.GuildID))
+		return columns
+	}
+
+	// settings saved before encryption was enabled are still read, and encrypted on startup.
+	require.NoError(t, NewGuildSettingsRepository(db).Save(ctx, settings))
+	repo := NewEncryptedGuildSettingsRepository(db, old)
+	found, err := repo.Find(ctx, settings.GuildID)
+	require.NoError(t, err)
+	require.Equal(t, settings, found)
+
+	reencrypted, err := ReencryptGuildSettings(ctx, db, old)
+	require.NoError(t, err)
+	require.Equal(t, 1, reencrypted)
+	columns := sensitiveOf()
+	require.Empty(t, columns.WebhookURL)
+	require.Zero(t, columns.TranscriptChannelID)
+	require.False(t, old.NeedsRotation(columns.WebhookURLEncrypted))
+	require.False(t, old.NeedsRotation(columns.TranscriptChannelIDEncrypted))
+
+	found, err = repo.Find(ctx, settings.GuildID)
+	require.NoError(t, err)
+	require.Equal(t, settings, found)
+
+	_, err = NewGuildSettingsRepository(db).Find(ctx, settings.GuildID)
+	require.ErrorIs(t, err, ErrNoEncryptionKey)
+
+	// a new primary key re-encrypts what the old key encrypted, after which the old key is not needed.
+	reencrypted, err = ReencryptGuildSettings(ctx, db, rotated)
+	require.NoError(t, err)
+	require.Equal(t, 1, reencrypted)
+	reencrypted, err = ReencryptGuildSettings(ctx, db, rotated)
+	require.NoError(t, err)
+	require.Zero(t, reencrypted)
+
+	_, err = repo.Find(ctx, settings.GuildID)
+	require.ErrorIs(t, err, database.ErrUnknownKey)
+	found, err = NewEncryptedGuildSettingsRepository(db, rotated).Find(ctx, settings.GuildID)
+	require.NoError(t, err)
+	require.Equal(t, settings, found)
+
+	// empty values are not encrypted.
+	settings.WebhookURL = ""
+	settings.TranscriptChannelID = 0
+	require.NoError(t, NewEncryptedGuildSettingsRepository(db, rotated).Save(ctx, settings))
+	require.Equal(t, sensitiveColumns{}, sensitiveOf())
+
+	require.NoError(t, repo.Delete(ctx, settings.GuildID))
+}
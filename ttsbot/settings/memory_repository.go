@@ -0,0 +1,60 @@
+package settings
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// NewMemoryGuildSettingsRepository returns a GuildSettingsRepository that keeps settings in memory.
+// It is used when the bot runs without a database, so settings are lost on restart.
+func NewMemoryGuildSettingsRepository() GuildSettingsRepository {
+	return &memoryGuildSettingsRepository{
+		settings: make(map[snowflake.ID]GuildSettings),
+	}
+}
+
+type memoryGuildSettingsRepository struct {
+	mu       sync.RWMutex
+	settings map[snowflake.ID]GuildSettings
+}
+
+func (r *memoryGuildSettingsRepository) Find(ctx context.Context, guildID snowflake.ID) (GuildSettings, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	settings, ok := r.settings[guildID]
+	if !ok {
+		return GuildSettings{}, ErrNotFound
+	}
+	settings.SilentRoleIDs = slices.Clone(settings.SilentRoleIDs)
+	settings.AnnouncementTemplates = maps.Clone(settings.AnnouncementTemplates)
+	settings.VoicePacks = slices.Clone(settings.VoicePacks)
+	settings.SpellOutPatterns = slices.Clone(settings.SpellOutPatterns)
+	return settings, nil
+}
+
+func (r *memoryGuildSettingsRepository) Save(ctx context.Context, settings GuildSettings) error {
+	if err := settings.validate(); err != nil {
+		return fmt.Errorf("invalid guild settings: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	settings.SilentRoleIDs = slices.Clone(settings.SilentRoleIDs)
+	settings.AnnouncementTemplates = maps.Clone(settings.AnnouncementTemplates)
+	settings.VoicePacks = slices.Clone(settings.VoicePacks)
+	settings.SpellOutPatterns = slices.Clone(settings.SpellOutPatterns)
+	r.settings[settings.GuildID] = settings
+	return nil
+}
+
+func (r *memoryGuildSettingsRepository) Delete(ctx context.Context, guildID snowflake.ID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.settings, guildID)
+	return nil
+}
@@ -0,0 +1,102 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// ErrLimitExceeded is returned when settings exceed a limit set by the operator.
+var ErrLimitExceeded = errors.New("guild settings exceed an operator limit")
+
+// Policy is the operator-wide configuration of guild settings.
+type Policy struct {
+	// Defaults seed the settings of guilds that have not configured anything yet. Its GuildID is ignored.
+	Defaults GuildSettings
+	Limits   Limits
+}
+
+// Limits are hard caps that guild admins cannot exceed.
+type Limits struct {
+	// MaxMessageLength caps the max message length of guilds. Zero leaves it uncapped.
+	MaxMessageLength int
+	// LockAnnouncements keeps the announcement toggles of every guild at their defaults.
+	LockAnnouncements bool
+}
+
+// DefaultPolicy returns the policy used when the operator does not configure anything.
+func DefaultPolicy() Policy {
+	return Policy{Defaults: DefaultGuildSettings(0)}
+}
+
+// seed returns the settings of a guild that has not configured anything yet.
+func (p Policy) seed(guildID snowflake.ID) GuildSettings {
+	settings := p.Defaults
+	settings.GuildID = guildID
+	return p.enforce(settings)
+}
+
+// enforce caps the settings to the limits.
+func (p Policy) enforce(settings GuildSettings) GuildSettings {
+	if p.Limits.MaxMessageLength > 0 {
+		settings.MaxMessageLength = min(settings.MaxMessageLength, p.Limits.MaxMessageLength)
+	}
+	if p.Limits.LockAnnouncements {
+		settings.AnnounceJoin = p.Defaults.AnnounceJoin
+		settings.AnnounceLeave = p.Defaults.AnnounceLeave
+		settings.AnnounceLaunch = p.Defaults.AnnounceLaunch
+		settings.AnnounceFarewell = p.Defaults.AnnounceFarewell
+		settings.AnnounceVoiceActivity = p.Defaults.AnnounceVoiceActivity
+	}
+	return settings
+}
+
+// check returns ErrLimitExceeded if the settings exceed the limits.
+func (p Policy) check(settings GuildSettings) error {
+	if p.Limits.MaxMessageLength > 0 && settings.MaxMessageLength > p.Limits.MaxMessageLength {
+		return fmt.Errorf("%w: max message length is capped at %d", ErrLimitExceeded, p.Limits.MaxMessageLength)
+	}
+	if p.Limits.LockAnnouncements && (settings.AnnounceJoin != p.Defaults.AnnounceJoin ||
+		settings.AnnounceLeave != p.Defaults.AnnounceLeave ||
+		settings.AnnounceLaunch != p.Defaults.AnnounceLaunch ||
+		settings.AnnounceFarewell != p.Defaults.AnnounceFarewell ||
+		settings.AnnounceVoiceActivity != p.Defaults.AnnounceVoiceActivity) {
+		return fmt.Errorf("%w: announcements are locked", ErrLimitExceeded)
+	}
+	return nil
+}
+
+// NewPolicyRepository wraps repository so that the policy applies to every guild.
+// Find returns the seeded defaults instead of ErrNotFound for guilds that have not configured anything yet,
+// and caps stored settings to the limits; Save rejects settings exceeding the limits with ErrLimitExceeded.
+func NewPolicyRepository(repository GuildSettingsRepository, policy Policy) GuildSettingsRepository {
+	return &policyRepository{
+		GuildSettingsRepository: repository,
+		policy:                  policy,
+	}
+}
+
+type policyRepository struct {
+	GuildSettingsRepository
+	policy Policy
+}
+
+func (r *policyRepository) Find(ctx context.Context, guildID snowflake.ID) (GuildSettings, error) {
+	settings, err := r.GuildSettingsRepository.Find(ctx, guildID)
+	if errors.Is(err, ErrNotFound) {
+		return r.policy.seed(guildID), nil
+	}
+	if err != nil {
+		return GuildSettings{}, err
+	}
+	return r.policy.enforce(settings), nil
+}
+
+func (r *policyRepository) Save(ctx context.Context, settings GuildSettings) error {
+	if err := r.policy.check(settings); err != nil {
+		return err
+	}
+	return r.GuildSettingsRepository.Save(ctx, settings)
+}
@@ -0,0 +1,60 @@
+package settings
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyRepository(t *testing.T) {
+	ctx := context.Background()
+
+	policy := DefaultPolicy()
+	policy.Defaults.MaxMessageLength = 80
+	policy.Defaults.AnnounceLaunch = false
+	policy.Limits = Limits{MaxMessageLength: 200, LockAnnouncements: true}
+
+	t.Run("Find seeds defaults", func(t *testing.T) {
+		repo := NewPolicyRepository(NewMemoryGuildSettingsRepository(), policy)
+
+		found, err := repo.Find(ctx, 12345)
+		require.NoError(t, err)
+
+		expected := DefaultGuildSettings(12345)
+		expected.MaxMessageLength = 80
+		expected.AnnounceLaunch = false
+		require.Equal(t, expected, found)
+	})
+
+	t.Run("Find enforces limits on stored settings", func(t *testing.T) {
+		inner := NewMemoryGuildSettingsRepository()
+		stored := DefaultGuildSettings(12345)
+		stored.MaxMessageLength = 500
+		stored.AnnounceLaunch = true
+		require.NoError(t, inner.Save(ctx, stored))
+
+		found, err := NewPolicyRepository(inner, policy).Find(ctx, 12345)
+		require.NoError(t, err)
+		require.Equal(t, 200, found.MaxMessageLength)
+		require.False(t, found.AnnounceLaunch)
+	})
+
+	t.Run("Save rejects settings exceeding limits", func(t *testing.T) {
+		repo := NewPolicyRepository(NewMemoryGuildSettingsRepository(), policy)
+
+		settings, err := repo.Find(ctx, 12345)
+		require.NoError(t, err)
+
+		tooLong := settings
+		tooLong.MaxMessageLength = 201
+		require.ErrorIs(t, repo.Save(ctx, tooLong), ErrLimitExceeded)
+
+		unlocked := settings
+		unlocked.AnnounceJoin = false
+		require.ErrorIs(t, repo.Save(ctx, unlocked), ErrLimitExceeded)
+
+		settings.MaxMessageLength = 200
+		require.NoError(t, repo.Save(ctx, settings))
+	})
+}
@@ -0,0 +1,403 @@
+package settings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database"
+)
+
+var (
+	ErrNotFound = errors.New("guild settings not found")
+)
+
+type GuildSettingsRepository interface {
+	Find(ctx context.Context, guildID snowflake.ID) (GuildSettings, error)
+	Save(ctx context.Context, settings GuildSettings) error
+	Delete(ctx context.Context, guildID snowflake.ID) error
+}
+
+// FindOrDefault returns the stored settings for the guild,
+// or the default settings if the guild has not configured anything yet.
+func FindOrDefault(ctx context.Context, repository GuildSettingsRepository, guildID snowflake.ID) (GuildSettings, error) {
+	settings, err := repository.Find(ctx, guildID)
+	if errors.Is(err, ErrNotFound) {
+		return DefaultGuildSettings(guildID), nil
+	}
+	if err != nil {
+		return GuildSettings{}, err
+	}
+	return settings, nil
+}
+
+func NewGuildSettingsRepository(db *sqlx.DB) GuildSettingsRepository {
+	return NewEncryptedGuildSettingsRepository(db, nil)
+}
+
+// NewEncryptedGuildSettingsRepository creates a repository encrypting the webhook URL and transcript channel of guilds
+// with the key ring. Settings saved before are still read, and encrypted once saved again or by ReencryptGuildSettings.
+// A nil key ring stores them as plaintext, while it can not read settings encrypted before.
+func NewEncryptedGuildSettingsRepository(db *sqlx.DB, keyRing *database.KeyRing) GuildSettingsRepository {
+	dialect := database.DialectOf(db)
+	return &guildSettingsRepositoryImpl{
+		db:      db,
+		psql:    dialect.StatementBuilder(),
+		dialect: dialect,
+		keyRing: keyRing,
+	}
+}
+
+type guildSettingsRepositoryImpl struct {
+	db      *sqlx.DB
+	psql    squirrel.StatementBuilderType
+	dialect database.Dialect
+	keyRing *database.KeyRing
+}
+
+type guildSettingsRow struct {
+	GuildID               snowflake.ID   `db:"guild_id"`
+	TakeoverPolicy        TakeoverPolicy `db:"takeover_policy"`
+	AnnounceVoiceActivity bool           `db:"announce_voice_activity"`
+	AnnounceJoin          bool           `db:"announce_join"`
+	AnnounceLeave         bool           `db:"announce_leave"`
+	AnnounceLaunch        bool           `db:"announce_launch"`
+	AnnounceFarewell      bool           `db:"announce_farewell"`
+	SkipReaction          string         `db:"skip_reaction"`
+	MaxMessageLength      int            `db:"max_message_length"`
+	CodeBlockMode         CodeBlockMode  `db:"code_block_mode"`
+	OmitStrikethrough     bool           `db:"omit_strikethrough"`
+	AnnounceMarkdown      bool           `db:"announce_markdown"`
+	Timezone              string         `db:"timezone"`
+	NameSource            NameSource     `db:"name_source"`
+	StripNameDecorations  bool           `db:"strip_name_decorations"`
+	CommandPrefix         string         `db:"command_prefix"`
+	SelfDeaf              bool           `db:"self_deaf"`
+	SelfMute              bool           `db:"self_mute"`
+	EphemeralResponses    bool           `db:"ephemeral_responses"`
+	SpellOut              bool           `db:"spell_out"`
+	VoiceTags             bool           `db:"voice_tags"`
+	CodeSwitch            bool           `db:"code_switch"`
+	SoftenAsides          bool           `db:"soften_asides"`
+	YieldToBots           bool           `db:"yield_to_bots"`
+	BargeIn               BargeIn        `db:"barge_in"`
+	CreatedAt             time.Time      `db:"created_at"`
+	UpdatedAt             time.Time      `db:"updated_at"`
+	sensitiveColumns
+}
+
+func (r *guildSettingsRepositoryImpl) Find(ctx context.Context, guildID snowflake.ID) (GuildSettings, error) {
+	query, args, err := r.psql.Select("guild_id", "takeover_policy", "announce_voice_activity", "announce_join", "announce_leave", "announce_launch", "announce_farewell", "skip_reaction", "max_message_length", "code_block_mode", "omit_strikethrough", "announce_markdown", "timezone", "name_source", "strip_name_decorations", "command_prefix", "self_deaf", "self_mute", "webhook_url", "ephemeral_responses", "transcript_channel_id", "spell_out", "voice_tags", "code_switch", "soften_asides", "yield_to_bots", "barge_in", "webhook_url_encrypted", "transcript_channel_id_encrypted", "created_at", "updated_at").
+		From("guild_settings").
+		Where(squirrel.Eq{"guild_id": guildID}).
+		ToSql()
+	if err != nil {
+		return GuildSettings{}, err
+	}
+
+	var row guildSettingsRow
+	if err := r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return GuildSettings{}, ErrNotFound
+		}
+		return GuildSettings{}, err
+	}
+
+	webhookURL, transcriptChannelID, err := row.decrypt(r.keyRing)
+	if err != nil {
+		return GuildSettings{}, err
+	}
+
+	silentRoleIDs, err := r.findSilentRoleIDs(ctx, guildID)
+	if err != nil {
+		return GuildSettings{}, err
+	}
+
+	announcementTemplates, err := r.findAnnouncementTemplates(ctx, guildID)
+	if err != nil {
+		return GuildSettings{}, err
+	}
+
+	voicePacks, err := r.findVoicePacks(ctx, guildID)
+	if err != nil {
+		return GuildSettings{}, err
+	}
+
+	spellOutPatterns, err := r.findSpellOutPatterns(ctx, guildID)
+	if err != nil {
+		return GuildSettings{}, err
+	}
+
+	return GuildSettings{
+		GuildID:               row.GuildID,
+		TakeoverPolicy:        row.TakeoverPolicy,
+		SilentRoleIDs:         silentRoleIDs,
+		AnnounceVoiceActivity: row.AnnounceVoiceActivity,
+		AnnounceJoin:          row.AnnounceJoin,
+		AnnounceLeave:         row.AnnounceLeave,
+		AnnounceLaunch:        row.AnnounceLaunch,
+		AnnounceFarewell:      row.AnnounceFarewell,
+		SkipReaction:          row.SkipReaction,
+		MaxMessageLength:      row.MaxMessageLength,
+		CodeBlockMode:         row.CodeBlockMode,
+		OmitStrikethrough:     row.OmitStrikethrough,
+		AnnounceMarkdown:      row.AnnounceMarkdown,
+		Timezone:              row.Timezone,
+		NameSource:            row.NameSource,
+		StripNameDecorations:  row.StripNameDecorations,
+		CommandPrefix:         row.CommandPrefix,
+		SelfDeaf:              row.SelfDeaf,
+		SelfMute:              row.SelfMute,
+		WebhookURL:            webhookURL,
+		EphemeralResponses:    row.EphemeralResponses,
+		TranscriptChannelID:   transcriptChannelID,
+		SpellOut:              row.SpellOut,
+		SpellOutPatterns:      spellOutPatterns,
+		VoiceTags:             row.VoiceTags,
+		CodeSwitch:            row.CodeSwitch,
+		SoftenAsides:          row.SoftenAsides,
+		YieldToBots:           row.YieldToBots,
+		BargeIn:               row.BargeIn,
+		AnnouncementTemplates: announcementTemplates,
+		VoicePacks:            voicePacks,
+	}, nil
+}
+
+func (r *guildSettingsRepositoryImpl) findSilentRoleIDs(ctx context.Context, guildID snowflake.ID) ([]snowflake.ID, error) {
+	query, args, err := r.psql.Select("role_id").
+		From("guild_silent_roles").
+		Where(squirrel.Eq{"guild_id": guildID}).
+		OrderBy("created_at", "role_id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var roleIDs []snowflake.ID
+	if err := r.db.SelectContext(ctx, &roleIDs, query, args...); err != nil {
+		return nil, err
+	}
+	return roleIDs, nil
+}
+
+func (r *guildSettingsRepositoryImpl) findAnnouncementTemplates(ctx context.Context, guildID snowflake.ID) (map[AnnouncementKey]string, error) {
+	query, args, err := r.psql.Select("announcement_key", "template").
+		From("guild_announcement_templates").
+		Where(squirrel.Eq{"guild_id": guildID}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Key      AnnouncementKey `db:"announcement_key"`
+		Template string          `db:"template"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	templates := make(map[AnnouncementKey]string, len(rows))
+	for _, row := range rows {
+		templates[row.Key] = row.Template
+	}
+	return templates, nil
+}
+
+func (r *guildSettingsRepositoryImpl) findVoicePacks(ctx context.Context, guildID snowflake.ID) ([]string, error) {
+	query, args, err := r.psql.Select("pack_id").
+		From("guild_voice_packs").
+		Where(squirrel.Eq{"guild_id": guildID}).
+		OrderBy("position").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var packIDs []string
+	if err := r.db.SelectContext(ctx, &packIDs, query, args...); err != nil {
+		return nil, err
+	}
+	return packIDs, nil
+}
+
+func (r *guildSettingsRepositoryImpl) findSpellOutPatterns(ctx context.Context, guildID snowflake.ID) ([]string, error) {
+	query, args, err := r.psql.Select("pattern").
+		From("guild_spell_out_patterns").
+		Where(squirrel.Eq{"guild_id": guildID}).
+		OrderBy("position").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	if err := r.db.SelectContext(ctx, &patterns, query, args...); err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
+
+func (r *guildSettingsRepositoryImpl) Save(ctx context.Context, settings GuildSettings) error {
+	if err := settings.validate(); err != nil {
+		return fmt.Errorf("invalid guild settings: %w", err)
+	}
+
+	sensitive, err := encryptSensitiveColumns(r.keyRing, settings)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	insert := r.psql.Insert("guild_settings").
+		Columns("guild_id", "takeover_policy", "announce_voice_activity", "announce_join", "announce_leave", "announce_launch", "announce_farewell", "skip_reaction", "max_message_length", "code_block_mode", "omit_strikethrough", "announce_markdown", "timezone", "name_source", "strip_name_decorations", "command_prefix", "self_deaf", "self_mute", "webhook_url", "ephemeral_responses", "transcript_channel_id", "spell_out", "voice_tags", "code_switch", "soften_asides", "yield_to_bots", "barge_in", "webhook_url_encrypted", "transcript_channel_id_encrypted", "created_at", "updated_at").
+		Values(settings.GuildID, settings.TakeoverPolicy, settings.AnnounceVoiceActivity, settings.AnnounceJoin, settings.AnnounceLeave, settings.AnnounceLaunch, settings.AnnounceFarewell, settings.SkipReaction, settings.MaxMessageLength, settings.CodeBlockMode, settings.OmitStrikethrough, settings.AnnounceMarkdown, settings.Timezone, settings.NameSource, settings.StripNameDecorations, settings.CommandPrefix, settings.SelfDeaf, settings.SelfMute, sensitive.WebhookURL, settings.EphemeralResponses, sensitive.TranscriptChannelID, settings.SpellOut, settings.VoiceTags, settings.CodeSwitch, settings.SoftenAsides, settings.YieldToBots, settings.BargeIn, sensitive.WebhookURLEncrypted, sensitive.TranscriptChannelIDEncrypted, now, now)
+	query, args, err := r.dialect.Upsert(insert, []string{"guild_id"},
+		[]string{"takeover_policy", "announce_voice_activity", "announce_join", "announce_leave", "announce_launch", "announce_farewell", "skip_reaction", "max_message_length", "code_block_mode", "omit_strikethrough", "announce_markdown", "timezone", "name_source", "strip_name_decorations", "command_prefix", "self_deaf", "self_mute", "webhook_url", "ephemeral_responses", "transcript_channel_id", "spell_out", "voice_tags", "code_switch", "soften_asides", "yield_to_bots", "barge_in", "webhook_url_encrypted", "transcript_channel_id_encrypted", "updated_at"}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	query, args, err = r.psql.Delete("guild_silent_roles").
+		Where(squirrel.Eq{"guild_id": settings.GuildID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	if len(settings.SilentRoleIDs) > 0 {
+		insert := r.psql.Insert("guild_silent_roles").
+			Columns("guild_id", "role_id", "created_at")
+		for _, roleID := range settings.SilentRoleIDs {
+			insert = insert.Values(settings.GuildID, roleID, now)
+		}
+		query, args, err = insert.ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	query, args, err = r.psql.Delete("guild_announcement_templates").
+		Where(squirrel.Eq{"guild_id": settings.GuildID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	if len(settings.AnnouncementTemplates) > 0 {
+		insert := r.psql.Insert("guild_announcement_templates").
+			Columns("guild_id", "announcement_key", "template", "created_at")
+		for key, template := range settings.AnnouncementTemplates {
+			insert = insert.Values(settings.GuildID, key, template, now)
+		}
+		query, args, err = insert.ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	query, args, err = r.psql.Delete("guild_voice_packs").
+		Where(squirrel.Eq{"guild_id": settings.GuildID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	if len(settings.VoicePacks) > 0 {
+		insert := r.psql.Insert("guild_voice_packs").
+			Columns("guild_id", "pack_id", "position", "created_at")
+		for i, packID := range settings.VoicePacks {
+			insert = insert.Values(settings.GuildID, packID, i, now)
+		}
+		query, args, err = insert.ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	query, args, err = r.psql.Delete("guild_spell_out_patterns").
+		Where(squirrel.Eq{"guild_id": settings.GuildID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	if len(settings.SpellOutPatterns) > 0 {
+		insert := r.psql.Insert("guild_spell_out_patterns").
+			Columns("guild_id", "pattern", "position", "created_at")
+		for i, pattern := range settings.SpellOutPatterns {
+			insert = insert.Values(settings.GuildID, pattern, i, now)
+		}
+		query, args, err = insert.ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *guildSettingsRepositoryImpl) Delete(ctx context.Context, guildID snowflake.ID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"guild_silent_roles", "guild_announcement_templates", "guild_voice_packs", "guild_spell_out_patterns", "guild_settings"} {
+		query, args, err := r.psql.Delete(table).
+			Where(squirrel.Eq{"guild_id": guildID}).
+			ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,165 @@
+package settings
+
+import (
+	"context"
+	"testing"
+
+	"github.com/disgoorg/snowflake/v2"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database/databasetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuildSettingsRepository(t *testing.T) {
+	for _, database := range databasetest.Databases {
+		t.Run(database.Name, func(t *testing.T) {
+			testGuildSettingsRepository(t, NewGuildSettingsRepository(database.Open(t, "../../migrations")))
+		})
+	}
+}
+
+func TestMemoryGuildSettingsRepository(t *testing.T) {
+	testGuildSettingsRepository(t, NewMemoryGuildSettingsRepository())
+}
+
+func testGuildSettingsRepository(t *testing.T, repo GuildSettingsRepository) {
+	ctx := context.Background()
+
+	t.Run("Save and Find", func(t *testing.T) {
+		settings := GuildSettings{GuildID: 12345, TakeoverPolicy: TakeoverPolicyMove, AnnounceVoiceActivity: true, AnnounceJoin: true, AnnounceLaunch: true, AnnounceFarewell: true, SkipReaction: "⏭️", MaxMessageLength: 500, CodeBlockMode: CodeBlockModeFirstLine, OmitStrikethrough: true, AnnounceMarkdown: true, Timezone: "Asia/Tokyo", NameSource: NameSourceUsername, StripNameDecorations: true, CommandPrefix: ";", SelfDeaf: false, SelfMute: true, WebhookURL: "https://example.com/hooks/tts", EphemeralResponses: true, TranscriptChannelID: 24680, SpellOut: true, VoiceTags: true, CodeSwitch: true, SoftenAsides: true, YieldToBots: true, BargeIn: BargeInPause}
+
+		require.NoError(t, repo.Save(ctx, settings))
+
+		found, err := repo.Find(ctx, settings.GuildID)
+		require.NoError(t, err)
+		require.Equal(t, settings, found)
+	})
+
+	t.Run("Save and Update", func(t *testing.T) {
+		guildID := snowflake.ID(67890)
+
+		require.NoError(t, repo.Save(ctx, GuildSettings{GuildID: guildID, TakeoverPolicy: TakeoverPolicyMove, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength}))
+		require.NoError(t, repo.Save(ctx, GuildSettings{GuildID: guildID, TakeoverPolicy: TakeoverPolicyRefuse, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength}))
+
+		found, err := repo.Find(ctx, guildID)
+		require.NoError(t, err)
+		require.Equal(t, TakeoverPolicyRefuse, found.TakeoverPolicy)
+	})
+
+	t.Run("Save Silent Roles", func(t *testing.T) {
+		guildID := snowflake.ID(13579)
+
+		require.NoError(t, repo.Save(ctx, GuildSettings{GuildID: guildID, TakeoverPolicy: TakeoverPolicyConfirm, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength, SilentRoleIDs: []snowflake.ID{1, 2}}))
+		found, err := repo.Find(ctx, guildID)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []snowflake.ID{1, 2}, found.SilentRoleIDs)
+
+		require.NoError(t, repo.Save(ctx, GuildSettings{GuildID: guildID, TakeoverPolicy: TakeoverPolicyConfirm, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength, SilentRoleIDs: []snowflake.ID{3}}))
+		found, err = repo.Find(ctx, guildID)
+		require.NoError(t, err)
+		require.Equal(t, []snowflake.ID{3}, found.SilentRoleIDs)
+		require.True(t, found.IsSilent([]snowflake.ID{4, 3}))
+		require.False(t, found.IsSilent([]snowflake.ID{1}))
+	})
+
+	t.Run("Save Announcement Templates", func(t *testing.T) {
+		guildID := snowflake.ID(24680)
+
+		templates := map[AnnouncementKey]string{AnnouncementKeyLaunch: "Hello!", AnnouncementKeyJoin: "{name} is here"}
+		require.NoError(t, repo.Save(ctx, GuildSettings{GuildID: guildID, TakeoverPolicy: TakeoverPolicyConfirm, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength, AnnouncementTemplates: templates}))
+		found, err := repo.Find(ctx, guildID)
+		require.NoError(t, err)
+		require.Equal(t, templates, found.AnnouncementTemplates)
+
+		require.NoError(t, repo.Save(ctx, GuildSettings{GuildID: guildID, TakeoverPolicy: TakeoverPolicyConfirm, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength, AnnouncementTemplates: map[AnnouncementKey]string{AnnouncementKeyLeave: "bye {name}"}}))
+		found, err = repo.Find(ctx, guildID)
+		require.NoError(t, err)
+		require.Equal(t, map[AnnouncementKey]string{AnnouncementKeyLeave: "bye {name}"}, found.AnnouncementTemplates)
+		announcement, ok := found.Announcement(AnnouncementKeyLeave, "Alice and Bob")
+		require.True(t, ok)
+		require.Equal(t, "bye Alice and Bob", announcement)
+		_, ok = found.Announcement(AnnouncementKeyJoin, "Alice")
+		require.False(t, ok)
+	})
+
+	t.Run("Save Voice Packs", func(t *testing.T) {
+		guildID := snowflake.ID(11223)
+
+		require.NoError(t, repo.Save(ctx, GuildSettings{GuildID: guildID, TakeoverPolicy: TakeoverPolicyConfirm, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength, VoicePacks: []string{"winter", "new-year"}}))
+		found, err := repo.Find(ctx, guildID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"winter", "new-year"}, found.VoicePacks, "packs keep their order, which decides the pack spoken when several overlap")
+
+		require.NoError(t, repo.Save(ctx, GuildSettings{GuildID: guildID, TakeoverPolicy: TakeoverPolicyConfirm, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength}))
+		found, err = repo.Find(ctx, guildID)
+		require.NoError(t, err)
+		require.Empty(t, found.VoicePacks)
+	})
+
+	t.Run("Save Spell-Out Patterns", func(t *testing.T) {
+		guildID := snowflake.ID(44556)
+
+		patterns := []string{`order-(\d+)`, `[A-Z]{3}\d{4}`}
+		require.NoError(t, repo.Save(ctx, GuildSettings{GuildID: guildID, TakeoverPolicy: TakeoverPolicyConfirm, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength, SpellOut: true, SpellOutPatterns: patterns}))
+		found, err := repo.Find(ctx, guildID)
+		require.NoError(t, err)
+		require.True(t, found.SpellOut)
+		require.Equal(t, patterns, found.SpellOutPatterns)
+
+		err = repo.Save(ctx, GuildSettings{GuildID: guildID, TakeoverPolicy: TakeoverPolicyConfirm, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength, SpellOutPatterns: []string{`(unclosed`}})
+		require.Error(t, err)
+	})
+
+	t.Run("Save Invalid", func(t *testing.T) {
+		err := repo.Save(ctx, GuildSettings{GuildID: 1, TakeoverPolicy: "unknown", CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength})
+		require.Error(t, err)
+
+		err = repo.Save(ctx, GuildSettings{GuildID: 1, TakeoverPolicy: TakeoverPolicyMove, CodeBlockMode: "unknown", NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength})
+		require.Error(t, err)
+
+		err = repo.Save(ctx, GuildSettings{GuildID: 1, TakeoverPolicy: TakeoverPolicyMove, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength, Timezone: "Mars/Olympus_Mons"})
+		require.Error(t, err)
+
+		err = repo.Save(ctx, GuildSettings{GuildID: 1, TakeoverPolicy: TakeoverPolicyMove, CodeBlockMode: CodeBlockModeAnnounce, NameSource: "unknown", MaxMessageLength: DefaultMaxMessageLength})
+		require.Error(t, err)
+
+		err = repo.Save(ctx, GuildSettings{GuildID: 1, TakeoverPolicy: TakeoverPolicyMove, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength, CommandPrefix: "tts "})
+		require.Error(t, err)
+
+		err = repo.Save(ctx, GuildSettings{GuildID: 1, TakeoverPolicy: TakeoverPolicyMove, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname})
+		require.Error(t, err)
+
+		err = repo.Save(ctx, GuildSettings{GuildID: 1, TakeoverPolicy: TakeoverPolicyMove, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: MaxMaxMessageLength + 1})
+		require.Error(t, err)
+
+		err = repo.Save(ctx, GuildSettings{GuildID: 1, TakeoverPolicy: TakeoverPolicyMove, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength, WebhookURL: "http://example.com/hooks/tts"})
+		require.Error(t, err)
+
+		err = repo.Save(ctx, GuildSettings{GuildID: 1, TakeoverPolicy: TakeoverPolicyMove, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength, AnnouncementTemplates: map[AnnouncementKey]string{AnnouncementKeyJoin: "{user} joined"}})
+		require.Error(t, err)
+	})
+
+	t.Run("Find Not Found", func(t *testing.T) {
+		_, err := repo.Find(ctx, 54321)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("FindOrDefault", func(t *testing.T) {
+		found, err := FindOrDefault(ctx, repo, 54321)
+		require.NoError(t, err)
+		require.Equal(t, DefaultGuildSettings(54321), found)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		guildID := snowflake.ID(98765)
+
+		require.NoError(t, repo.Save(ctx, GuildSettings{GuildID: guildID, TakeoverPolicy: TakeoverPolicyMove, CodeBlockMode: CodeBlockModeAnnounce, NameSource: NameSourceNickname, MaxMessageLength: DefaultMaxMessageLength}))
+		require.NoError(t, repo.Delete(ctx, guildID))
+
+		_, err := repo.Find(ctx, guildID)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
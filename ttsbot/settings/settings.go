@@ -0,0 +1,439 @@
+package settings
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// TakeoverPolicy decides what happens when /join is run for a voice channel
+// while the guild already has a session in another voice channel.
+type TakeoverPolicy string
+
+const (
+	// TakeoverPolicyConfirm asks the user to confirm before moving the session.
+	TakeoverPolicyConfirm TakeoverPolicy = "confirm"
+	// TakeoverPolicyMove moves the session without asking.
+	TakeoverPolicyMove TakeoverPolicy = "move"
+	// TakeoverPolicyRefuse keeps the existing session and rejects the command.
+	TakeoverPolicyRefuse TakeoverPolicy = "refuse"
+)
+
+var TakeoverPolicies = []TakeoverPolicy{
+	TakeoverPolicyConfirm,
+	TakeoverPolicyMove,
+	TakeoverPolicyRefuse,
+}
+
+func (p TakeoverPolicy) String() string {
+	return string(p)
+}
+
+func (p TakeoverPolicy) validate() error {
+	switch p {
+	case TakeoverPolicyConfirm, TakeoverPolicyMove, TakeoverPolicyRefuse:
+		return nil
+	default:
+		return fmt.Errorf("unknown takeover policy: %s", p)
+	}
+}
+
+// CodeBlockMode decides how code blocks in messages are read.
+type CodeBlockMode string
+
+const (
+	// CodeBlockModeAnnounce reads only the language of the code block, e.g. "code block: Go".
+	CodeBlockModeAnnounce CodeBlockMode = "announce"
+	// CodeBlockModeSkip does not read code blocks at all.
+	CodeBlockModeSkip CodeBlockMode = "skip"
+	// CodeBlockModeFirstLine reads the first line of the code block.
+	CodeBlockModeFirstLine CodeBlockMode = "first_line"
+)
+
+var CodeBlockModes = []CodeBlockMode{
+	CodeBlockModeAnnounce,
+	CodeBlockModeSkip,
+	CodeBlockModeFirstLine,
+}
+
+func (m CodeBlockMode) String() string {
+	return string(m)
+}
+
+func (m CodeBlockMode) validate() error {
+	switch m {
+	case CodeBlockModeAnnounce, CodeBlockModeSkip, CodeBlockModeFirstLine:
+		return nil
+	default:
+		return fmt.Errorf("unknown code block mode: %s", m)
+	}
+}
+
+// NameSource decides which name of a member is read as the speaker name.
+type NameSource string
+
+const (
+	// NameSourceNickname reads the server nickname, falling back to the display name and the username.
+	NameSourceNickname NameSource = "nickname"
+	// NameSourceDisplayName reads the global display name, falling back to the username.
+	NameSourceDisplayName NameSource = "display_name"
+	// NameSourceUsername reads the username.
+	NameSourceUsername NameSource = "username"
+)
+
+var NameSources = []NameSource{
+	NameSourceNickname,
+	NameSourceDisplayName,
+	NameSourceUsername,
+}
+
+func (n NameSource) String() string {
+	return string(n)
+}
+
+func (n NameSource) validate() error {
+	switch n {
+	case NameSourceNickname, NameSourceDisplayName, NameSourceUsername:
+		return nil
+	default:
+		return fmt.Errorf("unknown name source: %s", n)
+	}
+}
+
+// BargeIn decides what reading does while a member speaks in the voice channel.
+type BargeIn string
+
+const (
+	// BargeInOff keeps reading over members.
+	BargeInOff BargeIn = "off"
+	// BargeInDuck lowers the volume of reading while a member speaks.
+	BargeInDuck BargeIn = "duck"
+	// BargeInPause pauses reading while a member speaks, continuing where it paused once they stop.
+	BargeInPause BargeIn = "pause"
+)
+
+var BargeIns = []BargeIn{
+	BargeInOff,
+	BargeInDuck,
+	BargeInPause,
+}
+
+func (b BargeIn) String() string {
+	return string(b)
+}
+
+func (b BargeIn) validate() error {
+	switch b {
+	// the zero value keeps reading over members, like BargeInOff.
+	case "", BargeInOff, BargeInDuck, BargeInPause:
+		return nil
+	default:
+		return fmt.Errorf("unknown barge-in: %s", b)
+	}
+}
+
+// AnnouncementKey names an announcement whose text a guild can override with a template.
+type AnnouncementKey string
+
+const (
+	// AnnouncementKeyLaunch is the phrase spoken when a session starts.
+	AnnouncementKeyLaunch AnnouncementKey = "launch"
+	// AnnouncementKeyJoin is spoken when members join the voice channel.
+	AnnouncementKeyJoin AnnouncementKey = "join"
+	// AnnouncementKeyLeave is spoken when members leave the voice channel.
+	AnnouncementKeyLeave AnnouncementKey = "leave"
+)
+
+var AnnouncementKeys = []AnnouncementKey{
+	AnnouncementKeyLaunch,
+	AnnouncementKeyJoin,
+	AnnouncementKeyLeave,
+}
+
+func (k AnnouncementKey) String() string {
+	return string(k)
+}
+
+func (k AnnouncementKey) validate() error {
+	switch k {
+	case AnnouncementKeyLaunch, AnnouncementKeyJoin, AnnouncementKeyLeave:
+		return nil
+	default:
+		return fmt.Errorf("unknown announcement key: %s", k)
+	}
+}
+
+// NamePlaceholder is replaced with the names of the members in join and leave templates.
+const NamePlaceholder = "{name}"
+
+// MaxAnnouncementTemplateLength is the maximum number of characters of an announcement template.
+const MaxAnnouncementTemplateLength = 200
+
+var placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// ValidateAnnouncementTemplate returns an error if template cannot be used for the announcement of key.
+// Join and leave templates may contain NamePlaceholder; no other placeholder is known.
+func ValidateAnnouncementTemplate(key AnnouncementKey, template string) error {
+	if err := key.validate(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("announcement template cannot be empty")
+	}
+	if utf8.RuneCountInString(template) > MaxAnnouncementTemplateLength {
+		return fmt.Errorf("announcement template exceeds %d characters", MaxAnnouncementTemplateLength)
+	}
+	for _, placeholder := range placeholderPattern.FindAllString(template, -1) {
+		if placeholder != NamePlaceholder || key == AnnouncementKeyLaunch {
+			return fmt.Errorf("unknown placeholder in %s announcement template: %s", key, placeholder)
+		}
+	}
+	return nil
+}
+
+// GuildSettings holds the per-guild behavior of the bot.
+type GuildSettings struct {
+	GuildID        snowflake.ID
+	TakeoverPolicy TakeoverPolicy
+	// SilentRoleIDs are the roles whose members' joins and leaves are not announced,
+	// e.g. a role given to music bots or streamers.
+	SilentRoleIDs []snowflake.ID
+	// AnnounceVoiceActivity enables announcements when a member starts or stops streaming
+	// or becomes a speaker on stage.
+	AnnounceVoiceActivity bool
+	// AnnounceJoin, AnnounceLeave and AnnounceLaunch toggle the join/leave cues and the launch phrase.
+	AnnounceJoin   bool
+	AnnounceLeave  bool
+	AnnounceLaunch bool
+	// AnnounceFarewell speaks a farewell before the bot leaves the voice channel.
+	AnnounceFarewell bool
+	// SkipReaction is the emoji reacted to messages that were not (fully) read. Empty disables it.
+	SkipReaction string
+	// MaxMessageLength is the maximum number of characters read from a message; the rest is truncated.
+	MaxMessageLength int
+	CodeBlockMode    CodeBlockMode
+	// OmitStrikethrough skips ~~struck-through~~ text instead of reading it.
+	OmitStrikethrough bool
+	// AnnounceMarkdown announces quotes and headings, e.g. "quote, ..." instead of reading them as plain text.
+	AnnounceMarkdown bool
+	// Timezone is the IANA time zone name timestamps are read in, e.g. "Asia/Tokyo".
+	Timezone string
+	// NameSource is the name read for speakers and announcements.
+	NameSource NameSource
+	// StripNameDecorations removes emojis and decorative symbols from names before they are read.
+	StripNameDecorations bool
+	// CommandPrefix enables text commands such as ";skip" in the reading channel. Empty disables them.
+	CommandPrefix string
+	// SelfDeaf and SelfMute are the voice state of the bot in the voice channel.
+	// Deafened, the bot visibly does not listen; muted, it still speaks but shows as muted.
+	SelfDeaf bool
+	SelfMute bool
+	// YieldToBots holds back reading while another bot, e.g. a music bot, transmits in the voice channel.
+	// The bot has to hear the voice channel to notice it, so it does not work while deafened.
+	YieldToBots bool
+	// BargeIn is what reading does while a member speaks in the voice channel. Like YieldToBots, it needs the bot to hear it.
+	BargeIn BargeIn
+	// WebhookURL receives the events of the guild as JSON, e.g. when a session starts or ends. Empty disables it.
+	WebhookURL string
+	// EphemeralResponses shows the responses of /preset and /settings only to the member who invoked them.
+	EphemeralResponses bool
+	// AnnouncementTemplates override the shipped voice resources of announcements, e.g. "{name} is here!" for joins.
+	AnnouncementTemplates map[AnnouncementKey]string
+	// TranscriptChannelID is the text channel where what the bot spoke is posted, e.g. for members who cannot listen.
+	// 0 disables the transcript.
+	TranscriptChannelID snowflake.ID
+	// SpellOut spells out tokens matching the built-in patterns and SpellOutPatterns character by character,
+	// e.g. IDs and invite codes that engines read as nonsense words.
+	SpellOut bool
+	// SpellOutPatterns are regular expressions of further tokens to spell out. If a pattern has a capturing group,
+	// only the group is spelled out, e.g. the code of `discord\.gg/(\w+)`.
+	SpellOutPatterns []string
+	// VoiceTags lets members override the voice of a single message with a tag at its start,
+	// e.g. "[en]" for a preset speaking English or "[voice:anna]" for the preset "anna".
+	VoiceTags bool
+	// CodeSwitch reads the runs of a message written in another script, e.g. English words in Japanese,
+	// with a preset speaking their language.
+	CodeSwitch bool
+	// SoftenAsides reads the text in parentheses or spoiler markers softer and slower, like an aside.
+	// Only presets of engines accepting SSML soften them.
+	SoftenAsides bool
+	// VoicePacks are the IDs of the themed voice packs, e.g. seasonal greetings, spoken while their date range lasts.
+	// The first active pack is spoken when several overlap.
+	VoicePacks []string
+}
+
+// DefaultMaxMessageLength, MinMaxMessageLength and MaxMaxMessageLength bound the configurable message length.
+const (
+	DefaultMaxMessageLength = 300
+	MinMaxMessageLength     = 50
+	MaxMaxMessageLength     = 2000
+)
+
+// MaxCommandPrefixLength is the maximum number of characters of a command prefix.
+const MaxCommandPrefixLength = 8
+
+// IsValidCommandPrefix reports whether prefix can be used as a command prefix.
+// An empty prefix is valid and disables text commands.
+func IsValidCommandPrefix(prefix string) bool {
+	return utf8.RuneCountInString(prefix) <= MaxCommandPrefixLength && !strings.ContainsFunc(prefix, unicode.IsSpace)
+}
+
+// MaxWebhookURLLength is the maximum length of a webhook URL.
+const MaxWebhookURLLength = 512
+
+// IsValidWebhookURL reports whether events can be sent to rawURL.
+// Only HTTPS URLs of public hosts are accepted, so that the bot cannot be pointed at its own network.
+// An empty URL is valid and disables the webhook.
+func IsValidWebhookURL(rawURL string) bool {
+	if rawURL == "" {
+		return true
+	}
+	if len(rawURL) > MaxWebhookURLLength {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.User != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return IsPublicAddr(ip)
+	}
+	return true
+}
+
+// IsPublicAddr reports whether webhook events can be sent to addr.
+// Loopback, link-local, private and other non-global unicast addresses are rejected.
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+// MaxSpellOutPatterns and MaxSpellOutPatternLength bound the spell-out patterns of a guild.
+const (
+	MaxSpellOutPatterns      = 10
+	MaxSpellOutPatternLength = 100
+)
+
+// ValidateSpellOutPattern returns an error if pattern cannot be used as a spell-out pattern.
+func ValidateSpellOutPattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("spell-out pattern cannot be empty")
+	}
+	if utf8.RuneCountInString(pattern) > MaxSpellOutPatternLength {
+		return fmt.Errorf("spell-out pattern exceeds %d characters", MaxSpellOutPatternLength)
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("invalid spell-out pattern: %w", err)
+	}
+	return nil
+}
+
+// MaxVoicePackIDLength is the maximum length of the ID of a voice pack.
+const MaxVoicePackIDLength = 64
+
+// DefaultGuildSettings returns the settings used for guilds that have not configured anything yet.
+func DefaultGuildSettings(guildID snowflake.ID) GuildSettings {
+	return GuildSettings{
+		GuildID:          guildID,
+		TakeoverPolicy:   TakeoverPolicyConfirm,
+		AnnounceJoin:     true,
+		AnnounceLeave:    true,
+		AnnounceLaunch:   true,
+		MaxMessageLength: DefaultMaxMessageLength,
+		CodeBlockMode:    CodeBlockModeAnnounce,
+		Timezone:         "UTC",
+		NameSource:       NameSourceNickname,
+		SelfDeaf:         true,
+		BargeIn:          BargeInOff,
+	}
+}
+
+// IsSilent reports whether a member with the given roles should not be announced.
+func (s GuildSettings) IsSilent(roleIDs []snowflake.ID) bool {
+	for _, roleID := range roleIDs {
+		if slices.Contains(s.SilentRoleIDs, roleID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Announcement returns the announcement of key rendered from the template of the guild,
+// with names in place of NamePlaceholder. It returns false if the guild has not overridden the announcement.
+func (s GuildSettings) Announcement(key AnnouncementKey, names string) (string, bool) {
+	template, ok := s.AnnouncementTemplates[key]
+	if !ok {
+		return "", false
+	}
+	return strings.ReplaceAll(template, NamePlaceholder, names), true
+}
+
+// Location returns the time zone of the guild, or UTC if it is not valid.
+func (s GuildSettings) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func (s GuildSettings) validate() error {
+	if s.GuildID == 0 {
+		return fmt.Errorf("guild ID cannot be empty")
+	}
+	if s.MaxMessageLength < MinMaxMessageLength || s.MaxMessageLength > MaxMaxMessageLength {
+		return fmt.Errorf("max message length must be between %d and %d: %d", MinMaxMessageLength, MaxMaxMessageLength, s.MaxMessageLength)
+	}
+	if err := s.TakeoverPolicy.validate(); err != nil {
+		return err
+	}
+	if err := s.CodeBlockMode.validate(); err != nil {
+		return err
+	}
+	if err := s.NameSource.validate(); err != nil {
+		return err
+	}
+	if err := s.BargeIn.validate(); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone: %s", s.Timezone)
+	}
+	if !IsValidCommandPrefix(s.CommandPrefix) {
+		return fmt.Errorf("invalid command prefix: %q", s.CommandPrefix)
+	}
+	if !IsValidWebhookURL(s.WebhookURL) {
+		return fmt.Errorf("invalid webhook URL")
+	}
+	if len(s.SpellOutPatterns) > MaxSpellOutPatterns {
+		return fmt.Errorf("too many spell-out patterns: %d", len(s.SpellOutPatterns))
+	}
+	for _, pattern := range s.SpellOutPatterns {
+		if err := ValidateSpellOutPattern(pattern); err != nil {
+			return err
+		}
+	}
+	for _, packID := range s.VoicePacks {
+		if packID == "" || len(packID) > MaxVoicePackIDLength {
+			return fmt.Errorf("invalid voice pack ID: %q", packID)
+		}
+	}
+	for key, template := range s.AnnouncementTemplates {
+		if err := ValidateAnnouncementTemplate(key, template); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,15 +1,19 @@
 package tts
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"hash"
 	"hash/fnv"
+	"io"
 	"log/slog"
 	"time"
 
 	"github.com/disgoorg/log"
 	"github.com/go-redis/cache/v9"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/logging"
 )
 
 var _ Engine = (*CachedTTSEngine)(nil)
@@ -41,41 +45,94 @@ func (c *CachedTTSEngine) Name() string {
 	return c.nextEngine.Name() + "-cached"
 }
 
+// Unwrap returns the wrapped engine.
+func (c *CachedTTSEngine) Unwrap() Engine {
+	return c.nextEngine
+}
+
+// cachedSpeech is a SpeechResponse as stored in the cache.
+type cachedSpeech struct {
+	Format       AudioFormat
+	SampleRate   int
+	Channels     int
+	AudioContent []byte
+}
+
 // Generate generates the audio data for the given text, language code, and voice name.
 func (c *CachedTTSEngine) GenerateSpeech(ctx context.Context, request SpeechRequest) (*SpeechResponse, error) {
 	key := c.generateKey(request)
 
-	var resp *SpeechResponse
-	err := c.redisCache.Get(ctx, key, resp)
+	var cached cachedSpeech
+	err := c.redisCache.Get(ctx, key, &cached)
 
 	if err == nil {
-		slog.Info("cache hit", "key", key, "engine", c.Name())
-		return resp, nil
+		slog.InfoContext(ctx, "cache hit", logging.Component(logging.ComponentSynthesis), "key", key, "engine", c.Name())
+		return &SpeechResponse{
+			Format:     cached.Format,
+			SampleRate: cached.SampleRate,
+			Channels:   cached.Channels,
+			Audio:      bytes.NewReader(cached.AudioContent),
+			Size:       int64(len(cached.AudioContent)),
+		}, nil
 	}
 
-	resp, err = c.nextEngine.GenerateSpeech(ctx, request)
+	resp, err := c.nextEngine.GenerateSpeech(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	// Store the audio data in the cache with the generated key
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-		defer cancel()
-		if err = c.redisCache.Set(&cache.Item{
-			Ctx:   ctx,
-			Key:   key,
-			Value: resp,
-			TTL:   c.ttl,
-		}); err != nil {
-			// Log the error but do not return it, as we don't want to fail the request if caching fails
-			log.Warn("failed to cache audio data", "error", err, "key", key)
-		}
-	}()
-
+	// the audio is streamed to the player, so it is recorded as it is read, and stored once read to the end.
+	// speech that is skipped before its end is not cached.
+	resp.Audio = &recordingReader{source: resp.Audio, done: func(content []byte) {
+		go c.store(key, cachedSpeech{
+			Format:       resp.Format,
+			SampleRate:   resp.SampleRate,
+			Channels:     resp.Channels,
+			AudioContent: content,
+		})
+	}}
 	return resp, nil
 }
 
+// store stores the speech in the cache with the generated key.
+func (c *CachedTTSEngine) store(key string, speech cachedSpeech) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := c.redisCache.Set(&cache.Item{
+		Ctx:   ctx,
+		Key:   key,
+		Value: speech,
+		TTL:   c.ttl,
+	}); err != nil {
+		// Log the error but do not return it, as we don't want to fail the request if caching fails
+		log.Warn("failed to cache audio data", "error", err, "key", key)
+	}
+}
+
+// recordingReader keeps a copy of what is read from the source, and passes it to done once the source is read to the end.
+type recordingReader struct {
+	source   io.Reader
+	recorded bytes.Buffer
+	done     func(content []byte)
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.source.Read(p)
+	r.recorded.Write(p[:n])
+	if err == io.EOF && r.done != nil {
+		r.done(r.recorded.Bytes())
+		r.done = nil
+	}
+	return n, err
+}
+
+func (r *recordingReader) Close() error {
+	if closer, ok := r.source.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
 // generateKey creates a unique key for the cache based on the request parameters.
 func (c *CachedTTSEngine) generateKey(request SpeechRequest) string {
 	c.hash.Reset()
@@ -0,0 +1,30 @@
+package tts
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestRecordingReader(t *testing.T) {
+	var recorded []string
+	r := &recordingReader{
+		source: iotest.HalfReader(strings.NewReader("speech")),
+		done:   func(content []byte) { recorded = append(recorded, string(content)) },
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(content) != "speech" {
+		t.Errorf("read %q, want %q", content, "speech")
+	}
+	if _, err := r.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Read() after the end error = %v, want EOF", err)
+	}
+	if len(recorded) != 1 || recorded[0] != "speech" {
+		t.Errorf("recorded %q, want the content once", recorded)
+	}
+}
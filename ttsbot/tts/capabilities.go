@@ -0,0 +1,53 @@
+package tts
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ErrTextTooLong is returned for text longer than an engine accepts in a single request.
+var ErrTextTooLong = errors.New("text is too long for the engine")
+
+// Capabilities describes what an engine accepts and produces, so that presets are validated when they are registered
+// instead of failing at synthesis time with errors of the engine's API.
+type Capabilities struct {
+	// Output is the format the engine synthesizes.
+	Output OutputFormat
+	// SSML reports whether the engine accepts requests of InputKindSSML.
+	SSML bool
+	// MaxTextLength is the most characters, and MaxTextBytes the most bytes in UTF-8, of the text of a request.
+	// Zero does not limit it.
+	MaxTextLength int
+	MaxTextBytes  int
+	// ListsVoices reports whether the voices of the engine can be listed with ListVoices.
+	ListsVoices bool
+	// RequiresVoice reports whether requests must name a voice, as the engine can not pick one for the language.
+	RequiresVoice bool
+}
+
+// CheckText returns ErrTextTooLong if the text is longer than the engine accepts.
+func (c Capabilities) CheckText(text string) error {
+	if c.MaxTextBytes > 0 && len(text) > c.MaxTextBytes {
+		return fmt.Errorf("%w: %d bytes, at most %d", ErrTextTooLong, len(text), c.MaxTextBytes)
+	}
+	if length := utf8.RuneCountInString(text); c.MaxTextLength > 0 && length > c.MaxTextLength {
+		return fmt.Errorf("%w: %d characters, at most %d", ErrTextTooLong, length, c.MaxTextLength)
+	}
+	return nil
+}
+
+// CapabilityReporter is implemented by engines that report their capabilities.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the capabilities of the engine, looking through wrappers such as CachedTTSEngine.
+// It reports false if the engine does not report them.
+func CapabilitiesOf(engine Engine) (Capabilities, bool) {
+	reporter, ok := findEngine[CapabilityReporter](engine)
+	if !ok {
+		return Capabilities{}, false
+	}
+	return reporter.Capabilities(), true
+}
@@ -0,0 +1,53 @@
+package tts
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type reportingEngine struct {
+	stubEngine
+	capabilities Capabilities
+}
+
+func (e reportingEngine) Capabilities() Capabilities {
+	return e.capabilities
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	engine := NewMeasuredEngine(reportingEngine{capabilities: Capabilities{SSML: true, MaxTextLength: 10}}, NewLatencyRecorder(), 0)
+	capabilities, ok := CapabilitiesOf(engine)
+	if !ok || capabilities.MaxTextLength != 10 {
+		t.Fatalf("CapabilitiesOf() = %+v, %v, want the capabilities of the wrapped engine", capabilities, ok)
+	}
+	if !SupportsSSML(engine) {
+		t.Errorf("SupportsSSML() = false, want the SSML capability")
+	}
+
+	if _, ok := CapabilitiesOf(stubEngine{}); ok {
+		t.Errorf("CapabilitiesOf() of an engine without capabilities reported them")
+	}
+}
+
+func TestCheckText(t *testing.T) {
+	tests := []struct {
+		name         string
+		capabilities Capabilities
+		text         string
+		wantErr      bool
+	}{
+		{name: "unlimited", text: strings.Repeat("a", 10000)},
+		{name: "characters", capabilities: Capabilities{MaxTextLength: 3}, text: "あいう"},
+		{name: "too many characters", capabilities: Capabilities{MaxTextLength: 3}, text: "abcd", wantErr: true},
+		{name: "too many bytes", capabilities: Capabilities{MaxTextBytes: 6}, text: "あいう", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.capabilities.CheckText(tt.text)
+			if errors.Is(err, ErrTextTooLong) != tt.wantErr {
+				t.Errorf("CheckText() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+// ErrCircuitOpen is returned without calling the engine while it is considered unavailable.
+var ErrCircuitOpen = errors.New("engine is unavailable after repeated failures")
+
+var _ Engine = (*CircuitBreakerEngine)(nil)
+
+// CircuitBreakerEngine is a wrapper around an Engine that stops calling it after consecutive failures.
+// Once the cooldown has passed, a single request is let through: the circuit closes if it succeeds and stays open otherwise.
+// This keeps an outage of the engine from holding up every session until its requests time out.
+type CircuitBreakerEngine struct {
+	engine    Engine
+	threshold int
+	cooldown  time.Duration
+	// onChange is called when the circuit opens or closes.
+	onChange func(engine string, open bool, err error)
+	clock    clock.Clock
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreakerEngine wraps the engine to open the circuit after threshold consecutive failures, for the cooldown.
+// onChange may be nil.
+func NewCircuitBreakerEngine(engine Engine, threshold int, cooldown time.Duration, onChange func(engine string, open bool, err error)) *CircuitBreakerEngine {
+	return &CircuitBreakerEngine{
+		engine:    engine,
+		threshold: threshold,
+		cooldown:  cooldown,
+		onChange:  onChange,
+		clock:     clock.Real,
+	}
+}
+
+func (e *CircuitBreakerEngine) Name() string {
+	return e.engine.Name()
+}
+
+// Unwrap returns the wrapped engine.
+func (e *CircuitBreakerEngine) Unwrap() Engine {
+	return e.engine
+}
+
+// Open reports whether requests to the engine are currently rejected.
+func (e *CircuitBreakerEngine) Open() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.open
+}
+
+func (e *CircuitBreakerEngine) GenerateSpeech(ctx context.Context, request SpeechRequest) (*SpeechResponse, error) {
+	if !e.allow() {
+		return nil, fmt.Errorf("%s: %w", e.engine.Name(), ErrCircuitOpen)
+	}
+
+	resp, err := e.engine.GenerateSpeech(ctx, request)
+	// requests canceled by the caller or held back by a rate limit say nothing about the engine.
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrRateLimited) {
+		e.mu.Lock()
+		e.probing = false
+		e.mu.Unlock()
+		return resp, err
+	}
+	e.record(err)
+	return resp, err
+}
+
+// allow reports whether a request may be sent to the engine.
+func (e *CircuitBreakerEngine) allow() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.open {
+		return true
+	}
+	if e.probing || e.clock.Now().Sub(e.openedAt) < e.cooldown {
+		return false
+	}
+	e.probing = true
+	return true
+}
+
+func (e *CircuitBreakerEngine) record(err error) {
+	e.mu.Lock()
+	wasOpen := e.open
+	e.probing = false
+	if err == nil {
+		e.failures = 0
+		e.open = false
+	} else {
+		e.failures++
+		if e.open || e.failures >= e.threshold {
+			// a failed probe keeps the circuit open for another cooldown.
+			e.open = true
+			e.openedAt = e.clock.Now()
+		}
+	}
+	open := e.open
+	e.mu.Unlock()
+
+	if open == wasOpen {
+		return
+	}
+	if open {
+		slog.Error("Speech engine circuit opened", "engine", e.engine.Name(), "failures", e.threshold, "cooldown", e.cooldown, "err", err)
+	} else {
+		slog.Info("Speech engine circuit closed", "engine", e.engine.Name())
+	}
+	if e.onChange != nil {
+		e.onChange(e.engine.Name(), open, err)
+	}
+}
@@ -0,0 +1,74 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+type failingEngine struct {
+	err   error
+	calls int
+}
+
+func (e *failingEngine) Name() string {
+	return "failing"
+}
+
+func (e *failingEngine) GenerateSpeech(ctx context.Context, request SpeechRequest) (*SpeechResponse, error) {
+	e.calls++
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &SpeechResponse{}, nil
+}
+
+func TestCircuitBreakerEngine(t *testing.T) {
+	engine := &failingEngine{err: errors.New("unavailable")}
+	var changes []bool
+	breaker := NewCircuitBreakerEngine(engine, 2, time.Minute, func(name string, open bool, err error) {
+		changes = append(changes, open)
+	})
+	clk := clock.NewFake(time.Unix(0, 0))
+	breaker.clock = clk
+	ctx := context.Background()
+
+	for range 2 {
+		breaker.GenerateSpeech(ctx, SpeechRequest{})
+	}
+	if !breaker.Open() {
+		t.Fatal("circuit should open after 2 failures")
+	}
+
+	// requests are rejected without calling the engine during the cooldown.
+	if _, err := breaker.GenerateSpeech(ctx, SpeechRequest{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("err = %v, want ErrCircuitOpen", err)
+	}
+	if engine.calls != 2 {
+		t.Errorf("calls = %d, want 2", engine.calls)
+	}
+
+	// a failed probe after the cooldown keeps the circuit open.
+	clk.Advance(time.Minute)
+	breaker.GenerateSpeech(ctx, SpeechRequest{})
+	if !breaker.Open() || engine.calls != 3 {
+		t.Errorf("open = %v, calls = %d, want open after a failed probe", breaker.Open(), engine.calls)
+	}
+
+	// a successful probe closes it.
+	clk.Advance(time.Minute)
+	engine.err = nil
+	if _, err := breaker.GenerateSpeech(ctx, SpeechRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if breaker.Open() {
+		t.Error("circuit should close after a successful probe")
+	}
+
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("changes = %v, want [true false]", changes)
+	}
+}
@@ -0,0 +1,188 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/logging"
+)
+
+var (
+	_ Engine              = (*ElevenLabsEngine)(nil)
+	_ SpeakingRateLimiter = (*ElevenLabsEngine)(nil)
+	_ CapabilityReporter  = (*ElevenLabsEngine)(nil)
+)
+
+// ElevenLabsBaseURL is the endpoint of the ElevenLabs API.
+const ElevenLabsBaseURL = "https://api.elevenlabs.io/v1"
+
+// ElevenLabsDefaultOutputFormat is the MP3 ElevenLabs offers on every plan.
+var ElevenLabsDefaultOutputFormat = OutputFormat{Format: AudioFormatMp3, SampleRate: 44100}
+
+// elevenLabsDefaultModel speaks every language ElevenLabs offers.
+const elevenLabsDefaultModel = "eleven_multilingual_v2"
+
+// ElevenLabsEngine is an implementation of the Engine interface for ElevenLabs.
+// Voices are named by their ElevenLabs voice ID, e.g. "21m00Tcm4TlvDq8ikWAM", and speak the language of the text.
+//
+// Streaming, the engine returns the audio as ElevenLabs sends it, so that long messages start playing
+// before their synthesis ends. Otherwise, the audio is received entirely before GenerateSpeech returns.
+type ElevenLabsEngine struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	model   string
+	stream  bool
+	output  OutputFormat
+}
+
+// NewElevenLabsEngine creates the engine calling the API at the base URL, ElevenLabsBaseURL if empty, with the API key.
+// The model is "eleven_multilingual_v2" if empty, and a zero output format uses ElevenLabsDefaultOutputFormat.
+// ElevenLabs offers MP3 at 22050 and 44100 hertz, and LINEAR16 as raw PCM at 8000 to 48000 hertz.
+func NewElevenLabsEngine(client *http.Client, baseURL, apiKey, model string, stream bool, output OutputFormat) (*ElevenLabsEngine, error) {
+	if baseURL == "" {
+		baseURL = ElevenLabsBaseURL
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("elevenlabs api key cannot be empty")
+	}
+	if model == "" {
+		model = elevenLabsDefaultModel
+	}
+	if output.Format == AudioFormatUnknown {
+		output.Format = ElevenLabsDefaultOutputFormat.Format
+	}
+	if output.SampleRate == 0 {
+		output.SampleRate = ElevenLabsDefaultOutputFormat.SampleRate
+	}
+	if elevenLabsOutputFormat(output) == "" {
+		return nil, fmt.Errorf("elevenlabs does not offer %s at %d hertz", output.Format, output.SampleRate)
+	}
+	return &ElevenLabsEngine{
+		client:  client,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		stream:  stream,
+		output:  output,
+	}, nil
+}
+
+func (e *ElevenLabsEngine) Name() string {
+	return "elevenlabs"
+}
+
+// SpeakingRateRange returns the speeds accepted by the voice settings of ElevenLabs.
+func (e *ElevenLabsEngine) SpeakingRateRange() SpeakingRateRange {
+	return SpeakingRateRange{Min: 0.7, Max: 1.2}
+}
+
+// Capabilities reports the output format of e. The multilingual models accept at most 10000 characters.
+func (e *ElevenLabsEngine) Capabilities() Capabilities {
+	return Capabilities{Output: e.output, MaxTextLength: 10000, RequiresVoice: true}
+}
+
+func (e *ElevenLabsEngine) GenerateSpeech(ctx context.Context, request SpeechRequest) (*SpeechResponse, error) {
+	slog.InfoContext(ctx, "Synthesize speech", logging.Component(logging.ComponentSynthesis), slog.String("text", request.Text))
+	if request.InputKind == InputKindSSML {
+		return nil, fmt.Errorf("elevenlabs does not support SSML")
+	}
+	if request.VoiceName == "" {
+		return nil, fmt.Errorf("elevenlabs needs a voice ID")
+	}
+
+	speech := elevenLabsSpeechRequest{Text: request.Text, ModelID: e.model}
+	if request.SpeakingRate != 0 {
+		speech.VoiceSettings = &elevenLabsVoiceSettings{Speed: request.SpeakingRate}
+	}
+	body, err := json.Marshal(speech)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := e.baseURL + "/text-to-speech/" + url.PathEscape(request.VoiceName)
+	if e.stream {
+		endpoint += "/stream"
+	}
+	endpoint += "?" + url.Values{"output_format": {elevenLabsOutputFormat(e.output)}}.Encode()
+
+	requestCtx, cancel, stop := streamContext(ctx, e.stream)
+	defer stop()
+	req, err := http.NewRequestWithContext(requestCtx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("xi-api-key", e.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		cancel()
+		slog.ErrorContext(ctx, "failed to synthesize speech", "error", err)
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer cancel()
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("elevenlabs responded with %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+
+	speechResp := &SpeechResponse{
+		Format:     e.output.Format,
+		SampleRate: e.output.SampleRate,
+		Channels:   1,
+	}
+	if e.stream {
+		speechResp.Audio = &streamedAudio{ReadCloser: resp.Body, cancel: cancel}
+		return speechResp, nil
+	}
+
+	defer cancel()
+	defer resp.Body.Close()
+	audio, err := io.ReadAll(resp.Body)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read synthesized speech", "error", err)
+		return nil, err
+	}
+	speechResp.Audio = bytes.NewReader(audio)
+	speechResp.Size = int64(len(audio))
+	return speechResp, nil
+}
+
+type elevenLabsSpeechRequest struct {
+	Text          string                   `json:"text"`
+	ModelID       string                   `json:"model_id"`
+	VoiceSettings *elevenLabsVoiceSettings `json:"voice_settings,omitempty"`
+}
+
+type elevenLabsVoiceSettings struct {
+	Speed float64 `json:"speed"`
+}
+
+// elevenLabsOutputFormat returns the output format parameter for the output, or "" if ElevenLabs does not offer it.
+func elevenLabsOutputFormat(output OutputFormat) string {
+	switch output.Format {
+	case AudioFormatMp3:
+		switch output.SampleRate {
+		case 22050:
+			return "mp3_22050_32"
+		case 44100:
+			return "mp3_44100_128"
+		}
+	case AudioFormatLinear16:
+		switch output.SampleRate {
+		case 8000, 16000, 22050, 24000, 44100, 48000:
+			return fmt.Sprintf("pcm_%d", output.SampleRate)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElevenLabsEngine_GenerateSpeech(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/text-to-speech/voice-a", r.URL.Path)
+		assert.Equal(t, "mp3_44100_128", r.URL.Query().Get("output_format"))
+		assert.Equal(t, "xi-test", r.Header.Get("xi-api-key"))
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]any{"text": "hello", "model_id": "eleven_multilingual_v2", "voice_settings": map[string]any{"speed": 1.1}}, body)
+		w.Write([]byte("audio"))
+	}))
+	t.Cleanup(server.Close)
+
+	engine, err := NewElevenLabsEngine(server.Client(), server.URL+"/v1", "xi-test", "", false, OutputFormat{})
+	require.NoError(t, err)
+
+	resp, err := engine.GenerateSpeech(context.Background(), SpeechRequest{Text: "hello", VoiceName: "voice-a", SpeakingRate: 1.1})
+	require.NoError(t, err)
+	audio, _ := io.ReadAll(resp.Audio)
+	assert.Equal(t, "audio", string(audio))
+	assert.Equal(t, int64(5), resp.Size)
+	assert.Equal(t, AudioFormatMp3, resp.Format)
+	assert.Equal(t, 44100, resp.SampleRate)
+
+	_, err = engine.GenerateSpeech(context.Background(), SpeechRequest{Text: "hello"})
+	assert.Error(t, err, "elevenlabs needs a voice ID")
+}
+
+func TestElevenLabsEngine_GenerateSpeech_Stream(t *testing.T) {
+	// the second chunk is only sent once the first one was received, so the audio must be returned while it streams.
+	received := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/text-to-speech/voice-a/stream", r.URL.Path)
+		assert.Equal(t, "pcm_24000", r.URL.Query().Get("output_format"))
+		w.Write([]byte("chunk1"))
+		w.(http.Flusher).Flush()
+		<-received
+		w.Write([]byte("chunk2"))
+	}))
+	t.Cleanup(server.Close)
+
+	engine, err := NewElevenLabsEngine(server.Client(), server.URL, "xi-test", "eleven_flash_v2_5", true, OutputFormat{Format: AudioFormatLinear16, SampleRate: 24000})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := engine.GenerateSpeech(ctx, SpeechRequest{Text: "hello", VoiceName: "voice-a"})
+	require.NoError(t, err)
+	defer resp.Close()
+	// the audio outlives the context of the request.
+	cancel()
+	assert.Zero(t, resp.Size)
+
+	chunk := make([]byte, 6)
+	_, err = io.ReadFull(resp.Audio, chunk)
+	require.NoError(t, err)
+	assert.Equal(t, "chunk1", string(chunk))
+	close(received)
+	rest, err := io.ReadAll(resp.Audio)
+	require.NoError(t, err)
+	assert.Equal(t, "chunk2", string(rest))
+}
+
+func TestElevenLabsEngine_GenerateSpeech_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"detail":{"status":"voice_not_found"}}`, http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	for _, stream := range []bool{false, true} {
+		engine, err := NewElevenLabsEngine(server.Client(), server.URL, "xi-test", "", stream, OutputFormat{})
+		require.NoError(t, err)
+		_, err = engine.GenerateSpeech(context.Background(), SpeechRequest{Text: "hello", VoiceName: "unknown"})
+		assert.ErrorContains(t, err, "voice_not_found")
+	}
+}
+
+func TestNewElevenLabsEngine(t *testing.T) {
+	_, err := NewElevenLabsEngine(http.DefaultClient, "", "", "", false, OutputFormat{})
+	assert.Error(t, err)
+	_, err = NewElevenLabsEngine(http.DefaultClient, "", "xi-test", "", false, DefaultOutputFormat)
+	assert.Error(t, err, "elevenlabs does not offer mp3 at 48000 hertz")
+	_, err = NewElevenLabsEngine(http.DefaultClient, "", "xi-test", "", false, OutputFormat{Format: AudioFormatLinear16, SampleRate: 48000})
+	assert.NoError(t, err)
+}
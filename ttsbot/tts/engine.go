@@ -2,13 +2,18 @@ package tts
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
 )
 
 // Engine is a generic interface for text-to-speech engines.
 // It can be implemented by various TTS engines to provide a unified interface for text-to-speech operations.
-// However, currently it is only implemented by the Google TTS engine so SynthetizeRequest leaks some Google TTS specific parameters.
+// It is implemented by the Google TTS, Amazon Polly, VOICEVOX, OpenAI and ElevenLabs engines, and SpeechRequest still leaks some Google TTS specific parameters,
+// e.g. Polly applies SpeakingRate through SSML since it has no parameter for it.
 //
-// FIXME: when other TTS engines are implemented, this interface should be made more generic.
+// FIXME: this interface should be made more generic as more engines are implemented.
 // Yet, it is not clear how to make it generic, since different TTS engines have different parameters.
 type Engine interface {
 	// Name returns the name of the TTS engine, e.g. "Google TTS", "Azure TTS", etc.
@@ -20,26 +25,140 @@ type Engine interface {
 
 type (
 	SpeechRequest struct {
-		Text         string
+		Text string
+		// InputKind tells whether Text is plain text or SSML.
+		InputKind    InputKind
 		LanguageCode string
 		VoiceName    string
 		SpeakingRate float64
+		// Pitch is in semitones and VolumeGainDb in decibels from the normal voice. Zero keeps the normal voice.
+		// They are only applied by engines implementing ProsodyLimiter.
+		Pitch        float64
+		VolumeGainDb float64
 	}
 
+	// InputKind is the kind of input text of a SpeechRequest.
+	InputKind int
+
 	AudioFormat int
 
 	SpeechResponse struct {
-		Format       AudioFormat
-		Channels     int
-		AudioContent []byte
+		Format AudioFormat
+		// SampleRate is the sample rate of the audio content in hertz. Zero means 48000.
+		SampleRate int
+		Channels   int
+		// Audio is the audio content. It is read once, while the speech is played, so that the player decodes it
+		// without copying it first, and engines receiving audio in chunks can hand it over before it is received entirely.
+		// It outlives the request, so it must not be bound to the context given to GenerateSpeech.
+		Audio io.Reader
+		// Size is the number of bytes of Audio, or zero if it is not known, e.g. for audio streamed while synthesized.
+		Size int64
+		// Fallback is the fallback of a FallbackEngine that synthesized the audio, or nil if the engine asked did.
+		Fallback *Fallback
+	}
+
+	// OutputFormat is the audio format an engine is asked to synthesize.
+	OutputFormat struct {
+		Format     AudioFormat
+		SampleRate int
 	}
 )
 
 const (
 	AudioFormatUnknown AudioFormat = iota
 	AudioFormatMp3
+	// AudioFormatLinear16 is 16-bit little-endian PCM, optionally with a WAV header.
+	AudioFormatLinear16
+	// AudioFormatOggOpus is opus in an Ogg container. Its packets are sent to discord as they are when they hold 20ms of audio,
+	// which saves decoding and encoding the speech again.
+	AudioFormatOggOpus
+)
+
+// Close releases the audio content if it is an io.Closer.
+// Whoever takes a response from an engine closes it, once played or when dropping it.
+func (r *SpeechResponse) Close() error {
+	if closer, ok := r.Audio.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// streamContext returns the context of a request to an engine. A streamed response is read while it is played,
+// after GenerateSpeech has returned, so its request is only bound to ctx until stop is called once the response started,
+// and is canceled once its audio is closed. Other requests are bound to ctx as usual.
+func streamContext(ctx context.Context, stream bool) (requestCtx context.Context, cancel context.CancelFunc, stop func() bool) {
+	if !stream {
+		requestCtx, cancel = context.WithCancel(ctx)
+		return requestCtx, cancel, func() bool { return false }
+	}
+	requestCtx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+	return requestCtx, cancel, context.AfterFunc(ctx, cancel)
+}
+
+// streamedAudio is the body of a streamed response, which cancels its request once closed.
+type streamedAudio struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (a *streamedAudio) Close() error {
+	err := a.ReadCloser.Close()
+	a.cancel()
+	return err
+}
+
+// DefaultOutputFormat is MP3 at the sample rate of discord, which needs no resampling.
+var DefaultOutputFormat = OutputFormat{Format: AudioFormatMp3, SampleRate: 48000}
+
+// ParseAudioFormat parses the name of an audio format as used in the config, e.g. "mp3", "linear16" or "ogg_opus".
+func ParseAudioFormat(name string) (AudioFormat, error) {
+	switch name {
+	case "mp3":
+		return AudioFormatMp3, nil
+	case "linear16":
+		return AudioFormatLinear16, nil
+	case "ogg_opus":
+		return AudioFormatOggOpus, nil
+	default:
+		return AudioFormatUnknown, fmt.Errorf("unsupported audio format: %q", name)
+	}
+}
+
+func (f AudioFormat) String() string {
+	switch f {
+	case AudioFormatMp3:
+		return "mp3"
+	case AudioFormatLinear16:
+		return "linear16"
+	case AudioFormatOggOpus:
+		return "ogg_opus"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	InputKindText InputKind = iota
+	// InputKindSSML is only accepted by engines implementing SSMLSupporter.
+	InputKindSSML
 )
 
+// SSMLSupporter is implemented by engines that accept SSML input, i.e. Google TTS and Amazon Polly.
+// The other engines fail requests of InputKindSSML.
+type SSMLSupporter interface {
+	SupportsSSML() bool
+}
+
+// SupportsSSML reports whether the engine accepts SSML input, looking through wrappers such as CachedTTSEngine.
+// The capabilities of the engine are preferred if it reports them.
+func SupportsSSML(engine Engine) bool {
+	if capabilities, ok := CapabilitiesOf(engine); ok {
+		return capabilities.SSML
+	}
+	supporter, ok := findEngine[SSMLSupporter](engine)
+	return ok && supporter.SupportsSSML()
+}
+
 type EngineRegistry struct {
 	engines map[string]Engine // identifier -> Engine
 }
@@ -62,6 +181,11 @@ func (r *EngineRegistry) Get(identifier string) (Engine, bool) {
 	return engine, ok
 }
 
+// Identifiers returns the identifiers of the registered engines in lexical order.
+func (r *EngineRegistry) Identifiers() []string {
+	return slices.Sorted(maps.Keys(r.engines))
+}
+
 func (r *EngineRegistry) MustGet(identifier string) Engine {
 	engine, ok := r.Get(identifier)
 	if !ok {
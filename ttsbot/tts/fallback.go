@@ -0,0 +1,85 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var _ Engine = (*FallbackEngine)(nil)
+
+// Fallback is an engine tried once the engines before it have failed.
+// Voices are named differently by each engine, so the request is sent with the voice of the fallback.
+type Fallback struct {
+	Engine Engine
+	// VoiceName replaces the voice name of the request. Empty lets the engine pick a voice for the language.
+	VoiceName string
+}
+
+// FallbackEngine is a wrapper around an Engine that tries the fallbacks in order when it fails or times out,
+// so that an outage of one provider does not silence the bot.
+type FallbackEngine struct {
+	primary   Engine
+	timeout   time.Duration
+	fallbacks []Fallback
+}
+
+// NewFallbackEngine wraps the primary engine with the fallbacks. The primary engine is given up on after the timeout,
+// which leaves the rest of the deadline of the request to the fallbacks. A zero timeout waits as long as the request does.
+func NewFallbackEngine(primary Engine, timeout time.Duration, fallbacks ...Fallback) *FallbackEngine {
+	return &FallbackEngine{
+		primary:   primary,
+		timeout:   timeout,
+		fallbacks: fallbacks,
+	}
+}
+
+func (e *FallbackEngine) Name() string {
+	return e.primary.Name()
+}
+
+// Unwrap returns the primary engine.
+func (e *FallbackEngine) Unwrap() Engine {
+	return e.primary
+}
+
+func (e *FallbackEngine) GenerateSpeech(ctx context.Context, request SpeechRequest) (*SpeechResponse, error) {
+	primaryCtx, cancel := ctx, context.CancelFunc(func() {})
+	if e.timeout > 0 {
+		primaryCtx, cancel = context.WithTimeout(ctx, e.timeout)
+	}
+	resp, err := e.primary.GenerateSpeech(primaryCtx, request)
+	cancel()
+	if err == nil {
+		return resp, nil
+	}
+
+	errs := []error{fmt.Errorf("%s: %w", e.primary.Name(), err)}
+	for i := range e.fallbacks {
+		fallback := &e.fallbacks[i]
+		// requests canceled by the caller are not retried.
+		if ctx.Err() != nil {
+			break
+		}
+		// SSML can not be read as plain text, so it is only sent to engines accepting it.
+		if request.InputKind == InputKindSSML && !SupportsSSML(fallback.Engine) {
+			continue
+		}
+
+		slog.WarnContext(ctx, "Speech engine failed, falling back", slog.String("engine", e.primary.Name()), slog.String("fallback", fallback.Engine.Name()), slog.Any("err", err))
+		fallbackRequest := request
+		fallbackRequest.VoiceName = fallback.VoiceName
+		fallbackRequest.SpeakingRate, _ = ClampSpeakingRate(fallback.Engine, request.SpeakingRate)
+		fallbackRequest.Pitch, _ = ClampPitch(fallback.Engine, request.Pitch)
+		fallbackRequest.VolumeGainDb, _ = ClampVolumeGain(fallback.Engine, request.VolumeGainDb)
+		resp, err = fallback.Engine.GenerateSpeech(ctx, fallbackRequest)
+		if err == nil {
+			resp.Fallback = fallback
+			return resp, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", fallback.Engine.Name(), err))
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,96 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type recordingEngine struct {
+	failingEngine
+	ssml     bool
+	requests []SpeechRequest
+}
+
+func (e *recordingEngine) GenerateSpeech(ctx context.Context, request SpeechRequest) (*SpeechResponse, error) {
+	e.requests = append(e.requests, request)
+	return e.failingEngine.GenerateSpeech(ctx, request)
+}
+
+func (e *recordingEngine) SupportsSSML() bool {
+	return e.ssml
+}
+
+func TestFallbackEngine(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("primary succeeds", func(t *testing.T) {
+		primary := &recordingEngine{}
+		fallback := &recordingEngine{}
+		engine := NewFallbackEngine(primary, 0, Fallback{Engine: fallback})
+		resp, err := engine.GenerateSpeech(ctx, SpeechRequest{Text: "hello"})
+		if err != nil {
+			t.Fatalf("err = %v, want nil", err)
+		}
+		if len(fallback.requests) != 0 {
+			t.Errorf("fallback was called %d times, want 0", len(fallback.requests))
+		}
+		if resp.Fallback != nil {
+			t.Errorf("resp.Fallback = %+v, want nil", resp.Fallback)
+		}
+	})
+
+	t.Run("falls back with the voice of the fallback", func(t *testing.T) {
+		primary := &recordingEngine{failingEngine: failingEngine{err: errors.New("unavailable")}}
+		fallback := &recordingEngine{}
+		engine := NewFallbackEngine(primary, 0, Fallback{Engine: fallback, VoiceName: "Joanna"})
+		resp, err := engine.GenerateSpeech(ctx, SpeechRequest{Text: "hello", VoiceName: "en-US-Wavenet-A"})
+		if err != nil {
+			t.Fatalf("err = %v, want nil", err)
+		}
+		if len(fallback.requests) != 1 || fallback.requests[0].VoiceName != "Joanna" {
+			t.Errorf("fallback requests = %+v, want one with voice Joanna", fallback.requests)
+		}
+		if resp.Fallback == nil || resp.Fallback.Engine != fallback {
+			t.Errorf("resp.Fallback = %+v, want the fallback", resp.Fallback)
+		}
+	})
+
+	t.Run("skips fallbacks not accepting SSML", func(t *testing.T) {
+		primary := &recordingEngine{failingEngine: failingEngine{err: errors.New("unavailable")}, ssml: true}
+		plain := &recordingEngine{}
+		ssml := &recordingEngine{ssml: true}
+		engine := NewFallbackEngine(primary, 0, Fallback{Engine: plain}, Fallback{Engine: ssml})
+		if _, err := engine.GenerateSpeech(ctx, SpeechRequest{Text: "<speak>hello</speak>", InputKind: InputKindSSML}); err != nil {
+			t.Fatalf("err = %v, want nil", err)
+		}
+		if len(plain.requests) != 0 || len(ssml.requests) != 1 {
+			t.Errorf("plain calls = %d, ssml calls = %d, want 0 and 1", len(plain.requests), len(ssml.requests))
+		}
+	})
+
+	t.Run("joins the errors of every engine", func(t *testing.T) {
+		errPrimary, errFallback := errors.New("primary down"), errors.New("fallback down")
+		primary := &recordingEngine{failingEngine: failingEngine{err: errPrimary}}
+		fallback := &recordingEngine{failingEngine: failingEngine{err: errFallback}}
+		engine := NewFallbackEngine(primary, 0, Fallback{Engine: fallback})
+		_, err := engine.GenerateSpeech(ctx, SpeechRequest{Text: "hello"})
+		if !errors.Is(err, errPrimary) || !errors.Is(err, errFallback) {
+			t.Errorf("err = %v, want both errors", err)
+		}
+	})
+
+	t.Run("does not fall back once canceled", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		primary := &recordingEngine{failingEngine: failingEngine{err: context.Canceled}}
+		fallback := &recordingEngine{}
+		engine := NewFallbackEngine(primary, 0, Fallback{Engine: fallback})
+		if _, err := engine.GenerateSpeech(canceled, SpeechRequest{Text: "hello"}); !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+		if len(fallback.requests) != 0 {
+			t.Errorf("fallback was called %d times, want 0", len(fallback.requests))
+		}
+	})
+}
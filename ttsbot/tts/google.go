@@ -1,23 +1,43 @@
 package tts
 
 import (
+	"bytes"
 	"context"
 	"log/slog"
 
 	texttospeech "cloud.google.com/go/texttospeech/apiv1"
 	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/logging"
 )
 
-var _ Engine = (*GoogleEngine)(nil)
+var (
+	_ Engine              = (*GoogleEngine)(nil)
+	_ VoiceLister         = (*GoogleEngine)(nil)
+	_ SpeakingRateLimiter = (*GoogleEngine)(nil)
+	_ ProsodyLimiter      = (*GoogleEngine)(nil)
+	_ CapabilityReporter  = (*GoogleEngine)(nil)
+	_ SSMLSupporter       = (*GoogleEngine)(nil)
+)
 
 // GoogleEngine is an implementation of the Engine interface for Google Text-to-Speech.
 type GoogleEngine struct {
 	client *texttospeech.Client
+	output OutputFormat
 }
 
-func NewGoogleTTSEngine(client *texttospeech.Client) *GoogleEngine {
+// NewGoogleTTSEngine creates the engine synthesizing speech in the output format.
+// A zero output format uses DefaultOutputFormat.
+func NewGoogleTTSEngine(client *texttospeech.Client, output OutputFormat) *GoogleEngine {
+	if output.Format == AudioFormatUnknown {
+		output.Format = DefaultOutputFormat.Format
+	}
+	if output.SampleRate == 0 {
+		output.SampleRate = DefaultOutputFormat.SampleRate
+	}
 	return &GoogleEngine{
 		client: client,
+		output: output,
 	}
 }
 
@@ -25,33 +45,97 @@ func (g *GoogleEngine) Name() string {
 	return "google-cloud-text-to-speech"
 }
 
+// SpeakingRateRange returns the speaking rates accepted by the API.
+func (g *GoogleEngine) SpeakingRateRange() SpeakingRateRange {
+	return SpeakingRateRange{Min: 0.25, Max: 4.0}
+}
+
+// PitchRange returns the pitches accepted by the API.
+func (g *GoogleEngine) PitchRange() Range {
+	return Range{Min: -20.0, Max: 20.0}
+}
+
+// VolumeGainRange returns the volume gains accepted by the API.
+func (g *GoogleEngine) VolumeGainRange() Range {
+	return Range{Min: -96.0, Max: 16.0}
+}
+
+func (g *GoogleEngine) SupportsSSML() bool {
+	return true
+}
+
+// Capabilities reports the output format of g. The API accepts at most 5000 bytes of input.
+func (g *GoogleEngine) Capabilities() Capabilities {
+	return Capabilities{Output: g.output, SSML: true, MaxTextBytes: 5000, ListsVoices: true}
+}
+
 func (g *GoogleEngine) GenerateSpeech(ctx context.Context, request SpeechRequest) (*SpeechResponse, error) {
-	slog.Info("Synthesize speech", slog.String("text", request.Text))
-	resp, err := g.client.SynthesizeSpeech(ctx, &texttospeechpb.SynthesizeSpeechRequest{
-		Input: &texttospeechpb.SynthesisInput{
-			InputSource: &texttospeechpb.SynthesisInput_Text{
-				Text: request.Text,
-			},
+	slog.InfoContext(ctx, "Synthesize speech", logging.Component(logging.ComponentSynthesis), slog.String("text", request.Text))
+	input := &texttospeechpb.SynthesisInput{
+		InputSource: &texttospeechpb.SynthesisInput_Text{
+			Text: request.Text,
 		},
+	}
+	if request.InputKind == InputKindSSML {
+		input.InputSource = &texttospeechpb.SynthesisInput_Ssml{
+			Ssml: request.Text,
+		}
+	}
+
+	resp, err := g.client.SynthesizeSpeech(ctx, &texttospeechpb.SynthesizeSpeechRequest{
+		Input: input,
 		Voice: &texttospeechpb.VoiceSelectionParams{
 			LanguageCode: request.LanguageCode,
 			Name:         request.VoiceName,
 		},
 		AudioConfig: &texttospeechpb.AudioConfig{
-			AudioEncoding:   texttospeechpb.AudioEncoding_MP3,
-			SampleRateHertz: 48000,
+			AudioEncoding:   googleAudioEncoding(g.output.Format),
+			SampleRateHertz: int32(g.output.SampleRate),
 			SpeakingRate:    request.SpeakingRate,
+			Pitch:           request.Pitch,
+			VolumeGainDb:    request.VolumeGainDb,
 		},
 	})
 
 	if err != nil {
-		slog.Error("failed to synthesize speech", "error", err)
+		slog.ErrorContext(ctx, "failed to synthesize speech", "error", err)
 		return nil, err
 	}
 
 	return &SpeechResponse{
-		Format:       AudioFormatMp3,
-		Channels:     1,
-		AudioContent: resp.AudioContent,
+		Format:     g.output.Format,
+		SampleRate: g.output.SampleRate,
+		Channels:   1,
+		Audio:      bytes.NewReader(resp.AudioContent),
+		Size:       int64(len(resp.AudioContent)),
 	}, nil
 }
+
+func (g *GoogleEngine) ListVoices(ctx context.Context, languageCode string) ([]Voice, error) {
+	resp, err := g.client.ListVoices(ctx, &texttospeechpb.ListVoicesRequest{
+		LanguageCode: languageCode,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	voices := make([]Voice, 0, len(resp.Voices))
+	for _, voice := range resp.Voices {
+		voices = append(voices, Voice{
+			Name:          voice.Name,
+			LanguageCodes: voice.LanguageCodes,
+		})
+	}
+	return voices, nil
+}
+
+func googleAudioEncoding(format AudioFormat) texttospeechpb.AudioEncoding {
+	switch format {
+	case AudioFormatLinear16:
+		return texttospeechpb.AudioEncoding_LINEAR16
+	case AudioFormatOggOpus:
+		return texttospeechpb.AudioEncoding_OGG_OPUS
+	default:
+		return texttospeechpb.AudioEncoding_MP3
+	}
+}
@@ -0,0 +1,171 @@
+package tts
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/logging"
+)
+
+// latencyBuckets are the upper bounds of the latency histogram buckets.
+var latencyBuckets = []time.Duration{
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	300 * time.Millisecond,
+	500 * time.Millisecond,
+	750 * time.Millisecond,
+	1 * time.Second,
+	1500 * time.Millisecond,
+	2 * time.Second,
+	3 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// LatencyKey identifies the latency histogram of an engine and voice.
+type LatencyKey struct {
+	Engine string
+	Voice  string
+}
+
+// LatencySummary is a snapshot of a latency histogram.
+// Percentiles are the upper bounds of the buckets they fall in.
+type LatencySummary struct {
+	Count int
+	Mean  time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+type latencyHistogram struct {
+	// counts[i] counts observations up to latencyBuckets[i]; the last element counts the rest.
+	counts []int
+	count  int
+	sum    time.Duration
+	max    time.Duration
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	i, _ := slices.BinarySearch(latencyBuckets, d)
+	h.counts[i]++
+	h.count++
+	h.sum += d
+	h.max = max(h.max, d)
+}
+
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	rank := int(p * float64(h.count))
+	if rank >= h.count {
+		rank = h.count - 1
+	}
+	seen := 0
+	for i, count := range h.counts {
+		seen += count
+		if seen > rank {
+			if i == len(latencyBuckets) {
+				return h.max
+			}
+			return min(latencyBuckets[i], h.max)
+		}
+	}
+	return h.max
+}
+
+func (h *latencyHistogram) summary() LatencySummary {
+	if h.count == 0 {
+		return LatencySummary{}
+	}
+	return LatencySummary{
+		Count: h.count,
+		Mean:  h.sum / time.Duration(h.count),
+		P50:   h.percentile(0.50),
+		P95:   h.percentile(0.95),
+		P99:   h.percentile(0.99),
+		Max:   h.max,
+	}
+}
+
+// LatencyRecorder records synthesis latency per engine and voice.
+type LatencyRecorder struct {
+	mu         sync.Mutex
+	histograms map[LatencyKey]*latencyHistogram
+}
+
+func NewLatencyRecorder() *LatencyRecorder {
+	return &LatencyRecorder{
+		histograms: make(map[LatencyKey]*latencyHistogram),
+	}
+}
+
+func (r *LatencyRecorder) Observe(key LatencyKey, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.histograms[key]
+	if !ok {
+		h = &latencyHistogram{counts: make([]int, len(latencyBuckets)+1)}
+		r.histograms[key] = h
+	}
+	h.observe(d)
+}
+
+// Snapshot returns the latency summaries of every engine and voice observed so far.
+func (r *LatencyRecorder) Snapshot() map[LatencyKey]LatencySummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	snapshot := make(map[LatencyKey]LatencySummary, len(r.histograms))
+	for key, h := range r.histograms {
+		snapshot[key] = h.summary()
+	}
+	return snapshot
+}
+
+var _ Engine = (*MeasuredEngine)(nil)
+
+// MeasuredEngine is a wrapper around an Engine that records the latency of every request
+// and warns about requests slower than the threshold.
+type MeasuredEngine struct {
+	engine        Engine
+	recorder      *LatencyRecorder
+	slowThreshold time.Duration
+}
+
+// NewMeasuredEngine wraps the engine. Latency is recorded under the name of the wrapped engine.
+// A zero slowThreshold disables slow request warnings.
+func NewMeasuredEngine(engine Engine, recorder *LatencyRecorder, slowThreshold time.Duration) *MeasuredEngine {
+	return &MeasuredEngine{
+		engine:        engine,
+		recorder:      recorder,
+		slowThreshold: slowThreshold,
+	}
+}
+
+func (e *MeasuredEngine) Name() string {
+	return e.engine.Name()
+}
+
+// Unwrap returns the wrapped engine.
+func (e *MeasuredEngine) Unwrap() Engine {
+	return e.engine
+}
+
+func (e *MeasuredEngine) GenerateSpeech(ctx context.Context, request SpeechRequest) (*SpeechResponse, error) {
+	start := time.Now()
+	resp, err := e.engine.GenerateSpeech(ctx, request)
+	elapsed := time.Since(start)
+
+	if err == nil {
+		e.recorder.Observe(LatencyKey{Engine: e.engine.Name(), Voice: request.VoiceName}, elapsed)
+	}
+	if e.slowThreshold > 0 && elapsed > e.slowThreshold {
+		slog.WarnContext(ctx, "Slow speech synthesis", "engine", e.engine.Name(), "voice", request.VoiceName, "duration", elapsed, "threshold", e.slowThreshold, "textLength", len([]rune(request.Text)))
+	} else {
+		slog.DebugContext(ctx, "Speech synthesized", logging.Component(logging.ComponentSynthesis), "engine", e.engine.Name(), "voice", request.VoiceName, "duration", elapsed)
+	}
+	return resp, err
+}
@@ -0,0 +1,64 @@
+package tts
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatencyRecorder(t *testing.T) {
+	recorder := NewLatencyRecorder()
+	key := LatencyKey{Engine: "google", Voice: "en-US-Wavenet-A"}
+
+	for i := 0; i < 90; i++ {
+		recorder.Observe(key, 80*time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		recorder.Observe(key, 2500*time.Millisecond)
+	}
+
+	summary := recorder.Snapshot()[key]
+	if summary.Count != 100 {
+		t.Errorf("Count = %d, want 100", summary.Count)
+	}
+	if summary.P50 != 100*time.Millisecond {
+		t.Errorf("P50 = %v, want %v", summary.P50, 100*time.Millisecond)
+	}
+	if summary.P95 != 2500*time.Millisecond {
+		t.Errorf("P95 = %v, want %v", summary.P95, 2500*time.Millisecond)
+	}
+	if summary.Max != 2500*time.Millisecond {
+		t.Errorf("Max = %v, want %v", summary.Max, 2500*time.Millisecond)
+	}
+	if summary.Mean != 322*time.Millisecond {
+		t.Errorf("Mean = %v, want %v", summary.Mean, 322*time.Millisecond)
+	}
+}
+
+type stubEngine struct {
+	delay time.Duration
+}
+
+func (e stubEngine) Name() string { return "stub" }
+
+func (e stubEngine) GenerateSpeech(ctx context.Context, request SpeechRequest) (*SpeechResponse, error) {
+	time.Sleep(e.delay)
+	return &SpeechResponse{Format: AudioFormatMp3}, nil
+}
+
+func TestMeasuredEngine(t *testing.T) {
+	recorder := NewLatencyRecorder()
+	engine := NewMeasuredEngine(stubEngine{delay: 5 * time.Millisecond}, recorder, time.Millisecond)
+
+	if _, err := engine.GenerateSpeech(context.Background(), SpeechRequest{Text: "hello", VoiceName: "voice"}); err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+
+	summary, ok := recorder.Snapshot()[LatencyKey{Engine: "stub", Voice: "voice"}]
+	if !ok || summary.Count != 1 {
+		t.Fatalf("Snapshot() = %v, want one observation", recorder.Snapshot())
+	}
+	if summary.Max < 5*time.Millisecond {
+		t.Errorf("Max = %v, want at least %v", summary.Max, 5*time.Millisecond)
+	}
+}
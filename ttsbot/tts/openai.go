@@ -0,0 +1,197 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/logging"
+)
+
+var (
+	_ Engine              = (*OpenAIEngine)(nil)
+	_ SpeakingRateLimiter = (*OpenAIEngine)(nil)
+	_ CapabilityReporter  = (*OpenAIEngine)(nil)
+)
+
+// OpenAIBaseURL is the endpoint of the OpenAI API.
+const OpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAIDefaultOutputFormat is MP3 at the sample rate of every OpenAI voice.
+var OpenAIDefaultOutputFormat = OutputFormat{Format: AudioFormatMp3, SampleRate: openAISampleRate}
+
+const (
+	// openAISampleRate is the only sample rate OpenAI synthesizes speech at.
+	openAISampleRate = 24000
+	// openAIDefaultModel and openAIDefaultVoice are used when neither the engine nor the request chooses one.
+	openAIDefaultModel = "tts-1"
+	openAIDefaultVoice = "alloy"
+)
+
+// openAIModels are the speech models accepted by OpenAIEngine.
+var openAIModels = []string{"tts-1", "tts-1-hd"}
+
+// OpenAIEngine is an implementation of the Engine interface for the audio/speech API of OpenAI.
+// Voices are named by the OpenAI voice, e.g. "nova", optionally prefixed by the model, e.g. "tts-1-hd/nova".
+// The voices speak every language, which OpenAI detects from the text, so the language code of requests is ignored.
+type OpenAIEngine struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	model   string
+	format  AudioFormat
+	// stream returns the speech while it is synthesized, instead of once it was received entirely.
+	stream bool
+}
+
+// NewOpenAIEngine creates the engine calling the API at the base URL, OpenAIBaseURL if empty, with the API key.
+// The model, "tts-1" if empty, is used for voices that do not name one. OpenAI synthesizes speech at 24000 hertz only,
+// so a zero output format uses OpenAIDefaultOutputFormat and LINEAR16 is raw PCM without a WAV header.
+// If stream is set, speech is returned as soon as it starts to arrive, so that long messages start playing sooner.
+func NewOpenAIEngine(client *http.Client, baseURL, apiKey, model string, stream bool, output OutputFormat) (*OpenAIEngine, error) {
+	if baseURL == "" {
+		baseURL = OpenAIBaseURL
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("openai api key cannot be empty")
+	}
+	if model == "" {
+		model = openAIDefaultModel
+	}
+	if !slices.Contains(openAIModels, model) {
+		return nil, fmt.Errorf("unsupported openai model: %q", model)
+	}
+	if output.Format == AudioFormatUnknown {
+		output.Format = OpenAIDefaultOutputFormat.Format
+	}
+	if output.SampleRate != 0 && output.SampleRate != openAISampleRate {
+		return nil, fmt.Errorf("openai does not offer %d hertz", output.SampleRate)
+	}
+	return &OpenAIEngine{
+		client:  client,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		format:  output.Format,
+		stream:  stream,
+	}, nil
+}
+
+func (o *OpenAIEngine) Name() string {
+	return "openai"
+}
+
+// SpeakingRateRange returns the speeds accepted by the audio/speech API.
+func (o *OpenAIEngine) SpeakingRateRange() SpeakingRateRange {
+	return SpeakingRateRange{Min: 0.25, Max: 4.0}
+}
+
+// Capabilities reports the output format of o. The audio/speech API accepts at most 4096 characters.
+func (o *OpenAIEngine) Capabilities() Capabilities {
+	return Capabilities{Output: OutputFormat{Format: o.format, SampleRate: openAISampleRate}, MaxTextLength: 4096}
+}
+
+func (o *OpenAIEngine) GenerateSpeech(ctx context.Context, request SpeechRequest) (*SpeechResponse, error) {
+	slog.InfoContext(ctx, "Synthesize speech", logging.Component(logging.ComponentSynthesis), slog.String("text", request.Text))
+	if request.InputKind == InputKindSSML {
+		return nil, fmt.Errorf("openai does not support SSML")
+	}
+	model, voice, err := o.splitVoiceName(request.VoiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(openAISpeechRequest{
+		Model:          model,
+		Input:          request.Text,
+		Voice:          voice,
+		ResponseFormat: openAIResponseFormat(o.format),
+		Speed:          request.SpeakingRate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	requestCtx, cancel, stop := streamContext(ctx, o.stream)
+	defer stop()
+	req, err := http.NewRequestWithContext(requestCtx, http.MethodPost, o.baseURL+"/audio/speech", bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		cancel()
+		slog.ErrorContext(ctx, "failed to synthesize speech", "error", err)
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer cancel()
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("openai responded with %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+
+	speechResp := &SpeechResponse{
+		Format:     o.format,
+		SampleRate: openAISampleRate,
+		Channels:   1,
+	}
+	if o.stream {
+		speechResp.Audio = &streamedAudio{ReadCloser: resp.Body, cancel: cancel}
+		return speechResp, nil
+	}
+
+	defer cancel()
+	defer resp.Body.Close()
+	audio, err := io.ReadAll(resp.Body)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read synthesized speech", "error", err)
+		return nil, err
+	}
+	speechResp.Audio = bytes.NewReader(audio)
+	speechResp.Size = int64(len(audio))
+	return speechResp, nil
+}
+
+// splitVoiceName returns the model and the voice named by the voice name of a request, e.g. "tts-1-hd/nova".
+func (o *OpenAIEngine) splitVoiceName(voiceName string) (string, string, error) {
+	model, voice, ok := strings.Cut(voiceName, "/")
+	if !ok {
+		model, voice = o.model, voiceName
+	}
+	if !slices.Contains(openAIModels, model) {
+		return "", "", fmt.Errorf("unsupported openai model: %q", model)
+	}
+	if voice == "" {
+		voice = openAIDefaultVoice
+	}
+	return model, voice, nil
+}
+
+type openAISpeechRequest struct {
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice"`
+	ResponseFormat string  `json:"response_format"`
+	Speed          float64 `json:"speed,omitempty"`
+}
+
+func openAIResponseFormat(format AudioFormat) string {
+	switch format {
+	case AudioFormatLinear16:
+		return "pcm"
+	case AudioFormatOggOpus:
+		return "opus"
+	default:
+		return "mp3"
+	}
+}
@@ -0,0 +1,105 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIEngine_GenerateSpeech(t *testing.T) {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/audio/speech", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+		w.Write([]byte("audio"))
+	}))
+	t.Cleanup(server.Close)
+
+	engine, err := NewOpenAIEngine(server.Client(), server.URL+"/v1", "sk-test", "", false, OutputFormat{Format: AudioFormatLinear16})
+	require.NoError(t, err)
+
+	resp, err := engine.GenerateSpeech(context.Background(), SpeechRequest{Text: "hello", VoiceName: "nova", SpeakingRate: 1.5})
+	require.NoError(t, err)
+	audio, _ := io.ReadAll(resp.Audio)
+	assert.Equal(t, "audio", string(audio))
+	assert.Equal(t, AudioFormatLinear16, resp.Format)
+	assert.Equal(t, 24000, resp.SampleRate)
+	assert.Equal(t, map[string]any{"model": "tts-1", "input": "hello", "voice": "nova", "response_format": "pcm", "speed": 1.5}, requests[0])
+
+	_, err = engine.GenerateSpeech(context.Background(), SpeechRequest{Text: "hello", VoiceName: "tts-1-hd/"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"model": "tts-1-hd", "input": "hello", "voice": "alloy", "response_format": "pcm"}, requests[1])
+
+	_, err = engine.GenerateSpeech(context.Background(), SpeechRequest{Text: "hello", VoiceName: "dall-e/nova"})
+	assert.Error(t, err)
+	assert.Len(t, requests, 2)
+}
+
+func TestOpenAIEngine_GenerateSpeech_Stream(t *testing.T) {
+	// the second chunk is only sent once the first one was received, so the audio must be returned while it streams.
+	received := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("chunk1"))
+		w.(http.Flusher).Flush()
+		<-received
+		w.Write([]byte("chunk2"))
+	}))
+	t.Cleanup(server.Close)
+
+	engine, err := NewOpenAIEngine(server.Client(), server.URL, "sk-test", "", true, OutputFormat{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := engine.GenerateSpeech(ctx, SpeechRequest{Text: "hello"})
+	require.NoError(t, err)
+	defer resp.Close()
+	// the audio outlives the context of the request.
+	cancel()
+	assert.Zero(t, resp.Size)
+
+	chunk := make([]byte, 6)
+	_, err = io.ReadFull(resp.Audio, chunk)
+	require.NoError(t, err)
+	assert.Equal(t, "chunk1", string(chunk))
+	close(received)
+	rest, err := io.ReadAll(resp.Audio)
+	require.NoError(t, err)
+	assert.Equal(t, "chunk2", string(rest))
+}
+
+func TestOpenAIEngine_GenerateSpeech_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"invalid api key"}}`, http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	for _, stream := range []bool{false, true} {
+		engine, err := NewOpenAIEngine(server.Client(), server.URL, "sk-test", "tts-1-hd", stream, OutputFormat{})
+		require.NoError(t, err)
+		_, err = engine.GenerateSpeech(context.Background(), SpeechRequest{Text: "hello"})
+		assert.ErrorContains(t, err, "invalid api key")
+	}
+}
+
+func TestNewOpenAIEngine(t *testing.T) {
+	engine, err := NewOpenAIEngine(http.DefaultClient, "", "sk-test", "", false, OutputFormat{})
+	require.NoError(t, err)
+	assert.Equal(t, OpenAIBaseURL, engine.baseURL)
+	assert.Equal(t, AudioFormatMp3, engine.format)
+
+	_, err = NewOpenAIEngine(http.DefaultClient, "", "", "", false, OutputFormat{})
+	assert.Error(t, err)
+	_, err = NewOpenAIEngine(http.DefaultClient, "", "sk-test", "whisper-1", false, OutputFormat{})
+	assert.Error(t, err)
+	_, err = NewOpenAIEngine(http.DefaultClient, "", "sk-test", "", false, DefaultOutputFormat)
+	assert.Error(t, err, "openai does not offer 48000 hertz")
+}
@@ -0,0 +1,91 @@
+package tts
+
+// Range is the range of a speech parameter supported by an engine.
+type Range struct {
+	Min float64
+	Max float64
+}
+
+// Clamp returns the value clamped into the range, and whether it was changed.
+// A zero value means the default of the engine and is returned as is.
+func (r Range) Clamp(value float64) (float64, bool) {
+	switch {
+	case value == 0:
+		return value, false
+	case value < r.Min:
+		return r.Min, true
+	case value > r.Max:
+		return r.Max, true
+	default:
+		return value, false
+	}
+}
+
+// SpeakingRateRange is the range of speaking rates supported by an engine, where 1.0 is the normal speed.
+type SpeakingRateRange = Range
+
+// SpeakingRateLimiter is implemented by engines that support a limited range of speaking rates.
+type SpeakingRateLimiter interface {
+	SpeakingRateRange() SpeakingRateRange
+}
+
+// ClampSpeakingRate clamps the rate into the range supported by the engine, looking through wrappers such as CachedTTSEngine.
+// It reports whether the rate was changed. Rates of engines without a known range are returned as is.
+func ClampSpeakingRate(engine Engine, rate float64) (float64, bool) {
+	limiter, ok := findEngine[SpeakingRateLimiter](engine)
+	if !ok {
+		return rate, false
+	}
+	return limiter.SpeakingRateRange().Clamp(rate)
+}
+
+// ProsodyLimiter is implemented by engines that adjust the pitch and volume gain of speech.
+// The other engines ignore SpeechRequest.Pitch and SpeechRequest.VolumeGainDb.
+type ProsodyLimiter interface {
+	// PitchRange returns the pitches accepted, in semitones from the normal pitch of the voice.
+	PitchRange() Range
+	// VolumeGainRange returns the volume gains accepted, in decibels from the normal volume of the voice.
+	VolumeGainRange() Range
+}
+
+// SupportsProsody reports whether the engine adjusts the pitch and volume gain, looking through wrappers such as CachedTTSEngine.
+func SupportsProsody(engine Engine) bool {
+	_, ok := findEngine[ProsodyLimiter](engine)
+	return ok
+}
+
+// ClampPitch clamps the pitch into the range supported by the engine, and reports whether it was changed.
+// Pitches of engines ignoring the pitch are returned as is.
+func ClampPitch(engine Engine, pitch float64) (float64, bool) {
+	limiter, ok := findEngine[ProsodyLimiter](engine)
+	if !ok {
+		return pitch, false
+	}
+	return limiter.PitchRange().Clamp(pitch)
+}
+
+// ClampVolumeGain clamps the volume gain into the range supported by the engine, and reports whether it was changed.
+// Gains of engines ignoring the volume gain are returned as is.
+func ClampVolumeGain(engine Engine, gain float64) (float64, bool) {
+	limiter, ok := findEngine[ProsodyLimiter](engine)
+	if !ok {
+		return gain, false
+	}
+	return limiter.VolumeGainRange().Clamp(gain)
+}
+
+// findEngine returns the first engine implementing T, unwrapping engines that wrap another one.
+func findEngine[T any](engine Engine) (T, bool) {
+	for engine != nil {
+		if found, ok := engine.(T); ok {
+			return found, true
+		}
+		wrapper, ok := engine.(interface{ Unwrap() Engine })
+		if !ok {
+			break
+		}
+		engine = wrapper.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,70 @@
+package tts
+
+import (
+	"testing"
+)
+
+type limitedEngine struct {
+	stubEngine
+}
+
+func (e limitedEngine) SpeakingRateRange() SpeakingRateRange {
+	return SpeakingRateRange{Min: 0.25, Max: 4.0}
+}
+
+func TestClampSpeakingRate(t *testing.T) {
+	engine := NewMeasuredEngine(limitedEngine{}, NewLatencyRecorder(), 0)
+
+	tests := []struct {
+		rate        float64
+		want        float64
+		wantClamped bool
+	}{
+		{rate: 0, want: 0},
+		{rate: 1.2, want: 1.2},
+		{rate: 0.1, want: 0.25, wantClamped: true},
+		{rate: 10, want: 4.0, wantClamped: true},
+	}
+	for _, tt := range tests {
+		got, clamped := ClampSpeakingRate(engine, tt.rate)
+		if got != tt.want || clamped != tt.wantClamped {
+			t.Errorf("ClampSpeakingRate(%v) = %v, %v, want %v, %v", tt.rate, got, clamped, tt.want, tt.wantClamped)
+		}
+	}
+
+	if got, clamped := ClampSpeakingRate(stubEngine{}, 10); got != 10 || clamped {
+		t.Errorf("ClampSpeakingRate() of an engine without a range = %v, %v, want 10, false", got, clamped)
+	}
+}
+
+type prosodyEngine struct {
+	stubEngine
+}
+
+func (e prosodyEngine) PitchRange() Range {
+	return Range{Min: -20, Max: 20}
+}
+
+func (e prosodyEngine) VolumeGainRange() Range {
+	return Range{Min: -96, Max: 16}
+}
+
+func TestClampProsody(t *testing.T) {
+	engine := NewMeasuredEngine(prosodyEngine{}, NewLatencyRecorder(), 0)
+	if !SupportsProsody(engine) {
+		t.Fatalf("SupportsProsody() = false, want true")
+	}
+	if got, clamped := ClampPitch(engine, -30); got != -20 || !clamped {
+		t.Errorf("ClampPitch(-30) = %v, %v, want -20, true", got, clamped)
+	}
+	if got, clamped := ClampVolumeGain(engine, 6); got != 6 || clamped {
+		t.Errorf("ClampVolumeGain(6) = %v, %v, want 6, false", got, clamped)
+	}
+
+	if SupportsProsody(stubEngine{}) {
+		t.Errorf("SupportsProsody() of an engine without prosody = true, want false")
+	}
+	if got, clamped := ClampVolumeGain(stubEngine{}, 30); got != 30 || clamped {
+		t.Errorf("ClampVolumeGain() of an engine without prosody = %v, %v, want 30, false", got, clamped)
+	}
+}
@@ -0,0 +1,219 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/polly"
+	"github.com/aws/aws-sdk-go-v2/service/polly/types"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/logging"
+)
+
+var (
+	_ Engine              = (*PollyEngine)(nil)
+	_ VoiceLister         = (*PollyEngine)(nil)
+	_ SpeakingRateLimiter = (*PollyEngine)(nil)
+	_ SSMLSupporter       = (*PollyEngine)(nil)
+	_ CapabilityReporter  = (*PollyEngine)(nil)
+)
+
+// PollyDefaultOutputFormat is MP3 at the highest sample rate Polly offers for neural voices.
+var PollyDefaultOutputFormat = OutputFormat{Format: AudioFormatMp3, SampleRate: 24000}
+
+// PollyClient is the part of the Polly API used by PollyEngine, implemented by *polly.Client.
+type PollyClient interface {
+	SynthesizeSpeech(ctx context.Context, params *polly.SynthesizeSpeechInput, optFns ...func(*polly.Options)) (*polly.SynthesizeSpeechOutput, error)
+	DescribeVoices(ctx context.Context, params *polly.DescribeVoicesInput, optFns ...func(*polly.Options)) (*polly.DescribeVoicesOutput, error)
+}
+
+// PollyEngine is an implementation of the Engine interface for Amazon Polly.
+type PollyEngine struct {
+	client PollyClient
+	// voiceEngine is the Polly engine synthesizing speech, e.g. "neural" or "standard".
+	voiceEngine types.Engine
+	output      OutputFormat
+	// stream returns the speech while it is synthesized, instead of once it was received entirely.
+	stream bool
+}
+
+// NewPollyEngine creates the engine synthesizing speech with the voice engine, "neural" if empty, in the output format.
+// A zero output format uses PollyDefaultOutputFormat. Polly offers LINEAR16 at 8000 and 16000 hertz only,
+// and MP3 at up to 24000 hertz. If stream is set, speech is returned as soon as it starts to arrive.
+func NewPollyEngine(client PollyClient, voiceEngine string, stream bool, output OutputFormat) (*PollyEngine, error) {
+	if voiceEngine == "" {
+		voiceEngine = string(types.EngineNeural)
+	}
+	if !isPollyVoiceEngine(types.Engine(voiceEngine)) {
+		return nil, fmt.Errorf("unsupported polly voice engine: %q", voiceEngine)
+	}
+	if output.Format == AudioFormatUnknown {
+		output.Format = PollyDefaultOutputFormat.Format
+	}
+	if output.SampleRate == 0 {
+		output.SampleRate = PollyDefaultOutputFormat.SampleRate
+	}
+	if !isPollySampleRate(output) {
+		return nil, fmt.Errorf("polly does not offer %s at %d hertz", output.Format, output.SampleRate)
+	}
+	return &PollyEngine{
+		client:      client,
+		voiceEngine: types.Engine(voiceEngine),
+		output:      output,
+		stream:      stream,
+	}, nil
+}
+
+func (p *PollyEngine) Name() string {
+	return "amazon-polly"
+}
+
+// SpeakingRateRange returns the rates accepted by the prosody tag the speaking rate is applied with.
+func (p *PollyEngine) SpeakingRateRange() SpeakingRateRange {
+	return SpeakingRateRange{Min: 0.2, Max: 2.0}
+}
+
+func (p *PollyEngine) SupportsSSML() bool {
+	return true
+}
+
+// Capabilities reports the output format of p. Polly bills at most 3000 characters of a request.
+func (p *PollyEngine) Capabilities() Capabilities {
+	return Capabilities{Output: p.output, SSML: true, MaxTextLength: 3000, ListsVoices: true}
+}
+
+func (p *PollyEngine) GenerateSpeech(ctx context.Context, request SpeechRequest) (*SpeechResponse, error) {
+	slog.InfoContext(ctx, "Synthesize speech", logging.Component(logging.ComponentSynthesis), slog.String("text", request.Text))
+	text, textType := request.Text, types.TextTypeText
+	switch {
+	case request.InputKind == InputKindSSML:
+		textType = types.TextTypeSsml
+	case request.SpeakingRate != 0 && request.SpeakingRate != 1:
+		// Polly has no speaking rate parameter, so plain text is wrapped in a prosody tag instead.
+		text, textType = pollyProsodySSML(request.Text, request.SpeakingRate), types.TextTypeSsml
+	}
+
+	requestCtx, cancel, stop := streamContext(ctx, p.stream)
+	defer stop()
+	resp, err := p.client.SynthesizeSpeech(requestCtx, &polly.SynthesizeSpeechInput{
+		Text:         aws.String(text),
+		TextType:     textType,
+		VoiceId:      types.VoiceId(request.VoiceName),
+		LanguageCode: types.LanguageCode(request.LanguageCode),
+		Engine:       p.voiceEngine,
+		OutputFormat: pollyOutputFormat(p.output.Format),
+		SampleRate:   aws.String(strconv.Itoa(p.output.SampleRate)),
+	})
+	if err != nil {
+		cancel()
+		slog.ErrorContext(ctx, "failed to synthesize speech", "error", err)
+		return nil, err
+	}
+
+	speechResp := &SpeechResponse{
+		Format:     p.output.Format,
+		SampleRate: p.output.SampleRate,
+		Channels:   1,
+	}
+	if p.stream {
+		speechResp.Audio = &streamedAudio{ReadCloser: resp.AudioStream, cancel: cancel}
+		return speechResp, nil
+	}
+
+	defer cancel()
+	defer resp.AudioStream.Close()
+	// the stream is bound to the context of the request, so it is read before the request returns.
+	audio, err := io.ReadAll(resp.AudioStream)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read synthesized speech", "error", err)
+		return nil, err
+	}
+	speechResp.Audio = bytes.NewReader(audio)
+	speechResp.Size = int64(len(audio))
+	return speechResp, nil
+}
+
+// ListVoices returns the voices available to the voice engine of p.
+// Voices are named by their Polly voice ID, e.g. "Joanna", and also list their additional language codes.
+func (p *PollyEngine) ListVoices(ctx context.Context, languageCode string) ([]Voice, error) {
+	var (
+		voices    []Voice
+		nextToken *string
+	)
+	for {
+		resp, err := p.client.DescribeVoices(ctx, &polly.DescribeVoicesInput{
+			Engine:                         p.voiceEngine,
+			LanguageCode:                   types.LanguageCode(languageCode),
+			IncludeAdditionalLanguageCodes: true,
+			NextToken:                      nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, voice := range resp.Voices {
+			languageCodes := []string{string(voice.LanguageCode)}
+			for _, code := range voice.AdditionalLanguageCodes {
+				languageCodes = append(languageCodes, string(code))
+			}
+			voices = append(voices, Voice{
+				Name:          string(voice.Id),
+				LanguageCodes: languageCodes,
+			})
+		}
+
+		if resp.NextToken == nil || *resp.NextToken == "" {
+			return voices, nil
+		}
+		nextToken = resp.NextToken
+	}
+}
+
+// pollyProsodySSML wraps the text in SSML speaking it at the rate, where 1.0 is the normal speed.
+func pollyProsodySSML(text string, rate float64) string {
+	var escaped bytes.Buffer
+	// xml.EscapeText only fails on errors of the writer, which a bytes.Buffer never returns.
+	_ = xml.EscapeText(&escaped, []byte(text))
+	return fmt.Sprintf(`<speak><prosody rate="%d%%">%s</prosody></speak>`, int(rate*100+0.5), escaped.String())
+}
+
+func pollyOutputFormat(format AudioFormat) types.OutputFormat {
+	switch format {
+	case AudioFormatLinear16:
+		return types.OutputFormatPcm
+	default:
+		return types.OutputFormatMp3
+	}
+}
+
+func isPollyVoiceEngine(engine types.Engine) bool {
+	switch engine {
+	case types.EngineStandard, types.EngineNeural, types.EngineLongForm, types.EngineGenerative:
+		return true
+	default:
+		return false
+	}
+}
+
+func isPollySampleRate(output OutputFormat) bool {
+	switch output.Format {
+	case AudioFormatLinear16:
+		return output.SampleRate == 8000 || output.SampleRate == 16000
+	case AudioFormatMp3:
+		switch output.SampleRate {
+		case 8000, 16000, 22050, 24000:
+			return true
+		default:
+			return false
+		}
+	default:
+		// polly offers ogg with vorbis, but not with opus.
+		return false
+	}
+}
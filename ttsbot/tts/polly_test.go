@@ -0,0 +1,112 @@
+package tts
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/polly"
+	"github.com/aws/aws-sdk-go-v2/service/polly/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePollyClient struct {
+	synthesized []*polly.SynthesizeSpeechInput
+	// pages of voices returned by DescribeVoices, one per call.
+	pages [][]types.Voice
+}
+
+func (c *fakePollyClient) SynthesizeSpeech(ctx context.Context, params *polly.SynthesizeSpeechInput, optFns ...func(*polly.Options)) (*polly.SynthesizeSpeechOutput, error) {
+	c.synthesized = append(c.synthesized, params)
+	return &polly.SynthesizeSpeechOutput{AudioStream: io.NopCloser(strings.NewReader("audio"))}, nil
+}
+
+func (c *fakePollyClient) DescribeVoices(ctx context.Context, params *polly.DescribeVoicesInput, optFns ...func(*polly.Options)) (*polly.DescribeVoicesOutput, error) {
+	page := 0
+	if params.NextToken != nil {
+		page = len(*params.NextToken)
+	}
+	output := &polly.DescribeVoicesOutput{Voices: c.pages[page]}
+	if page+1 < len(c.pages) {
+		output.NextToken = aws.String(strings.Repeat("n", page+1))
+	}
+	return output, nil
+}
+
+func TestNewPollyEngine(t *testing.T) {
+	engine, err := NewPollyEngine(&fakePollyClient{}, "", false, OutputFormat{})
+	require.NoError(t, err)
+	assert.Equal(t, types.EngineNeural, engine.voiceEngine)
+	assert.Equal(t, PollyDefaultOutputFormat, engine.output)
+
+	_, err = NewPollyEngine(&fakePollyClient{}, "", false, DefaultOutputFormat)
+	assert.Error(t, err, "polly does not offer 48000 hertz")
+	_, err = NewPollyEngine(&fakePollyClient{}, "", false, OutputFormat{Format: AudioFormatLinear16, SampleRate: 24000})
+	assert.Error(t, err, "polly offers linear16 at 8000 and 16000 hertz only")
+	_, err = NewPollyEngine(&fakePollyClient{}, "", false, OutputFormat{Format: AudioFormatOggOpus, SampleRate: 24000})
+	assert.Error(t, err)
+	_, err = NewPollyEngine(&fakePollyClient{}, "turbo", false, OutputFormat{})
+	assert.Error(t, err)
+}
+
+func TestPollyEngine_GenerateSpeech(t *testing.T) {
+	client := &fakePollyClient{}
+	engine, err := NewPollyEngine(client, "standard", false, OutputFormat{Format: AudioFormatLinear16, SampleRate: 16000})
+	require.NoError(t, err)
+
+	resp, err := engine.GenerateSpeech(context.Background(), SpeechRequest{Text: "a < b", LanguageCode: "en-US", VoiceName: "Joanna", SpeakingRate: 1.25})
+	require.NoError(t, err)
+	audio, _ := io.ReadAll(resp.Audio)
+	assert.Equal(t, "audio", string(audio))
+	assert.Equal(t, int64(5), resp.Size)
+	assert.Equal(t, AudioFormatLinear16, resp.Format)
+	assert.Equal(t, 16000, resp.SampleRate)
+
+	input := client.synthesized[0]
+	assert.Equal(t, `<speak><prosody rate="125%">a &lt; b</prosody></speak>`, *input.Text)
+	assert.Equal(t, types.TextTypeSsml, input.TextType)
+	assert.Equal(t, types.VoiceId("Joanna"), input.VoiceId)
+	assert.Equal(t, types.EngineStandard, input.Engine)
+	assert.Equal(t, types.OutputFormatPcm, input.OutputFormat)
+	assert.Equal(t, "16000", *input.SampleRate)
+
+	_, err = engine.GenerateSpeech(context.Background(), SpeechRequest{Text: "plain", SpeakingRate: 1.0})
+	require.NoError(t, err)
+	assert.Equal(t, "plain", *client.synthesized[1].Text)
+	assert.Equal(t, types.TextTypeText, client.synthesized[1].TextType)
+}
+
+func TestPollyEngine_GenerateSpeech_Stream(t *testing.T) {
+	engine, err := NewPollyEngine(&fakePollyClient{}, "", true, OutputFormat{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := engine.GenerateSpeech(ctx, SpeechRequest{Text: "hello"})
+	require.NoError(t, err)
+	defer resp.Close()
+	// the audio outlives the context of the request.
+	cancel()
+	assert.Zero(t, resp.Size)
+	audio, err := io.ReadAll(resp.Audio)
+	require.NoError(t, err)
+	assert.Equal(t, "audio", string(audio))
+}
+
+func TestPollyEngine_ListVoices(t *testing.T) {
+	client := &fakePollyClient{pages: [][]types.Voice{
+		{{Id: types.VoiceIdJoanna, LanguageCode: types.LanguageCodeEnUs}},
+		{{Id: types.VoiceIdAditi, LanguageCode: types.LanguageCodeEnIn, AdditionalLanguageCodes: []types.LanguageCode{types.LanguageCodeHiIn}}},
+	}}
+	engine, err := NewPollyEngine(client, "", false, OutputFormat{})
+	require.NoError(t, err)
+
+	voices, err := engine.ListVoices(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, []Voice{
+		{Name: "Joanna", LanguageCodes: []string{"en-US"}},
+		{Name: "Aditi", LanguageCodes: []string{"en-IN", "hi-IN"}},
+	}, voices)
+}
@@ -0,0 +1,145 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+// ErrRateLimited is returned when a request would have to wait for the rate limit of the engine past its deadline.
+var ErrRateLimited = errors.New("engine is rate limited")
+
+var _ Engine = (*RateLimitedEngine)(nil)
+
+// RateLimit is the most requests and characters sent to an engine per minute. Zero does not limit them.
+type RateLimit struct {
+	RequestsPerMinute   int
+	CharactersPerMinute int
+}
+
+// RateLimitedEngine is a wrapper around an Engine that keeps its requests under a rate limit with token buckets,
+// so that many active guilds do not exceed the quota of the engine's API.
+// Requests over the limit wait for it, and fail with ErrRateLimited if they would wait past their deadline.
+type RateLimitedEngine struct {
+	engine     Engine
+	requests   *tokenBucket
+	characters *tokenBucket
+	clock      clock.Clock
+
+	// mu guards the buckets, so that a request takes from both at once.
+	mu sync.Mutex
+}
+
+// NewRateLimitedEngine wraps the engine to keep its requests under the limit.
+// The buckets start full, so a burst of up to a minute's worth of requests is sent right away.
+func NewRateLimitedEngine(engine Engine, limit RateLimit) *RateLimitedEngine {
+	e := &RateLimitedEngine{engine: engine, clock: clock.Real}
+	if limit.RequestsPerMinute > 0 {
+		e.requests = newTokenBucket(float64(limit.RequestsPerMinute))
+	}
+	if limit.CharactersPerMinute > 0 {
+		e.characters = newTokenBucket(float64(limit.CharactersPerMinute))
+	}
+	return e
+}
+
+func (e *RateLimitedEngine) Name() string {
+	return e.engine.Name()
+}
+
+// Unwrap returns the wrapped engine.
+func (e *RateLimitedEngine) Unwrap() Engine {
+	return e.engine
+}
+
+func (e *RateLimitedEngine) GenerateSpeech(ctx context.Context, request SpeechRequest) (*SpeechResponse, error) {
+	if err := e.wait(ctx, float64(utf8.RuneCountInString(request.Text))); err != nil {
+		return nil, fmt.Errorf("%s: %w", e.engine.Name(), err)
+	}
+	return e.engine.GenerateSpeech(ctx, request)
+}
+
+// wait blocks until a request of the characters is within the limit, and takes it from the buckets.
+func (e *RateLimitedEngine) wait(ctx context.Context, characters float64) error {
+	for {
+		now := e.clock.Now()
+		wait := e.take(now, characters)
+		if wait == 0 {
+			return nil
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return ErrRateLimited
+		}
+		select {
+		case <-e.clock.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// take takes the request from both buckets if both have enough tokens,
+// and otherwise returns how long until they will have.
+func (e *RateLimitedEngine) take(now time.Time, characters float64) time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	buckets := []struct {
+		bucket *tokenBucket
+		cost   float64
+	}{{e.requests, 1}, {e.characters, characters}}
+
+	var wait time.Duration
+	for _, b := range buckets {
+		if b.bucket != nil {
+			wait = max(wait, b.bucket.waitFor(now, b.cost))
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+	for _, b := range buckets {
+		if b.bucket != nil {
+			b.bucket.take(b.cost)
+		}
+	}
+	return 0
+}
+
+// tokenBucket holds up to a minute's worth of tokens, refilled continuously.
+type tokenBucket struct {
+	perMinute float64
+	tokens    float64
+	// filledAt is when the tokens were last refilled, or zero before the first request.
+	filledAt time.Time
+}
+
+func newTokenBucket(perMinute float64) *tokenBucket {
+	return &tokenBucket{perMinute: perMinute, tokens: perMinute}
+}
+
+// waitFor refills the bucket and returns how long until it has the tokens, or zero if it has them now.
+// Costs over the capacity wait for a full bucket.
+func (b *tokenBucket) waitFor(now time.Time, cost float64) time.Duration {
+	if b.filledAt.IsZero() {
+		b.filledAt = now
+	}
+	if elapsed := now.Sub(b.filledAt); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Minutes()*b.perMinute, b.perMinute)
+		b.filledAt = now
+	}
+	missing := min(cost, b.perMinute) - b.tokens
+	if missing <= 0 {
+		return 0
+	}
+	return max(time.Duration(missing/b.perMinute*float64(time.Minute)), time.Millisecond)
+}
+
+// take takes the tokens, leaving the bucket empty if it has fewer.
+func (b *tokenBucket) take(cost float64) {
+	b.tokens = max(b.tokens-cost, 0)
+}
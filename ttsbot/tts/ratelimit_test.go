@@ -0,0 +1,70 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+func TestRateLimitedEngine(t *testing.T) {
+	engine := &failingEngine{}
+	limited := NewRateLimitedEngine(engine, RateLimit{RequestsPerMinute: 2, CharactersPerMinute: 10})
+	clk := clock.NewFake(time.Unix(0, 0))
+	limited.clock = clk
+
+	// a minute's worth of requests is sent right away.
+	for range 2 {
+		if _, err := limited.GenerateSpeech(context.Background(), SpeechRequest{Text: "hi"}); err != nil {
+			t.Fatalf("err = %v, want nil", err)
+		}
+	}
+
+	// requests over the limit fail if they would wait past their deadline.
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(time.Second))
+	defer cancel()
+	if _, err := limited.GenerateSpeech(ctx, SpeechRequest{Text: "hi"}); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("err = %v, want ErrRateLimited", err)
+	}
+	if engine.calls != 2 {
+		t.Errorf("calls = %d, want 2", engine.calls)
+	}
+
+	// and otherwise wait for a token.
+	done := make(chan error)
+	go func() {
+		_, err := limited.GenerateSpeech(context.Background(), SpeechRequest{Text: "hi"})
+		done <- err
+	}()
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clk.Advance(30 * time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("err = %v, want nil after waiting", err)
+	}
+	if engine.calls != 3 {
+		t.Errorf("calls = %d, want 3", engine.calls)
+	}
+}
+
+func TestRateLimitedEngineCharacters(t *testing.T) {
+	limited := NewRateLimitedEngine(&failingEngine{}, RateLimit{CharactersPerMinute: 10})
+	clk := clock.NewFake(time.Unix(0, 0))
+	limited.clock = clk
+
+	if wait := limited.take(clk.Now(), 8); wait != 0 {
+		t.Fatalf("wait = %v, want 0", wait)
+	}
+	// 6 more characters need 4 more tokens, refilled in 24 seconds.
+	if wait := limited.take(clk.Now(), 6); wait != 24*time.Second {
+		t.Errorf("wait = %v, want 24s", wait)
+	}
+	// requests longer than a minute's worth wait for a full bucket instead of forever.
+	clk.Advance(time.Minute)
+	if wait := limited.take(clk.Now(), 50); wait != 0 {
+		t.Errorf("wait = %v, want 0 with a full bucket", wait)
+	}
+}
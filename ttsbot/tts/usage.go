@@ -0,0 +1,263 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+// ErrBudgetExceeded is returned when a guild has used up its monthly character budget.
+var ErrBudgetExceeded = errors.New("monthly character budget of the guild is used up")
+
+// UsageWarningRatio is the share of its budget after which a guild is warned that its budget is running low.
+const UsageWarningRatio = 0.8
+
+// BudgetFunc returns the monthly character budget of the guild. Zero does not limit it.
+type BudgetFunc func(guildID snowflake.ID) int64
+
+// Usage is the characters a guild synthesized in a month.
+type Usage struct {
+	// Period is the month, e.g. "2025-10".
+	Period string
+	// Engines are the characters synthesized with each engine.
+	Engines map[string]int64
+	// Budget is the monthly character budget of the guild, or zero if it is not limited.
+	Budget int64
+}
+
+// Total returns the characters synthesized with every engine.
+func (u Usage) Total() int64 {
+	var total int64
+	for _, characters := range u.Engines {
+		total += characters
+	}
+	return total
+}
+
+// Pricing is the cost of a million characters synthesized with each engine, in the currency of the bills of the operator.
+// Engines missing from it are taken as free, e.g. a self-hosted voicevox.
+type Pricing map[string]float64
+
+// Estimate returns the estimated cost of the characters synthesized with each engine.
+func (p Pricing) Estimate(engines map[string]int64) float64 {
+	var cost float64
+	for engine, characters := range engines {
+		cost += float64(characters) * p[engine] / 1_000_000
+	}
+	return cost
+}
+
+// UsageTracker records the characters each guild synthesizes with each engine, and enforces their monthly budget.
+// Usage is counted in memory and flushed to the repository periodically, so that reading does not wait for the database.
+type UsageTracker struct {
+	repository UsageRepository
+	budget     BudgetFunc
+	clock      clock.Clock
+
+	// flushing serializes flushes and the loading of usage, so that pending usage is neither lost nor counted twice.
+	flushing sync.Mutex
+
+	mu sync.Mutex
+	// pending is the usage not flushed to the repository yet.
+	pending map[usageKey]int64
+	// guilds caches the usage of the guilds in the current period, including the pending usage.
+	guilds map[snowflake.ID]Usage
+}
+
+type usageKey struct {
+	guildID snowflake.ID
+	engine  string
+	period  string
+}
+
+// NewUsageTracker creates a tracker storing the usage in the repository. budget may be nil, which does not limit any guild.
+func NewUsageTracker(repository UsageRepository, budget BudgetFunc) *UsageTracker {
+	return &UsageTracker{
+		repository: repository,
+		budget:     budget,
+		clock:      clock.Real,
+		pending:    make(map[usageKey]int64),
+		guilds:     make(map[snowflake.ID]Usage),
+	}
+}
+
+// Usage returns the usage of the guild in the current month.
+func (t *UsageTracker) Usage(ctx context.Context, guildID snowflake.ID) (Usage, error) {
+	period := t.period()
+	t.mu.Lock()
+	usage, ok := t.guilds[guildID]
+	// Record adds to the cached engines while holding mu, so they are cloned before it is released.
+	usage.Engines = maps.Clone(usage.Engines)
+	t.mu.Unlock()
+	if !ok || usage.Period != period {
+		var err error
+		if usage, err = t.load(ctx, guildID, period); err != nil {
+			return Usage{}, err
+		}
+	}
+	usage.Budget = t.budgetOf(guildID)
+	return usage, nil
+}
+
+// Check returns ErrBudgetExceeded if synthesizing the characters would exceed the budget of the guild.
+func (t *UsageTracker) Check(ctx context.Context, guildID snowflake.ID, characters int) error {
+	budget := t.budgetOf(guildID)
+	if budget <= 0 {
+		return nil
+	}
+	usage, err := t.Usage(ctx, guildID)
+	if err != nil {
+		return err
+	}
+	if total := usage.Total(); total+int64(characters) > budget {
+		return fmt.Errorf("%w: %d of %d characters used", ErrBudgetExceeded, total, budget)
+	}
+	return nil
+}
+
+// Record counts the characters the guild synthesized with the engine.
+// It reports whether they made the guild cross UsageWarningRatio of its budget.
+func (t *UsageTracker) Record(ctx context.Context, guildID snowflake.ID, engine string, characters int) (bool, error) {
+	if characters <= 0 {
+		return false, nil
+	}
+	period := t.period()
+	// the usage is loaded first, so that the characters are added to the stored usage of the month.
+	if _, err := t.Usage(ctx, guildID); err != nil {
+		return false, err
+	}
+	// the budget may be looked up from elsewhere, so it is not done while holding mu.
+	budget := t.budgetOf(guildID)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[usageKey{guildID, engine, period}] += int64(characters)
+	usage, ok := t.guilds[guildID]
+	if !ok || usage.Period != period {
+		// the month turned since the usage was loaded; it is loaded again with the pending usage.
+		delete(t.guilds, guildID)
+		return false, nil
+	}
+	before := usage.Total()
+	usage.Engines[engine] += int64(characters)
+
+	if budget <= 0 {
+		return false, nil
+	}
+	threshold := int64(float64(budget) * UsageWarningRatio)
+	return before < threshold && usage.Total() >= threshold, nil
+}
+
+// Flush writes the pending usage to the repository. Usage that failed to be written stays pending.
+func (t *UsageTracker) Flush(ctx context.Context) error {
+	t.flushing.Lock()
+	defer t.flushing.Unlock()
+
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[usageKey]int64)
+	t.mu.Unlock()
+
+	var errs []error
+	for key, characters := range pending {
+		if err := t.repository.Add(ctx, key.guildID, key.engine, key.period, characters); err != nil {
+			errs = append(errs, fmt.Errorf("failed to record usage of guild %s: %w", key.guildID, err))
+			t.mu.Lock()
+			t.pending[key] += characters
+			t.mu.Unlock()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Report returns the usage of every guild in the period, e.g. "2025-10". An empty period is the current month.
+// The pending usage is flushed first, so that the report is up to date.
+func (t *UsageTracker) Report(ctx context.Context, period string) (map[snowflake.ID]Usage, error) {
+	if period == "" {
+		period = t.period()
+	}
+	if err := t.Flush(ctx); err != nil {
+		return nil, err
+	}
+	usage, err := t.repository.FindPeriod(ctx, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load usage of %s: %w", period, err)
+	}
+	report := make(map[snowflake.ID]Usage, len(usage))
+	for guildID, engines := range usage {
+		report[guildID] = Usage{Period: period, Engines: engines, Budget: t.budgetOf(guildID)}
+	}
+	return report, nil
+}
+
+// StartFlushLoop flushes the pending usage every interval.
+func (t *UsageTracker) StartFlushLoop(interval time.Duration) {
+	ticker := t.clock.NewTicker(interval)
+	go func() {
+		for range ticker.C() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if err := t.Flush(ctx); err != nil {
+				slog.Error("Failed to flush usage", slog.Any("err", err))
+			}
+			cancel()
+		}
+	}()
+}
+
+// Delete forgets the usage of the guild, stored and pending.
+func (t *UsageTracker) Delete(ctx context.Context, guildID snowflake.ID) error {
+	t.flushing.Lock()
+	defer t.flushing.Unlock()
+
+	t.mu.Lock()
+	delete(t.guilds, guildID)
+	for key := range t.pending {
+		if key.guildID == guildID {
+			delete(t.pending, key)
+		}
+	}
+	t.mu.Unlock()
+	return t.repository.Delete(ctx, guildID)
+}
+
+// load reads the usage of the guild in the period from the repository, adds the pending usage, and caches it.
+// The returned usage has its own copy of the engines, as the cached ones are added to by Record.
+func (t *UsageTracker) load(ctx context.Context, guildID snowflake.ID, period string) (Usage, error) {
+	t.flushing.Lock()
+	defer t.flushing.Unlock()
+
+	engines, err := t.repository.Find(ctx, guildID, period)
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to load usage of guild %s: %w", guildID, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, characters := range t.pending {
+		if key.guildID == guildID && key.period == period {
+			engines[key.engine] += characters
+		}
+	}
+	t.guilds[guildID] = Usage{Period: period, Engines: engines}
+	return Usage{Period: period, Engines: maps.Clone(engines)}, nil
+}
+
+func (t *UsageTracker) budgetOf(guildID snowflake.ID) int64 {
+	if t.budget == nil {
+		return 0
+	}
+	return t.budget(guildID)
+}
+
+// period returns the current month in UTC, e.g. "2025-10".
+func (t *UsageTracker) period() string {
+	return t.clock.Now().UTC().Format("2006-01")
+}
@@ -0,0 +1,194 @@
+package tts
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database"
+)
+
+// UsageRepository stores the characters each guild synthesized with each engine per month.
+// Months are named as periods such as "2025-10".
+type UsageRepository interface {
+	// Add adds the characters to the usage of the engine by the guild in the period.
+	Add(ctx context.Context, guildID snowflake.ID, engine, period string, characters int64) error
+	// Find returns the characters the guild synthesized with each engine in the period.
+	Find(ctx context.Context, guildID snowflake.ID, period string) (map[string]int64, error)
+	// FindPeriod returns the characters every guild synthesized with each engine in the period.
+	FindPeriod(ctx context.Context, period string) (map[snowflake.ID]map[string]int64, error)
+	// Delete forgets the usage of the guild, e.g. once it removed the bot.
+	Delete(ctx context.Context, guildID snowflake.ID) error
+}
+
+func NewUsageRepository(db *sqlx.DB) UsageRepository {
+	return &usageRepositoryImpl{
+		db:   db,
+		psql: database.DialectOf(db).StatementBuilder(),
+	}
+}
+
+type usageRepositoryImpl struct {
+	db   *sqlx.DB
+	psql squirrel.StatementBuilderType
+}
+
+type usageRow struct {
+	GuildID    snowflake.ID `db:"guild_id"`
+	Engine     string       `db:"engine"`
+	Characters int64        `db:"characters"`
+}
+
+func (r *usageRepositoryImpl) Add(ctx context.Context, guildID snowflake.ID, engine, period string, characters int64) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the characters are added in place, as an upsert would overwrite them.
+	now := time.Now()
+	query, args, err := r.psql.Update("guild_engine_usage").
+		Set("characters", squirrel.Expr("characters + ?", characters)).
+		Set("updated_at", now).
+		Where(squirrel.Eq{"guild_id": guildID, "engine": engine, "period": period}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return err
+	} else if updated == 0 {
+		query, args, err := r.psql.Insert("guild_engine_usage").
+			Columns("guild_id", "engine", "period", "characters", "updated_at").
+			Values(guildID, engine, period, characters, now).
+			ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *usageRepositoryImpl) Find(ctx context.Context, guildID snowflake.ID, period string) (map[string]int64, error) {
+	query, args, err := r.psql.Select("engine", "characters").
+		From("guild_engine_usage").
+		Where(squirrel.Eq{"guild_id": guildID, "period": period}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []usageRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	usage := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		usage[row.Engine] = row.Characters
+	}
+	return usage, nil
+}
+
+func (r *usageRepositoryImpl) FindPeriod(ctx context.Context, period string) (map[snowflake.ID]map[string]int64, error) {
+	query, args, err := r.psql.Select("guild_id", "engine", "characters").
+		From("guild_engine_usage").
+		Where(squirrel.Eq{"period": period}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []usageRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	usage := make(map[snowflake.ID]map[string]int64)
+	for _, row := range rows {
+		if usage[row.GuildID] == nil {
+			usage[row.GuildID] = make(map[string]int64)
+		}
+		usage[row.GuildID][row.Engine] = row.Characters
+	}
+	return usage, nil
+}
+
+func (r *usageRepositoryImpl) Delete(ctx context.Context, guildID snowflake.ID) error {
+	query, args, err := r.psql.Delete("guild_engine_usage").
+		Where(squirrel.Eq{"guild_id": guildID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// NewMemoryUsageRepository returns a UsageRepository that keeps the usage in memory.
+// It is used when the bot runs without a database, so the usage is lost on restart.
+func NewMemoryUsageRepository() UsageRepository {
+	return &memoryUsageRepository{
+		usage: make(map[snowflake.ID]map[string]map[string]int64),
+	}
+}
+
+type memoryUsageRepository struct {
+	mu sync.RWMutex
+	// usage is keyed by guild, then period, then engine.
+	usage map[snowflake.ID]map[string]map[string]int64
+}
+
+func (r *memoryUsageRepository) Add(ctx context.Context, guildID snowflake.ID, engine, period string, characters int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.usage[guildID] == nil {
+		r.usage[guildID] = make(map[string]map[string]int64)
+	}
+	if r.usage[guildID][period] == nil {
+		r.usage[guildID][period] = make(map[string]int64)
+	}
+	r.usage[guildID][period][engine] += characters
+	return nil
+}
+
+func (r *memoryUsageRepository) Find(ctx context.Context, guildID snowflake.ID, period string) (map[string]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	usage := make(map[string]int64, len(r.usage[guildID][period]))
+	for engine, characters := range r.usage[guildID][period] {
+		usage[engine] = characters
+	}
+	return usage, nil
+}
+
+func (r *memoryUsageRepository) FindPeriod(ctx context.Context, period string) (map[snowflake.ID]map[string]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	usage := make(map[snowflake.ID]map[string]int64)
+	for guildID, periods := range r.usage {
+		if len(periods[period]) > 0 {
+			usage[guildID] = maps.Clone(periods[period])
+		}
+	}
+	return usage, nil
+}
+
+func (r *memoryUsageRepository) Delete(ctx context.Context, guildID snowflake.ID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.usage, guildID)
+	return nil
+}
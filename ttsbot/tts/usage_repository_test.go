@@ -0,0 +1,61 @@
+package tts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/disgoorg/snowflake/v2"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/database/databasetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageRepository(t *testing.T) {
+	for _, database := range databasetest.Databases {
+		t.Run(database.Name, func(t *testing.T) {
+			testUsageRepository(t, NewUsageRepository(database.Open(t, "../../migrations")))
+		})
+	}
+}
+
+func TestMemoryUsageRepository(t *testing.T) {
+	testUsageRepository(t, NewMemoryUsageRepository())
+}
+
+func testUsageRepository(t *testing.T, repo UsageRepository) {
+	ctx := context.Background()
+
+	usage, err := repo.Find(ctx, 1, "2025-10")
+	require.NoError(t, err)
+	require.Empty(t, usage)
+
+	require.NoError(t, repo.Add(ctx, 1, "google", "2025-10", 100))
+	require.NoError(t, repo.Add(ctx, 1, "google", "2025-10", 20))
+	require.NoError(t, repo.Add(ctx, 1, "voicevox", "2025-10", 5))
+	require.NoError(t, repo.Add(ctx, 1, "google", "2025-11", 7))
+	require.NoError(t, repo.Add(ctx, 2, "google", "2025-10", 3))
+
+	usage, err = repo.Find(ctx, 1, "2025-10")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"google": 120, "voicevox": 5}, usage)
+
+	usage, err = repo.Find(ctx, 1, "2025-11")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"google": 7}, usage)
+
+	report, err := repo.FindPeriod(ctx, "2025-10")
+	require.NoError(t, err)
+	require.Equal(t, map[snowflake.ID]map[string]int64{1: {"google": 120, "voicevox": 5}, 2: {"google": 3}}, report)
+
+	require.NoError(t, repo.Delete(ctx, 1))
+	usage, err = repo.Find(ctx, 1, "2025-10")
+	require.NoError(t, err)
+	require.Empty(t, usage)
+
+	usage, err = repo.Find(ctx, 2, "2025-10")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"google": 3}, usage)
+}
@@ -0,0 +1,159 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/clock"
+)
+
+func newTestUsageTracker(repo UsageRepository, budget int64) (*UsageTracker, *clock.Fake) {
+	tracker := NewUsageTracker(repo, func(guildID snowflake.ID) int64 {
+		return budget
+	})
+	clk := clock.NewFake(time.Date(2025, 10, 31, 23, 0, 0, 0, time.UTC))
+	tracker.clock = clk
+	return tracker, clk
+}
+
+func TestUsageTrackerBudget(t *testing.T) {
+	ctx := context.Background()
+	tracker, _ := newTestUsageTracker(NewMemoryUsageRepository(), 100)
+
+	require.NoError(t, tracker.Check(ctx, 1, 100))
+	low, err := tracker.Record(ctx, 1, "google", 70)
+	require.NoError(t, err)
+	require.False(t, low)
+
+	// crossing the warning ratio is reported once.
+	low, err = tracker.Record(ctx, 1, "voicevox", 10)
+	require.NoError(t, err)
+	require.True(t, low)
+	low, err = tracker.Record(ctx, 1, "voicevox", 10)
+	require.NoError(t, err)
+	require.False(t, low)
+
+	require.NoError(t, tracker.Check(ctx, 1, 10))
+	require.True(t, errors.Is(tracker.Check(ctx, 1, 11), ErrBudgetExceeded))
+	// other guilds have their own budget.
+	require.NoError(t, tracker.Check(ctx, 2, 100))
+
+	usage, err := tracker.Usage(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, Usage{Period: "2025-10", Engines: map[string]int64{"google": 70, "voicevox": 20}, Budget: 100}, usage)
+}
+
+func TestUsageTrackerUnlimited(t *testing.T) {
+	ctx := context.Background()
+	tracker, _ := newTestUsageTracker(NewMemoryUsageRepository(), 0)
+
+	low, err := tracker.Record(ctx, 1, "google", 1_000_000)
+	require.NoError(t, err)
+	require.False(t, low)
+	require.NoError(t, tracker.Check(ctx, 1, 1_000_000))
+}
+
+func TestUsageTrackerFlush(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUsageRepository()
+	tracker, _ := newTestUsageTracker(repo, 0)
+
+	_, err := tracker.Record(ctx, 1, "google", 30)
+	require.NoError(t, err)
+	stored, err := repo.Find(ctx, 1, "2025-10")
+	require.NoError(t, err)
+	require.Empty(t, stored)
+
+	require.NoError(t, tracker.Flush(ctx))
+	stored, err = repo.Find(ctx, 1, "2025-10")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"google": 30}, stored)
+
+	// a restarted tracker continues from the stored usage.
+	restarted, _ := newTestUsageTracker(repo, 0)
+	_, err = restarted.Record(ctx, 1, "google", 5)
+	require.NoError(t, err)
+	usage, err := restarted.Usage(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(35), usage.Total())
+
+	require.NoError(t, restarted.Delete(ctx, 1))
+	require.NoError(t, restarted.Flush(ctx))
+	stored, err = repo.Find(ctx, 1, "2025-10")
+	require.NoError(t, err)
+	require.Empty(t, stored)
+}
+
+func TestUsageTrackerMonthRollover(t *testing.T) {
+	ctx := context.Background()
+	tracker, clk := newTestUsageTracker(NewMemoryUsageRepository(), 100)
+
+	_, err := tracker.Record(ctx, 1, "google", 100)
+	require.NoError(t, err)
+	require.True(t, errors.Is(tracker.Check(ctx, 1, 1), ErrBudgetExceeded))
+
+	clk.Advance(2 * time.Hour)
+	require.NoError(t, tracker.Check(ctx, 1, 100))
+	usage, err := tracker.Usage(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, "2025-11", usage.Period)
+	require.Zero(t, usage.Total())
+}
+
+func TestUsageTrackerReport(t *testing.T) {
+	ctx := context.Background()
+	tracker, _ := newTestUsageTracker(NewMemoryUsageRepository(), 100)
+
+	_, err := tracker.Record(ctx, 1, "google", 30)
+	require.NoError(t, err)
+	_, err = tracker.Record(ctx, 2, "voicevox", 5)
+	require.NoError(t, err)
+
+	// the pending usage is part of the report.
+	report, err := tracker.Report(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, map[snowflake.ID]Usage{
+		1: {Period: "2025-10", Engines: map[string]int64{"google": 30}, Budget: 100},
+		2: {Period: "2025-10", Engines: map[string]int64{"voicevox": 5}, Budget: 100},
+	}, report)
+
+	report, err = tracker.Report(ctx, "2025-09")
+	require.NoError(t, err)
+	require.Empty(t, report)
+}
+
+func TestPricingEstimate(t *testing.T) {
+	pricing := Pricing{"google": 16, "polly": 4}
+	require.InDelta(t, 16*0.5+4*0.25, pricing.Estimate(map[string]int64{"google": 500_000, "polly": 250_000, "voicevox": 1_000_000}), 1e-9)
+}
+
+func TestUsageTrackerConcurrent(t *testing.T) {
+	ctx := context.Background()
+	tracker, _ := newTestUsageTracker(NewMemoryUsageRepository(), 1_000_000)
+
+	// segments of a task are recorded concurrently while messages check the budget of the same guild.
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				_, err := tracker.Record(ctx, 1, fmt.Sprintf("engine-%d", i), 1)
+				require.NoError(t, err)
+				require.NoError(t, tracker.Check(ctx, 1, 1))
+			}
+		}()
+	}
+	wg.Wait()
+
+	usage, err := tracker.Usage(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(16000), usage.Total())
+}
@@ -0,0 +1,181 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrVoiceListingUnsupported = errors.New("engine can not list its voices")
+	ErrUnknownVoice            = errors.New("unknown voice")
+)
+
+// Voice is a voice offered by an engine.
+type Voice struct {
+	Name          string
+	LanguageCodes []string
+}
+
+// VoiceLister is implemented by engines that can list the voices they offer.
+type VoiceLister interface {
+	// ListVoices returns the voices supporting the language code, or every voice if the language code is empty.
+	ListVoices(ctx context.Context, languageCode string) ([]Voice, error)
+}
+
+// ListVoices lists the voices of the engine, looking through wrappers such as CachedTTSEngine.
+// It returns ErrVoiceListingUnsupported if the engine can not list its voices.
+func ListVoices(ctx context.Context, engine Engine, languageCode string) ([]Voice, error) {
+	lister, ok := findEngine[VoiceLister](engine)
+	if !ok {
+		return nil, ErrVoiceListingUnsupported
+	}
+	return lister.ListVoices(ctx, languageCode)
+}
+
+// CheckVoice returns ErrUnknownVoice if none of the voices matches the language code and voice name.
+// An empty voice name matches any voice of the language, as the engine picks one in that case.
+// Language codes are compared case-insensitively.
+func CheckVoice(voices []Voice, languageCode, voiceName string) error {
+	speaks := func(voice Voice) bool {
+		return slices.ContainsFunc(voice.LanguageCodes, func(code string) bool {
+			return strings.EqualFold(code, languageCode)
+		})
+	}
+
+	if voiceName == "" {
+		if slices.ContainsFunc(voices, speaks) {
+			return nil
+		}
+		return fmt.Errorf("%w: no voice supports language %q", ErrUnknownVoice, languageCode)
+	}
+
+	i := slices.IndexFunc(voices, func(voice Voice) bool {
+		return voice.Name == voiceName
+	})
+	if i < 0 {
+		return fmt.Errorf("%w: %q", ErrUnknownVoice, voiceName)
+	}
+	if languageCode != "" && !speaks(voices[i]) {
+		return fmt.Errorf("%w: %q does not support language %q", ErrUnknownVoice, voiceName, languageCode)
+	}
+	return nil
+}
+
+// VoiceCatalog caches the voices of the engines in a registry, so that they are listed at most once per TTL.
+type VoiceCatalog struct {
+	registry *EngineRegistry
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]voiceCatalogEntry // engine identifier -> voices
+	now     func() time.Time
+}
+
+type voiceCatalogEntry struct {
+	voices   []Voice
+	listedAt time.Time
+}
+
+func NewVoiceCatalog(registry *EngineRegistry, ttl time.Duration) *VoiceCatalog {
+	return &VoiceCatalog{
+		registry: registry,
+		ttl:      ttl,
+		entries:  make(map[string]voiceCatalogEntry),
+		now:      time.Now,
+	}
+}
+
+// Voices returns every voice of the engine with the given identifier.
+// It returns ErrVoiceListingUnsupported if the engine can not list its voices.
+func (c *VoiceCatalog) Voices(ctx context.Context, engineID string) ([]Voice, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[engineID]
+	c.mu.Unlock()
+	if ok && c.now().Sub(entry.listedAt) < c.ttl {
+		return entry.voices, nil
+	}
+
+	engine, ok := c.registry.Get(engineID)
+	if !ok {
+		return nil, fmt.Errorf("engine not found: %s", engineID)
+	}
+	voices, err := ListVoices(ctx, engine, "")
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[engineID] = voiceCatalogEntry{voices: voices, listedAt: c.now()}
+	c.mu.Unlock()
+	return voices, nil
+}
+
+// DefaultVoice picks a voice of the engine for the language, along with the language code to request it with.
+// The language may be a full code such as "ja-JP" or just the language such as "ja".
+// Voices of the exact language code are preferred, and ties are broken by name so that the pick is stable.
+func (c *VoiceCatalog) DefaultVoice(ctx context.Context, engineID, language string) (Voice, string, error) {
+	voices, err := c.Voices(ctx, engineID)
+	if err != nil {
+		return Voice{}, "", err
+	}
+	return pickDefaultVoice(voices, language)
+}
+
+// LanguageVoices returns the voices of the engine speaking the language, sorted by name.
+// The language may be a full code such as "ja-JP" or just the language such as "ja", which matches every region of it.
+func (c *VoiceCatalog) LanguageVoices(ctx context.Context, engineID, language string) ([]Voice, error) {
+	voices, err := c.Voices(ctx, engineID)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Voice
+	for _, voice := range voices {
+		if slices.ContainsFunc(voice.LanguageCodes, func(code string) bool {
+			return strings.EqualFold(code, language) || hasLanguagePrefix(code, language)
+		}) {
+			found = append(found, voice)
+		}
+	}
+	slices.SortFunc(found, func(a, b Voice) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return found, nil
+}
+
+func pickDefaultVoice(voices []Voice, language string) (Voice, string, error) {
+	var (
+		best        Voice
+		bestCode    string
+		bestIsExact bool
+		found       bool
+	)
+	for _, voice := range voices {
+		for _, code := range voice.LanguageCodes {
+			exact := strings.EqualFold(code, language)
+			if !exact && !hasLanguagePrefix(code, language) {
+				continue
+			}
+			better := !found ||
+				(exact && !bestIsExact) ||
+				(exact == bestIsExact && voice.Name < best.Name)
+			if better {
+				best, bestCode, bestIsExact, found = voice, code, exact, true
+			}
+		}
+	}
+	if !found {
+		return Voice{}, "", fmt.Errorf("%w: no voice supports language %q", ErrUnknownVoice, language)
+	}
+	return best, bestCode, nil
+}
+
+// hasLanguagePrefix reports whether the language code is a variant of the language, e.g. "ja-JP" of "ja".
+func hasLanguagePrefix(code, language string) bool {
+	return len(code) > len(language) && code[len(language)] == '-' && strings.EqualFold(code[:len(language)], language)
+}
@@ -0,0 +1,135 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type listingEngine struct {
+	stubEngine
+	voices []Voice
+}
+
+func (e listingEngine) ListVoices(ctx context.Context, languageCode string) ([]Voice, error) {
+	return e.voices, nil
+}
+
+func TestListVoices(t *testing.T) {
+	voices := []Voice{{Name: "en-US-Wavenet-A", LanguageCodes: []string{"en-US"}}}
+	engine := NewMeasuredEngine(NewCachedTTSEngine(listingEngine{voices: voices}, nil, 0, nil), NewLatencyRecorder(), 0)
+
+	got, err := ListVoices(context.Background(), engine, "")
+	if err != nil {
+		t.Fatalf("ListVoices() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "en-US-Wavenet-A" {
+		t.Errorf("ListVoices() = %v, want %v", got, voices)
+	}
+
+	if _, err := ListVoices(context.Background(), stubEngine{}, ""); !errors.Is(err, ErrVoiceListingUnsupported) {
+		t.Errorf("ListVoices() error = %v, want %v", err, ErrVoiceListingUnsupported)
+	}
+}
+
+func TestCheckVoice(t *testing.T) {
+	voices := []Voice{
+		{Name: "en-US-Wavenet-A", LanguageCodes: []string{"en-US"}},
+		{Name: "ja-JP-Wavenet-A", LanguageCodes: []string{"ja-JP"}},
+	}
+
+	tests := []struct {
+		name         string
+		languageCode string
+		voiceName    string
+		wantErr      bool
+	}{
+		{name: "known voice", languageCode: "en-US", voiceName: "en-US-Wavenet-A"},
+		{name: "language case", languageCode: "ja-jp", voiceName: "ja-JP-Wavenet-A"},
+		{name: "language only", languageCode: "ja-JP"},
+		{name: "typo", languageCode: "en-US", voiceName: "en-US-Wavnet-A", wantErr: true},
+		{name: "wrong language", languageCode: "ja-JP", voiceName: "en-US-Wavenet-A", wantErr: true},
+		{name: "unsupported language", languageCode: "fr-FR", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckVoice(voices, tt.languageCode, tt.voiceName)
+			if tt.wantErr != errors.Is(err, ErrUnknownVoice) {
+				t.Errorf("CheckVoice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVoiceCatalog(t *testing.T) {
+	var lists int
+	registry := NewEngineRegistry()
+	registry.Register("listing", countingEngine{lists: &lists, voices: []Voice{
+		{Name: "ja-JP-Wavenet-B", LanguageCodes: []string{"ja-JP"}},
+		{Name: "ja-JP-Standard-A", LanguageCodes: []string{"ja-JP"}},
+		{Name: "en-US-Wavenet-A", LanguageCodes: []string{"en-US"}},
+		{Name: "en-GB-Wavenet-A", LanguageCodes: []string{"en-GB"}},
+	}})
+	registry.Register("stub", stubEngine{})
+
+	now := time.Now()
+	catalog := NewVoiceCatalog(registry, time.Hour)
+	catalog.now = func() time.Time { return now }
+
+	tests := []struct {
+		language     string
+		wantVoice    string
+		wantLanguage string
+	}{
+		{language: "ja", wantVoice: "ja-JP-Standard-A", wantLanguage: "ja-JP"},
+		{language: "en", wantVoice: "en-GB-Wavenet-A", wantLanguage: "en-GB"},
+		{language: "en-us", wantVoice: "en-US-Wavenet-A", wantLanguage: "en-US"},
+	}
+	for _, tt := range tests {
+		voice, languageCode, err := catalog.DefaultVoice(context.Background(), "listing", tt.language)
+		if err != nil {
+			t.Fatalf("DefaultVoice(%q) error = %v", tt.language, err)
+		}
+		if voice.Name != tt.wantVoice || languageCode != tt.wantLanguage {
+			t.Errorf("DefaultVoice(%q) = %q, %q, want %q, %q", tt.language, voice.Name, languageCode, tt.wantVoice, tt.wantLanguage)
+		}
+	}
+	if lists != 1 {
+		t.Errorf("voices listed %d times, want 1", lists)
+	}
+
+	voices, err := catalog.LanguageVoices(context.Background(), "listing", "ja")
+	if err != nil {
+		t.Fatalf("LanguageVoices() error = %v", err)
+	}
+	if len(voices) != 2 || voices[0].Name != "ja-JP-Standard-A" || voices[1].Name != "ja-JP-Wavenet-B" {
+		t.Errorf("LanguageVoices(%q) = %v, want the ja-JP voices sorted by name", "ja", voices)
+	}
+
+	if _, _, err := catalog.DefaultVoice(context.Background(), "listing", "ko"); !errors.Is(err, ErrUnknownVoice) {
+		t.Errorf("DefaultVoice(%q) error = %v, want %v", "ko", err, ErrUnknownVoice)
+	}
+	if _, _, err := catalog.DefaultVoice(context.Background(), "stub", "ja"); !errors.Is(err, ErrVoiceListingUnsupported) {
+		t.Errorf("DefaultVoice() error = %v, want %v", err, ErrVoiceListingUnsupported)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := catalog.Voices(context.Background(), "listing"); err != nil {
+		t.Fatalf("Voices() error = %v", err)
+	}
+	if lists != 2 {
+		t.Errorf("voices listed %d times after the TTL, want 2", lists)
+	}
+}
+
+type countingEngine struct {
+	stubEngine
+	lists  *int
+	voices []Voice
+}
+
+func (e countingEngine) ListVoices(ctx context.Context, languageCode string) ([]Voice, error) {
+	*e.lists++
+	return e.voices, nil
+}
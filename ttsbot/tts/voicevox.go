@@ -0,0 +1,178 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/logging"
+)
+
+var (
+	_ Engine              = (*VoicevoxEngine)(nil)
+	_ VoiceLister         = (*VoicevoxEngine)(nil)
+	_ SpeakingRateLimiter = (*VoicevoxEngine)(nil)
+	_ CapabilityReporter  = (*VoicevoxEngine)(nil)
+)
+
+// voicevoxLanguageCode is the language spoken by every VOICEVOX speaker.
+const voicevoxLanguageCode = "ja-JP"
+
+// VoicevoxEngine is an implementation of the Engine interface for a VOICEVOX engine server, e.g. one running locally.
+// Voices are named by the ID of the speaker style, e.g. "3", as listed by the /speakers endpoint.
+type VoicevoxEngine struct {
+	client     *http.Client
+	baseURL    string
+	sampleRate int
+}
+
+// NewVoicevoxEngine creates the engine calling the VOICEVOX server at the base URL, e.g. "http://localhost:50021".
+// VOICEVOX only synthesizes WAV, so the output format must be LINEAR16; a zero sample rate uses 48000.
+func NewVoicevoxEngine(client *http.Client, baseURL string, output OutputFormat) (*VoicevoxEngine, error) {
+	if _, err := url.ParseRequestURI(baseURL); err != nil {
+		return nil, fmt.Errorf("invalid voicevox url: %w", err)
+	}
+	if output.Format != AudioFormatUnknown && output.Format != AudioFormatLinear16 {
+		return nil, fmt.Errorf("voicevox does not offer %s", output.Format)
+	}
+	if output.SampleRate == 0 {
+		output.SampleRate = DefaultOutputFormat.SampleRate
+	}
+	return &VoicevoxEngine{
+		client:     client,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		sampleRate: output.SampleRate,
+	}, nil
+}
+
+func (v *VoicevoxEngine) Name() string {
+	return "voicevox"
+}
+
+// SpeakingRateRange returns the speed scales accepted by VOICEVOX.
+func (v *VoicevoxEngine) SpeakingRateRange() SpeakingRateRange {
+	return SpeakingRateRange{Min: 0.5, Max: 2.0}
+}
+
+// Capabilities reports the output format of v. VOICEVOX does not limit the length of the text.
+func (v *VoicevoxEngine) Capabilities() Capabilities {
+	return Capabilities{Output: OutputFormat{Format: AudioFormatLinear16, SampleRate: v.sampleRate}, ListsVoices: true, RequiresVoice: true}
+}
+
+func (v *VoicevoxEngine) GenerateSpeech(ctx context.Context, request SpeechRequest) (*SpeechResponse, error) {
+	slog.InfoContext(ctx, "Synthesize speech", logging.Component(logging.ComponentSynthesis), slog.String("text", request.Text))
+	if request.InputKind == InputKindSSML {
+		return nil, fmt.Errorf("voicevox does not support SSML")
+	}
+	speaker, err := strconv.Atoi(request.VoiceName)
+	if err != nil {
+		return nil, fmt.Errorf("voicevox voice name must be a speaker ID: %q", request.VoiceName)
+	}
+
+	// the audio query holds the accents and the parameters of the speech, which are adjusted before it is synthesized.
+	queryResp, err := v.post(ctx, "/audio_query", url.Values{"text": {request.Text}, "speaker": {strconv.Itoa(speaker)}}, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create audio query", "error", err)
+		return nil, err
+	}
+	var query map[string]any
+	if err := json.Unmarshal(queryResp, &query); err != nil {
+		return nil, fmt.Errorf("failed to decode audio query: %w", err)
+	}
+	if request.SpeakingRate != 0 {
+		query["speedScale"] = request.SpeakingRate
+	}
+	query["outputSamplingRate"] = v.sampleRate
+	query["outputStereo"] = false
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	audio, err := v.post(ctx, "/synthesis", url.Values{"speaker": {strconv.Itoa(speaker)}}, body)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to synthesize speech", "error", err)
+		return nil, err
+	}
+
+	return &SpeechResponse{
+		Format:     AudioFormatLinear16,
+		SampleRate: v.sampleRate,
+		Channels:   1,
+		Audio:      bytes.NewReader(audio),
+		Size:       int64(len(audio)),
+	}, nil
+}
+
+// ListVoices returns every style of every speaker, as VOICEVOX speakers only speak Japanese.
+func (v *VoicevoxEngine) ListVoices(ctx context.Context, languageCode string) ([]Voice, error) {
+	if languageCode != "" && !strings.EqualFold(languageCode, voicevoxLanguageCode) && !hasLanguagePrefix(voicevoxLanguageCode, languageCode) {
+		return nil, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.baseURL+"/speakers", nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := v.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var speakers []struct {
+		Name   string `json:"name"`
+		Styles []struct {
+			Name string `json:"name"`
+			ID   int    `json:"id"`
+		} `json:"styles"`
+	}
+	if err := json.Unmarshal(body, &speakers); err != nil {
+		return nil, fmt.Errorf("failed to decode speakers: %w", err)
+	}
+
+	var voices []Voice
+	for _, speaker := range speakers {
+		for _, style := range speaker.Styles {
+			voices = append(voices, Voice{
+				Name:          strconv.Itoa(style.ID),
+				LanguageCodes: []string{voicevoxLanguageCode},
+			})
+		}
+	}
+	return voices, nil
+}
+
+func (v *VoicevoxEngine) post(ctx context.Context, path string, query url.Values, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.baseURL+path+"?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return v.do(req)
+}
+
+func (v *VoicevoxEngine) do(req *http.Request) ([]byte, error) {
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("voicevox %s responded with %s: %s", req.URL.Path, resp.Status, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
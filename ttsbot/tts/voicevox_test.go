@@ -0,0 +1,78 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVoicevoxServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /audio_query", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "こんにちは", r.URL.Query().Get("text"))
+		assert.Equal(t, "3", r.URL.Query().Get("speaker"))
+		w.Write([]byte(`{"accent_phrases":[],"speedScale":1.0,"outputSamplingRate":24000,"outputStereo":false}`))
+	})
+	mux.HandleFunc("POST /synthesis", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "3", r.URL.Query().Get("speaker"))
+		var query map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&query))
+		assert.Equal(t, 1.5, query["speedScale"])
+		assert.Equal(t, 48000.0, query["outputSamplingRate"])
+		assert.Equal(t, []any{}, query["accent_phrases"])
+		w.Write([]byte("RIFF"))
+	})
+	mux.HandleFunc("GET /speakers", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name":"ずんだもん","styles":[{"name":"ノーマル","id":3},{"name":"あまあま","id":1}]}]`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVoicevoxEngine_GenerateSpeech(t *testing.T) {
+	server := newVoicevoxServer(t)
+	engine, err := NewVoicevoxEngine(server.Client(), server.URL+"/", OutputFormat{})
+	require.NoError(t, err)
+
+	resp, err := engine.GenerateSpeech(context.Background(), SpeechRequest{Text: "こんにちは", VoiceName: "3", SpeakingRate: 1.5})
+	require.NoError(t, err)
+	audio, _ := io.ReadAll(resp.Audio)
+	assert.Equal(t, "RIFF", string(audio))
+	assert.Equal(t, AudioFormatLinear16, resp.Format)
+	assert.Equal(t, 48000, resp.SampleRate)
+
+	_, err = engine.GenerateSpeech(context.Background(), SpeechRequest{Text: "こんにちは", VoiceName: "ずんだもん"})
+	assert.Error(t, err, "voice names are speaker IDs")
+}
+
+func TestVoicevoxEngine_ListVoices(t *testing.T) {
+	server := newVoicevoxServer(t)
+	engine, err := NewVoicevoxEngine(server.Client(), server.URL, OutputFormat{})
+	require.NoError(t, err)
+
+	voices, err := engine.ListVoices(context.Background(), "ja")
+	require.NoError(t, err)
+	assert.Equal(t, []Voice{
+		{Name: "3", LanguageCodes: []string{"ja-JP"}},
+		{Name: "1", LanguageCodes: []string{"ja-JP"}},
+	}, voices)
+
+	voices, err = engine.ListVoices(context.Background(), "en-US")
+	require.NoError(t, err)
+	assert.Empty(t, voices)
+}
+
+func TestNewVoicevoxEngine(t *testing.T) {
+	_, err := NewVoicevoxEngine(http.DefaultClient, "http://localhost:50021", OutputFormat{Format: AudioFormatMp3})
+	assert.Error(t, err, "voicevox only synthesizes WAV")
+	_, err = NewVoicevoxEngine(http.DefaultClient, "localhost", OutputFormat{})
+	assert.Error(t, err)
+}
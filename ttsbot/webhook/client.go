@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+var errForbiddenAddress = errors.New("webhook address is not public")
+
+// NewClient returns a client for delivering webhook events, which refuses to connect to addresses that are not public.
+// Hostnames are checked after they are resolved, as IsValidWebhookURL only sees the URL, and redirects are not followed,
+// so that a webhook cannot point the bot at its own network.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !settings.IsPublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errForbiddenAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		// the proxy of the environment is not used, as the address of the webhook would then never be dialed.
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
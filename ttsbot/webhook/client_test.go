@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClient(t *testing.T) {
+	t.Run("refuses addresses that are not public", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("request reached the server")
+		}))
+		defer server.Close()
+
+		_, err := NewClient(5 * time.Second).Get(server.URL)
+		require.ErrorIs(t, err, errForbiddenAddress)
+	})
+
+	t.Run("does not follow redirects", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/hook" {
+				http.Redirect(w, r, "/internal", http.StatusFound)
+				return
+			}
+			t.Error("redirect was followed")
+		}))
+		defer server.Close()
+
+		client := NewClient(5 * time.Second)
+		// the server listens on loopback, which the transport of the client refuses.
+		client.Transport = server.Client().Transport
+		resp, err := client.Get(server.URL + "/hook")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+	})
+}
@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+// EventType identifies what happened in a guild.
+type EventType string
+
+const (
+	EventSessionStarted EventType = "session.started"
+	EventSessionEnded   EventType = "session.ended"
+	// EventQuotaWarning is sent when the guild is about to run out of its synthesis quota.
+	EventQuotaWarning EventType = "quota.warning"
+	EventError        EventType = "error"
+)
+
+// Event is the JSON body posted to the webhook of a guild.
+type Event struct {
+	Type      EventType      `json:"type"`
+	GuildID   snowflake.ID   `json:"guild_id"`
+	Timestamp time.Time      `json:"timestamp"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// deliveryAttempts is how many times an event is posted before it is dropped.
+const deliveryAttempts = 3
+
+// Dispatcher posts the events of guilds to the webhook URLs in their settings.
+// Events are delivered in the background, in the order they were dispatched;
+// events dispatched while the queue is full are dropped, so a slow webhook never holds up the bot.
+type Dispatcher struct {
+	settings settings.GuildSettingsRepository
+	client   *http.Client
+	queue    chan Event
+	// retryDelay is the delay before the first retry, doubled on every further attempt.
+	retryDelay time.Duration
+}
+
+func NewDispatcher(settingsRepository settings.GuildSettingsRepository, client *http.Client, queueSize int) *Dispatcher {
+	return &Dispatcher{
+		settings:   settingsRepository,
+		client:     client,
+		queue:      make(chan Event, queueSize),
+		retryDelay: time.Second,
+	}
+}
+
+// Dispatch queues the event for delivery. It never blocks.
+func (d *Dispatcher) Dispatch(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	select {
+	case d.queue <- event:
+	default:
+		slog.Warn("Webhook queue is full, dropping event", slog.String("type", string(event.Type)), slog.Any("guildID", event.GuildID))
+	}
+}
+
+// Start delivers queued events until the context is done.
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-d.queue:
+				d.deliver(ctx, event)
+			}
+		}
+	}()
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, event Event) {
+	findCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	guildSettings, err := d.settings.Find(findCtx, event.GuildID)
+	cancel()
+	if err != nil || guildSettings.WebhookURL == "" {
+		// guilds without settings have no webhook either.
+		return
+	}
+
+	body, err := encodeEvent(guildSettings.WebhookURL, event)
+	if err != nil {
+		slog.Error("Failed to encode webhook event", slog.Any("err", err), slog.String("type", string(event.Type)))
+		return
+	}
+
+	delay := d.retryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(ctx, guildSettings.WebhookURL, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt == deliveryAttempts {
+			// the URL is not logged, as it usually contains a token.
+			slog.Warn("Failed to deliver webhook event", slog.Any("err", err), slog.String("type", string(event.Type)), slog.Any("guildID", event.GuildID), slog.Int("attempts", attempt))
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post sends the body to the URL, and reports whether a failed delivery is worth retrying.
+func (d *Dispatcher) post(ctx context.Context, url string, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook responded with %s", resp.Status)
+}
+
+// encodeEvent returns the body posted to the URL. Discord webhooks only accept messages,
+// so the event is posted to them as a message with the event in a code block.
+func encodeEvent(url string, event Event) ([]byte, error) {
+	if !isDiscordWebhook(url) {
+		return json.Marshal(event)
+	}
+
+	data, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]any{
+		"content":          fmt.Sprintf("**%s**\n```json\n%s\n```", event.Type, data),
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	})
+}
+
+func isDiscordWebhook(url string) bool {
+	for _, prefix := range []string{"https://discord.com/api/webhooks/", "https://discordapp.com/api/webhooks/", "https://canary.discord.com/api/webhooks/", "https://ptb.discord.com/api/webhooks/"} {
+		if strings.HasPrefix(url, prefix) {
+			return true
+		}
+	}
+	return false
+}
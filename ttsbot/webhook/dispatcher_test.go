@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makeitchaccha/text-to-speech/ttsbot/session"
+	"github.com/makeitchaccha/text-to-speech/ttsbot/settings"
+)
+
+// webhookSettings returns the settings of a guild whose webhook is url, bypassing the validation of Save.
+type webhookSettings struct {
+	settings.GuildSettingsRepository
+	url string
+}
+
+func (r webhookSettings) Find(ctx context.Context, guildID snowflake.ID) (settings.GuildSettings, error) {
+	guildSettings := settings.DefaultGuildSettings(guildID)
+	guildSettings.WebhookURL = r.url
+	return guildSettings, nil
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("delivers events as JSON", func(t *testing.T) {
+		received := make(chan Event, 1)
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			var event Event
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+			received <- event
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		dispatcher := NewDispatcher(webhookSettings{url: server.URL}, server.Client(), 10)
+		dispatcher.Start(ctx)
+
+		NewSessionObserver(dispatcher).OnDeleted(session.SessionDeletedEvent{Reason: session.CloseReasonEmpty})
+		select {
+		case event := <-received:
+			require.Equal(t, EventSessionEnded, event.Type)
+			require.Equal(t, "empty", event.Data["reason"])
+			require.False(t, event.Timestamp.IsZero())
+		case <-time.After(5 * time.Second):
+			t.Fatal("event was not delivered")
+		}
+	})
+
+	t.Run("retries server errors", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			if attempts.Add(1) < deliveryAttempts {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}))
+		defer server.Close()
+
+		dispatcher := NewDispatcher(webhookSettings{url: server.URL}, server.Client(), 10)
+		dispatcher.retryDelay = time.Millisecond
+		dispatcher.deliver(context.Background(), Event{Type: EventError, GuildID: 1})
+		require.Equal(t, int32(deliveryAttempts), attempts.Load())
+	})
+
+	t.Run("does not retry rejected events", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		dispatcher := NewDispatcher(webhookSettings{url: server.URL}, server.Client(), 10)
+		dispatcher.retryDelay = time.Millisecond
+		dispatcher.deliver(context.Background(), Event{Type: EventError, GuildID: 1})
+		require.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("drops events when the queue is full", func(t *testing.T) {
+		dispatcher := NewDispatcher(webhookSettings{}, http.DefaultClient, 1)
+		dispatcher.Dispatch(Event{Type: EventError, GuildID: 1})
+		dispatcher.Dispatch(Event{Type: EventError, GuildID: 2})
+		require.Len(t, dispatcher.queue, 1)
+	})
+}
+
+func TestEncodeEvent(t *testing.T) {
+	event := Event{Type: EventSessionStarted, GuildID: 1, Timestamp: time.Unix(0, 0).UTC()}
+
+	body, err := encodeEvent("https://example.com/hook", event)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"session.started","guild_id":"1","timestamp":"1970-01-01T00:00:00Z"}`, string(body))
+
+	body, err = encodeEvent("https://discord.com/api/webhooks/1/token", event)
+	require.NoError(t, err)
+	var message struct {
+		Content string `json:"content"`
+	}
+	require.NoError(t, json.Unmarshal(body, &message))
+	require.Contains(t, message.Content, "**session.started**")
+	require.Contains(t, message.Content, `"guild_id": "1"`)
+}
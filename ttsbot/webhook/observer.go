@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"github.com/makeitchaccha/text-to-speech/ttsbot/session"
+)
+
+var _ session.SessionLifecycleObserver = (*SessionObserver)(nil)
+
+// SessionObserver dispatches the lifecycle of sessions to the webhooks of their guilds.
+type SessionObserver struct {
+	session.NoOpSessionLifecycleObserver
+	dispatcher *Dispatcher
+}
+
+func NewSessionObserver(dispatcher *Dispatcher) *SessionObserver {
+	return &SessionObserver{dispatcher: dispatcher}
+}
+
+func (o *SessionObserver) OnCreated(e session.SessionCreatedEvent) {
+	o.dispatcher.Dispatch(Event{
+		Type:    EventSessionStarted,
+		GuildID: e.GuildID,
+		Data: map[string]any{
+			"voice_channel_id": e.VoiceChannelID,
+			"text_channel_id":  e.ReadingChannelID,
+		},
+	})
+}
+
+func (o *SessionObserver) OnRestored(e session.SessionRestoredEvent) {
+	o.dispatcher.Dispatch(Event{
+		Type:    EventSessionStarted,
+		GuildID: e.GuildID,
+		Data: map[string]any{
+			"voice_channel_id": e.VoiceChannelID,
+			"text_channel_id":  e.ReadingChannelID,
+			"restored":         true,
+		},
+	})
+}
+
+func (o *SessionObserver) OnFailed(e session.SessionFailedEvent) {
+	o.dispatcher.Dispatch(Event{
+		Type:    EventError,
+		GuildID: e.GuildID,
+		Data: map[string]any{
+			"voice_channel_id": e.VoiceChannelID,
+			"text_channel_id":  e.ReadingChannelID,
+			"message":          "the session could not be started",
+		},
+	})
+}
+
+func (o *SessionObserver) OnDeleted(e session.SessionDeletedEvent) {
+	o.dispatcher.Dispatch(Event{
+		Type:    EventSessionEnded,
+		GuildID: e.GuildID,
+		Data: map[string]any{
+			"voice_channel_id": e.VoiceChannelID,
+			"text_channel_id":  e.ReadingChannelID,
+			"reason":           e.Reason,
+		},
+	})
+}